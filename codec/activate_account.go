@@ -37,6 +37,12 @@ func (o ActivateAccount) MarshalJSON() ([]byte, error) {
 }
 
 func (o ActivateAccount) EncodeBuffer(buf *bytes.Buffer, p *tezos.Params) error {
+    if o.PublicKeyHash.Type != tezos.AddressTypeEd25519 || len(o.PublicKeyHash.Hash) != 20 {
+        return fmt.Errorf("tezos: activation requires a tz1 address, have %s", o.PublicKeyHash)
+    }
+    if len(o.Secret) != 20 {
+        return fmt.Errorf("tezos: invalid activation secret length %d", len(o.Secret))
+    }
     buf.WriteByte(o.Kind().TagVersion(p.OperationTagsVersion))
     buf.Write(o.PublicKeyHash.Hash) // only place where a 20 byte address is used (!)
     buf.Write(o.Secret.Bytes())
@@ -47,15 +53,15 @@ func (o *ActivateAccount) DecodeBuffer(buf *bytes.Buffer, p *tezos.Params) error
     if err := ensureTagAndSize(buf, o.Kind(), p.OperationTagsVersion); err != nil {
         return err
     }
+    if buf.Len() < 40 {
+        return io.ErrShortBuffer
+    }
     o.PublicKeyHash = tezos.NewAddress(tezos.AddressTypeEd25519, buf.Next(20))
     if !o.PublicKeyHash.IsValid() {
         return fmt.Errorf("invalid address type=%s len=%d", o.PublicKeyHash.Type, len(o.PublicKeyHash.Hash))
     }
     o.Secret = make([]byte, 20)
     copy(o.Secret, buf.Next(20))
-    if len(o.Secret) != 20 {
-        return io.ErrShortBuffer
-    }
     return nil
 }
 
@@ -0,0 +1,382 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package codec
+
+import (
+    "bytes"
+    "errors"
+    "fmt"
+    "io"
+
+    "blockwatch.cc/tzgo/tezos"
+)
+
+// ReadError describes a failed read from a binary message at byte offset Pos.
+// Truncated input wraps io.ErrShortBuffer.
+type ReadError struct {
+    Pos  int
+    What string
+    Err  error
+}
+
+func (e *ReadError) Error() string {
+    return fmt.Sprintf("tezos: reading %s at offset %d: %v", e.What, e.Pos, e.Err)
+}
+
+func (e *ReadError) Unwrap() error {
+    return e.Err
+}
+
+// Reader decodes Tezos binary encoded values such as zarith numbers, addresses,
+// keys and signatures from a byte slice. It is the building block for decoding
+// custom operation kinds or raw context values.
+type Reader struct {
+    buf []byte
+    pos int
+}
+
+// NewReader creates a reader for buf.
+func NewReader(buf []byte) *Reader {
+    return &Reader{buf: buf}
+}
+
+// Pos returns the offset of the next byte to read.
+func (r *Reader) Pos() int {
+    return r.pos
+}
+
+// Len returns the number of unread bytes.
+func (r *Reader) Len() int {
+    return len(r.buf) - r.pos
+}
+
+// Rest returns all unread bytes without consuming them.
+func (r *Reader) Rest() []byte {
+    return r.buf[r.pos:]
+}
+
+func (r *Reader) fail(what string, err error) error {
+    return &ReadError{Pos: r.pos, What: what, Err: err}
+}
+
+func (r *Reader) short(what string, n int) error {
+    return r.fail(what, fmt.Errorf("%w: need %d bytes, have %d", io.ErrShortBuffer, n, r.Len()))
+}
+
+func (r *Reader) next(what string, n int) ([]byte, error) {
+    if n < 0 || r.Len() < n {
+        return nil, r.short(what, n)
+    }
+    b := r.buf[r.pos : r.pos+n]
+    r.pos += n
+    return b, nil
+}
+
+// ReadBytes reads n bytes. The returned slice aliases the reader's buffer.
+func (r *Reader) ReadBytes(n int) ([]byte, error) {
+    return r.next("bytes", n)
+}
+
+// ReadVarBytes reads a byte string with a 4 byte length prefix.
+func (r *Reader) ReadVarBytes() ([]byte, error) {
+    start := r.pos
+    n, err := r.ReadUint32()
+    if err != nil {
+        return nil, err
+    }
+    b, err := r.next("bytes", int(n))
+    if err != nil {
+        r.pos = start
+        return nil, err
+    }
+    return b, nil
+}
+
+func (r *Reader) ReadByte() (byte, error) {
+    b, err := r.next("byte", 1)
+    if err != nil {
+        return 0, err
+    }
+    return b[0], nil
+}
+
+// ReadBool reads a boolean encoded as 0x00 or 0xff.
+func (r *Reader) ReadBool() (bool, error) {
+    b, err := r.next("bool", 1)
+    if err != nil {
+        return false, err
+    }
+    switch b[0] {
+    case 0x00:
+        return false, nil
+    case 0xff:
+        return true, nil
+    default:
+        r.pos--
+        return false, r.fail("bool", fmt.Errorf("invalid value %x", b[0]))
+    }
+}
+
+func (r *Reader) ReadUint16() (uint16, error) {
+    b, err := r.next("uint16", 2)
+    if err != nil {
+        return 0, err
+    }
+    return enc.Uint16(b), nil
+}
+
+func (r *Reader) ReadUint32() (uint32, error) {
+    b, err := r.next("uint32", 4)
+    if err != nil {
+        return 0, err
+    }
+    return enc.Uint32(b), nil
+}
+
+func (r *Reader) ReadInt32() (int32, error) {
+    b, err := r.next("int32", 4)
+    if err != nil {
+        return 0, err
+    }
+    return int32(enc.Uint32(b)), nil
+}
+
+func (r *Reader) ReadInt64() (int64, error) {
+    b, err := r.next("int64", 8)
+    if err != nil {
+        return 0, err
+    }
+    return int64(enc.Uint64(b)), nil
+}
+
+// ReadZ reads a signed zarith number.
+func (r *Reader) ReadZ() (tezos.Z, error) {
+    var z tezos.Z
+    buf := bytes.NewBuffer(r.Rest())
+    if err := z.DecodeBuffer(buf); err != nil {
+        return z, r.zarithError("zarith", err)
+    }
+    r.pos = len(r.buf) - buf.Len()
+    return z, nil
+}
+
+// ReadN reads an unsigned zarith number.
+func (r *Reader) ReadN() (tezos.N, error) {
+    var n tezos.N
+    buf := bytes.NewBuffer(r.Rest())
+    if err := n.DecodeBuffer(buf); err != nil {
+        return n, r.zarithError("natural", err)
+    }
+    r.pos = len(r.buf) - buf.Len()
+    return n, nil
+}
+
+// zarithError reports truncated zarith numbers at the end of input where
+// the continuation byte is missing.
+func (r *Reader) zarithError(what string, err error) error {
+    if errors.Is(err, io.ErrShortBuffer) {
+        return &ReadError{
+            Pos:  len(r.buf),
+            What: what,
+            Err:  fmt.Errorf("%w: number starting at offset %d is truncated", io.ErrShortBuffer, r.pos),
+        }
+    }
+    return r.fail(what, err)
+}
+
+// ReadAddress reads a 22 byte contract id as used for transaction destinations.
+func (r *Reader) ReadAddress() (tezos.Address, error) {
+    a, n, err := tezos.DecodeAddress(r.Rest())
+    if err != nil {
+        if r.Len() < 22 {
+            return a, r.short("address", 22)
+        }
+        return a, r.fail("address", err)
+    }
+    r.pos += n
+    return a, nil
+}
+
+// ReadPublicKeyHash reads a 21 byte implicit account address as used for
+// sources and delegates.
+func (r *Reader) ReadPublicKeyHash() (tezos.Address, error) {
+    b, err := r.next("public key hash", 21)
+    if err != nil {
+        return tezos.InvalidAddress, err
+    }
+    switch b[0] {
+    case 0, 1, 2:
+        return tezos.NewAddress(tezos.ParseAddressTag(b[0]), append([]byte{}, b[1:]...)), nil
+    default:
+        r.pos -= 21
+        return tezos.InvalidAddress, r.fail("public key hash", fmt.Errorf("invalid tag %x", b[0]))
+    }
+}
+
+// ReadKey reads a tagged public key.
+func (r *Reader) ReadKey() (tezos.Key, error) {
+    k, n, err := tezos.DecodeBinaryKey(r.Rest())
+    if err != nil {
+        if r.Len() > 0 && tezos.ParseKeyTag(r.buf[r.pos]).IsValid() {
+            return k, r.short("public key", tezos.ParseKeyTag(r.buf[r.pos]).PkHashType().Len()+1)
+        }
+        return k, r.fail("public key", err)
+    }
+    r.pos += n
+    return k, nil
+}
+
+// ReadSignature reads a tagged signature.
+func (r *Reader) ReadSignature() (tezos.Signature, error) {
+    s, n, err := tezos.DecodeSignature(r.Rest())
+    if err != nil {
+        if r.Len() > 0 && tezos.ParseSignatureTag(r.buf[r.pos]).IsValid() {
+            return s, r.short("signature", tezos.ParseSignatureTag(r.buf[r.pos]).Len()+1)
+        }
+        return s, r.fail("signature", err)
+    }
+    r.pos += n
+    return s, nil
+}
+
+// ReadGenericSignature reads an untagged 64 byte signature as found in
+// operations and block headers.
+func (r *Reader) ReadGenericSignature() (tezos.Signature, error) {
+    b, err := r.next("signature", tezos.SignatureTypeGeneric.Len())
+    if err != nil {
+        return tezos.InvalidSignature, err
+    }
+    return tezos.NewSignature(tezos.SignatureTypeGeneric, append([]byte{}, b...)), nil
+}
+
+// Writer encodes values in Tezos binary format. Writes to the underlying
+// buffer never fail, errors are only returned for values that cannot be
+// encoded.
+type Writer struct {
+    buf bytes.Buffer
+}
+
+// NewWriter creates an empty writer.
+func NewWriter() *Writer {
+    return &Writer{}
+}
+
+// Bytes returns the encoded data.
+func (w *Writer) Bytes() []byte {
+    return w.buf.Bytes()
+}
+
+// Len returns the number of bytes written.
+func (w *Writer) Len() int {
+    return w.buf.Len()
+}
+
+// Buffer returns the underlying buffer for use with EncodeBuffer methods.
+func (w *Writer) Buffer() *bytes.Buffer {
+    return &w.buf
+}
+
+func (w *Writer) Write(b []byte) (int, error) {
+    return w.buf.Write(b)
+}
+
+func (w *Writer) WriteByte(b byte) error {
+    return w.buf.WriteByte(b)
+}
+
+// WriteBytes writes b without length prefix.
+func (w *Writer) WriteBytes(b []byte) {
+    w.buf.Write(b)
+}
+
+// WriteVarBytes writes b with a 4 byte length prefix.
+func (w *Writer) WriteVarBytes(b []byte) {
+    w.WriteUint32(uint32(len(b)))
+    w.buf.Write(b)
+}
+
+// WriteBool writes a boolean as 0x00 or 0xff.
+func (w *Writer) WriteBool(b bool) {
+    if b {
+        w.buf.WriteByte(0xff)
+    } else {
+        w.buf.WriteByte(0x00)
+    }
+}
+
+func (w *Writer) WriteUint16(v uint16) {
+    var b [2]byte
+    enc.PutUint16(b[:], v)
+    w.buf.Write(b[:])
+}
+
+func (w *Writer) WriteUint32(v uint32) {
+    var b [4]byte
+    enc.PutUint32(b[:], v)
+    w.buf.Write(b[:])
+}
+
+func (w *Writer) WriteInt32(v int32) {
+    w.WriteUint32(uint32(v))
+}
+
+func (w *Writer) WriteInt64(v int64) {
+    var b [8]byte
+    enc.PutUint64(b[:], uint64(v))
+    w.buf.Write(b[:])
+}
+
+// WriteZ writes a signed zarith number.
+func (w *Writer) WriteZ(z tezos.Z) {
+    _ = z.EncodeBuffer(&w.buf)
+}
+
+// WriteN writes an unsigned zarith number.
+func (w *Writer) WriteN(n tezos.N) error {
+    return n.EncodeBuffer(&w.buf)
+}
+
+// WriteAddress writes the 22 byte contract id of a.
+func (w *Writer) WriteAddress(a tezos.Address) error {
+    b, err := a.EncodeBinary()
+    if err != nil {
+        return err
+    }
+    w.buf.Write(b)
+    return nil
+}
+
+// WritePublicKeyHash writes the 21 byte form of an implicit account address.
+func (w *Writer) WritePublicKeyHash(a tezos.Address) error {
+    switch a.Type {
+    case tezos.AddressTypeEd25519, tezos.AddressTypeSecp256k1, tezos.AddressTypeP256:
+    default:
+        return fmt.Errorf("tezos: %s address is not a public key hash", a.Type)
+    }
+    if len(a.Hash) != 20 {
+        return fmt.Errorf("tezos: invalid address hash length %d", len(a.Hash))
+    }
+    w.buf.Write(a.Bytes())
+    return nil
+}
+
+// WriteKey writes a tagged public key.
+func (w *Writer) WriteKey(k tezos.Key) error {
+    b, err := k.EncodeBinary()
+    if err != nil {
+        return err
+    }
+    w.buf.Write(b)
+    return nil
+}
+
+// WriteSignature writes a tagged signature or an untagged generic signature.
+func (w *Writer) WriteSignature(s tezos.Signature) error {
+    b, err := s.EncodeBinary()
+    if err != nil {
+        return err
+    }
+    w.buf.Write(b)
+    return nil
+}
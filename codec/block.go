@@ -56,6 +56,11 @@ func (h BlockHeader) Digest() []byte {
     return d[:]
 }
 
+// Hash returns the block hash of a signed block header.
+func (h BlockHeader) Hash() tezos.BlockHash {
+    return tezos.ComputeBlockHash(h.Bytes())
+}
+
 // Sign signs the block header using a private key and generates a generic signature.
 // If a valid signature already exists, this function is a noop.
 func (h *BlockHeader) Sign(key tezos.PrivateKey) error {
@@ -0,0 +1,138 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package codec
+
+import (
+    "fmt"
+
+    "blockwatch.cc/tzgo/micheline"
+    "blockwatch.cc/tzgo/tezos"
+)
+
+// WithTransfer adds a tez transfer of amount mutez to addr.
+func (o *Op) WithTransfer(to tezos.Address, amount int64) *Op {
+    return o.WithManager(&Transaction{
+        Amount:      tezos.N(amount),
+        Destination: to,
+    })
+}
+
+// WithContractCall adds a smart contract call with params to addr.
+func (o *Op) WithContractCall(to tezos.Address, amount int64, params micheline.Parameters) *Op {
+    return o.WithManager(&Transaction{
+        Amount:      tezos.N(amount),
+        Destination: to,
+        Parameters:  &params,
+    })
+}
+
+// WithOrigination adds a contract origination with initial balance in mutez.
+func (o *Op) WithOrigination(script micheline.Script, balance int64) *Op {
+    return o.WithManager(&Origination{
+        Balance: tezos.N(balance),
+        Script:  script,
+    })
+}
+
+// WithDelegation adds a delegation to addr. Use an empty address to
+// withdraw the current delegation.
+func (o *Op) WithDelegation(to tezos.Address) *Op {
+    return o.WithManager(&Delegation{
+        Delegate: to,
+    })
+}
+
+// WithReveal adds a reveal of key. Use it to publish the public key of a
+// freshly activated account before it can send other operations.
+func (o *Op) WithReveal(key tezos.Key) *Op {
+    return o.WithManager(&Reveal{
+        PublicKey: key,
+    })
+}
+
+// WithActivation adds a fundraiser account activation for the implicit
+// account pkh with its 20 byte activation secret. Activations are anonymous
+// operations, they cannot be combined with manager operations and are
+// broadcast without signature.
+func (o *Op) WithActivation(pkh tezos.Address, secret []byte) *Op {
+    return o.WithContents(&ActivateAccount{
+        PublicKeyHash: pkh,
+        Secret:        secret,
+    })
+}
+
+// WithManager adds a manager operation to the end of the contents list. When
+// the previous manager operation has a counter set the new operation uses the
+// next counter, and it inherits the source of the first manager operation.
+func (o *Op) WithManager(op Operation) *Op {
+    for i := len(o.Contents) - 1; i >= 0; i-- {
+        if c := o.Contents[i].GetCounter(); c > 0 {
+            op.WithCounter(c + 1)
+            break
+        }
+    }
+    for _, v := range o.Contents {
+        if src, ok := v.(interface{ GetSource() tezos.Address }); ok && src.GetSource().IsValid() {
+            op.WithSource(src.GetSource())
+            break
+        }
+    }
+    return o.WithContents(op)
+}
+
+// WithCounter assigns sequential counters starting at first to all manager
+// operations in the contents list.
+func (o *Op) WithCounter(first int64) *Op {
+    for _, v := range o.Contents {
+        // skip non-manager ops
+        if v.GetCounter() < 0 {
+            continue
+        }
+        v.WithCounter(first)
+        first++
+    }
+    return o
+}
+
+// Build validates the contents list and returns the operation. Manager
+// operations must not be mixed with other operation kinds, a reveal may only
+// appear first, all sources must match and counters must either be unset
+// or sequential.
+func (o *Op) Build() (*Op, error) {
+    if len(o.Contents) == 0 {
+        return nil, fmt.Errorf("tezos: empty operation contents")
+    }
+    var (
+        source  tezos.Address
+        counter int64
+    )
+    isManager := o.Contents[0].GetCounter() >= 0
+    for i, v := range o.Contents {
+        if (v.GetCounter() >= 0) != isManager {
+            return nil, fmt.Errorf("tezos: cannot mix manager and %s operations", v.Kind())
+        }
+        if !isManager {
+            continue
+        }
+        if v.Kind() == tezos.OpTypeReveal && i > 0 {
+            return nil, fmt.Errorf("tezos: reveal must be the first operation, found at position %d", i)
+        }
+        if src, ok := v.(interface{ GetSource() tezos.Address }); ok {
+            switch {
+            case i == 0:
+                source = src.GetSource()
+            case !src.GetSource().Equal(source):
+                return nil, fmt.Errorf("tezos: source mismatch at position %d: %s != %s", i, src.GetSource(), source)
+            }
+        }
+        c := v.GetCounter()
+        switch {
+        case i == 0:
+            counter = c
+        case counter == 0 && c != 0, counter > 0 && c != counter+int64(i):
+            return nil, fmt.Errorf("tezos: non-sequential counter %d at position %d", c, i)
+        }
+    }
+    return o, nil
+}
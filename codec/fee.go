@@ -0,0 +1,63 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package codec
+
+import (
+    "bytes"
+
+    "blockwatch.cc/tzgo/tezos"
+)
+
+// GasSafetyMargin is the amount of gas added on top of simulated gas usage
+// before fees are calculated. This matches the default used by tezos-client.
+const GasSafetyMargin int64 = 100
+
+// CalcFee returns the minimum fee in mutez for a signed operation that consumes
+// gas units in total. The formula matches the default baker fee filter
+//
+//   fee = 100 mutez + 1 mutez/byte * size + 0.1 mutez/gas * (gas + margin)
+//
+// where size includes branch and signature. Use params p to correctly encode
+// operation tags (nil means default params).
+func CalcFee(op *Op, gas int64, p *tezos.Params) int64 {
+    if p == nil {
+        p = op.Params
+    }
+    if p == nil {
+        p = tezos.DefaultParams
+    }
+    buf := bytes.NewBuffer(nil)
+    for _, v := range op.Contents {
+        _ = v.EncodeBuffer(buf, p)
+    }
+    sz := int64(buf.Len()) + 32 + 64 // branch + signature
+    gas += GasSafetyMargin * int64(len(op.Contents))
+    return nanoToMutez(minFeeFixedNanoTez + sz*minFeeByteNanoTez + gas*minFeeGasNanoTez)
+}
+
+// CalcBurn returns the amount of mutez burned for storageBytes of newly
+// allocated storage under chain params p.
+func CalcBurn(storageBytes int64, p *tezos.Params) int64 {
+    if p == nil {
+        p = tezos.DefaultParams
+    }
+    return storageBytes * p.CostPerByte
+}
+
+// SetFees sets the minimum fee for each content of a (batch) operation based on
+// gas consumed by the individual content. Gas and storage limits are kept. Each
+// content pays for its own size and gas, the first content additionally pays
+// for the operation header (branch and signature). This ensures each content
+// passes the fee filter even when bakers check batch contents individually.
+func SetFees(op *Op, gas []int64) {
+    for i, v := range op.Contents {
+        var g int64
+        if i < len(gas) {
+            g = gas[i]
+        }
+        l := v.Limits()
+        l.Fee = CalculateMinFee(v, g+GasSafetyMargin, i == 0)
+        v.WithLimits(l)
+    }
+}
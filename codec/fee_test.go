@@ -0,0 +1,82 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package codec
+
+import (
+    "testing"
+
+    "blockwatch.cc/tzgo/tezos"
+)
+
+var (
+    testFeeSource = tezos.MustParseAddress("tz1KqTpEZ7Yob7QbPE4Hy4Wo8fHG8LhKxZSx")
+    testFeeDest   = tezos.MustParseAddress("tz1S5WxdZR5f9NzsPXhr7L9L1vrEb5spZFur")
+)
+
+// testFeeTx returns a 52 byte transaction: tag, source, fee, counter, gas and
+// storage limits, 3 byte amount, destination and parameters flag.
+func testFeeTx() *Transaction {
+    return &Transaction{
+        Manager: Manager{
+            Source:  testFeeSource,
+            Counter: 1,
+        },
+        Amount:      1000000,
+        Destination: testFeeDest,
+    }
+}
+
+func TestCalcFee(t *testing.T) {
+    for _, test := range []struct {
+        name string
+        n    int
+        gas  int64
+        p    *tezos.Params
+        want int64
+    }{
+        // 100 + 148 bytes + (1000 + 100 gas) / 10
+        {name: "single", n: 1, gas: 1000, want: 358},
+        // 1101 gas costs 110.1 mutez which is rounded up
+        {name: "round up", n: 1, gas: 1001, want: 359},
+        {name: "no gas", n: 1, gas: 0, want: 258},
+        // 100 + 200 bytes + (1000 + 2 * 100 gas) / 10
+        {name: "batch", n: 2, gas: 1000, want: 420},
+        {name: "explicit params", n: 1, gas: 1000, p: tezos.DefaultParams, want: 358},
+    } {
+        op := &Op{}
+        for i := 0; i < test.n; i++ {
+            op.WithContents(testFeeTx())
+        }
+        if got := CalcFee(op, test.gas, test.p); got != test.want {
+            t.Errorf("%s: fee got %d want %d", test.name, got, test.want)
+        }
+    }
+}
+
+func TestCalcBurn(t *testing.T) {
+    for _, test := range []struct {
+        bytes int64
+        p     *tezos.Params
+        want  int64
+    }{
+        {0, nil, 0},
+        {257, nil, 64250},
+        {100, &tezos.Params{CostPerByte: 1000}, 100000},
+    } {
+        if got := CalcBurn(test.bytes, test.p); got != test.want {
+            t.Errorf("%d bytes: burn got %d want %d", test.bytes, got, test.want)
+        }
+    }
+}
+
+func TestSetFees(t *testing.T) {
+    op := NewOp().WithContents(testFeeTx()).WithContents(testFeeTx()).WithContents(testFeeTx())
+    SetFees(op, []int64{1000, 500})
+    // the first content pays for branch and signature, missing gas counts as zero
+    for i, want := range []int64{358, 212, 162} {
+        if got := op.Contents[i].Limits().Fee; got != want {
+            t.Errorf("content %d: fee got %d want %d", i, got, want)
+        }
+    }
+}
@@ -124,7 +124,7 @@ func (o *Op) WithBranch(hash tezos.BlockHash) *Op {
 // remaining fees to zero).
 func (o *Op) WithLimits(limits []tezos.Limits, margin int64) *Op {
     for i, v := range o.Contents {
-        if len(limits) >= i {
+        if i >= len(limits) {
             continue
         }
         gas := limits[i].GasLimit + margin
@@ -204,6 +204,12 @@ func (o *Op) Digest() []byte {
     return d[:]
 }
 
+// Hash returns the operation hash. The operation must be signed unless it is
+// an unsigned kind like account activation.
+func (o *Op) Hash() tezos.OpHash {
+    return tezos.ComputeOpHash(o.Bytes())
+}
+
 // WithSignature adds an externally created signature to the operation.
 // No signature validation is performed, it is assumed the signature is correct.
 func (o *Op) WithSignature(sig tezos.Signature) *Op {
@@ -303,6 +309,10 @@ func DecodeOp(data []byte) (*Op, error) {
             op = new(RegisterGlobalConstant)
         case tezos.OpTypeSetDepositsLimit:
             op = new(SetDepositsLimit)
+        case tezos.OpTypeUpdateConsensusKey:
+            op = new(UpdateConsensusKey)
+        case tezos.OpTypeDrainDelegate:
+            op = new(DrainDelegate)
         default:
             // stop if rest looks like a signature
             if buf.Len() == 64 {
@@ -0,0 +1,71 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package codec
+
+import (
+    "blockwatch.cc/tzgo/micheline"
+    "blockwatch.cc/tzgo/tezos"
+)
+
+// Staking pseudo-operations (v018+) are transactions from an implicit account
+// to itself that call one of the special entrypoints below.
+const (
+    EntrypointStake           = "stake"
+    EntrypointUnstake         = "unstake"
+    EntrypointFinalizeUnstake = "finalize_unstake"
+)
+
+// IsStakingEntrypoint returns true when ep is one of the staking entrypoints.
+func IsStakingEntrypoint(ep string) bool {
+    switch ep {
+    case EntrypointStake, EntrypointUnstake, EntrypointFinalizeUnstake:
+        return true
+    }
+    return false
+}
+
+// NewStake returns a transaction that stakes amount from source with its delegate.
+func NewStake(source tezos.Address, amount tezos.N) *Transaction {
+    return newStakingTransaction(source, amount, EntrypointStake)
+}
+
+// NewUnstake returns a transaction that requests to unstake amount. Use the
+// maximum int64 value to unstake everything.
+func NewUnstake(source tezos.Address, amount tezos.N) *Transaction {
+    return newStakingTransaction(source, amount, EntrypointUnstake)
+}
+
+// NewFinalizeUnstake returns a transaction that moves all finalizable unstaked
+// funds of source back to its spendable balance.
+func NewFinalizeUnstake(source tezos.Address) *Transaction {
+    return newStakingTransaction(source, 0, EntrypointFinalizeUnstake)
+}
+
+func newStakingTransaction(source tezos.Address, amount tezos.N, entrypoint string) *Transaction {
+    tx := &Transaction{
+        Amount:      amount,
+        Destination: source,
+        Parameters: &micheline.Parameters{
+            Entrypoint: entrypoint,
+            Value:      micheline.NewCode(micheline.D_UNIT),
+        },
+    }
+    tx.Manager.Source = source
+    return tx
+}
+
+// StakingEntrypoint returns the staking entrypoint called by o or an empty
+// string when o is not a staking pseudo-operation.
+func (o Transaction) StakingEntrypoint() string {
+    if o.Parameters == nil || !o.Source.Equal(o.Destination) || !IsStakingEntrypoint(o.Parameters.Entrypoint) {
+        return ""
+    }
+    return o.Parameters.Entrypoint
+}
+
+// IsStaking returns true when o is a stake, unstake or finalize_unstake
+// pseudo-operation.
+func (o Transaction) IsStaking() bool {
+    return o.StakingEntrypoint() != ""
+}
@@ -13,7 +13,7 @@ import (
 
 const (
     minFeeFixedNanoTez int64 = 100_000
-    minFeeByteNanoTez  int64 = 250_000
+    minFeeByteNanoTez  int64 = 1_000
     minFeeGasNanoTez   int64 = 100
 )
 
@@ -22,13 +22,17 @@ const (
 // pass the fee filter and may time out in the mempool.
 func CalculateMinFee(o Operation, gas int64, withHeader bool) int64 {
     buf := bytes.NewBuffer(nil)
-    _ = o.EncodeBuffer(buf, nil)
+    _ = o.EncodeBuffer(buf, tezos.DefaultParams)
     sz := int64(buf.Len())
     if withHeader {
         sz += 32 + 64 // branch + signature
     }
-    fee := minFeeFixedNanoTez + sz*minFeeByteNanoTez + gas*minFeeGasNanoTez
-    return fee / 1000 // nano -> micro
+    return nanoToMutez(minFeeFixedNanoTez + sz*minFeeByteNanoTez + gas*minFeeGasNanoTez)
+}
+
+// nanoToMutez converts nano tez to mutez rounding up.
+func nanoToMutez(n int64) int64 {
+    return (n + 999) / 1000
 }
 
 // ensureTagAndSize reads the binary operation's tag and matches it against the expected
@@ -0,0 +1,111 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package contract
+
+import (
+	"context"
+	"fmt"
+
+	"blockwatch.cc/tzgo/codec"
+	"blockwatch.cc/tzgo/micheline"
+	"blockwatch.cc/tzgo/rpc"
+	"blockwatch.cc/tzgo/tezos"
+)
+
+// BenchmarkResult summarizes the simulated costs of repeated entrypoint calls.
+// Gas is reported in gas units, storage as paid storage size diff in bytes.
+type BenchmarkResult struct {
+	Runs       []tezos.Costs // costs of each run in order
+	MinGas     int64
+	MaxGas     int64
+	AvgGas     int64
+	MinStorage int64
+	MaxStorage int64
+	AvgStorage int64
+}
+
+// BenchmarkEntrypoint simulates iterations calls to entrypoint of contract addr
+// with run_operation and reports min, max and average consumed gas and paid
+// storage. Params is called with the iteration number to produce each call's
+// argument which allows measuring the effect of input size. The client's
+// signer provides the source account. Nothing is injected.
+func BenchmarkEntrypoint(ctx context.Context, cli *rpc.Client, addr tezos.Address, entrypoint string, params func(i int) micheline.Prim, iterations int) (*BenchmarkResult, error) {
+	if iterations <= 0 {
+		return nil, fmt.Errorf("contract: invalid benchmark iterations %d", iterations)
+	}
+	if cli.Signer == nil {
+		return nil, rpc.ErrNoSigner
+	}
+	key, err := cli.Signer.Key(ctx)
+	if err != nil {
+		return nil, err
+	}
+	p, err := cli.Params(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res := &BenchmarkResult{
+		Runs: make([]tezos.Costs, 0, iterations),
+	}
+	for i := 0; i < iterations; i++ {
+		tx := &codec.Transaction{
+			Manager: codec.Manager{
+				Source: key.Address(),
+			},
+			Destination: addr,
+			Parameters: &micheline.Parameters{
+				Entrypoint: entrypoint,
+				Value:      params(i),
+			},
+		}
+		tx.WithLimits(tezos.Limits{
+			GasLimit:     p.HardGasLimitPerOperation,
+			StorageLimit: p.HardStorageLimitPerOperation,
+		})
+		op := codec.NewOp().WithParams(p).WithContents(tx)
+		if err := cli.Complete(ctx, op, key); err != nil {
+			return nil, err
+		}
+		sim, err := cli.Simulate(ctx, op)
+		if err != nil {
+			return nil, err
+		}
+		if len(sim.Op.Contents) == 0 {
+			return nil, fmt.Errorf("contract: empty simulation result in run %d", i)
+		}
+		// a reveal may precede the call
+		call := sim.Op.Contents[len(sim.Op.Contents)-1]
+		if r := call.Result(); !r.Status.IsSuccess() {
+			if len(r.Errors) > 0 {
+				return nil, fmt.Errorf("contract: benchmark run %d %s: %s", i, r.Status, r.Errors[len(r.Errors)-1].ID)
+			}
+			return nil, fmt.Errorf("contract: benchmark run %d %s", i, r.Status)
+		}
+		res.add(call.Costs())
+	}
+	return res, nil
+}
+
+func (r *BenchmarkResult) add(c tezos.Costs) {
+	if len(r.Runs) == 0 || c.GasUsed < r.MinGas {
+		r.MinGas = c.GasUsed
+	}
+	if c.GasUsed > r.MaxGas {
+		r.MaxGas = c.GasUsed
+	}
+	if len(r.Runs) == 0 || c.StorageUsed < r.MinStorage {
+		r.MinStorage = c.StorageUsed
+	}
+	if c.StorageUsed > r.MaxStorage {
+		r.MaxStorage = c.StorageUsed
+	}
+	r.Runs = append(r.Runs, c)
+	var gas, storage int64
+	for _, v := range r.Runs {
+		gas += v.GasUsed
+		storage += v.StorageUsed
+	}
+	r.AvgGas = gas / int64(len(r.Runs))
+	r.AvgStorage = storage / int64(len(r.Runs))
+}
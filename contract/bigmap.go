@@ -0,0 +1,583 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package contract
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"blockwatch.cc/tzgo/micheline"
+	"blockwatch.cc/tzgo/rpc"
+	"blockwatch.cc/tzgo/tezos"
+)
+
+// BlockBigmapDiff returns all updates applied by successful operations and
+// internal operations in block in execution order. Updates are in legacy
+// big_map_diff form, see rpc.OperationResult.BigmapUpdates.
+func BlockBigmapDiff(block *rpc.Block) micheline.BigmapDiff {
+	diff := make(micheline.BigmapDiff, 0)
+	if block == nil {
+		return diff
+	}
+	for _, list := range block.Operations {
+		for _, op := range list {
+			for _, c := range op.Contents {
+				if res := c.Result(); res.Status.IsSuccess() {
+					diff = append(diff, res.BigmapUpdates()...)
+				}
+				for _, in := range c.Meta().InternalResults {
+					if in.Result.Status.IsSuccess() {
+						diff = append(diff, in.Result.BigmapUpdates()...)
+					}
+				}
+			}
+		}
+	}
+	return diff
+}
+
+// ReplayBigmap reconstructs the contents of bigmap at toLevel by reading its
+// state at fromLevel once and then applying the bigmap diffs of all blocks in
+// between. This avoids re-reading every key at each block. A bigmap that does
+// not yet exist at fromLevel starts empty.
+//
+// Results are keyed by the base58 encoded key hash because ExprHash is not
+// comparable. When the bigmap is copied from another non-temporary bigmap the
+// source contents are read at the block preceding the copy.
+func ReplayBigmap(ctx context.Context, cli *rpc.Client, bigmap int64, fromLevel, toLevel int64) (map[string]micheline.Prim, error) {
+	if fromLevel > toLevel {
+		return nil, fmt.Errorf("contract: invalid bigmap replay range %d..%d", fromLevel, toLevel)
+	}
+	state, err := readBigmap(ctx, cli, bigmap, fromLevel)
+	if err != nil {
+		return nil, err
+	}
+	for level := fromLevel + 1; level <= toLevel; level++ {
+		block, err := cli.GetBlockHeight(ctx, level)
+		if err != nil {
+			return nil, err
+		}
+		state, err = applyBigmapDiff(ctx, cli, bigmap, level, state, BlockBigmapDiff(block))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return state, nil
+}
+
+func applyBigmapDiff(ctx context.Context, cli *rpc.Client, bigmap, level int64, state map[string]micheline.Prim, diff micheline.BigmapDiff) (map[string]micheline.Prim, error) {
+	// temporary bigmaps only live during a single block
+	temp := make(map[int64]map[string]micheline.Prim)
+	get := func(id int64) map[string]micheline.Prim {
+		if id == bigmap {
+			return state
+		}
+		return temp[id]
+	}
+	for _, d := range diff {
+		if d.Id != bigmap && d.Id >= 0 {
+			continue
+		}
+		switch d.Action {
+		case micheline.DiffActionAlloc:
+			if d.Id == bigmap {
+				state = make(map[string]micheline.Prim)
+			} else {
+				temp[d.Id] = make(map[string]micheline.Prim)
+			}
+		case micheline.DiffActionCopy:
+			if d.DestId != bigmap && d.DestId >= 0 {
+				continue
+			}
+			src := get(d.SourceId)
+			if src == nil && d.SourceId >= 0 {
+				var err error
+				if src, err = readBigmap(ctx, cli, d.SourceId, level-1); err != nil {
+					return nil, err
+				}
+			}
+			dst := make(map[string]micheline.Prim, len(src))
+			for k, v := range src {
+				dst[k] = v
+			}
+			if d.DestId == bigmap {
+				state = dst
+			} else {
+				temp[d.DestId] = dst
+			}
+		case micheline.DiffActionUpdate:
+			if m := get(d.Id); m != nil {
+				m[d.KeyHash.String()] = d.Value
+			}
+		case micheline.DiffActionRemove:
+			m := get(d.Id)
+			switch {
+			case m == nil:
+			case d.Key.OpCode == micheline.I_EMPTY_BIG_MAP && d.Key.Type == micheline.PrimNullary:
+				// removal of the entire bigmap
+				for k := range m {
+					delete(m, k)
+				}
+			default:
+				delete(m, d.KeyHash.String())
+			}
+		}
+	}
+	return state, nil
+}
+
+// readBigmap reads all values of bigmap at level. Missing bigmaps are empty.
+func readBigmap(ctx context.Context, cli *rpc.Client, bigmap, level int64) (map[string]micheline.Prim, error) {
+	state := make(map[string]micheline.Prim)
+	if level <= 0 {
+		return state, nil
+	}
+	hash, err := cli.GetBlockHash(ctx, rpc.BlockLevel(level))
+	if err != nil {
+		return nil, err
+	}
+	keys, err := cli.ListBigmapKeys(ctx, bigmap, hash)
+	if err != nil {
+		if rpc.ErrorStatus(err) == http.StatusNotFound {
+			return state, nil
+		}
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return state, nil
+	}
+	return cli.GetBigmapValues(ctx, bigmap, keys, hash, bigmapReadConcurrency)
+}
+
+// bigmapReadConcurrency is the number of parallel value requests used when
+// reading a full bigmap.
+const bigmapReadConcurrency = 8
+
+type BigmapEventType byte
+
+const (
+	BigmapEventAlloc  BigmapEventType = iota // bigmap was allocated
+	BigmapEventCopy                          // bigmap was created as copy of another bigmap
+	BigmapEventUpdate                        // key was added or changed
+	BigmapEventRemove                        // key or the entire bigmap was removed
+)
+
+func (t BigmapEventType) String() string {
+	switch t {
+	case BigmapEventAlloc:
+		return "alloc"
+	case BigmapEventCopy:
+		return "copy"
+	case BigmapEventUpdate:
+		return "update"
+	case BigmapEventRemove:
+		return "remove"
+	}
+	return ""
+}
+
+// BigmapEvent is a single change to a watched bigmap. Key, KeyHash, Prev and
+// Value are empty for alloc and copy events and for the removal of the entire
+// bigmap. Prev is only known when the watcher maintains a snapshot. Events
+// with Rollback set undo a change from a block that was reorganized out of
+// the chain, they are sent in reverse order.
+type BigmapEvent struct {
+	Type     BigmapEventType
+	Bigmap   int64
+	SourceId int64 // source bigmap on copy
+	Block    tezos.BlockHash
+	Height   int64
+	OpHash   tezos.OpHash
+	Key      micheline.Prim
+	KeyHash  tezos.ExprHash
+	Prev     micheline.Prim
+	Value    micheline.Prim
+	Rollback bool
+}
+
+// WatchBigmapOptions configures WatchBigmapWithOptions.
+type WatchBigmapOptions struct {
+	// Read the bigmap's contents on start and keep a local copy to fill in
+	// previous values of updated and removed keys.
+	Snapshot bool
+	// Max number of blocks rolled back during a reorganization, see
+	// rpc.BlockFollowerOptions.
+	MaxReorgDepth int
+	// Poll for new heads instead of streaming them.
+	Poll bool
+}
+
+var DefaultWatchBigmapOptions = WatchBigmapOptions{
+	MaxReorgDepth: rpc.DefaultBlockFollowerOptions.MaxReorgDepth,
+}
+
+// WatchBigmap streams changes to bigmap from new blocks using
+// DefaultWatchBigmapOptions. See WatchBigmapWithOptions.
+func (c *Contract) WatchBigmap(ctx context.Context, bigmap int64) (<-chan BigmapEvent, error) {
+	return c.WatchBigmapWithOptions(ctx, bigmap, DefaultWatchBigmapOptions)
+}
+
+// WatchBigmapWithOptions delivers alloc, copy, update and remove events for
+// bigmap as blocks arrive, starting after the current head. Changes from
+// blocks that are reorganized out of the chain are retracted with rollback
+// events. The channel is closed when ctx is canceled, a reorganization is
+// deeper than the configured max depth or the snapshot cannot be updated
+// because a copied bigmap cannot be read after several attempts. Connection
+// errors are retried in the background.
+func (c *Contract) WatchBigmapWithOptions(ctx context.Context, bigmap int64, opts WatchBigmapOptions) (<-chan BigmapEvent, error) {
+	if bigmap < 0 {
+		return nil, fmt.Errorf("contract: invalid bigmap id %d", bigmap)
+	}
+	head, err := c.rpc.GetTipHeader(ctx)
+	if err != nil {
+		return nil, err
+	}
+	w := &bigmapWatcher{
+		cli:    c.rpc,
+		bigmap: bigmap,
+		undo:   make(map[string]*bigmapUndo),
+		ch:     make(chan BigmapEvent),
+	}
+	if opts.Snapshot {
+		if w.state, err = readBigmap(ctx, c.rpc, bigmap, head.Level); err != nil {
+			return nil, err
+		}
+	}
+	f, err := rpc.ResumeBlockFollower(c.rpc, rpc.BlockCursor{{Height: head.Level, Hash: head.Hash}}, rpc.BlockFollowerOptions{
+		MaxReorgDepth: opts.MaxReorgDepth,
+		Poll:          opts.Poll,
+	})
+	if err != nil {
+		return nil, err
+	}
+	go w.run(ctx, f)
+	return w.ch, nil
+}
+
+// bigmapWatchRetries is the number of attempts to apply a block before a
+// bigmap watcher gives up.
+const bigmapWatchRetries = 5
+
+// isPermanent returns true for request errors that do not go away on retry.
+func isPermanent(err error) bool {
+	status := rpc.ErrorStatus(err)
+	return status >= 400 && status < 500
+}
+
+// bigmapUndo records the changes a block applied to a watched bigmap. When
+// the block replaced the entire bigmap, prev holds the snapshot before and
+// the first n events are changes applied to prev before the replacement.
+type bigmapUndo struct {
+	events  []BigmapEvent
+	prev    map[string]micheline.Prim
+	n       int
+	replace bool
+}
+
+type bigmapWatcher struct {
+	cli    *rpc.Client
+	bigmap int64
+	state  map[string]micheline.Prim // nil without snapshot
+	undo   map[string]*bigmapUndo    // block hash -> changes
+	ch     chan BigmapEvent
+}
+
+func (w *bigmapWatcher) run(ctx context.Context, f *rpc.BlockFollower) {
+	defer close(w.ch)
+	for ev := range f.Run(ctx) {
+		key := ev.Block.Hash.String()
+		switch ev.Type {
+		case rpc.BlockApply:
+			u, err := w.apply(ctx, ev.Block)
+			for i := 1; err != nil; i++ {
+				// undo partial changes and retry after 5 sec, stop when
+				// the snapshot cannot be updated
+				w.revert(u)
+				if i >= bigmapWatchRetries || isPermanent(err) {
+					return
+				}
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(5 * time.Second):
+				}
+				u, err = w.apply(ctx, ev.Block)
+			}
+			w.undo[key] = u
+			for _, v := range u.events {
+				if !w.send(ctx, v) {
+					return
+				}
+			}
+		case rpc.BlockRollback:
+			u, ok := w.undo[key]
+			if !ok {
+				continue
+			}
+			delete(w.undo, key)
+			w.revert(u)
+			for i := len(u.events) - 1; i >= 0; i-- {
+				v := u.events[i]
+				v.Rollback = true
+				if !w.send(ctx, v) {
+					return
+				}
+			}
+		}
+		// forget blocks that can no longer be rolled back
+		if len(w.undo) > len(ev.Cursor) {
+			keep := make(map[string]*bigmapUndo, len(ev.Cursor))
+			for _, ref := range ev.Cursor {
+				if u, ok := w.undo[ref.Hash.String()]; ok {
+					keep[ref.Hash.String()] = u
+				}
+			}
+			w.undo = keep
+		}
+	}
+}
+
+func (w *bigmapWatcher) send(ctx context.Context, ev BigmapEvent) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case w.ch <- ev:
+		return true
+	}
+}
+
+// apply translates a block's bigmap diffs into events and updates the
+// snapshot. Temporary bigmaps are tracked to resolve copies into the watched
+// bigmap.
+func (w *bigmapWatcher) apply(ctx context.Context, block *rpc.Block) (*bigmapUndo, error) {
+	u := &bigmapUndo{
+		events: make([]BigmapEvent, 0),
+	}
+	level := block.GetLevel()
+	blk := newBlockBigmaps(w.cli, level)
+	for _, list := range block.Operations {
+		for _, op := range list {
+			for _, c := range op.Contents {
+				diffs := make([]micheline.BigmapDiff, 0, 1)
+				if res := c.Result(); res.Status.IsSuccess() {
+					diffs = append(diffs, res.BigmapUpdates())
+				}
+				for _, in := range c.Meta().InternalResults {
+					if in.Result.Status.IsSuccess() {
+						diffs = append(diffs, in.Result.BigmapUpdates())
+					}
+				}
+				for _, diff := range diffs {
+					for _, d := range diff {
+						if err := w.applyElem(ctx, u, blk, block, level, op.Hash, d); err != nil {
+							return u, err
+						}
+					}
+				}
+			}
+		}
+	}
+	return u, nil
+}
+
+func (w *bigmapWatcher) applyElem(ctx context.Context, u *bigmapUndo, blk *blockBigmaps, block *rpc.Block, level int64, oh tezos.OpHash, d micheline.BigmapDiffElem) error {
+	ev := BigmapEvent{
+		Bigmap: w.bigmap,
+		Block:  block.Hash.Clone(),
+		Height: level,
+		OpHash: oh.Clone(),
+	}
+	switch d.Action {
+	case micheline.DiffActionAlloc:
+		if d.Id != w.bigmap {
+			if w.state != nil {
+				blk.alloc(d.Id)
+			}
+			return nil
+		}
+		ev.Type = BigmapEventAlloc
+		w.replace(u, make(map[string]micheline.Prim))
+	case micheline.DiffActionCopy:
+		if d.DestId != w.bigmap {
+			if w.state != nil {
+				src, err := w.source(ctx, blk, d.SourceId)
+				if err != nil {
+					return err
+				}
+				blk.set(d.DestId, src)
+			}
+			return nil
+		}
+		ev.Type = BigmapEventCopy
+		ev.SourceId = d.SourceId
+		if w.state != nil {
+			src, err := w.source(ctx, blk, d.SourceId)
+			if err != nil {
+				return err
+			}
+			w.replace(u, src)
+		}
+	case micheline.DiffActionUpdate, micheline.DiffActionRemove:
+		if d.Id != w.bigmap {
+			if w.state != nil {
+				blk.update(d)
+			}
+			return nil
+		}
+		if d.Action == micheline.DiffActionRemove && d.Key.OpCode == micheline.I_EMPTY_BIG_MAP && d.Key.Type == micheline.PrimNullary {
+			// removal of the entire bigmap
+			ev.Type = BigmapEventRemove
+			w.replace(u, make(map[string]micheline.Prim))
+			break
+		}
+		ev.Key = d.Key
+		ev.KeyHash = d.KeyHash.Clone()
+		key := d.KeyHash.String()
+		if w.state != nil {
+			ev.Prev = w.state[key]
+		}
+		if d.Action == micheline.DiffActionUpdate {
+			ev.Type = BigmapEventUpdate
+			ev.Value = d.Value
+			if w.state != nil {
+				w.state[key] = d.Value
+			}
+		} else {
+			ev.Type = BigmapEventRemove
+			if w.state != nil {
+				delete(w.state, key)
+			}
+		}
+	default:
+		return nil
+	}
+	u.events = append(u.events, ev)
+	return nil
+}
+
+// replace swaps the snapshot and remembers the state before the first
+// replacement in a block for rollbacks.
+func (w *bigmapWatcher) replace(u *bigmapUndo, state map[string]micheline.Prim) {
+	if w.state == nil {
+		return
+	}
+	if !u.replace {
+		u.replace = true
+		u.prev = w.state
+		u.n = len(u.events)
+	}
+	w.state = state
+}
+
+// source returns a copy of the current contents of bigmap id.
+func (w *bigmapWatcher) source(ctx context.Context, blk *blockBigmaps, id int64) (map[string]micheline.Prim, error) {
+	if id == w.bigmap {
+		return cloneBigmap(w.state), nil
+	}
+	return blk.get(ctx, id)
+}
+
+// revert restores the snapshot to the state before a block was applied.
+func (w *bigmapWatcher) revert(u *bigmapUndo) {
+	if w.state == nil {
+		return
+	}
+	events := u.events
+	if u.replace {
+		// changes before the replacement were applied to prev
+		w.state = u.prev
+		events = events[:u.n]
+	}
+	for i := len(events) - 1; i >= 0; i-- {
+		ev := events[i]
+		key := ev.KeyHash.String()
+		if ev.Prev.IsValid() {
+			w.state[key] = ev.Prev
+		} else {
+			delete(w.state, key)
+		}
+	}
+}
+
+// blockBigmaps tracks the contents of bigmaps other than the watched bigmap
+// during a single block, so that copies see changes made earlier in the same
+// block. Temporary bigmaps and bigmaps allocated or copied in the block are
+// kept in full. Changes to other stored bigmaps are kept until their contents
+// are read at the preceding block.
+type blockBigmaps struct {
+	cli     *rpc.Client
+	level   int64
+	maps    map[int64]map[string]micheline.Prim
+	pending map[int64]micheline.BigmapDiff
+}
+
+func newBlockBigmaps(cli *rpc.Client, level int64) *blockBigmaps {
+	return &blockBigmaps{
+		cli:     cli,
+		level:   level,
+		maps:    make(map[int64]map[string]micheline.Prim),
+		pending: make(map[int64]micheline.BigmapDiff),
+	}
+}
+
+func (b *blockBigmaps) alloc(id int64) {
+	b.set(id, make(map[string]micheline.Prim))
+}
+
+func (b *blockBigmaps) set(id int64, m map[string]micheline.Prim) {
+	b.maps[id] = m
+	delete(b.pending, id)
+}
+
+func (b *blockBigmaps) update(d micheline.BigmapDiffElem) {
+	if m, ok := b.maps[d.Id]; ok {
+		applyBigmapElem(m, d)
+		return
+	}
+	if d.Id >= 0 {
+		b.pending[d.Id] = append(b.pending[d.Id], d)
+	}
+}
+
+// get returns a copy of the current contents of bigmap id.
+func (b *blockBigmaps) get(ctx context.Context, id int64) (map[string]micheline.Prim, error) {
+	m, ok := b.maps[id]
+	if !ok && id >= 0 {
+		var err error
+		if m, err = readBigmap(ctx, b.cli, id, b.level-1); err != nil {
+			return nil, err
+		}
+		for _, d := range b.pending[id] {
+			applyBigmapElem(m, d)
+		}
+		b.set(id, m)
+	}
+	return cloneBigmap(m), nil
+}
+
+// applyBigmapElem applies an update or remove diff to m.
+func applyBigmapElem(m map[string]micheline.Prim, d micheline.BigmapDiffElem) {
+	switch {
+	case d.Action == micheline.DiffActionUpdate:
+		m[d.KeyHash.String()] = d.Value
+	case d.Action != micheline.DiffActionRemove:
+	case d.Key.OpCode == micheline.I_EMPTY_BIG_MAP && d.Key.Type == micheline.PrimNullary:
+		// removal of the entire bigmap
+		for k := range m {
+			delete(m, k)
+		}
+	default:
+		delete(m, d.KeyHash.String())
+	}
+}
+
+func cloneBigmap(src map[string]micheline.Prim) map[string]micheline.Prim {
+	dst := make(map[string]micheline.Prim, len(src))
+	for k, v := range src {
+		dst[k] = v
+	}
+	return dst
+}
@@ -0,0 +1,191 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package contract
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"testing"
+
+	"blockwatch.cc/tzgo/micheline"
+	"blockwatch.cc/tzgo/rpc"
+	"blockwatch.cc/tzgo/tezos"
+)
+
+func testKeyHash(key byte) tezos.ExprHash {
+	buf := make([]byte, 32)
+	buf[0] = key
+	return tezos.NewExprHash(buf)
+}
+
+func testUpdate(id int64, key byte, val int) string {
+	return fmt.Sprintf(`{"action":"update","big_map":"%d","key_hash":%q,"key":{"int":"%d"},"value":{"int":"%d"}}`, id, testKeyHash(key), key, val)
+}
+
+func testRemove(id int64, key byte) string {
+	return fmt.Sprintf(`{"action":"remove","big_map":"%d","key_hash":%q,"key":{"int":"%d"}}`, id, testKeyHash(key), key)
+}
+
+func testAlloc(id int64) string {
+	return fmt.Sprintf(`{"action":"alloc","big_map":"%d","key_type":{"prim":"nat"},"value_type":{"prim":"nat"}}`, id)
+}
+
+func testCopy(src, dst int64) string {
+	return fmt.Sprintf(`{"action":"copy","source_big_map":"%d","destination_big_map":"%d"}`, src, dst)
+}
+
+// testDiffBlock returns a block at level with a single transaction that
+// applied diffs in order.
+func testDiffBlock(t *testing.T, level int64, diffs ...string) *rpc.Block {
+	t.Helper()
+	op := fmt.Sprintf(`{"hash":%q,"contents":[{"kind":"transaction","source":%q,"destination":%q,"amount":"0","fee":"0","counter":"1","gas_limit":"0","storage_limit":"0","metadata":{"operation_result":{"status":"applied","big_map_diff":[%s]}}}]}`,
+		tezos.NewOpHash(testHash(level)), testSender, testContract, strings.Join(diffs, ","))
+	buf := fmt.Sprintf(`{"hash":%q,"header":{"level":%d,"predecessor":%q},"operations":[[],[],[],[%s]]}`,
+		testBlockHash(level), level, testBlockHash(level-1), op)
+	var b rpc.Block
+	if err := json.Unmarshal([]byte(buf), &b); err != nil {
+		t.Fatal(err)
+	}
+	return &b
+}
+
+// testState returns a snapshot with values for keys.
+func testState(kv ...int) map[string]micheline.Prim {
+	m := make(map[string]micheline.Prim)
+	for i := 0; i < len(kv); i += 2 {
+		m[testKeyHash(byte(kv[i])).String()] = micheline.NewInt64(int64(kv[i+1]))
+	}
+	return m
+}
+
+// dumpState formats a snapshot as sorted key=value list.
+func dumpState(m map[string]micheline.Prim) string {
+	list := make([]string, 0, len(m))
+	for k, v := range m {
+		h, _ := tezos.ParseExprHash(k)
+		list = append(list, fmt.Sprintf("%d=%s", h.Hash.Hash[0], v.Int))
+	}
+	sort.Strings(list)
+	return strings.Join(list, " ")
+}
+
+func newTestBigmapWatcher(t *testing.T, m *rpc.Mock, bigmap int64, state map[string]micheline.Prim) *bigmapWatcher {
+	t.Helper()
+	cli, err := m.Client()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &bigmapWatcher{
+		cli:    cli,
+		bigmap: bigmap,
+		state:  state,
+		undo:   make(map[string]*bigmapUndo),
+	}
+}
+
+func TestBigmapWatcherSnapshot(t *testing.T) {
+	w := newTestBigmapWatcher(t, rpc.NewMock(), 5, testState(1, 10, 2, 20))
+	ctx := context.Background()
+	block := testDiffBlock(t, 10,
+		testUpdate(5, 1, 11),
+		testRemove(5, 2),
+		testUpdate(5, 3, 30),
+		testUpdate(6, 4, 40), // other bigmap
+	)
+	u, err := w.apply(ctx, block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var events []string
+	for _, ev := range u.events {
+		events = append(events, fmt.Sprintf("%s %s %s->%s", ev.Type, ev.Key.Int, ev.Prev.Int, ev.Value.Int))
+	}
+	want := "[update 1 10->11 remove 2 20-><nil> update 3 <nil>->30]"
+	if have := fmt.Sprint(events); have != want {
+		t.Errorf("events:\n have %s\n want %s", have, want)
+	}
+	if have, want := dumpState(w.state), "1=11 3=30"; have != want {
+		t.Errorf("state have %q want %q", have, want)
+	}
+	w.revert(u)
+	if have, want := dumpState(w.state), "1=10 2=20"; have != want {
+		t.Errorf("reverted state have %q want %q", have, want)
+	}
+}
+
+func TestBigmapWatcherRollbackReplace(t *testing.T) {
+	w := newTestBigmapWatcher(t, rpc.NewMock(), 5, testState(1, 10))
+	ctx := context.Background()
+	// the bigmap is changed and then replaced by a copy of a temporary
+	// bigmap within the same block
+	block := testDiffBlock(t, 10,
+		testUpdate(5, 2, 20),
+		testAlloc(-1),
+		testUpdate(-1, 3, 30),
+		testCopy(-1, 5),
+		testUpdate(5, 4, 40),
+	)
+	u, err := w.apply(ctx, block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := dumpState(w.state), "3=30 4=40"; have != want {
+		t.Errorf("state have %q want %q", have, want)
+	}
+	w.revert(u)
+	if have, want := dumpState(w.state), "1=10"; have != want {
+		t.Errorf("reverted state have %q want %q", have, want)
+	}
+}
+
+func TestBigmapWatcherCopySameBlock(t *testing.T) {
+	m := rpc.NewMock()
+	// bigmap 7 holds key 1 at the preceding block
+	m.On(http.MethodGet, "chains/main/blocks/9/hash", testBlockHash(9))
+	m.On(http.MethodGet, "chains/main/blocks/"+testBlockHash(9).String()+"/context/raw/json/big_maps/index/7/contents", []tezos.ExprHash{testKeyHash(1)})
+	m.On(http.MethodGet, fmt.Sprintf("chains/main/blocks/%s/context/big_maps/7/%s", testBlockHash(9), testKeyHash(1)), micheline.NewInt64(10))
+	w := newTestBigmapWatcher(t, m, 5, testState())
+	block := testDiffBlock(t, 10,
+		testUpdate(7, 2, 20),
+		testRemove(7, 1),
+		testUpdate(7, 3, 30),
+		testCopy(7, -1),
+		testUpdate(7, 4, 40), // after the copy
+		testCopy(-1, 5),
+	)
+	u, err := w.apply(context.Background(), block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := dumpState(w.state), "2=20 3=30"; have != want {
+		t.Errorf("state have %q want %q", have, want)
+	}
+	if n := len(u.events); n != 1 || u.events[0].Type != BigmapEventCopy || u.events[0].SourceId != -1 {
+		t.Errorf("unexpected events %+v", u.events)
+	}
+}
+
+func TestBigmapWatcherPermanentError(t *testing.T) {
+	m := rpc.NewMock()
+	m.On(http.MethodGet, "chains/main/blocks/9/hash", testBlockHash(9))
+	m.On(http.MethodGet, "chains/main/blocks/"+testBlockHash(9).String()+"/context/raw/json/big_maps/index/7/contents", nil).WithStatus(http.StatusBadRequest)
+	w := newTestBigmapWatcher(t, m, 5, testState(1, 10))
+	block := testDiffBlock(t, 10,
+		testUpdate(5, 2, 20),
+		testCopy(7, 5),
+	)
+	u, err := w.apply(context.Background(), block)
+	if err == nil || !isPermanent(err) {
+		t.Fatalf("expected permanent error, got %v", err)
+	}
+	// partial changes are undone
+	w.revert(u)
+	if have, want := dumpState(w.state), "1=10"; have != want {
+		t.Errorf("reverted state have %q want %q", have, want)
+	}
+}
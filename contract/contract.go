@@ -10,7 +10,6 @@ import (
 	"blockwatch.cc/tzgo/codec"
 	"blockwatch.cc/tzgo/micheline"
 	"blockwatch.cc/tzgo/rpc"
-	"blockwatch.cc/tzgo/signer"
 	"blockwatch.cc/tzgo/tezos"
 )
 
@@ -40,20 +39,10 @@ func (a *TxArgs) WithAmount(amount tezos.N) {
 	a.Amount = amount
 }
 
-type CallOptions struct {
-	Confirmations int64         // number of confirmations to wait after broadcast
-	TTL           int64         // max number of blocks to wait in total
-	Limits        tezos.Limits  // optional gas, storage and fee limits to override estimations
-	MaxFee        int64         // max acceptable fee, optional (default = 0)
-	Signer        signer.Signer // optional signer interface to use for signing the transaction
-	Observer      *rpc.Observer // optional custom block observer for waiting on confirmations
-}
+// CallOptions control how contract calls and deployments are sent.
+type CallOptions = rpc.CallOptions
 
-var DefaultOptions = CallOptions{
-	Confirmations: 6,
-	TTL:           120,
-	MaxFee:        1000000,
-}
+var DefaultOptions = rpc.DefaultOptions
 
 type Contract struct {
 	addr   tezos.Address     // contract address
@@ -85,11 +74,6 @@ func (c *Contract) Resolve(ctx context.Context) error {
 	return nil
 }
 
-func (c *Contract) ResolveMetadata(ctx context.Context) (*Tz16, error) {
-	// TODO
-	return nil, nil
-}
-
 func (c *Contract) WithScript(script *micheline.Script) *Contract {
 	c.script = script
 	return c
@@ -131,11 +115,11 @@ func (c Contract) IsFA1() bool {
 }
 
 func (c Contract) IsFA12() bool {
-	return c.script != nil && c.script.Implements(micheline.ITzip7)
+	return c.script != nil && c.script.IsFA12()
 }
 
 func (c Contract) IsFA2() bool {
-	return c.script != nil && c.script.Implements(micheline.ITzip12)
+	return c.script != nil && c.script.IsFA2()
 }
 
 // func (c *Contract) IsNFT() bool {}
@@ -190,6 +174,40 @@ func (c *Contract) View(name string) (micheline.View, bool) {
 	return view, ok
 }
 
+// Executes an on-chain view declared in the contract's script and returns the
+// result typed with the view's return type. On-chain views live in their own
+// namespace, so a view may share its name with an entrypoint.
+func (c *Contract) RunScriptView(ctx context.Context, name string, args micheline.Prim) (micheline.Value, error) {
+	if c.script == nil {
+		if err := c.Resolve(ctx); err != nil {
+			return micheline.Value{}, err
+		}
+	}
+	views, err := c.script.Views(false, false)
+	if err != nil {
+		return micheline.Value{}, err
+	}
+	view, ok := views[name]
+	if !ok {
+		return micheline.Value{}, fmt.Errorf("contract: %s has no view %q", c.addr, name)
+	}
+	if !args.IsValid() && view.Param.OpCode == micheline.T_UNIT {
+		args = micheline.NewPrim(micheline.D_UNIT)
+	}
+	req := rpc.RunScriptViewRequest{
+		Contract: c.addr,
+		View:     name,
+		Input:    args,
+		ChainId:  c.rpc.ChainId,
+		Mode:     rpc.UnparsingModeReadable,
+	}
+	var res rpc.RunViewResponse
+	if err := c.rpc.RunScriptView(ctx, rpc.Head, &req, &res); err != nil {
+		return micheline.Value{}, err
+	}
+	return micheline.NewValue(view.Retval, res.Data), nil
+}
+
 // func (c *Contract) GetStorageValue(path string) (*micheline.Value, error) {}
 
 // func (c *Contract) GetBigmapValue(path string, key micheline.Key) (*micheline.Value, error) {}
@@ -273,70 +291,5 @@ func (c *Contract) DeployExt(ctx context.Context, delegate tezos.Address, balanc
 }
 
 func (c *Contract) signAndBroadcast(ctx context.Context, op *codec.Op, opts *CallOptions) (*rpc.Receipt, error) {
-	signer := c.rpc.Signer
-	if opts.Signer != nil {
-		signer = opts.Signer
-	}
-
-	key, err := opts.Signer.Key(ctx)
-	if err != nil {
-		return nil, err
-	}
-
-	// set source on all ops
-	op.WithSource(key.Address())
-
-	// auto-complete op with branch/ttl, source counter, reveal
-	err = c.rpc.Complete(ctx, op, key)
-	if err != nil {
-		return nil, err
-	}
-
-	// simulate to check tx validity and estimate cost
-	sim, err := c.rpc.Simulate(ctx, op)
-	if err != nil {
-		return nil, err
-	}
-
-	// apply simulated cost as limits to tx list
-	op.WithLimits(sim.MapLimits(), rpc.GasSafetyMargin)
-
-	// check minFee calc against maxFee if set
-	if opts.MaxFee > 0 {
-		if l := op.Limits(); l.Fee > opts.MaxFee {
-			return nil, fmt.Errorf("estimated cost %d > max %d", l.Fee, opts.MaxFee)
-		}
-	}
-
-	// sign digest
-	sig, err := signer.SignOperation(ctx, op)
-	if err != nil {
-		return nil, err
-	}
-	op.WithSignature(sig)
-
-	// broadcast
-	hash, err := c.rpc.Broadcast(ctx, op)
-	if err != nil {
-		return nil, err
-	}
-
-	// wait for confirmations
-	res := rpc.NewResult(hash).WithTTL(opts.TTL).WithConfirmations(opts.Confirmations)
-
-	// use custom observer when provided
-	mon := c.rpc.BlockObserver
-	if opts.Observer != nil {
-		mon = opts.Observer
-	}
-
-	// wait for confirmations
-	res.Listen(mon)
-	res.WaitContext(ctx)
-	if err := res.Err(); err != nil {
-		return nil, err
-	}
-
-	// return receipt
-	return res.GetReceipt(ctx)
+	return c.rpc.Send(ctx, op, opts)
 }
@@ -0,0 +1,169 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package contract
+
+import (
+	"context"
+	"time"
+
+	"blockwatch.cc/tzgo/micheline"
+	"blockwatch.cc/tzgo/rpc"
+	"blockwatch.cc/tzgo/tezos"
+)
+
+// Event is a contract event emitted by the EMIT instruction. The payload is
+// decoded against the type declared by the emitting contract.
+type Event struct {
+	Block   tezos.BlockHash // block where the event was emitted, may be empty
+	OpHash  tezos.OpHash    // hash of the operation that emitted the event
+	Source  tezos.Address   // emitting contract
+	Tag     string          // event tag, empty for the default tag
+	Payload micheline.Value // typed event payload
+}
+
+// Events returns all events emitted during execution of the receipt's operation.
+// When tags are non-empty only events with a matching tag are returned.
+func Events(rec *rpc.Receipt, tags ...string) []Event {
+	if rec == nil || rec.Op == nil {
+		return nil
+	}
+	events := OperationEvents(rec.Op, tags...)
+	for i := range events {
+		events[i].Block = rec.Block.Clone()
+	}
+	return events
+}
+
+// OperationEvents returns all events emitted by an operation, optionally
+// filtered by tags.
+func OperationEvents(op *rpc.Operation, tags ...string) []Event {
+	if op == nil {
+		return nil
+	}
+	events := make([]Event, 0)
+	for _, c := range op.Contents {
+		for _, ev := range c.Meta().Events() {
+			if !matchTag(ev.Tag, tags) {
+				continue
+			}
+			events = append(events, newEvent(op.Hash, ev))
+		}
+	}
+	return events
+}
+
+// BlockEvents returns all events emitted by contract addr in block, optionally
+// filtered by tags.
+func BlockEvents(block *rpc.Block, addr tezos.Address, tags ...string) []Event {
+	if block == nil {
+		return nil
+	}
+	events := make([]Event, 0)
+	for _, list := range block.Operations {
+		for _, op := range list {
+			for _, ev := range OperationEvents(op, tags...) {
+				if !ev.Source.Equal(addr) {
+					continue
+				}
+				ev.Block = block.Hash.Clone()
+				events = append(events, ev)
+			}
+		}
+	}
+	return events
+}
+
+// Events returns all events emitted by this contract in the receipt's operation.
+func (c *Contract) Events(rec *rpc.Receipt, tags ...string) []Event {
+	events := make([]Event, 0)
+	for _, ev := range Events(rec, tags...) {
+		if ev.Source.Equal(c.addr) {
+			events = append(events, ev)
+		}
+	}
+	return events
+}
+
+// WatchEvents follows the chain from the current head and delivers events
+// emitted by this contract on the returned channel. Blocks are processed in
+// order without gaps and each block is processed once, also when a head is
+// announced again. The channel is closed when ctx is canceled or the
+// underlying block follower stops. Use FollowEvents to control the follower
+// and inspect its error. Events from blocks that are later reorganized out of
+// the chain are not retracted.
+func (c *Contract) WatchEvents(ctx context.Context, tags ...string) <-chan Event {
+	ch := make(chan Event)
+	go func() {
+		defer close(ch)
+		var start int64
+		for {
+			head, err := c.rpc.GetTipHeader(ctx)
+			if err == nil {
+				start = head.Level
+				break
+			}
+			// wait 5 sec, but also return on close
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(5 * time.Second):
+			}
+		}
+		f := rpc.NewBlockFollower(c.rpc, start, rpc.DefaultBlockFollowerOptions)
+		c.sendEvents(ctx, f.Run(ctx), ch, tags)
+	}()
+	return ch
+}
+
+// FollowEvents delivers events emitted by this contract in blocks applied by
+// follower f. The channel is closed when ctx is canceled or f stops, see
+// f.Err. Rollback events are ignored, i.e. events from blocks that are
+// reorganized out of the chain are not retracted.
+func (c *Contract) FollowEvents(ctx context.Context, f *rpc.BlockFollower, tags ...string) <-chan Event {
+	ch := make(chan Event)
+	go func() {
+		defer close(ch)
+		c.sendEvents(ctx, f.Run(ctx), ch, tags)
+	}()
+	return ch
+}
+
+func (c *Contract) sendEvents(ctx context.Context, blocks <-chan rpc.BlockEvent, ch chan<- Event, tags []string) {
+	for b := range blocks {
+		if b.Type != rpc.BlockApply {
+			continue
+		}
+		for _, ev := range BlockEvents(b.Block, c.addr, tags...) {
+			select {
+			case <-ctx.Done():
+				return
+			case ch <- ev:
+			}
+		}
+	}
+}
+
+func newEvent(oh tezos.OpHash, in rpc.ContractEvent) Event {
+	ev := Event{
+		OpHash: oh.Clone(),
+		Source: in.Source.Clone(),
+		Tag:    in.Tag,
+	}
+	if in.Type.IsValid() && in.Payload.IsValid() {
+		ev.Payload = micheline.NewValue(micheline.NewType(in.Type), in.Payload)
+	}
+	return ev
+}
+
+func matchTag(tag string, tags []string) bool {
+	if len(tags) == 0 {
+		return true
+	}
+	for _, v := range tags {
+		if v == tag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,131 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package contract
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"blockwatch.cc/tzgo/rpc"
+	"blockwatch.cc/tzgo/tezos"
+)
+
+var (
+	testContract = tezos.MustParseAddress("KT1GyeRktoGPEKsWpchWguyy8FAf3aNHkw2T")
+	testSender   = tezos.MustParseAddress("tz1S5WxdZR5f9NzsPXhr7L9L1vrEb5spZFur")
+)
+
+func testHash(level int64) []byte {
+	buf := make([]byte, 32)
+	buf[1], buf[2] = byte(level>>8), byte(level)
+	return buf
+}
+
+func testBlockHash(level int64) tezos.BlockHash {
+	return tezos.NewBlockHash(testHash(level))
+}
+
+// testEventChain registers canonical blocks from..to by hash and level. Each
+// block emits one event with tag "level" and the block level as payload and
+// one event with tag "other".
+func testEventChain(m *rpc.Mock, from, to int64) {
+	for level := from; level <= to; level++ {
+		event := func(tag string, nonce int) string {
+			return fmt.Sprintf(`{"kind":"event","source":%q,"nonce":%d,"type":{"prim":"nat"},"tag":%q,"payload":{"int":"%d"},"result":{"status":"applied"}}`,
+				testContract, nonce, tag, level)
+		}
+		op := fmt.Sprintf(`{"hash":%q,"contents":[{"kind":"transaction","source":%q,"destination":%q,"amount":"0","fee":"0","counter":"1","gas_limit":"0","storage_limit":"0","metadata":{"operation_result":{"status":"applied"},"internal_operation_results":[%s,%s]}}]}`,
+			tezos.NewOpHash(testHash(level)), testSender, testContract, event("level", 0), event("other", 1))
+		block := fmt.Sprintf(`{"hash":%q,"header":{"level":%d,"predecessor":%q},"operations":[[],[],[],[%s]]}`,
+			testBlockHash(level), level, testBlockHash(level-1), op)
+		m.On(http.MethodGet, "chains/main/blocks/"+testBlockHash(level).String(), []byte(block))
+		m.On(http.MethodGet, fmt.Sprintf("chains/main/blocks/%d", level), []byte(block))
+	}
+}
+
+func testHead(level int64) map[string]interface{} {
+	return map[string]interface{}{"hash": testBlockHash(level), "level": level}
+}
+
+func TestWatchEvents(t *testing.T) {
+	m := rpc.NewMock()
+	testEventChain(m, 9, 13)
+	m.On(http.MethodGet, "chains/main/blocks/head/header", testHead(10))
+	// the head at 10 is announced twice and the monitor skips 11 and 12, the
+	// stream is replayed after each reconnect
+	m.On(http.MethodGet, "monitor/heads/main", nil).
+		WithStream(testHead(10), testHead(10), testHead(13)).
+		WithDelay(5 * time.Millisecond)
+	cli, err := m.Client()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ch := NewContract(testContract, cli).WatchEvents(ctx, "level")
+	var levels []string
+	timeout := time.After(5 * time.Second)
+	for len(levels) < 4 {
+		select {
+		case ev, ok := <-ch:
+			if !ok {
+				t.Fatalf("channel closed after %v", levels)
+			}
+			if ev.Tag != "level" || !ev.Source.Equal(testContract) {
+				t.Errorf("unexpected event %+v", ev)
+			}
+			levels = append(levels, fmt.Sprintf("%s@%s", ev.Payload.Value.Int, ev.Block))
+		case <-timeout:
+			t.Fatalf("timeout after %v", levels)
+		}
+	}
+	for i, level := range []int64{10, 11, 12, 13} {
+		if want := fmt.Sprintf("%d@%s", level, testBlockHash(level)); levels[i] != want {
+			t.Errorf("event %d: have=%s want=%s", i, levels[i], want)
+		}
+	}
+
+	// replayed heads deliver no duplicates
+	select {
+	case ev := <-ch:
+		t.Errorf("duplicate event %s@%s", ev.Payload.Value.Int, ev.Block)
+	case <-time.After(100 * time.Millisecond):
+	}
+	cancel()
+	for range ch {
+	}
+}
+
+func TestFollowEvents(t *testing.T) {
+	m := rpc.NewMock()
+	testEventChain(m, 1, 3)
+	m.On(http.MethodGet, "monitor/heads/main", nil).WithStream(testHead(3))
+	cli, err := m.Client()
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := rpc.NewBlockFollower(cli, 1, rpc.BlockFollowerOptions{MaxReorgDepth: 1})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// without tags all events are delivered
+	tags := make([]string, 0)
+	for ev := range NewContract(testContract, cli).FollowEvents(ctx, f) {
+		tags = append(tags, fmt.Sprintf("%s@%s", ev.Tag, ev.Payload.Value.Int))
+		if len(tags) == 6 {
+			cancel()
+		}
+	}
+	want := "[level@1 other@1 level@2 other@2 level@3 other@3]"
+	if have := fmt.Sprint(tags); have != want {
+		t.Errorf("have %s want %s", have, want)
+	}
+	if err := f.Err(); err != nil {
+		t.Errorf("unexpected follower error %v", err)
+	}
+}
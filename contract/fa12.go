@@ -18,6 +18,7 @@ import (
 type FA1Token struct {
 	Address  tezos.Address
 	contract *Contract
+	meta     *TokenMetadata
 }
 
 func NewFA1Token(addr tezos.Address, cli *rpc.Client) *FA1Token {
@@ -32,7 +33,17 @@ func (t FA1Token) Equal(v FA1Token) bool {
 	return t.Address.Equal(v.Address)
 }
 
+// WithMetadata sets the token's TZIP-12 metadata, e.g. loaded from an indexer,
+// which enables scaled balances.
+func (t *FA1Token) WithMetadata(meta TokenMetadata) *FA1Token {
+	t.meta = &meta
+	return t
+}
+
 func (t FA1Token) GetMetadata(ctx context.Context) (TokenMetadata, error) {
+	if t.meta != nil {
+		return *t.meta, nil
+	}
 	// TODO
 	return TokenMetadata{}, nil
 }
@@ -46,6 +57,19 @@ func (t FA1Token) GetBalance(ctx context.Context, owner tezos.Address) (tezos.Z,
 	return balance, err
 }
 
+// GetBalanceAmount returns the balance of owner scaled by the token's decimals.
+// It fails with ErrNoTokenMetadata when metadata was not set.
+func (t FA1Token) GetBalanceAmount(ctx context.Context, owner tezos.Address) (tezos.TokenAmount, error) {
+	if t.meta == nil {
+		return tezos.TokenAmount{}, ErrNoTokenMetadata
+	}
+	balance, err := t.GetBalance(ctx, owner)
+	if err != nil {
+		return tezos.TokenAmount{}, err
+	}
+	return t.meta.Amount(balance), nil
+}
+
 func (t FA1Token) GetTotalSupply(ctx context.Context) (tezos.Z, error) {
 	var supply tezos.Z
 	prim, err := t.contract.RunView(ctx, "getTotalSupply", micheline.NewPrim(micheline.D_UNIT))
@@ -21,6 +21,7 @@ type FA2Token struct {
 	Address  tezos.Address
 	TokenId  tezos.Z
 	contract *Contract
+	meta     *TokenMetadata
 }
 
 func NewFA2Token(addr tezos.Address, id int64, cli *rpc.Client) *FA2Token {
@@ -37,7 +38,17 @@ func (t FA2Token) Equal(v FA2Token) bool {
 	return t.Address.Equal(v.Address) && t.TokenId.Equal(v.TokenId)
 }
 
+// WithMetadata sets the token's TZIP-12 metadata, e.g. loaded from an indexer,
+// which enables scaled balances.
+func (t *FA2Token) WithMetadata(meta TokenMetadata) *FA2Token {
+	t.meta = &meta
+	return t
+}
+
 func (t FA2Token) GetMetadata(ctx context.Context) (TokenMetadata, error) {
+	if t.meta != nil {
+		return *t.meta, nil
+	}
 	// TODO
 	return TokenMetadata{}, nil
 }
@@ -73,6 +84,22 @@ func (t FA2Token) GetBalances(ctx context.Context, req []FA2BalanceRequest) ([]F
 	return resp, err
 }
 
+// GetBalanceAmount returns the balance of owner for this token scaled by the
+// token's decimals. It fails with ErrNoTokenMetadata when metadata was not set.
+func (t FA2Token) GetBalanceAmount(ctx context.Context, owner tezos.Address) (tezos.TokenAmount, error) {
+	if t.meta == nil {
+		return tezos.TokenAmount{}, ErrNoTokenMetadata
+	}
+	resp, err := t.GetBalances(ctx, []FA2BalanceRequest{{Owner: owner, TokenId: t.TokenId}})
+	if err != nil {
+		return tezos.TokenAmount{}, err
+	}
+	if len(resp) != 1 {
+		return tezos.TokenAmount{}, fmt.Errorf("contract: unexpected balance_of result count %d", len(resp))
+	}
+	return t.meta.Amount(resp[0].Balance), nil
+}
+
 type FA2Approval struct {
 	Owner    tezos.Address `json:"owner"`
 	Operator tezos.Address `json:"operator"`
@@ -0,0 +1,83 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package contract
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// IPFSResolver fetches content referenced by an ipfs://<cid>[/<path>] URI.
+type IPFSResolver interface {
+	Resolve(ctx context.Context, uri string) ([]byte, error)
+}
+
+// DefaultIPFSResolver is used to resolve ipfs:// metadata URIs.
+var DefaultIPFSResolver IPFSResolver = NewIPFSGateway("https://ipfs.io/ipfs/")
+
+// IPFSGateway resolves IPFS content through a public or private HTTP gateway.
+type IPFSGateway struct {
+	URL     string        // gateway base URL, e.g. https://ipfs.io/ipfs/
+	Timeout time.Duration // per request timeout, zero means no timeout
+	Client  *http.Client  // defaults to MetadataClient
+}
+
+// NewIPFSGateway returns a resolver that uses the HTTP gateway at url.
+func NewIPFSGateway(url string) *IPFSGateway {
+	return &IPFSGateway{
+		URL:     url,
+		Timeout: 30 * time.Second,
+	}
+}
+
+// WithTimeout sets the per request timeout.
+func (g *IPFSGateway) WithTimeout(d time.Duration) *IPFSGateway {
+	g.Timeout = d
+	return g
+}
+
+// WithClient sets the HTTP client used for gateway requests.
+func (g *IPFSGateway) WithClient(c *http.Client) *IPFSGateway {
+	g.Client = c
+	return g
+}
+
+// Resolve fetches the content of an ipfs:// URI from the gateway.
+func (g *IPFSGateway) Resolve(ctx context.Context, uri string) ([]byte, error) {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "ipfs" || u.Host == "" {
+		return nil, fmt.Errorf("contract: invalid ipfs uri %q", uri)
+	}
+	if g.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, g.Timeout)
+		defer cancel()
+	}
+	cli := g.Client
+	if cli == nil {
+		cli = MetadataClient
+	}
+	return fetchHTTP(ctx, cli, strings.TrimSuffix(g.URL, "/")+"/"+u.Host+u.EscapedPath())
+}
+
+func fetchHTTP(ctx context.Context, cli *http.Client, uri string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := cli.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("contract: fetching %s: %s", uri, resp.Status)
+	}
+	return ioutil.ReadAll(http.MaxBytesReader(nil, resp.Body, MaxMetadataSize))
+}
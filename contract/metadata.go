@@ -0,0 +1,175 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package contract
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"blockwatch.cc/tzgo/micheline"
+	"blockwatch.cc/tzgo/rpc"
+	"blockwatch.cc/tzgo/tezos"
+)
+
+var (
+	// MetadataClient is the HTTP client used to fetch off-chain metadata.
+	MetadataClient = http.DefaultClient
+
+	// MaxMetadataSize limits the size of fetched metadata documents.
+	MaxMetadataSize int64 = 1 << 20
+)
+
+// ResolveMetadata reads the TZIP-16 metadata URI stored under the empty key
+// of contract addr's %metadata bigmap and fetches and decodes the metadata
+// document. Supported URI schemes are tezos-storage, http(s), ipfs and sha256
+// for integrity checked content.
+func ResolveMetadata(ctx context.Context, cli *rpc.Client, addr tezos.Address) (*Tz16, error) {
+	bigmap, err := metadataBigmap(ctx, cli, addr)
+	if err != nil {
+		return nil, err
+	}
+	uri, err := readMetadataKey(ctx, cli, bigmap, "")
+	if err != nil {
+		return nil, fmt.Errorf("contract: reading metadata uri: %w", err)
+	}
+	buf, err := fetchMetadata(ctx, cli, addr, bigmap, string(uri))
+	if err != nil {
+		return nil, err
+	}
+	var meta Tz16
+	if err := json.Unmarshal(buf, &meta); err != nil {
+		return nil, fmt.Errorf("contract: decoding metadata: %w", err)
+	}
+	return &meta, nil
+}
+
+// ResolveMetadata fetches and caches the contract's TZIP-16 metadata.
+func (c *Contract) ResolveMetadata(ctx context.Context) (*Tz16, error) {
+	meta, err := ResolveMetadata(ctx, c.rpc, c.addr)
+	if err != nil {
+		return nil, err
+	}
+	c.meta = meta
+	return meta, nil
+}
+
+func metadataBigmap(ctx context.Context, cli *rpc.Client, addr tezos.Address) (int64, error) {
+	script, err := cli.GetContractScript(ctx, addr)
+	if err != nil {
+		return 0, err
+	}
+	id, ok := script.BigmapsByName()["metadata"]
+	if !ok {
+		return 0, fmt.Errorf("contract: %s has no metadata bigmap", addr)
+	}
+	return id, nil
+}
+
+func readMetadataKey(ctx context.Context, cli *rpc.Client, bigmap int64, key string) ([]byte, error) {
+	k, err := micheline.NewKey(micheline.NewType(micheline.NewPrim(micheline.T_STRING)), micheline.NewString(key))
+	if err != nil {
+		return nil, err
+	}
+	val, err := cli.GetBigmapValue(ctx, bigmap, k.Hash(), rpc.Head)
+	if err != nil {
+		return nil, err
+	}
+	if val.Type != micheline.PrimBytes {
+		return nil, fmt.Errorf("contract: unexpected metadata value type %s", val.Type)
+	}
+	return val.Bytes, nil
+}
+
+// parseStorageURI returns the contract address and unescaped bigmap key of a
+// tezos-storage URI. The address is invalid for URIs relative to the current
+// contract.
+//
+//	tezos-storage:<key>
+//	tezos-storage://<address>[.<network>]/<key>
+func parseStorageURI(u *url.URL) (tezos.Address, string, error) {
+	var addr tezos.Address
+	key := u.Opaque
+	if u.Host != "" {
+		var err error
+		addr, err = tezos.ParseAddress(strings.Split(u.Host, ".")[0])
+		if err != nil {
+			return addr, "", err
+		}
+		key = strings.TrimPrefix(u.EscapedPath(), "/")
+	}
+	key, err := url.PathUnescape(key)
+	if err != nil {
+		return addr, "", err
+	}
+	return addr, key, nil
+}
+
+// parseHashURI returns the expected hash and unescaped inner URI of a sha256
+// URI in the form sha256://0x<hash>/<url-encoded uri>.
+func parseHashURI(u *url.URL) ([]byte, string, error) {
+	hash, err := hex.DecodeString(strings.TrimPrefix(u.Host, "0x"))
+	if err != nil || len(hash) != sha256.Size {
+		return nil, "", fmt.Errorf("invalid sha256 hash %q", u.Host)
+	}
+	inner, err := url.PathUnescape(strings.TrimPrefix(u.EscapedPath(), "/"))
+	if err != nil {
+		return nil, "", err
+	}
+	return hash, inner, nil
+}
+
+// fetchMetadata resolves a TZIP-16 URI. Bigmap and addr identify the contract
+// that stores the URI for relative tezos-storage references.
+func fetchMetadata(ctx context.Context, cli *rpc.Client, addr tezos.Address, bigmap int64, uri string) ([]byte, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, fmt.Errorf("contract: invalid metadata uri %q: %w", uri, err)
+	}
+	switch u.Scheme {
+	case "tezos-storage":
+		other, key, err := parseStorageURI(u)
+		if err != nil {
+			return nil, fmt.Errorf("contract: invalid metadata uri %q: %w", uri, err)
+		}
+		if other.IsValid() && !other.Equal(addr) {
+			if bigmap, err = metadataBigmap(ctx, cli, other); err != nil {
+				return nil, err
+			}
+		}
+		return readMetadataKey(ctx, cli, bigmap, key)
+
+	case "http", "https":
+		return fetchHTTP(ctx, MetadataClient, uri)
+
+	case "ipfs":
+		if DefaultIPFSResolver == nil {
+			return nil, fmt.Errorf("contract: no ipfs resolver for %q", uri)
+		}
+		return DefaultIPFSResolver.Resolve(ctx, uri)
+
+	case "sha256":
+		want, inner, err := parseHashURI(u)
+		if err != nil {
+			return nil, fmt.Errorf("contract: invalid metadata uri %q: %w", uri, err)
+		}
+		buf, err := fetchMetadata(ctx, cli, addr, bigmap, inner)
+		if err != nil {
+			return nil, err
+		}
+		if have := sha256.Sum256(buf); !bytes.Equal(have[:], want) {
+			return nil, fmt.Errorf("contract: metadata hash mismatch have=%x want=%x", have, want)
+		}
+		return buf, nil
+
+	default:
+		return nil, fmt.Errorf("contract: unsupported metadata uri %q", uri)
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package contract
+
+import (
+	"bytes"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestParseStorageURI(t *testing.T) {
+	kt := "KT1GyeRktoGPEKsWpchWguyy8FAf3aNHkw2T"
+	for _, test := range []struct {
+		uri  string
+		addr string
+		key  string
+		err  bool
+	}{
+		{uri: "tezos-storage:here", key: "here"},
+		{uri: "tezos-storage:hello%2Fworld", key: "hello/world"},
+		{uri: "tezos-storage:100%25", key: "100%"},
+		{uri: "tezos-storage://" + kt + "/here", addr: kt, key: "here"},
+		{uri: "tezos-storage://" + kt + ".NetXdQprcVkpaWU/here", addr: kt, key: "here"},
+		{uri: "tezos-storage://" + kt + "/foo%2Fbar", addr: kt, key: "foo/bar"},
+		// escapes are decoded exactly once
+		{uri: "tezos-storage://" + kt + "/%2525", addr: kt, key: "%25"},
+		{uri: "tezos-storage://KT1invalid/here", err: true},
+		{uri: "tezos-storage:bad%zz", err: true},
+	} {
+		u, err := url.Parse(test.uri)
+		if err != nil {
+			t.Fatalf("%s: %v", test.uri, err)
+		}
+		addr, key, err := parseStorageURI(u)
+		if test.err {
+			if err == nil {
+				t.Errorf("%s: expected error", test.uri)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", test.uri, err)
+			continue
+		}
+		if test.addr == "" && addr.IsValid() || test.addr != "" && addr.String() != test.addr {
+			t.Errorf("%s: address have=%s want=%s", test.uri, addr, test.addr)
+		}
+		if key != test.key {
+			t.Errorf("%s: key have=%q want=%q", test.uri, key, test.key)
+		}
+	}
+}
+
+func TestParseHashURI(t *testing.T) {
+	hash := strings.Repeat("ab", 32)
+	for _, test := range []struct {
+		uri   string
+		inner string
+		err   bool
+	}{
+		{uri: "sha256://0x" + hash + "/https:%2F%2Fexample.com%2Fmeta.json", inner: "https://example.com/meta.json"},
+		{uri: "sha256://0x" + hash + "/tezos-storage:hello%252Fworld", inner: "tezos-storage:hello%2Fworld"},
+		{uri: "sha256://0xabcd/https:%2F%2Fexample.com", err: true},
+		{uri: "sha256://0x" + strings.Repeat("zz", 32) + "/x", err: true},
+	} {
+		u, err := url.Parse(test.uri)
+		if err != nil {
+			t.Fatalf("%s: %v", test.uri, err)
+		}
+		h, inner, err := parseHashURI(u)
+		if test.err {
+			if err == nil {
+				t.Errorf("%s: expected error", test.uri)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", test.uri, err)
+			continue
+		}
+		if !bytes.Equal(h, bytes.Repeat([]byte{0xab}, 32)) {
+			t.Errorf("%s: hash have=%x", test.uri, h)
+		}
+		if inner != test.inner {
+			t.Errorf("%s: inner have=%q want=%q", test.uri, inner, test.inner)
+		}
+	}
+}
@@ -4,9 +4,15 @@
 package contract
 
 import (
+	"errors"
+
 	"blockwatch.cc/tzgo/tezos"
 )
 
+// ErrNoTokenMetadata is returned when a scaled token amount is requested but
+// the token's metadata is unknown.
+var ErrNoTokenMetadata = errors.New("contract: token metadata unknown")
+
 // Represents Tzip12 token metadata used by FA1 and FA2 tokens
 type TokenMetadata struct {
 	Name     string `json:"name"`
@@ -14,6 +20,11 @@ type TokenMetadata struct {
 	Decimals int    `json:"decimals"`
 }
 
+// Amount returns a raw token balance scaled by the token's decimals.
+func (m TokenMetadata) Amount(balance tezos.Z) tezos.TokenAmount {
+	return tezos.NewTokenAmount(balance, m.Decimals)
+}
+
 type TokenKind byte
 
 const (
@@ -5,6 +5,8 @@ package micheline
 
 import (
 	"math/big"
+
+	"blockwatch.cc/tzgo/tezos"
 )
 
 func NewCode(c OpCode, args ...Prim) Prim {
@@ -75,6 +77,60 @@ func NewCombPairType(contents ...Prim) Prim {
 	return Prim{Type: PrimSequence, OpCode: T_PAIR, Args: contents}
 }
 
+func NewUnit() Prim {
+	return NewCode(D_UNIT)
+}
+
+func NewBool(b bool) Prim {
+	if b {
+		return NewCode(D_TRUE)
+	}
+	return NewCode(D_FALSE)
+}
+
+// NewOption returns Some(p) or None when p is nil.
+func NewOption(p *Prim) Prim {
+	if p == nil {
+		return NewNone()
+	}
+	return NewSome(*p)
+}
+
+func NewSome(p Prim) Prim {
+	return NewCode(D_SOME, p)
+}
+
+func NewNone() Prim {
+	return NewCode(D_NONE)
+}
+
+func NewLeft(p Prim) Prim {
+	return NewCode(D_LEFT, p)
+}
+
+func NewRight(p Prim) Prim {
+	return NewCode(D_RIGHT, p)
+}
+
+func NewElt(k, v Prim) Prim {
+	return NewCode(D_ELT, k, v)
+}
+
+// NewMap returns a map value from Elt primitives. The protocol requires
+// elements to be sorted by key.
+func NewMap(elts ...Prim) Prim {
+	return NewSeq(elts...)
+}
+
+// NewAddress returns an address value in optimized (binary) or readable
+// (base58 string) form.
+func NewAddress(a tezos.Address, optimized bool) Prim {
+	if optimized {
+		return NewBytes(a.Bytes22())
+	}
+	return NewString(a.String())
+}
+
 func NewPrim(c OpCode, anno ...string) Prim {
 	typ := PrimNullary
 	if len(anno) > 0 {
@@ -0,0 +1,67 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package micheline
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"blockwatch.cc/tzgo/tezos"
+)
+
+func TestBuilder(t *testing.T) {
+	addr := tezos.MustParseAddress("KT1GyeRktoGPEKsWpchWguyy8FAf3aNHkw2T")
+	one := NewInt64(1)
+	var tests = []struct {
+		name string
+		prim Prim
+		json string
+	}{
+		{"unit", NewUnit(), `{"prim":"Unit"}`},
+		{"bool", NewBool(true), `{"prim":"True"}`},
+		{"string", NewString("a"), `{"string":"a"}`},
+		{"int", NewInt64(-5), `{"int":"-5"}`},
+		{"big", NewBig(big.NewInt(1 << 40)), `{"int":"1099511627776"}`},
+		{"bytes", NewBytes([]byte{0xca, 0xfe}), `{"bytes":"cafe"}`},
+		{"some", NewOption(&one), `{"prim":"Some","args":[{"int":"1"}]}`},
+		{"none", NewOption(nil), `{"prim":"None"}`},
+		{"left", NewLeft(NewUnit()), `{"prim":"Left","args":[{"prim":"Unit"}]}`},
+		{"right", NewRight(NewString("b")), `{"prim":"Right","args":[{"string":"b"}]}`},
+		{"pair", NewPair(one, NewString("a")), `{"prim":"Pair","args":[{"int":"1"},{"string":"a"}]}`},
+		{"comb", NewCombPair(one, one, one), `[{"int":"1"},{"int":"1"},{"int":"1"}]`},
+		{"seq", NewSeq(one), `[{"int":"1"}]`},
+		{"map", NewMap(NewElt(NewString("a"), one)), `[{"prim":"Elt","args":[{"string":"a"},{"int":"1"}]}]`},
+		{"address", NewAddress(addr, false), `{"string":"KT1GyeRktoGPEKsWpchWguyy8FAf3aNHkw2T"}`},
+		{"address_opt", NewAddress(addr, true), `{"bytes":"` + hex.EncodeToString(addr.Bytes22()) + `"}`},
+	}
+	for _, test := range tests {
+		buf, err := json.Marshal(test.prim)
+		if err != nil {
+			t.Fatalf("%s: marshal json: %v", test.name, err)
+		}
+		if got := string(buf); got != test.json {
+			t.Errorf("%s: json mismatch\n got  %s\n want %s", test.name, got, test.json)
+		}
+		var p Prim
+		if err := json.Unmarshal(buf, &p); err != nil {
+			t.Fatalf("%s: unmarshal json: %v", test.name, err)
+		}
+		if !p.IsEqual(test.prim) {
+			t.Errorf("%s: json round-trip mismatch %s", test.name, p.Dump())
+		}
+		bin, err := test.prim.MarshalBinary()
+		if err != nil {
+			t.Fatalf("%s: marshal binary: %v", test.name, err)
+		}
+		p = Prim{}
+		if err := p.UnmarshalBinary(bin); err != nil {
+			t.Fatalf("%s: unmarshal binary: %v", test.name, err)
+		}
+		if !p.IsEqual(test.prim) {
+			t.Errorf("%s: binary round-trip mismatch %s", test.name, p.Dump())
+		}
+	}
+}
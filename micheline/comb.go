@@ -0,0 +1,195 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package micheline
+
+// Fold converts nested right-hand pairs into canonical comb form, i.e.
+// (Pair a (Pair b c)) becomes (Pair a b c). Works on values and types including
+// comb pair types in sequence form. Annotated nested pair types are kept so that
+// no field names are lost. Values in sequence form cannot be identified without
+// type info, use Value.Fold to fold a value and its type consistently.
+func (p Prim) Fold() Prim {
+	if len(p.Args) == 0 {
+		return p
+	}
+	args := make([]Prim, len(p.Args))
+	for i, v := range p.Args {
+		args[i] = v.Fold()
+	}
+	p.Args = args
+	if !isPairPrim(p) {
+		return p
+	}
+	p.Args = combLeaves(p, true)
+	p.Type = pairPrimType(len(p.Args), len(p.Anno) > 0)
+	return p
+}
+
+// Unfold converts comb pairs into nested right-hand binary pairs, i.e.
+// (Pair a b c) becomes (Pair a (Pair b c)). Works on values and types including
+// comb pair types in sequence form. Use Value.Unfold to unfold a value and its
+// type consistently.
+func (p Prim) Unfold() Prim {
+	if len(p.Args) == 0 {
+		return p
+	}
+	args := make([]Prim, len(p.Args))
+	for i, v := range p.Args {
+		args[i] = v.Unfold()
+	}
+	p.Args = args
+	if !isPairPrim(p) {
+		return p
+	}
+	p.Args = nestPair(p.OpCode, args)
+	p.Type = pairPrimType(len(p.Args), len(p.Anno) > 0)
+	return p
+}
+
+// Fold converts the value and its type into canonical comb form. Unlike
+// Prim.Fold it also handles pair values in sequence form.
+func (v Value) Fold() Value {
+	typ := v.Type.Prim.Fold()
+	return Value{
+		Type:   Type{typ},
+		Value:  foldValue(typ, v.Value, true),
+		Render: v.Render,
+	}
+}
+
+// Unfold converts the value and its type into nested binary pairs. Unlike
+// Prim.Unfold it also handles pair values in sequence form.
+func (v Value) Unfold() Value {
+	typ := v.Type.Prim.Fold()
+	return Value{
+		Type:   Type{typ.Unfold()},
+		Value:  foldValue(typ, v.Value, false),
+		Render: v.Render,
+	}
+}
+
+// foldValue folds or unfolds val along type typ which must be in comb form.
+func foldValue(typ, val Prim, fold bool) Prim {
+	switch typ.OpCode {
+	case T_PAIR:
+		if len(typ.Args) < 2 || !(val.OpCode == D_PAIR || val.IsSequence()) {
+			return val
+		}
+		leaves := pairValueLeaves(val, len(typ.Args))
+		if len(leaves) != len(typ.Args) {
+			return val
+		}
+		for i := range leaves {
+			leaves[i] = foldValue(typ.Args[i], leaves[i], fold)
+		}
+		if !fold {
+			leaves = nestPair(D_PAIR, leaves)
+		}
+		return Prim{
+			Type:   pairPrimType(len(leaves), false),
+			OpCode: D_PAIR,
+			Args:   leaves,
+		}
+	case T_OPTION:
+		if val.OpCode == D_SOME && len(val.Args) == 1 && len(typ.Args) == 1 {
+			val.Args = []Prim{foldValue(typ.Args[0], val.Args[0], fold)}
+		}
+	case T_OR:
+		if len(val.Args) == 1 && len(typ.Args) == 2 {
+			switch val.OpCode {
+			case D_LEFT:
+				val.Args = []Prim{foldValue(typ.Args[0], val.Args[0], fold)}
+			case D_RIGHT:
+				val.Args = []Prim{foldValue(typ.Args[1], val.Args[0], fold)}
+			}
+		}
+	case T_LIST, T_SET:
+		if val.IsSequence() && len(typ.Args) == 1 {
+			args := make([]Prim, len(val.Args))
+			for i, v := range val.Args {
+				args[i] = foldValue(typ.Args[0], v, fold)
+			}
+			val.Args = args
+		}
+	case T_MAP, T_BIG_MAP:
+		if val.IsSequence() && len(typ.Args) == 2 {
+			args := make([]Prim, len(val.Args))
+			for i, v := range val.Args {
+				if v.OpCode == D_ELT && len(v.Args) == 2 {
+					v.Args = []Prim{
+						foldValue(typ.Args[0], v.Args[0], fold),
+						foldValue(typ.Args[1], v.Args[1], fold),
+					}
+				}
+				args[i] = v
+			}
+			val.Args = args
+		}
+	}
+	return val
+}
+
+// pairValueLeaves expands a pair value in nested, comb or sequence form into
+// n leaves by expanding the last element as long as required. When a flat
+// comb has more elements than required the tail is regrouped into a pair,
+// e.g. Pair 1 2 3 becomes [1, Pair 2 3] for n = 2.
+func pairValueLeaves(val Prim, n int) []Prim {
+	leaves := make([]Prim, 0, n)
+	for {
+		if len(val.Args) == 0 {
+			return append(leaves, val)
+		}
+		if k := n - len(leaves); k > 1 && len(val.Args) > k {
+			leaves = append(leaves, val.Args[:k-1]...)
+			return append(leaves, combValue(val.Args[k-1:]))
+		}
+		leaves = append(leaves, val.Args[:len(val.Args)-1]...)
+		last := val.Args[len(val.Args)-1]
+		if len(leaves)+1 >= n || !(last.OpCode == D_PAIR || last.IsSequence()) {
+			return append(leaves, last)
+		}
+		val = last
+	}
+}
+
+// combValue wraps comb elements into a single pair value.
+func combValue(args []Prim) Prim {
+	if len(args) == 2 {
+		return NewPair(args[0], args[1])
+	}
+	return Prim{Type: PrimVariadicAnno, OpCode: D_PAIR, Args: args}
+}
+
+// nestPair converts a list of comb arguments into the arguments of a binary
+// right-hand pair.
+func nestPair(op OpCode, args []Prim) []Prim {
+	if len(args) <= 2 {
+		return args
+	}
+	return []Prim{
+		args[0],
+		{
+			Type:   PrimBinary,
+			OpCode: op,
+			Args:   nestPair(op, args[1:]),
+		},
+	}
+}
+
+func pairPrimType(n int, withAnno bool) PrimType {
+	var typ PrimType
+	switch n {
+	case 0:
+		typ = PrimNullary
+	case 1:
+		typ = PrimUnary
+	case 2:
+		typ = PrimBinary
+	default:
+		return PrimVariadicAnno
+	}
+	if withAnno {
+		typ++
+	}
+	return typ
+}
@@ -4,9 +4,20 @@
 package micheline
 
 import (
+	"errors"
+	"fmt"
+
 	"blockwatch.cc/tzgo/tezos"
 )
 
+// ErrConstantCycle is returned when a global constant directly or indirectly
+// references itself.
+var ErrConstantCycle = errors.New("micheline: cyclic global constant reference")
+
+// ConstantResolver is a callback that returns the definition of the global
+// constant registered under hash.
+type ConstantResolver func(hash tezos.ExprHash) (Prim, error)
+
 type ConstantDict map[string]Prim
 
 func (d *ConstantDict) Add(address tezos.ExprHash, value Prim) {
@@ -40,6 +51,14 @@ func (d ConstantDict) GetString(address string) (Prim, bool) {
 	return p, ok
 }
 
+// Resolve implements ConstantResolver for constants stored in the dictionary.
+func (d ConstantDict) Resolve(hash tezos.ExprHash) (Prim, error) {
+	if p, ok := d.Get(hash); ok {
+		return p, nil
+	}
+	return InvalidPrim, fmt.Errorf("micheline: unknown global constant %s", hash)
+}
+
 func (p Prim) Constants() []tezos.ExprHash {
 	c := make([]tezos.ExprHash, 0)
 	p.Walk(func(p Prim) error {
@@ -52,3 +71,73 @@ func (p Prim) Constants() []tezos.ExprHash {
 	})
 	return c
 }
+
+// ExpandConstants returns a copy of prim with all global constant references
+// replaced by their definitions as returned by resolver. Constants that
+// reference other constants are expanded recursively, each constant is
+// resolved at most once. Use it to materialize code, types or values outside
+// a full script, e.g. before hashing or typechecking.
+func ExpandConstants(prim Prim, resolver ConstantResolver) (Prim, error) {
+	p := prim.Clone()
+	if err := newConstantExpander(resolver).Expand(&p); err != nil {
+		return InvalidPrim, err
+	}
+	return p, nil
+}
+
+// constantExpander replaces constant references with their (recursively
+// expanded) definitions. Each constant is resolved at most once.
+type constantExpander struct {
+	resolve ConstantResolver
+	cache   map[string]Prim
+	active  map[string]struct{}
+}
+
+func newConstantExpander(fn ConstantResolver) *constantExpander {
+	return &constantExpander{
+		resolve: fn,
+		cache:   make(map[string]Prim),
+		active:  make(map[string]struct{}),
+	}
+}
+
+func (e *constantExpander) Expand(p *Prim) error {
+	return p.Visit(func(x *Prim) error {
+		if !x.IsConstant() || len(x.Args) == 0 {
+			return nil
+		}
+		c, err := e.lookup(x.Args[0].String)
+		if err != nil {
+			return err
+		}
+		*x = c.Clone()
+		// definitions are already fully expanded
+		return PrimSkip
+	})
+}
+
+func (e *constantExpander) lookup(key string) (Prim, error) {
+	if c, ok := e.cache[key]; ok {
+		return c, nil
+	}
+	if _, ok := e.active[key]; ok {
+		return InvalidPrim, fmt.Errorf("%w %s", ErrConstantCycle, key)
+	}
+	h, err := tezos.ParseExprHash(key)
+	if err != nil {
+		return InvalidPrim, fmt.Errorf("micheline: invalid global constant hash %q: %w", key, err)
+	}
+	c, err := e.resolve(h)
+	if err != nil {
+		return InvalidPrim, err
+	}
+	c = c.Clone()
+	e.active[key] = struct{}{}
+	err = e.Expand(&c)
+	delete(e.active, key)
+	if err != nil {
+		return InvalidPrim, err
+	}
+	e.cache[key] = c
+	return c, nil
+}
@@ -0,0 +1,99 @@
+// Copyright (c) 2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+//
+
+package micheline
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"blockwatch.cc/tzgo/tezos"
+)
+
+func constantHash(n byte) tezos.ExprHash {
+	return tezos.NewExprHash(bytes.Repeat([]byte{n}, 32))
+}
+
+func constantRef(h tezos.ExprHash) Prim {
+	return NewCode(H_CONSTANT, NewString(h.String()))
+}
+
+func TestExpandConstants(t *testing.T) {
+	h1, h2 := constantHash(1), constantHash(2)
+	var dict ConstantDict
+	dict.Add(h1, NewSeq(NewCode(I_DROP), constantRef(h2)))
+	dict.Add(h2, NewCode(I_UNIT))
+
+	script := NewScript()
+	script.Code.Code.Args[0].Args = []Prim{constantRef(h1)}
+	if err := script.ExpandConstants(dict.Resolve); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := NewSeq(NewCode(I_DROP), NewCode(I_UNIT))
+	if got := script.Code.Code.Args[0].Args[0]; !got.IsEqual(want) {
+		t.Errorf("mismatch\n  got=%s\n want=%s", got.Dump(), want.Dump())
+	}
+	if n := len(script.Constants()); n != 0 {
+		t.Errorf("expected no remaining constants, got %d", n)
+	}
+}
+
+func TestExpandConstantsCycle(t *testing.T) {
+	h1, h2 := constantHash(1), constantHash(2)
+	var dict ConstantDict
+	dict.Add(h1, NewSeq(constantRef(h2)))
+	dict.Add(h2, NewSeq(constantRef(h1)))
+
+	script := NewScript()
+	script.Code.Code.Args[0].Args = []Prim{constantRef(h1)}
+	err := script.ExpandConstants(dict.Resolve)
+	if !errors.Is(err, ErrConstantCycle) {
+		t.Errorf("expected cycle error, got %v", err)
+	}
+}
+
+func TestExpandConstantsMissing(t *testing.T) {
+	script := NewScript()
+	script.Code.Code.Args[0].Args = []Prim{constantRef(constantHash(3))}
+	if err := script.ExpandConstants(ConstantDict{}.Resolve); err == nil {
+		t.Errorf("expected error for unknown constant")
+	}
+}
+
+func TestExpandConstantsPrim(t *testing.T) {
+	h1, h2 := constantHash(1), constantHash(2)
+	var (
+		dict  ConstantDict
+		calls int
+	)
+	dict.Add(h1, NewSeq(constantRef(h2), constantRef(h2)))
+	dict.Add(h2, NewCode(I_UNIT))
+	resolve := func(h tezos.ExprHash) (Prim, error) {
+		calls++
+		return dict.Resolve(h)
+	}
+
+	in := NewSeq(constantRef(h1), NewCode(I_DROP))
+	out, err := ExpandConstants(in, resolve)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := NewSeq(NewSeq(NewCode(I_UNIT), NewCode(I_UNIT)), NewCode(I_DROP))
+	if !out.IsEqual(want) {
+		t.Errorf("mismatch\n  got=%s\n want=%s", out.Dump(), want.Dump())
+	}
+	if calls != 2 {
+		t.Errorf("expected 2 resolver calls, got %d", calls)
+	}
+	if n := len(in.Constants()); n != 1 {
+		t.Errorf("input was modified, have %d constants", n)
+	}
+
+	// a constant at the root is replaced as well
+	out, err = ExpandConstants(constantRef(h2), dict.Resolve)
+	if err != nil || !out.IsEqual(NewCode(I_UNIT)) {
+		t.Errorf("root constant not expanded: %s %v", out.Dump(), err)
+	}
+}
@@ -27,6 +27,67 @@ func (e Entrypoint) Type() Type {
 	return typ
 }
 
+// Path returns the sequence of D_LEFT and D_RIGHT constructors that select
+// this entrypoint's branch in the full parameter type, outermost first.
+func (e Entrypoint) Path() []OpCode {
+	branch := strings.Trim(e.Branch, "/")
+	if branch == "" {
+		return nil
+	}
+	parts := strings.Split(branch, "/")
+	path := make([]OpCode, 0, len(parts))
+	for _, v := range parts {
+		switch v {
+		case "L":
+			path = append(path, D_LEFT)
+		case "R":
+			path = append(path, D_RIGHT)
+		}
+	}
+	return path
+}
+
+// Wrap wraps an entrypoint argument into Left/Right constructors along the
+// entrypoint's branch. Branches are relative to the root of the parameter type,
+// so the result is only valid as root parameter, e.g. when calling a contract
+// without explicit %default entrypoint via its default entrypoint. It must not
+// be sent to a named entrypoint. Use WrapEntrypoint to look up the entrypoint
+// by name and handle contracts with an annotated %default branch.
+func (e Entrypoint) Wrap(val Prim) Prim {
+	path := e.Path()
+	for i := len(path) - 1; i >= 0; i-- {
+		val = NewCode(path[i], val)
+	}
+	return val
+}
+
+// WrapEntrypoint wraps value for the named entrypoint of paramType into the
+// Left/Right constructors that select the entrypoint in the full parameter
+// type. The result can be passed to run_code or sent to the default entrypoint
+// of contracts that only accept the root parameter. An empty name selects the
+// default entrypoint. When no branch is annotated %default, value must already
+// be of the root parameter type and is returned unchanged.
+func WrapEntrypoint(paramType Prim, entrypoint string, value Prim) (Prim, error) {
+	if paramType.OpCode == K_PARAMETER && len(paramType.Args) > 0 {
+		paramType = paramType.Args[0]
+	}
+	if entrypoint == "" {
+		entrypoint = "default"
+	}
+	eps, err := NewType(paramType).Entrypoints(false)
+	if err != nil {
+		return InvalidPrim, err
+	}
+	ep, ok := eps[entrypoint]
+	if !ok {
+		if entrypoint == "default" {
+			return value, nil
+		}
+		return InvalidPrim, fmt.Errorf("micheline: unknown entrypoint %q", entrypoint)
+	}
+	return ep.Wrap(value), nil
+}
+
 func (e Entrypoint) IsCallback() bool {
 	if e.Prim == nil {
 		return false
@@ -48,6 +109,14 @@ func (e Entrypoints) FindBranch(branch string) (Entrypoint, bool) {
 	return Entrypoint{}, false
 }
 
+// Default returns the entrypoint that receives calls without explicit entrypoint
+// name. When ok is false no branch is annotated %default and default calls
+// take the full parameter type.
+func (e Entrypoints) Default() (Entrypoint, bool) {
+	ep, ok := e["default"]
+	return ep, ok
+}
+
 func (e Entrypoints) FindId(id int) (Entrypoint, bool) {
 	for _, v := range e {
 		if v.Id == id {
@@ -83,7 +152,7 @@ func (t Type) ResolveEntrypointPath(name string) string {
 }
 
 func resolveEntrypointPath(name, branch string, node Prim) string {
-	if node.GetVarAnnoAny() == name {
+	if node.GetFieldAnnoAny() == name {
 		return branch
 	}
 	if node.OpCode == T_OR && (len(branch) == 0 || !node.HasAnno()) {
@@ -123,7 +192,7 @@ func isKnownEntrypointPrefix(s string) bool {
 // walks T_OR expressions and stores each non-T_OR branch as entrypoint
 func listEntrypoints(e Entrypoints, branch string, node Prim) error {
 	// prefer % annotations
-	name := node.GetVarAnnoAny()
+	name := node.GetFieldAnnoAny()
 	if node.OpCode == T_OR && !isKnownEntrypointPrefix(name) {
 		if l := len(node.Args); l != 2 {
 			return fmt.Errorf("micheline: expected 2 arguments for T_OR, got %d", l)
@@ -140,13 +209,18 @@ func listEntrypoints(e Entrypoints, branch string, node Prim) error {
 		return nil
 	}
 
-	// need unique entrypoint name
-	if name == "" {
-		if len(e) == 0 {
-			name = "default"
-		} else {
-			name = fmt.Sprintf("%s_%d", CONST_ENTRYPOINT, len(e))
-		}
+	// need unique entrypoint name; an unnamed root is the default entrypoint,
+	// unnamed branches are only reachable through the root
+	switch {
+	case name == "" && branch == "":
+		name = "default"
+	case name == "":
+		name = fmt.Sprintf("%s_%d", CONST_ENTRYPOINT, len(e))
+	}
+	if _, ok := e[name]; ok {
+		// keep both when annotations collide, e.g. a var annotation that
+		// repeats a field name or a %default that is not unique
+		name = fmt.Sprintf("%s_%d", CONST_ENTRYPOINT, len(e))
 	}
 
 	// process non-T_OR branches
@@ -90,6 +90,16 @@ var entryInfo = []entryTest{
             }
         }`,
 	},
+
+	// unnamed branch next to explicit %default
+	entryTest{
+		Name: "unnamed and default",
+		Spec: `{"prim":"parameter","args":[{"prim":"or","args":[{"prim":"unit"},{"prim":"nat","annots":["%default"]}]}]}`,
+		Want: `{
+            "@entrypoint_0": {"branch": "/L", "call": "@entrypoint_0", "id": 0, "type": [{"name":"","type":"unit"}]},
+            "default": {"branch": "/R", "call": "default", "id": 1, "type": [{"name":"","type":"nat"}]}
+        }`,
+	},
 }
 
 func TestEntrypointRendering(t *testing.T) {
@@ -115,3 +125,61 @@ func TestEntrypointRendering(t *testing.T) {
 		})
 	}
 }
+
+func TestEntrypointWrap(t *testing.T) {
+	ep := Entrypoint{Branch: "/R/L"}
+	have := ep.Wrap(NewInt64(1))
+	want := NewCode(D_RIGHT, NewCode(D_LEFT, NewInt64(1)))
+	if !have.IsEqual(want) {
+		t.Errorf("wrap mismatch: %s", have.Dump())
+	}
+	params := Parameters{Value: have}
+	if v := params.Unwrap(ep.Branch); !v.IsEqual(NewInt64(1)) {
+		t.Errorf("unwrap mismatch: %s", v.Dump())
+	}
+}
+
+func TestWrapEntrypoint(t *testing.T) {
+	// or (or (nat %a) (string %b)) (unit %c)
+	typ := NewCode(T_OR,
+		NewCode(T_OR, NewCodeAnno(T_NAT, "%a"), NewCodeAnno(T_STRING, "%b")),
+		NewCodeAnno(T_UNIT, "%c"),
+	)
+	have, err := WrapEntrypoint(typ, "b", NewString("x"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := NewCode(D_LEFT, NewCode(D_RIGHT, NewString("x")))
+	if !have.IsEqual(want) {
+		t.Errorf("wrap mismatch: %s", have.Dump())
+	}
+	have, err = WrapEntrypoint(NewCode(K_PARAMETER, typ), "c", NewCode(D_UNIT))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := NewCode(D_RIGHT, NewCode(D_UNIT)); !have.IsEqual(want) {
+		t.Errorf("wrap mismatch: %s", have.Dump())
+	}
+	if _, err := WrapEntrypoint(typ, "d", NewCode(D_UNIT)); err == nil {
+		t.Errorf("expected unknown entrypoint error")
+	}
+	// an unnamed root is the default entrypoint
+	have, err = WrapEntrypoint(NewCode(T_NAT), "", NewInt64(1))
+	if err != nil || !have.IsEqual(NewInt64(1)) {
+		t.Errorf("default wrap mismatch: %s %v", have.Dump(), err)
+	}
+	// without %default the root parameter is passed through
+	val := NewCode(D_LEFT, NewCode(D_LEFT, NewInt64(1)))
+	for _, name := range []string{"", "default"} {
+		have, err = WrapEntrypoint(typ, name, val)
+		if err != nil || !have.IsEqual(val) {
+			t.Errorf("root wrap %q mismatch: %s %v", name, have.Dump(), err)
+		}
+	}
+	// an explicit %default branch is selected
+	typ = NewCode(T_OR, NewCodeAnno(T_NAT, "%default"), NewCodeAnno(T_UNIT, "%c"))
+	have, err = WrapEntrypoint(typ, "", NewInt64(1))
+	if want := NewCode(D_LEFT, NewInt64(1)); err != nil || !have.IsEqual(want) {
+		t.Errorf("default branch wrap mismatch: %s %v", have.Dump(), err)
+	}
+}
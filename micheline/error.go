@@ -0,0 +1,125 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package micheline
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"blockwatch.cc/tzgo/tezos"
+)
+
+// Well-known script error kinds as reported by the node without protocol prefix.
+const (
+	ErrorScriptRejected    = "script_rejected"
+	ErrorRuntime           = "runtime_error"
+	ErrorScriptOverflow    = "script_overflow"
+	ErrorGasExhausted      = "gas_exhausted.operation"
+	ErrorGasExhaustedBlock = "gas_exhausted.block"
+	ErrorBadParameter      = "bad_contract_parameter"
+	ErrorIllTypedData      = "ill_typed_data"
+)
+
+// ScriptError is a Michelson runtime error decoded from the error list returned
+// by the node when a contract call fails.
+type ScriptError struct {
+	Kind     string        // error kind without protocol prefix, e.g. script_rejected
+	ID       string        // full error id
+	Contract tezos.Address // contract that failed, if reported
+	Location int64         // location in the contract code, -1 if unknown
+	With     *Prim         // FAILWITH argument, if any
+	Trace    []string      // kinds of all errors in the list
+}
+
+type scriptErrorJSON struct {
+	ID       string        `json:"id"`
+	Location *int64        `json:"location"`
+	With     *Prim         `json:"with"`
+	Contract tezos.Address `json:"contract_handle"`
+}
+
+// DecodeScriptError decodes a node error list (or a single error object) and
+// returns the most specific script error contained. Returns nil when raw does
+// not contain any error.
+func DecodeScriptError(raw json.RawMessage) *ScriptError {
+	var list []scriptErrorJSON
+	if err := json.Unmarshal(raw, &list); err != nil {
+		var single scriptErrorJSON
+		if err := json.Unmarshal(raw, &single); err != nil {
+			return nil
+		}
+		list = []scriptErrorJSON{single}
+	}
+	e := &ScriptError{Location: -1}
+	best := -1
+	for i, v := range list {
+		if v.ID == "" {
+			continue
+		}
+		e.Trace = append(e.Trace, errorKind(v.ID))
+		if v.Contract.IsValid() {
+			e.Contract = v.Contract
+		}
+		// prefer errors carrying a failwith value, then those with location
+		switch {
+		case v.With != nil:
+			best = i
+		case v.Location != nil && (best < 0 || list[best].With == nil):
+			best = i
+		case best < 0 || (list[best].With == nil && list[best].Location == nil):
+			best = i
+		}
+	}
+	if best < 0 {
+		return nil
+	}
+	v := list[best]
+	e.ID = v.ID
+	e.Kind = errorKind(v.ID)
+	e.With = v.With
+	if v.Location != nil {
+		e.Location = *v.Location
+	}
+	return e
+}
+
+// errorKind strips the protocol and michelson_v1 prefixes from an error id,
+// e.g. proto.017-PtNairob.michelson_v1.script_rejected becomes script_rejected.
+func errorKind(id string) string {
+	if strings.HasPrefix(id, "proto.") {
+		if i := strings.IndexByte(id[6:], '.'); i >= 0 {
+			id = id[6+i+1:]
+		}
+	}
+	return strings.TrimPrefix(id, "michelson_v1.")
+}
+
+// IsRejected returns true when the script failed with an explicit FAILWITH.
+func (e *ScriptError) IsRejected() bool {
+	return e.Kind == ErrorScriptRejected
+}
+
+// IsGasExhausted returns true when the operation or block ran out of gas.
+func (e *ScriptError) IsGasExhausted() bool {
+	return strings.HasPrefix(e.Kind, "gas_exhausted")
+}
+
+func (e *ScriptError) Error() string {
+	var b strings.Builder
+	b.WriteString("micheline: ")
+	b.WriteString(e.Kind)
+	if e.Contract.IsValid() {
+		b.WriteString(" in ")
+		b.WriteString(e.Contract.String())
+	}
+	if e.Location >= 0 {
+		fmt.Fprintf(&b, " at location %d", e.Location)
+	}
+	if e.With != nil {
+		b.WriteString(" with ")
+		b.WriteString(e.With.Dump())
+	}
+	return b.String()
+}
@@ -0,0 +1,43 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package micheline
+
+import (
+	"testing"
+)
+
+func TestDecodeScriptError(t *testing.T) {
+	raw := []byte(`[
+    {"kind":"temporary","id":"proto.017-PtNairob.michelson_v1.runtime_error","contract_handle":"KT1GyeRktoGPEKsWpchWguyy8FAf3aNHkw2T","contract_code":"Deprecated"},
+    {"kind":"temporary","id":"proto.017-PtNairob.michelson_v1.script_rejected","location":123,"with":{"string":"FA2_INSUFFICIENT_BALANCE"}}
+]`)
+	e := DecodeScriptError(raw)
+	if e == nil {
+		t.Fatal("expected error")
+	}
+	if !e.IsRejected() || e.Location != 123 || e.With == nil || e.With.String != "FA2_INSUFFICIENT_BALANCE" {
+		t.Errorf("unexpected error %#v", e)
+	}
+	if e.Contract.String() != "KT1GyeRktoGPEKsWpchWguyy8FAf3aNHkw2T" {
+		t.Errorf("unexpected contract %s", e.Contract)
+	}
+	if len(e.Trace) != 2 || e.Trace[0] != ErrorRuntime {
+		t.Errorf("unexpected trace %v", e.Trace)
+	}
+	want := `micheline: script_rejected in KT1GyeRktoGPEKsWpchWguyy8FAf3aNHkw2T at location 123 with {"string":"FA2_INSUFFICIENT_BALANCE"}`
+	if e.Error() != want {
+		t.Errorf("unexpected message %q", e.Error())
+	}
+
+	e = DecodeScriptError([]byte(`{"kind":"temporary","id":"proto.017-PtNairob.gas_exhausted.operation"}`))
+	if e == nil || !e.IsGasExhausted() || e.Location != -1 || e.With != nil {
+		t.Errorf("unexpected error %#v", e)
+	}
+
+	for _, s := range []string{``, `[]`, `"oops"`, `[{"kind":"temporary"}]`} {
+		if e := DecodeScriptError([]byte(s)); e != nil {
+			t.Errorf("%q: unexpected error %#v", s, e)
+		}
+	}
+}
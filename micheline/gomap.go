@@ -0,0 +1,239 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package micheline
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+)
+
+// ToMap decodes the value p of type typ into nested Go maps and slices for
+// ad-hoc inspection and logging. Object keys follow the RENDER_TYPE_JSON
+// conventions of Value.MarshalJSON, i.e. pair fields are named after
+// annotations or their position.
+// Scalars use natural Go types:
+//
+//   - int, nat, mutez and bigmap references become *big.Int
+//   - timestamps become time.Time in UTC
+//   - bool becomes bool, unit becomes an empty map
+//   - strings, bytes (0x-prefixed hex) and base58 types become string
+//   - options become nil or the inner value
+//   - lists and sets become []interface{}
+//   - lambdas and other code stay Prim
+//
+// Values that do not decode into a map are returned under their annotation
+// or "0" when unannotated.
+func (p Prim) ToMap(typ Prim) (map[string]interface{}, error) {
+	typ = typ.Fold()
+	v, err := toGo(typ, p)
+	if err != nil {
+		return nil, err
+	}
+	if m, ok := v.(map[string]interface{}); ok {
+		return m, nil
+	}
+	name := typ.GetVarAnnoAny()
+	if name == "" {
+		name = "0"
+	}
+	return map[string]interface{}{name: v}, nil
+}
+
+func toGo(typ, val Prim) (interface{}, error) {
+	switch typ.OpCode {
+	case T_UNIT:
+		if val.OpCode != D_UNIT {
+			return nil, renderMismatch(typ, val)
+		}
+		return map[string]interface{}{}, nil
+
+	case T_INT, T_NAT, T_MUTEZ:
+		if val.Type != PrimInt {
+			return nil, renderMismatch(typ, val)
+		}
+		return new(big.Int).Set(val.Int), nil
+
+	case T_TIMESTAMP:
+		tm, err := val.Time()
+		if err != nil {
+			return nil, renderMismatch(typ, val)
+		}
+		if y := tm.Year(); y < 0 || y >= 10000 {
+			if val.Type == PrimString {
+				return val.String, nil
+			}
+			return new(big.Int).Set(val.Int), nil
+		}
+		return tm, nil
+
+	case T_OPTION:
+		switch val.OpCode {
+		case D_NONE:
+			return nil, nil
+		case D_SOME:
+			if len(val.Args) == 1 {
+				return toGo(typ.Args[0], val.Args[0])
+			}
+		}
+		return nil, renderMismatch(typ, val)
+
+	case T_OR:
+		if len(val.Args) != 1 {
+			return nil, renderMismatch(typ, val)
+		}
+		var branch Prim
+		name := "left"
+		switch val.OpCode {
+		case D_LEFT:
+			branch = typ.Args[0]
+		case D_RIGHT:
+			branch, name = typ.Args[1], "right"
+		default:
+			return nil, renderMismatch(typ, val)
+		}
+		inner, err := toGo(branch, val.Args[0])
+		if err != nil {
+			return nil, err
+		}
+		if label := branch.GetVarAnnoAny(); label != "" {
+			name = label
+		}
+		return map[string]interface{}{name: inner}, nil
+
+	case T_PAIR:
+		m := make(map[string]interface{}, len(typ.Args))
+		if err := toGoPair(typ, val, m); err != nil {
+			return nil, err
+		}
+		return m, nil
+
+	case T_LIST, T_SET:
+		if !val.IsSequence() {
+			return nil, renderMismatch(typ, val)
+		}
+		arr := make([]interface{}, len(val.Args))
+		for i, v := range val.Args {
+			r, err := toGo(typ.Args[0], v)
+			if err != nil {
+				return nil, err
+			}
+			arr[i] = r
+		}
+		return arr, nil
+
+	case T_MAP, T_BIG_MAP:
+		if val.Type == PrimInt {
+			// bigmap reference
+			return new(big.Int).Set(val.Int), nil
+		}
+		if !val.IsSequence() {
+			return nil, renderMismatch(typ, val)
+		}
+		return toGoMap(typ, val)
+
+	case T_SAPLING_STATE:
+		if val.Type == PrimInt {
+			return new(big.Int).Set(val.Int), nil
+		}
+		return val, nil
+
+	case T_TICKET:
+		leaves := pairValueLeaves(val, 3)
+		if len(leaves) != 3 || len(typ.Args) != 1 {
+			return nil, renderMismatch(typ, val)
+		}
+		ticketer, err := toGo(NewPrim(T_ADDRESS), leaves[0])
+		if err != nil {
+			return nil, err
+		}
+		contents, err := toGo(typ.Args[0], leaves[1])
+		if err != nil {
+			return nil, err
+		}
+		amount, err := toGo(NewPrim(T_NAT), leaves[2])
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{
+			"ticketer": ticketer,
+			"value":    contents,
+			"amount":   amount,
+		}, nil
+
+	default:
+		// bool, strings, bytes, base58 types, lambdas and operations
+		return renderTyped(typ, val)
+	}
+}
+
+// toGoPair adds pair fields to m merging unannotated nested pairs.
+func toGoPair(typ, val Prim, m map[string]interface{}) error {
+	if !(val.OpCode == D_PAIR || val.IsSequence()) {
+		return renderMismatch(typ, val)
+	}
+	leaves := pairValueLeaves(val, len(typ.Args))
+	if len(leaves) != len(typ.Args) {
+		return renderMismatch(typ, val)
+	}
+	for i, t := range typ.Args {
+		if t.OpCode == T_PAIR && t.GetVarAnnoAny() == "" {
+			if err := toGoPair(t, leaves[i], m); err != nil {
+				return err
+			}
+			continue
+		}
+		r, err := toGo(t, leaves[i])
+		if err != nil {
+			return err
+		}
+		pos := strconv.Itoa(len(m))
+		name := t.GetVarAnnoAny()
+		_, dup := m[name]
+		switch {
+		case name == "":
+			name = pos
+		case dup:
+			name += "_" + pos
+		}
+		m[name] = r
+	}
+	return nil
+}
+
+func toGoMap(typ, val Prim) (interface{}, error) {
+	simple := isStringKey(typ.Args[0].OpCode)
+	obj := make(map[string]interface{}, len(val.Args))
+	arr := make([]interface{}, 0, len(val.Args))
+	for _, v := range val.Args {
+		if v.OpCode != D_ELT || len(v.Args) != 2 {
+			return nil, renderMismatch(typ, val)
+		}
+		value, err := toGo(typ.Args[1], v.Args[1])
+		if err != nil {
+			return nil, err
+		}
+		if simple {
+			key, err := renderTyped(typ.Args[0], v.Args[0])
+			if err != nil {
+				return nil, err
+			}
+			name, ok := key.(string)
+			if !ok {
+				name = fmt.Sprint(key)
+			}
+			obj[name] = value
+			continue
+		}
+		key, err := toGo(typ.Args[0], v.Args[0])
+		if err != nil {
+			return nil, err
+		}
+		arr = append(arr, map[string]interface{}{"key": key, "value": value})
+	}
+	if simple {
+		return obj, nil
+	}
+	return arr, nil
+}
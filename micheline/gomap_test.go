@@ -0,0 +1,109 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package micheline
+
+import (
+	"math/big"
+	"testing"
+	"time"
+)
+
+func TestPrimToMap(t *testing.T) {
+	typ := NewPairType(
+		NewCodeAnno(T_ADDRESS, "%owner"),
+		NewPairType(
+			NewCodeAnno(T_MUTEZ, "%balance"),
+			NewPairType(
+				NewCode(T_TIMESTAMP),
+				NewPairType(
+					NewCodeAnno(T_OPTION, "%flag", NewCode(T_BOOL)),
+					NewCodeAnno(T_MAP, "%meta", NewCode(T_STRING), NewCode(T_NAT)),
+				),
+			),
+		),
+	)
+	val := NewPair(
+		NewString("tz1burnburnburnburnburnburnburjAYjjX"),
+		NewPair(
+			NewInt64(1500000),
+			NewPair(
+				NewInt64(1647270566),
+				NewPair(
+					NewSome(NewCode(D_TRUE)),
+					NewSeq(NewElt(NewString("a"), NewInt64(1))),
+				),
+			),
+		),
+	)
+	m, err := val.ToMap(typ)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := m["owner"]; got != "tz1burnburnburnburnburnburnburjAYjjX" {
+		t.Errorf("owner got=%v", got)
+	}
+	if got, ok := m["balance"].(*big.Int); !ok || got.Int64() != 1500000 {
+		t.Errorf("balance got=%v", m["balance"])
+	}
+	if got, ok := m["2"].(time.Time); !ok || !got.Equal(time.Unix(1647270566, 0)) {
+		t.Errorf("timestamp got=%v", m["2"])
+	}
+	if got := m["flag"]; got != true {
+		t.Errorf("flag got=%v", got)
+	}
+	meta, ok := m["meta"].(map[string]interface{})
+	if !ok || meta["a"].(*big.Int).Int64() != 1 {
+		t.Errorf("meta got=%v", m["meta"])
+	}
+
+	// scalars are returned under their annotation or position
+	m, err = NewInt64(5).ToMap(NewCodeAnno(T_NAT, "%counter"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, ok := m["counter"].(*big.Int); !ok || got.Int64() != 5 {
+		t.Errorf("counter got=%v", m)
+	}
+	if _, err := NewString("x").ToMap(NewCode(T_NAT)); err == nil {
+		t.Errorf("expected type mismatch error")
+	}
+}
+
+func TestPrimToMapEdgeCases(t *testing.T) {
+	// timestamps outside the RFC3339 range keep their original form
+	for _, v := range []Prim{NewInt64(253402300800), NewString("253402300800")} {
+		m, err := v.ToMap(NewCode(T_TIMESTAMP))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		switch got := m["0"].(type) {
+		case *big.Int:
+			if got.Int64() != 253402300800 {
+				t.Errorf("timestamp got=%v", got)
+			}
+		case string:
+			if got != "253402300800" {
+				t.Errorf("timestamp got=%v", got)
+			}
+		default:
+			t.Errorf("timestamp got=%T %v", got, got)
+		}
+	}
+
+	// flat comb value under an annotated nested pair type
+	typ := NewPairType(
+		NewCodeAnno(T_NAT, "%a"),
+		NewPairType(NewCodeAnno(T_NAT, "%b"), NewCodeAnno(T_NAT, "%c"), "%x"),
+	)
+	val := NewCombPair(NewInt64(1), NewInt64(2), NewInt64(3))
+	val = Prim{Type: PrimVariadicAnno, OpCode: D_PAIR, Args: val.Args}
+	m, err := val.ToMap(typ)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	x, ok := m["x"].(map[string]interface{})
+	if !ok || m["a"].(*big.Int).Int64() != 1 || x["b"].(*big.Int).Int64() != 2 || x["c"].(*big.Int).Int64() != 3 {
+		t.Errorf("comb got=%v", m)
+	}
+}
@@ -0,0 +1,297 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package micheline
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// marshalTypedJSON renders the value as idiomatic JSON driven by its type. See
+// MarshalJSON for the conventions.
+func (v Value) marshalTypedJSON() ([]byte, error) {
+	r, err := renderTyped(v.Type.Prim.Fold(), v.Value)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(r)
+}
+
+// jsonField is a named member of a jsonObject.
+type jsonField struct {
+	Name  string
+	Value interface{}
+}
+
+// jsonObject is a JSON object that keeps the order of its fields.
+type jsonObject []jsonField
+
+func (o jsonObject) MarshalJSON() ([]byte, error) {
+	buf := bytes.NewBuffer(nil)
+	buf.WriteByte('{')
+	for i, f := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, _ := json.Marshal(f.Name)
+		buf.Write(name)
+		buf.WriteByte(':')
+		val, err := json.Marshal(f.Value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(val)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func (o jsonObject) has(name string) bool {
+	for _, f := range o {
+		if f.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func renderTyped(typ, val Prim) (interface{}, error) {
+	switch typ.OpCode {
+	case T_UNIT:
+		if val.OpCode != D_UNIT {
+			return nil, renderMismatch(typ, val)
+		}
+		return jsonObject{}, nil
+
+	case T_BOOL:
+		switch val.OpCode {
+		case D_TRUE:
+			return true, nil
+		case D_FALSE:
+			return false, nil
+		}
+		return nil, renderMismatch(typ, val)
+
+	case T_INT, T_NAT, T_MUTEZ:
+		if val.Type != PrimInt {
+			return nil, renderMismatch(typ, val)
+		}
+		return val.Int.Text(10), nil
+
+	case T_STRING:
+		if val.Type != PrimString {
+			return nil, renderMismatch(typ, val)
+		}
+		return val.String, nil
+
+	case T_BYTES, T_BLS12_381_G1, T_BLS12_381_G2, T_BLS12_381_FR,
+		T_SAPLING_TRANSACTION, T_CHEST, T_CHEST_KEY:
+		if val.Type != PrimBytes {
+			return nil, renderMismatch(typ, val)
+		}
+		return "0x" + hex.EncodeToString(val.Bytes), nil
+
+	case T_TIMESTAMP:
+		tm, err := val.Time()
+		if err != nil {
+			return nil, renderMismatch(typ, val)
+		}
+		if y := tm.Year(); y < 0 || y >= 10000 {
+			return val.Int.Text(10), nil
+		}
+		return tm.UTC().Format(time.RFC3339), nil
+
+	case T_ADDRESS, T_CONTRACT, T_KEY_HASH, T_KEY, T_SIGNATURE, T_CHAIN_ID:
+		if val.Type == PrimBytes {
+			val = readableHash(typ.OpCode, val)
+		}
+		if val.Type != PrimString {
+			return nil, renderMismatch(typ, val)
+		}
+		return val.String, nil
+
+	case T_OPTION:
+		switch val.OpCode {
+		case D_NONE:
+			return nil, nil
+		case D_SOME:
+			if len(val.Args) == 1 {
+				return renderTyped(typ.Args[0], val.Args[0])
+			}
+		}
+		return nil, renderMismatch(typ, val)
+
+	case T_OR:
+		if len(val.Args) != 1 {
+			return nil, renderMismatch(typ, val)
+		}
+		var branch Prim
+		name := "left"
+		switch val.OpCode {
+		case D_LEFT:
+			branch = typ.Args[0]
+		case D_RIGHT:
+			branch, name = typ.Args[1], "right"
+		default:
+			return nil, renderMismatch(typ, val)
+		}
+		inner, err := renderTyped(branch, val.Args[0])
+		if err != nil {
+			return nil, err
+		}
+		if label := branch.GetVarAnnoAny(); label != "" {
+			name = label
+		}
+		return jsonObject{{name, inner}}, nil
+
+	case T_PAIR:
+		obj := make(jsonObject, 0, len(typ.Args))
+		if err := renderPair(typ, val, &obj); err != nil {
+			return nil, err
+		}
+		return obj, nil
+
+	case T_LIST, T_SET:
+		if !val.IsSequence() {
+			return nil, renderMismatch(typ, val)
+		}
+		arr := make([]interface{}, len(val.Args))
+		for i, v := range val.Args {
+			r, err := renderTyped(typ.Args[0], v)
+			if err != nil {
+				return nil, err
+			}
+			arr[i] = r
+		}
+		return arr, nil
+
+	case T_MAP, T_BIG_MAP:
+		if val.Type == PrimInt {
+			// bigmap reference
+			return val.Int.Text(10), nil
+		}
+		if !val.IsSequence() {
+			return nil, renderMismatch(typ, val)
+		}
+		return renderMap(typ, val)
+
+	case T_SAPLING_STATE:
+		if val.Type == PrimInt {
+			return val.Int.Text(10), nil
+		}
+		return val, nil
+
+	case T_TICKET:
+		// Pair ticketer (Pair value amount)
+		leaves := pairValueLeaves(val, 3)
+		if len(leaves) != 3 || len(typ.Args) != 1 {
+			return nil, renderMismatch(typ, val)
+		}
+		ticketer, err := renderTyped(NewPrim(T_ADDRESS), leaves[0])
+		if err != nil {
+			return nil, err
+		}
+		contents, err := renderTyped(typ.Args[0], leaves[1])
+		if err != nil {
+			return nil, err
+		}
+		amount, err := renderTyped(NewPrim(T_NAT), leaves[2])
+		if err != nil {
+			return nil, err
+		}
+		return jsonObject{
+			{"ticketer", ticketer},
+			{"value", contents},
+			{"amount", amount},
+		}, nil
+
+	default:
+		// lambdas, operations and unknown types
+		return val, nil
+	}
+}
+
+// renderPair appends pair fields to obj merging unannotated nested pairs.
+func renderPair(typ, val Prim, obj *jsonObject) error {
+	if !(val.OpCode == D_PAIR || val.IsSequence()) {
+		return renderMismatch(typ, val)
+	}
+	leaves := pairValueLeaves(val, len(typ.Args))
+	if len(leaves) != len(typ.Args) {
+		return renderMismatch(typ, val)
+	}
+	for i, t := range typ.Args {
+		if t.OpCode == T_PAIR && t.GetVarAnnoAny() == "" {
+			if err := renderPair(t, leaves[i], obj); err != nil {
+				return err
+			}
+			continue
+		}
+		r, err := renderTyped(t, leaves[i])
+		if err != nil {
+			return err
+		}
+		pos := strconv.Itoa(len(*obj))
+		name := t.GetVarAnnoAny()
+		switch {
+		case name == "":
+			name = pos
+		case obj.has(name):
+			name += "_" + pos
+		}
+		*obj = append(*obj, jsonField{name, r})
+	}
+	return nil
+}
+
+func renderMap(typ, val Prim) (interface{}, error) {
+	simple := isStringKey(typ.Args[0].OpCode)
+	obj := make(jsonObject, 0, len(val.Args))
+	arr := make([]interface{}, 0, len(val.Args))
+	for _, v := range val.Args {
+		if v.OpCode != D_ELT || len(v.Args) != 2 {
+			return nil, renderMismatch(typ, val)
+		}
+		key, err := renderTyped(typ.Args[0], v.Args[0])
+		if err != nil {
+			return nil, err
+		}
+		value, err := renderTyped(typ.Args[1], v.Args[1])
+		if err != nil {
+			return nil, err
+		}
+		if simple {
+			name, ok := key.(string)
+			if !ok {
+				name = fmt.Sprint(key)
+			}
+			obj = append(obj, jsonField{name, value})
+		} else {
+			arr = append(arr, jsonObject{{"key", key}, {"value", value}})
+		}
+	}
+	if simple {
+		return obj, nil
+	}
+	return arr, nil
+}
+
+// isStringKey returns true for map key types that render as JSON strings.
+func isStringKey(op OpCode) bool {
+	switch op {
+	case T_INT, T_NAT, T_MUTEZ, T_STRING, T_BYTES, T_BOOL, T_TIMESTAMP,
+		T_ADDRESS, T_KEY_HASH, T_KEY, T_SIGNATURE, T_CHAIN_ID:
+		return true
+	default:
+		return false
+	}
+}
+
+func renderMismatch(typ, val Prim) error {
+	return fmt.Errorf("micheline: cannot render %s as %s", val.DumpLimit(64), typ.OpCode)
+}
@@ -14,7 +14,6 @@ import (
 	"time"
 
 	"blockwatch.cc/tzgo/tezos"
-	"golang.org/x/crypto/blake2b"
 )
 
 // Comparable key as used in bigmaps and maps
@@ -366,15 +365,11 @@ func (k Key) Hash() tezos.ExprHash {
 }
 
 func KeyHash(buf []byte) tezos.ExprHash {
-	// blake2b with digest size 32 byte
-	h, _ := blake2b.New(32, nil)
-
 	// encode with pack byte
-	h.Write([]byte{0x5})
-	h.Write(buf)
-
-	// wrap in exprhash
-	return tezos.NewExprHash(h.Sum(nil))
+	packed := make([]byte, 0, len(buf)+1)
+	packed = append(packed, 0x5)
+	packed = append(packed, buf...)
+	return tezos.ComputeExprHash(packed)
 }
 
 func (k Key) String() string {
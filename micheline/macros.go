@@ -0,0 +1,660 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package micheline
+
+import (
+	"math/big"
+	"strings"
+)
+
+// Macro expansion follows the rewrite rules of the octez Michelson parser
+// including its annotation propagation. Expanded nodes are checked for nested
+// macros, e.g. FAIL inside an expanded ASSERT.
+
+type macroExpander func(*srcNode) (*srcNode, error)
+
+var macroExpanders []macroExpander
+
+func init() {
+	macroExpanders = []macroExpander{
+		expandCarN,
+		expandCaddadr,
+		expandSetCaddadr,
+		expandMapCaddadr,
+		expandDiip,
+		expandDuup,
+		expandCompare,
+		expandAsserts,
+		expandIfSomeRight,
+		expandFail,
+		expandPappaiir,
+		expandUnpappaiir,
+	}
+}
+
+func expandMacros(n *srcNode) (*srcNode, error) {
+	x, err := expandMacro(n)
+	if err != nil {
+		return nil, err
+	}
+	if len(x.args) == 0 {
+		return x, nil
+	}
+	clone := *x
+	clone.args = make([]*srcNode, len(x.args))
+	for i, v := range x.args {
+		if clone.args[i], err = expandMacros(v); err != nil {
+			return nil, err
+		}
+	}
+	return &clone, nil
+}
+
+func expandMacro(n *srcNode) (*srcNode, error) {
+	if !n.isApp() {
+		return n, nil
+	}
+	for _, fn := range macroExpanders {
+		x, err := fn(n)
+		if err != nil {
+			return nil, err
+		}
+		if x != nil {
+			return x, nil
+		}
+	}
+	return n, nil
+}
+
+// node constructors at the macro's source position
+
+func (n *srcNode) app(name string, annot []string, args ...*srcNode) *srcNode {
+	return &srcNode{typ: PrimNullary, name: name, annot: annot, args: args, line: n.line, col: n.col}
+}
+
+func (n *srcNode) seq(items ...*srcNode) *srcNode {
+	return &srcNode{typ: PrimSequence, args: items, line: n.line, col: n.col}
+}
+
+func (n *srcNode) intLit(i int64) *srcNode {
+	return &srcNode{typ: PrimInt, lit: NewInt64(i), line: n.line, col: n.col}
+}
+
+// dip wraps code into DIP like octez does, omitting the depth when it is 1.
+func (n *srcNode) dip(depth int64, code *srcNode) *srcNode {
+	if depth == 1 {
+		return n.app("DIP", nil, code)
+	}
+	return n.app("DIP", nil, n.intLit(depth), code)
+}
+
+func (n *srcNode) checkArity(want int) error {
+	if len(n.args) != want {
+		return n.errorf("macro %s expects %d arguments, got %d", n.name, want, len(n.args))
+	}
+	return nil
+}
+
+func (n *srcNode) checkNoAnnot() error {
+	if len(n.annot) > 0 {
+		return n.errorf("unexpected annotation on macro %s", n.name)
+	}
+	return nil
+}
+
+func (n *srcNode) checkDualSeq() error {
+	if err := n.checkArity(2); err != nil {
+		return err
+	}
+	if !n.args[0].isSeq() || !n.args[1].isSeq() {
+		return n.errorf("macro %s expects sequence arguments", n.name)
+	}
+	return nil
+}
+
+func annot(a ...string) []string {
+	return a
+}
+
+// checkLetters returns true when all characters of s between positions from
+// and to (inclusive) satisfy fn.
+func checkLetters(s string, from, to int, fn func(byte) bool) bool {
+	for i := from; i <= to; i++ {
+		if !fn(s[i]) {
+			return false
+		}
+	}
+	return from <= to
+}
+
+func isAD(c byte) bool {
+	return c == 'A' || c == 'D'
+}
+
+func isPAI(c byte) bool {
+	return c == 'P' || c == 'A' || c == 'I'
+}
+
+func isCmpOp(s string) bool {
+	switch s {
+	case "EQ", "NEQ", "LT", "GT", "LE", "GE":
+		return true
+	}
+	return false
+}
+
+// extractFieldAnnots splits annotations into field annotations and others.
+func extractFieldAnnots(a []string) ([]string, []string) {
+	var fields, other []string
+	for _, v := range a {
+		if strings.HasPrefix(v, "%") {
+			fields = append(fields, v)
+		} else {
+			other = append(other, v)
+		}
+	}
+	return fields, other
+}
+
+// CAR k => GET (2k+1), CDR k => GET 2k
+func expandCarN(n *srcNode) (*srcNode, error) {
+	if (n.name != "CAR" && n.name != "CDR") || len(n.args) != 1 || n.args[0].typ != PrimInt {
+		return nil, nil
+	}
+	k := new(big.Int).Lsh(n.args[0].lit.Int, 1)
+	if n.name == "CAR" {
+		k.Add(k, big.NewInt(1))
+	}
+	get := n.app("GET", n.annot, &srcNode{typ: PrimInt, lit: NewBig(k), line: n.line, col: n.col})
+	return n.seq(get), nil
+}
+
+// C[AD]+R => { CAR ; CDR ; ... }
+//
+// The last instruction receives all annotations, intermediate instructions
+// only receive @% and @%% annotations.
+func expandCaddadr(n *srcNode) (*srcNode, error) {
+	s, l := n.name, len(n.name)
+	if l <= 3 || s[0] != 'C' || s[l-1] != 'R' || !checkLetters(s, 1, l-2, isAD) {
+		return nil, nil
+	}
+	if err := n.checkArity(0); err != nil {
+		return nil, err
+	}
+	var pathAnnot []string
+	for _, v := range n.annot {
+		if v == "@%" || v == "@%%" {
+			pathAnnot = append(pathAnnot, v)
+		}
+	}
+	items := make([]*srcNode, 0, l-2)
+	for i := 1; i <= l-2; i++ {
+		a := pathAnnot
+		if i == l-2 {
+			a = n.annot
+		}
+		name := "CAR"
+		if s[i] == 'D' {
+			name = "CDR"
+		}
+		items = append(items, n.app(name, a))
+	}
+	return n.seq(items...), nil
+}
+
+// singleFieldAnnot returns the only field annotation of a SET_C[AD]+R or
+// MAP_C[AD]+R macro.
+func (n *srcNode) singleFieldAnnot() (string, error) {
+	fields, other := extractFieldAnnots(n.annot)
+	if len(other) > 0 || len(fields) > 1 {
+		return "", n.errorf("unexpected annotation on macro %s", n.name)
+	}
+	if len(fields) == 1 {
+		return fields[0], nil
+	}
+	return "", nil
+}
+
+// wrapCaddadr wraps the innermost expansion of SET_C[AD]+R and MAP_C[AD]+R
+// for the path letters in s between positions 5 and from.
+func (n *srcNode) wrapCaddadr(s string, from int, acc *srcNode) *srcNode {
+	for i := from; i >= 5; i-- {
+		if s[i] == 'A' {
+			acc = n.seq(
+				n.app("DUP", nil),
+				n.app("DIP", nil, n.seq(n.app("CAR", annot("@%%")), acc)),
+				n.app("CDR", annot("@%%")),
+				n.app("SWAP", nil),
+				n.app("PAIR", annot("%@", "%@")),
+			)
+		} else {
+			acc = n.seq(
+				n.app("DUP", nil),
+				n.app("DIP", nil, n.seq(n.app("CDR", annot("@%%")), acc)),
+				n.app("CAR", annot("@%%")),
+				n.app("PAIR", annot("%@", "%@")),
+			)
+		}
+	}
+	return acc
+}
+
+// SET_CAR => { CDR @%% ; SWAP ; PAIR % %@ }
+// SET_CDR => { CAR @%% ; PAIR %@ % }
+// SET_CA(A|D)+R => { DUP ; DIP { CAR @%% ; SET_C(A|D)+R } ; CDR @%% ; SWAP ; PAIR %@ %@ }
+// SET_CD(A|D)+R => { DUP ; DIP { CDR @%% ; SET_C(A|D)+R } ; CAR @%% ; PAIR %@ %@ }
+//
+// A field annotation is checked against the updated field.
+func expandSetCaddadr(n *srcNode) (*srcNode, error) {
+	s, l := n.name, len(n.name)
+	if l < 7 || !strings.HasPrefix(s, "SET_C") || s[l-1] != 'R' || !checkLetters(s, 5, l-2, isAD) {
+		return nil, nil
+	}
+	if err := n.checkArity(0); err != nil {
+		return nil, err
+	}
+	field, err := n.singleFieldAnnot()
+	if err != nil {
+		return nil, err
+	}
+	fieldOr := field
+	if fieldOr == "" {
+		fieldOr = "%"
+	}
+	var items []*srcNode
+	if s[l-2] == 'A' {
+		if field != "" {
+			items = append(items, n.app("DUP", nil), n.app("CAR", annot(field)), n.app("DROP", nil))
+		}
+		items = append(items,
+			n.app("CDR", annot("@%%")),
+			n.app("SWAP", nil),
+			n.app("PAIR", annot(fieldOr, "%@")),
+		)
+	} else {
+		if field != "" {
+			items = append(items, n.app("DUP", nil), n.app("CDR", annot(field)), n.app("DROP", nil))
+		}
+		items = append(items,
+			n.app("CAR", annot("@%%")),
+			n.app("PAIR", annot("%@", fieldOr)),
+		)
+	}
+	return n.wrapCaddadr(s, l-3, n.seq(items...)), nil
+}
+
+// MAP_CAR code => { DUP ; CDR @%% ; DIP { CAR ; code } ; SWAP ; PAIR % %@ }
+// MAP_CDR code => { DUP ; CDR ; code ; SWAP ; CAR @%% ; PAIR %@ % }
+// MAP_CA(A|D)+R code => { DUP ; DIP { CAR @%% ; MAP_C(A|D)+R code } ; CDR @%% ; SWAP ; PAIR %@ %@ }
+// MAP_CD(A|D)+R code => { DUP ; DIP { CDR @%% ; MAP_C(A|D)+R code } ; CAR @%% ; PAIR %@ %@ }
+//
+// A field annotation names the mapped value.
+func expandMapCaddadr(n *srcNode) (*srcNode, error) {
+	s, l := n.name, len(n.name)
+	if l < 7 || !strings.HasPrefix(s, "MAP_C") || s[l-1] != 'R' || !checkLetters(s, 5, l-2, isAD) {
+		return nil, nil
+	}
+	if err := n.checkArity(1); err != nil {
+		return nil, err
+	}
+	code := n.args[0]
+	if !code.isSeq() {
+		return nil, n.errorf("macro %s expects a sequence argument", n.name)
+	}
+	field, err := n.singleFieldAnnot()
+	if err != nil {
+		return nil, err
+	}
+	fieldOr := field
+	var crAnnot []string
+	if fieldOr == "" {
+		fieldOr = "%"
+	} else {
+		crAnnot = annot("@" + field[1:])
+	}
+	var init *srcNode
+	if s[l-2] == 'A' {
+		init = n.seq(
+			n.app("DUP", nil),
+			n.app("CDR", annot("@%%")),
+			n.app("DIP", nil, n.seq(n.app("CAR", crAnnot), code)),
+			n.app("SWAP", nil),
+			n.app("PAIR", annot(fieldOr, "%@")),
+		)
+	} else {
+		init = n.seq(
+			n.app("DUP", nil),
+			n.app("CDR", crAnnot),
+			code,
+			n.app("SWAP", nil),
+			n.app("CAR", annot("@%%")),
+			n.app("PAIR", annot("%@", fieldOr)),
+		)
+	}
+	return n.wrapCaddadr(s, l-3, init), nil
+}
+
+// DI(I+)P code => DIP n code
+func expandDiip(n *srcNode) (*srcNode, error) {
+	s, l := n.name, len(n.name)
+	if l <= 3 || s[0] != 'D' || s[l-1] != 'P' || !checkLetters(s, 1, l-2, func(c byte) bool { return c == 'I' }) {
+		return nil, nil
+	}
+	if err := n.checkArity(1); err != nil {
+		return nil, err
+	}
+	if !n.args[0].isSeq() {
+		return nil, n.errorf("macro %s expects a sequence argument", n.name)
+	}
+	return n.app("DIP", n.annot, n.intLit(int64(l-2)), n.args[0]), nil
+}
+
+// DU(U+)P => { DUP n }
+func expandDuup(n *srcNode) (*srcNode, error) {
+	s, l := n.name, len(n.name)
+	if l <= 3 || s[0] != 'D' || s[l-1] != 'P' || !checkLetters(s, 1, l-2, func(c byte) bool { return c == 'U' }) {
+		return nil, nil
+	}
+	if err := n.checkArity(0); err != nil {
+		return nil, err
+	}
+	return n.seq(n.app("DUP", n.annot, n.intLit(int64(l-2)))), nil
+}
+
+// CMP{OP} => { COMPARE ; OP }
+// IF{OP} bt bf => { OP ; IF bt bf }
+// IFCMP{OP} bt bf => { COMPARE ; OP ; IF bt bf }
+func expandCompare(n *srcNode) (*srcNode, error) {
+	s := n.name
+	switch {
+	case strings.HasPrefix(s, "CMP") && isCmpOp(s[3:]):
+		if err := n.checkArity(0); err != nil {
+			return nil, err
+		}
+		return n.seq(n.app("COMPARE", nil), n.app(s[3:], n.annot)), nil
+	case strings.HasPrefix(s, "IFCMP") && isCmpOp(s[5:]):
+		if err := n.checkDualSeq(); err != nil {
+			return nil, err
+		}
+		return n.seq(
+			n.app("COMPARE", nil),
+			n.app(s[5:], nil),
+			n.app("IF", n.annot, n.args...),
+		), nil
+	case strings.HasPrefix(s, "IF") && isCmpOp(s[2:]):
+		if err := n.checkDualSeq(); err != nil {
+			return nil, err
+		}
+		return n.seq(n.app(s[2:], nil), n.app("IF", n.annot, n.args...)), nil
+	}
+	return nil, nil
+}
+
+// ASSERT => { IF {} { FAIL } }
+// ASSERT_{OP} => { OP ; IF {} { FAIL } }
+// ASSERT_CMP{OP} => { { COMPARE ; OP } ; IF {} { FAIL } }
+// ASSERT_NONE => { IF_NONE {} { FAIL } }
+// ASSERT_SOME @x => { IF_NONE { FAIL } { RENAME @x } }
+// ASSERT_LEFT @x => { IF_LEFT { RENAME @x } { FAIL } }
+// ASSERT_RIGHT @x => { IF_LEFT { FAIL } { RENAME @x } }
+func expandAsserts(n *srcNode) (*srcNode, error) {
+	s := n.name
+	if !strings.HasPrefix(s, "ASSERT") {
+		return nil, nil
+	}
+	rename := func(a []string) *srcNode {
+		if len(a) == 0 {
+			return n.seq()
+		}
+		return n.seq(n.app("RENAME", a))
+	}
+	fail := func() *srcNode {
+		return n.seq(n.app("FAIL", nil))
+	}
+	switch s {
+	case "ASSERT", "ASSERT_NONE":
+		if err := n.checkArity(0); err != nil {
+			return nil, err
+		}
+		if err := n.checkNoAnnot(); err != nil {
+			return nil, err
+		}
+		op := "IF"
+		if s == "ASSERT_NONE" {
+			op = "IF_NONE"
+		}
+		return n.seq(n.app(op, nil, rename(nil), fail())), nil
+	case "ASSERT_SOME":
+		if err := n.checkArity(0); err != nil {
+			return nil, err
+		}
+		return n.seq(n.app("IF_NONE", nil, fail(), rename(n.annot))), nil
+	case "ASSERT_LEFT":
+		if err := n.checkArity(0); err != nil {
+			return nil, err
+		}
+		return n.seq(n.app("IF_LEFT", nil, rename(n.annot), fail())), nil
+	case "ASSERT_RIGHT":
+		if err := n.checkArity(0); err != nil {
+			return nil, err
+		}
+		return n.seq(n.app("IF_LEFT", nil, fail(), rename(n.annot))), nil
+	}
+	if len(s) <= 7 || s[6] != '_' {
+		return nil, nil
+	}
+	rest := s[7:]
+	var test *srcNode
+	switch {
+	case isCmpOp(rest):
+		test = n.app(rest, nil)
+	case strings.HasPrefix(rest, "CMP") && isCmpOp(rest[3:]):
+		test, _ = expandCompare(n.app(rest, nil))
+	default:
+		return nil, nil
+	}
+	if err := n.checkArity(0); err != nil {
+		return nil, err
+	}
+	if err := n.checkNoAnnot(); err != nil {
+		return nil, err
+	}
+	return n.seq(test, n.app("IF", nil, rename(nil), fail())), nil
+}
+
+// IF_SOME bt bf => { IF_NONE bf bt }
+// IF_RIGHT bt bf => { IF_LEFT bf bt }
+func expandIfSomeRight(n *srcNode) (*srcNode, error) {
+	var op string
+	switch n.name {
+	case "IF_SOME":
+		op = "IF_NONE"
+	case "IF_RIGHT":
+		op = "IF_LEFT"
+	default:
+		return nil, nil
+	}
+	if err := n.checkDualSeq(); err != nil {
+		return nil, err
+	}
+	return n.seq(n.app(op, n.annot, n.args[1], n.args[0])), nil
+}
+
+// FAIL => { UNIT ; FAILWITH }
+func expandFail(n *srcNode) (*srcNode, error) {
+	if n.name != "FAIL" {
+		return nil, nil
+	}
+	if err := n.checkArity(0); err != nil {
+		return nil, err
+	}
+	if err := n.checkNoAnnot(); err != nil {
+		return nil, err
+	}
+	return n.seq(n.app("UNIT", nil), n.app("FAILWITH", nil)), nil
+}
+
+// pairItem is a node of a parsed P[AIP]+R macro name.
+type pairItem struct {
+	pos         int // position of P in the macro name
+	leaf        byte
+	left, right *pairItem
+}
+
+// parsePairMacro parses the pair structure of a P[AIP]+R name starting with
+// the P at position start. The name must end in R right after the structure.
+func parsePairMacro(s string, start int) (*pairItem, bool) {
+	var parse func(i int, left bool) (int, *pairItem, bool)
+	parse = func(i int, left bool) (int, *pairItem, bool) {
+		if i >= len(s)-1 {
+			return i, nil, false
+		}
+		switch {
+		case s[i] == 'P':
+			next, l, ok := parse(i+1, true)
+			if !ok {
+				return i, nil, false
+			}
+			next, r, ok := parse(next, false)
+			if !ok {
+				return i, nil, false
+			}
+			return next, &pairItem{pos: i, left: l, right: r}, true
+		case s[i] == 'A' && left:
+			return i + 1, &pairItem{leaf: 'A'}, true
+		case s[i] == 'I' && !left:
+			return i + 1, &pairItem{leaf: 'I'}, true
+		}
+		return i, nil, false
+	}
+	last, item, ok := parse(start, false)
+	if !ok || last != len(s)-1 {
+		return nil, false
+	}
+	return item, true
+}
+
+// PA(\right)R => { DIP ((\right)R) ; PAIR }
+// P(\left)IR => { (\left)R ; PAIR }
+// P(\left)(\right)R => { (\left)R ; DIP ((\right)R) ; PAIR }
+//
+// Field annotations are assigned to the leaves in order, other annotations
+// apply to the outermost PAIR.
+func expandPappaiir(n *srcNode) (*srcNode, error) {
+	s, l := n.name, len(n.name)
+	if l <= 4 || s[0] != 'P' || s[l-1] != 'R' || !checkLetters(s, 1, l-2, isPAI) {
+		return nil, nil
+	}
+	ast, ok := parsePairMacro(s, 0)
+	if !ok {
+		return nil, nil
+	}
+	if err := n.checkArity(0); err != nil {
+		return nil, err
+	}
+	fields, other := extractFieldAnnots(n.annot)
+
+	// assign field annotations to the car and cdr of their parent pair
+	type carCdr struct{ car, cdr []string }
+	pos := make(map[int]*carCdr)
+	var assign func(parent int, p *pairItem)
+	assign = func(parent int, p *pairItem) {
+		if len(fields) == 0 {
+			return
+		}
+		switch p.leaf {
+		case 0:
+			assign(p.pos, p.left)
+			assign(p.pos, p.right)
+		case 'A':
+			if pos[parent] == nil {
+				pos[parent] = &carCdr{}
+			}
+			pos[parent].car = fields[:1]
+			fields = fields[1:]
+		case 'I':
+			if pos[parent] == nil {
+				pos[parent] = &carCdr{}
+			}
+			pos[parent].cdr = fields[:1]
+			fields = fields[1:]
+		}
+	}
+	assign(0, ast)
+
+	var (
+		items []*srcNode
+		depth int64
+		walk  func(p *pairItem)
+	)
+	walk = func(p *pairItem) {
+		if p.leaf != 0 {
+			depth++
+			return
+		}
+		var a []string
+		if cc, ok := pos[p.pos]; ok {
+			if len(cc.car) == 0 {
+				a = append(a, "%")
+			}
+			a = append(a, cc.car...)
+			a = append(a, cc.cdr...)
+		}
+		if p.pos == 0 {
+			a = append(a, other...)
+		}
+		pair := n.app("PAIR", a)
+		if depth > 0 {
+			pair = n.dip(depth, n.seq(pair))
+		}
+		items = append(items, pair)
+		walk(p.left)
+		walk(p.right)
+	}
+	walk(ast)
+
+	// pairs are built from the innermost to the outermost
+	for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+		items[i], items[j] = items[j], items[i]
+	}
+	return n.seq(items...), nil
+}
+
+// UNP(\left)(\right)R => { UNPAIR ; (\left)R ; DIP ((\right)R) }
+func expandUnpappaiir(n *srcNode) (*srcNode, error) {
+	s, l := n.name, len(n.name)
+	if l <= 6 || !strings.HasPrefix(s, "UNP") || s[l-1] != 'R' || !checkLetters(s, 3, l-2, isPAI) {
+		return nil, nil
+	}
+	ast, ok := parsePairMacro(s, 2)
+	if !ok {
+		return nil, nil
+	}
+	if err := n.checkArity(0); err != nil {
+		return nil, err
+	}
+	if err := n.checkNoAnnot(); err != nil {
+		return nil, err
+	}
+	var (
+		items []*srcNode
+		depth int64
+		walk  func(p *pairItem)
+	)
+	walk = func(p *pairItem) {
+		if p.leaf != 0 {
+			depth++
+			return
+		}
+		unpair := n.app("UNPAIR", nil)
+		if depth > 0 {
+			unpair = n.dip(depth, n.seq(unpair))
+		}
+		items = append(items, unpair)
+		walk(p.left)
+		walk(p.right)
+	}
+	walk(ast)
+	return n.seq(items...), nil
+}
@@ -0,0 +1,261 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package micheline
+
+import (
+	"strings"
+	"time"
+
+	"blockwatch.cc/tzgo/tezos"
+)
+
+// NormalizeMode selects the representation produced by Normalize. Values match
+// the unparsing modes of Tezos nodes.
+type NormalizeMode string
+
+const (
+	NormalizeReadable  NormalizeMode = "Readable"         // base58 strings, RFC3339 timestamps, comb pairs
+	NormalizeOptimized NormalizeMode = "Optimized"        // bytes, integer timestamps, long combs as sequence
+	NormalizeLegacy    NormalizeMode = "Optimized_legacy" // like optimized with binary pairs only
+)
+
+// Normalize converts value val of type typ into the representation a node
+// returns for unparsing mode mode. Pairs are rewritten into the mode's comb form
+// and addresses, keys, key hashes, signatures, chain ids and timestamps are
+// converted between their readable and optimized encodings. Values that do not
+// match typ are returned unchanged.
+func Normalize(typ, val Prim, mode NormalizeMode) Prim {
+	return normalize(typ, val, mode, false)
+}
+
+// Decorate converts optimized leaves of value p with type typ into readable
+// form, i.e. address, key_hash, key, signature and chain_id bytes become base58
+// strings (addresses keep an %entrypoint suffix) and integer timestamps become
+// RFC3339 strings. Unlike Normalize the pair structure of p is kept.
+func (p Prim) Decorate(typ Prim) Prim {
+	return normalize(typ, p, NormalizeReadable, true)
+}
+
+// Optimize is the reverse of Decorate and converts readable leaves of value p
+// with type typ into their optimized binary form as used for packing and
+// computing bigmap key hashes. The pair structure of p is kept.
+func (p Prim) Optimize(typ Prim) Prim {
+	return normalize(typ, p, NormalizeOptimized, true)
+}
+
+func normalize(typ, val Prim, mode NormalizeMode, keep bool) Prim {
+	switch typ.OpCode {
+	case T_PAIR:
+		types := combLeaves(typ, false)
+		if len(types) < 2 || !(val.OpCode == D_PAIR || val.IsSequence()) {
+			return val
+		}
+		if keep {
+			return normalizeKeepPair(types, val, mode)
+		}
+		leaves := pairValueLeaves(val, len(types))
+		if len(leaves) != len(types) {
+			return val
+		}
+		for i := range leaves {
+			leaves[i] = normalize(types[i], leaves[i], mode, keep)
+		}
+		return normalizePair(leaves, mode)
+
+	case T_OPTION:
+		if val.OpCode == D_SOME && len(val.Args) == 1 && len(typ.Args) == 1 {
+			val.Args = []Prim{normalize(typ.Args[0], val.Args[0], mode, keep)}
+		}
+
+	case T_OR:
+		if len(val.Args) == 1 && len(typ.Args) == 2 {
+			switch val.OpCode {
+			case D_LEFT:
+				val.Args = []Prim{normalize(typ.Args[0], val.Args[0], mode, keep)}
+			case D_RIGHT:
+				val.Args = []Prim{normalize(typ.Args[1], val.Args[0], mode, keep)}
+			}
+		}
+
+	case T_LIST, T_SET:
+		if val.IsSequence() && len(typ.Args) == 1 {
+			args := make([]Prim, len(val.Args))
+			for i, v := range val.Args {
+				args[i] = normalize(typ.Args[0], v, mode, keep)
+			}
+			val.Args = args
+		}
+
+	case T_MAP, T_BIG_MAP:
+		if val.IsSequence() && len(typ.Args) == 2 {
+			args := make([]Prim, len(val.Args))
+			for i, v := range val.Args {
+				if v.OpCode == D_ELT && len(v.Args) == 2 {
+					v.Args = []Prim{
+						normalize(typ.Args[0], v.Args[0], mode, keep),
+						normalize(typ.Args[1], v.Args[1], mode, keep),
+					}
+				}
+				args[i] = v
+			}
+			val.Args = args
+		}
+
+	case T_TIMESTAMP:
+		return normalizeTime(val, mode)
+
+	case T_ADDRESS, T_CONTRACT, T_KEY_HASH, T_KEY, T_SIGNATURE, T_CHAIN_ID:
+		if mode == NormalizeReadable {
+			return readableHash(typ.OpCode, val)
+		}
+		return optimizedHash(typ.OpCode, val)
+	}
+	return val
+}
+
+// normalizeKeepPair converts the leaves of pair value val in nested, comb or
+// sequence form without changing its structure. The last argument of val
+// covers all remaining comb leaves types.
+func normalizeKeepPair(types []Prim, val Prim, mode NormalizeMode) Prim {
+	n := len(val.Args)
+	if n < 2 || n > len(types) {
+		return val
+	}
+	args := make([]Prim, n)
+	for i := 0; i < n-1; i++ {
+		args[i] = normalize(types[i], val.Args[i], mode, true)
+	}
+	last := types[n-1]
+	if len(types) > n {
+		last = Prim{
+			Type:   pairPrimType(len(types)-n+1, false),
+			OpCode: T_PAIR,
+			Args:   types[n-1:],
+		}
+	}
+	args[n-1] = normalize(last, val.Args[n-1], mode, true)
+	val.Args = args
+	return val
+}
+
+// normalizePair builds a pair from comb leaves in the form used by mode.
+func normalizePair(leaves []Prim, mode NormalizeMode) Prim {
+	switch {
+	case mode == NormalizeReadable:
+		return Prim{
+			Type:   pairPrimType(len(leaves), false),
+			OpCode: D_PAIR,
+			Args:   leaves,
+		}
+	case mode != NormalizeLegacy && len(leaves) >= 4:
+		return NewSeq(leaves...)
+	default:
+		return Prim{
+			Type:   PrimBinary,
+			OpCode: D_PAIR,
+			Args:   nestPair(D_PAIR, leaves),
+		}
+	}
+}
+
+func normalizeTime(val Prim, mode NormalizeMode) Prim {
+	switch {
+	case mode == NormalizeReadable && val.Type == PrimInt:
+		tm := time.Unix(val.Int.Int64(), 0).UTC()
+		if !val.Int.IsInt64() || tm.Year() < 0 || tm.Year() >= 10000 {
+			return val
+		}
+		return NewString(tm.Format(time.RFC3339))
+	case mode != NormalizeReadable && val.Type == PrimString:
+		tm, err := time.Parse(time.RFC3339, val.String)
+		if err != nil {
+			return val
+		}
+		return NewInt64(tm.Unix())
+	}
+	return val
+}
+
+// readableHash converts optimized bytes into a base58 string.
+func readableHash(typ OpCode, val Prim) Prim {
+	if val.Type != PrimBytes {
+		return val
+	}
+	switch typ {
+	case T_ADDRESS, T_CONTRACT, T_KEY_HASH:
+		var a tezos.Address
+		if err := a.UnmarshalBinary(val.Bytes); err != nil {
+			return val
+		}
+		s := a.String()
+		if typ != T_KEY_HASH && len(val.Bytes) > 22 {
+			s += "%" + string(val.Bytes[22:])
+		}
+		return NewString(s)
+	case T_KEY:
+		var k tezos.Key
+		if err := k.UnmarshalBinary(val.Bytes); err != nil {
+			return val
+		}
+		return NewString(k.String())
+	case T_SIGNATURE:
+		// untagged generic or BLS12-381 signature
+		decode := tezos.DecodeGenericSignature
+		if len(val.Bytes) == tezos.SignatureTypeBls12_381.Len() {
+			decode = tezos.DecodeBlsSignature
+		}
+		s, n, err := decode(val.Bytes)
+		if err != nil || n != len(val.Bytes) {
+			return val
+		}
+		return NewString(s.String())
+	case T_CHAIN_ID:
+		if len(val.Bytes) != tezos.HashTypeChainId.Len() {
+			return val
+		}
+		return NewString(tezos.NewChainIdHash(val.Bytes).String())
+	}
+	return val
+}
+
+// optimizedHash converts a base58 string into optimized bytes.
+func optimizedHash(typ OpCode, val Prim) Prim {
+	if val.Type != PrimString {
+		return val
+	}
+	switch typ {
+	case T_ADDRESS, T_CONTRACT, T_KEY_HASH:
+		s, ep := val.String, ""
+		if i := strings.IndexByte(s, '%'); i >= 0 && typ != T_KEY_HASH {
+			s, ep = s[:i], s[i+1:]
+		}
+		a, err := tezos.ParseAddress(s)
+		if err != nil {
+			return val
+		}
+		if typ == T_KEY_HASH {
+			return NewBytes(a.Bytes())
+		}
+		return NewBytes(append(a.Bytes22(), ep...))
+	case T_KEY:
+		k, err := tezos.ParseKey(val.String)
+		if err != nil {
+			return val
+		}
+		return NewBytes(k.Bytes())
+	case T_SIGNATURE:
+		s, err := tezos.ParseSignature(val.String)
+		if err != nil {
+			return val
+		}
+		return NewBytes(s.Data)
+	case T_CHAIN_ID:
+		h, err := tezos.ParseChainIdHash(val.String)
+		if err != nil {
+			return val
+		}
+		return NewBytes(h.Bytes())
+	}
+	return val
+}
@@ -142,6 +142,16 @@ func (p Parameters) EncodeBuffer(buf *bytes.Buffer) error {
 		buf.WriteByte(3)
 	case "remove_delegate":
 		buf.WriteByte(4)
+	case "deposit":
+		buf.WriteByte(5)
+	case "stake":
+		buf.WriteByte(6)
+	case "unstake":
+		buf.WriteByte(7)
+	case "finalize_unstake":
+		buf.WriteByte(8)
+	case "set_delegate_parameters":
+		buf.WriteByte(9)
 	default:
 		buf.WriteByte(255)
 		buf.WriteByte(byte(len(p.Entrypoint)))
@@ -167,8 +177,8 @@ func (p *Parameters) UnmarshalJSON(data []byte) error {
 		return json.Unmarshal(data, &p.Value)
 	} else {
 		// try entrypoint calling convention
-		type alias *Parameters
-		if err := json.Unmarshal(data, alias(p)); err != nil {
+		type alias Parameters
+		if err := json.Unmarshal(data, (*alias)(p)); err != nil {
 			return err
 		}
 		if p.Value.IsValid() {
@@ -199,6 +209,16 @@ func (p *Parameters) DecodeBuffer(buf *bytes.Buffer) error {
 		p.Entrypoint = "set_delegate"
 	case 4:
 		p.Entrypoint = "remove_delegate"
+	case 5:
+		p.Entrypoint = "deposit"
+	case 6:
+		p.Entrypoint = "stake"
+	case 7:
+		p.Entrypoint = "unstake"
+	case 8:
+		p.Entrypoint = "finalize_unstake"
+	case 9:
+		p.Entrypoint = "set_delegate_parameters"
 	default:
 		sz := buf.Next(1)
 		if len(sz) == 0 || buf.Len() < int(sz[0]) {
@@ -0,0 +1,30 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package micheline
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParametersJSON(t *testing.T) {
+	for _, v := range []struct {
+		raw        string
+		entrypoint string
+		value      Prim
+	}{
+		{`{"entrypoint":"stake","value":{"prim":"Unit"}}`, "stake", NewCode(D_UNIT)},
+		{`{"entrypoint":"transfer","value":{"int":"5"}}`, "transfer", NewInt64(5)},
+		{`{"prim":"Unit"}`, "default", NewCode(D_UNIT)},
+	} {
+		var p Parameters
+		if err := json.Unmarshal([]byte(v.raw), &p); err != nil {
+			t.Errorf("%s: %v", v.raw, err)
+			continue
+		}
+		if p.Entrypoint != v.entrypoint || !p.Value.IsEqual(v.value) {
+			t.Errorf("%s: unexpected entrypoint %q value %s", v.raw, p.Entrypoint, p.Value.Dump())
+		}
+	}
+}
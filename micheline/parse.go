@@ -0,0 +1,448 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package micheline
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// ParseOptions controls how Michelson source text is parsed.
+type ParseOptions struct {
+	// ExpandMacros rewrites macros like CMPEQ, IF_SOME, ASSERT_SOME, DUUP,
+	// DIIP, PAPPAIIR, UNPAPPAIIR, CADR, SET_CADR and MAP_CADR into primitive
+	// instructions following the octez expansion and annotation rules. When
+	// unset macros are rejected as unknown primitives.
+	ExpandMacros bool
+}
+
+// ParseMichelson parses Michelson source text in strict mode. The source may
+// contain a single expression like `Pair 1 "a"` or `{ DUP ; CAR }` or a
+// script with semicolon separated toplevel sections which is returned as
+// sequence.
+func ParseMichelson(src string) (Prim, error) {
+	return ParseMichelsonWithOptions(src, ParseOptions{})
+}
+
+// ParseMichelsonWithOptions parses Michelson source text with options opts.
+func ParseMichelsonWithOptions(src string, opts ParseOptions) (Prim, error) {
+	p := &parser{lex: lexer{src: src, line: 1, col: 1}}
+	if err := p.next(); err != nil {
+		return InvalidPrim, err
+	}
+	items, semi, err := p.parseSeqBody(tokEOF)
+	if err != nil {
+		return InvalidPrim, err
+	}
+	root := &srcNode{typ: PrimSequence, args: items, line: 1, col: 1}
+	if len(items) == 1 && !semi {
+		root = items[0]
+	}
+	if opts.ExpandMacros {
+		if root, err = expandMacros(root); err != nil {
+			return InvalidPrim, err
+		}
+	}
+	return root.prim()
+}
+
+// srcNode is a parsed Michelson node with primitive names kept as text so
+// that macros can be expanded before names are resolved to opcodes.
+type srcNode struct {
+	typ       PrimType // PrimInt, PrimString, PrimBytes, PrimSequence or PrimNullary for applications
+	name      string
+	annot     []string
+	args      []*srcNode
+	lit       Prim
+	line, col int
+}
+
+func (n *srcNode) isSeq() bool {
+	return n.typ == PrimSequence
+}
+
+func (n *srcNode) isApp() bool {
+	return n.typ == PrimNullary
+}
+
+func (n *srcNode) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("micheline: line %d col %d: %s", n.line, n.col, fmt.Sprintf(format, args...))
+}
+
+func (n *srcNode) prim() (Prim, error) {
+	switch n.typ {
+	case PrimInt, PrimString, PrimBytes:
+		return n.lit, nil
+	}
+	args := make([]Prim, len(n.args))
+	for i, v := range n.args {
+		p, err := v.prim()
+		if err != nil {
+			return InvalidPrim, err
+		}
+		args[i] = p
+	}
+	if n.isSeq() {
+		return NewSeq(args...), nil
+	}
+	op, err := ParseOpCode(n.name)
+	if err != nil {
+		return InvalidPrim, n.errorf("unknown primitive %s", n.name)
+	}
+	typ := PrimNullary
+	switch len(args) {
+	case 0:
+	case 1:
+		typ = PrimUnary
+	case 2:
+		typ = PrimBinary
+	default:
+		typ = PrimVariadicAnno
+	}
+	if len(n.annot) > 0 && typ != PrimVariadicAnno {
+		typ++
+	}
+	p := Prim{Type: typ, OpCode: op, Args: args}
+	if len(n.annot) > 0 {
+		p.Anno = n.annot
+	}
+	if len(args) == 0 {
+		p.Args = nil
+	}
+	return p, nil
+}
+
+type parser struct {
+	lex lexer
+	tok token
+}
+
+func (p *parser) next() error {
+	tok, err := p.lex.next()
+	if err != nil {
+		return err
+	}
+	p.tok = tok
+	return nil
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("micheline: line %d col %d: %s", p.tok.line, p.tok.col, fmt.Sprintf(format, args...))
+}
+
+// parseSeqBody parses semicolon separated applications up to token end. It
+// reports whether any separator was present.
+func (p *parser) parseSeqBody(end tokenType) ([]*srcNode, bool, error) {
+	items := make([]*srcNode, 0)
+	var semi bool
+	for {
+		switch p.tok.typ {
+		case end:
+			return items, semi, nil
+		case tokSemi:
+			return nil, false, p.errorf("unexpected ;")
+		}
+		item, err := p.parseApp()
+		if err != nil {
+			return nil, false, err
+		}
+		items = append(items, item)
+		switch p.tok.typ {
+		case end:
+			return items, semi, nil
+		case tokSemi:
+			semi = true
+			if err := p.next(); err != nil {
+				return nil, false, err
+			}
+		default:
+			return nil, false, p.errorf("unexpected %s", p.tok)
+		}
+	}
+}
+
+// parseApp parses a primitive application with annotations and arguments or
+// a single argument.
+func (p *parser) parseApp() (*srcNode, error) {
+	if p.tok.typ != tokIdent {
+		return p.parseArg()
+	}
+	n := &srcNode{typ: PrimNullary, name: p.tok.text, line: p.tok.line, col: p.tok.col}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	for p.tok.typ == tokAnnot {
+		n.annot = append(n.annot, p.tok.text)
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+	}
+	for p.tok.isArg() {
+		arg, err := p.parseArg()
+		if err != nil {
+			return nil, err
+		}
+		n.args = append(n.args, arg)
+	}
+	return n, nil
+}
+
+func (p *parser) parseArg() (*srcNode, error) {
+	tok := p.tok
+	n := &srcNode{line: tok.line, col: tok.col}
+	switch tok.typ {
+	case tokInt:
+		i, ok := new(big.Int).SetString(tok.text, 10)
+		if !ok {
+			return nil, p.errorf("invalid int %s", tok.text)
+		}
+		n.typ, n.lit = PrimInt, NewBig(i)
+	case tokString:
+		n.typ, n.lit = PrimString, NewString(tok.text)
+	case tokBytes:
+		b, err := hex.DecodeString(tok.text[2:])
+		if err != nil {
+			return nil, p.errorf("invalid bytes %s", tok.text)
+		}
+		n.typ, n.lit = PrimBytes, NewBytes(b)
+	case tokIdent:
+		n.typ, n.name = PrimNullary, tok.text
+	case tokLParen:
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		if p.tok.typ != tokIdent {
+			return nil, p.errorf("expected primitive, got %s", p.tok)
+		}
+		app, err := p.parseApp()
+		if err != nil {
+			return nil, err
+		}
+		if p.tok.typ != tokRParen {
+			return nil, p.errorf("expected ), got %s", p.tok)
+		}
+		n = app
+	case tokLBrace:
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		items, _, err := p.parseSeqBody(tokRBrace)
+		if err != nil {
+			return nil, err
+		}
+		if p.tok.typ != tokRBrace {
+			return nil, p.errorf("expected }, got %s", p.tok)
+		}
+		n.typ, n.args = PrimSequence, items
+	default:
+		return nil, p.errorf("unexpected %s", tok)
+	}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+type tokenType byte
+
+const (
+	tokEOF tokenType = iota
+	tokInt
+	tokString
+	tokBytes
+	tokIdent
+	tokAnnot
+	tokLBrace
+	tokRBrace
+	tokLParen
+	tokRParen
+	tokSemi
+)
+
+type token struct {
+	typ       tokenType
+	text      string
+	line, col int
+}
+
+func (t token) isArg() bool {
+	switch t.typ {
+	case tokInt, tokString, tokBytes, tokIdent, tokLParen, tokLBrace:
+		return true
+	}
+	return false
+}
+
+func (t token) String() string {
+	switch t.typ {
+	case tokEOF:
+		return "end of input"
+	case tokString:
+		return fmt.Sprintf("string %q", t.text)
+	default:
+		return fmt.Sprintf("%q", t.text)
+	}
+}
+
+type lexer struct {
+	src       string
+	pos       int
+	line, col int
+}
+
+func (l *lexer) peek(n int) byte {
+	if l.pos+n < len(l.src) {
+		return l.src[l.pos+n]
+	}
+	return 0
+}
+
+func (l *lexer) advance(n int) {
+	for i := 0; i < n && l.pos < len(l.src); i++ {
+		if l.src[l.pos] == '\n' {
+			l.line++
+			l.col = 1
+		} else {
+			l.col++
+		}
+		l.pos++
+	}
+}
+
+func (l *lexer) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("micheline: line %d col %d: %s", l.line, l.col, fmt.Sprintf(format, args...))
+}
+
+func (l *lexer) skipSpace() error {
+	for l.pos < len(l.src) {
+		switch c := l.src[l.pos]; {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			l.advance(1)
+		case c == '#':
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' {
+				l.advance(1)
+			}
+		case c == '/' && l.peek(1) == '*':
+			end := strings.Index(l.src[l.pos+2:], "*/")
+			if end < 0 {
+				return l.errorf("unterminated comment")
+			}
+			l.advance(end + 4)
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+func (l *lexer) next() (token, error) {
+	if err := l.skipSpace(); err != nil {
+		return token{}, err
+	}
+	tok := token{line: l.line, col: l.col}
+	if l.pos >= len(l.src) {
+		return tok, nil
+	}
+	start := l.pos
+	switch c := l.src[l.pos]; {
+	case c == '{' || c == '}' || c == '(' || c == ')' || c == ';':
+		tok.typ = map[byte]tokenType{
+			'{': tokLBrace,
+			'}': tokRBrace,
+			'(': tokLParen,
+			')': tokRParen,
+			';': tokSemi,
+		}[c]
+		l.advance(1)
+	case c == '0' && l.peek(1) == 'x':
+		l.advance(2)
+		for isHexDigit(l.peek(0)) {
+			l.advance(1)
+		}
+		tok.typ = tokBytes
+	case isDigit(c) || (c == '-' && isDigit(l.peek(1))):
+		l.advance(1)
+		for isDigit(l.peek(0)) {
+			l.advance(1)
+		}
+		tok.typ = tokInt
+	case c == '"':
+		s, err := l.scanString()
+		if err != nil {
+			return tok, err
+		}
+		tok.typ, tok.text = tokString, s
+		return tok, nil
+	case c == '@' || c == '%' || c == ':':
+		l.advance(1)
+		for isAnnotChar(l.peek(0)) {
+			l.advance(1)
+		}
+		tok.typ = tokAnnot
+	case isLetter(c) || c == '_':
+		for isLetter(l.peek(0)) || isDigit(l.peek(0)) || l.peek(0) == '_' {
+			l.advance(1)
+		}
+		tok.typ = tokIdent
+	default:
+		return tok, l.errorf("unexpected character %q", c)
+	}
+	tok.text = l.src[start:l.pos]
+	return tok, nil
+}
+
+func (l *lexer) scanString() (string, error) {
+	var b strings.Builder
+	l.advance(1)
+	for {
+		if l.pos >= len(l.src) {
+			return "", l.errorf("unterminated string")
+		}
+		c := l.src[l.pos]
+		switch c {
+		case '"':
+			l.advance(1)
+			return b.String(), nil
+		case '\n', '\r':
+			return "", l.errorf("newline in string")
+		case '\\':
+			switch l.peek(1) {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r':
+				b.WriteByte('\r')
+			case 'b':
+				b.WriteByte('\b')
+			case '\\', '"':
+				b.WriteByte(l.peek(1))
+			default:
+				return "", l.errorf("invalid escape sequence in string")
+			}
+			l.advance(2)
+		default:
+			b.WriteByte(c)
+			l.advance(1)
+		}
+	}
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isHexDigit(c byte) bool {
+	return isDigit(c) || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+func isAnnotChar(c byte) bool {
+	return isLetter(c) || isDigit(c) || c == '_' || c == '.' || c == '%' || c == '@'
+}
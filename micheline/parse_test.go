@@ -0,0 +1,155 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package micheline
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestParseMichelson(t *testing.T) {
+	src := `# minimal contract
+parameter (or (nat %add) (unit %reset)) ;
+storage (pair (nat %total) (string %name)) ;
+code { UNPAIR ; /* dispatch */
+       IF_LEFT { DIP { UNPAIR } ; ADD ; PAIR }
+               { DROP ; CDR ; PUSH nat 0 ; PAIR } ;
+       NIL operation ; PAIR }`
+	want := `[{"prim":"parameter","args":[{"prim":"or","args":[{"prim":"nat","annots":["%add"]},{"prim":"unit","annots":["%reset"]}]}]},
+{"prim":"storage","args":[{"prim":"pair","args":[{"prim":"nat","annots":["%total"]},{"prim":"string","annots":["%name"]}]}]},
+{"prim":"code","args":[[{"prim":"UNPAIR"},
+  {"prim":"IF_LEFT","args":[[{"prim":"DIP","args":[[{"prim":"UNPAIR"}]]},{"prim":"ADD"},{"prim":"PAIR"}],
+                            [{"prim":"DROP"},{"prim":"CDR"},{"prim":"PUSH","args":[{"prim":"nat"},{"int":"0"}]},{"prim":"PAIR"}]]},
+  {"prim":"NIL","args":[{"prim":"operation"}]},{"prim":"PAIR"}]]}]`
+	have, err := ParseMichelson(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var exp Prim
+	if err := json.Unmarshal([]byte(want), &exp); err != nil {
+		t.Fatal(err)
+	}
+	if !have.IsEqualWithAnno(exp) {
+		t.Errorf("mismatch\n have %s\n want %s", have.Dump(), exp.Dump())
+	}
+
+	// single expressions
+	var tests = []struct {
+		src  string
+		json string
+	}{
+		{`Pair -1 "a\"b\n" 0xcafe`, `{"prim":"Pair","args":[{"int":"-1"},{"string":"a\"b\n"},{"bytes":"cafe"}]}`},
+		{`{ Elt "a" (Some Unit) }`, `[{"prim":"Elt","args":[{"string":"a"},{"prim":"Some","args":[{"prim":"Unit"}]}]}]`},
+		{`{}`, `[]`},
+		{`(big_map :t %ledger address nat)`, `{"prim":"big_map","args":[{"prim":"address"},{"prim":"nat"}],"annots":[":t","%ledger"]}`},
+		{`{ DROP ; }`, `[{"prim":"DROP"}]`},
+		{`42`, `{"int":"42"}`},
+	}
+	for _, test := range tests {
+		have, err := ParseMichelson(test.src)
+		if err != nil {
+			t.Errorf("%s: %v", test.src, err)
+			continue
+		}
+		var exp Prim
+		if err := json.Unmarshal([]byte(test.json), &exp); err != nil {
+			t.Fatal(err)
+		}
+		if !have.IsEqualWithAnno(exp) {
+			t.Errorf("%s: mismatch\n have %s\n want %s", test.src, have.Dump(), exp.Dump())
+		}
+	}
+
+	// errors
+	var errs = []struct {
+		src string
+		err string
+	}{
+		{`{ DUP ; CMPEQ }`, `line 1 col 9: unknown primitive CMPEQ`},
+		{"{ PUSH string \"abc }", `unterminated string`},
+		{"{ DUP ;\n  ; DROP }", `line 2 col 3: unexpected ;`},
+		{`{ DUP`, `unexpected end of input`},
+		{`Pair 1 2)`, `unexpected ")"`},
+		{`/* open`, `unterminated comment`},
+	}
+	for _, test := range errs {
+		_, err := ParseMichelson(test.src)
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s: expected error %q, got %v", test.src, test.err, err)
+		}
+	}
+}
+
+func TestParseMacros(t *testing.T) {
+	var tests = []struct {
+		src  string
+		want string
+	}{
+		{`CMPEQ`, `{ COMPARE ; EQ }`},
+		{`CMPLE @le`, `{ COMPARE ; LE @le }`},
+		{`IFEQ { DROP } {}`, `{ EQ ; IF { DROP } {} }`},
+		{`IFCMPLT { DROP } {}`, `{ COMPARE ; LT ; IF { DROP } {} }`},
+		{`IF_SOME { DROP } { UNIT }`, `{ IF_NONE { UNIT } { DROP } }`},
+		{`IF_RIGHT { DROP } { UNIT }`, `{ IF_LEFT { UNIT } { DROP } }`},
+		{`FAIL`, `{ UNIT ; FAILWITH }`},
+		{`ASSERT`, `{ IF {} { { UNIT ; FAILWITH } } }`},
+		{`ASSERT_NEQ`, `{ NEQ ; IF {} { { UNIT ; FAILWITH } } }`},
+		{`ASSERT_CMPGE`, `{ { COMPARE ; GE } ; IF {} { { UNIT ; FAILWITH } } }`},
+		{`ASSERT_NONE`, `{ IF_NONE {} { { UNIT ; FAILWITH } } }`},
+		{`ASSERT_SOME @x`, `{ IF_NONE { { UNIT ; FAILWITH } } { RENAME @x } }`},
+		{`ASSERT_LEFT`, `{ IF_LEFT {} { { UNIT ; FAILWITH } } }`},
+		{`ASSERT_RIGHT`, `{ IF_LEFT { { UNIT ; FAILWITH } } {} }`},
+		{`DUUP @a`, `{ DUP @a 2 }`},
+		{`DIIIP { DROP }`, `DIP 3 { DROP }`},
+		{`CADR @x %y`, `{ CAR ; CDR @x %y }`},
+		{`CDDAR @%% %f`, `{ CDR @%% ; CDR @%% ; CAR @%% %f }`},
+		{`CAR 2`, `{ GET 5 }`},
+		{`CDR 2`, `{ GET 4 }`},
+		{`PAPPAIIR`, `{ DIP { PAIR } ; DIP { PAIR } ; PAIR }`},
+		{`PAPAIR %a %b %c`, `{ DIP { PAIR %b %c } ; PAIR %a }`},
+		{`PAPAPAIR`, `{ DIP 2 { PAIR } ; DIP { PAIR } ; PAIR }`},
+		{`PPAIIR @p`, `{ PAIR ; PAIR @p }`},
+		{`UNPAPAIR`, `{ UNPAIR ; DIP { UNPAIR } }`},
+		{`UNPAPPAIIR`, `{ UNPAIR ; DIP { UNPAIR } ; DIP { UNPAIR } }`},
+		{`UNPAPAPAIR`, `{ UNPAIR ; DIP { UNPAIR } ; DIP 2 { UNPAIR } }`},
+		{`SET_CAR`, `{ CDR @%% ; SWAP ; PAIR % %@ }`},
+		{`SET_CDR %x`, `{ DUP ; CDR %x ; DROP ; CAR @%% ; PAIR %@ %x }`},
+		{`SET_CADR`, `{ DUP ; DIP { CAR @%% ; { CAR @%% ; PAIR %@ % } } ; CDR @%% ; SWAP ; PAIR %@ %@ }`},
+		{`MAP_CAR { PUSH nat 1 ; ADD }`, `{ DUP ; CDR @%% ; DIP { CAR ; { PUSH nat 1 ; ADD } } ; SWAP ; PAIR % %@ }`},
+		{`MAP_CDR %n { PUSH nat 1 ; ADD }`, `{ DUP ; CDR @n ; { PUSH nat 1 ; ADD } ; SWAP ; CAR @%% ; PAIR %@ %n }`},
+		{`{ DUP ; IFCMPEQ { FAIL } {} }`, `{ DUP ; { COMPARE ; EQ ; IF { { UNIT ; FAILWITH } } {} } }`},
+		{`{ UNPAIR ; PAIR ; CAR ; DIP { DUP } }`, `{ UNPAIR ; PAIR ; CAR ; DIP { DUP } }`},
+	}
+	for _, test := range tests {
+		have, err := ParseMichelsonWithOptions(test.src, ParseOptions{ExpandMacros: true})
+		if err != nil {
+			t.Errorf("%s: %v", test.src, err)
+			continue
+		}
+		want, err := ParseMichelson(test.want)
+		if err != nil {
+			t.Fatalf("%s: %v", test.want, err)
+		}
+		if !have.IsEqualWithAnno(want) {
+			t.Errorf("%s: mismatch\n have %s\n want %s", test.src, have.Dump(), want.Dump())
+		}
+	}
+
+	// expansion matches the builder macro
+	have, err := ParseMichelsonWithOptions(`ASSERT_CMPEQ`, ParseOptions{ExpandMacros: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !have.IsEqual(ASSERT_CMPEQ()) {
+		t.Errorf("ASSERT_CMPEQ mismatch %s", have.Dump())
+	}
+
+	// invalid macro use
+	for _, src := range []string{`CMPEQ {}`, `IF_SOME {}`, `DIIP DROP`, `FAIL @x`, `PAIIR`} {
+		if _, err := ParseMichelsonWithOptions(src, ParseOptions{ExpandMacros: true}); err == nil {
+			t.Errorf("%s: expected error", src)
+		}
+	}
+}
@@ -0,0 +1,332 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package micheline
+
+import (
+	"encoding/hex"
+	"strconv"
+	"strings"
+)
+
+type ChangeKind byte
+
+const (
+	ChangeAdded ChangeKind = iota
+	ChangeRemoved
+	ChangeModified
+)
+
+func (k ChangeKind) String() string {
+	switch k {
+	case ChangeAdded:
+		return "added"
+	case ChangeRemoved:
+		return "removed"
+	case ChangeModified:
+		return "modified"
+	}
+	return ""
+}
+
+func (k ChangeKind) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+// Change is a single difference between two prim trees. Path contains
+// canonical child positions as understood by GetCanonicalPath. Paths of removed
+// nodes refer to the old tree, all other paths refer to the new tree.
+type Change struct {
+	Kind ChangeKind
+	Path []int
+	Old  Prim // InvalidPrim when added
+	New  Prim // InvalidPrim when removed
+}
+
+// PathString returns the slash separated path of the change.
+func (c Change) PathString() string {
+	segs := make([]string, len(c.Path))
+	for i, v := range c.Path {
+		segs[i] = strconv.Itoa(v)
+	}
+	return strings.Join(segs, "/")
+}
+
+func (c Change) String() string {
+	return c.format(c.PathString())
+}
+
+func (c Change) format(path string) string {
+	if path == "" {
+		path = "/"
+	}
+	switch c.Kind {
+	case ChangeAdded:
+		return "+ " + path + ": " + formatMichelson(c.New)
+	case ChangeRemoved:
+		return "- " + path + ": " + formatMichelson(c.Old)
+	default:
+		return "~ " + path + ": " + formatMichelson(c.Old) + " => " + formatMichelson(c.New)
+	}
+}
+
+// Diff compares a and b and returns the smallest changed subtrees in pre-order.
+// Nodes with different opcodes, annotations or literal values are reported as
+// modified. Sequences are aligned on their longest common subsequence so that
+// inserting an instruction into code yields a single added change. Pairs are
+// compared in canonical form, comb and nested layouts of equal values do not
+// differ.
+func Diff(a, b Prim) []Change {
+	changes := make([]Change, 0)
+	diffPrim(nil, a, b, &changes)
+	return changes
+}
+
+func diffPrim(path []int, a, b Prim, changes *[]Change) {
+	if a.IsEqualWithAnno(b) {
+		return
+	}
+	if !sameNode(a, b) {
+		*changes = append(*changes, newChange(ChangeModified, path, a, b))
+		return
+	}
+	if a.IsSequence() {
+		diffSeq(path, a.Args, b.Args, changes)
+		return
+	}
+	aargs, bargs := a.canonicalArgs(), b.canonicalArgs()
+	if len(aargs) != len(bargs) {
+		*changes = append(*changes, newChange(ChangeModified, path, a, b))
+		return
+	}
+	for i := range aargs {
+		diffPrim(append(path, i), aargs[i], bargs[i], changes)
+	}
+}
+
+// diffSeq aligns both sequences on their longest common subsequence and
+// compares unmatched items pairwise.
+func diffSeq(path []int, a, b []Prim, changes *[]Change) {
+	n, m := len(a), len(b)
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			switch {
+			case a[i].IsEqualWithAnno(b[j]):
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				lcs[i][j] = lcs[i+1][j]
+			default:
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	var i, j int
+	flush := func(i0, j0 int) {
+		for k := 0; i0+k < i || j0+k < j; k++ {
+			switch {
+			case i0+k < i && j0+k < j:
+				diffPrim(append(path, j0+k), a[i0+k], b[j0+k], changes)
+			case i0+k < i:
+				*changes = append(*changes, newChange(ChangeRemoved, append(path, i0+k), a[i0+k], InvalidPrim))
+			default:
+				*changes = append(*changes, newChange(ChangeAdded, append(path, j0+k), InvalidPrim, b[j0+k]))
+			}
+		}
+	}
+	i0, j0 := 0, 0
+	for i < n && j < m {
+		switch {
+		case a[i].IsEqualWithAnno(b[j]):
+			flush(i0, j0)
+			i++
+			j++
+			i0, j0 = i, j
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+	i, j = n, m
+	flush(i0, j0)
+}
+
+func newChange(kind ChangeKind, path []int, a, b Prim) Change {
+	return Change{
+		Kind: kind,
+		Path: append([]int{}, path...),
+		Old:  a,
+		New:  b,
+	}
+}
+
+// sameNode reports whether a and b are the same kind of node with equal opcode
+// and annotations so that their children can be compared.
+func sameNode(a, b Prim) bool {
+	if nodeKind(a) != nodeKind(b) || a.OpCode != b.OpCode {
+		return false
+	}
+	switch nodeKind(a) {
+	case PrimInt, PrimString, PrimBytes:
+		return false
+	}
+	if len(a.Anno) != len(b.Anno) {
+		return false
+	}
+	for i := range a.Anno {
+		if a.Anno[i] != b.Anno[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func nodeKind(p Prim) PrimType {
+	switch p.Type {
+	case PrimInt, PrimString, PrimBytes, PrimSequence:
+		return p.Type
+	}
+	return PrimNullary
+}
+
+// RenderDiff compares a and b and renders one line per change with Michelson
+// formatted values. When typ is valid, path segments use field annotations of
+// the type, Left/Right branch names and map keys where available.
+func RenderDiff(a, b Prim, typ Type) string {
+	changes := Diff(a, b)
+	lines := make([]string, len(changes))
+	for i, c := range changes {
+		root := b
+		if c.Kind == ChangeRemoved {
+			root = a
+		}
+		lines[i] = c.format(labelPath(typ.Prim, root, c.Path))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// labelPath translates a canonical path into a path of field names using
+// type typ. Segments without a name fall back to their position, unnamed
+// nested pairs and or/option wrappers are omitted.
+func labelPath(typ, val Prim, path []int) string {
+	segs := make([]string, 0, len(path))
+	for _, idx := range path {
+		args := val.canonicalArgs()
+		if idx >= len(args) {
+			break
+		}
+		seg := strconv.Itoa(idx)
+		next, named := InvalidPrim, true
+		switch typ.OpCode {
+		case T_PAIR:
+			if targs := typ.canonicalArgs(); idx < len(targs) {
+				next = targs[idx]
+				if isPairPrim(next) {
+					// unnamed nested pairs are structural
+					seg = ""
+				}
+			}
+		case T_OR:
+			if len(typ.Args) == 2 {
+				switch val.OpCode {
+				case D_LEFT:
+					next = typ.Args[0]
+				case D_RIGHT:
+					next = typ.Args[1]
+				}
+			}
+			seg = ""
+		case T_OPTION:
+			if len(typ.Args) == 1 {
+				next = typ.Args[0]
+			}
+			seg = ""
+		case T_LIST, T_SET:
+			if len(typ.Args) == 1 {
+				next = typ.Args[0]
+			}
+		case T_MAP, T_BIG_MAP:
+			if len(typ.Args) == 2 {
+				switch {
+				case val.IsSequence():
+					// step into Elt, name it by its key
+					next, named = typ, false
+					if elt := args[idx]; elt.IsElt() && len(elt.Args) == 2 {
+						seg = "[" + formatMichelson(elt.Args[0]) + "]"
+					}
+				case val.IsElt():
+					next = typ.Args[idx]
+					if idx == 1 {
+						seg = ""
+					} else {
+						seg = "key"
+					}
+				}
+			}
+		}
+		if name := next.GetVarAnno(); name != "" && named {
+			seg = name
+		}
+		if seg != "" {
+			segs = append(segs, seg)
+		}
+		typ, val = next, args[idx]
+	}
+	return strings.Join(segs, "/")
+}
+
+// formatMichelson renders p as single-line Michelson expression.
+func formatMichelson(p Prim) string {
+	var b strings.Builder
+	writeMichelson(&b, p, false)
+	return b.String()
+}
+
+func writeMichelson(b *strings.Builder, p Prim, wrap bool) {
+	switch p.Type {
+	case PrimInt:
+		b.WriteString(p.Int.Text(10))
+	case PrimString:
+		b.WriteString(strconv.Quote(p.String))
+	case PrimBytes:
+		b.WriteString("0x")
+		b.WriteString(hex.EncodeToString(p.Bytes))
+	case PrimSequence:
+		if len(p.Args) == 0 {
+			b.WriteString("{}")
+			return
+		}
+		b.WriteString("{ ")
+		for i, v := range p.Args {
+			if i > 0 {
+				b.WriteString(" ; ")
+			}
+			writeMichelson(b, v, false)
+		}
+		b.WriteString(" }")
+	default:
+		if !p.IsValid() {
+			return
+		}
+		wrap = wrap && (len(p.Args) > 0 || len(p.Anno) > 0)
+		if wrap {
+			b.WriteByte('(')
+		}
+		b.WriteString(p.OpCode.String())
+		for _, v := range p.Anno {
+			b.WriteByte(' ')
+			b.WriteString(v)
+		}
+		for _, v := range p.Args {
+			b.WriteByte(' ')
+			writeMichelson(b, v, true)
+		}
+		if wrap {
+			b.WriteByte(')')
+		}
+	}
+}
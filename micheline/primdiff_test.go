@@ -0,0 +1,71 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package micheline
+
+import (
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	mustParse := func(src string) Prim {
+		p, err := ParseMichelson(src)
+		if err != nil {
+			t.Fatalf("%s: %v", src, err)
+		}
+		return p
+	}
+	typ := NewType(mustParse(`pair (address %admin) (map %ledger string nat) (lambda %admin_lambda unit (list operation))`))
+	a := mustParse(`Pair "tz1KqTpEZ7Yob7QbPE4Hy4Wo8fHG8LhKxZSx" { Elt "a" 1 ; Elt "b" 2 } { DROP ; NIL operation ; PUSH nat 1 ; DROP }`)
+	b := mustParse(`Pair "tz1KqTpEZ7Yob7QbPE4Hy4Wo8fHG8LhKxZSx" (Pair { Elt "a" 1 ; Elt "b" 3 } { DROP ; NIL operation ; PUSH nat 2 ; SWAP ; DROP })`)
+
+	changes := Diff(a, b)
+	want := []string{
+		`~ 1/0/1/1: 2 => 3`,
+		`~ 1/1/2/1: 1 => 2`,
+		`+ 1/1/3: SWAP`,
+	}
+	if len(changes) != len(want) {
+		for _, c := range changes {
+			t.Log(c)
+		}
+		t.Fatalf("expected %d changes, got %d", len(want), len(changes))
+	}
+	for i, c := range changes {
+		if have := c.String(); have != want[i] {
+			t.Errorf("change %d: have %q want %q", i, have, want[i])
+		}
+	}
+	if changes[2].Kind != ChangeAdded || changes[2].Old.IsValid() {
+		t.Errorf("unexpected change %#v", changes[2])
+	}
+	if p, err := b.GetCanonicalPath(changes[1].PathString()); err != nil || !p.IsEqual(changes[1].New) {
+		t.Errorf("path does not resolve to new node: %v", err)
+	}
+
+	// comb and nested layouts are equal
+	if c := Diff(a, mustParse(`Pair "tz1KqTpEZ7Yob7QbPE4Hy4Wo8fHG8LhKxZSx" (Pair { Elt "a" 1 ; Elt "b" 2 } { DROP ; NIL operation ; PUSH nat 1 ; DROP })`)); len(c) != 0 {
+		t.Errorf("expected no changes, got %v", c)
+	}
+
+	// removal and rendering with type labels
+	c := mustParse(`Pair "tz1KqTpEZ7Yob7QbPE4Hy4Wo8fHG8LhKxZSx" { Elt "a" 1 } { DROP ; NIL operation }`)
+	have := RenderDiff(a, c, typ)
+	exp := `- ledger/["b"]: Elt "b" 2` + "\n" +
+		`- admin_lambda/2: PUSH nat 1` + "\n" +
+		`- admin_lambda/3: DROP`
+	if have != exp {
+		t.Errorf("render mismatch\n have %s\n want %s", have, exp)
+	}
+
+	// or branches use their field names
+	otyp := NewType(mustParse(`or (nat %add) (option %reset (pair (nat %x) (int %y)))`))
+	have = RenderDiff(mustParse(`Right (Some (Pair 1 2))`), mustParse(`Right (Some (Pair 1 -2))`), otyp)
+	if exp := `~ reset/y: 2 => -2`; have != exp {
+		t.Errorf("render mismatch\n have %s\n want %s", have, exp)
+	}
+	have = RenderDiff(mustParse(`Left 1`), mustParse(`Right None`), otyp)
+	if exp := `~ /: Left 1 => Right None`; have != exp {
+		t.Errorf("render mismatch\n have %s\n want %s", have, exp)
+	}
+}
@@ -19,11 +19,12 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"hash"
+	"hash/fnv"
 	"io"
 	"math/big"
 	"strconv"
 	"strings"
-	"time"
 
 	"blockwatch.cc/tzgo/tezos"
 )
@@ -207,7 +208,7 @@ func IsEqualPrim(p1, p2 Prim, withAnno bool) bool {
 			return false
 		}
 		for i := range p1.Anno {
-			if p1.Anno[i][1:] != p2.Anno[i][1:] {
+			if trimAnno(p1.Anno[i]) != trimAnno(p2.Anno[i]) {
 				return false
 			}
 		}
@@ -242,6 +243,138 @@ func IsEqualPrim(p1, p2 Prim, withAnno bool) bool {
 	return true
 }
 
+func trimAnno(s string) string {
+	if len(s) > 0 {
+		return s[1:]
+	}
+	return s
+}
+
+// Equal compares prim trees structurally including prim types, args and scalar
+// values. Annotations are ignored and right-comb pairs are considered equal to
+// their nested form, i.e. (Pair a b c) equals (Pair a (Pair b c)).
+func (p Prim) Equal(p2 Prim) bool {
+	return equalPrim(p, p2, false)
+}
+
+// EqualWithAnno works like Equal, but also compares annotations which is
+// useful for type equality.
+func (p Prim) EqualWithAnno(p2 Prim) bool {
+	return equalPrim(p, p2, true)
+}
+
+func equalPrim(p1, p2 Prim, withAnno bool) bool {
+	if p1.OpCode != p2.OpCode {
+		return false
+	}
+	if withAnno {
+		if len(p1.Anno) != len(p2.Anno) {
+			return false
+		}
+		for i := range p1.Anno {
+			if p1.Anno[i] != p2.Anno[i] {
+				return false
+			}
+		}
+	}
+	if isPairPrim(p1) && isPairPrim(p2) {
+		a1, a2 := combLeaves(p1, withAnno), combLeaves(p2, withAnno)
+		if len(a1) != len(a2) {
+			return false
+		}
+		for i := range a1 {
+			if !equalPrim(a1[i], a2[i], withAnno) {
+				return false
+			}
+		}
+		return true
+	}
+	if stripAnnoType(p1.Type) != stripAnnoType(p2.Type) || len(p1.Args) != len(p2.Args) {
+		return false
+	}
+	if p1.String != p2.String || !bytes.Equal(p1.Bytes, p2.Bytes) {
+		return false
+	}
+	if (p1.Int == nil) != (p2.Int == nil) || (p1.Int != nil && p1.Int.Cmp(p2.Int) != 0) {
+		return false
+	}
+	for i := range p1.Args {
+		if !equalPrim(p1.Args[i], p2.Args[i], withAnno) {
+			return false
+		}
+	}
+	return true
+}
+
+// Hash returns a 64-bit FNV-1a hash of the prim tree that is consistent with
+// Equal, i.e. equal prims have the same hash. Use it for map keys and
+// deduplication, but check Equal on collision.
+func (p Prim) Hash() uint64 {
+	h := fnv.New64a()
+	hashPrim(h, p)
+	return h.Sum64()
+}
+
+func hashPrim(h hash.Hash64, p Prim) {
+	var buf [binary.MaxVarintLen64]byte
+	h.Write([]byte{byte(p.OpCode)})
+	if isPairPrim(p) {
+		leaves := combLeaves(p, false)
+		h.Write(buf[:binary.PutUvarint(buf[:], uint64(len(leaves)))])
+		for _, v := range leaves {
+			hashPrim(h, v)
+		}
+		return
+	}
+	h.Write([]byte{byte(stripAnnoType(p.Type))})
+	switch {
+	case p.Int != nil:
+		h.Write(p.Int.Bytes())
+		h.Write([]byte{byte(p.Int.Sign() + 1)})
+	case p.Bytes != nil:
+		h.Write(p.Bytes)
+	default:
+		h.Write([]byte(p.String))
+	}
+	h.Write(buf[:binary.PutUvarint(buf[:], uint64(len(p.Args)))])
+	for _, v := range p.Args {
+		hashPrim(h, v)
+	}
+}
+
+// isPairPrim returns true for pair values and types including comb pair types
+// in sequence form. Untyped sequences are ambiguous (list or comb pair value)
+// and never considered a pair.
+func isPairPrim(p Prim) bool {
+	return p.OpCode == D_PAIR || p.OpCode == T_PAIR
+}
+
+// combLeaves returns the args of a right-comb pair with nested pairs in last
+// position expanded. When withAnno is set annotated nested pairs are kept.
+func combLeaves(p Prim, withAnno bool) []Prim {
+	leaves := make([]Prim, 0, len(p.Args))
+	for {
+		n := len(p.Args)
+		if n == 0 {
+			return leaves
+		}
+		leaves = append(leaves, p.Args[:n-1]...)
+		last := p.Args[n-1]
+		if last.OpCode != p.OpCode || !isPairPrim(last) || (withAnno && len(last.Anno) > 0) {
+			return append(leaves, last)
+		}
+		p = last
+	}
+}
+
+func stripAnnoType(t PrimType) PrimType {
+	switch t {
+	case PrimNullaryAnno, PrimUnaryAnno, PrimBinaryAnno:
+		return t - 1
+	}
+	return t
+}
+
 // PrimWalkerFunc is the callback function signature used while
 // traversing a prim tree in read-only mode.
 type PrimWalkerFunc func(p Prim) error
@@ -710,7 +843,10 @@ func (p Prim) Value(as OpCode) interface{} {
 	case PrimInt:
 		switch as {
 		case T_TIMESTAMP:
-			tm := time.Unix(p.Int.Int64(), 0).UTC()
+			tm, err := p.Time()
+			if err != nil {
+				return p.Int.Text(10)
+			}
 			if y := tm.Year(); y < 0 || y >= 10000 {
 				return p.Int.Text(10)
 			}
@@ -724,7 +860,7 @@ func (p Prim) Value(as OpCode) interface{} {
 	case PrimString:
 		switch as {
 		case T_TIMESTAMP:
-			if t, err := time.Parse(time.RFC3339, p.String); err == nil {
+			if t, err := p.Time(); err == nil {
 				return t
 			}
 			return p.String
@@ -908,38 +1044,104 @@ func (p Prim) Value(as OpCode) interface{} {
 	return p
 }
 
+// MarshalJSON produces the canonical Micheline JSON encoding used by Tezos
+// nodes: compact, keys in the order prim, args, annots, integers as decimal
+// strings, bytes as lowercase hex and strings escaped without HTML escaping.
+// Note that encoding/json re-escapes HTML characters when a Prim is marshaled
+// as part of a larger value unless the encoder disables HTML escaping.
 func (p Prim) MarshalJSON() ([]byte, error) {
 	if !p.IsValid() {
 		return []byte("{}"), nil
 	}
-	m := make(map[string]interface{})
+	buf := bytes.NewBuffer(make([]byte, 0, 64))
+	p.encodeJSON(buf)
+	return buf.Bytes(), nil
+}
+
+func (p Prim) encodeJSON(buf *bytes.Buffer) {
 	switch p.Type {
 	case PrimSequence:
-		return json.Marshal(p.Args)
+		encodeJSONList(buf, p.Args)
 	case PrimInt:
-		m["int"] = p.Int.Text(10)
+		buf.WriteString(`{"int":"`)
+		buf.WriteString(p.Int.Text(10))
+		buf.WriteString(`"}`)
 	case PrimString:
-		m["string"] = p.String
+		buf.WriteString(`{"string":`)
+		encodeJSONString(buf, p.String)
+		buf.WriteByte('}')
 	case PrimBytes:
-		m["bytes"] = hex.EncodeToString(p.Bytes)
+		buf.WriteString(`{"bytes":"`)
+		buf.WriteString(hex.EncodeToString(p.Bytes))
+		buf.WriteString(`"}`)
 	default:
-		m["prim"] = p.OpCode.String()
-		if len(p.Anno) > 0 {
-			m["annots"] = p.Anno
-		}
+		buf.WriteString(`{"prim":"`)
+		buf.WriteString(p.OpCode.String())
+		buf.WriteByte('"')
 		if len(p.Args) > 0 {
-			args := make([]json.RawMessage, 0, len(p.Args))
-			for _, v := range p.Args {
-				arg, err := json.Marshal(v)
-				if err != nil {
-					return nil, err
+			buf.WriteString(`,"args":`)
+			encodeJSONList(buf, p.Args)
+		}
+		if len(p.Anno) > 0 {
+			buf.WriteString(`,"annots":[`)
+			for i, v := range p.Anno {
+				if i > 0 {
+					buf.WriteByte(',')
 				}
-				args = append(args, arg)
+				encodeJSONString(buf, v)
+			}
+			buf.WriteByte(']')
+		}
+		buf.WriteByte('}')
+	}
+}
+
+func encodeJSONList(buf *bytes.Buffer, list []Prim) {
+	buf.WriteByte('[')
+	for i, v := range list {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		if v.IsValid() {
+			v.encodeJSON(buf)
+		} else {
+			buf.WriteString("{}")
+		}
+	}
+	buf.WriteByte(']')
+}
+
+// encodeJSONString writes s as JSON string using the short escapes emitted by
+// Tezos nodes for control characters.
+func encodeJSONString(buf *bytes.Buffer, s string) {
+	const hexDigits = "0123456789abcdef"
+	buf.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '"', '\\':
+			buf.WriteByte('\\')
+			buf.WriteByte(c)
+		case '\n':
+			buf.WriteString(`\n`)
+		case '\r':
+			buf.WriteString(`\r`)
+		case '\t':
+			buf.WriteString(`\t`)
+		case '\b':
+			buf.WriteString(`\b`)
+		case '\f':
+			buf.WriteString(`\f`)
+		default:
+			if c < 0x20 || c == 0x7f {
+				buf.WriteString(`\u00`)
+				buf.WriteByte(hexDigits[c>>4])
+				buf.WriteByte(hexDigits[c&0xf])
+			} else {
+				buf.WriteByte(c)
 			}
-			m["args"] = args
 		}
 	}
-	return json.Marshal(m)
+	buf.WriteByte('"')
 }
 
 func (p Prim) MarshalBinary() ([]byte, error) {
@@ -950,6 +1152,72 @@ func (p Prim) MarshalBinary() ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
+// EncodeBinary returns the raw Micheline binary encoding of p as used inside
+// forged operations and scripts, i.e. without the 0x05 PACK prefix.
+func (p Prim) EncodeBinary() ([]byte, error) {
+	return p.MarshalBinary()
+}
+
+// Size returns the length of the binary encoding of p in bytes without
+// allocating. This is the size the protocol uses to charge storage burn.
+func (p Prim) Size() int {
+	sz := 1 // tag
+	switch p.Type {
+	case PrimInt:
+		sz += zarithSize(p.Int)
+	case PrimString:
+		sz += 4 + len(p.String)
+	case PrimBytes:
+		sz += 4 + len(p.Bytes)
+	case PrimSequence:
+		sz += 4
+		for _, v := range p.Args {
+			sz += v.Size()
+		}
+	default:
+		sz++ // opcode
+		if p.Type == PrimVariadicAnno {
+			sz += 4
+		}
+		for _, v := range p.Args {
+			sz += v.Size()
+		}
+		switch p.Type {
+		case PrimNullaryAnno, PrimUnaryAnno, PrimBinaryAnno, PrimVariadicAnno:
+			sz += 4
+			for i, v := range p.Anno {
+				if i > 0 {
+					sz++ // separator
+				}
+				sz += len(v)
+			}
+		}
+	}
+	return sz
+}
+
+// zarithSize returns the size of the variable length zarith encoding of x.
+func zarithSize(x *big.Int) int {
+	n := 0
+	if x != nil {
+		n = x.BitLen()
+	}
+	if n <= 6 {
+		return 1
+	}
+	return 1 + (n-6+6)/7
+}
+
+// NodeCount returns the number of nodes in the tree including literals and
+// sequences. The protocol charges deserialization gas based on this count.
+func (p Prim) NodeCount() int {
+	n := 1
+	for _, v := range p.Args {
+		n += v.NodeCount()
+	}
+	return n
+}
+
 func (p Prim) EncodeBuffer(buf *bytes.Buffer) error {
 	buf.WriteByte(byte(p.Type))
 	switch p.Type {
@@ -1225,6 +1493,19 @@ func (p *Prim) UnmarshalBinary(data []byte) error {
 	return p.DecodeBuffer(bytes.NewBuffer(data))
 }
 
+// DecodePrimBinary decodes a single prim from the start of raw Micheline binary
+// data (without the 0x05 PACK prefix) and returns the number of bytes consumed.
+// Any trailing data is ignored, which allows decoding prims embedded in larger
+// structures like forged operations.
+func DecodePrimBinary(data []byte) (Prim, int, error) {
+	var p Prim
+	buf := bytes.NewBuffer(data)
+	if err := p.DecodeBuffer(buf); err != nil {
+		return InvalidPrim, 0, err
+	}
+	return p, len(data) - buf.Len(), nil
+}
+
 func (p *Prim) DecodeBuffer(buf *bytes.Buffer) error {
 	b := buf.Next(1)
 	if len(b) == 0 {
@@ -1242,17 +1523,17 @@ func (p *Prim) DecodeBuffer(buf *bytes.Buffer) error {
 
 	case PrimString:
 		// cross-check content size
-		size := int(binary.BigEndian.Uint32(buf.Next(4)))
-		if buf.Len() < size {
-			return io.ErrShortBuffer
+		size, err := readSize(buf)
+		if err != nil {
+			return err
 		}
 		p.String = string(buf.Next(size))
 
 	case PrimSequence:
 		// cross-check content size
-		size := int(binary.BigEndian.Uint32(buf.Next(4)))
-		if buf.Len() < size {
-			return io.ErrShortBuffer
+		size, err := readSize(buf)
+		if err != nil {
+			return err
 		}
 		// extract sub-buffer
 		seq := bytes.NewBuffer(buf.Next(size))
@@ -1283,12 +1564,11 @@ func (p *Prim) DecodeBuffer(buf *bytes.Buffer) error {
 		p.OpCode = OpCode(b[0])
 
 		// annotation array byte size
-		size := int(binary.BigEndian.Uint32(buf.Next(4)))
-		if buf.Len() < size {
-			return io.ErrShortBuffer
+		anno, err := readAnno(buf)
+		if err != nil {
+			return err
 		}
-		anno := buf.Next(size)
-		p.Anno = strings.Split(string(anno), " ")
+		p.Anno = anno
 
 	case PrimUnary:
 		// opcode with single argument
@@ -1321,12 +1601,11 @@ func (p *Prim) DecodeBuffer(buf *bytes.Buffer) error {
 		p.Args = append(p.Args, prim)
 
 		// annotation array byte size
-		size := int(binary.BigEndian.Uint32(buf.Next(4)))
-		if buf.Len() < size {
-			return io.ErrShortBuffer
+		anno, err := readAnno(buf)
+		if err != nil {
+			return err
 		}
-		anno := buf.Next(size)
-		p.Anno = strings.Split(string(anno), " ")
+		p.Anno = anno
 
 	case PrimBinary:
 		// opcode with two arguments
@@ -1363,12 +1642,11 @@ func (p *Prim) DecodeBuffer(buf *bytes.Buffer) error {
 		}
 
 		// annotation array byte size
-		size := int(binary.BigEndian.Uint32(buf.Next(4)))
-		if buf.Len() < size {
-			return io.ErrShortBuffer
+		anno, err := readAnno(buf)
+		if err != nil {
+			return err
 		}
-		anno := buf.Next(size)
-		p.Anno = strings.Split(string(anno), " ")
+		p.Anno = anno
 
 	case PrimVariadicAnno:
 		// opcode with N arguments and optional annotations
@@ -1379,7 +1657,10 @@ func (p *Prim) DecodeBuffer(buf *bytes.Buffer) error {
 		p.OpCode = OpCode(b[0])
 
 		// argument array byte size
-		size := int(binary.BigEndian.Uint32(buf.Next(4)))
+		size, err := readSize(buf)
+		if err != nil {
+			return err
+		}
 
 		// extract sub-buffer
 		seq := bytes.NewBuffer(buf.Next(size))
@@ -1393,18 +1674,17 @@ func (p *Prim) DecodeBuffer(buf *bytes.Buffer) error {
 			p.Args = append(p.Args, prim)
 		}
 		// annotation array byte size
-		size = int(binary.BigEndian.Uint32(buf.Next(4)))
-		if buf.Len() < size {
-			return io.ErrShortBuffer
+		anno, err := readAnno(buf)
+		if err != nil {
+			return err
 		}
-		anno := buf.Next(size)
-		p.Anno = strings.Split(string(anno), " ")
+		p.Anno = anno
 
 	case PrimBytes:
 		// cross-check content size
-		size := int(binary.BigEndian.Uint32(buf.Next(4)))
-		if buf.Len() < size {
-			return io.ErrShortBuffer
+		size, err := readSize(buf)
+		if err != nil {
+			return err
 		}
 		p.Bytes = buf.Next(size)
 
@@ -1415,6 +1695,30 @@ func (p *Prim) DecodeBuffer(buf *bytes.Buffer) error {
 	return nil
 }
 
+// readSize reads a 4 byte length prefix and checks it against the remaining
+// buffer length.
+func readSize(buf *bytes.Buffer) (int, error) {
+	b := buf.Next(4)
+	if len(b) < 4 {
+		return 0, io.ErrShortBuffer
+	}
+	size := int(binary.BigEndian.Uint32(b))
+	if buf.Len() < size {
+		return 0, io.ErrShortBuffer
+	}
+	return size, nil
+}
+
+// readAnno reads a length prefixed list of space separated annotations.
+func readAnno(buf *bytes.Buffer) ([]string, error) {
+	size, err := readSize(buf)
+	if err != nil {
+		return nil, err
+	}
+	// an empty field decodes to [""] like the node renders it in JSON
+	return strings.Split(string(buf.Next(size)), " "), nil
+}
+
 func (p Prim) FindOpCodes(typ OpCode) ([]Prim, bool) {
 	if p.OpCode == typ {
 		return []Prim{p}, true
@@ -1490,6 +1794,41 @@ func (p Prim) GetPath(path string) (Prim, error) {
 	return p.GetIndex(index)
 }
 
+// GetCanonicalPath returns the node at a slash separated path of child
+// positions. Unlike GetPath pairs are indexed in canonical binary form, i.e. a
+// right-comb Pair a b c is treated like Pair a (Pair b c), so that 0 selects the
+// left and 1 the right hand side of any pair regardless of its layout. L and R
+// are accepted as aliases for 0 and 1.
+func (p Prim) GetCanonicalPath(path string) (Prim, error) {
+	path = strings.TrimPrefix(path, "/")
+	path = strings.TrimSuffix(path, "/")
+	prim := p
+	if path == "" {
+		return prim, nil
+	}
+	for i, v := range strings.Split(path, "/") {
+		var idx int
+		switch v {
+		case "L", "l":
+			idx = 0
+		case "R", "r":
+			idx = 1
+		default:
+			var err error
+			idx, err = strconv.Atoi(v)
+			if err != nil {
+				return InvalidPrim, fmt.Errorf("micheline: invalid path component '%v' at pos %d", v, i)
+			}
+		}
+		args := prim.canonicalArgs()
+		if idx < 0 || len(args) <= idx {
+			return InvalidPrim, fmt.Errorf("micheline: path index %d out of bounds at pos %d", idx, i)
+		}
+		prim = args[idx]
+	}
+	return prim, nil
+}
+
 func (p Prim) GetIndex(index []int) (Prim, error) {
 	prim := p
 	for _, v := range index {
@@ -1500,3 +1839,158 @@ func (p Prim) GetIndex(index []int) (Prim, error) {
 	}
 	return prim, nil
 }
+
+// canonicalArgs returns the children of p with comb pairs split into a left
+// hand side and a nested pair holding the remaining fields.
+func (p Prim) canonicalArgs() []Prim {
+	if !isPairPrim(p) || len(p.Args) <= 2 {
+		return p.Args
+	}
+	rest := Prim{
+		Type:   PrimBinary,
+		OpCode: p.OpCode,
+		Args:   p.Args[1:],
+	}
+	if len(rest.Args) > 2 {
+		rest.Type = PrimVariadicAnno
+	}
+	return []Prim{p.Args[0], rest}
+}
+
+// PrimPathWalkerFunc is the callback function signature used by WalkPath. Path
+// contains canonical child positions as understood by GetCanonicalPath and must
+// not be retained by the callback.
+type PrimPathWalkerFunc func(path []int, p Prim) error
+
+// WalkPath traverses the prim tree in pre-order like Walk, but visits pairs
+// in canonical binary form (see GetCanonicalPath). Right-comb and nested pairs
+// hence produce the same sequence of paths and nodes. Return PrimSkip from the
+// callback to skip a node's children.
+func (p Prim) WalkPath(f PrimPathWalkerFunc) error {
+	return p.walkPath(make([]int, 0, 8), f)
+}
+
+func (p Prim) walkPath(path []int, f PrimPathWalkerFunc) error {
+	if err := f(path, p); err != nil {
+		if err == PrimSkip {
+			return nil
+		}
+		return err
+	}
+	for i, v := range p.canonicalArgs() {
+		if err := v.walkPath(append(path, i), f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FindByAnnot returns the first node in pre-order carrying annotation anno and
+// its canonical path for use with GetCanonicalPath. When anno starts with a %,
+// @ or : prefix only annotations of the same kind match, otherwise any kind
+// matches.
+func (p Prim) FindByAnnot(anno string) (Prim, string, bool) {
+	var (
+		found Prim
+		where string
+		ok    bool
+	)
+	_ = p.WalkPath(func(path []int, x Prim) error {
+		if !x.hasAnno(anno) {
+			return nil
+		}
+		found, ok = x, true
+		segs := make([]string, len(path))
+		for i, v := range path {
+			segs[i] = strconv.Itoa(v)
+		}
+		where = strings.Join(segs, "/")
+		return errFound
+	})
+	return found, where, ok
+}
+
+var errFound = errors.New("found")
+
+func (p Prim) hasAnno(anno string) bool {
+	if anno == "" {
+		return false
+	}
+	switch anno[:1] {
+	case TypeAnnoPrefix, VarAnnoPrefix, FieldAnnoPrefix:
+		for _, v := range p.Anno {
+			if v == anno {
+				return true
+			}
+		}
+		return false
+	default:
+		return p.MatchesAnno(anno)
+	}
+}
+
+// Match reports whether p structurally matches pattern. InvalidPrim in the
+// pattern matches any node, int, string and bytes literals without a value
+// match any literal of the same kind. Opcodes and the number of children must
+// be equal, annotations are only compared when the pattern has any. Pairs are
+// compared in canonical form so that comb and nested layouts match each other.
+func (p Prim) Match(pattern Prim) bool {
+	if !pattern.IsValid() {
+		return true
+	}
+	switch pattern.Type {
+	case PrimInt:
+		return p.Type == PrimInt && (pattern.Int == nil || pattern.Int.Cmp(p.Int) == 0)
+	case PrimString:
+		return p.Type == PrimString && (pattern.String == "" || pattern.String == p.String)
+	case PrimBytes:
+		return p.Type == PrimBytes && (len(pattern.Bytes) == 0 || bytes.Equal(pattern.Bytes, p.Bytes))
+	}
+	if p.OpCode != pattern.OpCode {
+		return false
+	}
+	if !isPairPrim(p) && (p.Type == PrimSequence) != (pattern.Type == PrimSequence) {
+		return false
+	}
+	if p.Type != PrimSequence && !isPrimCode(p) {
+		return false
+	}
+	for _, v := range pattern.Anno {
+		if !p.hasAnno(v) {
+			return false
+		}
+	}
+	args, pargs := p.canonicalArgs(), pattern.canonicalArgs()
+	if len(args) != len(pargs) {
+		return false
+	}
+	for i := range pargs {
+		if !args[i].Match(pargs[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Locate returns the node at Micheline location loc. Locations are numbered in
+// prefix order starting with 0 at the root as used in node type errors and
+// execution traces.
+func (p Prim) Locate(loc int) (Prim, bool) {
+	if loc < 0 {
+		return InvalidPrim, false
+	}
+	return p.locate(&loc)
+}
+
+func (p Prim) locate(n *int) (Prim, bool) {
+	if *n == 0 {
+		return p, true
+	}
+	*n--
+	for i := range p.Args {
+		if x, ok := p.Args[i].locate(n); ok {
+			return x, true
+		}
+	}
+	return InvalidPrim, false
+}
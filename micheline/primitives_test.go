@@ -0,0 +1,310 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package micheline
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"math/big"
+	"reflect"
+	"testing"
+)
+
+func TestPrimPath(t *testing.T) {
+	// pair (big_map %ledger address nat) (pair (string %name) (nat %total))
+	nested := `{"prim":"pair","args":[{"prim":"big_map","args":[{"prim":"address"},{"prim":"nat"}],"annots":["%ledger"]},{"prim":"pair","args":[{"prim":"string","annots":["%name"]},{"prim":"nat","annots":["%total"]}]}]}`
+	comb := `{"prim":"pair","args":[{"prim":"big_map","args":[{"prim":"address"},{"prim":"nat"}],"annots":["%ledger"]},{"prim":"string","annots":["%name"]},{"prim":"nat","annots":["%total"]}]}`
+	for _, src := range []string{nested, comb} {
+		var p Prim
+		if err := json.Unmarshal([]byte(src), &p); err != nil {
+			t.Fatal(err)
+		}
+		x, err := p.GetCanonicalPath("1/1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if x.OpCode != T_NAT || x.GetVarAnnoAny() != "total" {
+			t.Errorf("GetCanonicalPath 1/1: unexpected %s", x.Dump())
+		}
+		x, err = p.GetCanonicalPath("R/L")
+		if err != nil || x.OpCode != T_STRING {
+			t.Errorf("GetCanonicalPath R/L: unexpected %s %v", x.Dump(), err)
+		}
+		if _, err := p.GetCanonicalPath("2"); err == nil {
+			t.Errorf("GetCanonicalPath 2: expected error")
+		}
+		x, path, ok := p.FindByAnnot("%total")
+		if !ok || path != "1/1" || x.OpCode != T_NAT {
+			t.Errorf("FindByAnnot: unexpected %q %s", path, x.Dump())
+		}
+		if _, _, ok := p.FindByAnnot("@total"); ok {
+			t.Errorf("FindByAnnot: var annot must not match field annot")
+		}
+		x, path, ok = p.FindByAnnot("ledger")
+		if !ok || path != "0" || x.OpCode != T_BIG_MAP {
+			t.Errorf("FindByAnnot: unexpected %q %s", path, x.Dump())
+		}
+
+		paths := make([][]int, 0)
+		_ = p.WalkPath(func(path []int, x Prim) error {
+			paths = append(paths, append([]int{}, path...))
+			if x.OpCode == T_BIG_MAP {
+				return PrimSkip
+			}
+			return nil
+		})
+		exp := [][]int{{}, {0}, {1}, {1, 0}, {1, 1}}
+		if !reflect.DeepEqual(paths, exp) {
+			t.Errorf("WalkPath: got %v, want %v", paths, exp)
+		}
+	}
+
+	// GetPath keeps addressing raw child positions
+	var p Prim
+	if err := json.Unmarshal([]byte(comb), &p); err != nil {
+		t.Fatal(err)
+	}
+	if x, err := p.GetPath("2"); err != nil || x.GetVarAnnoAny() != "total" {
+		t.Errorf("GetPath 2: unexpected %s %v", x.Dump(), err)
+	}
+	if _, err := p.GetPath("1/1"); err == nil {
+		t.Errorf("GetPath 1/1: expected error")
+	}
+}
+
+func TestPrimMatch(t *testing.T) {
+	val := NewCombPairType(
+		NewCodeAnno(T_ADDRESS, "%owner"),
+		NewPrim(T_NAT),
+		NewCode(T_MAP, NewPrim(T_STRING), NewPrim(T_BYTES)),
+	)
+	nested := NewPairType(
+		NewCodeAnno(T_ADDRESS, "%owner"),
+		NewPairType(NewPrim(T_NAT), NewCode(T_MAP, NewPrim(T_STRING), NewPrim(T_BYTES))),
+	)
+	var tests = []struct {
+		name    string
+		pattern Prim
+		match   bool
+	}{
+		{"self", val, true},
+		{"nested", nested, true},
+		{"wildcard", InvalidPrim, true},
+		{"wildcard_args", NewPairType(InvalidPrim, InvalidPrim), true},
+		{"wildcard_leaf", NewCode(T_PAIR, NewPrim(T_ADDRESS), NewPrim(T_NAT), InvalidPrim), true},
+		{"anno", NewCode(T_PAIR, NewPrim(T_ADDRESS, "%owner"), InvalidPrim), true},
+		{"wrong_anno", NewCode(T_PAIR, NewPrim(T_ADDRESS, "%admin"), InvalidPrim), false},
+		{"wrong_type", NewCode(T_PAIR, NewPrim(T_KEY_HASH), InvalidPrim), false},
+		{"wrong_arity", NewCode(T_PAIR, InvalidPrim, InvalidPrim, InvalidPrim, InvalidPrim), false},
+	}
+	for _, test := range tests {
+		if got := val.Match(test.pattern); got != test.match {
+			t.Errorf("%s: got match=%t, want %t", test.name, got, test.match)
+		}
+	}
+	lit := NewPair(NewInt64(1), NewString("a"))
+	if !lit.Match(NewPair(Prim{Type: PrimInt}, NewString("a"))) {
+		t.Errorf("literal wildcard did not match")
+	}
+	if lit.Match(NewPair(NewInt64(2), InvalidPrim)) {
+		t.Errorf("literal value must match")
+	}
+}
+
+func TestPrimSize(t *testing.T) {
+	// literals and annotations
+	var tests = []Prim{
+		NewInt64(0),
+		NewInt64(63),
+		NewInt64(64),
+		NewInt64(-8192),
+		NewBig(new(big.Int).Lsh(big.NewInt(1), 200)),
+		NewString("hello"),
+		NewBytes(make([]byte, 33)),
+		NewPrim(T_NAT, "%a", ":b"),
+		NewCodeAnno(T_OPTION, "%x", NewPrim(T_UNIT)),
+		NewCode(T_PAIR, NewPrim(T_NAT), NewPrim(T_NAT), NewPrim(T_NAT)),
+		NewSeq(),
+	}
+	for _, p := range tests {
+		buf, err := p.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if have, want := p.Size(), len(buf); have != want {
+			t.Errorf("%s: size mismatch have=%d want=%d", p.Dump(), have, want)
+		}
+	}
+
+	// node encoded types and values from test data
+	for _, cat := range testcats {
+		scanTestFiles(t, cat)
+		var next int
+		for {
+			var tests []testcase
+			var err error
+			next, err = loadNextTestFile(cat, next, &tests)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, test := range tests {
+				for _, h := range []string{test.TypeHex, test.ValueHex, test.KeyHex} {
+					if h == "" {
+						continue
+					}
+					buf, err := hex.DecodeString(h)
+					if err != nil {
+						t.Fatal(err)
+					}
+					var p Prim
+					if err := p.UnmarshalBinary(buf); err != nil {
+						continue
+					}
+					if have, want := p.Size(), len(buf); have != want {
+						t.Errorf("%s: size mismatch have=%d want=%d", test.Name, have, want)
+					}
+				}
+			}
+		}
+	}
+}
+
+// canonicalJSONCorpus returns the types, values and keys of all node
+// responses recorded in testdata-*.
+func canonicalJSONCorpus(t *testing.T) []json.RawMessage {
+	corpus := make([]json.RawMessage, 0)
+	for _, cat := range testcats {
+		scanTestFiles(t, cat)
+		var next int
+		for {
+			var tests []testcase
+			var err error
+			next, err = loadNextTestFile(cat, next, &tests)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, test := range tests {
+				for _, v := range []json.RawMessage{test.Type, test.Value, test.Key} {
+					if len(v) == 0 || string(v) == "null" {
+						continue
+					}
+					corpus = append(corpus, v)
+				}
+			}
+		}
+	}
+	if len(corpus) == 0 {
+		t.Fatal("no node responses in testdata")
+	}
+	return corpus
+}
+
+// edge cases in node key order that must round-trip byte for byte
+var canonicalJSONEdgeCases = []string{
+	`{"bytes":"0079943a60100e0394ac1c8f6ccfaeee71ec9c2d94"}`,
+	`{"prim":"Pair","args":[{"int":"-42"},{"int":"340282366920938463463374607431768211456"},{"string":"2022-06-01T12:00:00Z"},{"string":"a<b> & \"c\"\\d\n\te/f"}]}`,
+	`[{"prim":"Elt","args":[{"string":"tz1KqTpEZ7Yob7QbPE4Hy4Wo8fHG8LhKxZSx"},{"prim":"Pair","args":[[],{"int":"0"}]}]},{"prim":"Elt","args":[{"bytes":""},{"prim":"None"}]}]`,
+	`{"prim":"big_map","args":[{"prim":"pair","args":[{"prim":"address","annots":[":owner"]},{"prim":"nat","annots":[":token_id"]}]},{"prim":"nat"}],"annots":[":ledger","%ledger"]}`,
+	`[{"prim":"DROP","args":[{"int":"2"}]},{"prim":"PUSH","args":[{"prim":"string"},{"string":"\u0001"}]},{"prim":"FAILWITH"}]`,
+}
+
+func TestPrimCanonicalJSON(t *testing.T) {
+	for i, src := range canonicalJSONCorpus(t) {
+		var p Prim
+		if err := json.Unmarshal(src, &p); err != nil {
+			t.Fatalf("%d: %v", i, err)
+		}
+		have, err := p.MarshalJSON()
+		if err != nil {
+			t.Fatal(err)
+		}
+		// test data is stored with sorted keys, so compare decoded
+		if !jsonDiff(t, have, src) {
+			t.Errorf("%d: mismatch", i)
+		}
+	}
+	for i, src := range canonicalJSONEdgeCases {
+		var p Prim
+		if err := json.Unmarshal([]byte(src), &p); err != nil {
+			t.Fatalf("edge %d: %v", i, err)
+		}
+		have, err := p.MarshalJSON()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(have) != src {
+			t.Errorf("edge %d: mismatch\n have %s\n want %s", i, have, src)
+		}
+	}
+}
+
+func TestPrimBinary(t *testing.T) {
+	corpus := canonicalJSONCorpus(t)
+	for _, src := range canonicalJSONEdgeCases {
+		corpus = append(corpus, json.RawMessage(src))
+	}
+	for i, src := range corpus {
+		var p Prim
+		if err := json.Unmarshal(src, &p); err != nil {
+			t.Fatalf("%d: %v", i, err)
+		}
+		buf, err := p.EncodeBinary()
+		if err != nil {
+			t.Fatalf("%d: %v", i, err)
+		}
+		// trailing data must not be consumed
+		q, n, err := DecodePrimBinary(append(buf, 0xff, 0xff))
+		if err != nil {
+			t.Fatalf("%d: %v", i, err)
+		}
+		if n != len(buf) {
+			t.Errorf("%d: consumed %d bytes, want %d", i, n, len(buf))
+		}
+		// variadic prims always carry an annots field which decodes to [""]
+		// when empty, so compare the re-encoded bytes instead
+		if !q.IsEqual(p) {
+			t.Errorf("%d: mismatch\n have %s\n want %s", i, q.Dump(), p.Dump())
+		}
+		if buf2, _ := q.EncodeBinary(); !bytes.Equal(buf2, buf) {
+			t.Errorf("%d: re-encoding mismatch\n have %x\n want %x", i, buf2, buf)
+		}
+		// truncated data must fail
+		if _, _, err := DecodePrimBinary(buf[:len(buf)-1]); err == nil {
+			t.Errorf("%d: expected error on truncated input", i)
+		}
+	}
+	if _, _, err := DecodePrimBinary(nil); err == nil {
+		t.Errorf("expected error on empty input")
+	}
+}
+
+func TestPrimBinaryEmptyAnno(t *testing.T) {
+	// older nodes render empty annotation fields as [""] and the binary
+	// encoding must preserve them
+	src := `{"prim":"pair","args":[{"prim":"nat","annots":[""]},{"prim":"nat"}],"annots":[""]}`
+	var p Prim
+	if err := json.Unmarshal([]byte(src), &p); err != nil {
+		t.Fatal(err)
+	}
+	buf, err := p.EncodeBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	q, _, err := DecodePrimBinary(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	have, _ := q.MarshalJSON()
+	if string(have) != src {
+		t.Errorf("mismatch\n have %s\n want %s", have, src)
+	}
+}
@@ -4,41 +4,260 @@
 package micheline
 
 import (
-// "bytes"
-// "encoding/binary"
-// "encoding/hex"
-// "encoding/json"
-// "fmt"
-// "math/big"
-// "blockwatch.cc/tzgo/tezos"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"blockwatch.cc/tzgo/tezos"
 )
 
+// SaplingDiffElem is a sapling state update contained in lazy storage diffs.
 type SaplingDiffElem struct {
 	Action   DiffAction    `json:"action"`
 	Updates  SaplingUpdate `json:"updates"`
-	MemoSize int           `json:"memo_size"`
+	MemoSize int           `json:"memo_size,omitempty"`     // alloc only
+	SourceId int64         `json:"source,string,omitempty"` // copy only
 }
 
+// SaplingUpdate contains new note commitments with their ciphertexts and
+// new nullifiers of a sapling state.
 type SaplingUpdate struct {
-	Commitments [][]byte     `json:"commitments"`
-	Ciphertexts []Ciphertext `json:"ciphertexts"`
-	Nullifiers  [][]byte     `json:"nullifiers"`
+	Commitments []tezos.HexBytes
+	Ciphertexts []Ciphertext
+	Nullifiers  []tezos.HexBytes
 }
 
+// Ciphertext is an encrypted sapling note.
 type Ciphertext struct {
-	Cv         []byte
-	Epk        []byte
-	PayloadEnc []byte
-	NonceEnc   []byte
-	PayloadOut []byte
-	NonceOut   []byte
+	Cv         tezos.HexBytes `json:"cv"`
+	Epk        tezos.HexBytes `json:"epk"`
+	PayloadEnc tezos.HexBytes `json:"payload_enc"`
+	NonceEnc   tezos.HexBytes `json:"nonce_enc"`
+	PayloadOut tezos.HexBytes `json:"payload_out"`
+	NonceOut   tezos.HexBytes `json:"nonce_out"`
+}
+
+type saplingUpdateJSON struct {
+	CommitmentsAndCiphertexts [][2]json.RawMessage `json:"commitments_and_ciphertexts"`
+	Nullifiers                []tezos.HexBytes     `json:"nullifiers"`
+}
+
+func (u *SaplingUpdate) UnmarshalJSON(data []byte) error {
+	var v saplingUpdateJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	u.Commitments = make([]tezos.HexBytes, len(v.CommitmentsAndCiphertexts))
+	u.Ciphertexts = make([]Ciphertext, len(v.CommitmentsAndCiphertexts))
+	for i, cc := range v.CommitmentsAndCiphertexts {
+		if err := json.Unmarshal(cc[0], &u.Commitments[i]); err != nil {
+			return fmt.Errorf("micheline: sapling commitment %d: %w", i, err)
+		}
+		if err := json.Unmarshal(cc[1], &u.Ciphertexts[i]); err != nil {
+			return fmt.Errorf("micheline: sapling ciphertext %d: %w", i, err)
+		}
+	}
+	u.Nullifiers = v.Nullifiers
+	return nil
+}
+
+func (u SaplingUpdate) MarshalJSON() ([]byte, error) {
+	if len(u.Commitments) != len(u.Ciphertexts) {
+		return nil, fmt.Errorf("micheline: sapling update has %d commitments and %d ciphertexts",
+			len(u.Commitments), len(u.Ciphertexts))
+	}
+	v := struct {
+		CommitmentsAndCiphertexts [][2]interface{} `json:"commitments_and_ciphertexts"`
+		Nullifiers                []tezos.HexBytes `json:"nullifiers"`
+	}{
+		CommitmentsAndCiphertexts: make([][2]interface{}, len(u.Commitments)),
+		Nullifiers:                u.Nullifiers,
+	}
+	for i := range u.Commitments {
+		v.CommitmentsAndCiphertexts[i] = [2]interface{}{u.Commitments[i], u.Ciphertexts[i]}
+	}
+	if v.Nullifiers == nil {
+		v.Nullifiers = []tezos.HexBytes{}
+	}
+	return json.Marshal(v)
+}
+
+// SaplingState is the value of a sapling_state type. In storage the state is
+// referenced by its lazy storage id, in origination scripts it is empty (-1).
+type SaplingState struct {
+	Id       int64
+	MemoSize int
+}
+
+// NewSaplingState decodes a sapling_state value with memo size taken from
+// type typ.
+func NewSaplingState(typ Type, val Prim) (SaplingState, error) {
+	s := SaplingState{Id: -1}
+	if typ.OpCode != T_SAPLING_STATE || len(typ.Args) == 0 {
+		return s, fmt.Errorf("micheline: expected sapling_state type, got %s", typ.OpCode)
+	}
+	if m := typ.Args[0].Int; m != nil {
+		s.MemoSize = int(m.Int64())
+	}
+	switch val.Type {
+	case PrimInt:
+		s.Id = val.Int.Int64()
+	case PrimSequence:
+		if len(val.Args) > 0 {
+			return s, fmt.Errorf("micheline: unexpected non-empty sapling_state value")
+		}
+	default:
+		return s, fmt.Errorf("micheline: unexpected sapling_state value type %s", val.Type)
+	}
+	return s, nil
+}
+
+// IsEmpty returns true when the state does not reference a lazy storage id.
+func (s SaplingState) IsEmpty() bool {
+	return s.Id < 0
+}
+
+// SaplingTransaction is a decoded sapling_transaction value as passed in
+// contract call parameters.
+type SaplingTransaction struct {
+	Inputs     []SaplingInput  `json:"inputs"`
+	Outputs    []SaplingOutput `json:"outputs"`
+	BindingSig tezos.HexBytes  `json:"binding_sig"` // 64 bytes
+	Balance    int64           `json:"balance"`     // net value moved in or out of the shielded pool
+	Root       tezos.HexBytes  `json:"root"`        // 32 bytes, commitment tree root the inputs refer to
+	BoundData  tezos.HexBytes  `json:"bound_data"`  // data bound to the transaction, e.g. unshield recipient
+}
+
+// SaplingInput is a spend description.
+type SaplingInput struct {
+	Cv        tezos.HexBytes `json:"cv"`        // 32 bytes value commitment
+	Nf        tezos.HexBytes `json:"nf"`        // 32 bytes nullifier
+	Rk        tezos.HexBytes `json:"rk"`        // 32 bytes re-randomized public key
+	Proof     tezos.HexBytes `json:"proof_i"`   // 192 bytes zk-SNARK proof
+	Signature tezos.HexBytes `json:"signature"` // 64 bytes spend auth signature
+}
+
+// SaplingOutput is an output description.
+type SaplingOutput struct {
+	Cm         tezos.HexBytes `json:"cm"`      // 32 bytes note commitment
+	Proof      tezos.HexBytes `json:"proof_o"` // 192 bytes zk-SNARK proof
+	Ciphertext Ciphertext     `json:"ciphertext"`
 }
 
-// TODO
-func (c Ciphertext) MarshalJSON() ([]byte, error) {
-	return []byte{}, nil
+// NewSaplingTransaction decodes sapling transaction bytes from val.
+func NewSaplingTransaction(val Prim) (*SaplingTransaction, error) {
+	if val.Type != PrimBytes {
+		return nil, fmt.Errorf("micheline: unexpected sapling_transaction value type %s", val.Type)
+	}
+	tx := &SaplingTransaction{}
+	if err := tx.UnmarshalBinary(val.Bytes); err != nil {
+		return nil, err
+	}
+	return tx, nil
 }
 
-func (c *Ciphertext) UnmarshalJSON(data []byte) error {
+func (t *SaplingTransaction) UnmarshalBinary(data []byte) error {
+	r := &saplingReader{buf: data}
+	inputs := r.dynamic()
+	for inputs.err == nil && len(inputs.buf) > 0 {
+		t.Inputs = append(t.Inputs, SaplingInput{
+			Cv:        inputs.fixed(32),
+			Nf:        inputs.fixed(32),
+			Rk:        inputs.fixed(32),
+			Proof:     inputs.fixed(192),
+			Signature: inputs.fixed(64),
+		})
+	}
+	outputs := r.dynamic()
+	for outputs.err == nil && len(outputs.buf) > 0 {
+		t.Outputs = append(t.Outputs, SaplingOutput{
+			Cm:    outputs.fixed(32),
+			Proof: outputs.fixed(192),
+			Ciphertext: Ciphertext{
+				Cv:         outputs.fixed(32),
+				Epk:        outputs.fixed(32),
+				PayloadEnc: outputs.dynamic().buf,
+				NonceEnc:   outputs.fixed(24),
+				PayloadOut: outputs.fixed(80),
+				NonceOut:   outputs.fixed(24),
+			},
+		})
+	}
+	t.BindingSig = r.fixed(64)
+	t.Balance = int64(binary.BigEndian.Uint64(r.fixed(8)))
+	t.Root = r.fixed(32)
+	t.BoundData = r.dynamic().buf
+	for _, err := range []error{inputs.err, outputs.err, r.err} {
+		if err != nil {
+			return fmt.Errorf("micheline: invalid sapling transaction: %w", err)
+		}
+	}
+	if len(r.buf) > 0 {
+		return fmt.Errorf("micheline: invalid sapling transaction: %d trailing bytes", len(r.buf))
+	}
 	return nil
 }
+
+func (t SaplingTransaction) MarshalBinary() ([]byte, error) {
+	var inputs, outputs, buf bytes.Buffer
+	for _, v := range t.Inputs {
+		inputs.Write(v.Cv)
+		inputs.Write(v.Nf)
+		inputs.Write(v.Rk)
+		inputs.Write(v.Proof)
+		inputs.Write(v.Signature)
+	}
+	for _, v := range t.Outputs {
+		outputs.Write(v.Cm)
+		outputs.Write(v.Proof)
+		outputs.Write(v.Ciphertext.Cv)
+		outputs.Write(v.Ciphertext.Epk)
+		writeSaplingDynamic(&outputs, v.Ciphertext.PayloadEnc)
+		outputs.Write(v.Ciphertext.NonceEnc)
+		outputs.Write(v.Ciphertext.PayloadOut)
+		outputs.Write(v.Ciphertext.NonceOut)
+	}
+	writeSaplingDynamic(&buf, inputs.Bytes())
+	writeSaplingDynamic(&buf, outputs.Bytes())
+	buf.Write(t.BindingSig)
+	binary.Write(&buf, binary.BigEndian, t.Balance)
+	buf.Write(t.Root)
+	writeSaplingDynamic(&buf, t.BoundData)
+	return buf.Bytes(), nil
+}
+
+func writeSaplingDynamic(buf *bytes.Buffer, data []byte) {
+	binary.Write(buf, binary.BigEndian, uint32(len(data)))
+	buf.Write(data)
+}
+
+// saplingReader reads fixed and length-prefixed fields and remembers the first error.
+type saplingReader struct {
+	buf []byte
+	err error
+}
+
+func (r *saplingReader) fixed(n int) []byte {
+	if r.err == nil && len(r.buf) < n {
+		r.err = io.ErrUnexpectedEOF
+	}
+	if r.err != nil {
+		return make([]byte, n)
+	}
+	v := r.buf[:n:n]
+	r.buf = r.buf[n:]
+	return v
+}
+
+func (r *saplingReader) dynamic() *saplingReader {
+	n := binary.BigEndian.Uint32(r.fixed(4))
+	if r.err == nil && uint64(len(r.buf)) < uint64(n) {
+		r.err = io.ErrUnexpectedEOF
+	}
+	if r.err != nil {
+		return &saplingReader{err: r.err}
+	}
+	return &saplingReader{buf: r.fixed(int(n))}
+}
@@ -0,0 +1,110 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package micheline
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"blockwatch.cc/tzgo/tezos"
+)
+
+func TestSaplingDiffJSON(t *testing.T) {
+	in := `{"action":"update","updates":{"commitments_and_ciphertexts":[["aa",{"cv":"01","epk":"02","payload_enc":"03","nonce_enc":"04","payload_out":"05","nonce_out":"06"}]],"nullifiers":["bb"]}}`
+	var d SaplingDiffElem
+	if err := json.Unmarshal([]byte(in), &d); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if d.Action != DiffActionUpdate {
+		t.Errorf("action mismatch: %s", d.Action)
+	}
+	if len(d.Updates.Commitments) != 1 || d.Updates.Commitments[0].String() != "aa" {
+		t.Errorf("commitments mismatch: %v", d.Updates.Commitments)
+	}
+	if len(d.Updates.Ciphertexts) != 1 || d.Updates.Ciphertexts[0].NonceOut.String() != "06" {
+		t.Errorf("ciphertexts mismatch: %v", d.Updates.Ciphertexts)
+	}
+	if len(d.Updates.Nullifiers) != 1 || d.Updates.Nullifiers[0].String() != "bb" {
+		t.Errorf("nullifiers mismatch: %v", d.Updates.Nullifiers)
+	}
+	buf, err := json.Marshal(d)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if string(buf) != in {
+		t.Errorf("roundtrip mismatch:\n have %s\n want %s", buf, in)
+	}
+}
+
+func TestSaplingState(t *testing.T) {
+	typ := NewType(NewCode(T_SAPLING_STATE, NewInt64(8)))
+	s, err := NewSaplingState(typ, NewInt64(5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Id != 5 || s.MemoSize != 8 || s.IsEmpty() {
+		t.Errorf("unexpected state %+v", s)
+	}
+	s, err = NewSaplingState(typ, NewSeq())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.IsEmpty() || s.MemoSize != 8 {
+		t.Errorf("unexpected empty state %+v", s)
+	}
+	if _, err := NewSaplingState(typ, NewString("x")); err == nil {
+		t.Error("expected error for string value")
+	}
+}
+
+func TestSaplingTransaction(t *testing.T) {
+	fill := func(n int, b byte) tezos.HexBytes {
+		return bytes.Repeat([]byte{b}, n)
+	}
+	tx := SaplingTransaction{
+		Inputs: []SaplingInput{{
+			Cv:        fill(32, 1),
+			Nf:        fill(32, 2),
+			Rk:        fill(32, 3),
+			Proof:     fill(192, 4),
+			Signature: fill(64, 5),
+		}},
+		Outputs: []SaplingOutput{{
+			Cm:    fill(32, 6),
+			Proof: fill(192, 7),
+			Ciphertext: Ciphertext{
+				Cv:         fill(32, 8),
+				Epk:        fill(32, 9),
+				PayloadEnc: fill(19, 10),
+				NonceEnc:   fill(24, 11),
+				PayloadOut: fill(80, 12),
+				NonceOut:   fill(24, 13),
+			},
+		}},
+		BindingSig: fill(64, 14),
+		Balance:    -1000,
+		Root:       fill(32, 15),
+		BoundData:  fill(22, 16),
+	}
+	buf, err := tx.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 4+352 + 4+(32+192+32+32+4+19+24+80+24) + 64+8+32 + 4+22
+	if len(buf) != 929 {
+		t.Errorf("unexpected encoded length %d", len(buf))
+	}
+	dec, err := NewSaplingTransaction(NewBytes(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(*dec, tx) {
+		t.Errorf("roundtrip mismatch:\n have %+v\n want %+v", *dec, tx)
+	}
+	if _, err := NewSaplingTransaction(NewBytes(buf[:len(buf)-1])); err == nil {
+		t.Error("expected error for truncated transaction")
+	}
+}
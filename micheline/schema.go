@@ -0,0 +1,269 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package micheline
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+const (
+	jsonSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+	intPattern        = "^-?[0-9]+$"
+	natPattern        = "^[0-9]+$"
+	bytesPattern      = "^0x([0-9a-fA-F]{2})*$"
+)
+
+// ToJSONSchema returns a JSON Schema document (draft 2020-12) that describes
+// values of type typ as rendered by Value.MarshalJSON with RENDER_TYPE_JSON. Pair fields become
+// object properties named after their annotations. Base58 encoded types,
+// mutez and timestamps carry a format hint such as "address", "mutez" or
+// "date-time". Lambdas and operations are left unconstrained.
+func ToJSONSchema(typ Prim) (json.RawMessage, error) {
+	if !typ.IsValid() || !typ.OpCode.IsTypeCode() {
+		return nil, fmt.Errorf("micheline: cannot build schema for non-type %s", typ.DumpLimit(64))
+	}
+	s, err := typeSchema(typ.Fold())
+	if err != nil {
+		return nil, err
+	}
+	doc := append(jsonObject{{"$schema", jsonSchemaDialect}}, s...)
+	return json.Marshal(doc)
+}
+
+func typeSchema(typ Prim) (jsonObject, error) {
+	switch typ.OpCode {
+	case T_UNIT:
+		return jsonObject{
+			{"type", "object"},
+			{"additionalProperties", false},
+		}, nil
+
+	case T_BOOL:
+		return jsonObject{{"type", "boolean"}}, nil
+
+	case T_INT:
+		return jsonObject{{"type", "string"}, {"pattern", intPattern}}, nil
+
+	case T_NAT:
+		return jsonObject{{"type", "string"}, {"pattern", natPattern}}, nil
+
+	case T_MUTEZ:
+		return jsonObject{{"type", "string"}, {"format", "mutez"}, {"pattern", natPattern}}, nil
+
+	case T_STRING:
+		return jsonObject{{"type", "string"}}, nil
+
+	case T_BYTES, T_BLS12_381_G1, T_BLS12_381_G2, T_BLS12_381_FR,
+		T_SAPLING_TRANSACTION, T_CHEST, T_CHEST_KEY:
+		return jsonObject{{"type", "string"}, {"pattern", bytesPattern}}, nil
+
+	case T_TIMESTAMP:
+		return jsonObject{{"type", "string"}, {"format", "date-time"}}, nil
+
+	case T_ADDRESS, T_CONTRACT:
+		return jsonObject{{"type", "string"}, {"format", "address"}}, nil
+
+	case T_KEY_HASH:
+		return jsonObject{{"type", "string"}, {"format", "key_hash"}}, nil
+
+	case T_KEY:
+		return jsonObject{{"type", "string"}, {"format", "key"}}, nil
+
+	case T_SIGNATURE:
+		return jsonObject{{"type", "string"}, {"format", "signature"}}, nil
+
+	case T_CHAIN_ID:
+		return jsonObject{{"type", "string"}, {"format", "chain_id"}}, nil
+
+	case T_SAPLING_STATE:
+		return jsonObject{{"type", "string"}, {"pattern", natPattern}}, nil
+
+	case T_OPTION:
+		if len(typ.Args) != 1 {
+			return nil, schemaError(typ)
+		}
+		inner, err := typeSchema(typ.Args[0])
+		if err != nil {
+			return nil, err
+		}
+		return jsonObject{{"oneOf", []interface{}{
+			jsonObject{{"type", "null"}},
+			inner,
+		}}}, nil
+
+	case T_OR:
+		branches := make([]interface{}, 0, 2)
+		if err := orSchema(typ, &branches); err != nil {
+			return nil, err
+		}
+		return jsonObject{{"oneOf", branches}}, nil
+
+	case T_PAIR:
+		props := make(jsonObject, 0, len(typ.Args))
+		if err := pairSchema(typ, &props); err != nil {
+			return nil, err
+		}
+		required := make([]string, len(props))
+		for i, f := range props {
+			required[i] = f.Name
+		}
+		return jsonObject{
+			{"type", "object"},
+			{"properties", props},
+			{"required", required},
+			{"additionalProperties", false},
+		}, nil
+
+	case T_LIST, T_SET:
+		if len(typ.Args) != 1 {
+			return nil, schemaError(typ)
+		}
+		items, err := typeSchema(typ.Args[0])
+		if err != nil {
+			return nil, err
+		}
+		s := jsonObject{{"type", "array"}, {"items", items}}
+		if typ.OpCode == T_SET {
+			s = append(s, jsonField{"uniqueItems", true})
+		}
+		return s, nil
+
+	case T_MAP, T_BIG_MAP:
+		s, err := mapSchema(typ)
+		if err != nil {
+			return nil, err
+		}
+		if typ.OpCode == T_MAP {
+			return s, nil
+		}
+		// bigmaps in storage are usually references
+		return jsonObject{{"oneOf", []interface{}{
+			jsonObject{{"type", "string"}, {"pattern", natPattern}},
+			s,
+		}}}, nil
+
+	case T_TICKET:
+		if len(typ.Args) != 1 {
+			return nil, schemaError(typ)
+		}
+		contents, err := typeSchema(typ.Args[0])
+		if err != nil {
+			return nil, err
+		}
+		ticketer, _ := typeSchema(NewPrim(T_ADDRESS))
+		amount, _ := typeSchema(NewPrim(T_NAT))
+		return jsonObject{
+			{"type", "object"},
+			{"properties", jsonObject{
+				{"ticketer", ticketer},
+				{"value", contents},
+				{"amount", amount},
+			}},
+			{"required", []string{"ticketer", "value", "amount"}},
+			{"additionalProperties", false},
+		}, nil
+
+	default:
+		// lambdas, operations and unknown types are rendered as Micheline JSON
+		return jsonObject{{"description", typ.OpCode.String() + " as Micheline JSON"}}, nil
+	}
+}
+
+// orSchema appends one schema per union branch to list. Like renderTyped each
+// branch is an object keyed by its annotation or "left"/"right".
+func orSchema(typ Prim, list *[]interface{}) error {
+	if len(typ.Args) != 2 {
+		return schemaError(typ)
+	}
+	for i, branch := range typ.Args {
+		label := branch.GetVarAnnoAny()
+		inner, err := typeSchema(branch)
+		if err != nil {
+			return err
+		}
+		if label == "" {
+			label = "left"
+			if i == 1 {
+				label = "right"
+			}
+		}
+		*list = append(*list, jsonObject{
+			{"type", "object"},
+			{"properties", jsonObject{{label, inner}}},
+			{"required", []string{label}},
+			{"additionalProperties", false},
+		})
+	}
+	return nil
+}
+
+// pairSchema appends pair field schemas to props merging unannotated nested
+// pairs and naming fields like renderPair does.
+func pairSchema(typ Prim, props *jsonObject) error {
+	if len(typ.Args) < 2 {
+		return schemaError(typ)
+	}
+	for _, t := range typ.Args {
+		if t.OpCode == T_PAIR && t.GetVarAnnoAny() == "" {
+			if err := pairSchema(t, props); err != nil {
+				return err
+			}
+			continue
+		}
+		s, err := typeSchema(t)
+		if err != nil {
+			return err
+		}
+		pos := strconv.Itoa(len(*props))
+		name := t.GetVarAnnoAny()
+		switch {
+		case name == "":
+			name = pos
+		case props.has(name):
+			name += "_" + pos
+		}
+		*props = append(*props, jsonField{name, s})
+	}
+	return nil
+}
+
+func mapSchema(typ Prim) (jsonObject, error) {
+	if len(typ.Args) != 2 {
+		return nil, schemaError(typ)
+	}
+	key, err := typeSchema(typ.Args[0])
+	if err != nil {
+		return nil, err
+	}
+	value, err := typeSchema(typ.Args[1])
+	if err != nil {
+		return nil, err
+	}
+	if isStringKey(typ.Args[0].OpCode) {
+		if typ.Args[0].OpCode == T_BOOL {
+			// property names are strings
+			key = jsonObject{{"enum", []string{"true", "false"}}}
+		}
+		return jsonObject{
+			{"type", "object"},
+			{"propertyNames", key},
+			{"additionalProperties", value},
+		}, nil
+	}
+	return jsonObject{
+		{"type", "array"},
+		{"items", jsonObject{
+			{"type", "object"},
+			{"properties", jsonObject{{"key", key}, {"value", value}}},
+			{"required", []string{"key", "value"}},
+			{"additionalProperties", false},
+		}},
+	}, nil
+}
+
+func schemaError(typ Prim) error {
+	return fmt.Errorf("micheline: invalid %s type %s", typ.OpCode, typ.DumpLimit(64))
+}
@@ -0,0 +1,82 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package micheline
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestToJSONSchema(t *testing.T) {
+	// pair (address %owner) (pair (mutez %balance) (pair (timestamp) (map %meta string bytes)))
+	typ := NewPairType(
+		NewCodeAnno(T_ADDRESS, "%owner"),
+		NewPairType(
+			NewCodeAnno(T_MUTEZ, "%balance"),
+			NewPairType(
+				NewCode(T_TIMESTAMP),
+				NewCodeAnno(T_MAP, "%meta", NewCode(T_STRING), NewCode(T_BYTES)),
+			),
+		),
+	)
+	buf, err := ToJSONSchema(typ)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var s map[string]interface{}
+	if err := json.Unmarshal(buf, &s); err != nil {
+		t.Fatalf("invalid schema json: %v", err)
+	}
+	if s["$schema"] != jsonSchemaDialect || s["type"] != "object" {
+		t.Errorf("unexpected schema header %s", string(buf))
+	}
+	want := []interface{}{"owner", "balance", "2", "meta"}
+	if got := s["required"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("required mismatch got=%v want=%v", got, want)
+	}
+	props := s["properties"].(map[string]interface{})
+	for name, format := range map[string]string{
+		"owner":   "address",
+		"balance": "mutez",
+		"2":       "date-time",
+	} {
+		if got := props[name].(map[string]interface{})["format"]; got != format {
+			t.Errorf("%s: format got=%v want=%s", name, got, format)
+		}
+	}
+	if meta := props["meta"].(map[string]interface{}); meta["type"] != "object" || meta["additionalProperties"] == nil {
+		t.Errorf("unexpected map schema %v", meta)
+	}
+
+	// or (unit %a) (or (nat %b) (list int)) keeps the nested union below "right"
+	typ = NewCode(T_OR,
+		NewCodeAnno(T_UNIT, "%a"),
+		NewCode(T_OR, NewCodeAnno(T_NAT, "%b"), NewCode(T_LIST, NewCode(T_INT))),
+	)
+	buf, err = ToJSONSchema(typ)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	s = nil
+	_ = json.Unmarshal(buf, &s)
+	branches := s["oneOf"].([]interface{})
+	if len(branches) != 2 {
+		t.Fatalf("expected 2 branches, got %d in %s", len(branches), string(buf))
+	}
+	for i, name := range []string{"a", "right"} {
+		req := branches[i].(map[string]interface{})["required"].([]interface{})
+		if len(req) != 1 || req[0] != name {
+			t.Errorf("branch %d: got=%v want=%s", i, req, name)
+		}
+	}
+	nested := branches[1].(map[string]interface{})["properties"].(map[string]interface{})["right"].(map[string]interface{})
+	if n := len(nested["oneOf"].([]interface{})); n != 2 {
+		t.Errorf("expected 2 nested branches, got %d", n)
+	}
+
+	if _, err := ToJSONSchema(NewInt64(1)); err == nil {
+		t.Errorf("expected error for non-type prim")
+	}
+}
@@ -10,7 +10,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"sort"
 	"strconv"
+	"strings"
 
 	"blockwatch.cc/tzgo/tezos"
 )
@@ -60,6 +62,12 @@ func (s *Script) Views(withPrim, withCode bool) (Views, error) {
 	views := make(Views, len(s.Code.View.Args))
 	for _, v := range s.Code.View.Args {
 		view := NewView(v)
+		if !view.IsValid() {
+			return nil, fmt.Errorf("micheline: invalid view %s", v.Dump())
+		}
+		if _, ok := views[view.Name]; ok {
+			return nil, fmt.Errorf("micheline: duplicate view %q", view.Name)
+		}
 		if !withPrim {
 			view.Prim = InvalidPrim
 		}
@@ -91,22 +99,23 @@ func (s *Script) Constants() []tezos.ExprHash {
 	return c
 }
 
-func (s *Script) ExpandConstants(dict ConstantDict) {
+// ExpandConstants replaces all global constant references in the script's code
+// with their definitions as returned by resolver. Constants that reference
+// other constants are expanded recursively. Use ConstantDict.Resolve to
+// expand from a prefetched set of constants.
+func (s *Script) ExpandConstants(resolver ConstantResolver) error {
+	exp := newConstantExpander(resolver)
 	for _, prim := range []*Prim{
 		&s.Code.Param,
 		&s.Code.Storage,
 		&s.Code.Code,
 		&s.Code.View,
 	} {
-		_ = prim.Visit(func(p *Prim) error {
-			if p.IsConstant() {
-				if c, ok := dict.GetString(p.Args[0].String); ok {
-					*p = c
-				}
-			}
-			return nil
-		})
+		if err := exp.Expand(prim); err != nil {
+			return err
+		}
 	}
+	return nil
 }
 
 // Returns the first 4 bytes of the SHA256 hash from a binary encoded parameter type
@@ -138,6 +147,112 @@ func (s *Script) CodeHash() []byte {
 	return h[:4]
 }
 
+// TzktTypeHash returns the 32-bit type hash TzKT uses to find contracts with
+// the same interface. It covers the binary encoded parameter and storage
+// sections and all views sorted by their binary encoding.
+func (s *Script) TzktTypeHash() int32 {
+	return tzktHash(s.tzktTypeSchema())
+}
+
+// TzktCodeHash returns the 32-bit code hash TzKT uses to group contracts
+// originated from identical code. It covers the type schema of TzktTypeHash
+// followed by the binary encoded code section.
+func (s *Script) TzktCodeHash() int32 {
+	buf := s.tzktTypeSchema()
+	code, _ := s.Code.Code.MarshalBinary()
+	return tzktHash(append(buf, code...))
+}
+
+func (s *Script) tzktTypeSchema() []byte {
+	param, _ := s.Code.Param.MarshalBinary()
+	storage, _ := s.Code.Storage.MarshalBinary()
+	views := make([][]byte, 0, len(s.Code.View.Args))
+	for _, v := range s.Code.View.Args {
+		buf, _ := v.MarshalBinary()
+		views = append(views, buf)
+	}
+	sort.Slice(views, func(i, j int) bool { return bytes.Compare(views[i], views[j]) < 0 })
+	buf := append(param, storage...)
+	for _, v := range views {
+		buf = append(buf, v...)
+	}
+	return buf
+}
+
+// tzktHash is the FNV-1 based 32-bit hash with extra avalanche steps used by
+// TzKT for code and type hashes.
+func tzktHash(buf []byte) int32 {
+	const prime int32 = 16777619
+	hash := int32(-2128831035) // 2166136261
+	for _, b := range buf {
+		hash = (hash ^ int32(b)) * prime
+	}
+	hash += hash << 13
+	hash ^= hash >> 7
+	hash += hash << 3
+	hash ^= hash >> 17
+	hash += hash << 5
+	return hash
+}
+
+// IsFA12 returns true when the contract implements the FA1.2 (TZIP-7)
+// token interface.
+func (s *Script) IsFA12() bool {
+	return s.Implements(ITzip7)
+}
+
+// IsFA2 returns true when the contract implements the FA2 (TZIP-12)
+// token interface.
+func (s *Script) IsFA2() bool {
+	return s.Implements(ITzip12)
+}
+
+// CodeSize returns the size in bytes of the binary encoded code section
+// including its 4 byte length prefix as stored by the protocol.
+func (s *Script) CodeSize() int {
+	return 4 + s.Code.root().Size()
+}
+
+// StorageSize returns the size in bytes of the binary encoded storage including
+// its 4 byte length prefix as stored by the protocol.
+func (s *Script) StorageSize() int {
+	return 4 + s.Storage.Size()
+}
+
+// Size returns the total size in bytes the protocol charges for storing the
+// script at origination. This matches paid_storage_size_diff of an origination
+// receipt unless the initial storage allocates bigmaps.
+func (s *Script) Size() int {
+	return s.CodeSize() + s.StorageSize()
+}
+
+// NodeCount returns the number of Micheline nodes in code and storage.
+func (s *Script) NodeCount() int {
+	return s.Code.root().NodeCount() + s.Storage.NodeCount()
+}
+
+// StorageCost returns the amount of mutez burned for storing the serialized
+// code and initial storage under params p, i.e. script size * cost_per_byte.
+// Use rpc.Constants.MapToChainParams to obtain params from a node. The fixed
+// origination burn is not included, see OriginationBurn.
+func (s *Script) StorageCost(p *tezos.Params) int64 {
+	if p == nil {
+		p = tezos.DefaultParams
+	}
+	return int64(s.Size()) * p.CostPerByte
+}
+
+// OriginationBurn estimates the total amount of mutez burned for originating
+// the script under params p, i.e. StorageCost plus the fixed origination burn
+// of origination_size * cost_per_byte (0.06425 tez on mainnet). Bigmaps
+// allocated by the initial storage are not accounted for.
+func (s *Script) OriginationBurn(p *tezos.Params) int64 {
+	if p == nil {
+		p = tezos.DefaultParams
+	}
+	return s.StorageCost(p) + p.OriginationSize*p.CostPerByte
+}
+
 // Returns a list of bigmaps referenced by a contracts current storage. Note that
 // in rare cases when storage type uses a T_OR branch above its bigmap type definitions
 // and the relevant branch is inactive/hidden the storage value lacks bigmap
@@ -207,6 +322,118 @@ func (s *Script) BigmapTypesByName() map[string]Type {
 	return named
 }
 
+// BigmapRef describes a bigmap referenced from a contract's storage value.
+type BigmapRef struct {
+	Id        int64  // bigmap id
+	Path      string // dot separated label path into storage, e.g. tokens.0.ledger
+	KeyType   Type   // bigmap key type
+	ValueType Type   // bigmap value type
+}
+
+// Key builds a bigmap key from val using the bigmap's key type.
+func (r BigmapRef) Key(val Prim) (Key, error) {
+	return NewKey(r.KeyType, val)
+}
+
+// Bigmaps returns ids of all bigmaps referenced by the current storage value
+// keyed by their label path. See BigmapRefs for details.
+func (s *Script) Bigmaps() map[string]int64 {
+	refs := s.BigmapRefs()
+	m := make(map[string]int64, len(refs))
+	for _, v := range refs {
+		m[v.Path] = v.Id
+	}
+	return m
+}
+
+// BigmapRefs resolves bigmaps referenced by the current storage value against
+// the storage type including bigmaps nested in options, unions, lists and maps.
+// Path elements are field annotations or, when missing, positions within the
+// enclosing pair. List elements are labeled by position and map values by
+// their key.
+func (s *Script) BigmapRefs() []BigmapRef {
+	typ := s.Code.Storage
+	if typ.OpCode == K_STORAGE && len(typ.Args) > 0 {
+		typ = typ.Args[0]
+	}
+	// rewrite flat combs into nested pairs first
+	val := Normalize(typ, s.Storage, NormalizeLegacy)
+	refs := make([]BigmapRef, 0)
+	findBigmaps(typ.Fold(), val, nil, &refs)
+	return refs
+}
+
+func findBigmaps(typ, val Prim, path []string, refs *[]BigmapRef) {
+	switch typ.OpCode {
+	case T_BIG_MAP:
+		if val.Type == PrimInt && len(typ.Args) == 2 {
+			*refs = append(*refs, BigmapRef{
+				Id:        val.Int.Int64(),
+				Path:      strings.Join(path, PATH_SEPARATOR),
+				KeyType:   NewType(typ.Args[0]),
+				ValueType: NewType(typ.Args[1]),
+			})
+		}
+	case T_PAIR:
+		if !(val.OpCode == D_PAIR || val.IsSequence()) {
+			return
+		}
+		leaves := pairValueLeaves(val, len(typ.Args))
+		if len(leaves) != len(typ.Args) {
+			return
+		}
+		for i, v := range typ.Args {
+			label := v.GetVarAnnoAny()
+			if label == "" {
+				label = strconv.Itoa(i)
+			}
+			findBigmaps(v, leaves[i], append(path[:len(path):len(path)], label), refs)
+		}
+	case T_OPTION:
+		if val.OpCode == D_SOME && len(val.Args) == 1 && len(typ.Args) == 1 {
+			findBigmaps(typ.Args[0], val.Args[0], path, refs)
+		}
+	case T_OR:
+		if len(val.Args) != 1 || len(typ.Args) != 2 {
+			return
+		}
+		var branch Prim
+		switch val.OpCode {
+		case D_LEFT:
+			branch = typ.Args[0]
+		case D_RIGHT:
+			branch = typ.Args[1]
+		default:
+			return
+		}
+		if label := branch.GetVarAnnoAny(); label != "" {
+			path = append(path[:len(path):len(path)], label)
+		}
+		findBigmaps(branch, val.Args[0], path, refs)
+	case T_LIST:
+		if !val.IsSequence() || len(typ.Args) != 1 {
+			return
+		}
+		for i, v := range val.Args {
+			findBigmaps(typ.Args[0], v, append(path[:len(path):len(path)], strconv.Itoa(i)), refs)
+		}
+	case T_MAP:
+		if !val.IsSequence() || len(typ.Args) != 2 {
+			return
+		}
+		for i, v := range val.Args {
+			if v.OpCode != D_ELT || len(v.Args) != 2 {
+				continue
+			}
+			label := strconv.Itoa(i)
+			if k, err := NewKey(NewType(typ.Args[0]), v.Args[0]); err == nil {
+				label = k.String()
+			}
+			findBigmaps(typ.Args[1], v.Args[1], append(path[:len(path):len(path)], label), refs)
+		}
+	}
+}
+
 func (p Script) EncodeBuffer(buf *bytes.Buffer) error {
 	// 1 write code segment
 	code, err := p.Code.MarshalBinary()
@@ -284,13 +511,8 @@ func (p *Script) UnmarshalBinary(data []byte) error {
 	return nil
 }
 
-func (c Code) MarshalBinary() ([]byte, error) {
-	buf := bytes.NewBuffer(nil)
-
-	// keep space for size
-	binary.Write(buf, binary.BigEndian, uint32(0))
-
-	// root element is a sequence
+// root returns the code section as single sequence.
+func (c Code) root() Prim {
 	root := Prim{
 		Type: PrimSequence,
 		Args: []Prim{c.Param, c.Storage, c.Code},
@@ -307,8 +529,16 @@ func (c Code) MarshalBinary() ([]byte, error) {
 			Args: []Prim{EmptyPrim, EmptyPrim, EmptyPrim, *c.BadCode},
 		}
 	}
+	return root
+}
+
+func (c Code) MarshalBinary() ([]byte, error) {
+	buf := bytes.NewBuffer(nil)
 
-	if err := root.EncodeBuffer(buf); err != nil {
+	// keep space for size
+	binary.Write(buf, binary.BigEndian, uint32(0))
+
+	if err := c.root().EncodeBuffer(buf); err != nil {
 		return nil, err
 	}
 
@@ -362,7 +592,12 @@ func (c *Code) DecodeBuffer(buf *bytes.Buffer) error {
 	return nil
 }
 
-func (c Code) MarshalJSON() ([]byte, error) {
+// Prim returns the code as single sequence of parameter, storage, code and views
+// as sent to and received from a node. Node locations refer to this tree.
+func (c Code) Prim() Prim {
+	if c.BadCode != nil {
+		return *c.BadCode
+	}
 	root := Prim{
 		Type: PrimSequence,
 		Args: []Prim{c.Param, c.Storage, c.Code},
@@ -370,10 +605,11 @@ func (c Code) MarshalJSON() ([]byte, error) {
 	if len(c.View.Args) > 0 {
 		root.Args = append(root.Args, c.View.Args...)
 	}
-	if c.BadCode != nil {
-		root = *c.BadCode
-	}
-	return json.Marshal(root)
+	return root
+}
+
+func (c Code) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.Prim())
 }
 
 func (c *Code) UnmarshalJSON(data []byte) error {
@@ -0,0 +1,238 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package micheline
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"blockwatch.cc/tzgo/tezos"
+)
+
+func TestScriptBigmapRefs(t *testing.T) {
+	// storage (pair (big_map %ledger address nat)
+	//               (pair (map %tokens string (pair (big_map %meta string bytes) nat))
+	//                     (option (big_map string bytes))))
+	raw := `{"code":[
+{"prim":"parameter","args":[{"prim":"unit"}]},
+{"prim":"storage","args":[{"prim":"pair","args":[
+  {"prim":"big_map","args":[{"prim":"address"},{"prim":"nat"}],"annots":["%ledger"]},
+  {"prim":"map","args":[{"prim":"string"},{"prim":"pair","args":[{"prim":"big_map","args":[{"prim":"string"},{"prim":"bytes"}],"annots":["%meta"]},{"prim":"nat"}]}],"annots":["%tokens"]},
+  {"prim":"option","args":[{"prim":"big_map","args":[{"prim":"string"},{"prim":"bytes"}]}]}
+]}]},
+{"prim":"code","args":[[{"prim":"CDR"},{"prim":"NIL","args":[{"prim":"operation"}]},{"prim":"PAIR"}]]}
+],
+"storage":{"prim":"Pair","args":[{"int":"511"},
+  [{"prim":"Elt","args":[{"string":"a"},{"prim":"Pair","args":[{"int":"512"},{"int":"1"}]}]},
+   {"prim":"Elt","args":[{"string":"b"},{"prim":"Pair","args":[{"int":"513"},{"int":"2"}]}]}],
+  {"prim":"Some","args":[{"int":"514"}]}]}}`
+	var s Script
+	if err := json.Unmarshal([]byte(raw), &s); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]int64{
+		"ledger":        511,
+		"tokens.a.meta": 512,
+		"tokens.b.meta": 513,
+		"2":             514,
+	}
+	have := s.Bigmaps()
+	if len(have) != len(want) {
+		t.Fatalf("unexpected bigmaps %v", have)
+	}
+	for n, id := range want {
+		if have[n] != id {
+			t.Errorf("%s: have id %d want %d", n, have[n], id)
+		}
+	}
+	refs := s.BigmapRefs()
+	if refs[0].KeyType.OpCode != T_ADDRESS || refs[0].ValueType.OpCode != T_NAT {
+		t.Errorf("unexpected ledger types %s %s", refs[0].KeyType.Dump(), refs[0].ValueType.Dump())
+	}
+}
+
+func TestScriptBigmapRefsComb(t *testing.T) {
+	// real storage in flat comb form with bigmaps below annotated nested pairs
+	for _, c := range []struct {
+		file string
+		want map[string]int64
+	}{
+		{
+			file: "testdata-mainnet/storage/KT1PYnvMA8Tso6G5LjHufuEemNJQXKfnxdGf.json",
+			want: map[string]int64{
+				"0.assets.0.ledger":       781,
+				"0.assets.0.operators":    782,
+				"0.assets.token_metadata": 783,
+				"metadata":                784,
+			},
+		},
+		{
+			file: "testdata-edonet2/storage/KT1M7keBVNkvRoc8kGaAQ3cLGWKqqcKDXiTi.json",
+			want: map[string]int64{
+				"0.futureRounds.toActivate":   28134,
+				"0.futureRounds.toDeactivate": 28135,
+				"1.metaData":                  28136,
+				"oracles":                     28137,
+			},
+		},
+	} {
+		buf, err := ioutil.ReadFile(c.file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var tests []testcase
+		if err := json.Unmarshal(buf, &tests); err != nil {
+			t.Fatal(err)
+		}
+		var s Script
+		if err := s.Code.Storage.UnmarshalJSON(tests[0].Type); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Storage.UnmarshalJSON(tests[0].Value); err != nil {
+			t.Fatal(err)
+		}
+		have := s.Bigmaps()
+		if len(have) != len(c.want) {
+			t.Errorf("%s: unexpected bigmaps %v", c.file, have)
+			continue
+		}
+		for n, id := range c.want {
+			if have[n] != id {
+				t.Errorf("%s: %s have id %d want %d", c.file, n, have[n], id)
+			}
+		}
+	}
+}
+
+func TestScriptSize(t *testing.T) {
+	// parameter unit; storage unit; code { CDR ; NIL operation ; PAIR }
+	raw := `{"code":[{"prim":"parameter","args":[{"prim":"unit"}]},{"prim":"storage","args":[{"prim":"unit"}]},{"prim":"code","args":[[{"prim":"CDR"},{"prim":"NIL","args":[{"prim":"operation"}]},{"prim":"PAIR"}]]}],"storage":{"prim":"Unit"}}`
+	var s Script
+	if err := json.Unmarshal([]byte(raw), &s); err != nil {
+		t.Fatal(err)
+	}
+	buf, err := s.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := s.Size(), len(buf); have != want {
+		t.Errorf("size mismatch with binary encoding have=%d want=%d", have, want)
+	}
+	if s.CodeSize()+s.StorageSize() != s.Size() {
+		t.Errorf("unexpected sizes code=%d storage=%d total=%d", s.CodeSize(), s.StorageSize(), s.Size())
+	}
+	if have := s.NodeCount(); have != 12 {
+		t.Errorf("unexpected node count %d", have)
+	}
+	params := &tezos.Params{OriginationSize: 257, CostPerByte: 250}
+	if have, want := s.StorageCost(params), int64(s.Size()*250); have != want {
+		t.Errorf("unexpected storage cost have=%d want=%d", have, want)
+	}
+	if have, want := s.OriginationBurn(params), int64((s.Size()+257)*250); have != want {
+		t.Errorf("unexpected burn have=%d want=%d", have, want)
+	}
+}
+
+// originationReceipt is an origination operation as returned by the node
+// reduced to the fields used for checking storage size estimates.
+type originationReceipt struct {
+	Hash     string `json:"hash"`
+	Script   Script `json:"script"`
+	Metadata struct {
+		Result struct {
+			PaidStorageSizeDiff int `json:"paid_storage_size_diff,string"`
+		} `json:"operation_result"`
+	} `json:"metadata"`
+}
+
+// TestScriptSizeReceipts compares size estimates with the storage paid by
+// originations recorded from the node in testdata-*/origination.
+func TestScriptSizeReceipts(t *testing.T) {
+	scanTestFiles(t, "origination")
+	if len(testfiles["origination"]) == 0 {
+		t.Skip("no origination receipts in testdata")
+	}
+	var next int
+	for {
+		var ops []originationReceipt
+		var err error
+		next, err = loadNextTestFile("origination", next, &ops)
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			t.Fatal(err)
+		}
+		for _, op := range ops {
+			if have, want := op.Script.Size(), op.Metadata.Result.PaidStorageSizeDiff; have != want {
+				t.Errorf("%s: size have=%d want=%d", op.Hash, have, want)
+			}
+		}
+	}
+}
+
+func TestScriptHashes(t *testing.T) {
+	specs := InterfaceSpecs[ITzip7]
+	build := func(eps []Prim, code string) *Script {
+		tree := eps[len(eps)-1]
+		for i := len(eps) - 2; i >= 0; i-- {
+			tree = NewCode(T_OR, eps[i], tree)
+		}
+		param, _ := json.Marshal(tree)
+		raw := `{"code":[{"prim":"parameter","args":[` + string(param) + `]},` +
+			`{"prim":"storage","args":[{"prim":"unit"}]},` +
+			`{"prim":"code","args":[[` + code + `]]}],"storage":{"prim":"Unit"}}`
+		var s Script
+		if err := json.Unmarshal([]byte(raw), &s); err != nil {
+			t.Fatal(err)
+		}
+		return &s
+	}
+	fail := `{"prim":"FAILWITH"}`
+	reversed := make([]Prim, len(specs))
+	for i, v := range specs {
+		reversed[len(specs)-1-i] = v
+	}
+	a := build(specs, fail)
+	b := build(reversed, fail)
+	c := build(specs, `{"prim":"DROP"},`+fail)
+
+	if !a.IsFA12() || !b.IsFA12() || a.IsFA2() {
+		t.Errorf("unexpected interface detection fa12=%t/%t fa2=%t", a.IsFA12(), b.IsFA12(), a.IsFA2())
+	}
+	if !bytes.Equal(a.InterfaceHash(), c.InterfaceHash()) || bytes.Equal(a.InterfaceHash(), b.InterfaceHash()) {
+		t.Errorf("interface hash must cover the parameter type only")
+	}
+	if !bytes.Equal(a.CodeHash(), b.CodeHash()) || bytes.Equal(a.CodeHash(), c.CodeHash()) {
+		t.Errorf("code hash must cover the code section only")
+	}
+	if !bytes.Equal(a.StorageHash(), b.StorageHash()) {
+		t.Errorf("storage hash mismatch for equal storage types")
+	}
+	if a.TzktTypeHash() != c.TzktTypeHash() || a.TzktTypeHash() == b.TzktTypeHash() {
+		t.Errorf("tzkt type hash must not depend on code")
+	}
+	if a.TzktCodeHash() == c.TzktCodeHash() || a.TzktCodeHash() != build(specs, fail).TzktCodeHash() {
+		t.Errorf("tzkt code hash is not stable")
+	}
+	// parameter unit; storage unit; code { CDR ; NIL operation ; PAIR }
+	var e Script
+	raw := `{"code":[{"prim":"parameter","args":[{"prim":"unit"}]},{"prim":"storage","args":[{"prim":"unit"}]},{"prim":"code","args":[[{"prim":"CDR"},{"prim":"NIL","args":[{"prim":"operation"}]},{"prim":"PAIR"}]]}],"storage":{"prim":"Unit"}}`
+	if err := json.Unmarshal([]byte(raw), &e); err != nil {
+		t.Fatal(err)
+	}
+	if have, want := e.TzktTypeHash(), int32(1288388636); have != want {
+		t.Errorf("tzkt type hash have=%d want=%d", have, want)
+	}
+	if have, want := e.TzktCodeHash(), int32(-488288240); have != want {
+		t.Errorf("tzkt code hash have=%d want=%d", have, want)
+	}
+	d := build(specs[:len(specs)-1], fail)
+	if d.IsFA12() {
+		t.Errorf("incomplete interface not detected")
+	}
+}
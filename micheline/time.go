@@ -0,0 +1,37 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package micheline
+
+import (
+	"fmt"
+	"math/big"
+	"time"
+
+	"blockwatch.cc/tzgo/tezos"
+)
+
+// NewTimestamp returns a timestamp primitive in optimized form (unix seconds).
+func NewTimestamp(t time.Time) Prim {
+	return NewBig(big.NewInt(t.Unix()))
+}
+
+// Time decodes a timestamp primitive. Both the optimized form (unix seconds
+// as int) and the readable form (RFC3339 string) are supported.
+func (p Prim) Time() (time.Time, error) {
+	switch p.Type {
+	case PrimInt:
+		if p.Int == nil || !p.Int.IsInt64() {
+			return time.Time{}, fmt.Errorf("micheline: timestamp out of range")
+		}
+		return time.Unix(p.Int.Int64(), 0).UTC(), nil
+	case PrimString:
+		t, err := tezos.ParseTimestamp(p.String)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return t.Time(), nil
+	default:
+		return time.Time{}, fmt.Errorf("micheline: unexpected prim type %s for timestamp", p.Type)
+	}
+}
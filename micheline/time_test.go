@@ -0,0 +1,68 @@
+// Copyright (c) 2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+//
+
+package micheline
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func TestPrimTime(t *testing.T) {
+	want := time.Date(2022, 3, 14, 15, 9, 26, 0, time.UTC)
+	for _, p := range []Prim{
+		NewTimestamp(want),
+		NewString("2022-03-14T15:09:26Z"),
+		NewString("2022-03-14T16:09:26+01:00"),
+	} {
+		// round-trip through binary and JSON encodings
+		buf, err := p.MarshalBinary()
+		if err != nil {
+			t.Fatalf("%s: marshal binary: %v", p.Dump(), err)
+		}
+		var p2 Prim
+		if err := p2.UnmarshalBinary(buf); err != nil {
+			t.Fatalf("%s: unmarshal binary: %v", p.Dump(), err)
+		}
+		buf, _ = json.Marshal(p2)
+		var p3 Prim
+		if err := json.Unmarshal(buf, &p3); err != nil {
+			t.Fatalf("%s: unmarshal json: %v", p.Dump(), err)
+		}
+		tm, err := p3.Time()
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", p.Dump(), err)
+		}
+		if !tm.Equal(want) {
+			t.Errorf("%s: time mismatch got=%s want=%s", p.Dump(), tm, want)
+		}
+	}
+	if _, err := NewBytes([]byte{1}).Time(); err == nil {
+		t.Errorf("expected error for bytes prim")
+	}
+}
+
+func TestPrimValueTimestamp(t *testing.T) {
+	want := time.Date(2022, 3, 14, 15, 9, 26, 0, time.UTC)
+	for _, p := range []Prim{
+		NewInt64(1647270566),
+		NewString("1647270566"),
+		NewString("2022-03-14T16:09:26+01:00"),
+	} {
+		tm, ok := p.Value(T_TIMESTAMP).(time.Time)
+		if !ok {
+			t.Fatalf("%s: expected time.Time, got %T", p.Dump(), p.Value(T_TIMESTAMP))
+		}
+		if !tm.Equal(want) || tm.Location() != time.UTC {
+			t.Errorf("%s: time mismatch got=%s want=%s", p.Dump(), tm, want)
+		}
+	}
+	// out of range values render as numbers
+	p := NewBig(new(big.Int).Lsh(big.NewInt(1), 70))
+	if _, ok := p.Value(T_TIMESTAMP).(string); !ok {
+		t.Errorf("expected string for out of range timestamp")
+	}
+}
@@ -5,7 +5,11 @@
 package micheline
 
 import (
+	"bytes"
+	"reflect"
 	"testing"
+
+	"blockwatch.cc/tzgo/tezos"
 )
 
 type typedefTest struct {
@@ -204,3 +208,195 @@ func TestTypeRendering(t *testing.T) {
 		})
 	}
 }
+
+func TestPrimLocate(t *testing.T) {
+	// { parameter unit ; storage unit ; code { CDR ; NIL operation ; PAIR } }
+	code := NewSeq(
+		NewCode(K_PARAMETER, NewCode(T_UNIT)),
+		NewCode(K_STORAGE, NewCode(T_UNIT)),
+		NewCode(K_CODE, NewSeq(
+			NewCode(I_CDR),
+			NewCode(I_NIL, NewCode(T_OPERATION)),
+			NewCode(I_PAIR),
+		)),
+	)
+	const seq = OpCode(255)
+	for loc, op := range []OpCode{seq, K_PARAMETER, T_UNIT, K_STORAGE, T_UNIT, K_CODE, seq, I_CDR, I_NIL, T_OPERATION, I_PAIR} {
+		p, ok := code.Locate(loc)
+		if !ok {
+			t.Fatalf("location %d not found", loc)
+		}
+		if op != seq && p.OpCode != op {
+			t.Errorf("location %d: want %s, got %s", loc, op, p.OpCode)
+		}
+		if op == seq && p.Type != PrimSequence {
+			t.Errorf("location %d: want sequence, got %s", loc, p.Type)
+		}
+	}
+	if _, ok := code.Locate(11); ok {
+		t.Errorf("location 11 should not exist")
+	}
+}
+
+func TestPrimEqual(t *testing.T) {
+	a, b, c := NewInt64(1), NewString("b"), NewBytes([]byte{0xc})
+
+	// comb pair values
+	comb := NewCode(D_PAIR, a, b, c)
+	nested := NewPair(a, NewPair(b, c))
+	left := NewPair(NewPair(a, b), c)
+	if !comb.Equal(nested) || !nested.Equal(comb) {
+		t.Errorf("comb pair should equal nested pair")
+	}
+	if comb.Hash() != nested.Hash() {
+		t.Errorf("comb pair hash mismatch %x != %x", comb.Hash(), nested.Hash())
+	}
+	if comb.Equal(left) {
+		t.Errorf("comb pair should not equal left-nested pair")
+	}
+
+	// comb pair types in sequence form, annotations
+	typ := NewCombPairType(NewPrim(T_NAT, "%a"), NewPrim(T_STRING, "%b"), NewPrim(T_BYTES, "%c"))
+	ntyp := NewPairType(NewPrim(T_NAT, "%a"), NewPairType(NewPrim(T_STRING, "%b"), NewPrim(T_BYTES, "%c")))
+	plain := NewPairType(NewPrim(T_NAT), NewPairType(NewPrim(T_STRING), NewPrim(T_BYTES)))
+	if !typ.EqualWithAnno(ntyp) {
+		t.Errorf("comb pair type should equal nested pair type")
+	}
+	if !typ.Equal(plain) || typ.Hash() != plain.Hash() {
+		t.Errorf("annotations should be ignored for value equality")
+	}
+	if typ.EqualWithAnno(plain) {
+		t.Errorf("annotations should be compared for type equality")
+	}
+
+	// scalars and type tags
+	if NewInt64(1).Equal(NewInt64(-1)) || NewInt64(1).Hash() == NewInt64(-1).Hash() {
+		t.Errorf("int sign should be compared")
+	}
+	if NewString("1").Equal(NewInt64(1)) {
+		t.Errorf("prim types should be compared")
+	}
+	if NewBytes(nil).Equal(NewString("")) {
+		t.Errorf("empty bytes should not equal empty string")
+	}
+	if !NewSeq(a, b).Equal(NewSeq(a, b)) || NewSeq(a, b).Equal(NewSeq(b, a)) {
+		t.Errorf("sequence equality mismatch")
+	}
+}
+
+func TestPrimFold(t *testing.T) {
+	a, b, c, d := NewInt64(1), NewString("b"), NewBytes([]byte{0xc}), NewInt64(4)
+	nested := NewPair(a, NewPair(b, NewPair(c, d)))
+	comb := NewCode(D_PAIR, a, b, c, d)
+
+	if f := nested.Fold(); !reflect.DeepEqual(f, comb) {
+		t.Errorf("fold mismatch: %s", f.Dump())
+	}
+	if u := comb.Unfold(); !reflect.DeepEqual(u, nested) {
+		t.Errorf("unfold mismatch: %s", u.Dump())
+	}
+
+	// annotated nested pair types are kept
+	typ := NewPairType(NewPrim(T_NAT), NewPairType(NewPrim(T_STRING), NewPairType(NewPrim(T_BYTES), NewPrim(T_INT)), "%x"))
+	ft := typ.Fold()
+	if len(ft.Args) != 2 || len(ft.Args[1].Args) != 3 || ft.Args[1].Type != PrimVariadicAnno {
+		t.Errorf("type fold mismatch: %s", ft.Dump())
+	}
+	if ut := ft.Unfold(); !ut.EqualWithAnno(typ) || !reflect.DeepEqual(ut, typ) {
+		t.Errorf("type unfold mismatch: %s", ut.Dump())
+	}
+
+	// value and type are folded consistently, also for values in sequence form
+	val := NewValue(Type{typ}, NewSeq(a, NewSeq(b, c, d)))
+	fv := val.Fold()
+	want := NewPair(a, NewCode(D_PAIR, b, c, d))
+	if !reflect.DeepEqual(fv.Value, want) || !reflect.DeepEqual(fv.Type.Prim, ft) {
+		t.Errorf("value fold mismatch: %s", fv.Value.Dump())
+	}
+	uv := val.Unfold()
+	if !reflect.DeepEqual(uv.Value, nested) || !reflect.DeepEqual(uv.Type.Prim, typ) {
+		t.Errorf("value unfold mismatch: %s", uv.Value.Dump())
+	}
+	if !fv.Value.Equal(uv.Value) {
+		t.Errorf("folded and unfolded values should be equal")
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	addr := tezos.MustParseAddress("KT1GyeRktoGPEKsWpchWguyy8FAf3aNHkw2T")
+	key := tezos.MustParseKey("edpkuBknW28nW72KG6RoHtYW7p12T6GKc7nAbwYX5m8Wd9sDVC9yav")
+	typ := NewPairType(
+		NewPrim(T_ADDRESS),
+		NewPairType(NewPrim(T_KEY), NewPairType(NewPrim(T_TIMESTAMP), NewPrim(T_NAT)), "%x"),
+	)
+	optimized := NewSeq(
+		NewBytes(append(addr.Bytes22(), "transfer"...)),
+		NewBytes(key.Bytes()),
+		NewInt64(1577836800),
+		NewInt64(7),
+	)
+	readable := NewCode(D_PAIR,
+		NewString(addr.String()+"%transfer"),
+		NewString(key.String()),
+		NewString("2020-01-01T00:00:00Z"),
+		NewInt64(7),
+	)
+	if v := Normalize(typ, optimized, NormalizeReadable); !reflect.DeepEqual(v, readable) {
+		t.Errorf("readable mismatch: %s", v.Dump())
+	}
+	if v := Normalize(typ, readable, NormalizeOptimized); !reflect.DeepEqual(v, optimized) {
+		t.Errorf("optimized mismatch: %s", v.Dump())
+	}
+	legacy := Normalize(typ, readable, NormalizeLegacy)
+	if legacy.OpCode != D_PAIR || len(legacy.Args) != 2 || len(legacy.Args[1].Args) != 2 {
+		t.Errorf("legacy mismatch: %s", legacy.Dump())
+	}
+	if v := Normalize(typ, optimized, NormalizeLegacy); !reflect.DeepEqual(v, legacy) {
+		t.Errorf("legacy mismatch: %s", v.Dump())
+	}
+}
+
+func TestNormalizeSignature(t *testing.T) {
+	typ := NewPrim(T_SIGNATURE)
+	for _, sig := range []tezos.Signature{
+		tezos.NewSignature(tezos.SignatureTypeGeneric, bytes.Repeat([]byte{0xaa}, 64)),
+		tezos.NewSignature(tezos.SignatureTypeBls12_381, bytes.Repeat([]byte{0xbb}, 96)),
+	} {
+		optimized, readable := NewBytes(sig.Data), NewString(sig.String())
+		if v := Normalize(typ, optimized, NormalizeReadable); !reflect.DeepEqual(v, readable) {
+			t.Errorf("%s: readable mismatch: %s", sig.Type, v.Dump())
+		}
+		if v := Normalize(typ, readable, NormalizeOptimized); !reflect.DeepEqual(v, optimized) {
+			t.Errorf("%s: optimized mismatch: %s", sig.Type, v.Dump())
+		}
+	}
+	// other lengths are kept
+	bad := NewBytes(bytes.Repeat([]byte{0xcc}, 65))
+	if v := Normalize(typ, bad, NormalizeReadable); !reflect.DeepEqual(v, bad) {
+		t.Errorf("invalid signature converted: %s", v.Dump())
+	}
+}
+
+func TestDecorate(t *testing.T) {
+	addr := tezos.MustParseAddress("tz1KqTpEZ7Yob7QbPE4Hy4Wo8fHG8LhKxZSx")
+	chain := tezos.MustParseChainIdHash("NetXdQprcVkpaWU")
+	typ := NewPairType(
+		NewPrim(T_ADDRESS),
+		NewPairType(NewPrim(T_CHAIN_ID), NewCode(T_OPTION, NewPrim(T_TIMESTAMP))),
+	)
+	// nested binary pair form must be kept
+	optimized := NewPair(
+		NewBytes(append(addr.Bytes22(), "mint"...)),
+		NewPair(NewBytes(chain.Bytes()), NewCode(D_SOME, NewInt64(0))),
+	)
+	readable := NewPair(
+		NewString(addr.String()+"%mint"),
+		NewPair(NewString(chain.String()), NewCode(D_SOME, NewString("1970-01-01T00:00:00Z"))),
+	)
+	if v := optimized.Decorate(typ); !reflect.DeepEqual(v, readable) {
+		t.Errorf("decorate mismatch: %s", v.Dump())
+	}
+	if v := readable.Optimize(typ); !reflect.DeepEqual(v, optimized) {
+		t.Errorf("optimize mismatch: %s", v.Dump())
+	}
+}
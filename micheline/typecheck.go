@@ -0,0 +1,323 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package micheline
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"blockwatch.cc/tzgo/tezos"
+)
+
+// TypeError is returned by TypeCheck when a value does not match its type.
+// Path is a dot separated list of field annotations, pair positions, list
+// positions and map keys pointing to the offending value.
+type TypeError struct {
+	Path string
+	Msg  string
+}
+
+func (e *TypeError) Error() string {
+	if e.Path == "" {
+		return "micheline: " + e.Msg
+	}
+	return "micheline: at " + e.Path + ": " + e.Msg
+}
+
+// TypeCheck validates value val against type typ similar to the protocol's
+// typechecker. It checks primitive compatibility, pair and union arity in any
+// comb form, option, list, set and map elements, tickets, comparability of set
+// elements and map keys and the format of readable addresses, keys,
+// signatures, chain ids and timestamps. Lambdas are accepted without checking their code.
+func TypeCheck(typ, val Prim) error {
+	return typeCheck(typ, val, "")
+}
+
+func typeCheck(typ, val Prim, path string) error {
+	// constants cannot be checked without expansion
+	if val.OpCode == H_CONSTANT {
+		return nil
+	}
+	switch typ.OpCode {
+	case T_UNIT:
+		if val.OpCode != D_UNIT || val.Type == PrimSequence {
+			return mismatch(path, typ, val)
+		}
+	case T_BOOL:
+		if !(val.OpCode == D_TRUE || val.OpCode == D_FALSE) || !isPrimCode(val) {
+			return mismatch(path, typ, val)
+		}
+	case T_INT:
+		if val.Type != PrimInt {
+			return mismatch(path, typ, val)
+		}
+	case T_NAT, T_MUTEZ:
+		if val.Type != PrimInt {
+			return mismatch(path, typ, val)
+		}
+		if val.Int.Sign() < 0 {
+			return typeErr(path, "expected %s, got negative int %s", typ.OpCode, val.Int)
+		}
+		if typ.OpCode == T_MUTEZ && !val.Int.IsInt64() {
+			return typeErr(path, "mutez overflow %s", val.Int)
+		}
+	case T_STRING:
+		if val.Type != PrimString {
+			return mismatch(path, typ, val)
+		}
+		for i, c := range []byte(val.String) {
+			if (c < ' ' || c > '~') && c != '\n' {
+				return typeErr(path, "invalid character 0x%02x at position %d in string", c, i)
+			}
+		}
+	case T_BYTES, T_BLS12_381_G1, T_BLS12_381_G2, T_BLS12_381_FR,
+		T_SAPLING_TRANSACTION, T_CHEST, T_CHEST_KEY:
+		if val.Type != PrimBytes {
+			return mismatch(path, typ, val)
+		}
+	case T_TIMESTAMP:
+		if val.Type != PrimInt && val.Type != PrimString {
+			return mismatch(path, typ, val)
+		}
+		if _, err := val.Time(); err != nil {
+			return typeErr(path, "invalid timestamp %s", describe(val))
+		}
+	case T_ADDRESS, T_CONTRACT, T_KEY_HASH, T_KEY, T_SIGNATURE, T_CHAIN_ID:
+		return checkHash(typ.OpCode, val, path)
+	case T_OPTION:
+		if err := checkArity(typ, 1, path); err != nil {
+			return err
+		}
+		switch {
+		case val.OpCode == D_NONE && isPrimCode(val) && len(val.Args) == 0:
+		case val.OpCode == D_SOME && isPrimCode(val) && len(val.Args) == 1:
+			return typeCheck(typ.Args[0], val.Args[0], path)
+		default:
+			return mismatch(path, typ, val)
+		}
+	case T_OR:
+		if err := checkArity(typ, 2, path); err != nil {
+			return err
+		}
+		if !(val.OpCode == D_LEFT || val.OpCode == D_RIGHT) || !isPrimCode(val) || len(val.Args) != 1 {
+			return mismatch(path, typ, val)
+		}
+		branch := typ.Args[0]
+		if val.OpCode == D_RIGHT {
+			branch = typ.Args[1]
+		}
+		return typeCheck(branch, val.Args[0], joinPath(path, branch.GetVarAnnoAny()))
+	case T_PAIR:
+		types := combLeaves(typ, false)
+		if !((val.OpCode == D_PAIR && isPrimCode(val)) || val.IsSequence()) {
+			return mismatch(path, typ, val)
+		}
+		if len(val.Args) < 2 {
+			return typeErr(path, "expected pair with %d fields, got %d", len(types), len(val.Args))
+		}
+		leaves := pairValueLeaves(val, len(types))
+		if len(leaves) != len(types) {
+			return typeErr(path, "expected pair with %d fields, got %d", len(types), len(leaves))
+		}
+		for i, t := range types {
+			label := t.GetVarAnnoAny()
+			if label == "" {
+				label = strconv.Itoa(i)
+			}
+			if err := typeCheck(t, leaves[i], joinPath(path, label)); err != nil {
+				return err
+			}
+		}
+	case T_LIST, T_SET:
+		if err := checkArity(typ, 1, path); err != nil {
+			return err
+		}
+		if !val.IsSequence() {
+			return mismatch(path, typ, val)
+		}
+		if typ.OpCode == T_SET && !isComparable(typ.Args[0]) {
+			return typeErr(path, "set element type %s is not comparable", typ.Args[0].OpCode)
+		}
+		for i, v := range val.Args {
+			if err := typeCheck(typ.Args[0], v, joinPath(path, strconv.Itoa(i))); err != nil {
+				return err
+			}
+		}
+	case T_MAP, T_BIG_MAP:
+		if err := checkArity(typ, 2, path); err != nil {
+			return err
+		}
+		// big_map values may refer to an existing bigmap by id
+		if typ.OpCode == T_BIG_MAP && val.Type == PrimInt {
+			return nil
+		}
+		if !val.IsSequence() {
+			return mismatch(path, typ, val)
+		}
+		if !isComparable(typ.Args[0]) {
+			return typeErr(path, "map key type %s is not comparable", typ.Args[0].OpCode)
+		}
+		for i, v := range val.Args {
+			label := joinPath(path, strconv.Itoa(i))
+			if v.OpCode != D_ELT || !isPrimCode(v) || len(v.Args) != 2 {
+				return typeErr(label, "expected Elt, got %s", describe(v))
+			}
+			if err := typeCheck(typ.Args[0], v.Args[0], label); err != nil {
+				return err
+			}
+			if k, err := NewKey(NewType(typ.Args[0]), v.Args[0]); err == nil {
+				label = joinPath(path, k.String())
+			}
+			if err := typeCheck(typ.Args[1], v.Args[1], label); err != nil {
+				return err
+			}
+		}
+	case T_TICKET:
+		if err := checkArity(typ, 1, path); err != nil {
+			return err
+		}
+		// tickets are pairs of ticketer, contents and amount
+		ticket := NewPairType(
+			NewCodeAnno(T_ADDRESS, "%ticketer"),
+			NewPairType(typ.Args[0], NewCodeAnno(T_NAT, "%amount")),
+		)
+		return typeCheck(ticket, val, path)
+	case T_LAMBDA:
+		if !val.IsSequence() {
+			return mismatch(path, typ, val)
+		}
+	case T_SAPLING_STATE:
+		if !val.IsSequence() && val.Type != PrimInt {
+			return mismatch(path, typ, val)
+		}
+	case T_NEVER:
+		return typeErr(path, "type never has no values")
+	case T_OPERATION:
+		return typeErr(path, "operation values cannot be constructed")
+	}
+	return nil
+}
+
+func checkArity(typ Prim, n int, path string) error {
+	if len(typ.Args) != n {
+		return typeErr(path, "invalid type %s with %d arguments", typ.OpCode, len(typ.Args))
+	}
+	return nil
+}
+
+func checkHash(typ OpCode, val Prim, path string) error {
+	switch val.Type {
+	case PrimBytes:
+		var ok bool
+		switch n := len(val.Bytes); typ {
+		case T_ADDRESS, T_CONTRACT:
+			ok = n >= 22 && tezos.IsAddressBytes(val.Bytes[:22])
+		case T_KEY_HASH:
+			ok = n == 21
+		case T_KEY:
+			var k tezos.Key
+			ok = k.UnmarshalBinary(val.Bytes) == nil
+		case T_SIGNATURE:
+			// generic or BLS12-381 signature without tag
+			ok = n == tezos.SignatureTypeGeneric.Len() || n == tezos.SignatureTypeBls12_381.Len()
+		case T_CHAIN_ID:
+			ok = n == 4
+		}
+		if !ok {
+			return typeErr(path, "invalid %s bytes 0x%s", typ, hex.EncodeToString(val.Bytes))
+		}
+	case PrimString:
+		var err error
+		switch typ {
+		case T_ADDRESS, T_CONTRACT:
+			s := val.String
+			if i := strings.IndexByte(s, '%'); i >= 0 {
+				if len(s)-i-1 > 31 {
+					return typeErr(path, "entrypoint name too long in %q", s)
+				}
+				s = s[:i]
+			}
+			_, err = tezos.ParseAddress(s)
+		case T_KEY_HASH:
+			var a tezos.Address
+			if a, err = tezos.ParseAddress(val.String); err == nil && !a.IsEOA() {
+				err = fmt.Errorf("not an implicit account")
+			}
+		case T_KEY:
+			_, err = tezos.ParseKey(val.String)
+		case T_SIGNATURE:
+			_, err = tezos.ParseSignature(val.String)
+		case T_CHAIN_ID:
+			_, err = tezos.ParseChainIdHash(val.String)
+		}
+		if err != nil {
+			return typeErr(path, "invalid %s %q", typ, val.String)
+		}
+	default:
+		return typeErr(path, "expected %s, got %s", typ, describe(val))
+	}
+	return nil
+}
+
+// isComparable returns true for types that may be used as set elements and
+// map keys.
+func isComparable(typ Prim) bool {
+	switch typ.OpCode {
+	case T_UNIT, T_NEVER, T_BOOL, T_INT, T_NAT, T_STRING, T_CHAIN_ID, T_BYTES,
+		T_MUTEZ, T_KEY_HASH, T_KEY, T_SIGNATURE, T_TIMESTAMP, T_ADDRESS:
+		return true
+	case T_PAIR, T_OPTION, T_OR:
+		for _, v := range typ.Args {
+			if !isComparable(v) {
+				return false
+			}
+		}
+		return len(typ.Args) > 0
+	default:
+		return false
+	}
+}
+
+// isPrimCode returns true when p is a primitive application and not a literal
+// or sequence whose zero opcode may alias a data constructor.
+func isPrimCode(p Prim) bool {
+	switch p.Type {
+	case PrimInt, PrimString, PrimBytes, PrimSequence:
+		return false
+	}
+	return true
+}
+
+func mismatch(path string, typ, val Prim) error {
+	return typeErr(path, "expected %s, got %s", typ.OpCode, describe(val))
+}
+
+func typeErr(path, format string, args ...interface{}) error {
+	return &TypeError{Path: path, Msg: fmt.Sprintf(format, args...)}
+}
+
+func joinPath(path, label string) string {
+	if label == "" {
+		return path
+	}
+	return path + PATH_SEPARATOR + label
+}
+
+// describe renders a short description of a value for error messages.
+func describe(p Prim) string {
+	switch p.Type {
+	case PrimInt:
+		return "int " + p.Int.String()
+	case PrimString:
+		return strconv.Quote(limit(p.String, 64))
+	case PrimBytes:
+		return "bytes 0x" + limit(hex.EncodeToString(p.Bytes), 64)
+	case PrimSequence:
+		return "sequence of " + strconv.Itoa(len(p.Args))
+	default:
+		return p.OpCode.String()
+	}
+}
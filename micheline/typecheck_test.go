@@ -0,0 +1,95 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package micheline
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestTypeCheck(t *testing.T) {
+	// pair (address %spender) (nat %value) (map %data string (option bytes))
+	typ := `{"prim":"pair","args":[{"prim":"address","annots":["%spender"]},{"prim":"nat","annots":["%value"]},{"prim":"map","args":[{"prim":"string"},{"prim":"option","args":[{"prim":"bytes"}]}],"annots":["%data"]}]}`
+	var tests = []struct {
+		val string
+		err string
+	}{
+		{`{"prim":"Pair","args":[{"string":"tz1KqTpEZ7Yob7QbPE4Hy4Wo8fHG8LhKxZSx"},{"int":"1"},[{"prim":"Elt","args":[{"string":"a"},{"prim":"None"}]}]]}`, ``},
+		{`[{"string":"KT1GyeRktoGPEKsWpchWguyy8FAf3aNHkw2T%transfer"},{"int":"1"},[]]`, ``},
+		{`{"prim":"Pair","args":[{"bytes":"0000b4a2a8e6e5cbeee2fb7d0fa3ad6e42c89d40a87e"},{"prim":"Pair","args":[{"int":"1"},[]]}]}`, ``},
+		{`{"prim":"Pair","args":[{"int":"5"},{"int":"1"},[]]}`, `micheline: at .spender: expected address, got int 5`},
+		{`{"prim":"Pair","args":[{"string":"tz1KqTpEZ7Yob7QbPE4Hy4Wo8fHG8LhKxZSx"},{"int":"-1"},[]]}`, `micheline: at .value: expected nat, got negative int -1`},
+		{`{"prim":"Pair","args":[{"string":"tz1KqTpEZ7Yob7QbPE4Hy4Wo8fHG8LhKxZSx"},{"int":"1"}]}`, `micheline: expected pair with 3 fields, got 2`},
+		{`{"prim":"Pair","args":[{"string":"tz1KqTpEZ7Yob7QbPE4Hy4Wo8fHG8LhKxZSx"},{"int":"1"},[{"prim":"Elt","args":[{"string":"a"},{"bytes":"00"}]}]]}`, `micheline: at .data.a: expected option, got bytes 0x00`},
+		{`{"prim":"Pair","args":[{"string":"tz1invalid"},{"int":"1"},[]]}`, `micheline: at .spender: invalid address "tz1invalid"`},
+		{`{"prim":"Left","args":[{"int":"1"}]}`, `micheline: expected pair, got Left`},
+	}
+	var tp Prim
+	if err := json.Unmarshal([]byte(typ), &tp); err != nil {
+		t.Fatal(err)
+	}
+	for i, test := range tests {
+		var val Prim
+		if err := json.Unmarshal([]byte(test.val), &val); err != nil {
+			t.Fatalf("%d: %v", i, err)
+		}
+		err := TypeCheck(tp, val)
+		switch {
+		case test.err == "" && err != nil:
+			t.Errorf("%d: unexpected error %v", i, err)
+		case test.err != "" && (err == nil || err.Error() != test.err):
+			t.Errorf("%d: have error %v want %s", i, err, test.err)
+		}
+	}
+
+	// non-comparable map key
+	var bad Prim
+	_ = json.Unmarshal([]byte(`{"prim":"set","args":[{"prim":"list","args":[{"prim":"int"}]}]}`), &bad)
+	if err := TypeCheck(bad, NewSeq()); err == nil {
+		t.Errorf("expected comparable error")
+	}
+}
+
+func TestTypeCheckTypes(t *testing.T) {
+	sig64 := `{"bytes":"` + strings.Repeat("ab", 64) + `"}`
+	sig96 := `{"bytes":"` + strings.Repeat("ab", 96) + `"}`
+	var tests = []struct {
+		typ string
+		val string
+		err string
+	}{
+		{`{"prim":"signature"}`, sig64, ``},
+		{`{"prim":"signature"}`, sig96, ``},
+		{`{"prim":"signature"}`, `{"bytes":"` + strings.Repeat("ab", 65) + `"}`, `micheline: invalid signature bytes 0x` + strings.Repeat("ab", 65)},
+		// malformed types
+		{`{"prim":"option"}`, `{"prim":"None"}`, `micheline: invalid type option with 0 arguments`},
+		{`{"prim":"or","args":[{"prim":"int"}]}`, `{"prim":"Right","args":[{"int":"1"}]}`, `micheline: invalid type or with 1 arguments`},
+		{`{"prim":"set"}`, `[]`, `micheline: invalid type set with 0 arguments`},
+		{`{"prim":"map","args":[{"prim":"int"}]}`, `[]`, `micheline: invalid type map with 1 arguments`},
+		{`{"prim":"ticket"}`, `[]`, `micheline: invalid type ticket with 0 arguments`},
+		// tickets
+		{`{"prim":"ticket","args":[{"prim":"string"}]}`, `{"prim":"Pair","args":[{"string":"KT1GyeRktoGPEKsWpchWguyy8FAf3aNHkw2T"},{"prim":"Pair","args":[{"string":"a"},{"int":"1"}]}]}`, ``},
+		{`{"prim":"ticket","args":[{"prim":"string"}]}`, `[{"string":"KT1GyeRktoGPEKsWpchWguyy8FAf3aNHkw2T"},{"string":"a"},{"int":"1"}]`, ``},
+		{`{"prim":"ticket","args":[{"prim":"string"}]}`, `[{"string":"KT1GyeRktoGPEKsWpchWguyy8FAf3aNHkw2T"},{"int":"1"},{"int":"1"}]`, `micheline: at .1: expected string, got int 1`},
+		{`{"prim":"ticket","args":[{"prim":"string"}]}`, `[{"string":"KT1GyeRktoGPEKsWpchWguyy8FAf3aNHkw2T"},{"string":"a"},{"int":"-1"}]`, `micheline: at .amount: expected nat, got negative int -1`},
+		{`{"prim":"ticket","args":[{"prim":"string"}]}`, `{"string":"a"}`, `micheline: expected pair, got "a"`},
+	}
+	for i, test := range tests {
+		var typ, val Prim
+		if err := json.Unmarshal([]byte(test.typ), &typ); err != nil {
+			t.Fatalf("%d: %v", i, err)
+		}
+		if err := json.Unmarshal([]byte(test.val), &val); err != nil {
+			t.Fatalf("%d: %v", i, err)
+		}
+		err := TypeCheck(typ, val)
+		switch {
+		case test.err == "" && err != nil:
+			t.Errorf("%d: unexpected error %v", i, err)
+		case test.err != "" && (err == nil || err.Error() != test.err):
+			t.Errorf("%d: have error %v want %s", i, err, test.err)
+		}
+	}
+}
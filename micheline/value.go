@@ -19,6 +19,7 @@ const (
 	RENDER_TYPE_PRIM  = 0      // silently output primitive tree instead if human-readable
 	RENDER_TYPE_FAIL  = 1      // return error if human-readable formatting fails
 	RENDER_TYPE_PANIC = 2      // panic with error if human-readable formatting fails
+	RENDER_TYPE_JSON  = 3      // render idiomatic type-driven JSON, return errors
 )
 
 type Value struct {
@@ -114,7 +115,33 @@ func (e *Value) Map() (interface{}, error) {
 	return e.mapped, nil
 }
 
+// MarshalJSON renders the value as JSON object built by Map. With render mode
+// RENDER_TYPE_JSON the output is instead fully deterministic and follows these
+// conventions:
+//
+//   - pairs become objects with fields in type order; field names are taken
+//     from annotations, unannotated fields are named by their position "0",
+//     "1", ... and unannotated nested pairs are merged into their parent;
+//     duplicate names get a "_<position>" suffix
+//   - or values become an object with a single key named after the branch
+//     annotation or "left"/"right"; unannotated nested unions keep one key
+//     per level, e.g. {"right":{"left":..}}
+//   - options become null or the inner value, unit becomes {}
+//   - int, nat and mutez become decimal strings
+//   - bytes become hex strings with 0x prefix
+//   - timestamps become RFC3339 strings
+//   - addresses, key hashes, keys, signatures and chain ids become base58
+//     strings, addresses keep an %entrypoint suffix
+//   - lists and sets become arrays
+//   - maps with keys that render as strings become objects in key order,
+//     other maps become arrays of {"key":..,"value":..} objects
+//   - bigmap references and sapling state ids become decimal strings
+//   - tickets become {"ticketer":..,"value":..,"amount":..} objects
+//   - lambdas and other code are rendered as Micheline JSON
 func (e Value) MarshalJSON() ([]byte, error) {
+	if e.Render == RENDER_TYPE_JSON {
+		return e.marshalTypedJSON()
+	}
 	m, err := e.Map()
 	if err != nil {
 		type xErrorMessage struct {
@@ -336,8 +363,8 @@ func (v *Value) GetTime(label string) (time.Time, bool) {
 			case time.Time:
 				return t, true
 			case string:
-				if b, err := time.Parse(t, time.RFC3339); err == nil {
-					return b, true
+				if b, err := tezos.ParseTimestamp(t); err == nil {
+					return b.Time(), true
 				}
 			}
 		}
@@ -10,6 +10,7 @@ package micheline
 import (
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
@@ -76,6 +77,10 @@ func scanTestFiles(t *testing.T, category string) {
 		err = filepath.WalkDir(
 			filepath.Join(testPath, category),
 			func(path string, d fs.DirEntry, err error) error {
+				if errors.Is(err, fs.ErrNotExist) && d == nil {
+					// category not present in this test data set
+					return nil
+				}
 				if err != nil {
 					return err
 				}
@@ -394,3 +399,57 @@ func TestParamsValues(t *testing.T) {
 		}
 	}
 }
+
+func TestValueMarshalTypedJSON(t *testing.T) {
+	typ := `{"prim":"pair","args":[
+{"prim":"address","annots":["%owner"]},
+{"prim":"pair","args":[{"prim":"mutez"},{"prim":"bytes"}]},
+{"prim":"option","args":[{"prim":"timestamp"}],"annots":["%expiry"]},
+{"prim":"map","args":[{"prim":"nat"},{"prim":"unit"}],"annots":["%flags"]},
+{"prim":"map","args":[{"prim":"pair","args":[{"prim":"nat"},{"prim":"bool"}]},{"prim":"string"}],"annots":["%index"]},
+{"prim":"or","args":[{"prim":"nat","annots":["%count"]},{"prim":"or","args":[{"prim":"string"},{"prim":"unit","annots":["%none"]}]}],"annots":["%mode"]},
+{"prim":"big_map","args":[{"prim":"address"},{"prim":"nat"}],"annots":["%ledger"]},
+{"prim":"nat","annots":["%owner"]}]}`
+	val := `{"prim":"Pair","args":[
+{"bytes":"000002298c03ed7d454a101eb7022bc95f7e5f41ac78"},
+{"prim":"Pair","args":[{"int":"1000000"},{"bytes":"cafe"}]},
+{"prim":"Some","args":[{"int":"1577836800"}]},
+[{"prim":"Elt","args":[{"int":"10"},{"prim":"Unit"}]},{"prim":"Elt","args":[{"int":"2"},{"prim":"Unit"}]}],
+[{"prim":"Elt","args":[{"prim":"Pair","args":[{"int":"1"},{"prim":"True"}]},{"string":"x"}]}],
+{"prim":"Right","args":[{"prim":"Left","args":[{"string":"y"}]}]},
+{"int":"511"},
+{"int":"7"}]}`
+	want := `{"owner":"tz1KqTpEZ7Yob7QbPE4Hy4Wo8fHG8LhKxZSx","1":"1000000","2":"0xcafe","expiry":"2020-01-01T00:00:00Z","flags":{"10":{},"2":{}},"index":[{"key":{"0":"1","1":true},"value":"x"}],"mode":{"right":{"left":"y"}},"ledger":"511","owner_8":"7"}`
+	var tp, vp Prim
+	if err := json.Unmarshal([]byte(typ), &tp); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(val), &vp); err != nil {
+		t.Fatal(err)
+	}
+	v := NewValue(NewType(tp), vp)
+	v.Render = RENDER_TYPE_JSON
+	buf, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(buf) != want {
+		t.Errorf("mismatch\nhave %s\nwant %s", buf, want)
+	}
+	v.Value = NewInt64(1)
+	if _, err := v.MarshalJSON(); err == nil {
+		t.Errorf("expected render error")
+	}
+
+	// flat comb value under an annotated nested pair
+	tp = NewPairType(NewCodeAnno(T_NAT, "%a"), NewPairType(NewCodeAnno(T_NAT, "%b"), NewCodeAnno(T_NAT, "%c"), "%x"))
+	v = NewValue(NewType(tp), Prim{Type: PrimVariadicAnno, OpCode: D_PAIR, Args: []Prim{NewInt64(1), NewInt64(2), NewInt64(3)}})
+	v.Render = RENDER_TYPE_JSON
+	buf, err = v.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"a":"1","x":{"b":"2","c":"3"}}`; string(buf) != want {
+		t.Errorf("comb mismatch\nhave %s\nwant %s", buf, want)
+	}
+}
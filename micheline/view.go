@@ -18,6 +18,9 @@ type View struct {
 type Views map[string]View
 
 func NewView(p Prim) View {
+	if len(p.Args) != 4 {
+		return View{}
+	}
 	vp := p.Clone()
 	return View{
 		Name:   vp.Args[0].String,
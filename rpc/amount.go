@@ -0,0 +1,42 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"blockwatch.cc/tzgo/tezos"
+)
+
+// getBig reads an amount the node encodes as decimal string. A null result
+// reads as zero.
+func (c *Client) getBig(ctx context.Context, u string) (*big.Int, error) {
+	var s string
+	if err := c.Get(ctx, u, &s); err != nil {
+		return nil, err
+	}
+	if s == "" {
+		return new(big.Int), nil
+	}
+	v, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		return nil, fmt.Errorf("rpc: invalid amount %q", s)
+	}
+	return v, nil
+}
+
+// getMutez reads an amount and returns an error wrapping
+// tezos.ErrMutezOverflow when it exceeds the int64 range.
+func (c *Client) getMutez(ctx context.Context, u string) (tezos.Mutez, error) {
+	v, err := c.getBig(ctx, u)
+	if err != nil {
+		return 0, err
+	}
+	if !v.IsInt64() {
+		return 0, fmt.Errorf("rpc: amount %s: %w", v, tezos.ErrMutezOverflow)
+	}
+	return tezos.Mutez(v.Int64()), nil
+}
@@ -0,0 +1,144 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package rpc
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// requestExtras are headers and query parameters added to requests. Their
+// values are treated as secrets and never logged or included in errors.
+type requestExtras struct {
+	header http.Header
+	query  url.Values
+}
+
+func (x *requestExtras) clone() *requestExtras {
+	clone := &requestExtras{
+		header: make(http.Header),
+		query:  make(url.Values),
+	}
+	if x != nil {
+		for k, v := range x.header {
+			clone.header[k] = append([]string{}, v...)
+		}
+		for k, v := range x.query {
+			clone.query[k] = append([]string{}, v...)
+		}
+	}
+	return clone
+}
+
+type extrasKey struct{}
+
+// secretsKey marks the request context with query keys and headers that must
+// be redacted.
+type secretsKey struct{}
+
+type requestSecrets struct {
+	header []string
+	query  []string
+}
+
+// WithHeader adds a header to every request including streaming monitors.
+// Call before the client is used concurrently.
+func (c *Client) WithHeader(key, value string) *Client {
+	c.extras = c.extras.clone()
+	c.extras.header.Set(key, value)
+	return c
+}
+
+// WithBearerToken sends tok as bearer token in the Authorization header.
+func (c *Client) WithBearerToken(tok string) *Client {
+	return c.WithHeader("Authorization", "Bearer "+tok)
+}
+
+// WithQueryParam adds a query parameter to every request, e.g. an API key.
+func (c *Client) WithQueryParam(key, value string) *Client {
+	c.extras = c.extras.clone()
+	c.extras.query.Set(key, value)
+	return c
+}
+
+// ContextWithHeader returns a context that sets a header on requests made
+// with it. It overrides a client header with the same key, which allows
+// services to use a single client with different credentials.
+func ContextWithHeader(ctx context.Context, key, value string) context.Context {
+	x := contextExtras(ctx).clone()
+	x.header.Set(key, value)
+	return context.WithValue(ctx, extrasKey{}, x)
+}
+
+// ContextWithBearerToken returns a context that sends tok as bearer token.
+func ContextWithBearerToken(ctx context.Context, tok string) context.Context {
+	return ContextWithHeader(ctx, "Authorization", "Bearer "+tok)
+}
+
+// ContextWithQueryParam returns a context that sets a query parameter on
+// requests made with it, overriding a client parameter with the same key.
+func ContextWithQueryParam(ctx context.Context, key, value string) context.Context {
+	x := contextExtras(ctx).clone()
+	x.query.Set(key, value)
+	return context.WithValue(ctx, extrasKey{}, x)
+}
+
+func contextExtras(ctx context.Context) *requestExtras {
+	x, _ := ctx.Value(extrasKey{}).(*requestExtras)
+	return x
+}
+
+// applyExtras adds client and context headers and query parameters to req
+// and records their keys for redaction.
+func (c *Client) applyExtras(req *http.Request) *http.Request {
+	cx, rx := c.extras, contextExtras(req.Context())
+	if cx == nil && rx == nil {
+		return req
+	}
+	var sec requestSecrets
+	q := req.URL.Query()
+	for _, x := range []*requestExtras{cx, rx} {
+		if x == nil {
+			continue
+		}
+		for k, v := range x.header {
+			req.Header[k] = v
+			sec.header = append(sec.header, k)
+		}
+		for k, v := range x.query {
+			q[k] = v
+			sec.query = append(sec.query, k)
+		}
+	}
+	if len(sec.query) > 0 {
+		req.URL.RawQuery = q.Encode()
+	}
+	return req.WithContext(context.WithValue(req.Context(), secretsKey{}, &sec))
+}
+
+// redactedURI returns the request URI with values of secret query
+// parameters replaced.
+func redactedURI(req *http.Request) string {
+	sec, _ := req.Context().Value(secretsKey{}).(*requestSecrets)
+	if sec == nil || len(sec.query) == 0 {
+		return req.URL.RequestURI()
+	}
+	u := *req.URL
+	q := u.Query()
+	for _, k := range sec.query {
+		q.Set(k, "<redacted>")
+	}
+	u.RawQuery = q.Encode()
+	return u.RequestURI()
+}
+
+// secretHeaders returns all header keys of req that must be redacted.
+func secretHeaders(req *http.Request) []string {
+	keys := append([]string{}, redactedHeaders...)
+	if sec, _ := req.Context().Value(secretsKey{}).(*requestSecrets); sec != nil {
+		keys = append(keys, sec.header...)
+	}
+	return keys
+}
@@ -4,13 +4,16 @@
 package rpc
 
 import (
+	"encoding/json"
+	"fmt"
+
 	"blockwatch.cc/tzgo/tezos"
 )
 
 // BalanceUpdate is a variable structure depending on the Kind field
 type BalanceUpdate struct {
-	Kind     string `json:"kind"`          // contract, freezer, accumulator, commitment, minted, burned
-	Origin   string `json:"origin"`        // block, migration, subsidy
+	Kind     string `json:"kind"`          // contract, freezer, accumulator, commitment, minted, burned, staking
+	Origin   string `json:"origin"`        // block, migration, subsidy, simulation, delayed_operation
 	Category string `json:"category"`      // optional, used on mint, burn, freezer
 	Change   int64  `json:"change,string"` // amount, <0 =
 
@@ -18,6 +21,7 @@ type BalanceUpdate struct {
 	Contract  tezos.Address `json:"contract"`  // contract only
 	Delegate  tezos.Address `json:"delegate"`  // freezer and burn only
 	Committer tezos.Address `json:"committer"` // committer only
+	Staker    tezos.Address `json:"staker"`    // v018+ staking contract on freezer deposits and unstaked deposits
 
 	//
 	IsParticipationBurn bool `json:"participation"` // burn only
@@ -26,8 +30,130 @@ type BalanceUpdate struct {
 	// legacy freezer cycle
 	Level_ int64 `json:"level"` // wrongly called level, it's cycle
 	Cycle_ int64 `json:"cycle"` // v4 fix
+
+	// v018+ delayed operation (e.g. finalize unstake)
+	DelayedOperation tezos.OpHash `json:"delayed_operation_hash"`
+}
+
+// balanceStaker is the staker object used in freezer and staking updates since
+// Oxford. Depending on protocol and category only some fields are set.
+type balanceStaker struct {
+	Contract      tezos.Address `json:"contract"`
+	Delegate      tezos.Address `json:"delegate"`
+	Baker         tezos.Address `json:"baker"`
+	BakerOwnStake tezos.Address `json:"baker_own_stake"`
+	BakerEdge     tezos.Address `json:"baker_edge"`
+}
+
+// UnmarshalJSON accepts staker objects and plain address strings as written
+// by BalanceUpdate's default JSON encoding.
+func (s *balanceStaker) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		*s = balanceStaker{}
+		return json.Unmarshal(data, &s.Contract)
+	}
+	type alias balanceStaker
+	return json.Unmarshal(data, (*alias)(s))
 }
 
+// UnmarshalJSON decodes balance updates from all protocol versions. Amounts
+// may be encoded as string or number and staker objects (v018+) are flattened
+// into Staker and Delegate. Staker may also be a plain address string so that
+// marshaled balance updates decode again.
+func (b *BalanceUpdate) UnmarshalJSON(data []byte) error {
+	var v struct {
+		Kind                string         `json:"kind"`
+		Origin              string         `json:"origin"`
+		Category            string         `json:"category"`
+		Change              json.Number    `json:"change"`
+		Contract            tezos.Address  `json:"contract"`
+		Delegate            tezos.Address  `json:"delegate"`
+		Delegator           tezos.Address  `json:"delegator"`
+		Committer           tezos.Address  `json:"committer"`
+		Staker              *balanceStaker `json:"staker"`
+		IsParticipationBurn bool           `json:"participation"`
+		IsRevelationBurn    bool           `json:"revelation"`
+		Level               int64          `json:"level"`
+		Cycle               int64          `json:"cycle"`
+		DelayedOperation    tezos.OpHash   `json:"delayed_operation_hash"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	var change int64
+	if v.Change != "" {
+		var err error
+		if change, err = v.Change.Int64(); err != nil {
+			return fmt.Errorf("rpc: invalid balance update change %q: %w", v.Change, err)
+		}
+	}
+	*b = BalanceUpdate{
+		Kind:                v.Kind,
+		Origin:              v.Origin,
+		Category:            v.Category,
+		Change:              change,
+		Contract:            v.Contract,
+		Delegate:            v.Delegate,
+		Committer:           v.Committer,
+		IsParticipationBurn: v.IsParticipationBurn,
+		IsRevelationBurn:    v.IsRevelationBurn,
+		Level_:              v.Level,
+		Cycle_:              v.Cycle,
+		DelayedOperation:    v.DelayedOperation,
+	}
+	if v.Delegator.IsValid() {
+		// v018 staking pseudotokens
+		b.Staker = v.Delegator
+	}
+	if s := v.Staker; s != nil {
+		b.Staker = s.Contract
+		for _, a := range []tezos.Address{s.Delegate, s.Baker, s.BakerOwnStake, s.BakerEdge} {
+			if a.IsValid() {
+				b.Delegate = a
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// Balance update kinds
+const (
+	BalanceKindContract    = "contract"
+	BalanceKindFreezer     = "freezer"
+	BalanceKindAccumulator = "accumulator"
+	BalanceKindCommitment  = "commitment"
+	BalanceKindMinted      = "minted"
+	BalanceKindBurned      = "burned"
+	BalanceKindStaking     = "staking"
+)
+
+// Balance update categories
+const (
+	BalanceCategoryDeposits         = "deposits"
+	BalanceCategoryUnstakedDeposits = "unstaked_deposits"
+	BalanceCategoryLegacyDeposits   = "legacy_deposits"
+	BalanceCategoryLegacyFees       = "legacy_fees"
+	BalanceCategoryLegacyRewards    = "legacy_rewards"
+	BalanceCategoryFees             = "fees"    // frozen fees before Ithaca
+	BalanceCategoryRewards          = "rewards" // frozen rewards before Ithaca
+	BalanceCategoryBonds            = "bonds"
+	BalanceCategoryBlockFees        = "block fees"
+	BalanceCategoryStorageFees      = "storage fees"
+	BalanceCategoryPunishments      = "punishments"
+	BalanceCategoryLostRewards      = "lost endorsing rewards"
+	BalanceCategoryNonceRewards     = "nonce revelation rewards"
+	BalanceCategoryDoubleSigning    = "double signing evidence rewards"
+	BalanceCategoryEndorsingRewards = "endorsing rewards"
+	BalanceCategoryAttestingRewards = "attesting rewards"
+	BalanceCategoryBakingRewards    = "baking rewards"
+	BalanceCategoryBakingBonuses    = "baking bonuses"
+	BalanceCategorySubsidy          = "subsidy"
+	BalanceCategoryInvoice          = "invoice"
+	BalanceCategoryCommitment       = "commitment"
+	BalanceCategoryBootstrap        = "bootstrap"
+)
+
 // Categories
 //
 // # Mint categories
@@ -53,12 +179,15 @@ type BalanceUpdate struct {
 //
 // # Freezer categories
 // - `legacy_deposits`, `legacy_fees`, or `legacy_rewards` represent the accounts of frozen deposits, frozen fees or frozen rewards up to protocol HANGZHOU.
+// - `deposits`, `fees` and `rewards` are the original names of these accounts in receipts of protocols before Ithaca.
 // - `deposits` represents the account of frozen deposits in subsequent protocols (replacing the legacy container account `legacy_deposits` above).
 
 func (b BalanceUpdate) Address() tezos.Address {
 	switch {
 	case b.Contract.IsValid():
 		return b.Contract
+	case b.Staker.IsValid():
+		return b.Staker
 	case b.Delegate.IsValid():
 		return b.Delegate
 	case b.Committer.IsValid():
@@ -80,3 +209,160 @@ func (b BalanceUpdate) Cycle() int64 {
 
 // BalanceUpdates is a list of balance update operations
 type BalanceUpdates []BalanceUpdate
+
+// IsReward returns true for minted rewards and legacy frozen rewards.
+func (b BalanceUpdate) IsReward() bool {
+	switch b.Category {
+	case BalanceCategoryRewards,
+		BalanceCategoryLegacyRewards,
+		BalanceCategoryNonceRewards,
+		BalanceCategoryDoubleSigning,
+		BalanceCategoryEndorsingRewards,
+		BalanceCategoryAttestingRewards,
+		BalanceCategoryBakingRewards,
+		BalanceCategoryBakingBonuses:
+		return true
+	}
+	return false
+}
+
+// IsDeposit returns true for frozen and unstaked deposits.
+func (b BalanceUpdate) IsDeposit() bool {
+	switch b.Category {
+	case BalanceCategoryDeposits, BalanceCategoryLegacyDeposits, BalanceCategoryUnstakedDeposits:
+		return true
+	}
+	return false
+}
+
+// IsFee returns true for fee accumulator and legacy frozen fee updates.
+func (b BalanceUpdate) IsFee() bool {
+	switch b.Category {
+	case BalanceCategoryBlockFees, BalanceCategoryFees, BalanceCategoryLegacyFees:
+		return true
+	}
+	return false
+}
+
+// IsBurn returns true for burned tokens.
+func (b BalanceUpdate) IsBurn() bool {
+	return b.Kind == BalanceKindBurned
+}
+
+// IsMint returns true for minted tokens.
+func (b BalanceUpdate) IsMint() bool {
+	return b.Kind == BalanceKindMinted
+}
+
+// IsTez returns false for updates that do not represent tez amounts like
+// v018 staking pseudotokens.
+func (b BalanceUpdate) IsTez() bool {
+	return b.Kind != BalanceKindStaking
+}
+
+// BalanceSum is the aggregated change of an account.
+type BalanceSum struct {
+	Address tezos.Address
+	Change  int64
+}
+
+// SumByAddress aggregates tez changes by affected account in order of first
+// appearance. Updates without an account (mint, burn, accumulator) are skipped.
+func (l BalanceUpdates) SumByAddress() []BalanceSum {
+	sums := make([]BalanceSum, 0)
+	idx := make(map[string]int)
+	for _, v := range l {
+		addr := v.Address()
+		if !addr.IsValid() || !v.IsTez() {
+			continue
+		}
+		key := addr.String()
+		if i, ok := idx[key]; ok {
+			sums[i].Change += v.Change
+			continue
+		}
+		idx[key] = len(sums)
+		sums = append(sums, BalanceSum{Address: addr, Change: v.Change})
+	}
+	return sums
+}
+
+// ContractDebits returns the total amount debited from contracts as positive
+// number. In operation results these are storage and allocation burns.
+func (l BalanceUpdates) ContractDebits() int64 {
+	var sum int64
+	for _, v := range l {
+		if v.Kind == BalanceKindContract && v.Change < 0 {
+			sum -= v.Change
+		}
+	}
+	return sum
+}
+
+// BalanceEffect classifies the net effect of balance updates on an account.
+type BalanceEffect struct {
+	Spendable int64 // net change of spendable balance
+	Frozen    int64 // net change of frozen deposits
+	Unstaked  int64 // net change of unstaked deposits
+	Bonds     int64 // net change of frozen rollup bonds
+	Rewards   int64 // rewards received
+	Fees      int64 // fees paid (<0) or received (>0)
+	Burned    int64 // tokens burned from the account (<0)
+}
+
+// EffectOn classifies the net effect of balance updates on account addr.
+// Since Ithaca updates come in debit/credit pairs, the counterpart of an
+// account update identifies its reason (e.g. minted rewards, burned storage
+// fees or the block fee accumulator). Legacy updates are classified by category.
+// Staking pseudotoken updates are ignored and do not break up pairs.
+func (l BalanceUpdates) EffectOn(addr tezos.Address) BalanceEffect {
+	var e BalanceEffect
+	tez := make([]int, 0, len(l))
+	for i, v := range l {
+		if v.IsTez() {
+			tez = append(tez, i)
+		}
+	}
+	paired := len(tez)%2 == 0
+	for i := 0; paired && i < len(tez); i += 2 {
+		paired = l[tez[i]].Change == -l[tez[i+1]].Change
+	}
+	for j, i := range tez {
+		v := l[i]
+		if !v.Address().Equal(addr) {
+			continue
+		}
+		switch v.Kind {
+		case BalanceKindFreezer:
+			switch v.Category {
+			case BalanceCategoryDeposits, BalanceCategoryLegacyDeposits:
+				e.Frozen += v.Change
+			case BalanceCategoryUnstakedDeposits:
+				e.Unstaked += v.Change
+			case BalanceCategoryBonds:
+				e.Bonds += v.Change
+			case BalanceCategoryRewards, BalanceCategoryLegacyRewards:
+				e.Rewards += v.Change
+			case BalanceCategoryFees, BalanceCategoryLegacyFees:
+				e.Fees += v.Change
+			}
+			continue
+		case BalanceKindContract:
+			e.Spendable += v.Change
+		default:
+			continue
+		}
+		if !paired {
+			continue
+		}
+		switch other := l[tez[j^1]]; {
+		case other.IsReward():
+			e.Rewards += v.Change
+		case other.IsFee():
+			e.Fees += v.Change
+		case other.IsBurn():
+			e.Burned += v.Change
+		}
+	}
+	return e
+}
@@ -0,0 +1,154 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package rpc
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"blockwatch.cc/tzgo/tezos"
+)
+
+// Fixtures in testdata/balance contain balance update lists in the shape of
+// block and operation receipts of different protocol generations:
+//
+//	v001  level instead of cycle, frozen deposits, fees and rewards
+//	v005  cycle and origin fields
+//	v012  Ithaca debit/credit pairs with mint, burn and accumulator accounts
+//	v018  Oxford staker objects and staking pseudotokens (delegator)
+//	v019  Paris baker_own_stake/baker_edge stakers and delayed operations
+//
+// The simulation list in v019 uses numeric amounts.
+var (
+	testBaker  = tezos.MustParseAddress("tz1KqTpEZ7Yob7QbPE4Hy4Wo8fHG8LhKxZSx")
+	testUser   = tezos.MustParseAddress("tz1S5WxdZR5f9NzsPXhr7L9L1vrEb5spZFur")
+	testStaker = tezos.MustParseAddress("tz1cUwqynCFDp1D22kLNtWMKxpoZFDHg5eZH")
+)
+
+func loadBalanceUpdates(t *testing.T, proto string) map[string]BalanceUpdates {
+	t.Helper()
+	buf, err := os.ReadFile(filepath.Join("testdata", "balance", proto+".json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lists map[string]BalanceUpdates
+	if err := json.Unmarshal(buf, &lists); err != nil {
+		t.Fatalf("%s: %v", proto, err)
+	}
+	return lists
+}
+
+func TestBalanceUpdateUnmarshal(t *testing.T) {
+	for _, test := range []struct {
+		proto string
+		list  string
+		pos   int
+		want  BalanceUpdate
+	}{
+		{"v001", "block", 1, BalanceUpdate{Kind: "freezer", Category: "deposits", Change: 512000000, Delegate: testBaker, Level_: 12}},
+		{"v005", "fees", 1, BalanceUpdate{Kind: "freezer", Origin: "block", Category: "fees", Change: 1420, Delegate: testBaker, Cycle_: 200}},
+		{"v012", "block", 9, BalanceUpdate{Kind: "burned", Origin: "block", Category: "lost endorsing rewards", Change: 2857120, Delegate: testStaker, IsParticipationBurn: true}},
+		{"v012", "result", 1, BalanceUpdate{Kind: "burned", Origin: "block", Category: "storage fees", Change: 64250}},
+		{"v018", "block", 1, BalanceUpdate{Kind: "freezer", Origin: "block", Category: "deposits", Change: 5000000, Delegate: testBaker}},
+		{"v018", "stake", 1, BalanceUpdate{Kind: "freezer", Origin: "block", Category: "deposits", Change: 1000000000, Staker: testStaker, Delegate: testBaker}},
+		{"v018", "stake", 2, BalanceUpdate{Kind: "staking", Origin: "block", Category: "delegator_numerator", Change: 1000000000, Staker: testStaker}},
+		{"v018", "unstake", 3, BalanceUpdate{Kind: "freezer", Origin: "block", Category: "unstaked_deposits", Change: 400000000, Staker: testStaker, Delegate: testBaker, Cycle_: 700}},
+		{"v019", "block", 3, BalanceUpdate{Kind: "freezer", Origin: "block", Category: "deposits", Change: 800000, Delegate: testBaker}},
+		{"v019", "block", 5, BalanceUpdate{Kind: "freezer", Origin: "block", Category: "deposits", Change: 120000, Delegate: testBaker}},
+		{"v019", "finalize", 1, BalanceUpdate{Kind: "contract", Origin: "delayed_operation", Change: 400000000, Contract: testStaker, DelayedOperation: tezos.MustParseOpHash("op7uysDzGrmGSQaabZrjn5tv3Vwp4ZN3BVtsAps3xgXsYLFCwSo")}},
+		{"v019", "simulation", 0, BalanceUpdate{Kind: "contract", Origin: "simulation", Change: -1420, Contract: testUser}},
+	} {
+		lists := loadBalanceUpdates(t, test.proto)
+		list, ok := lists[test.list]
+		if !ok || test.pos >= len(list) {
+			t.Fatalf("%s %s: missing update %d", test.proto, test.list, test.pos)
+		}
+		if got := list[test.pos]; !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s %s[%d]:\n got  %+v\n want %+v", test.proto, test.list, test.pos, got, test.want)
+		}
+	}
+}
+
+func TestBalanceUpdateInvalidChange(t *testing.T) {
+	var b BalanceUpdate
+	if err := json.Unmarshal([]byte(`{"kind":"contract","change":"1.5"}`), &b); err == nil {
+		t.Errorf("expected error for fractional change")
+	}
+}
+
+func TestBalanceUpdateRoundTrip(t *testing.T) {
+	for _, proto := range []string{"v001", "v005", "v012", "v018", "v019"} {
+		for name, list := range loadBalanceUpdates(t, proto) {
+			buf, err := json.Marshal(list)
+			if err != nil {
+				t.Fatalf("%s %s: %v", proto, name, err)
+			}
+			var list2 BalanceUpdates
+			if err := json.Unmarshal(buf, &list2); err != nil {
+				t.Fatalf("%s %s: %v", proto, name, err)
+			}
+			if !reflect.DeepEqual(list, list2) {
+				t.Errorf("%s %s: round-trip mismatch\n got  %+v\n want %+v", proto, name, list2, list)
+			}
+		}
+	}
+}
+
+func TestBalanceUpdatesSumByAddress(t *testing.T) {
+	for _, test := range []struct {
+		proto string
+		list  string
+		want  []BalanceSum
+	}{
+		{"v001", "block", []BalanceSum{{testBaker, 16000000}}},
+		{"v005", "fees", []BalanceSum{{testUser, -1420}, {testBaker, 1420}}},
+		{"v005", "result", []BalanceSum{{testUser, -321250}}},
+		{"v012", "block", []BalanceSum{{testBaker, 14128420}, {testStaker, 2857120}}},
+		{"v012", "result", []BalanceSum{{testUser, -321250}}},
+		// staking pseudotokens are not tez
+		{"v018", "stake", []BalanceSum{{testStaker, 0}}},
+		{"v018", "unstake", []BalanceSum{{testStaker, 0}}},
+		{"v019", "block", []BalanceSum{{testBaker, 4200000}}},
+		{"v019", "simulation", []BalanceSum{{testUser, -1420}}},
+	} {
+		got := loadBalanceUpdates(t, test.proto)[test.list].SumByAddress()
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s %s: got %v want %v", test.proto, test.list, got, test.want)
+		}
+	}
+}
+
+func TestBalanceUpdatesEffectOn(t *testing.T) {
+	for _, test := range []struct {
+		proto string
+		list  string
+		addr  tezos.Address
+		want  BalanceEffect
+	}{
+		{"v001", "block", testBaker, BalanceEffect{Spendable: -512000000, Frozen: 512000000, Rewards: 16000000}},
+		{"v001", "fees", testUser, BalanceEffect{Spendable: -1420, Fees: -1420}},
+		{"v001", "fees", testBaker, BalanceEffect{Fees: 1420}},
+		{"v005", "block", testBaker, BalanceEffect{Spendable: -512000000, Frozen: 512000000, Rewards: 40000000, Fees: 1420}},
+		{"v005", "fees", testUser, BalanceEffect{Spendable: -1420, Fees: -1420}},
+		{"v005", "result", testUser, BalanceEffect{Spendable: -321250}},
+		{"v012", "block", testBaker, BalanceEffect{Spendable: 14127000 + 1420 - 6000000000, Frozen: 6000000000, Rewards: 14127000, Fees: 1420}},
+		{"v012", "fees", testUser, BalanceEffect{Spendable: -1420, Fees: -1420}},
+		{"v012", "result", testUser, BalanceEffect{Spendable: -321250, Burned: -321250}},
+		{"v012", "migration", testBaker, BalanceEffect{Spendable: 80000000}},
+		{"v018", "block", testBaker, BalanceEffect{Spendable: 15000000, Frozen: 5000000, Rewards: 15000000}},
+		{"v018", "stake", testStaker, BalanceEffect{Spendable: -1000000000, Frozen: 1000000000}},
+		{"v018", "unstake", testStaker, BalanceEffect{Frozen: -400000000, Unstaked: 400000000}},
+		{"v019", "block", testBaker, BalanceEffect{Spendable: 3200000, Frozen: 1000000, Rewards: 3200000}},
+		{"v019", "finalize", testStaker, BalanceEffect{Spendable: 400000000, Unstaked: -400000000}},
+		{"v019", "simulation", testUser, BalanceEffect{Spendable: -1420, Fees: -1420}},
+	} {
+		got := loadBalanceUpdates(t, test.proto)[test.list].EffectOn(test.addr)
+		if got != test.want {
+			t.Errorf("%s %s %s:\n got  %+v\n want %+v", test.proto, test.list, test.addr, got, test.want)
+		}
+	}
+}
@@ -26,7 +26,7 @@ func (b Block) GetLevel() int64 {
 }
 
 func (b Block) GetTimestamp() time.Time {
-	return b.Header.Timestamp
+	return b.Header.Timestamp.Time()
 }
 
 func (b Block) GetVersion() int {
@@ -43,6 +43,26 @@ func (b Block) GetCycle() int64 {
 	return 0
 }
 
+// GetLbVote returns the baker's liquidity baking vote, one of "on", "off" or
+// "pass". Before v013 the escape vote is translated to "off" (escape) or "on".
+func (b Block) GetLbVote() string {
+	if b.Header.LiquidityBakingToggleVote != "" {
+		return b.Header.LiquidityBakingToggleVote
+	}
+	if b.Header.LiquidityBakingEscapeVote {
+		return "off"
+	}
+	return "on"
+}
+
+// GetLbEma returns the liquidity baking escape/toggle exponential moving average.
+func (b Block) GetLbEma() int64 {
+	if b.Metadata.LiquidityBakingToggleEma > 0 {
+		return b.Metadata.LiquidityBakingToggleEma
+	}
+	return b.Metadata.LiquidityBakingEscapeEma
+}
+
 func (b Block) GetLevelInfo() LevelInfo {
 	if b.Metadata.LevelInfo != nil {
 		return *b.Metadata.LevelInfo
@@ -109,7 +129,7 @@ type BlockHeader struct {
 	Level                     int64                `json:"level"`
 	Proto                     int                  `json:"proto"`
 	Predecessor               tezos.BlockHash      `json:"predecessor"`
-	Timestamp                 time.Time            `json:"timestamp"`
+	Timestamp                 tezos.Timestamp      `json:"timestamp"`
 	ValidationPass            int                  `json:"validation_pass"`
 	OperationsHash            tezos.OpListListHash `json:"operations_hash"`
 	Fitness                   []tezos.HexBytes     `json:"fitness"`
@@ -122,6 +142,7 @@ type BlockHeader struct {
 	Signature                 tezos.Signature      `json:"signature"`
 	Content                   *BlockContent        `json:"content,omitempty"`
 	LiquidityBakingEscapeVote bool                 `json:"liquidity_baking_escape_vote"`
+	LiquidityBakingToggleVote string               `json:"liquidity_baking_toggle_vote"` // v013+
 
 	// only present when header is fetched explicitly
 	Hash     tezos.BlockHash    `json:"hash"`
@@ -168,6 +189,16 @@ type VotingPeriodInfo struct {
 	VotingPeriod VotingPeriod `json:"voting_period"`
 }
 
+// StartLevel returns the first block level of the voting period.
+func (i VotingPeriodInfo) StartLevel() int64 {
+	return i.VotingPeriod.StartPosition + 1
+}
+
+// EndLevel returns the last block level of the voting period.
+func (i VotingPeriodInfo) EndLevel() int64 {
+	return i.StartLevel() + i.Position + i.Remaining
+}
+
 // BlockMetadata is a part of the Tezos block data
 type BlockMetadata struct {
 	Protocol               tezos.ProtocolHash     `json:"protocol"`
@@ -194,6 +225,9 @@ type BlockMetadata struct {
 	// v010
 	ImplicitOperationsResults []ImplicitResult `json:"implicit_operations_results"`
 	LiquidityBakingEscapeEma  int64            `json:"liquidity_baking_escape_ema"`
+
+	// v013
+	LiquidityBakingToggleEma int64 `json:"liquidity_baking_toggle_ema"`
 }
 
 // GetBlock returns information about a Tezos block
@@ -267,6 +301,18 @@ func (c *Client) GetBlockHeader(ctx context.Context, id BlockID) (*BlockHeader,
 	return &head, nil
 }
 
+// GetLevelInfo returns level and cycle information for the block offset levels
+// after block id. Offset may be negative and point to past or future levels.
+// https://tezos.gitlab.io/active/rpc.html#get-block-id-helpers-current-level
+func (c *Client) GetLevelInfo(ctx context.Context, id BlockID, offset int64) (*LevelInfo, error) {
+	var info LevelInfo
+	u := fmt.Sprintf("chains/main/blocks/%s/helpers/current_level?offset=%d", id, offset)
+	if err := c.Get(ctx, u, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
 // GetBlockHash returns the main chain's block header.
 // https://tezos.gitlab.io/mainnet/api/rpc.html#chains-chain-id-blocks
 func (c *Client) GetBlockHash(ctx context.Context, id BlockID) (hash tezos.BlockHash, err error) {
@@ -0,0 +1,210 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package rpc
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"blockwatch.cc/tzgo/tezos"
+)
+
+// Cache is a backend for caching raw responses of immutable RPC requests.
+// Implementations must be safe for concurrent use.
+type Cache interface {
+	Get(key string) ([]byte, bool)
+	Add(key string, val []byte)
+}
+
+// CacheStats contains response cache counters.
+type CacheStats struct {
+	Hits   int64
+	Misses int64
+}
+
+// headRefreshInterval defines how long a known head level is used to decide
+// whether level-addressed requests are deep enough to be cached. A stale head
+// only makes caching more conservative.
+const headRefreshInterval = 30 * time.Second
+
+type responseCache struct {
+	backend Cache
+	depth   int64
+	hits    int64
+	misses  int64
+
+	mu        sync.Mutex
+	headLevel int64
+	headTime  time.Time
+}
+
+// WithCache enables caching of responses for requests addressed by block hash
+// or by a block level at least depth blocks below the current head. Requests
+// relative to head are never cached. Depth is at least 1 because the block at
+// head level may still be replaced by a reorg.
+func (c *Client) WithCache(backend Cache, depth int64) *Client {
+	if backend == nil {
+		c.cache = nil
+		return c
+	}
+	if depth < 1 {
+		depth = 1
+	}
+	c.cache = &responseCache{
+		backend: backend,
+		depth:   depth,
+	}
+	return c
+}
+
+// CacheStats returns hit and miss counters of the response cache.
+func (c *Client) CacheStats() CacheStats {
+	if c.cache == nil {
+		return CacheStats{}
+	}
+	return CacheStats{
+		Hits:   atomic.LoadInt64(&c.cache.hits),
+		Misses: atomic.LoadInt64(&c.cache.misses),
+	}
+}
+
+// getCached serves GET requests for immutable blocks from cache. Returns false
+// when the request is not cacheable.
+func (c *Client) getCached(ctx context.Context, urlpath string, result interface{}) (bool, error) {
+	if c.cache == nil || !c.isImmutablePath(ctx, urlpath) {
+		return false, nil
+	}
+	if buf, ok := c.cache.backend.Get(urlpath); ok {
+		atomic.AddInt64(&c.cache.hits, 1)
+		if result == nil {
+			return true, nil
+		}
+		return true, json.Unmarshal(buf, result)
+	}
+	atomic.AddInt64(&c.cache.misses, 1)
+	req, err := c.NewRequest(ctx, http.MethodGet, urlpath, nil)
+	if err != nil {
+		return true, err
+	}
+	var buf json.RawMessage
+	if err := c.Do(req, &buf); err != nil {
+		return true, err
+	}
+	if len(buf) == 0 {
+		return true, nil
+	}
+	c.cache.backend.Add(urlpath, buf)
+	if result == nil {
+		return true, nil
+	}
+	return true, json.Unmarshal(buf, result)
+}
+
+// isImmutablePath checks if urlpath addresses a block by hash or by a level
+// deep enough below head.
+func (c *Client) isImmutablePath(ctx context.Context, urlpath string) bool {
+	const prefix = "chains/main/blocks/"
+	if !strings.HasPrefix(urlpath, prefix) {
+		return false
+	}
+	id := strings.TrimPrefix(urlpath, prefix)
+	if i := strings.IndexAny(id, "/?"); i >= 0 {
+		id = id[:i]
+	}
+	if _, err := tezos.ParseBlockHash(id); err == nil {
+		return true
+	}
+	level, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return false
+	}
+	head, err := c.cachedHeadLevel(ctx)
+	if err != nil {
+		return false
+	}
+	return level <= head-c.cache.depth
+}
+
+func (c *Client) cachedHeadLevel(ctx context.Context) (int64, error) {
+	c.cache.mu.Lock()
+	level, t := c.cache.headLevel, c.cache.headTime
+	c.cache.mu.Unlock()
+	if time.Since(t) < headRefreshInterval {
+		return level, nil
+	}
+	head, err := c.GetTipHeader(ctx)
+	if err != nil {
+		return 0, err
+	}
+	c.cache.mu.Lock()
+	c.cache.headLevel = head.Level
+	c.cache.headTime = time.Now()
+	c.cache.mu.Unlock()
+	return head.Level, nil
+}
+
+// LRUCache is an in-memory least recently used Cache with a fixed number of entries.
+type LRUCache struct {
+	mu    sync.Mutex
+	size  int
+	ll    *list.List
+	items map[string]*list.Element
+}
+
+type lruEntry struct {
+	key string
+	val []byte
+}
+
+// NewLRUCache returns an in-memory cache that holds up to size entries.
+func NewLRUCache(size int) *LRUCache {
+	if size < 1 {
+		size = 1
+	}
+	return &LRUCache{
+		size:  size,
+		ll:    list.New(),
+		items: make(map[string]*list.Element),
+	}
+}
+
+func (l *LRUCache) Get(key string) ([]byte, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if e, ok := l.items[key]; ok {
+		l.ll.MoveToFront(e)
+		return e.Value.(*lruEntry).val, true
+	}
+	return nil, false
+}
+
+func (l *LRUCache) Add(key string, val []byte) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if e, ok := l.items[key]; ok {
+		l.ll.MoveToFront(e)
+		e.Value.(*lruEntry).val = val
+		return
+	}
+	l.items[key] = l.ll.PushFront(&lruEntry{key, val})
+	for l.ll.Len() > l.size {
+		e := l.ll.Back()
+		l.ll.Remove(e)
+		delete(l.items, e.Value.(*lruEntry).key)
+	}
+}
+
+// Len returns the number of cached entries.
+func (l *LRUCache) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.ll.Len()
+}
@@ -0,0 +1,40 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package rpc
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestCacheDepth(t *testing.T) {
+	for _, test := range []struct {
+		depth int64
+		level BlockLevel
+		want  CacheStats
+	}{
+		{depth: 0, level: 10, want: CacheStats{}},
+		{depth: 0, level: 9, want: CacheStats{Hits: 1, Misses: 1}},
+		{depth: 2, level: 9, want: CacheStats{}},
+		{depth: 2, level: 8, want: CacheStats{Hits: 1, Misses: 1}},
+	} {
+		m := NewMock()
+		m.On(http.MethodGet, "chains/main/blocks/head/header", []byte(`{"level":10}`))
+		m.On(http.MethodGet, "chains/main/blocks/"+test.level.String()+"/hash", testSnapshotBlock)
+		c, err := m.Client()
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.WithCache(NewLRUCache(10), test.depth)
+		for i := 0; i < 2; i++ {
+			if _, err := c.GetBlockHash(context.Background(), test.level); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if got := c.CacheStats(); got != test.want {
+			t.Errorf("depth %d level %d: got %+v want %+v", test.depth, test.level, got, test.want)
+		}
+	}
+}
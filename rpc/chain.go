@@ -5,13 +5,29 @@ package rpc
 
 import (
     "context"
+    "encoding/json"
+    "fmt"
+    "io"
 
     "blockwatch.cc/tzgo/tezos"
 )
 
-// GetChainId returns the chain id (i.e. network id).
+// GetChainId returns the chain id (i.e. network id). The id is fetched once
+// and cached on the client.
 // https://tezos.gitlab.io/shell/rpc.html#get-chains-chain-id-chain-id
 func (c *Client) GetChainId(ctx context.Context) (tezos.ChainIdHash, error) {
+    if c.ChainId.IsValid() {
+        return c.ChainId, nil
+    }
+    id, err := c.fetchChainId(ctx)
+    if err != nil {
+        return id, err
+    }
+    c.ChainId = id
+    return id, nil
+}
+
+func (c *Client) fetchChainId(ctx context.Context) (tezos.ChainIdHash, error) {
     var id tezos.ChainIdHash
     err := c.Get(ctx, "chains/main/chain_id", &id)
     return id, err
@@ -31,12 +47,79 @@ func (c *Client) GetStatus(ctx context.Context) (Status, error) {
     return s, err
 }
 
+// IsSynced returns true when the node is bootstrapped and in sync with its peers.
+func (s Status) IsSynced() bool {
+    return s.Bootstrapped && s.SyncState == "synced"
+}
+
+// WaitBootstrapped blocks until the node reports it is bootstrapped by following
+// the bootstrapped blocks stream. It returns immediately when the node is already
+// synced.
+func (c *Client) WaitBootstrapped(ctx context.Context) error {
+    if s, err := c.GetStatus(ctx); err != nil {
+        return err
+    } else if s.IsSynced() {
+        return nil
+    }
+    mon := NewBootstrapMonitor()
+    defer mon.Close()
+    if err := c.MonitorBootstrapped(ctx, mon); err != nil {
+        return err
+    }
+    for {
+        // the node closes the stream once bootstrapped
+        if _, err := mon.Recv(ctx); err != nil {
+            if err == io.EOF || err == ErrMonitorClosed {
+                return nil
+            }
+            return err
+        }
+    }
+}
+
 type NodeVersion struct {
     Major          int    `json:"major"`
     Minor          int    `json:"minor"`
     AdditionalInfo string `json:"additional_info"`
 }
 
+// UnmarshalJSON accepts additional info as string ("dev", "release") and in
+// release candidate form ({"rc": n}), which is mapped to "rc<n>".
+func (v *NodeVersion) UnmarshalJSON(data []byte) error {
+    var val struct {
+        Major          int             `json:"major"`
+        Minor          int             `json:"minor"`
+        AdditionalInfo json.RawMessage `json:"additional_info"`
+    }
+    if err := json.Unmarshal(data, &val); err != nil {
+        return err
+    }
+    v.Major, v.Minor, v.AdditionalInfo = val.Major, val.Minor, ""
+    if len(val.AdditionalInfo) == 0 {
+        return nil
+    }
+    if val.AdditionalInfo[0] == '{' {
+        var rc struct {
+            Rc int `json:"rc"`
+        }
+        if err := json.Unmarshal(val.AdditionalInfo, &rc); err != nil {
+            return err
+        }
+        v.AdditionalInfo = fmt.Sprintf("rc%d", rc.Rc)
+        return nil
+    }
+    return json.Unmarshal(val.AdditionalInfo, &v.AdditionalInfo)
+}
+
+func (v NodeVersion) String() string {
+    switch v.AdditionalInfo {
+    case "", "release":
+        return fmt.Sprintf("%d.%d", v.Major, v.Minor)
+    default:
+        return fmt.Sprintf("%d.%d~%s", v.Major, v.Minor, v.AdditionalInfo)
+    }
+}
+
 type NetworkVersion struct {
     ChainName            string `json:"chain_name"`
     DistributedDbVersion int    `json:"distributed_db_version"`
@@ -54,7 +137,7 @@ type VersionInfo struct {
     CommitInfo     CommitInfo     `json:"commit_info"`
 }
 
-// GetVersion returns node's version info.
+// GetVersionInfo returns node's version info.
 // https://tezos.gitlab.io/shell/rpc.html#get-version
 func (c *Client) GetVersionInfo(ctx context.Context) (VersionInfo, error) {
     var v VersionInfo
@@ -14,6 +14,8 @@ import (
 	"net/http/httputil"
 	"net/url"
 	"strings"
+	"sync"
+	"time"
 
 	"blockwatch.cc/tzgo/signer"
 	"blockwatch.cc/tzgo/tezos"
@@ -37,16 +39,39 @@ type Client struct {
 	ApiKey string
 	// The chain the client will query.
 	ChainId tezos.ChainIdHash
-	// The current chain configuration.
-	Params *tezos.Params
 	// An active event observer to watch for operation inclusion
 	BlockObserver *Observer
 	// An active event observer to watch for operation posting to the mempool
 	MempoolObserver *Observer
 	// A default signer used for transaction sending
 	Signer signer.Signer
+
+	mu            sync.Mutex
+	requestHooks  []RequestHook
+	responseHooks []responseHook
+	cache         *responseCache
+	limiter       Limiter
+	inflight      chan struct{}
+	backoffUntil  time.Time
+	logger        Logger
+	extras        *requestExtras
+	params        *tezos.Params
+
+	maxResponseBytes int64
 }
 
+// RequestHook is called with every outgoing request before it is sent. Hooks
+// may modify the request, e.g. to add tracing headers.
+type RequestHook func(*http.Request)
+
+// ResponseHook is called after every request completed with the response (nil
+// on transport errors), the round-trip duration and the transport error. For
+// streaming monitors the hook runs once the connection is established.
+type ResponseHook func(*http.Response, time.Duration, error)
+
+// responseHook is the internal form of ResponseHook and StatsHook.
+type responseHook func(req *http.Request, resp *http.Response, start time.Time, err error)
+
 // NewClient returns a new Tezos RPC client.
 func NewClient(baseURL string, httpClient *http.Client) (*Client, error) {
 	if httpClient == nil {
@@ -69,6 +94,34 @@ func NewClient(baseURL string, httpClient *http.Client) (*Client, error) {
 	return c, nil
 }
 
+// WithRequestHook registers fn to run before each request is sent. Hooks run
+// in registration order and may be added while the client is in use.
+func (c *Client) WithRequestHook(fn RequestHook) *Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	hooks := make([]RequestHook, len(c.requestHooks), len(c.requestHooks)+1)
+	copy(hooks, c.requestHooks)
+	c.requestHooks = append(hooks, fn)
+	return c
+}
+
+// WithResponseHook registers fn to run after each request completed. Hooks run
+// in registration order and may be added while the client is in use.
+func (c *Client) WithResponseHook(fn ResponseHook) *Client {
+	return c.addResponseHook(func(_ *http.Request, resp *http.Response, start time.Time, err error) {
+		fn(resp, time.Since(start), err)
+	})
+}
+
+func (c *Client) addResponseHook(fn responseHook) *Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	hooks := make([]responseHook, len(c.responseHooks), len(c.responseHooks)+1)
+	copy(hooks, c.responseHooks)
+	c.responseHooks = append(hooks, fn)
+	return c
+}
+
 func (c *Client) Init(ctx context.Context) error {
 	// pull chain id if not yet set
 	_, err := c.ResolveChainId(ctx)
@@ -96,27 +149,66 @@ func (c *Client) Close() {
 	c.MempoolObserver.Close()
 }
 
+// ResolveChainId fetches the chain id from the node and checks it against a
+// previously configured chain id.
 func (c *Client) ResolveChainId(ctx context.Context) (tezos.ChainIdHash, error) {
-	id, err := c.GetChainId(ctx)
+	id, err := c.fetchChainId(ctx)
+	if err != nil {
+		return id, err
+	}
 	if c.ChainId.IsValid() {
 		if !c.ChainId.Equal(id) {
 			return id, fmt.Errorf("rpc: chain mismatch detected, expected=%s seen=%s", c.ChainId, id)
 		}
 	}
 	c.ChainId = id
-	return id, err
+	return id, nil
 }
 
+// ResolveChainConfig fetches protocol and constants at head and caches them as
+// the client's current params.
 func (c *Client) ResolveChainConfig(ctx context.Context) (*tezos.Params, error) {
-	con, err := c.GetConstants(ctx, Head)
+	p, err := c.GetParams(ctx, Head)
 	if err != nil {
 		return nil, err
 	}
-	c.Params = con.MapToChainParams()
-	return c.Params, nil
+	c.mu.Lock()
+	c.params = p
+	c.mu.Unlock()
+	return p, nil
+}
+
+// Params returns the cached params of the current protocol. Params are
+// resolved on first use and after the cache was invalidated by a protocol change.
+func (c *Client) Params(ctx context.Context) (*tezos.Params, error) {
+	c.mu.Lock()
+	p := c.params
+	c.mu.Unlock()
+	if p != nil {
+		return p, nil
+	}
+	return c.ResolveChainConfig(ctx)
+}
+
+// cachedParams returns the cached params without resolving them.
+func (c *Client) cachedParams() *tezos.Params {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.params
+}
+
+// InvalidateParams clears cached params so that the next call to Params
+// fetches them again.
+func (c *Client) InvalidateParams() {
+	c.mu.Lock()
+	c.params = nil
+	c.mu.Unlock()
 }
 
 func (c *Client) Get(ctx context.Context, urlpath string, result interface{}) error {
+	if ok, err := c.getCached(ctx, urlpath, result); ok {
+		return err
+	}
 	req, err := c.NewRequest(ctx, http.MethodGet, urlpath, nil)
 	if err != nil {
 		return err
@@ -177,10 +269,10 @@ func (c *Client) NewRequest(ctx context.Context, method, urlStr string, body int
 	if c.ApiKey != "" {
 		req.Header.Add("X-Api-Key", c.ApiKey)
 	}
+	req = c.applyExtras(req)
 
 	log.Debug(newLogClosure(func() string {
-		d, _ := httputil.DumpRequest(req, true)
-		return string(d)
+		return dumpRequest(req)
 	}))
 
 	return req, nil
@@ -214,7 +306,7 @@ func (c *Client) handleResponseMonitor(ctx context.Context, resp *http.Response,
 				mon.Err(io.EOF)
 				return
 			}
-			mon.Err(fmt.Errorf("rpc: %w", err))
+			mon.Err(wrapError(resp.Request, resp, fmt.Errorf("decoding stream at offset %d: %w", dec.InputOffset(), err)))
 			return
 		}
 		select {
@@ -228,11 +320,55 @@ func (c *Client) handleResponseMonitor(ctx context.Context, resp *http.Response,
 	}
 }
 
+// roundTrip sends req through the http client and runs all registered hooks.
+// Requests wait for configured rate and concurrency limits.
+func (c *Client) roundTrip(req *http.Request) (*http.Response, error) {
+	release, err := c.acquire(req)
+	if err != nil {
+		if c.logger != nil {
+			c.logger.Warnf("rpc: %s %s not sent: %v", req.Method, req.URL.Path, err)
+		}
+		return nil, err
+	}
+	c.mu.Lock()
+	requestHooks, responseHooks := c.requestHooks, c.responseHooks
+	c.mu.Unlock()
+	for _, fn := range requestHooks {
+		fn(req)
+	}
+	start := time.Now()
+	resp, err := c.client.Do(req)
+	if err != nil {
+		// redact the request URL once before the error reaches logs and hooks
+		err = wrapError(req, nil, err)
+	}
+	if err != nil || resp.Body == nil {
+		release()
+	} else if c.inflight != nil {
+		resp.Body = &limitedBody{ReadCloser: resp.Body, release: release}
+	}
+	if c.logger != nil {
+		c.logRequest(req, resp, time.Since(start), err)
+	}
+	if err == nil && resp.StatusCode == http.StatusTooManyRequests {
+		c.handleTooManyRequests(resp)
+	}
+	for _, fn := range responseHooks {
+		fn(req, resp, start, err)
+	}
+	return resp, err
+}
+
 // Do retrieves values from the API and marshals them into the provided interface.
 func (c *Client) Do(req *http.Request, v interface{}) error {
-	resp, err := c.client.Do(req)
+	resp, err := c.roundTrip(req)
 	if err != nil {
-		return err
+		return wrapError(req, nil, err)
+	}
+
+	if err := c.limitResponse(resp); err != nil {
+		resp.Body.Close()
+		return wrapError(req, resp, err)
 	}
 
 	defer func() {
@@ -254,20 +390,56 @@ func (c *Client) Do(req *http.Request, v interface{}) error {
 		if v == nil {
 			return nil
 		}
-		return c.handleResponse(req.Context(), resp, v)
+		return wrapError(req, resp, c.handleResponse(req.Context(), resp, v))
+	}
+
+	return handleError(resp)
+}
+
+// doStream executes req and passes the response body to fn for incremental
+// decoding of large responses. The response size limit is not applied, fn is
+// expected to limit individual elements instead.
+func (c *Client) doStream(req *http.Request, fn func(io.Reader) error) error {
+	resp, err := c.roundTrip(req)
+	if err != nil {
+		return wrapError(req, nil, err)
+	}
+
+	// drain the body for connection reuse unless the stream was aborted,
+	// which could otherwise read an unbounded remainder
+	drain := true
+	defer func() {
+		if drain {
+			io.Copy(ioutil.Discard, resp.Body)
+		}
+		resp.Body.Close()
+	}()
+
+	if resp.StatusCode == http.StatusNoContent {
+		return nil
 	}
 
+	statusClass := resp.StatusCode / 100
+	if statusClass == 2 {
+		if err := fn(resp.Body); err != nil {
+			drain = false
+			return wrapError(req, resp, err)
+		}
+		return nil
+	}
+
+	// error bodies are read at once and limited as a whole
+	if err := c.limitResponse(resp); err != nil {
+		return wrapError(req, resp, err)
+	}
 	return handleError(resp)
 }
 
 // DoAsync retrieves values from the API and sends responses using the provided monitor.
 func (c *Client) DoAsync(req *http.Request, mon Monitor) error {
-	resp, err := c.client.Do(req)
+	resp, err := c.roundTrip(req)
 	if err != nil {
-		if e, ok := err.(*url.Error); ok {
-			return e.Err
-		}
-		return err
+		return wrapError(req, nil, err)
 	}
 
 	if resp.StatusCode == http.StatusNoContent {
@@ -279,12 +451,18 @@ func (c *Client) DoAsync(req *http.Request, mon Monitor) error {
 	statusClass := resp.StatusCode / 100
 	if statusClass == 2 {
 		if mon != nil {
+			// long-lived streams must not occupy an in-flight slot
+			if b, ok := resp.Body.(*limitedBody); ok {
+				b.done()
+			}
 			go func() {
 				c.handleResponseMonitor(req.Context(), resp, mon)
 			}()
 			return nil
 		}
 	} else {
+		// closing the body also frees the in-flight slot
+		defer resp.Body.Close()
 		return handleError(resp)
 	}
 	io.Copy(ioutil.Discard, resp.Body)
@@ -295,11 +473,12 @@ func (c *Client) DoAsync(req *http.Request, mon Monitor) error {
 func handleError(resp *http.Response) error {
 	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return err
+		return wrapError(resp.Request, resp, err)
 	}
 
 	httpErr := httpError{
-		request:    resp.Request.Method + " " + resp.Request.URL.RequestURI(),
+		method:     resp.Request.Method,
+		path:       redactedURI(resp.Request),
 		status:     resp.Status,
 		statusCode: resp.StatusCode,
 		body:       bytes.ReplaceAll(body, []byte("\n"), []byte{}),
@@ -312,7 +491,7 @@ func handleError(resp *http.Response) error {
 
 	var errs Errors
 	if err := json.Unmarshal(body, &errs); err != nil {
-		return &plainError{&httpErr, fmt.Sprintf("rpc: error decoding RPC error: %v", err)}
+		return &plainError{&httpErr, fmt.Sprintf("error decoding RPC error: %v", err)}
 	}
 
 	if len(errs) == 0 {
@@ -0,0 +1,221 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"blockwatch.cc/tzgo/micheline"
+)
+
+func TestMonitorErrorReleasesSlot(t *testing.T) {
+	m := NewMock()
+	m.On(http.MethodGet, "monitor/bootstrapped", "unavailable").WithStatus(http.StatusServiceUnavailable)
+	m.On(http.MethodGet, "chains/main/blocks/head/hash", "BLockGenesisGenesisGenesisGenesisGenesisf79b5d1CoW2")
+	c, err := m.Client()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.WithOptions(ClientOptions{MaxConcurrency: 2})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	for i := 0; i < 2; i++ {
+		mon := NewBootstrapMonitor()
+		err := c.MonitorBootstrapped(ctx, mon)
+		if ErrorStatus(err) != http.StatusServiceUnavailable {
+			t.Fatalf("connect %d: unexpected error %v", i, err)
+		}
+		mon.Close()
+	}
+	// blocks until timeout when failed connects leak their slots
+	if _, err := c.GetBlockHash(ctx, Head); err != nil {
+		t.Fatalf("request after failed monitors: %v", err)
+	}
+}
+
+func TestErrorBodyNotTruncated(t *testing.T) {
+	errs := make([]GenericError, 40)
+	for i := range errs {
+		errs[i] = GenericError{ID: "proto.alpha.michelson_v1.ill_typed_data", Kind: ErrorKindPermanent}
+	}
+	m := NewMock()
+	m.On(http.MethodGet, "chains/main/blocks/head/hash", errs).WithStatus(http.StatusInternalServerError)
+	c, err := m.Client()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.GetBlockHash(context.Background(), Head)
+	herr, ok := err.(HTTPError)
+	if !ok {
+		t.Fatalf("unexpected error type %T", err)
+	}
+	if len(herr.Body()) <= maxErrorBody {
+		t.Fatalf("test body too short %d", len(herr.Body()))
+	}
+	var list Errors
+	if err := json.Unmarshal(herr.Body(), &list); err != nil || len(list) != len(errs) {
+		t.Errorf("body decode: n=%d err=%v", len(list), err)
+	}
+	if want := "GET /chains/main/blocks/head/hash status 500"; !strings.Contains(err.Error(), want) {
+		t.Errorf("message %q does not contain %q", err, want)
+	}
+}
+
+func TestHooksConcurrentRegistration(t *testing.T) {
+	m := NewMock()
+	m.On(http.MethodGet, "chains/main/blocks/head/hash", "BLockGenesisGenesisGenesisGenesisGenesisf79b5d1CoW2")
+	c, err := m.Client()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var (
+		mu    sync.Mutex
+		order []string
+		stats []RequestStats
+	)
+	c.WithResponseHook(func(*http.Response, time.Duration, error) {
+		mu.Lock()
+		order = append(order, "response")
+		mu.Unlock()
+	})
+	c.WithStatsHook(func(s RequestStats) {
+		mu.Lock()
+		order = append(order, "stats")
+		stats = append(stats, s)
+		mu.Unlock()
+	})
+	if _, err := c.GetBlockHash(context.Background(), Head); err != nil {
+		t.Fatal(err)
+	}
+	if len(stats) != 1 || stats[0].Status != http.StatusOK || stats[0].Bytes == 0 || stats[0].Route != "/chains/main/blocks/head/hash" {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+	if strings.Join(order, ",") != "response,stats" {
+		t.Errorf("unexpected hook order %v", order)
+	}
+
+	// hooks may be added while requests are in flight
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			c.GetBlockHash(context.Background(), Head)
+		}()
+		go func() {
+			defer wg.Done()
+			c.WithRequestHook(func(*http.Request) {})
+			c.WithStatsHook(func(RequestStats) {})
+		}()
+	}
+	wg.Wait()
+}
+
+// countingBody counts bytes read from the response body.
+type countingBody struct {
+	io.Reader
+	n      int
+	closed bool
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.Reader.Read(p)
+	b.n += n
+	return n, err
+}
+
+func (b *countingBody) Close() error {
+	b.closed = true
+	return nil
+}
+
+type bodyTransport struct {
+	body *countingBody
+}
+
+func (t bodyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp := newMockResponse(req, http.StatusOK, nil)
+	resp.Body = t.body
+	resp.ContentLength = -1
+	return resp, nil
+}
+
+func TestStreamAbortSkipsDrain(t *testing.T) {
+	const size = 1 << 20
+	stream := "[" + strings.Repeat("{\"int\":\"1\"},", size/12) + "{\"int\":\"1\"}]"
+	body := &countingBody{Reader: strings.NewReader(stream)}
+	c, err := NewClient("http://node", &http.Client{Transport: bodyTransport{body}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stop := errors.New("stop")
+	err = c.GetBigmapValuesStream(context.Background(), 1, Head, func(micheline.Prim) error {
+		return stop
+	})
+	if !errors.Is(err, stop) {
+		t.Errorf("unexpected error %v", err)
+	}
+	if !body.closed {
+		t.Errorf("body not closed")
+	}
+	if body.n >= len(stream) {
+		t.Errorf("aborted stream was drained (%d bytes)", body.n)
+	}
+
+	// a completed stream is drained for connection reuse
+	body = &countingBody{Reader: strings.NewReader("[]\n\n")}
+	c, _ = NewClient("http://node", &http.Client{Transport: bodyTransport{body}})
+	if err := c.GetBigmapValuesStream(context.Background(), 1, Head, func(micheline.Prim) error { return nil }); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	if !body.closed || body.n != 4 {
+		t.Errorf("completed stream: closed=%t read=%d", body.closed, body.n)
+	}
+}
+
+func TestHTTPErrorAs(t *testing.T) {
+	const path = "/chains/main/blocks/head/hash"
+	for _, test := range []struct {
+		name   string
+		setup  func(*MockResponse)
+		status int
+	}{
+		{"status", func(r *MockResponse) { r.WithStatus(http.StatusNotFound) }, http.StatusNotFound},
+		{"rpc error", func(r *MockResponse) {
+			r.Body = json.RawMessage(`[{"kind":"temporary","id":"failure"}]`)
+			r.WithStatus(http.StatusInternalServerError)
+		}, http.StatusInternalServerError},
+		{"decode", func(r *MockResponse) { r.Body = json.RawMessage(`{}`) }, http.StatusOK},
+		{"transport", func(r *MockResponse) { r.WithError(io.ErrUnexpectedEOF) }, 0},
+	} {
+		m := NewMock()
+		test.setup(m.On(http.MethodGet, "chains/main/blocks/head/hash", nil))
+		c, err := m.Client()
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = c.GetBlockHash(context.Background(), Head)
+		var herr HTTPError
+		if !errors.As(fmt.Errorf("wrapped: %w", err), &herr) {
+			t.Errorf("%s: %T is not an HTTPError", test.name, err)
+			continue
+		}
+		if herr.Method() != http.MethodGet || herr.Path() != path || herr.StatusCode() != test.status {
+			t.Errorf("%s: got %s %s status %d", test.name, herr.Method(), herr.Path(), herr.StatusCode())
+		}
+		if herr.Request() != http.MethodGet+" "+path {
+			t.Errorf("%s: request %q", test.name, herr.Request())
+		}
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package rpc
+
+import (
+	"context"
+	"fmt"
+
+	"blockwatch.cc/tzgo/tezos"
+)
+
+// Ensure UpdateConsensusKey implements the TypedOperation interface.
+var _ TypedOperation = (*UpdateConsensusKey)(nil)
+
+// UpdateConsensusKey represents a consensus key update operation (v015+).
+type UpdateConsensusKey struct {
+	Manager
+	PublicKey tezos.Key         `json:"pk"`
+	Metadata  OperationMetadata `json:"metadata"`
+}
+
+// Meta returns operation metadata to implement TypedOperation interface.
+func (o UpdateConsensusKey) Meta() OperationMetadata {
+	return o.Metadata
+}
+
+// Result returns operation result to implement TypedOperation interface.
+func (o UpdateConsensusKey) Result() OperationResult {
+	return o.Metadata.Result
+}
+
+// Costs returns operation cost to implement TypedOperation interface.
+func (o UpdateConsensusKey) Costs() tezos.Costs {
+	return tezos.Costs{
+		Fee:     o.Manager.Fee,
+		GasUsed: o.Metadata.Result.ConsumedGas,
+	}
+}
+
+// Ensure DrainDelegate implements the TypedOperation interface.
+var _ TypedOperation = (*DrainDelegate)(nil)
+
+// DrainDelegate represents a drain_delegate operation (v015+).
+type DrainDelegate struct {
+	Generic
+	ConsensusKey tezos.Address     `json:"consensus_key"`
+	Delegate     tezos.Address     `json:"delegate"`
+	Destination  tezos.Address     `json:"destination"`
+	Metadata     OperationMetadata `json:"metadata"`
+}
+
+// Meta returns operation metadata to implement TypedOperation interface.
+func (o DrainDelegate) Meta() OperationMetadata {
+	return o.Metadata
+}
+
+// ConsensusKey is a delegate's consensus key.
+type ConsensusKey struct {
+	Address   tezos.Address `json:"pkh"`
+	PublicKey tezos.Key     `json:"pk"`
+}
+
+// PendingConsensusKey is a consensus key that becomes active in cycle.
+type PendingConsensusKey struct {
+	ConsensusKey
+	Cycle int64 `json:"cycle"`
+}
+
+// ConsensusKeyInfo contains the active and pending consensus keys of a delegate.
+type ConsensusKeyInfo struct {
+	Active   ConsensusKey          `json:"active"`
+	Pendings []PendingConsensusKey `json:"pendings"`
+}
+
+// GetDelegateConsensusKey returns the active and pending consensus keys of
+// delegate addr at block id (v015+).
+func (c *Client) GetDelegateConsensusKey(ctx context.Context, addr tezos.Address, id BlockID) (*ConsensusKeyInfo, error) {
+	u := fmt.Sprintf("chains/main/blocks/%s/context/delegates/%s/consensus_key", id, addr)
+	info := &ConsensusKeyInfo{}
+	if err := c.Get(ctx, u, info); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
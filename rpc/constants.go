@@ -179,11 +179,21 @@ func (c *Client) GetConstantsHeight(ctx context.Context, height int64) (Constant
 // GetParamsByHeight returns a translated parameters structure for the current
 // network
 func (c *Client) GetParamsByHeight(ctx context.Context, height int64) (*tezos.Params, error) {
-	con, err := c.GetConstantsHeight(ctx, height)
+	return c.GetParams(ctx, BlockLevel(height))
+}
+
+// GetParams returns a translated parameters structure for the network and
+// protocol active at block id.
+func (c *Client) GetParams(ctx context.Context, id BlockID) (*tezos.Params, error) {
+	head, err := c.GetBlockHeader(ctx, id)
 	if err != nil {
 		return nil, err
 	}
-	head, err := c.GetBlockHeader(ctx, BlockLevel(height))
+	// pin constants to the same block when id is relative
+	if head.Hash.IsValid() {
+		id = head.Hash
+	}
+	con, err := c.GetConstants(ctx, id)
 	if err != nil {
 		return nil, err
 	}
@@ -5,7 +5,12 @@ package rpc
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"strconv"
 
 	"blockwatch.cc/tzgo/micheline"
 	"blockwatch.cc/tzgo/tezos"
@@ -71,7 +76,7 @@ func (c *Client) GetManagerKey(ctx context.Context, addr tezos.Address, id Block
 
 // GetContractExt returns info about an account at block id including its public key when revealed.
 func (c *Client) GetContractExt(ctx context.Context, addr tezos.Address, id BlockID) (*ContractInfo, error) {
-	u := fmt.Sprintf("chains/main/blocks/%s/context/raw/json/contracts/index/%s", id, addr)
+	u := rawPath(id, "contracts", "index", addr.String())
 	var info ContractInfo
 	err := c.Get(ctx, u, &info)
 	if err != nil {
@@ -80,20 +85,141 @@ func (c *Client) GetContractExt(ctx context.Context, addr tezos.Address, id Bloc
 	return &info, nil
 }
 
-// ListContracts returns a list of all known contracts at head. This call may be very SLOW for
-// large chains and there is no means to limit the result. Use with caution and consider
-// calling an indexer API instead.
+// ListContracts returns a list of all known contracts at block id. This call may be very
+// SLOW for large chains and there is no means to limit the result. Use with caution and
+// consider calling GetContractsStream or an indexer API instead.
 func (c *Client) ListContracts(ctx context.Context, id BlockID) (Contracts, error) {
 	contracts := make(Contracts, 0)
-	u := fmt.Sprintf("chains/main/blocks/%s/context/contracts", id)
-	if err := c.Get(ctx, u, &contracts); err != nil {
+	err := c.GetContractsStream(ctx, id, func(addr tezos.Address) error {
+		contracts = append(contracts, addr)
+		return nil
+	})
+	if err != nil {
 		return nil, err
 	}
 	return contracts, nil
 }
 
+// GetContractsStream decodes the list of all known contracts at block id incrementally
+// and calls fn for each address. Decoding stops when fn returns an error or the
+// context is canceled. The error is returned to the caller.
+func (c *Client) GetContractsStream(ctx context.Context, id BlockID, fn func(tezos.Address) error) error {
+	u := fmt.Sprintf("chains/main/blocks/%s/context/contracts", id)
+	return c.getArrayStream(ctx, u, func(dec *json.Decoder) error {
+		var addr tezos.Address
+		if err := dec.Decode(&addr); err != nil {
+			return err
+		}
+		return fn(addr)
+	})
+}
+
+// getArrayStream requests a JSON array from urlpath and decodes it element by
+// element instead of buffering the entire response. fn is called for each
+// element and must decode it from dec. The client's response size limit
+// applies to each element.
+func (c *Client) getArrayStream(ctx context.Context, urlpath string, fn func(*json.Decoder) error) error {
+	req, err := c.NewRequest(ctx, http.MethodGet, urlpath, nil)
+	if err != nil {
+		return err
+	}
+	return c.doStream(req, func(r io.Reader) error {
+		var lr *elementLimitReader
+		if c.maxResponseBytes > 0 {
+			lr = newElementLimitReader(r, c.maxResponseBytes)
+			r = lr
+		}
+		dec := json.NewDecoder(r)
+
+		// read open bracket
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		if d, ok := tok.(json.Delim); !ok || d != '[' {
+			return fmt.Errorf("rpc: unexpected token %v, expected array", tok)
+		}
+
+		for dec.More() {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if lr != nil {
+				lr.next(dec.InputOffset())
+			}
+			if err := fn(dec); err != nil {
+				return err
+			}
+		}
+
+		// read closing bracket
+		_, err = dec.Token()
+		return err
+	})
+}
+
+// GetContractBalance returns the spendable balance of an account at block id.
+func (c *Client) GetContractBalance(ctx context.Context, addr tezos.Address, id BlockID) (tezos.Mutez, error) {
+	u := fmt.Sprintf("chains/main/blocks/%s/context/contracts/%s/balance", id, addr)
+	return c.getMutez(ctx, u)
+}
+
+// GetContractBalanceBig returns the balance of addr at block id as big integer
+// for use in aggregations that may exceed the int64 range.
+func (c *Client) GetContractBalanceBig(ctx context.Context, addr tezos.Address, id BlockID) (*big.Int, error) {
+	u := fmt.Sprintf("chains/main/blocks/%s/context/contracts/%s/balance", id, addr)
+	return c.getBig(ctx, u)
+}
+
+// ScriptOptions controls how the node represents scripts and storage. The
+// zero value uses the node's default representation.
+type ScriptOptions struct {
+	Mode           UnparsingMode // Readable, Optimized or Optimized_legacy
+	NormalizeTypes bool          // expand comb pair types to nested pairs (scripts only)
+}
+
+// WithUnparsingMode returns options that request data in unparsing mode.
+// Use UnparsingModeOptimized for a stable binary representation, e.g. for
+// hashing.
+func WithUnparsingMode(mode UnparsingMode) ScriptOptions {
+	return ScriptOptions{Mode: mode}
+}
+
+// WithUnparsingMode sets the unparsing mode.
+func (o ScriptOptions) WithUnparsingMode(mode UnparsingMode) ScriptOptions {
+	o.Mode = mode
+	return o
+}
+
+// WithNormalizeTypes requests normalized types in scripts.
+func (o ScriptOptions) WithNormalizeTypes() ScriptOptions {
+	o.NormalizeTypes = true
+	return o
+}
+
+// IsDefault returns true when no option is set.
+func (o ScriptOptions) IsDefault() bool {
+	return o.Mode == "" && !o.NormalizeTypes
+}
+
+func mergeScriptOptions(opts []ScriptOptions) ScriptOptions {
+	var o ScriptOptions
+	for _, v := range opts {
+		if v.Mode != "" {
+			o.Mode = v.Mode
+		}
+		o.NormalizeTypes = o.NormalizeTypes || v.NormalizeTypes
+	}
+	return o
+}
+
 // GetContractScript returns the originated contract script in default data mode.
-func (c *Client) GetContractScript(ctx context.Context, addr tezos.Address) (*micheline.Script, error) {
+// When options are given the script is read from the normalized endpoint in the
+// requested representation.
+func (c *Client) GetContractScript(ctx context.Context, addr tezos.Address, opts ...ScriptOptions) (*micheline.Script, error) {
+	if o := mergeScriptOptions(opts); !o.IsDefault() {
+		return c.getContractScriptNormalized(ctx, addr, Head, o)
+	}
 	u := fmt.Sprintf("chains/main/blocks/head/context/contracts/%s/script", addr)
 	s := micheline.NewScript()
 	err := c.Get(ctx, u, s)
@@ -106,15 +232,31 @@ func (c *Client) GetContractScript(ctx context.Context, addr tezos.Address) (*mi
 // GetNormalizedScript returns the originated contract script with global constants
 // expanded using given unparsing mode.
 func (c *Client) GetNormalizedScript(ctx context.Context, addr tezos.Address, mode UnparsingMode) (*micheline.Script, error) {
-	u := fmt.Sprintf("chains/main/blocks/head/context/contracts/%s/script/normalized", addr)
-	s := micheline.NewScript()
+	return c.GetContractScriptNormalized(ctx, addr, Head, mode)
+}
+
+// GetContractScriptNormalized returns the contract script at block id with global
+// constants expanded and data represented in unparsing mode.
+func (c *Client) GetContractScriptNormalized(ctx context.Context, addr tezos.Address, id BlockID, mode UnparsingMode) (*micheline.Script, error) {
 	if mode == "" {
 		mode = UnparsingModeOptimized
 	}
+	return c.getContractScriptNormalized(ctx, addr, id, ScriptOptions{Mode: mode})
+}
+
+func (c *Client) getContractScriptNormalized(ctx context.Context, addr tezos.Address, id BlockID, o ScriptOptions) (*micheline.Script, error) {
+	u := fmt.Sprintf("chains/main/blocks/%s/context/contracts/%s/script/normalized", id, addr)
+	s := micheline.NewScript()
+	if o.Mode == "" {
+		// the endpoint requires a mode, match the node's default
+		o.Mode = UnparsingModeReadable
+	}
 	postData := struct {
-		Mode UnparsingMode `json:"unparsing_mode"`
+		Mode           UnparsingMode `json:"unparsing_mode"`
+		NormalizeTypes bool          `json:"normalize_types,omitempty"`
 	}{
-		Mode: mode,
+		Mode:           o.Mode,
+		NormalizeTypes: o.NormalizeTypes,
 	}
 	err := c.Post(ctx, u, &postData, s)
 	if err != nil {
@@ -123,8 +265,12 @@ func (c *Client) GetNormalizedScript(ctx context.Context, addr tezos.Address, mo
 	return s, nil
 }
 
-// GetContractStorage returns the contract's storage at block id.
-func (c *Client) GetContractStorage(ctx context.Context, addr tezos.Address, id BlockID) (micheline.Prim, error) {
+// GetContractStorage returns the contract's storage at block id. When an
+// unparsing mode is given the storage is read from the normalized endpoint.
+func (c *Client) GetContractStorage(ctx context.Context, addr tezos.Address, id BlockID, opts ...ScriptOptions) (micheline.Prim, error) {
+	if o := mergeScriptOptions(opts); o.Mode != "" {
+		return c.GetContractStorageNormalized(ctx, addr, id, o.Mode)
+	}
 	u := fmt.Sprintf("chains/main/blocks/%s/context/contracts/%s/storage", id, addr)
 	prim := micheline.Prim{}
 	err := c.Get(ctx, u, &prim)
@@ -169,17 +315,37 @@ func (c *Client) GetContractEntrypoints(ctx context.Context, addr tezos.Address)
 
 // ListBigmapKeys returns all keys in the bigmap at block id. This call may be very SLOW for
 // large bigmaps and there is no means to limit the result. Use with caution and consider
-// calling an indexer API instead.
+// calling an indexer API instead. Keys are read from the raw context, on nodes
+// that do not serve it the error wraps ErrRawContextDisabled.
 func (c *Client) ListBigmapKeys(ctx context.Context, bigmap int64, id BlockID) ([]tezos.ExprHash, error) {
-	u := fmt.Sprintf("chains/main/blocks/%s/context/raw/json/big_maps/index/%d/contents", id, bigmap)
+	u := rawPath(id, "big_maps", "index", strconv.FormatInt(bigmap, 10), "contents")
 	hashes := make([]tezos.ExprHash, 0)
 	err := c.Get(ctx, u, &hashes)
 	if err != nil {
-		return nil, err
+		return nil, c.rawError(ctx, id, err)
 	}
 	return hashes, nil
 }
 
+// GetBigmapKeysStream decodes all keys in the bigmap at block id incrementally
+// and calls fn for each key hash. Decoding stops when fn returns an error or the
+// context is canceled. The error is returned to the caller. Like ListBigmapKeys
+// it fails with ErrRawContextDisabled on nodes that do not serve the raw context.
+func (c *Client) GetBigmapKeysStream(ctx context.Context, bigmap int64, id BlockID, fn func(tezos.ExprHash) error) error {
+	u := rawPath(id, "big_maps", "index", strconv.FormatInt(bigmap, 10), "contents")
+	err := c.getArrayStream(ctx, u, func(dec *json.Decoder) error {
+		var h tezos.ExprHash
+		if err := dec.Decode(&h); err != nil {
+			return err
+		}
+		return fn(h)
+	})
+	if err != nil {
+		return c.rawError(ctx, id, err)
+	}
+	return nil
+}
+
 // ListActiveBigmapKeys returns all active keys in the bigmap. This call may be very SLOW for
 // large bigmaps and there is no means to limit the result. Use with caution and consider
 // calling an indexer API instead.
@@ -216,6 +382,20 @@ func (c *Client) ListBigmapValues(ctx context.Context, bigmap int64, id BlockID)
 	return vals, nil
 }
 
+// GetBigmapValuesStream decodes all values from bigmap at block id
+// incrementally and calls fn for each value. Decoding stops when fn returns an
+// error or the context is canceled. The error is returned to the caller.
+func (c *Client) GetBigmapValuesStream(ctx context.Context, bigmap int64, id BlockID, fn func(micheline.Prim) error) error {
+	u := fmt.Sprintf("chains/main/blocks/%s/context/big_maps/%d", id, bigmap)
+	return c.getArrayStream(ctx, u, func(dec *json.Decoder) error {
+		var prim micheline.Prim
+		if err := dec.Decode(&prim); err != nil {
+			return err
+		}
+		return fn(prim)
+	})
+}
+
 // ListActiveBigmapValues returns all values from bigmap at block id. This call may be very SLOW for
 // large bigmaps and there is no means to limit the result. Use with caution and consider
 // calling an indexer API instead.
@@ -234,13 +414,15 @@ func (c *Client) GetActiveBigmapInfo(ctx context.Context, bigmap int64) (*Bigmap
 	return c.GetBigmapInfo(ctx, bigmap, Head)
 }
 
-// GetBigmapInfo returns type and content info from bigmap at block id.
+// GetBigmapInfo returns type and content info from bigmap at block id. Info is
+// read from the raw context, on nodes that do not serve it the error wraps
+// ErrRawContextDisabled.
 func (c *Client) GetBigmapInfo(ctx context.Context, bigmap int64, id BlockID) (*BigmapInfo, error) {
-	u := fmt.Sprintf("chains/main/blocks/%s/context/raw/json/big_maps/index/%d", id, bigmap)
+	u := rawPath(id, "big_maps", "index", strconv.FormatInt(bigmap, 10))
 	info := &BigmapInfo{}
 	err := c.Get(ctx, u, info)
 	if err != nil {
-		return nil, err
+		return nil, c.rawError(ctx, id, err)
 	}
 	return info, nil
 }
@@ -5,8 +5,9 @@ package rpc
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
-	"strconv"
+	"math/big"
 
 	"blockwatch.cc/tzgo/tezos"
 )
@@ -55,7 +56,7 @@ func (c *Client) ListActiveDelegates(ctx context.Context, id BlockID) (DelegateL
 // who have at least one roll. Deprecated in Ithaca.
 func (c *Client) ListActiveDelegatesWithRolls(ctx context.Context, id BlockID) (DelegateList, error) {
 	delegates := make(DelegateList, 0)
-	u := fmt.Sprintf("chains/main/blocks/%s/context/raw/json/active_delegates_with_rolls", id)
+	u := rawPath(id, "active_delegates_with_rolls")
 	if err := c.Get(ctx, u, &delegates); err != nil {
 		return nil, err
 	}
@@ -77,11 +78,219 @@ func (c *Client) GetDelegate(ctx context.Context, addr tezos.Address, id BlockID
 
 // GetDelegateBalance returns a delegate's balance
 func (c *Client) GetDelegateBalance(ctx context.Context, addr tezos.Address, id BlockID) (int64, error) {
+	bal, err := c.GetDelegateBalanceMutez(ctx, addr, id)
+	return bal.Int64(), err
+}
+
+// GetDelegateBalanceMutez returns a delegate's balance as Mutez.
+func (c *Client) GetDelegateBalanceMutez(ctx context.Context, addr tezos.Address, id BlockID) (tezos.Mutez, error) {
+	u := fmt.Sprintf("chains/main/blocks/%s/context/delegates/%s/balance", id, addr)
+	return c.getMutez(ctx, u)
+}
+
+// GetDelegateBalanceBig returns a delegate's balance as big integer.
+func (c *Client) GetDelegateBalanceBig(ctx context.Context, addr tezos.Address, id BlockID) (*big.Int, error) {
 	u := fmt.Sprintf("chains/main/blocks/%s/context/delegates/%s/balance", id, addr)
-	var bal string
-	err := c.Get(ctx, u, &bal)
+	return c.getBig(ctx, u)
+}
+
+// GetDelegateFrozenDeposits returns a delegate's frozen deposits. v012+
+func (c *Client) GetDelegateFrozenDeposits(ctx context.Context, addr tezos.Address, id BlockID) (tezos.Mutez, error) {
+	u := fmt.Sprintf("chains/main/blocks/%s/context/delegates/%s/frozen_deposits", id, addr)
+	return c.getMutez(ctx, u)
+}
+
+// GetDelegateFrozenDepositsBig returns a delegate's frozen deposits as big
+// integer. v012+
+func (c *Client) GetDelegateFrozenDepositsBig(ctx context.Context, addr tezos.Address, id BlockID) (*big.Int, error) {
+	u := fmt.Sprintf("chains/main/blocks/%s/context/delegates/%s/frozen_deposits", id, addr)
+	return c.getBig(ctx, u)
+}
+
+// GetDelegateStakingBalance returns a delegate's staking balance which
+// includes delegated balances.
+func (c *Client) GetDelegateStakingBalance(ctx context.Context, addr tezos.Address, id BlockID) (tezos.Mutez, error) {
+	u := fmt.Sprintf("chains/main/blocks/%s/context/delegates/%s/staking_balance", id, addr)
+	return c.getMutez(ctx, u)
+}
+
+// GetDelegateStakingBalanceBig returns a delegate's staking balance as big
+// integer.
+func (c *Client) GetDelegateStakingBalanceBig(ctx context.Context, addr tezos.Address, id BlockID) (*big.Int, error) {
+	u := fmt.Sprintf("chains/main/blocks/%s/context/delegates/%s/staking_balance", id, addr)
+	return c.getBig(ctx, u)
+}
+
+// Participation summarizes a delegate's consensus activity in the current
+// cycle (v012+). Slots and levels count endorsements/attestations.
+type Participation struct {
+	ExpectedCycleActivity       int64 `json:"expected_cycle_activity"`
+	MinimalCycleActivity        int64 `json:"minimal_cycle_activity"`
+	MissedSlots                 int64 `json:"missed_slots"`
+	MissedLevels                int64 `json:"missed_levels"`
+	RemainingAllowedMissedSlots int64 `json:"remaining_allowed_missed_slots"`
+	ExpectedRewards             int64 `json:"expected_rewards"` // endorsing/attesting rewards in mutez
+}
+
+func (p *Participation) UnmarshalJSON(data []byte) error {
+	var v struct {
+		ExpectedCycleActivity       int64 `json:"expected_cycle_activity"`
+		MinimalCycleActivity        int64 `json:"minimal_cycle_activity"`
+		MissedSlots                 int64 `json:"missed_slots"`
+		MissedLevels                int64 `json:"missed_levels"`
+		RemainingAllowedMissedSlots int64 `json:"remaining_allowed_missed_slots"`
+		EndorsingRewards            int64 `json:"expected_endorsing_rewards,string"`
+		AttestingRewards            int64 `json:"expected_attesting_rewards,string"` // v018+
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*p = Participation{
+		ExpectedCycleActivity:       v.ExpectedCycleActivity,
+		MinimalCycleActivity:        v.MinimalCycleActivity,
+		MissedSlots:                 v.MissedSlots,
+		MissedLevels:                v.MissedLevels,
+		RemainingAllowedMissedSlots: v.RemainingAllowedMissedSlots,
+		ExpectedRewards:             v.EndorsingRewards + v.AttestingRewards,
+	}
+	return nil
+}
+
+// IsAtRisk returns true when the delegate may not miss any more slots this
+// cycle without losing its endorsing/attesting rewards.
+func (p Participation) IsAtRisk() bool {
+	return p.ExpectedCycleActivity > 0 && p.RemainingAllowedMissedSlots <= 0
+}
+
+// GetParticipation returns a delegate's participation in the cycle of block id.
+// https://tezos.gitlab.io/active/rpc.html#get-block-id-context-delegates-pkh-participation
+func (c *Client) GetParticipation(ctx context.Context, addr tezos.Address, id BlockID) (*Participation, error) {
+	var p Participation
+	u := fmt.Sprintf("chains/main/blocks/%s/context/delegates/%s/participation", id, addr)
+	if err := c.Get(ctx, u, &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// DelegateActivity combines a delegate's deactivation status with its
+// participation in the current cycle.
+type DelegateActivity struct {
+	Delegate      tezos.Address
+	Cycle         int64          // cycle of the queried block
+	Deactivated   bool           // delegate is inactive
+	GracePeriod   int64          // last cycle before deactivation
+	Participation *Participation // nil before v012
+}
+
+// CyclesUntilDeactivation returns the number of cycles the delegate may stay
+// inactive before it is deactivated.
+func (a DelegateActivity) CyclesUntilDeactivation() int64 {
+	if a.Deactivated || a.GracePeriod < a.Cycle {
+		return 0
+	}
+	return a.GracePeriod - a.Cycle
+}
+
+// GetDelegateActivity returns deactivation status, grace period and
+// participation of a delegate at block id.
+func (c *Client) GetDelegateActivity(ctx context.Context, addr tezos.Address, id BlockID) (*DelegateActivity, error) {
+	d, err := c.GetDelegate(ctx, addr, id)
+	if err != nil {
+		return nil, err
+	}
+	level, err := c.GetLevelInfo(ctx, id, 0)
+	if err != nil {
+		return nil, err
+	}
+	act := &DelegateActivity{
+		Delegate:    addr,
+		Cycle:       level.Cycle,
+		Deactivated: d.Deactivated,
+		GracePeriod: d.GracePeriod,
+	}
+	p, err := c.GetParticipation(ctx, addr, id)
+	switch {
+	case err == nil:
+		act.Participation = p
+	case ErrorStatus(err) != 404:
+		return nil, err
+	}
+	return act, nil
+}
+
+// CycleRewards is the sum of rewards and fees a delegate earned in a cycle.
+type CycleRewards struct {
+	Delegate   tezos.Address
+	Cycle      int64
+	Total      int64            // sum of all categories in mutez
+	ByCategory map[string]int64 // balance update category -> mutez
+}
+
+// GetDelegateCycleRewards sums reward and fee balance updates credited to
+// addr in all blocks of cycle. Rewards paid to stake delegated to addr are
+// included because protocols do not separate them from the baker's own
+// stake. Cycle bounds are derived from the client's current params.
+func (c *Client) GetDelegateCycleRewards(ctx context.Context, addr tezos.Address, cycle int64) (*CycleRewards, error) {
+	p, err := c.Params(ctx)
 	if err != nil {
-		return 0, err
+		return nil, err
+	}
+	res := &CycleRewards{
+		Delegate:   addr,
+		Cycle:      cycle,
+		ByCategory: make(map[string]int64),
+	}
+	for height := p.CycleStartHeight(cycle); height <= p.CycleEndHeight(cycle); height++ {
+		b, err := c.GetBlockHeight(ctx, height)
+		if err != nil {
+			return nil, err
+		}
+		res.AddBlock(b)
+	}
+	return res, nil
+}
+
+// AddBlock adds rewards and fees credited to the delegate in block b.
+func (r *CycleRewards) AddBlock(b *Block) {
+	r.add(b.Metadata.BalanceUpdates)
+	for _, list := range b.Operations {
+		for _, op := range list {
+			for _, c := range op.Contents {
+				r.add(c.Meta().BalanceUpdates)
+			}
+		}
 	}
-	return strconv.ParseInt(bal, 10, 64)
+}
+
+// add matches each reward or fee source update with the credit that follows
+// it. Legacy frozen rewards and fees are credited directly.
+func (r *CycleRewards) add(list BalanceUpdates) {
+	for i, v := range list {
+		if !v.IsReward() && !v.IsFee() {
+			continue
+		}
+		if v.Kind == BalanceKindFreezer {
+			// legacy frozen rewards and fees
+			if v.Change > 0 && v.Delegate.Equal(r.Delegate) {
+				r.credit(v.Category, v.Change)
+			}
+			continue
+		}
+		if v.Change >= 0 || i+1 >= len(list) {
+			continue
+		}
+		next := list[i+1]
+		if next.Change != -v.Change || !next.IsTez() {
+			continue
+		}
+		if next.Contract.Equal(r.Delegate) || (!next.Contract.IsValid() && next.Delegate.Equal(r.Delegate)) {
+			r.credit(v.Category, next.Change)
+		}
+	}
+}
+
+func (r *CycleRewards) credit(category string, amount int64) {
+	r.ByCategory[category] += amount
+	r.Total += amount
 }
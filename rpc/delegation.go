@@ -27,6 +27,11 @@ func (d Delegation) Result() OperationResult {
 	return d.Metadata.Result
 }
 
+// IsWithdrawal returns true when the operation removes the source's delegate.
+func (d Delegation) IsWithdrawal() bool {
+	return !d.Delegate.IsValid()
+}
+
 // Cost returns operation cost to implement TypedOperation interface.
 func (d Delegation) Costs() tezos.Costs {
 	return tezos.Costs{
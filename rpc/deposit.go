@@ -4,6 +4,8 @@
 package rpc
 
 import (
+    "encoding/json"
+
     "blockwatch.cc/tzgo/tezos"
 )
 
@@ -11,12 +13,52 @@ import (
 var _ TypedOperation = (*SetDepositsLimit)(nil)
 
 // SetDepositsLimit represents a baker deposit limit update operation.
+// The limit is optional, when HasLimit is false the operation removes
+// an existing limit.
 type SetDepositsLimit struct {
     Manager
     Limit    int64             `json:"limit,string"`
+    HasLimit bool              `json:"-"`
     Metadata OperationMetadata `json:"metadata"`
 }
 
+func (r SetDepositsLimit) MarshalJSON() ([]byte, error) {
+    type alias SetDepositsLimit
+    v := struct {
+        alias
+        Limit *tezos.Z `json:"limit,omitempty"`
+    }{
+        alias: alias(r),
+    }
+    if r.HasLimit {
+        z := tezos.NewZ(r.Limit)
+        v.Limit = &z
+    }
+    return json.Marshal(v)
+}
+
+func (r *SetDepositsLimit) UnmarshalJSON(data []byte) error {
+    type alias SetDepositsLimit
+    var v struct {
+        *alias
+        Limit *json.RawMessage `json:"limit"`
+    }
+    v.alias = (*alias)(r)
+    if err := json.Unmarshal(data, &v); err != nil {
+        return err
+    }
+    r.Limit, r.HasLimit = 0, false
+    if v.Limit == nil || string(*v.Limit) == "null" {
+        return nil
+    }
+    var z tezos.Z
+    if err := json.Unmarshal(*v.Limit, &z); err != nil {
+        return err
+    }
+    r.Limit, r.HasLimit = z.Int64(), true
+    return nil
+}
+
 // Meta returns operation metadata to implement TypedOperation interface.
 func (r SetDepositsLimit) Meta() OperationMetadata {
     return r.Metadata
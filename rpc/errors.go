@@ -6,9 +6,16 @@ package rpc
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
+	"net/url"
 )
 
+// maxErrorBody limits the number of response body bytes printed in error
+// messages. Body returns the full response.
+const maxErrorBody = 1024
+
 const (
 	// ErrorKindPermanent Tezos RPC error kind.
 	ErrorKindPermanent = "permanent"
@@ -19,12 +26,11 @@ const (
 )
 
 func ErrorStatus(err error) int {
-	switch e := err.(type) {
-	case *httpError:
-		return e.statusCode
-	default:
-		return 0
+	var e HTTPStatus
+	if errors.As(err, &e) {
+		return e.StatusCode()
 	}
+	return 0
 }
 
 // Error is a Tezos error as documented on http://tezos.gitlab.io/mainnet/api/errors.html.
@@ -62,10 +68,13 @@ type HTTPStatus interface {
 	Body() []byte
 }
 
-// HTTPError retains HTTP status
+// HTTPError retains HTTP status and the failed request. Use errors.As to
+// extract it from errors returned by the client.
 type HTTPError interface {
 	error
 	HTTPStatus
+	Method() string // e.g. GET
+	Path() string   // request URI with secrets redacted, e.g. /chains/main/blocks/head
 }
 
 // RPCError is a Tezos RPC error as documented on http://tezos.gitlab.io/mainnet/api/errors.html.
@@ -118,19 +127,76 @@ func (e Errors) ErrorKind() string {
 	return e[0].ErrorKind()
 }
 
+// httpError describes a failed request. Status fields are empty when no
+// response was received. Transport, decoding and stream errors are kept as
+// cause and can be inspected with errors.Is and errors.As.
 type httpError struct {
-	request    string
+	method     string
+	path       string
 	status     string
 	statusCode int
 	body       []byte
+	err        error
+}
+
+// wrapError adds request method, path and response status to err unless err
+// already carries them.
+func wrapError(req *http.Request, resp *http.Response, err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := err.(HTTPStatus); ok {
+		return err
+	}
+	// the url error repeats the unredacted request URL
+	if e, ok := err.(*url.Error); ok {
+		err = e.Err
+	}
+	e := &httpError{
+		method: req.Method,
+		path:   redactedURI(req),
+		err:    err,
+	}
+	if resp != nil {
+		e.status = resp.Status
+		e.statusCode = resp.StatusCode
+	}
+	return e
 }
 
 func (e *httpError) Error() string {
-	return fmt.Sprintf("rpc: %s status %d (%v)", e.request, e.statusCode, string(e.body))
+	switch {
+	case e.err == nil:
+		return fmt.Sprintf("rpc: %s status %d (%s)", e.Request(), e.statusCode, e.shortBody())
+	case e.statusCode == 0:
+		return fmt.Sprintf("rpc: %s: %v", e.Request(), e.err)
+	default:
+		return fmt.Sprintf("rpc: %s status %d: %v", e.Request(), e.statusCode, e.err)
+	}
+}
+
+// shortBody returns the response body truncated for use in error messages.
+func (e *httpError) shortBody() string {
+	if len(e.body) > maxErrorBody {
+		return string(e.body[:maxErrorBody]) + "..."
+	}
+	return string(e.body)
+}
+
+func (e *httpError) Unwrap() error {
+	return e.err
 }
 
 func (e *httpError) Request() string {
-	return e.request
+	return e.method + " " + e.path
+}
+
+func (e *httpError) Method() string {
+	return e.method
+}
+
+func (e *httpError) Path() string {
+	return e.path
 }
 
 func (e *httpError) Status() string {
@@ -151,7 +217,7 @@ type rpcError struct {
 }
 
 func (e *rpcError) Error() string {
-	return e.errors.Error()
+	return fmt.Sprintf("rpc: %s status %d: %s", e.Request(), e.statusCode, e.errors.Error())
 }
 
 func (e *rpcError) ErrorID() string {
@@ -172,7 +238,7 @@ type plainError struct {
 }
 
 func (e *plainError) Error() string {
-	return e.msg
+	return fmt.Sprintf("rpc: %s status %d: %s", e.Request(), e.statusCode, e.msg)
 }
 
 var (
@@ -0,0 +1,66 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package rpc
+
+import (
+	"blockwatch.cc/tzgo/micheline"
+	"blockwatch.cc/tzgo/tezos"
+)
+
+// ContractEvent is an event emitted by a contract with the EMIT instruction
+// (v014+). Events appear as internal operation results of kind event.
+type ContractEvent struct {
+	Source  tezos.Address  // emitting contract
+	Type    micheline.Prim // declared payload type
+	Tag     string         // event tag, empty for the default tag
+	Payload micheline.Prim // event payload
+	Nonce   int64          // internal operation nonce
+}
+
+// NewContractEvent converts an internal result of kind event. Returns false for
+// other kinds.
+func NewContractEvent(r *InternalResult) (ContractEvent, bool) {
+	if r == nil || !r.IsEvent() {
+		return ContractEvent{}, false
+	}
+	ev := ContractEvent{
+		Source: r.Source,
+		Tag:    r.Tag,
+		Nonce:  r.Nonce,
+	}
+	if r.Type != nil {
+		ev.Type = *r.Type
+	}
+	if r.Payload != nil {
+		ev.Payload = *r.Payload
+	}
+	return ev, true
+}
+
+// Events returns all successfully emitted events contained in internal results.
+func (m OperationMetadata) Events() []ContractEvent {
+	events := make([]ContractEvent, 0)
+	for _, in := range m.InternalResults {
+		if !in.Result.Status.IsSuccess() {
+			continue
+		}
+		if ev, ok := NewContractEvent(in); ok {
+			events = append(events, ev)
+		}
+	}
+	return events
+}
+
+// DecodeEvents returns all events emitted by the receipt's operation across
+// all batched contents.
+func DecodeEvents(rec *Receipt) []ContractEvent {
+	if rec == nil || rec.Op == nil {
+		return nil
+	}
+	events := make([]ContractEvent, 0)
+	for _, c := range rec.Op.Contents {
+		events = append(events, c.Meta().Events()...)
+	}
+	return events
+}
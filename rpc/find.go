@@ -0,0 +1,133 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package rpc
+
+import (
+	"context"
+	"errors"
+
+	"blockwatch.cc/tzgo/tezos"
+)
+
+// ErrOperationNotFound is returned when an operation hash is not contained in
+// any of the searched blocks.
+var ErrOperationNotFound = errors.New("rpc: operation not found")
+
+// FindOperation looks up operation oph in block id. It first lists the block's
+// operation hashes to locate the operation's list and position and then fetches
+// only this single operation including receipts.
+func (c *Client) FindOperation(ctx context.Context, id BlockID, oph tezos.OpHash) (*Operation, error) {
+	hashes, err := c.GetBlockOperationHashes(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	l, n, ok := findOpPosition(hashes, oph)
+	if !ok {
+		return nil, ErrOperationNotFound
+	}
+	return c.GetBlockOperation(ctx, id, l, n)
+}
+
+// FindRecentOperation searches for operation oph in blocks walking backwards
+// from the current head for up to depth blocks. When depth is <= 0 the protocol's
+// max_operations_ttl is used which covers the lifetime of any operation still
+// valid for inclusion. Returns the operation and the level of the containing block.
+func (c *Client) FindRecentOperation(ctx context.Context, oph tezos.OpHash, depth int64) (*Operation, int64, error) {
+	if depth <= 0 {
+		p, err := c.Params(ctx)
+		if err != nil {
+			return nil, 0, err
+		}
+		depth = p.MaxOperationsTTL
+	}
+	head, err := c.GetTipHeader(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	from := head.Level - depth
+	if from < 0 {
+		from = 0
+	}
+	return c.FindOperationInRange(ctx, oph, from, head.Level)
+}
+
+// FindOperationInRange searches for operation oph in blocks between levels
+// from and to (inclusive), walking backwards from to. Returns the operation and
+// the level of the containing block or ErrOperationNotFound.
+func (c *Client) FindOperationInRange(ctx context.Context, oph tezos.OpHash, from, to int64) (*Operation, int64, error) {
+	for level := to; level >= from; level-- {
+		if err := ctx.Err(); err != nil {
+			return nil, 0, err
+		}
+		id := BlockLevel(level)
+		hashes, err := c.GetBlockOperationHashes(ctx, id)
+		if err != nil {
+			return nil, 0, err
+		}
+		l, n, ok := findOpPosition(hashes, oph)
+		if !ok {
+			continue
+		}
+		op, err := c.GetBlockOperation(ctx, id, l, n)
+		if err != nil {
+			return nil, 0, err
+		}
+		return op, level, nil
+	}
+	return nil, 0, ErrOperationNotFound
+}
+
+// FindOperationNear searches for operation oph in blocks around hintLevel,
+// e.g. the level reported by a receipt or confirmation, checking the closest
+// blocks first. The search covers max_operations_ttl levels in both directions
+// and never goes past the current head. Without a hint it behaves like
+// FindRecentOperation. Returns the operation and the level of the containing
+// block or ErrOperationNotFound.
+func (c *Client) FindOperationNear(ctx context.Context, oph tezos.OpHash, hintLevel int64) (*Operation, int64, error) {
+	if hintLevel <= 0 {
+		return c.FindRecentOperation(ctx, oph, 0)
+	}
+	p, err := c.Params(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	head, err := c.GetTipHeader(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	for dist := int64(0); dist <= p.MaxOperationsTTL; dist++ {
+		levels := []int64{hintLevel + dist, hintLevel - dist}
+		if dist == 0 {
+			levels = levels[:1]
+		}
+		for _, level := range levels {
+			if level < 0 || level > head.Level {
+				continue
+			}
+			if err := ctx.Err(); err != nil {
+				return nil, 0, err
+			}
+			op, err := c.FindOperation(ctx, BlockLevel(level), oph)
+			switch err {
+			case nil:
+				return op, level, nil
+			case ErrOperationNotFound:
+			default:
+				return nil, 0, err
+			}
+		}
+	}
+	return nil, 0, ErrOperationNotFound
+}
+
+func findOpPosition(hashes [][]tezos.OpHash, oph tezos.OpHash) (int, int, bool) {
+	for l, list := range hashes {
+		for n, h := range list {
+			if h.Equal(oph) {
+				return l, n, true
+			}
+		}
+	}
+	return 0, 0, false
+}
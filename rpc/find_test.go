@@ -0,0 +1,199 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"blockwatch.cc/tzgo/tezos"
+)
+
+var testFindOp = testOpHash(42, 0xf0)
+
+// newFindMock serves a chain with head at level head where operation
+// testFindOp is at position 1 of list 3 in the block at level found. All
+// other blocks contain unrelated operations.
+func newFindMock(t *testing.T, head, found int64) (*Client, *Mock) {
+	t.Helper()
+	m := NewMock()
+	m.On(http.MethodGet, "chains/main/blocks/head/header", []byte(fmt.Sprintf(`{"level":%d}`, head)))
+	for level := int64(0); level <= head; level++ {
+		hashes := [][]tezos.OpHash{{testOpHash(1, 0xf0)}, {}, {}, {testOpHash(2, 0xf0)}}
+		if level == found {
+			hashes[3] = append(hashes[3], testFindOp)
+			m.On(http.MethodGet, fmt.Sprintf("chains/main/blocks/%d/operations/3/1", level), []byte(fmt.Sprintf(`{"hash":%q}`, testFindOp)))
+		}
+		m.On(http.MethodGet, fmt.Sprintf("chains/main/blocks/%d/operation_hashes", level), hashes)
+	}
+	c, err := m.Client()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := tezos.NewParams()
+	p.MaxOperationsTTL = 3
+	c.params = p
+	return c, m
+}
+
+// searchedLevels returns the levels of all listed blocks in request order.
+func searchedLevels(m *Mock) []int64 {
+	levels := make([]int64, 0)
+	for _, r := range m.Requests() {
+		if !strings.HasSuffix(r, "/operation_hashes") {
+			continue
+		}
+		var level int64
+		fmt.Sscanf(r, "GET chains/main/blocks/%d/operation_hashes", &level)
+		levels = append(levels, level)
+	}
+	return levels
+}
+
+func TestFindOpPosition(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		hashes [][]tezos.OpHash
+		l, n   int
+		ok     bool
+	}{
+		{name: "empty", hashes: nil},
+		{name: "first", hashes: [][]tezos.OpHash{{testFindOp}}, l: 0, n: 0, ok: true},
+		{name: "manager list", hashes: [][]tezos.OpHash{{testOpHash(1, 0xf0)}, {}, {}, {testOpHash(2, 0xf0), testOpHash(3, 0xf0), testFindOp}}, l: 3, n: 2, ok: true},
+		{name: "missing", hashes: [][]tezos.OpHash{{testOpHash(1, 0xf0)}, {testOpHash(2, 0xf0)}}},
+	} {
+		l, n, ok := findOpPosition(test.hashes, testFindOp)
+		if l != test.l || n != test.n || ok != test.ok {
+			t.Errorf("%s: got %d/%d %t want %d/%d %t", test.name, l, n, ok, test.l, test.n, test.ok)
+		}
+	}
+}
+
+func TestFindOperation(t *testing.T) {
+	ctx := context.Background()
+	for _, test := range []struct {
+		name   string
+		found  int64
+		search func(c *Client) (*Operation, int64, error)
+		level  int64
+		levels []int64
+	}{
+		{
+			name:  "block",
+			found: 5,
+			search: func(c *Client) (*Operation, int64, error) {
+				op, err := c.FindOperation(ctx, BlockLevel(5), testFindOp)
+				return op, 5, err
+			},
+			level:  5,
+			levels: []int64{5},
+		},
+		{
+			name:  "block miss",
+			found: 5,
+			search: func(c *Client) (*Operation, int64, error) {
+				op, err := c.FindOperation(ctx, BlockLevel(6), testFindOp)
+				return op, 6, err
+			},
+			levels: []int64{6},
+		},
+		{
+			name:  "range",
+			found: 7,
+			search: func(c *Client) (*Operation, int64, error) {
+				return c.FindOperationInRange(ctx, testFindOp, 5, 9)
+			},
+			level:  7,
+			levels: []int64{9, 8, 7},
+		},
+		{
+			name:  "range miss",
+			found: 3,
+			search: func(c *Client) (*Operation, int64, error) {
+				return c.FindOperationInRange(ctx, testFindOp, 5, 7)
+			},
+			levels: []int64{7, 6, 5},
+		},
+		{
+			name:  "recent ttl",
+			found: 18,
+			search: func(c *Client) (*Operation, int64, error) {
+				return c.FindRecentOperation(ctx, testFindOp, 0)
+			},
+			level:  18,
+			levels: []int64{20, 19, 18},
+		},
+		{
+			name:  "recent depth",
+			found: 15,
+			search: func(c *Client) (*Operation, int64, error) {
+				return c.FindRecentOperation(ctx, testFindOp, 2)
+			},
+			levels: []int64{20, 19, 18},
+		},
+		{
+			name:  "near",
+			found: 9,
+			search: func(c *Client) (*Operation, int64, error) {
+				return c.FindOperationNear(ctx, testFindOp, 10)
+			},
+			level:  9,
+			levels: []int64{10, 11, 9},
+		},
+		{
+			name:  "near head",
+			found: 17,
+			search: func(c *Client) (*Operation, int64, error) {
+				return c.FindOperationNear(ctx, testFindOp, 19)
+			},
+			level:  17,
+			levels: []int64{19, 20, 18, 17},
+		},
+		{
+			name:  "near genesis",
+			found: 15,
+			search: func(c *Client) (*Operation, int64, error) {
+				return c.FindOperationNear(ctx, testFindOp, 1)
+			},
+			levels: []int64{1, 2, 0, 3, 4},
+		},
+		{
+			name:  "near without hint",
+			found: 17,
+			search: func(c *Client) (*Operation, int64, error) {
+				return c.FindOperationNear(ctx, testFindOp, 0)
+			},
+			level:  17,
+			levels: []int64{20, 19, 18, 17},
+		},
+	} {
+		c, m := newFindMock(t, 20, test.found)
+		op, level, err := test.search(c)
+		switch {
+		case test.level == 0:
+			if err != ErrOperationNotFound {
+				t.Errorf("%s: expected ErrOperationNotFound, got %v", test.name, err)
+			}
+		case err != nil:
+			t.Errorf("%s: %v", test.name, err)
+		case level != test.level || !op.Hash.Equal(testFindOp):
+			t.Errorf("%s: found %s at level %d", test.name, op.Hash, level)
+		}
+		if got := searchedLevels(m); !reflect.DeepEqual(got, test.levels) {
+			t.Errorf("%s: searched %v want %v", test.name, got, test.levels)
+		}
+	}
+}
+
+func TestFindOperationError(t *testing.T) {
+	c, m := newFindMock(t, 20, 9)
+	m.On(http.MethodGet, "chains/main/blocks/11/operation_hashes", nil).WithStatus(http.StatusInternalServerError)
+	if _, _, err := c.FindOperationNear(context.Background(), testFindOp, 10); ErrorStatus(err) != http.StatusInternalServerError {
+		t.Errorf("unexpected error %v", err)
+	}
+}
@@ -0,0 +1,353 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"blockwatch.cc/tzgo/tezos"
+)
+
+// ErrReorgTooDeep is returned by a block follower when a reorganization
+// exceeds the configured max depth.
+var ErrReorgTooDeep = errors.New("rpc: reorganization exceeds max depth")
+
+type BlockEventType byte
+
+const (
+	BlockApply    BlockEventType = iota // block was added to the canonical chain
+	BlockRollback                       // block was removed from the canonical chain
+)
+
+func (t BlockEventType) String() string {
+	switch t {
+	case BlockApply:
+		return "apply"
+	case BlockRollback:
+		return "rollback"
+	}
+	return ""
+}
+
+// BlockEvent is sent by a block follower for every block applied to or
+// rolled back from the local canonical chain. Cursor is the follower state
+// after the event and may be persisted to resume following later.
+type BlockEvent struct {
+	Type   BlockEventType
+	Block  *Block
+	Cursor BlockCursor
+}
+
+// BlockRef identifies a block by height and hash.
+type BlockRef struct {
+	Height int64           `json:"height"`
+	Hash   tezos.BlockHash `json:"hash"`
+}
+
+// BlockCursor holds the most recent blocks of a follower's canonical chain in
+// ascending order. It is JSON serializable.
+type BlockCursor []BlockRef
+
+// Tip returns the most recent block of the cursor.
+func (c BlockCursor) Tip() (BlockRef, bool) {
+	if len(c) == 0 {
+		return BlockRef{}, false
+	}
+	return c[len(c)-1], true
+}
+
+func (c BlockCursor) Clone() BlockCursor {
+	clone := make(BlockCursor, len(c))
+	for i, v := range c {
+		clone[i] = BlockRef{Height: v.Height, Hash: v.Hash.Clone()}
+	}
+	return clone
+}
+
+// BlockFollowerOptions configures a block follower.
+type BlockFollowerOptions struct {
+	// Max number of blocks rolled back during a single reorganization. Deeper
+	// reorganizations stop the follower with ErrReorgTooDeep. Also defines how
+	// many block hashes the cursor keeps.
+	MaxReorgDepth int
+	// Poll for new heads instead of streaming them. Nodes without support for
+	// streaming are polled automatically.
+	Poll bool
+	// Interval for polling new heads, defaults to half the minimal block delay.
+	PollInterval time.Duration
+}
+
+var DefaultBlockFollowerOptions = BlockFollowerOptions{
+	MaxReorgDepth: 16,
+}
+
+// BlockFollower maintains a local copy of the canonical chain starting at a
+// given level. It emits apply events for new blocks in order and rollback
+// events in reverse order for blocks that were reorganized out of the chain.
+type BlockFollower struct {
+	c      *Client
+	opts   BlockFollowerOptions
+	mu     sync.Mutex
+	cursor BlockCursor
+	next   int64
+	err    error
+}
+
+// NewBlockFollower creates a follower that starts at block level start.
+func NewBlockFollower(c *Client, start int64, opts BlockFollowerOptions) *BlockFollower {
+	if opts.MaxReorgDepth <= 0 {
+		opts.MaxReorgDepth = DefaultBlockFollowerOptions.MaxReorgDepth
+	}
+	return &BlockFollower{
+		c:      c,
+		opts:   opts,
+		cursor: make(BlockCursor, 0, opts.MaxReorgDepth+1),
+		next:   start,
+	}
+}
+
+// ResumeBlockFollower creates a follower that continues after the tip of a
+// previously persisted cursor. Blocks in the cursor that are no longer
+// canonical are rolled back first.
+func ResumeBlockFollower(c *Client, cursor BlockCursor, opts BlockFollowerOptions) (*BlockFollower, error) {
+	tip, ok := cursor.Tip()
+	if !ok {
+		return nil, fmt.Errorf("rpc: empty block cursor")
+	}
+	f := NewBlockFollower(c, tip.Height+1, opts)
+	f.cursor = append(f.cursor, cursor.Clone()...)
+	f.trim()
+	return f, nil
+}
+
+// Cursor returns a copy of the follower's current state.
+func (f *BlockFollower) Cursor() BlockCursor {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.cursor.Clone()
+}
+
+// Err returns the error that stopped the follower, if any.
+func (f *BlockFollower) Err() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.err
+}
+
+// Run starts following the chain and returns a channel of block events. The
+// channel is closed when ctx is canceled or a reorganization exceeds the max
+// depth, see Err. Connection errors are retried in the background.
+func (f *BlockFollower) Run(ctx context.Context) <-chan BlockEvent {
+	ch := make(chan BlockEvent)
+	go func() {
+		defer close(ch)
+		if err := f.run(ctx, ch); err != nil && ctx.Err() == nil {
+			f.mu.Lock()
+			f.err = err
+			f.mu.Unlock()
+		}
+	}()
+	return ch
+}
+
+func (f *BlockFollower) run(ctx context.Context, ch chan<- BlockEvent) error {
+	var mon *BlockHeaderMonitor
+	defer func() {
+		if mon != nil {
+			mon.Close()
+		}
+	}()
+	poll := f.opts.Poll
+	interval := f.opts.PollInterval
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		// (re)connect
+		if mon == nil && !poll {
+			mon = NewBlockHeaderMonitor()
+			if err := f.c.MonitorBlockHeader(ctx, mon); err != nil {
+				mon.Close()
+				mon = nil
+				if ErrorStatus(err) == 404 {
+					f.c.debugf("rpc: block follower: head monitor unsupported, polling")
+					poll = true
+				} else {
+					f.c.debugf("rpc: block follower: head monitor: %v", err)
+					if !sleep(ctx, 5*time.Second) {
+						return ctx.Err()
+					}
+				}
+				continue
+			}
+		}
+
+		var head BlockRef
+		if poll {
+			h, err := f.c.GetTipHeader(ctx)
+			if err != nil {
+				if !sleep(ctx, 5*time.Second) {
+					return ctx.Err()
+				}
+				continue
+			}
+			head = BlockRef{Height: h.Level, Hash: h.Hash}
+		} else {
+			h, err := mon.Recv(ctx)
+			if err != nil {
+				f.c.debugf("rpc: block follower: reconnecting head monitor: %v", err)
+				mon.Close()
+				mon = nil
+				continue
+			}
+			head = BlockRef{Height: h.Level, Hash: h.Hash}
+		}
+
+		if err := f.sync(ctx, head, ch); err != nil {
+			if err == ErrReorgTooDeep || ctx.Err() != nil {
+				return err
+			}
+			f.c.debugf("rpc: block follower: %v", err)
+			if !sleep(ctx, 5*time.Second) {
+				return ctx.Err()
+			}
+			continue
+		}
+
+		if poll {
+			if interval <= 0 {
+				interval = tezos.DefaultParams.MinimalBlockDelay / 2
+				if p, err := f.c.Params(ctx); err == nil && p.MinimalBlockDelay > 0 {
+					interval = p.MinimalBlockDelay / 2
+				}
+			}
+			if !sleep(ctx, interval) {
+				return ctx.Err()
+			}
+		}
+	}
+}
+
+// sync brings the local chain in line with the node's chain ending at head.
+func (f *BlockFollower) sync(ctx context.Context, head BlockRef, ch chan<- BlockEvent) error {
+	var depth int
+
+	// roll back blocks at or above a head that replaced them
+	ref := head
+	tip, ok := f.tip()
+	check := ok && tip.Height >= head.Height && !(tip.Height == head.Height && tip.Hash.Equal(head.Hash))
+	for {
+		if check {
+			// roll back blocks that are not ancestors of ref
+			removed, _, err := f.c.orphanedBlocks(ctx, f.Cursor(), ref)
+			if err != nil {
+				return err
+			}
+			if len(removed) == 0 {
+				// the node switched branches while we were syncing
+				return fmt.Errorf("rpc: block follower: chain changed at height %d", ref.Height+1)
+			}
+			if depth += len(removed); depth > f.opts.MaxReorgDepth {
+				return ErrReorgTooDeep
+			}
+			for _, v := range removed {
+				if err := f.rollback(ctx, v, ch); err != nil {
+					return err
+				}
+			}
+			check = false
+		}
+
+		// apply missing blocks until the predecessor does not match
+		next := f.nextHeight()
+		if next > head.Height {
+			return nil
+		}
+		var (
+			b   *Block
+			err error
+		)
+		if next == head.Height {
+			b, err = f.c.GetBlock(ctx, head.Hash)
+		} else {
+			b, err = f.c.GetBlockHeight(ctx, next)
+		}
+		if err != nil {
+			return err
+		}
+		if tip, ok := f.tip(); ok && !b.Header.Predecessor.Equal(tip.Hash) {
+			ref = BlockRef{Height: b.Header.Level - 1, Hash: b.Header.Predecessor}
+			check = true
+			continue
+		}
+		f.mu.Lock()
+		f.cursor = append(f.cursor, BlockRef{Height: b.Header.Level, Hash: b.Hash.Clone()})
+		f.next = b.Header.Level + 1
+		f.trim()
+		cursor := f.cursor.Clone()
+		f.mu.Unlock()
+		if err := f.send(ctx, ch, BlockEvent{Type: BlockApply, Block: b, Cursor: cursor}); err != nil {
+			return err
+		}
+	}
+}
+
+func (f *BlockFollower) rollback(ctx context.Context, tip BlockRef, ch chan<- BlockEvent) error {
+	b, err := f.c.GetBlock(ctx, tip.Hash)
+	if err != nil {
+		// the node may no longer know the orphaned block
+		b = &Block{Hash: tip.Hash.Clone()}
+		b.Header.Level = tip.Height
+	}
+	f.mu.Lock()
+	f.cursor = f.cursor[:len(f.cursor)-1]
+	f.next = tip.Height
+	cursor := f.cursor.Clone()
+	f.mu.Unlock()
+	return f.send(ctx, ch, BlockEvent{Type: BlockRollback, Block: b, Cursor: cursor})
+}
+
+func (f *BlockFollower) send(ctx context.Context, ch chan<- BlockEvent, ev BlockEvent) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case ch <- ev:
+		return nil
+	}
+}
+
+func (f *BlockFollower) tip() (BlockRef, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.cursor.Tip()
+}
+
+func (f *BlockFollower) nextHeight() int64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.next
+}
+
+// trim drops cursor entries beyond the max reorg depth. Callers must hold mu.
+func (f *BlockFollower) trim() {
+	if n := len(f.cursor) - f.opts.MaxReorgDepth - 1; n > 0 {
+		f.cursor = append(f.cursor[:0], f.cursor[n:]...)
+	}
+}
+
+func sleep(ctx context.Context, d time.Duration) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(d):
+		return true
+	}
+}
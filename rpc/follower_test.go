@@ -0,0 +1,123 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func newTestFollower(t *testing.T, m *Mock, start int64, depth int) *BlockFollower {
+	t.Helper()
+	c, err := m.Client()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewBlockFollower(c, start, BlockFollowerOptions{MaxReorgDepth: depth})
+}
+
+// drainBlocks returns the types and hashes of all queued events.
+func drainBlocks(ch chan BlockEvent) []string {
+	list := make([]string, 0)
+	for {
+		select {
+		case ev := <-ch:
+			list = append(list, fmt.Sprintf("%s %s", ev.Type, ev.Block.Hash))
+		default:
+			return list
+		}
+	}
+}
+
+func TestBlockFollowerRollback(t *testing.T) {
+	m := NewMock()
+	testChainAt(m, 1, 12, 0, 0, true)
+	f := newTestFollower(t, m, 1, 5)
+	ch := make(chan BlockEvent, 100)
+	ctx := context.Background()
+
+	// follow the old branch up to 12
+	if err := f.sync(ctx, BlockRef{Height: 12, Hash: testBlockHash(12, 0)}, ch); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(drainBlocks(ch)); n != 12 {
+		t.Fatalf("expected 12 applied blocks, got %d", n)
+	}
+
+	// switch to the new branch forked at 11
+	testChainAt(m, 11, 13, 1, 0, true)
+	if err := f.sync(ctx, BlockRef{Height: 13, Hash: testBlockHash(13, 1)}, ch); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"rollback " + testBlockHash(12, 0).String(),
+		"rollback " + testBlockHash(11, 0).String(),
+		"apply " + testBlockHash(11, 1).String(),
+		"apply " + testBlockHash(12, 1).String(),
+		"apply " + testBlockHash(13, 1).String(),
+	}
+	events := drainBlocks(ch)
+	if len(events) != len(want) {
+		t.Fatalf("unexpected events %v", events)
+	}
+	for i := range want {
+		if events[i] != want[i] {
+			t.Errorf("event %d: got %s want %s", i, events[i], want[i])
+		}
+	}
+	tip, _ := f.Cursor().Tip()
+	if tip.Height != 13 || !tip.Hash.Equal(testBlockHash(13, 1)) {
+		t.Errorf("unexpected tip %v", tip)
+	}
+	if n := len(f.Cursor()); n != 6 {
+		t.Errorf("cursor not trimmed to max depth, len=%d", n)
+	}
+}
+
+func TestBlockFollowerRollbackSameHeight(t *testing.T) {
+	m := NewMock()
+	testChainAt(m, 1, 12, 0, 0, true)
+	f := newTestFollower(t, m, 10, 5)
+	ch := make(chan BlockEvent, 100)
+	ctx := context.Background()
+
+	if err := f.sync(ctx, BlockRef{Height: 12, Hash: testBlockHash(12, 0)}, ch); err != nil {
+		t.Fatal(err)
+	}
+	drainBlocks(ch)
+
+	// competing block at 12
+	testChainAt(m, 12, 12, 1, 0, true)
+	if err := f.sync(ctx, BlockRef{Height: 12, Hash: testBlockHash(12, 1)}, ch); err != nil {
+		t.Fatal(err)
+	}
+	events := drainBlocks(ch)
+	want := []string{
+		"rollback " + testBlockHash(12, 0).String(),
+		"apply " + testBlockHash(12, 1).String(),
+	}
+	if fmt.Sprint(events) != fmt.Sprint(want) {
+		t.Errorf("got %v want %v", events, want)
+	}
+}
+
+func TestBlockFollowerReorgTooDeep(t *testing.T) {
+	m := NewMock()
+	testChainAt(m, 1, 12, 0, 0, true)
+	f := newTestFollower(t, m, 1, 3)
+	ch := make(chan BlockEvent, 100)
+	ctx := context.Background()
+
+	if err := f.sync(ctx, BlockRef{Height: 12, Hash: testBlockHash(12, 0)}, ch); err != nil {
+		t.Fatal(err)
+	}
+	drainBlocks(ch)
+
+	// fork at level 6 is below the cursor
+	testChainAt(m, 6, 13, 1, 0, true)
+	if err := f.sync(ctx, BlockRef{Height: 13, Hash: testBlockHash(13, 1)}, ch); err != ErrReorgTooDeep {
+		t.Fatalf("expected ErrReorgTooDeep, got %v", err)
+	}
+}
@@ -4,6 +4,9 @@
 package rpc
 
 import (
+	"context"
+	"fmt"
+
 	"blockwatch.cc/tzgo/micheline"
 	"blockwatch.cc/tzgo/tezos"
 )
@@ -28,15 +31,41 @@ func (c ConstantRegistration) Result() OperationResult {
 	return c.Metadata.Result
 }
 
+// GlobalAddress returns the expression hash of the registered constant.
+// It is empty when registration failed.
+func (c ConstantRegistration) GlobalAddress() tezos.ExprHash {
+	return c.Metadata.Result.GlobalAddress
+}
+
 // Costs returns operation cost to implement TypedOperation interface.
 func (c ConstantRegistration) Costs() tezos.Costs {
 	res := c.Metadata.Result
-	burn := res.BalanceUpdates[0].Amount()
+	burn := res.BalanceUpdates.ContractDebits()
 	return tezos.Costs{
 		Fee:         c.Manager.Fee,
 		GasUsed:     res.ConsumedGas,
-		Burn:        -burn,
+		Burn:        burn,
 		StorageUsed: res.StorageSize,
-		StorageBurn: -burn,
+		StorageBurn: burn,
+	}
+}
+
+// GetGlobalConstant returns the definition of a registered global constant.
+// Since constants are immutable once registered, the current head is queried.
+func (c *Client) GetGlobalConstant(ctx context.Context, hash tezos.ExprHash) (micheline.Prim, error) {
+	u := fmt.Sprintf("chains/main/blocks/head/context/global_constants/%s", hash)
+	prim := micheline.Prim{}
+	err := c.Get(ctx, u, &prim)
+	if err != nil {
+		return micheline.InvalidPrim, err
+	}
+	return prim, nil
+}
+
+// ResolveGlobalConstant implements micheline.ConstantResolver and can be used to
+// expand scripts that reference global constants.
+func (c *Client) ResolveGlobalConstant(ctx context.Context) micheline.ConstantResolver {
+	return func(hash tezos.ExprHash) (micheline.Prim, error) {
+		return c.GetGlobalConstant(ctx, hash)
 	}
 }
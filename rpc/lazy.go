@@ -8,6 +8,7 @@ import (
 	"fmt"
 
 	"blockwatch.cc/tzgo/micheline"
+	"blockwatch.cc/tzgo/tezos"
 )
 
 type LazyDiffKind string
@@ -113,7 +114,92 @@ loop:
 
 type LazyBigMapDiff struct {
 	GenericDiff
-	Diff micheline.BigmapDiffElem `json:"diff"`
+	Diff    micheline.BigmapDiffElem `json:"diff"`
+	Updates micheline.BigmapDiff     `json:"-"`
+}
+
+type lazyBigMapUpdate struct {
+	KeyHash tezos.ExprHash  `json:"key_hash"`
+	Key     micheline.Prim  `json:"key"`
+	Value   *micheline.Prim `json:"value,omitempty"`
+}
+
+func (d *LazyBigMapDiff) UnmarshalJSON(data []byte) error {
+	var val struct {
+		GenericDiff
+		Diff struct {
+			Action    micheline.DiffAction `json:"action"`
+			Source    int64                `json:"source,string"`     // copy
+			KeyType   micheline.Prim       `json:"key_type"`          // alloc
+			ValueType micheline.Prim       `json:"value_type"`        // alloc
+			Updates   []lazyBigMapUpdate   `json:"updates,omitempty"` // alloc, copy, update
+		} `json:"diff"`
+	}
+	if err := json.Unmarshal(data, &val); err != nil {
+		return err
+	}
+	d.GenericDiff = val.GenericDiff
+	d.Diff = micheline.BigmapDiffElem{
+		Action:    val.Diff.Action,
+		Id:        val.DiffId,
+		KeyType:   val.Diff.KeyType,
+		ValueType: val.Diff.ValueType,
+	}
+	if val.Diff.Action == micheline.DiffActionCopy {
+		d.Diff.SourceId = val.Diff.Source
+		d.Diff.DestId = val.DiffId
+	}
+	d.Updates = make(micheline.BigmapDiff, len(val.Diff.Updates))
+	for i, u := range val.Diff.Updates {
+		elem := micheline.BigmapDiffElem{
+			Action:  micheline.DiffActionUpdate,
+			Id:      val.DiffId,
+			KeyHash: u.KeyHash,
+			Key:     u.Key,
+		}
+		if u.Value != nil {
+			elem.Value = *u.Value
+		} else {
+			elem.Action = micheline.DiffActionRemove
+		}
+		d.Updates[i] = elem
+	}
+	return nil
+}
+
+// BigmapDiff converts the lazy diff into the legacy big_map_diff form. Alloc
+// and copy actions are followed by their updates, a remove action without key
+// denotes removal of the entire bigmap.
+func (d *LazyBigMapDiff) BigmapDiff() micheline.BigmapDiff {
+	diff := make(micheline.BigmapDiff, 0, len(d.Updates)+1)
+	switch d.Diff.Action {
+	case micheline.DiffActionAlloc, micheline.DiffActionCopy:
+		diff = append(diff, d.Diff)
+	case micheline.DiffActionRemove:
+		elem := d.Diff
+		elem.Key = micheline.Prim{
+			Type:   micheline.PrimNullary,
+			OpCode: micheline.I_EMPTY_BIG_MAP,
+		}
+		diff = append(diff, elem)
+	}
+	return append(diff, d.Updates...)
+}
+
+// BigmapUpdates returns all bigmap updates contained in the result. Lazy storage
+// diffs (v008+) are converted into the legacy big_map_diff form. When a result
+// contains both forms the lazy diff is used.
+func (r OperationResult) BigmapUpdates() micheline.BigmapDiff {
+	if len(r.LazyStorageDiff) == 0 {
+		return r.BigmapDiff
+	}
+	diff := make(micheline.BigmapDiff, 0)
+	for _, v := range r.LazyStorageDiff {
+		if d, ok := v.(*LazyBigMapDiff); ok {
+			diff = append(diff, d.BigmapDiff()...)
+		}
+	}
+	return diff
 }
 
 type LazySaplingDiff struct {
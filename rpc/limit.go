@@ -0,0 +1,310 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ClientOptions configures request throttling and response size limits. Zero
+// values disable the respective limit unless noted otherwise.
+type ClientOptions struct {
+	// Max average number of requests sent per second.
+	RequestsPerSecond float64
+	// Max number of requests sent at once before throttling kicks in.
+	// Defaults to RequestsPerSecond rounded up.
+	Burst int
+	// Max number of requests in flight. A request occupies its slot until the
+	// response body is closed, streaming monitors release it once connected.
+	MaxConcurrency int
+	// Custom limiter, e.g. a golang.org/x/time/rate.Limiter shared between
+	// clients. Replaces the built-in limiter when set.
+	Limiter Limiter
+	// Max size of a response body. Larger responses fail with
+	// ErrResponseTooLarge. Streaming list calls apply it per element, it does
+	// not apply to streaming monitors. Zero keeps the limit set with
+	// WithMaxResponseBytes.
+	MaxResponseBytes int64
+}
+
+// Limiter gates outgoing requests. Wait blocks until a request may be sent or
+// ctx is canceled. It is satisfied by golang.org/x/time/rate.Limiter.
+type Limiter interface {
+	Wait(ctx context.Context) error
+}
+
+// WithOptions applies throttling options to the client. When a limit is hit
+// requests wait until they may proceed or their context is canceled. Call
+// before the client is used concurrently.
+func (c *Client) WithOptions(opts ClientOptions) *Client {
+	c.limiter = nil
+	switch {
+	case opts.Limiter != nil:
+		c.limiter = opts.Limiter
+	case opts.RequestsPerSecond > 0:
+		c.limiter = newRateLimiter(opts.RequestsPerSecond, opts.Burst)
+	}
+	c.inflight = nil
+	if opts.MaxConcurrency > 0 {
+		c.inflight = make(chan struct{}, opts.MaxConcurrency)
+	}
+	if opts.MaxResponseBytes > 0 {
+		c.maxResponseBytes = opts.MaxResponseBytes
+	}
+	return c
+}
+
+// WithLimiter gates all requests including monitor reconnects through l.
+// Call before the client is used concurrently.
+func (c *Client) WithLimiter(l Limiter) *Client {
+	c.limiter = l
+	return c
+}
+
+// acquire blocks until req may be sent and returns a func that releases its
+// in-flight slot. Requests also wait while the node asked to back off.
+func (c *Client) acquire(req *http.Request) (func(), error) {
+	ctx := req.Context()
+	if err := c.waitBackoff(ctx); err != nil {
+		return nil, err
+	}
+	if c.logger != nil && (c.inflight != nil || c.limiter != nil) {
+		start := time.Now()
+		defer func() {
+			if d := time.Since(start); d >= time.Millisecond {
+				c.logger.Debugf("rpc: %s %s throttled for %s", req.Method, req.URL.Path, d)
+			}
+		}()
+	}
+	if c.inflight != nil {
+		select {
+		case c.inflight <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	release := func() {
+		if c.inflight != nil {
+			<-c.inflight
+		}
+	}
+	if c.limiter != nil {
+		if err := c.limiter.Wait(ctx); err != nil {
+			release()
+			return nil, err
+		}
+	}
+	return release, nil
+}
+
+// waitBackoff blocks until a pause requested by a 429 response has passed.
+func (c *Client) waitBackoff(ctx context.Context) error {
+	c.mu.Lock()
+	until := c.backoffUntil
+	c.mu.Unlock()
+	wait := time.Until(until)
+	if wait <= 0 {
+		return nil
+	}
+	t := time.NewTimer(wait)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// handleTooManyRequests pauses all requests for the duration the node asks for
+// in a 429 response's Retry-After header, one second when missing.
+func (c *Client) handleTooManyRequests(resp *http.Response) {
+	wait := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+	if wait <= 0 {
+		wait = time.Second
+	}
+	until := time.Now().Add(wait)
+	c.mu.Lock()
+	if until.After(c.backoffUntil) {
+		c.backoffUntil = until
+	}
+	c.mu.Unlock()
+	if c.logger != nil {
+		c.logger.Warnf("rpc: rate limited by node, pausing requests for %s", wait)
+	}
+}
+
+// parseRetryAfter decodes a Retry-After value in seconds or as HTTP date.
+func parseRetryAfter(val string, now time.Time) time.Duration {
+	if val == "" {
+		return 0
+	}
+	if sec, err := strconv.ParseInt(val, 10, 64); err == nil {
+		return time.Duration(sec) * time.Second
+	}
+	if t, err := http.ParseTime(val); err == nil {
+		return t.Sub(now)
+	}
+	return 0
+}
+
+// rateLimiter is a token bucket that refills at rate tokens per second up to
+// burst tokens.
+type rateLimiter struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+	if burst <= 0 {
+		burst = int(math.Ceil(rate))
+	}
+	return &rateLimiter{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// Wait reserves a token and blocks until it becomes available. On context
+// cancellation the reservation is returned to the bucket.
+func (l *rateLimiter) Wait(ctx context.Context) error {
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens = math.Min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	l.last = now
+	l.tokens--
+	if l.tokens >= 0 {
+		l.mu.Unlock()
+		return nil
+	}
+	wait := time.Duration(-l.tokens / l.rate * float64(time.Second))
+	l.mu.Unlock()
+
+	t := time.NewTimer(wait)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		l.tokens++
+		l.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// limitedBody releases an in-flight slot when the response body is closed.
+type limitedBody struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+func (b *limitedBody) done() {
+	b.once.Do(b.release)
+}
+
+func (b *limitedBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.done()
+	return err
+}
+
+// ErrResponseTooLarge is returned when a response body exceeds the client's
+// MaxResponseBytes limit.
+var ErrResponseTooLarge = errors.New("rpc: response too large")
+
+// WithMaxResponseBytes limits the size of response bodies to protect against
+// huge or hostile responses from untrusted nodes. Zero disables the limit.
+// Call before the client is used concurrently.
+func (c *Client) WithMaxResponseBytes(n int64) *Client {
+	c.maxResponseBytes = n
+	return c
+}
+
+// limitResponse wraps the response body so reads fail with ErrResponseTooLarge
+// once more than maxResponseBytes were read. Responses announcing a larger
+// content length fail immediately.
+func (c *Client) limitResponse(resp *http.Response) error {
+	max := c.maxResponseBytes
+	if max <= 0 {
+		return nil
+	}
+	if resp.ContentLength > max {
+		return fmt.Errorf("%w: %s %s content length %d exceeds %d bytes",
+			ErrResponseTooLarge, resp.Request.Method, resp.Request.URL.Path, resp.ContentLength, max)
+	}
+	resp.Body = &maxBytesBody{ReadCloser: resp.Body, n: max, max: max}
+	return nil
+}
+
+type maxBytesBody struct {
+	io.ReadCloser
+	n   int64 // remaining
+	max int64
+	err error
+}
+
+func (b *maxBytesBody) Read(p []byte) (int, error) {
+	if b.err != nil {
+		return 0, b.err
+	}
+	// read one byte more than allowed to detect oversized bodies
+	if int64(len(p)) > b.n+1 {
+		p = p[:b.n+1]
+	}
+	n, err := b.ReadCloser.Read(p)
+	if int64(n) > b.n {
+		n = int(b.n)
+		b.n = 0
+		b.err = fmt.Errorf("%w: limit is %d bytes", ErrResponseTooLarge, b.max)
+		return n, b.err
+	}
+	b.n -= int64(n)
+	return n, err
+}
+
+// elementLimitReader limits the size of each element in a streamed JSON
+// array instead of the entire response. Call next with the decoder's input
+// offset before decoding an element.
+type elementLimitReader struct {
+	r     io.Reader
+	read  int64 // total bytes read
+	limit int64 // offset at which reading fails
+	max   int64
+}
+
+func newElementLimitReader(r io.Reader, max int64) *elementLimitReader {
+	return &elementLimitReader{r: r, limit: max, max: max}
+}
+
+func (l *elementLimitReader) next(offset int64) {
+	l.limit = offset + l.max
+}
+
+func (l *elementLimitReader) Read(p []byte) (int, error) {
+	rem := l.limit - l.read
+	if rem <= 0 {
+		return 0, fmt.Errorf("%w: array element exceeds %d bytes", ErrResponseTooLarge, l.max)
+	}
+	if int64(len(p)) > rem {
+		p = p[:rem]
+	}
+	n, err := l.r.Read(p)
+	l.read += int64(n)
+	return n, err
+}
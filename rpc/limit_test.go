@@ -0,0 +1,74 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package rpc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"blockwatch.cc/tzgo/micheline"
+	"blockwatch.cc/tzgo/tezos"
+)
+
+func TestWithOptionsKeepsMaxResponseBytes(t *testing.T) {
+	m := NewMock()
+	m.On(http.MethodGet, "chains/main/blocks/head/hash", "BLockGenesisGenesisGenesisGenesisGenesisf79b5d1CoW2")
+	c, err := m.Client()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.WithMaxResponseBytes(16).WithOptions(ClientOptions{MaxConcurrency: 2})
+	if _, err := c.GetBlockHash(context.Background(), Head); !errors.Is(err, ErrResponseTooLarge) {
+		t.Errorf("expected ErrResponseTooLarge, got %v", err)
+	}
+	c.WithOptions(ClientOptions{MaxResponseBytes: 1024})
+	if _, err := c.GetBlockHash(context.Background(), Head); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestStreamLimitPerElement(t *testing.T) {
+	addrs := make([]tezos.Address, 100)
+	for i := range addrs {
+		h := make([]byte, 20)
+		h[0] = byte(i)
+		addrs[i] = tezos.NewAddress(tezos.AddressTypeEd25519, h)
+	}
+	big := micheline.NewString(strings.Repeat("x", 256))
+	m := NewMock()
+	m.On(http.MethodGet, "chains/main/blocks/head/context/contracts", addrs)
+	m.On(http.MethodGet, "chains/main/blocks/head/context/big_maps/1", []micheline.Prim{
+		micheline.NewInt64(1),
+		big,
+	})
+	c, err := m.Client()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.WithMaxResponseBytes(128)
+	ctx := context.Background()
+
+	// the response exceeds the limit, but each element fits
+	list, err := c.ListContracts(ctx, Head)
+	if err != nil || len(list) != len(addrs) {
+		t.Fatalf("ListContracts: n=%d err=%v", len(list), err)
+	}
+	for i, a := range list {
+		if !a.Equal(addrs[i]) {
+			t.Errorf("ListContracts %d: got %s want %s", i, a, addrs[i])
+		}
+	}
+
+	var n int
+	err = c.GetBigmapValuesStream(ctx, 1, Head, func(micheline.Prim) error {
+		n++
+		return nil
+	})
+	if !errors.Is(err, ErrResponseTooLarge) || n != 1 {
+		t.Errorf("GetBigmapValuesStream: n=%d err=%v", n, err)
+	}
+}
@@ -0,0 +1,113 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package rpc
+
+import (
+	"context"
+	"fmt"
+
+	"blockwatch.cc/tzgo/micheline"
+	"blockwatch.cc/tzgo/tezos"
+)
+
+// LiquidityBakingInfo contains the state of the liquidity baking CPMM contract.
+type LiquidityBakingInfo struct {
+	Address      tezos.Address // CPMM contract address
+	TokenPool    tezos.Z       // tzBTC pool in sats (8 decimals)
+	XtzPool      tezos.Mutez   // tez pool in mutez (6 decimals)
+	LqtTotal     tezos.Z       // total supply of liquidity tokens
+	TokenAddress tezos.Address // tzBTC token contract
+	LqtAddress   tezos.Address // liquidity token contract
+}
+
+// Price returns the implied price of one tzBTC in tez.
+func (i LiquidityBakingInfo) Price() float64 {
+	tok := float64(i.TokenPool.Int64()) / 1e8
+	if tok == 0 {
+		return 0
+	}
+	return i.XtzPool.Float64() / tok
+}
+
+// GetLiquidityBakingCPMMAddress returns the address of the liquidity baking CPMM
+// contract at block id.
+func (c *Client) GetLiquidityBakingCPMMAddress(ctx context.Context, id BlockID) (tezos.Address, error) {
+	u := fmt.Sprintf("chains/main/blocks/%s/context/liquidity_baking/cpmm_address", id)
+	var addr tezos.Address
+	if err := c.Get(ctx, u, &addr); err != nil {
+		return tezos.InvalidAddress, err
+	}
+	return addr, nil
+}
+
+// GetLiquidityBakingInfo returns the liquidity baking CPMM state at block id.
+func (c *Client) GetLiquidityBakingInfo(ctx context.Context, id BlockID) (*LiquidityBakingInfo, error) {
+	addr, err := c.GetLiquidityBakingCPMMAddress(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	store, err := c.GetContractStorage(ctx, addr, id)
+	if err != nil {
+		return nil, err
+	}
+	info, err := ParseLiquidityBakingStorage(store)
+	if err != nil {
+		return nil, err
+	}
+	info.Address = addr
+	return info, nil
+}
+
+// ParseLiquidityBakingStorage decodes CPMM storage of type
+// (pair nat mutez nat address address) in readable or optimized form.
+func ParseLiquidityBakingStorage(store micheline.Prim) (*LiquidityBakingInfo, error) {
+	args := flattenPair(store, nil)
+	if len(args) != 5 {
+		return nil, fmt.Errorf("rpc: unexpected liquidity baking storage with %d fields", len(args))
+	}
+	for _, v := range args[:3] {
+		if v.Type != micheline.PrimInt {
+			return nil, fmt.Errorf("rpc: unexpected liquidity baking storage type %s", v.Type)
+		}
+	}
+	info := &LiquidityBakingInfo{}
+	info.TokenPool.Set(args[0].Int)
+	if !args[1].Int.IsInt64() {
+		return nil, tezos.ErrMutezOverflow
+	}
+	info.XtzPool = tezos.Mutez(args[1].Int.Int64())
+	info.LqtTotal.Set(args[2].Int)
+	var err error
+	if info.TokenAddress, err = primAddress(args[3]); err != nil {
+		return nil, err
+	}
+	if info.LqtAddress, err = primAddress(args[4]); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// flattenPair returns the leaves of a (right-)comb pair.
+func flattenPair(p micheline.Prim, args []micheline.Prim) []micheline.Prim {
+	if p.OpCode != micheline.D_PAIR || len(p.Args) == 0 {
+		return append(args, p)
+	}
+	for _, v := range p.Args {
+		args = flattenPair(v, args)
+	}
+	return args
+}
+
+func primAddress(p micheline.Prim) (tezos.Address, error) {
+	switch p.Type {
+	case micheline.PrimString:
+		return tezos.ParseAddress(p.String)
+	case micheline.PrimBytes:
+		var a tezos.Address
+		err := a.UnmarshalBinary(p.Bytes)
+		return a, err
+	default:
+		return tezos.InvalidAddress, fmt.Errorf("rpc: unexpected address type %s", p.Type)
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package rpc
+
+import (
+	"context"
+	"math/big"
+	"net/http"
+	"testing"
+
+	"blockwatch.cc/tzgo/micheline"
+	"blockwatch.cc/tzgo/tezos"
+)
+
+var (
+	testCPMM  = tezos.MustParseAddress("KT1TxqZ8QtKvLu3V3JH7Gx58n7Co8pgtpQU5")
+	testTzBTC = tezos.MustParseAddress("KT1PWx2mnDueood7fEmfbBDKx1D9BAnnXitn")
+	testLqt   = tezos.MustParseAddress("KT1AafHA1C1vk959wvHWBispY9Y2f3fxBUUo")
+)
+
+func testLbStorage(tok, xtz, lqt, tokAddr, lqtAddr micheline.Prim) micheline.Prim {
+	return micheline.NewPair(tok, micheline.NewPair(xtz, micheline.NewPair(lqt, micheline.NewPair(tokAddr, lqtAddr))))
+}
+
+func TestParseLiquidityBakingStorage(t *testing.T) {
+	var (
+		tok     = micheline.NewInt64(30000000000)
+		xtz     = micheline.NewInt64(600000000000)
+		lqt     = micheline.NewInt64(5000000)
+		tokAddr = micheline.NewString(testTzBTC.String())
+		lqtAddr = micheline.NewString(testLqt.String())
+		tokBin  = micheline.NewBytes(testTzBTC.Bytes22())
+		lqtBin  = micheline.NewBytes(testLqt.Bytes22())
+	)
+	overflow := new(big.Int).Lsh(big.NewInt(1), 63)
+	for _, test := range []struct {
+		name  string
+		store micheline.Prim
+		error bool
+	}{
+		{name: "readable", store: testLbStorage(tok, xtz, lqt, tokAddr, lqtAddr)},
+		{name: "optimized", store: testLbStorage(tok, xtz, lqt, tokBin, lqtBin)},
+		{name: "flat comb", store: micheline.NewCode(micheline.D_PAIR, tok, xtz, lqt, tokBin, lqtAddr)},
+		{name: "left comb", store: micheline.NewPair(micheline.NewPair(micheline.NewPair(tok, xtz), micheline.NewPair(lqt, tokAddr)), lqtAddr)},
+		{name: "too few fields", store: micheline.NewCode(micheline.D_PAIR, tok, xtz, lqt, tokAddr), error: true},
+		{name: "too many fields", store: micheline.NewCode(micheline.D_PAIR, tok, xtz, lqt, tokAddr, lqtAddr, lqt), error: true},
+		{name: "string pool", store: testLbStorage(micheline.NewString("1"), xtz, lqt, tokAddr, lqtAddr), error: true},
+		{name: "mutez overflow", store: testLbStorage(tok, micheline.NewBig(overflow), lqt, tokAddr, lqtAddr), error: true},
+		{name: "int address", store: testLbStorage(tok, xtz, lqt, micheline.NewInt64(1), lqtAddr), error: true},
+		{name: "bad address", store: testLbStorage(tok, xtz, lqt, tokAddr, micheline.NewString("KT1")), error: true},
+		{name: "not a pair", store: tok, error: true},
+	} {
+		info, err := ParseLiquidityBakingStorage(test.store)
+		if test.error {
+			if err == nil {
+				t.Errorf("%s: expected error", test.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if info.TokenPool.Int64() != 30000000000 || info.XtzPool != 600000000000 || info.LqtTotal.Int64() != 5000000 {
+			t.Errorf("%s: unexpected pools %+v", test.name, info)
+		}
+		if !info.TokenAddress.Equal(testTzBTC) || !info.LqtAddress.Equal(testLqt) {
+			t.Errorf("%s: unexpected addresses %s %s", test.name, info.TokenAddress, info.LqtAddress)
+		}
+	}
+}
+
+func TestLiquidityBakingPrice(t *testing.T) {
+	for _, test := range []struct {
+		tok  int64
+		xtz  tezos.Mutez
+		want float64
+	}{
+		{tok: 100000000, xtz: 20000000000, want: 20000},
+		{tok: 50000000, xtz: 1000000, want: 2},
+		{tok: 0, xtz: 1000000, want: 0},
+	} {
+		info := LiquidityBakingInfo{XtzPool: test.xtz}
+		info.TokenPool.SetInt64(test.tok)
+		if got := info.Price(); got != test.want {
+			t.Errorf("%d/%d: price got %f want %f", test.xtz, test.tok, got, test.want)
+		}
+	}
+}
+
+func TestGetLiquidityBakingInfo(t *testing.T) {
+	m := NewMock()
+	m.On(http.MethodGet, "chains/main/blocks/head/context/liquidity_baking/cpmm_address", testCPMM)
+	m.On(http.MethodGet, "chains/main/blocks/head/context/contracts/"+testCPMM.String()+"/storage",
+		testLbStorage(micheline.NewInt64(100000000), micheline.NewInt64(20000000000), micheline.NewInt64(1),
+			micheline.NewBytes(testTzBTC.Bytes22()), micheline.NewBytes(testLqt.Bytes22())))
+	c, err := m.Client()
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := c.GetLiquidityBakingInfo(context.Background(), Head)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.Address.Equal(testCPMM) || info.Price() != 20000 {
+		t.Errorf("unexpected info %+v", info)
+	}
+}
@@ -3,7 +3,14 @@
 
 package rpc
 
-import logpkg "github.com/echa/log"
+import (
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"time"
+
+	logpkg "github.com/echa/log"
+)
 
 // log is a logger that is initialized with no output filters.  This
 // means the package will not perform any logging by default until the caller
@@ -44,3 +51,57 @@ func (c logClosure) String() string {
 func newLogClosure(c func() string) logClosure {
 	return logClosure(c)
 }
+
+// Logger receives diagnostic messages from a Client. The interface is
+// satisfied by github.com/echa/log loggers and thin wrappers around most
+// structured loggers. Messages never contain request headers or bodies.
+type Logger interface {
+	Debugf(format string, args ...interface{})
+	Warnf(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+}
+
+// WithLogger sets a logger for request, throttling and monitor diagnostics.
+// Without a logger the client does not format any messages. Call before the
+// client is used concurrently.
+func (c *Client) WithLogger(l Logger) *Client {
+	c.logger = l
+	return c
+}
+
+// logRequest logs a completed round trip. Only method and path are logged
+// since query strings and headers may carry credentials.
+func (c *Client) logRequest(req *http.Request, resp *http.Response, dur time.Duration, err error) {
+	if err != nil {
+		c.logger.Debugf("%v (after %s)", err, dur)
+		return
+	}
+	c.logger.Debugf("rpc: %s %s %d in %s", req.Method, req.URL.Path, resp.StatusCode, dur)
+}
+
+// redactedHeaders are replaced in request dumps.
+var redactedHeaders = []string{"Authorization", "X-Api-Key", "Cookie"}
+
+// dumpRequest dumps req for trace logging with credentials removed.
+func dumpRequest(req *http.Request) string {
+	r := req.Clone(req.Context())
+	for _, h := range secretHeaders(req) {
+		if r.Header.Get(h) != "" {
+			r.Header.Set(h, "<redacted>")
+		}
+	}
+	r.URL.User = nil
+	if u, err := url.ParseRequestURI(redactedURI(req)); err == nil {
+		r.URL.RawQuery = u.RawQuery
+	}
+	d, _ := httputil.DumpRequest(r, false)
+	return string(d)
+}
+
+// debugf logs rare events like reconnects. Hot paths check c.logger first to
+// avoid formatting arguments.
+func (c *Client) debugf(format string, args ...interface{}) {
+	if c.logger != nil {
+		c.logger.Debugf(format, args...)
+	}
+}
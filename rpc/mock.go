@@ -0,0 +1,294 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package rpc
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MockResponse is a canned response served by Mock.
+type MockResponse struct {
+	Method string            `json:"method"`
+	Path   string            `json:"path"`
+	Status int               `json:"status"`
+	Body   json.RawMessage   `json:"body,omitempty"`
+	Stream []json.RawMessage `json:"stream,omitempty"` // chunks of a streaming response
+	Delay  time.Duration     `json:"-"`                // latency before the response (and between stream chunks)
+	Err    error             `json:"-"`                // transport error
+}
+
+// WithStatus sets the HTTP status code.
+func (r *MockResponse) WithStatus(code int) *MockResponse {
+	r.Status = code
+	return r
+}
+
+// WithDelay simulates latency before responding.
+func (r *MockResponse) WithDelay(d time.Duration) *MockResponse {
+	r.Delay = d
+	return r
+}
+
+// WithError makes the request fail with a transport error.
+func (r *MockResponse) WithError(err error) *MockResponse {
+	r.Err = err
+	return r
+}
+
+// WithStream turns the response into a stream of JSON chunks as sent by
+// monitor endpoints. Chunks are delivered with the configured delay in between.
+func (r *MockResponse) WithStream(chunks ...interface{}) *MockResponse {
+	for _, v := range chunks {
+		r.Stream = append(r.Stream, mustMarshalMock(v))
+	}
+	return r
+}
+
+// Mock is an http.RoundTripper that serves RPC responses from fixtures keyed by
+// method and request path including query parameters, e.g.
+// "chains/main/blocks/head/header". Because requests are created by a regular
+// Client, all URL construction is exercised as in production.
+//
+//	m := rpc.NewMock()
+//	m.On(http.MethodGet, "chains/main/blocks/head/header", hdr)
+//	c, _ := m.Client()
+type Mock struct {
+	mu       sync.Mutex
+	fixtures map[string]*MockResponse
+	requests []string
+}
+
+// NewMock returns an empty mock.
+func NewMock() *Mock {
+	return &Mock{
+		fixtures: make(map[string]*MockResponse),
+	}
+}
+
+// Client returns a client that sends all requests to the mock.
+func (m *Mock) Client() (*Client, error) {
+	return NewClient("http://mock", &http.Client{Transport: m})
+}
+
+// On registers a response for method and path. Values of type []byte and
+// json.RawMessage are used as raw JSON, others are marshaled.
+func (m *Mock) On(method, path string, v interface{}) *MockResponse {
+	r := &MockResponse{
+		Method: method,
+		Path:   strings.TrimPrefix(path, "/"),
+		Status: http.StatusOK,
+	}
+	if v != nil {
+		r.Body = mustMarshalMock(v)
+	}
+	m.Add(r)
+	return r
+}
+
+// Add registers a response.
+func (m *Mock) Add(r *MockResponse) {
+	if r.Method == "" {
+		r.Method = http.MethodGet
+	}
+	if r.Status == 0 {
+		r.Status = http.StatusOK
+	}
+	m.mu.Lock()
+	m.fixtures[mockKey(r.Method, r.Path)] = r
+	m.mu.Unlock()
+}
+
+// Requests returns all requests seen so far as "METHOD path" strings.
+func (m *Mock) Requests() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string{}, m.requests...)
+}
+
+// Load registers all fixtures stored in dir, e.g. by a Recorder.
+func (m *Mock) Load(dir string) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		buf, err := ioutil.ReadFile(f)
+		if err != nil {
+			return err
+		}
+		r := &MockResponse{}
+		if err := json.Unmarshal(buf, r); err != nil {
+			return fmt.Errorf("rpc: loading fixture %s: %w", f, err)
+		}
+		m.Add(r)
+	}
+	return nil
+}
+
+func (m *Mock) RoundTrip(req *http.Request) (*http.Response, error) {
+	path := requestPath(req)
+	key := mockKey(req.Method, path)
+	m.mu.Lock()
+	m.requests = append(m.requests, key)
+	r, ok := m.fixtures[key]
+	m.mu.Unlock()
+	if !ok {
+		return newMockResponse(req, http.StatusNotFound, []byte("no fixture for "+key)), nil
+	}
+	ctx := req.Context()
+	if r.Delay > 0 {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(r.Delay):
+		}
+	}
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	if r.Stream == nil {
+		return newMockResponse(req, r.Status, r.Body), nil
+	}
+	pr, pw := io.Pipe()
+	go func() {
+		defer pw.Close()
+		for i, chunk := range r.Stream {
+			if i > 0 && r.Delay > 0 {
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(r.Delay):
+				}
+			}
+			if _, err := pw.Write(append(chunk, '\n')); err != nil {
+				return
+			}
+		}
+	}()
+	resp := newMockResponse(req, r.Status, nil)
+	resp.Body = pr
+	return resp, nil
+}
+
+// Recorder is an http.RoundTripper that forwards requests to a real node and
+// stores each response as fixture file in Dir for later replay with Mock.Load.
+// Streaming responses from monitor endpoints are passed through unrecorded.
+type Recorder struct {
+	Dir       string
+	Transport http.RoundTripper // defaults to http.DefaultTransport
+}
+
+// NewRecorder returns a recorder that writes fixtures into dir.
+func NewRecorder(dir string) *Recorder {
+	return &Recorder{Dir: dir}
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	t := r.Transport
+	if t == nil {
+		t = http.DefaultTransport
+	}
+	resp, err := t.RoundTrip(req)
+	if err != nil || isMonitorRequest(req) {
+		return resp, err
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	fix := MockResponse{
+		Method: req.Method,
+		Path:   requestPath(req),
+		Status: resp.StatusCode,
+	}
+	if json.Valid(body) {
+		fix.Body = body
+	} else {
+		fix.Body = mustMarshalMock(string(body))
+	}
+	buf, err := json.MarshalIndent(fix, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	name := filepath.Join(r.Dir, mockFileName(fix.Method, fix.Path))
+	if err := ioutil.WriteFile(name, buf, 0644); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// isMonitorRequest reports whether req targets a streaming endpoint such as
+// monitor/heads/main, chains/main/mempool/monitor_operations or the peer and
+// point logs queried with ?monitor.
+func isMonitorRequest(req *http.Request) bool {
+	path := "/" + strings.TrimPrefix(req.URL.Path, "/")
+	switch {
+	case strings.Contains(path, "/monitor/"),
+		strings.HasSuffix(path, "/monitor_operations"):
+		return true
+	}
+	return req.URL.Query().Has("monitor")
+}
+
+func mockKey(method, path string) string {
+	return method + " " + path
+}
+
+func mockFileName(method, path string) string {
+	h := sha256.Sum256([]byte(mockKey(method, path)))
+	return hex.EncodeToString(h[:8]) + ".json"
+}
+
+// requestPath returns the request path relative to the root without leading
+// slash and including the query string.
+func requestPath(req *http.Request) string {
+	path := strings.TrimPrefix(req.URL.Path, "/")
+	if req.URL.RawQuery != "" {
+		path += "?" + req.URL.RawQuery
+	}
+	return path
+}
+
+func newMockResponse(req *http.Request, status int, body []byte) *http.Response {
+	h := make(http.Header)
+	h.Set("Content-Type", mediaType)
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        h,
+		Body:          ioutil.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+func mustMarshalMock(v interface{}) json.RawMessage {
+	switch val := v.(type) {
+	case json.RawMessage:
+		return val
+	case []byte:
+		return val
+	}
+	buf, err := json.Marshal(v)
+	if err != nil {
+		panic(fmt.Errorf("rpc: marshal mock response: %w", err))
+	}
+	return buf
+}
@@ -0,0 +1,108 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package rpc
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// streamTransport serves never ending streams on monitor paths and forwards
+// all other requests.
+type streamTransport struct {
+	next  http.RoundTripper
+	paths map[string]bool
+}
+
+func (t *streamTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !t.paths[requestPath(req)] {
+		return t.next.RoundTrip(req)
+	}
+	pr, pw := io.Pipe()
+	go func() {
+		pw.Write([]byte("[]\n"))
+		<-req.Context().Done()
+		pw.CloseWithError(req.Context().Err())
+	}()
+	resp := newMockResponse(req, http.StatusOK, nil)
+	resp.Body = pr
+	return resp, nil
+}
+
+func TestRecorderReplay(t *testing.T) {
+	const hash = "BLockGenesisGenesisGenesisGenesisGenesisf79b5d1CoW2"
+	node := NewMock()
+	node.On(http.MethodGet, "chains/main/blocks/head/hash", hash)
+	monitors := []string{
+		"monitor/heads/main",
+		"chains/main/mempool/monitor_operations",
+		"network/peers/idrvyiKHURFhWDbV9VbgBHYrtNcGKm/log?monitor",
+	}
+	tr := &streamTransport{next: node, paths: make(map[string]bool)}
+	for _, p := range monitors {
+		tr.paths[p] = true
+	}
+
+	dir := t.TempDir()
+	rec := NewRecorder(dir)
+	rec.Transport = tr
+	c, err := NewClient("http://node", &http.Client{Transport: rec})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if h, err := c.GetBlockHash(ctx, Head); err != nil || h.String() != hash {
+		t.Fatalf("record: %s %v", h, err)
+	}
+
+	// monitor streams must be passed through without waiting for their end
+	for _, p := range monitors {
+		req, err := c.NewRequest(ctx, http.MethodGet, p, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		done := make(chan error, 1)
+		go func() {
+			resp, err := rec.RoundTrip(req)
+			if err == nil {
+				resp.Body.Close()
+			}
+			done <- err
+		}()
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Errorf("%s: %v", p, err)
+			}
+		case <-ctx.Done():
+			t.Fatalf("%s: recorder blocked on stream", p)
+		}
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("expected one fixture, got %v %v", files, err)
+	}
+	if buf, err := ioutil.ReadFile(files[0]); err != nil || len(buf) == 0 {
+		t.Fatalf("reading fixture: %v", err)
+	}
+
+	m := NewMock()
+	if err := m.Load(dir); err != nil {
+		t.Fatal(err)
+	}
+	c, err = m.Client()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h, err := c.GetBlockHash(ctx, Head); err != nil || h.String() != hash {
+		t.Errorf("replay: %s %v", h, err)
+	}
+}
@@ -25,7 +25,7 @@ type Monitor interface {
 // BootstrappedBlock represents bootstrapped block stream message
 type BootstrappedBlock struct {
 	Block     tezos.BlockHash `json:"block"`
-	Timestamp time.Time       `json:"timestamp"`
+	Timestamp tezos.Timestamp `json:"timestamp"`
 }
 
 type BootstrapMonitor struct {
@@ -107,7 +107,7 @@ type BlockHeaderLogEntry struct {
 	Level          int64                `json:"level"`
 	Proto          int                  `json:"proto"`
 	Predecessor    tezos.BlockHash      `json:"predecessor"`
-	Timestamp      time.Time            `json:"timestamp"`
+	Timestamp      tezos.Timestamp      `json:"timestamp"`
 	ValidationPass int                  `json:"validation_pass"`
 	OperationsHash tezos.OpListListHash `json:"operations_hash"`
 	Fitness        []tezos.HexBytes     `json:"fitness"`
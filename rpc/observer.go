@@ -47,6 +47,7 @@ type Observer struct {
 	minDelay   time.Duration
 	bestHash   tezos.BlockHash
 	bestHeight int64
+	proto      int
 }
 
 func NewObserver() *Observer {
@@ -108,8 +109,8 @@ func (m *Observer) Unsubscribe(id int) {
 func (m *Observer) Listen(cli *Client) {
 	m.once.Do(func() {
 		m.c = cli
-		if m.c.Params != nil {
-			m.minDelay = m.c.Params.MinimalBlockDelay
+		if p := m.c.cachedParams(); p != nil {
+			m.minDelay = p.MinimalBlockDelay
 		}
 		go m.listenBlocks()
 	})
@@ -118,8 +119,8 @@ func (m *Observer) Listen(cli *Client) {
 func (m *Observer) ListenMempool(cli *Client) {
 	m.once.Do(func() {
 		m.c = cli
-		if m.c.Params != nil {
-			m.minDelay = m.c.Params.MinimalBlockDelay
+		if p := m.c.cachedParams(); p != nil {
+			m.minDelay = p.MinimalBlockDelay
 		}
 		go m.listenMempool()
 	})
@@ -154,8 +155,10 @@ func (m *Observer) listenBlocks() {
 				mon = nil
 				if e, ok := err.(HTTPStatus); ok && e.StatusCode() == 404 {
 					fmt.Println("monitor: event mode unsupported, falling back to poll mode.")
+					m.c.debugf("rpc: observer: head monitor unsupported, polling")
 					useEvents = false
 				} else {
+					m.c.debugf("rpc: observer: head monitor: %v", err)
 					// wait 5 sec, but also return on close
 					select {
 					case <-m.ctx.Done():
@@ -170,12 +173,14 @@ func (m *Observer) listenBlocks() {
 		var (
 			headBlock  tezos.BlockHash
 			headHeight int64
+			headProto  int
 		)
 		if mon != nil && useEvents {
 			// event mode: wait for next block message
 			head, err := mon.Recv(m.ctx)
 			// reconnect on error unless context was cancelled
 			if err != nil {
+				m.c.debugf("rpc: observer: reconnecting head monitor: %v", err)
 				mon.Close()
 				mon = nil
 				continue
@@ -183,6 +188,7 @@ func (m *Observer) listenBlocks() {
 			fmt.Println("monitor: new head", head.Hash)
 			headBlock = head.Hash
 			headHeight = head.Level
+			headProto = head.Proto
 		} else {
 			// poll mode: check every 30sec
 			head, err := m.c.GetTipHeader(m.ctx)
@@ -197,8 +203,15 @@ func (m *Observer) listenBlocks() {
 			}
 			headBlock = head.Hash.Clone()
 			headHeight = head.Level
+			headProto = head.Proto
 		}
 
+		// invalidate cached client params on protocol upgrade
+		if m.proto != 0 && headProto != m.proto {
+			m.c.InvalidateParams()
+		}
+		m.proto = headProto
+
 		// skip already processed blocks
 		if headBlock.Equal(m.bestHash) && !useEvents {
 			// wait minDelay/2 for late blocks
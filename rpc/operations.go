@@ -73,6 +73,9 @@ type OperationMetadata struct {
 	Delegate tezos.Address `json:"delegate"`
 	Slots    []int         `json:"slots,omitempty"`
 	Power    int           `json:"endorsement_power,omitempty"`
+
+	// drain_delegate only
+	AllocatedDestinationContract bool `json:"allocated_destination_contract,omitempty"`
 }
 
 // Address returns the delegate address for endorsements.
@@ -229,6 +232,8 @@ func (e *OperationList) UnmarshalJSON(data []byte) error {
 			op = &DoubleEndorsement{}
 		case tezos.OpTypeSeedNonceRevelation:
 			op = &SeedNonce{}
+		case tezos.OpTypeDrainDelegate:
+			op = &DrainDelegate{}
 
 		// consensus operations
 		case tezos.OpTypeEndorsement,
@@ -255,6 +260,10 @@ func (e *OperationList) UnmarshalJSON(data []byte) error {
 			op = &ConstantRegistration{}
 		case tezos.OpTypeSetDepositsLimit:
 			op = &SetDepositsLimit{}
+		case tezos.OpTypeIncreasePaidStorage:
+			op = &IncreasePaidStorage{}
+		case tezos.OpTypeUpdateConsensusKey:
+			op = &UpdateConsensusKey{}
 
 		default:
 			return fmt.Errorf("rpc: unsupported op %q", kind)
@@ -372,6 +381,13 @@ func (c *Client) RunView(ctx context.Context, id BlockID, body, resp interface{}
 	return c.Post(ctx, u, body, resp)
 }
 
+// RunScriptView simulates executing an on-chain view declared in a contract's script
+// on the context of a contract at selected block.
+func (c *Client) RunScriptView(ctx context.Context, id BlockID, body, resp interface{}) error {
+	u := fmt.Sprintf("chains/main/blocks/%s/helpers/scripts/run_script_view", id)
+	return c.Post(ctx, u, body, resp)
+}
+
 // TraceCode simulates executing of code on the context of a contract at selected block and
 // returns a full execution trace.
 func (c *Client) TraceCode(ctx context.Context, id BlockID, body, resp interface{}) error {
@@ -0,0 +1,44 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package rpc
+
+import (
+	"blockwatch.cc/tzgo/tezos"
+)
+
+// Ensure IncreasePaidStorage implements the TypedOperation interface.
+var _ TypedOperation = (*IncreasePaidStorage)(nil)
+
+// IncreasePaidStorage represents an operation that prepays storage space
+// of a smart contract (v014+).
+type IncreasePaidStorage struct {
+	Manager
+	Amount      tezos.Z           `json:"amount"`
+	Destination tezos.Address     `json:"destination"`
+	Metadata    OperationMetadata `json:"metadata"`
+}
+
+// Meta returns operation metadata to implement TypedOperation interface.
+func (o IncreasePaidStorage) Meta() OperationMetadata {
+	return o.Metadata
+}
+
+// Result returns operation result to implement TypedOperation interface.
+func (o IncreasePaidStorage) Result() OperationResult {
+	return o.Metadata.Result
+}
+
+// Costs returns operation cost to implement TypedOperation interface.
+// Prepaid storage is burned from the source.
+func (o IncreasePaidStorage) Costs() tezos.Costs {
+	res := o.Metadata.Result
+	burn := res.BalanceUpdates.ContractDebits()
+	return tezos.Costs{
+		Fee:         o.Manager.Fee,
+		GasUsed:     res.ConsumedGas,
+		Burn:        burn,
+		StorageUsed: o.Amount.Int64(),
+		StorageBurn: burn,
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"blockwatch.cc/tzgo/codec"
+	"blockwatch.cc/tzgo/tezos"
+)
+
+// PreapplyResult contains the receipts of a preapplied operation.
+type PreapplyResult struct {
+	Contents  OperationList   `json:"contents"`
+	Signature tezos.Signature `json:"signature"`
+}
+
+// PreapplyError describes the first operation content that would not be
+// applied, including the Michelson errors reported by the node.
+type PreapplyError struct {
+	Index  int              // position in the contents list
+	Kind   tezos.OpType     // operation kind
+	Status tezos.OpStatus   // failed, skipped or backtracked
+	Errors []OperationError // errors reported for this content
+}
+
+func (e *PreapplyError) Error() string {
+	ids := make([]string, len(e.Errors))
+	for i, v := range e.Errors {
+		ids[i] = v.ID
+	}
+	if len(ids) == 0 {
+		return fmt.Sprintf("rpc: %s at position %d would be %s", e.Kind, e.Index, e.Status)
+	}
+	return fmt.Sprintf("rpc: %s at position %d would be %s: %s", e.Kind, e.Index, e.Status, strings.Join(ids, ", "))
+}
+
+// IsSuccess returns true when all contents would be applied.
+func (r PreapplyResult) IsSuccess() bool {
+	return r.Err() == nil
+}
+
+// Err returns a *PreapplyError for the content that caused the operation to
+// fail or nil when all contents would be applied. Failed contents take
+// precedence over skipped and backtracked ones.
+func (r PreapplyResult) Err() error {
+	var first *PreapplyError
+	for i, op := range r.Contents {
+		res := op.Result()
+		status, errs := res.Status, res.Errors
+		// internal operations may fail while the outer result is backtracked
+		for _, v := range op.Meta().InternalResults {
+			if v.Result.Status == tezos.OpStatusFailed {
+				status, errs = v.Result.Status, v.Result.Errors
+				break
+			}
+		}
+		if !status.IsValid() || status.IsSuccess() {
+			continue
+		}
+		e := &PreapplyError{
+			Index:  i,
+			Kind:   op.Kind(),
+			Status: status,
+			Errors: errs,
+		}
+		if status == tezos.OpStatusFailed {
+			return e
+		}
+		if first == nil {
+			first = e
+		}
+	}
+	if first != nil {
+		return first
+	}
+	return nil
+}
+
+// PreapplyOperation simulates the application of a signed operation on top of
+// block id and returns its receipts. Use Err on the result to check whether
+// any content would fail.
+func (c *Client) PreapplyOperation(ctx context.Context, op *codec.Op, id BlockID) (*PreapplyResult, error) {
+	if !op.Signature.IsValid() {
+		return nil, errors.New("rpc: preapply requires a signed operation")
+	}
+	p := op.Params
+	if p == nil || !p.Protocol.IsValid() {
+		var err error
+		if p, err = c.Params(ctx); err != nil {
+			return nil, err
+		}
+	}
+	body := []struct {
+		Protocol  tezos.ProtocolHash `json:"protocol"`
+		Branch    tezos.BlockHash    `json:"branch"`
+		Contents  []codec.Operation  `json:"contents"`
+		Signature tezos.Signature    `json:"signature"`
+	}{{
+		Protocol:  p.Protocol,
+		Branch:    op.Branch,
+		Contents:  op.Contents,
+		Signature: op.Signature,
+	}}
+	u := fmt.Sprintf("chains/main/blocks/%s/helpers/preapply/operations", id)
+	res := make([]PreapplyResult, 0, 1)
+	if err := c.Post(ctx, u, &body, &res); err != nil {
+		return nil, err
+	}
+	if len(res) == 0 {
+		return nil, errors.New("rpc: empty preapply result")
+	}
+	return &res[0], nil
+}
@@ -0,0 +1,143 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"blockwatch.cc/tzgo/tezos"
+)
+
+// ErrRawContextDisabled is returned by calls that read the raw context when the
+// node or a proxy in front of it does not serve context/raw/json. Many public
+// RPC providers block this endpoint. Callers should switch to another data
+// source such as an indexer instead of treating the data as missing.
+var ErrRawContextDisabled = errors.New("rpc: raw context RPC disabled")
+
+// rawPath builds a raw context URL at block id. Path segments are escaped.
+func rawPath(id BlockID, path ...string) string {
+	segs := make([]string, len(path))
+	for i, v := range path {
+		segs[i] = url.PathEscape(v)
+	}
+	return fmt.Sprintf("chains/main/blocks/%s/context/raw/json/%s", id, strings.Join(segs, "/"))
+}
+
+// rawContextError reports a disabled raw context RPC. It matches
+// ErrRawContextDisabled with errors.Is and unwraps to the original error so
+// that HTTPError details remain accessible.
+type rawContextError struct {
+	err error
+}
+
+func (e rawContextError) Error() string {
+	return fmt.Sprintf("%s: %v", ErrRawContextDisabled, e.err)
+}
+
+func (e rawContextError) Unwrap() error {
+	return e.err
+}
+
+func (e rawContextError) Is(target error) bool {
+	return target == ErrRawContextDisabled
+}
+
+// rawError wraps err from a raw context call with ErrRawContextDisabled when
+// the raw context RPC is unavailable. Unauthorized (octez RPC ACL) and
+// forbidden (reverse proxies) responses always count as disabled. Since
+// missing keys also yield not found, a 404 is only treated as disabled when
+// the global counter, which exists in all protocols, cannot be read either
+// while block id exists.
+func (c *Client) rawError(ctx context.Context, id BlockID, err error) error {
+	switch ErrorStatus(err) {
+	case http.StatusUnauthorized, http.StatusForbidden:
+	case http.StatusNotFound:
+		var counter json.RawMessage
+		perr := c.Get(ctx, rawPath(id, "contracts", "global_counter"), &counter)
+		switch ErrorStatus(perr) {
+		case http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound:
+		default:
+			return err
+		}
+		if _, herr := c.GetBlockHash(ctx, id); herr != nil {
+			return err
+		}
+	default:
+		return err
+	}
+	return rawContextError{err}
+}
+
+// GetRaw returns the JSON encoded raw context data stored under path at block id,
+// e.g. GetRaw(ctx, Head, "big_maps", "index", "1"). Returns an error wrapping
+// ErrRawContextDisabled when the node does not serve the raw context.
+func (c *Client) GetRaw(ctx context.Context, id BlockID, path ...string) (json.RawMessage, error) {
+	var msg json.RawMessage
+	if err := c.Get(ctx, rawPath(id, path...), &msg); err != nil {
+		return nil, c.rawError(ctx, id, err)
+	}
+	return msg, nil
+}
+
+// GetStakeSnapshot returns the stake distribution selected for cycle as seen
+// from block id. v012+ Returns an error wrapping ErrRawContextDisabled when
+// the node does not serve the raw context.
+func (c *Client) GetStakeSnapshot(ctx context.Context, id BlockID, cycle int64) ([]StakeInfo, error) {
+	stake := make([]StakeInfo, 0)
+	u := rawPath(id, "cycle", strconv.FormatInt(cycle, 10), "selected_stake_distribution")
+	if err := c.Get(ctx, u, &stake); err != nil {
+		return nil, c.rawError(ctx, id, err)
+	}
+	return stake, nil
+}
+
+// GetTotalActiveStake returns the total active stake selected for cycle as seen
+// from block id. v012+ Returns an error wrapping ErrRawContextDisabled when
+// the node does not serve the raw context.
+func (c *Client) GetTotalActiveStake(ctx context.Context, id BlockID, cycle int64) (int64, error) {
+	stake, err := c.GetTotalActiveStakeMutez(ctx, id, cycle)
+	return stake.Int64(), err
+}
+
+// GetTotalActiveStakeMutez returns the total active stake selected for cycle
+// as Mutez. v012+
+func (c *Client) GetTotalActiveStakeMutez(ctx context.Context, id BlockID, cycle int64) (tezos.Mutez, error) {
+	u := rawPath(id, "cycle", strconv.FormatInt(cycle, 10), "total_active_stake")
+	stake, err := c.getMutez(ctx, u)
+	if err != nil {
+		return 0, c.rawError(ctx, id, err)
+	}
+	return stake, nil
+}
+
+// GetRandomSeed returns the random seed for cycle as seen from block id.
+// Returns an error wrapping ErrRawContextDisabled when the node does not serve
+// the raw context.
+func (c *Client) GetRandomSeed(ctx context.Context, id BlockID, cycle int64) (tezos.HexBytes, error) {
+	var seed tezos.HexBytes
+	u := rawPath(id, "cycle", strconv.FormatInt(cycle, 10), "random_seed")
+	if err := c.Get(ctx, u, &seed); err != nil {
+		return nil, c.rawError(ctx, id, err)
+	}
+	return seed, nil
+}
+
+// GetRollSnapshotIndex returns the index of the roll snapshot selected for
+// cycle as seen from block id. Until v011. Returns an error wrapping
+// ErrRawContextDisabled when the node does not serve the raw context.
+func (c *Client) GetRollSnapshotIndex(ctx context.Context, id BlockID, cycle int64) (int64, error) {
+	var index int64
+	u := rawPath(id, "cycle", strconv.FormatInt(cycle, 10), "roll_snapshot")
+	if err := c.Get(ctx, u, &index); err != nil {
+		return 0, c.rawError(ctx, id, err)
+	}
+	return index, nil
+}
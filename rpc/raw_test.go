@@ -0,0 +1,150 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package rpc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"blockwatch.cc/tzgo/micheline"
+	"blockwatch.cc/tzgo/tezos"
+)
+
+// Fixtures in testdata/raw reproduce the raw context responses of the RPC
+// setups callers run into:
+//
+//	octez            octez node serving its RPC on localhost, raw context enabled
+//	octez-acl        octez node behind the default public RPC ACL (401)
+//	proxy-forbidden  nginx style reverse proxy rejecting context/raw (403)
+//	proxy-notfound   reverse proxy hiding context/raw entirely (404)
+//
+// The fixtures were written by hand after the documented octez RPC ACL and
+// the error pages of common proxy setups, they are not recordings from live
+// nodes. See testdata/raw/README.md.
+const testBigmapKey = "exprtXKYTCuvMGzz8QrdrM58m2pNPn1m2XVpZVzUft7pakKr5UySVt"
+
+func loadRawMock(t *testing.T, provider string) *Client {
+	t.Helper()
+	m := NewMock()
+	if err := m.Load(filepath.Join("testdata", "raw", provider)); err != nil {
+		t.Fatal(err)
+	}
+	c, err := m.Client()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestRawContext(t *testing.T) {
+	ctx := context.Background()
+	c := loadRawMock(t, "octez")
+
+	stake, err := c.GetTotalActiveStakeMutez(ctx, Head, 600)
+	if err != nil || stake.Int64() != 7265511934059 {
+		t.Errorf("GetTotalActiveStakeMutez: %d %v", stake, err)
+	}
+	if n, err := c.GetTotalActiveStake(ctx, Head, 600); err != nil || n != 7265511934059 {
+		t.Errorf("GetTotalActiveStake: %d %v", n, err)
+	}
+	seed, err := c.GetRandomSeed(ctx, Head, 600)
+	if err != nil || len(seed) != 32 {
+		t.Errorf("GetRandomSeed: %x %v", seed, err)
+	}
+	if idx, err := c.GetRollSnapshotIndex(ctx, Head, 400); err != nil || idx != 9 {
+		t.Errorf("GetRollSnapshotIndex: %d %v", idx, err)
+	}
+	snap, err := c.GetStakeSnapshot(ctx, Head, 600)
+	if err != nil || len(snap) != 2 {
+		t.Fatalf("GetStakeSnapshot: %v %v", snap, err)
+	}
+	if snap[0].Baker.String() != "tz1aRoaRhSpRYvFdyvgWLL6TGyRoGF51wDjM" || snap[0].ActiveStake != 5162071423112 {
+		t.Errorf("GetStakeSnapshot: unexpected %+v", snap[0])
+	}
+
+	keys, err := c.ListBigmapKeys(ctx, 42, Head)
+	if err != nil || len(keys) != 2 || keys[0].String() != testBigmapKey {
+		t.Errorf("ListBigmapKeys: %v %v", keys, err)
+	}
+	streamed := make([]string, 0)
+	err = c.GetBigmapKeysStream(ctx, 42, Head, func(h tezos.ExprHash) error {
+		streamed = append(streamed, h.String())
+		return nil
+	})
+	if err != nil || len(streamed) != 2 || streamed[0] != keys[0].String() || streamed[1] != keys[1].String() {
+		t.Errorf("GetBigmapKeysStream: %v %v", streamed, err)
+	}
+	info, err := c.GetBigmapInfo(ctx, 42, Head)
+	if err != nil || info.KeyType.OpCode != micheline.T_ADDRESS || info.ValueType.OpCode != micheline.T_NAT || info.TotalBytes != 4096 {
+		t.Errorf("GetBigmapInfo: %+v %v", info, err)
+	}
+
+	// a missing key is not a disabled raw context
+	_, err = c.GetRandomSeed(ctx, Head, 9999)
+	if err == nil || errors.Is(err, ErrRawContextDisabled) || ErrorStatus(err) != http.StatusNotFound {
+		t.Errorf("missing key: unexpected error %v", err)
+	}
+}
+
+func TestRawContextDisabled(t *testing.T) {
+	ctx := context.Background()
+	for _, test := range []struct {
+		provider string
+		status   int
+	}{
+		{"octez-acl", http.StatusUnauthorized},
+		{"proxy-forbidden", http.StatusForbidden},
+		{"proxy-notfound", http.StatusNotFound},
+	} {
+		c := loadRawMock(t, test.provider)
+		calls := map[string]func() error{
+			"GetTotalActiveStake": func() error {
+				_, err := c.GetTotalActiveStake(ctx, Head, 600)
+				return err
+			},
+			"GetTotalActiveStakeMutez": func() error {
+				_, err := c.GetTotalActiveStakeMutez(ctx, Head, 600)
+				return err
+			},
+			"GetRandomSeed": func() error {
+				_, err := c.GetRandomSeed(ctx, Head, 600)
+				return err
+			},
+			"GetRollSnapshotIndex": func() error {
+				_, err := c.GetRollSnapshotIndex(ctx, Head, 400)
+				return err
+			},
+			"GetStakeSnapshot": func() error {
+				_, err := c.GetStakeSnapshot(ctx, Head, 600)
+				return err
+			},
+			"ListBigmapKeys": func() error {
+				_, err := c.ListBigmapKeys(ctx, 42, Head)
+				return err
+			},
+			"GetBigmapKeysStream": func() error {
+				return c.GetBigmapKeysStream(ctx, 42, Head, func(tezos.ExprHash) error { return nil })
+			},
+			"GetBigmapInfo": func() error {
+				_, err := c.GetBigmapInfo(ctx, 42, Head)
+				return err
+			},
+		}
+		for name, fn := range calls {
+			err := fn()
+			if !errors.Is(err, ErrRawContextDisabled) {
+				t.Errorf("%s %s: expected ErrRawContextDisabled, got %v", test.provider, name, err)
+				continue
+			}
+			// the original HTTP error stays accessible
+			var herr HTTPError
+			if !errors.As(err, &herr) || herr.StatusCode() != test.status {
+				t.Errorf("%s %s: lost HTTP error %v", test.provider, name, err)
+			}
+		}
+	}
+}
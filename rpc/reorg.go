@@ -0,0 +1,129 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package rpc
+
+import (
+	"context"
+	"sync"
+
+	"blockwatch.cc/tzgo/tezos"
+)
+
+// ReorgDetector compares successive chain heads against a window of recent
+// blocks and reports blocks that were reorganized out of the chain. It is a
+// building block for indexers that process heads themselves, see
+// BlockFollower for a complete solution.
+type ReorgDetector struct {
+	c     *Client
+	depth int
+	mu    sync.Mutex
+	chain BlockCursor
+}
+
+// NewReorgDetector creates a detector that tracks up to depth recent blocks.
+// Deeper reorganizations are reported as ErrReorgTooDeep.
+func NewReorgDetector(c *Client, depth int) *ReorgDetector {
+	if depth <= 0 {
+		depth = DefaultBlockFollowerOptions.MaxReorgDepth
+	}
+	return &ReorgDetector{
+		c:     c,
+		depth: depth,
+		chain: make(BlockCursor, 0, depth+1),
+	}
+}
+
+// Chain returns a copy of the tracked blocks in ascending order.
+func (d *ReorgDetector) Chain() BlockCursor {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.chain.Clone()
+}
+
+// Reset forgets all tracked blocks.
+func (d *ReorgDetector) Reset() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.chain = d.chain[:0]
+}
+
+// Update adds a new head and returns tracked blocks that are no longer part
+// of the head's chain in descending order. Missing blocks between the
+// previous and the new head are added from the node. When no tracked block is
+// an ancestor of head, all tracked blocks are returned with ErrReorgTooDeep
+// and tracking restarts at head.
+func (d *ReorgDetector) Update(ctx context.Context, head *BlockHeader) ([]BlockRef, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	ref := BlockRef{Height: head.Level, Hash: head.Hash.Clone()}
+	tip, ok := d.chain.Tip()
+	switch {
+	case !ok:
+		d.chain = append(d.chain, ref)
+		return nil, nil
+	case tip.Height == head.Level && tip.Hash.Equal(head.Hash):
+		// known head
+		return nil, nil
+	case tip.Height+1 == head.Level && tip.Hash.Equal(head.Predecessor):
+		d.chain = append(d.chain, ref)
+		d.trim()
+		return nil, nil
+	}
+
+	// roll back tracked blocks until the fork point
+	removed, hashes, err := d.c.orphanedBlocks(ctx, d.chain, ref)
+	if err != nil {
+		return nil, err
+	}
+	d.chain = d.chain[:len(d.chain)-len(removed)]
+	if len(d.chain) == 0 {
+		d.chain = append(d.chain, ref)
+		return removed, ErrReorgTooDeep
+	}
+
+	// add the new branch
+	for height := d.chain[len(d.chain)-1].Height + 1; height <= head.Level; height++ {
+		i := head.Level - height
+		if i >= int64(len(hashes)) {
+			break
+		}
+		d.chain = append(d.chain, BlockRef{Height: height, Hash: hashes[i].Clone()})
+	}
+	d.trim()
+	return removed, nil
+}
+
+// orphanedBlocks returns the blocks at the end of chain that are not ancestors
+// of head in descending order. It also returns the hashes of head and its
+// ancestors down to the oldest block in chain, hashes[i] is the canonical
+// block at height head.Height-i. Used by BlockFollower and ReorgDetector.
+func (c *Client) orphanedBlocks(ctx context.Context, chain BlockCursor, head BlockRef) ([]BlockRef, []tezos.BlockHash, error) {
+	if len(chain) == 0 {
+		return nil, nil, nil
+	}
+	count := int(head.Height-chain[0].Height) + 1
+	if count < 1 {
+		count = 1
+	}
+	hashes, err := c.GetBlockPredHashes(ctx, head.Hash, count)
+	if err != nil {
+		return nil, nil, err
+	}
+	removed := make([]BlockRef, 0)
+	for i := len(chain) - 1; i >= 0; i-- {
+		ref := chain[i]
+		if j := head.Height - ref.Height; j >= 0 && j < int64(len(hashes)) && hashes[j].Equal(ref.Hash) {
+			break
+		}
+		removed = append(removed, ref)
+	}
+	return removed, hashes, nil
+}
+
+// trim drops tracked blocks beyond depth. Callers must hold mu.
+func (d *ReorgDetector) trim() {
+	if n := len(d.chain) - d.depth - 1; n > 0 {
+		d.chain = append(d.chain[:0], d.chain[n:]...)
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package rpc
+
+import (
+	"context"
+	"testing"
+)
+
+func testHeader(level int64, fork, parent byte, from int64) *BlockHeader {
+	h := &BlockHeader{
+		Level:       level,
+		Hash:        testBlockHash(level, fork),
+		Predecessor: testBlockHash(level-1, fork),
+	}
+	if level == from {
+		h.Predecessor = testBlockHash(level-1, parent)
+	}
+	return h
+}
+
+func newTestDetector(t *testing.T, m *Mock, depth int) *ReorgDetector {
+	t.Helper()
+	c, err := m.Client()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewReorgDetector(c, depth)
+}
+
+func TestReorgDetector(t *testing.T) {
+	m := NewMock()
+	testChainAt(m, 1, 15, 0, 0, true)
+	testChainAt(m, 11, 13, 1, 0, true) // fork at level 11
+	d := newTestDetector(t, m, 5)
+	ctx := context.Background()
+
+	for _, level := range []int64{8, 9, 10, 11, 12} {
+		if removed, err := d.Update(ctx, testHeader(level, 0, 0, 1)); err != nil || len(removed) > 0 {
+			t.Fatalf("update %d: %v %v", level, removed, err)
+		}
+	}
+	removed, err := d.Update(ctx, testHeader(13, 1, 0, 11))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(removed) != 2 || !removed[0].Hash.Equal(testBlockHash(12, 0)) || !removed[1].Hash.Equal(testBlockHash(11, 0)) {
+		t.Fatalf("unexpected removed blocks %v", removed)
+	}
+	chain := d.Chain()
+	if len(chain) != 6 {
+		t.Fatalf("unexpected chain %v", chain)
+	}
+	for i, ref := range chain {
+		level, fork := int64(8+i), byte(0)
+		if level >= 11 {
+			fork = 1
+		}
+		if ref.Height != level || !ref.Hash.Equal(testBlockHash(level, fork)) {
+			t.Errorf("chain %d: unexpected %d %s", i, ref.Height, ref.Hash)
+		}
+	}
+}
+
+func TestReorgDetectorGap(t *testing.T) {
+	m := NewMock()
+	testChainAt(m, 1, 15, 0, 0, true)
+	d := newTestDetector(t, m, 5)
+	ctx := context.Background()
+
+	if _, err := d.Update(ctx, testHeader(10, 0, 0, 1)); err != nil {
+		t.Fatal(err)
+	}
+	if removed, err := d.Update(ctx, testHeader(14, 0, 0, 1)); err != nil || len(removed) > 0 {
+		t.Fatalf("unexpected %v %v", removed, err)
+	}
+	chain := d.Chain()
+	if len(chain) != 5 || chain[0].Height != 10 || chain[4].Height != 14 {
+		t.Errorf("missing blocks not added %v", chain)
+	}
+}
+
+func TestReorgDetectorTooDeep(t *testing.T) {
+	m := NewMock()
+	testChainAt(m, 1, 12, 0, 0, true)
+	testChainAt(m, 6, 13, 1, 0, true) // fork at level 6
+	d := newTestDetector(t, m, 3)
+	ctx := context.Background()
+
+	for level := int64(9); level <= 12; level++ {
+		if _, err := d.Update(ctx, testHeader(level, 0, 0, 1)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	removed, err := d.Update(ctx, testHeader(13, 1, 0, 6))
+	if err != ErrReorgTooDeep || len(removed) != 4 {
+		t.Fatalf("expected ErrReorgTooDeep with 4 blocks, got %v %v", removed, err)
+	}
+	if chain := d.Chain(); len(chain) != 1 || !chain[0].Hash.Equal(testBlockHash(13, 1)) {
+		t.Errorf("tracking did not restart at head %v", chain)
+	}
+}
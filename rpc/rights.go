@@ -117,8 +117,12 @@ type SnapshotOwners struct {
 // ListBakingRights returns information about baking rights at block id.
 // Use max to set a max block priority (before Ithaca) or a max round (after Ithaca).
 func (c *Client) ListBakingRights(ctx context.Context, id BlockID, max int) ([]BakingRight, error) {
+	p, err := c.Params(ctx)
+	if err != nil {
+		return nil, err
+	}
 	maxSelector := "max_priority=%d"
-	if c.Params.Version >= 12 {
+	if p.Version >= 12 {
 		maxSelector = "max_round=%d"
 	}
 	rights := make([]BakingRight, 0)
@@ -134,11 +138,15 @@ func (c *Client) ListBakingRights(ctx context.Context, id BlockID, max int) ([]B
 // away from each other. Use max to set a max block priority (before Ithaca) or a max
 // round (after Ithaca).
 func (c *Client) ListBakingRightsCycle(ctx context.Context, id BlockID, cycle int64, max int) ([]BakingRight, error) {
+	p, err := c.Params(ctx)
+	if err != nil {
+		return nil, err
+	}
 	maxSelector := "max_priority=%d"
-	if c.Params.Version >= 12 {
+	if p.Version >= 12 {
 		maxSelector = "max_round=%d"
 	}
-	rights := make([]BakingRight, 0, (max+1)*int(c.Params.BlocksPerCycle))
+	rights := make([]BakingRight, 0, (max+1)*int(p.BlocksPerCycle))
 	u := fmt.Sprintf("chains/main/blocks/%s/helpers/baking_rights?all=true&cycle=%d&"+maxSelector, id, cycle, max)
 	if err := c.Get(ctx, u, &rights); err != nil {
 		return nil, err
@@ -148,9 +156,13 @@ func (c *Client) ListBakingRightsCycle(ctx context.Context, id BlockID, cycle in
 
 // ListEndorsingRights returns information about block endorsing rights.
 func (c *Client) ListEndorsingRights(ctx context.Context, id BlockID) ([]EndorsingRight, error) {
+	p, err := c.Params(ctx)
+	if err != nil {
+		return nil, err
+	}
 	u := fmt.Sprintf("chains/main/blocks/%s/helpers/endorsing_rights?all=true", id)
-	rights := make([]EndorsingRight, 0, (c.Params.EndorsersPerBlock + c.Params.ConsensusCommitteeSize))
-	if c.Params.Version >= 12 {
+	rights := make([]EndorsingRight, 0, (p.EndorsersPerBlock + p.ConsensusCommitteeSize))
+	if p.Version >= 12 {
 		var v12rights []struct {
 			Level         int64            `json:"level"`
 			Delegates     []EndorsingRight `json:"delegates"`
@@ -179,9 +191,13 @@ func (c *Client) ListEndorsingRights(ctx context.Context, id BlockID) ([]Endorsi
 // as seen from block id. Note block and cycle must be no further than preserved cycles
 // away.
 func (c *Client) ListEndorsingRightsCycle(ctx context.Context, id BlockID, cycle int64) ([]EndorsingRight, error) {
-	rights := make([]EndorsingRight, 0, (c.Params.EndorsersPerBlock+c.Params.ConsensusCommitteeSize)*int(c.Params.BlocksPerCycle))
+	p, err := c.Params(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rights := make([]EndorsingRight, 0, (p.EndorsersPerBlock+p.ConsensusCommitteeSize)*int(p.BlocksPerCycle))
 	u := fmt.Sprintf("chains/main/blocks/%s/helpers/endorsing_rights?all=true&cycle=%d", id, cycle)
-	if c.Params.Version >= 12 {
+	if p.Version >= 12 {
 		var v12rights []struct {
 			Level         int64            `json:"level"`
 			Delegates     []EndorsingRight `json:"delegates"`
@@ -210,7 +226,7 @@ func (c *Client) ListEndorsingRightsCycle(ctx context.Context, id BlockID, cycle
 // Note block and cycle must be no further than preserved cycles away.
 func (c *Client) GetSnapshotIndexCycle(ctx context.Context, id BlockID, cycle int64) (*SnapshotIndex, error) {
 	idx := &SnapshotIndex{Cycle: cycle}
-	u := fmt.Sprintf("chains/main/blocks/%s/context/raw/json/cycle/%d", id, cycle)
+	u := rawPath(id, "cycle", strconv.FormatInt(cycle, 10))
 	if err := c.Get(ctx, u, idx); err != nil {
 		return nil, err
 	}
@@ -224,7 +240,7 @@ func (c *Client) GetSnapshotIndexCycle(ctx context.Context, id BlockID, cycle in
 // Response is a nested array `[[roll_id, pubkey]]`. Deprecated in Ithaca.
 func (c *Client) ListSnapshotRollOwners(ctx context.Context, id BlockID, cycle, index int64) (*SnapshotOwners, error) {
 	owners := &SnapshotOwners{Cycle: cycle, Index: index}
-	u := fmt.Sprintf("chains/main/blocks/%s/context/raw/json/rolls/owner/snapshot/%d/%d?depth=1", id, cycle, index)
+	u := rawPath(id, "rolls", "owner", "snapshot", strconv.FormatInt(cycle, 10), strconv.FormatInt(index, 10)) + "?depth=1"
 	if err := c.Get(ctx, u, &owners.Rolls); err != nil {
 		return nil, err
 	}
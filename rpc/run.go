@@ -53,6 +53,20 @@ type RunViewRequest struct {
 	Mode       string            `json:"unparsing_mode"` // "Readable" | "Optimized"
 }
 
+// RunScriptViewRequest contains inputs for executing an on-chain view with the
+// run_script_view helper.
+type RunScriptViewRequest struct {
+	Contract     tezos.Address     `json:"contract"`
+	View         string            `json:"view"`
+	Input        micheline.Prim    `json:"input"`
+	UnlimitedGas bool              `json:"unlimited_gas"`
+	ChainId      tezos.ChainIdHash `json:"chain_id"`
+	Source       *tezos.Address    `json:"source,omitempty"`
+	Payer        *tezos.Address    `json:"payer,omitempty"`
+	Gas          *tezos.N          `json:"gas,omitempty"`
+	Mode         UnparsingMode     `json:"unparsing_mode"`
+}
+
 type RunViewResponse struct {
 	Data micheline.Prim `json:"data"`
 }
@@ -61,9 +75,13 @@ type RunViewResponse struct {
 // on-chain state. Sets branch for TTL control, replay counters, and reveals
 // the sender's pubkey if not published yet.
 func (c *Client) Complete(ctx context.Context, o *codec.Op, key tezos.Key) error {
+	return c.complete(ctx, o, key, true)
+}
+
+func (c *Client) complete(ctx context.Context, o *codec.Op, key tezos.Key, reveal bool) error {
 	needBranch := !o.Branch.IsValid()
 	needCounter := len(o.Contents) > 0 && o.Contents[0].GetCounter() == 0
-	mayNeedReveal := len(o.Contents) > 0 && o.Contents[0].Kind() != tezos.OpTypeReveal
+	mayNeedReveal := reveal && len(o.Contents) > 0 && o.Contents[0].Kind() != tezos.OpTypeReveal
 
 	if !needBranch && !mayNeedReveal && !needCounter {
 		return nil
@@ -71,6 +89,13 @@ func (c *Client) Complete(ctx context.Context, o *codec.Op, key tezos.Key) error
 
 	// add branch for TTL control
 	if needBranch {
+		if o.Params == nil {
+			p, err := c.Params(ctx)
+			if err != nil {
+				return err
+			}
+			o.WithParams(p)
+		}
 		ofs := o.Params.MaxOperationsTTL - o.TTL
 		hash, err := c.GetBlockHash(ctx, NewBlockOffset(Head, -ofs))
 		if err != nil {
@@ -79,15 +104,13 @@ func (c *Client) Complete(ctx context.Context, o *codec.Op, key tezos.Key) error
 		o.WithBranch(hash)
 	}
 
-	if needCounter || mayNeedReveal {
-		// fetch current state
-		state, err := c.GetContractExt(ctx, key.Address(), Head)
+	// add reveal if the sender's manager key is not published yet
+	if mayNeedReveal {
+		mk, err := c.GetManagerKey(ctx, key.Address(), Head)
 		if err != nil {
 			return err
 		}
-
-		// add reveal if necessary
-		if mayNeedReveal && !state.IsRevealed() {
+		if !mk.IsValid() {
 			reveal := &codec.Reveal{
 				Manager: codec.Manager{
 					Source: key.Address(),
@@ -96,19 +119,26 @@ func (c *Client) Complete(ctx context.Context, o *codec.Op, key tezos.Key) error
 			}
 			reveal.WithLimits(defaultRevealLimits)
 			o.WithContentsFront(reveal)
+
+			// the reveal consumes the first counter
+			needCounter = true
 		}
+	}
 
-		// add counters
-		if needCounter {
-			nextCounter := state.Counter + 1
-			for _, op := range o.Contents {
-				// skip non-manager ops
-				if op.GetCounter() < 0 {
-					continue
-				}
-				op.WithCounter(nextCounter)
-				nextCounter++
+	// add counters
+	if needCounter {
+		state, err := c.GetContractExt(ctx, key.Address(), Head)
+		if err != nil {
+			return err
+		}
+		nextCounter := state.Counter + 1
+		for _, op := range o.Contents {
+			// skip non-manager ops
+			if op.GetCounter() < 0 {
+				continue
 			}
+			op.WithCounter(nextCounter)
+			nextCounter++
 		}
 	}
 	return nil
@@ -125,8 +155,16 @@ func (c *Client) Simulate(ctx context.Context, o *codec.Op) (*Receipt, error) {
 		Params:    o.Params,
 	}
 
+	if sim.Params == nil {
+		p, err := c.Params(ctx)
+		if err != nil {
+			return nil, err
+		}
+		sim.Params = p
+	}
+
 	if !sim.Branch.IsValid() {
-		ofs := o.Params.MaxOperationsTTL - sim.TTL
+		ofs := sim.Params.MaxOperationsTTL - sim.TTL
 		hash, err := c.GetBlockHash(ctx, NewBlockOffset(Head, -ofs))
 		if err != nil {
 			return nil, err
@@ -0,0 +1,55 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"blockwatch.cc/tzgo/micheline"
+	"blockwatch.cc/tzgo/tezos"
+)
+
+// SaplingDiff is a sapling state diff as returned by the sapling get_diff RPCs.
+// Updates contains all commitments and nullifiers after the requested offsets.
+type SaplingDiff struct {
+	Root    tezos.HexBytes
+	Updates micheline.SaplingUpdate
+}
+
+func (d *SaplingDiff) UnmarshalJSON(data []byte) error {
+	var root struct {
+		Root tezos.HexBytes `json:"root"`
+	}
+	if err := json.Unmarshal(data, &root); err != nil {
+		return err
+	}
+	d.Root = root.Root
+	return json.Unmarshal(data, &d.Updates)
+}
+
+// GetSaplingDiff returns the diff of sapling state id at block id starting at
+// the given commitment and nullifier offsets.
+func (c *Client) GetSaplingDiff(ctx context.Context, sapling int64, offsetCommitment, offsetNullifier int64, id BlockID) (*SaplingDiff, error) {
+	u := fmt.Sprintf("chains/main/blocks/%s/context/sapling/%d/get_diff?offset_commitment=%d&offset_nullifier=%d",
+		id, sapling, offsetCommitment, offsetNullifier)
+	diff := &SaplingDiff{}
+	if err := c.Get(ctx, u, diff); err != nil {
+		return nil, err
+	}
+	return diff, nil
+}
+
+// GetContractSaplingDiff returns the diff of the single sapling state in contract
+// addr at block id starting at the given commitment and nullifier offsets.
+func (c *Client) GetContractSaplingDiff(ctx context.Context, addr tezos.Address, offsetCommitment, offsetNullifier int64, id BlockID) (*SaplingDiff, error) {
+	u := fmt.Sprintf("chains/main/blocks/%s/context/contracts/%s/single_sapling_get_diff?offset_commitment=%d&offset_nullifier=%d",
+		id, addr, offsetCommitment, offsetNullifier)
+	diff := &SaplingDiff{}
+	if err := c.Get(ctx, u, diff); err != nil {
+		return nil, err
+	}
+	return diff, nil
+}
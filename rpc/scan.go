@@ -0,0 +1,130 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+type scanResult struct {
+	height int64
+	block  *Block
+	err    error
+}
+
+// ScanBlocks fetches all blocks between from and to (inclusive) using up to
+// concurrency parallel requests and emits them in ascending order. Blocks that
+// arrive out of order are buffered, workers stay at most 2*concurrency blocks
+// ahead of the consumer. The block channel is closed when all blocks were
+// delivered or scanning stopped. The first error is sent on the error channel
+// which is closed afterwards. Cancel ctx to stop early.
+//
+//	blocks, errc := c.ScanBlocks(ctx, 1000, 2000, 8)
+//	for b := range blocks {
+//	    // process block
+//	}
+//	if err := <-errc; err != nil {
+//	    // handle error
+//	}
+func (c *Client) ScanBlocks(ctx context.Context, from, to int64, concurrency int) (<-chan *Block, <-chan error) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	out := make(chan *Block, concurrency)
+	errc := make(chan error, 1)
+	if from > to {
+		close(out)
+		close(errc)
+		return out, errc
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	jobs := make(chan int64)
+	results := make(chan scanResult, concurrency)
+	window := make(chan struct{}, 2*concurrency)
+
+	// dispatcher, a window slot is released when the block was emitted
+	go func() {
+		defer close(jobs)
+		for h := from; h <= to; h++ {
+			select {
+			case <-ctx.Done():
+				return
+			case window <- struct{}{}:
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case jobs <- h:
+			}
+		}
+	}()
+
+	// workers
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for h := range jobs {
+				b, err := c.GetBlock(ctx, BlockLevel(h))
+				if err != nil {
+					err = fmt.Errorf("rpc: scanning block %d: %w", h, err)
+				}
+				select {
+				case <-ctx.Done():
+					return
+				case results <- scanResult{height: h, block: b, err: err}:
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	// collector, emits blocks in order
+	go func() {
+		defer close(errc)
+		defer close(out)
+		defer cancel()
+		var (
+			next    = from
+			pending = make(map[int64]*Block)
+			failed  bool
+		)
+		for r := range results {
+			if failed {
+				continue
+			}
+			if r.err != nil {
+				errc <- r.err
+				failed = true
+				cancel()
+				continue
+			}
+			pending[r.height] = r.block
+			for b, ok := pending[next]; ok && !failed; b, ok = pending[next] {
+				select {
+				case <-ctx.Done():
+					errc <- ctx.Err()
+					failed = true
+				case out <- b:
+					delete(pending, next)
+					next++
+					<-window
+				}
+			}
+		}
+		if !failed && next <= to {
+			if err := ctx.Err(); err != nil {
+				errc <- err
+			}
+		}
+	}()
+	return out, errc
+}
@@ -0,0 +1,203 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"blockwatch.cc/tzgo/micheline"
+	"blockwatch.cc/tzgo/tezos"
+)
+
+// RunCodeRequest contains inputs for executing a script with the run_code helper.
+// Optional fields are omitted when nil or empty and the node uses defaults.
+type RunCodeRequest struct {
+	Script     micheline.Code    `json:"script"`
+	Storage    micheline.Prim    `json:"storage"`
+	Input      micheline.Prim    `json:"input"`
+	Amount     tezos.N           `json:"amount"`
+	Balance    tezos.N           `json:"balance"`
+	ChainId    tezos.ChainIdHash `json:"chain_id"`
+	Source     *tezos.Address    `json:"source,omitempty"`
+	Payer      *tezos.Address    `json:"payer,omitempty"`
+	Self       *tezos.Address    `json:"self,omitempty"`
+	Entrypoint string            `json:"entrypoint,omitempty"`
+	Gas        *tezos.N          `json:"gas,omitempty"`
+	Now        *tezos.Timestamp  `json:"now,omitempty"`
+	Level      *tezos.N          `json:"level,omitempty"`
+	Mode       UnparsingMode     `json:"unparsing_mode,omitempty"`
+}
+
+// RunScriptResult contains the result of running a script.
+type RunScriptResult struct {
+	Storage         micheline.Prim       `json:"storage"`
+	Operations      []InternalResult     `json:"operations"`
+	BigmapDiff      micheline.BigmapDiff `json:"big_map_diff,omitempty"`
+	LazyStorageDiff LazyStorageDiff      `json:"lazy_storage_diff,omitempty"`
+}
+
+// RunScript executes script with input parameter and storage at block id without
+// originating a contract. Amount and balance are zero, use RunScriptWith to
+// control all execution parameters.
+func (c *Client) RunScript(ctx context.Context, script micheline.Script, storage, input micheline.Prim, id BlockID) (*RunScriptResult, error) {
+	return c.RunScriptWith(ctx, id, RunCodeRequest{
+		Script:  script.Code,
+		Storage: storage,
+		Input:   input,
+	})
+}
+
+// RunScriptWith executes a script as described by req at block id. When chain id is
+// empty the client's chain id is used.
+func (c *Client) RunScriptWith(ctx context.Context, id BlockID, req RunCodeRequest) (*RunScriptResult, error) {
+	if !req.ChainId.IsValid() {
+		req.ChainId = c.ChainId
+	}
+	var res RunScriptResult
+	if err := c.RunCode(ctx, id, &req, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// TraceScriptResult contains the result and execution trace of running a script.
+type TraceScriptResult struct {
+	RunScriptResult
+	Trace Trace `json:"trace"`
+}
+
+// Trace is a list of execution steps as returned by the trace_code helper.
+type Trace []TraceStep
+
+// TraceStep is a single execution step in a script trace.
+type TraceStep struct {
+	Location int              `json:"location"` // node index in the script's code
+	Gas      TraceGas         `json:"gas"`      // remaining gas after the step
+	Stack    []TraceStackItem `json:"stack"`    // stack contents after the step
+
+	// Instruction is the script node at Location, set by Trace.Locate.
+	Instruction micheline.Prim `json:"-"`
+}
+
+// TraceStackItem is a stack element in a trace step.
+type TraceStackItem struct {
+	Value micheline.Prim `json:"item"`
+	Annot string         `json:"annot,omitempty"`
+}
+
+// UnmarshalJSON decodes stack items from both formats used by different protocols,
+// objects with item and annotation or plain Micheline values.
+func (i *TraceStackItem) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '{' {
+		var probe struct {
+			Item  json.RawMessage `json:"item"`
+			Annot string          `json:"annot"`
+		}
+		if err := json.Unmarshal(data, &probe); err != nil {
+			return err
+		}
+		if probe.Item != nil {
+			i.Annot = probe.Annot
+			return json.Unmarshal(probe.Item, &i.Value)
+		}
+	}
+	return json.Unmarshal(data, &i.Value)
+}
+
+// TraceGas is remaining gas in milligas. Steps that are not gas accounted
+// (e.g. on failure) use -1.
+type TraceGas int64
+
+// Unaccounted is true when the trace step did not account gas.
+func (g TraceGas) Unaccounted() bool {
+	return g < 0
+}
+
+// Gas returns remaining gas in gas units rounded up.
+func (g TraceGas) Gas() int64 {
+	if g < 0 {
+		return 0
+	}
+	return (int64(g) + 999) / 1000
+}
+
+func (g *TraceGas) UnmarshalText(data []byte) error {
+	s := string(data)
+	if s == "unaccounted" {
+		*g = -1
+		return nil
+	}
+	// decimal gas with up to 3 fractional digits, e.g. 1039.335
+	whole, frac := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		whole, frac = s[:i], s[i+1:]
+	}
+	if len(frac) > 3 {
+		return fmt.Errorf("rpc: invalid trace gas %q", s)
+	}
+	frac += strings.Repeat("0", 3-len(frac))
+	w, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil {
+		return fmt.Errorf("rpc: invalid trace gas %q: %w", s, err)
+	}
+	f, err := strconv.ParseInt(frac, 10, 64)
+	if err != nil {
+		return fmt.Errorf("rpc: invalid trace gas %q: %w", s, err)
+	}
+	*g = TraceGas(w*1000 + f)
+	return nil
+}
+
+// GasUsed returns milligas consumed by each step, computed from the difference
+// in remaining gas to the previous step. The first step's usage is measured
+// against limit (in milligas). Unaccounted steps report zero.
+func (t Trace) GasUsed(limit int64) []int64 {
+	used := make([]int64, len(t))
+	prev := limit
+	for i, v := range t {
+		if v.Gas.Unaccounted() {
+			continue
+		}
+		used[i] = prev - int64(v.Gas)
+		prev = int64(v.Gas)
+	}
+	return used
+}
+
+// Locate resolves each step's location to the executed instruction in code
+// which must be the full script code as sent to the node.
+func (t Trace) Locate(code micheline.Prim) {
+	for i := range t {
+		t[i].Instruction, _ = code.Locate(t[i].Location)
+	}
+}
+
+// TraceScript executes script like RunScript and additionally returns an execution
+// trace with remaining gas and stack contents after each instruction.
+func (c *Client) TraceScript(ctx context.Context, script micheline.Script, storage, input micheline.Prim, id BlockID) (*TraceScriptResult, error) {
+	return c.TraceScriptWith(ctx, id, RunCodeRequest{
+		Script:  script.Code,
+		Storage: storage,
+		Input:   input,
+	})
+}
+
+// TraceScriptWith executes a script as described by req at block id and returns
+// an execution trace with instructions resolved from the script. When chain id is
+// empty the client's chain id is used.
+func (c *Client) TraceScriptWith(ctx context.Context, id BlockID, req RunCodeRequest) (*TraceScriptResult, error) {
+	if !req.ChainId.IsValid() {
+		req.ChainId = c.ChainId
+	}
+	var res TraceScriptResult
+	if err := c.TraceCode(ctx, id, &req, &res); err != nil {
+		return nil, err
+	}
+	res.Trace.Locate(req.Script.Prim())
+	return &res, nil
+}
@@ -0,0 +1,248 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"blockwatch.cc/tzgo/micheline"
+	"blockwatch.cc/tzgo/tezos"
+)
+
+// bodyRecorder keeps the body of the last request and forwards the request.
+type bodyRecorder struct {
+	next http.RoundTripper
+	body []byte
+}
+
+func (r *bodyRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	r.body = nil
+	if req.Body != nil {
+		buf, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		r.body = buf
+		req.Body = ioutil.NopCloser(strings.NewReader(string(buf)))
+	}
+	return r.next.RoundTrip(req)
+}
+
+const testScript = `[
+	{"prim":"parameter","args":[{"prim":"nat"}]},
+	{"prim":"storage","args":[{"prim":"nat"}]},
+	{"prim":"code","args":[[{"prim":"UNPAIR"},{"prim":"ADD"},{"prim":"NIL","args":[{"prim":"operation"}]},{"prim":"PAIR"}]]}
+]`
+
+func newScriptMock(t *testing.T, path string, result string) (*Client, *bodyRecorder) {
+	t.Helper()
+	m := NewMock()
+	m.On(http.MethodPost, path, []byte(result))
+	rec := &bodyRecorder{next: m}
+	c, err := NewClient("http://mock", &http.Client{Transport: rec})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.ChainId = tezos.Mainnet
+	return c, rec
+}
+
+func testScriptCode(t *testing.T) micheline.Code {
+	t.Helper()
+	var code micheline.Code
+	if err := json.Unmarshal([]byte(testScript), &code); err != nil {
+		t.Fatal(err)
+	}
+	return code
+}
+
+func TestRunScriptWith(t *testing.T) {
+	source := tezos.MustParseAddress("tz1KqTpEZ7Yob7QbPE4Hy4Wo8fHG8LhKxZSx")
+	gas := tezos.N(1000)
+	for _, test := range []struct {
+		name    string
+		req     RunCodeRequest
+		chain   string
+		present []string
+		absent  []string
+	}{
+		{
+			name:   "defaults",
+			chain:  tezos.Mainnet.String(),
+			absent: []string{"source", "payer", "self", "entrypoint", "gas", "now", "level", "unparsing_mode"},
+		},
+		{
+			name:  "explicit chain",
+			req:   RunCodeRequest{ChainId: tezos.Ghostnet},
+			chain: tezos.Ghostnet.String(),
+		},
+		{
+			name:    "options",
+			req:     RunCodeRequest{Source: &source, Entrypoint: "default", Gas: &gas, Mode: UnparsingModeReadable},
+			chain:   tezos.Mainnet.String(),
+			present: []string{"source", "entrypoint", "gas", "unparsing_mode"},
+			absent:  []string{"payer", "self", "now", "level"},
+		},
+	} {
+		c, rec := newScriptMock(t, "chains/main/blocks/head/helpers/scripts/run_code", `{"storage":{"int":"3"},"operations":[]}`)
+		req := test.req
+		req.Script = testScriptCode(t)
+		req.Storage = micheline.NewInt64(0)
+		req.Input = micheline.NewInt64(3)
+		res, err := c.RunScriptWith(context.Background(), Head, req)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if res.Storage.Int == nil || res.Storage.Int.Int64() != 3 || len(res.Operations) != 0 {
+			t.Errorf("%s: unexpected result %+v", test.name, res)
+		}
+		var body map[string]json.RawMessage
+		if err := json.Unmarshal(rec.body, &body); err != nil {
+			t.Fatalf("%s: request %s: %v", test.name, rec.body, err)
+		}
+		if got := strings.Trim(string(body["chain_id"]), `"`); got != test.chain {
+			t.Errorf("%s: chain id %s want %s", test.name, got, test.chain)
+		}
+		for _, key := range append([]string{"script", "storage", "input", "amount", "balance"}, test.present...) {
+			if _, ok := body[key]; !ok {
+				t.Errorf("%s: missing %q in request %s", test.name, key, rec.body)
+			}
+		}
+		for _, key := range test.absent {
+			if _, ok := body[key]; ok {
+				t.Errorf("%s: unexpected %q in request %s", test.name, key, rec.body)
+			}
+		}
+	}
+}
+
+func TestRunScript(t *testing.T) {
+	c, rec := newScriptMock(t, "chains/main/blocks/head/helpers/scripts/run_code", `{"storage":{"int":"5"},"operations":[]}`)
+	script := micheline.Script{Code: testScriptCode(t)}
+	res, err := c.RunScript(context.Background(), script, micheline.NewInt64(2), micheline.NewInt64(3), Head)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Storage.Int == nil || res.Storage.Int.Int64() != 5 {
+		t.Errorf("unexpected storage %s", res.Storage.Dump())
+	}
+	var req RunCodeRequest
+	if err := json.Unmarshal(rec.body, &req); err != nil {
+		t.Fatal(err)
+	}
+	if req.Amount != 0 || req.Balance != 0 || req.Input.Int.Int64() != 3 || req.Storage.Int.Int64() != 2 {
+		t.Errorf("unexpected request %s", rec.body)
+	}
+}
+
+func TestTraceGas(t *testing.T) {
+	for _, test := range []struct {
+		in    string
+		want  TraceGas
+		gas   int64
+		error bool
+	}{
+		{in: "1039.335", want: 1039335, gas: 1040},
+		{in: "12", want: 12000, gas: 12},
+		{in: "0.5", want: 500, gas: 1},
+		{in: "7.01", want: 7010, gas: 8},
+		{in: "unaccounted", want: -1, gas: 0},
+		{in: "1.2345", error: true},
+		{in: "abc", error: true},
+		{in: "1.x", error: true},
+	} {
+		var g TraceGas
+		err := g.UnmarshalText([]byte(test.in))
+		if test.error {
+			if err == nil {
+				t.Errorf("%q: expected error", test.in)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", test.in, err)
+			continue
+		}
+		if g != test.want || g.Gas() != test.gas || g.Unaccounted() != (test.want < 0) {
+			t.Errorf("%q: got %d gas %d want %d gas %d", test.in, g, g.Gas(), test.want, test.gas)
+		}
+	}
+}
+
+func TestTraceStackItem(t *testing.T) {
+	for _, test := range []struct {
+		in    string
+		value string
+		annot string
+	}{
+		{in: `{"item":{"int":"1"},"annot":"@x"}`, value: `{"int":"1"}`, annot: "@x"},
+		{in: `{"item":{"string":"a"}}`, value: `{"string":"a"}`},
+		{in: `{"int":"1"}`, value: `{"int":"1"}`},
+		{in: `{"prim":"Unit"}`, value: `{"prim":"Unit"}`},
+		{in: `[{"int":"1"}]`, value: `[{"int":"1"}]`},
+	} {
+		var item TraceStackItem
+		if err := json.Unmarshal([]byte(test.in), &item); err != nil {
+			t.Errorf("%s: %v", test.in, err)
+			continue
+		}
+		buf, _ := json.Marshal(item.Value)
+		if string(buf) != test.value || item.Annot != test.annot {
+			t.Errorf("%s: got %s %q want %s %q", test.in, buf, item.Annot, test.value, test.annot)
+		}
+	}
+}
+
+func TestTraceGasUsed(t *testing.T) {
+	trace := Trace{{Gas: 9000}, {Gas: -1}, {Gas: 8500}, {Gas: 8500}, {Gas: 7000}}
+	want := []int64{1000, 0, 500, 0, 1500}
+	got := trace.GasUsed(10000)
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("gas used got %v want %v", got, want)
+			break
+		}
+	}
+}
+
+func TestTraceScriptWith(t *testing.T) {
+	c, _ := newScriptMock(t, "chains/main/blocks/head/helpers/scripts/trace_code", `{
+		"storage":{"int":"5"},
+		"operations":[],
+		"trace":[
+			{"location":7,"gas":"1039.335","stack":[{"item":{"prim":"Pair","args":[{"int":"3"},{"int":"2"}]}}]},
+			{"location":8,"gas":"1039.300","stack":[{"item":{"int":"3"}},{"item":{"int":"2"}}]},
+			{"location":11,"gas":"1039.290","stack":[{"item":{"int":"5"}}]},
+			{"location":99,"gas":"unaccounted","stack":[]}
+		]
+	}`)
+	res, err := c.TraceScriptWith(context.Background(), Head, RunCodeRequest{
+		Script:  testScriptCode(t),
+		Storage: micheline.NewInt64(2),
+		Input:   micheline.NewInt64(3),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Storage.Int == nil || res.Storage.Int.Int64() != 5 || len(res.Trace) != 4 {
+		t.Fatalf("unexpected result %+v", res)
+	}
+	// locations count nodes of the full script in pre-order
+	for i, want := range []micheline.OpCode{micheline.I_UNPAIR, micheline.I_ADD, micheline.I_PAIR} {
+		if got := res.Trace[i].Instruction.OpCode; got != want {
+			t.Errorf("step %d: instruction %s want %s", i, got, want)
+		}
+	}
+	if res.Trace[3].Instruction.IsValid() {
+		t.Errorf("unknown location resolved to %s", res.Trace[3].Instruction.Dump())
+	}
+	if len(res.Trace[1].Stack) != 2 || res.Trace[1].Gas != 1039300 || !res.Trace[3].Gas.Unaccounted() {
+		t.Errorf("unexpected step %+v", res.Trace[1])
+	}
+}
@@ -0,0 +1,157 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"blockwatch.cc/tzgo/codec"
+	"blockwatch.cc/tzgo/signer"
+	"blockwatch.cc/tzgo/tezos"
+)
+
+// ErrNoSigner is returned by Send when neither the client nor call options
+// define a signer.
+var ErrNoSigner = errors.New("rpc: missing signer")
+
+// CallOptions control how Send completes, signs and broadcasts operations.
+type CallOptions struct {
+	Confirmations int64         // number of confirmations to wait after broadcast
+	TTL           int64         // max number of blocks to wait in total
+	Limits        tezos.Limits  // optional gas, storage and fee limits to override estimations
+	MaxFee        int64         // max acceptable fee, optional (default = 0)
+	Signer        signer.Signer // optional signer interface to use for signing the transaction
+	Observer      *Observer     // optional custom block observer for waiting on confirmations
+	NoAutoReveal  bool          // don't prepend a reveal when the sender key is unrevealed
+	Preapply      bool          // preapply the signed operation and refuse to broadcast on failure
+}
+
+var DefaultOptions = CallOptions{
+	Confirmations: 6,
+	TTL:           120,
+	MaxFee:        1000000,
+}
+
+// Send completes op with branch, counters and, unless disabled, a reveal for
+// unrevealed senders, then simulates, signs, optionally preapplies and
+// broadcasts op and waits for confirmations. When opts is nil DefaultOptions are used.
+func (c *Client) Send(ctx context.Context, op *codec.Op, opts *CallOptions) (*Receipt, error) {
+	if opts == nil {
+		opts = &DefaultOptions
+	}
+
+	signer := c.Signer
+	if opts.Signer != nil {
+		signer = opts.Signer
+	}
+	if signer == nil {
+		return nil, ErrNoSigner
+	}
+
+	key, err := signer.Key(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// set source on all ops
+	op.WithSource(key.Address())
+
+	// auto-complete op with branch/ttl, source counter, reveal
+	if err := c.complete(ctx, op, key, !opts.NoAutoReveal); err != nil {
+		return nil, err
+	}
+
+	// simulate to check tx validity and estimate cost
+	sim, err := c.Simulate(ctx, op)
+	if err != nil {
+		return nil, err
+	}
+
+	// apply simulated cost as limits to tx list
+	op.WithLimits(sim.MapLimits(), GasSafetyMargin)
+
+	// check minFee calc against maxFee if set
+	if opts.MaxFee > 0 {
+		if l := op.Limits(); l.Fee > opts.MaxFee {
+			return nil, fmt.Errorf("estimated cost %d > max %d", l.Fee, opts.MaxFee)
+		}
+	}
+
+	// sign digest
+	sig, err := signer.SignOperation(ctx, op)
+	if err != nil {
+		return nil, err
+	}
+	op.WithSignature(sig)
+
+	// check the signed operation would apply
+	if opts.Preapply {
+		pre, err := c.PreapplyOperation(ctx, op, Head)
+		if err != nil {
+			return nil, err
+		}
+		if err := pre.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	// broadcast
+	hash, err := c.Broadcast(ctx, op)
+	if err != nil {
+		return nil, err
+	}
+	return c.waitReceipt(ctx, hash, opts)
+}
+
+// Activate claims the balance of a fundraiser account by broadcasting an
+// activate_account operation for the tz1 address pkh with its activation
+// secret and waits for confirmations. Activations need no signer and pay no
+// fees. To use the account afterwards send a reveal signed with its key.
+// When opts is nil DefaultOptions are used.
+//
+//	acc, _ := tezos.LoadFaucetAccount("faucet.json")
+//	rcpt, err := c.Activate(ctx, acc.Pkh, acc.Secret, nil)
+//	...
+//	op := codec.NewOp().WithReveal(acc.Key.Public())
+//	rcpt, err = c.Send(ctx, op, &rpc.CallOptions{Signer: signer.NewFromKey(acc.Key)})
+func (c *Client) Activate(ctx context.Context, pkh tezos.Address, secret []byte, opts *CallOptions) (*Receipt, error) {
+	if opts == nil {
+		opts = &DefaultOptions
+	}
+	op := codec.NewOp().WithActivation(pkh, secret)
+	if err := c.complete(ctx, op, tezos.InvalidKey, false); err != nil {
+		return nil, err
+	}
+	if _, err := op.Build(); err != nil {
+		return nil, err
+	}
+	hash, err := c.Broadcast(ctx, op)
+	if err != nil {
+		return nil, err
+	}
+	return c.waitReceipt(ctx, hash, opts)
+}
+
+// waitReceipt waits for confirmations of operation hash and returns its receipt.
+func (c *Client) waitReceipt(ctx context.Context, hash tezos.OpHash, opts *CallOptions) (*Receipt, error) {
+	res := NewResult(hash).WithTTL(opts.TTL).WithConfirmations(opts.Confirmations)
+
+	// use custom observer when provided
+	mon := c.BlockObserver
+	if opts.Observer != nil {
+		mon = opts.Observer
+	}
+
+	// wait for confirmations
+	res.Listen(mon)
+	res.WaitContext(ctx)
+	if err := res.Err(); err != nil {
+		return nil, err
+	}
+
+	// return receipt
+	return res.GetReceipt(ctx)
+}
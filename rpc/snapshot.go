@@ -0,0 +1,325 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"blockwatch.cc/tzgo/micheline"
+	"blockwatch.cc/tzgo/tezos"
+)
+
+// BigmapEntry is a single key/value pair of a bigmap snapshot.
+type BigmapEntry struct {
+	Hash  tezos.ExprHash
+	Value micheline.Prim
+}
+
+// BigmapProgressFunc is called after each delivered snapshot entry with the
+// number of entries delivered so far and the total number of entries.
+type BigmapProgressFunc func(done, total int)
+
+// BigmapSnapshot iterates over all entries of a bigmap at a fixed block. Values
+// are fetched concurrently by a bounded pool of workers and delivered in
+// arbitrary order. After a failure the snapshot can be resumed and will only
+// fetch entries that have not been delivered yet.
+//
+//	snap, err := c.GetBigmapSnapshot(ctx, id, Head, 8)
+//	for snap.Next(ctx) {
+//	    e := snap.Entry()
+//	}
+//	if err := snap.Err(); err != nil {
+//	    // handle error, optionally call snap.Resume() and continue
+//	}
+type BigmapSnapshot struct {
+	Bigmap int64           // bigmap id
+	Block  tezos.BlockHash // pinned block hash all values are read from
+	Keys   []tezos.ExprHash
+
+	c           *Client
+	concurrency int
+	retries     int
+	progress    BigmapProgressFunc
+	done        []bool
+	ndone       int
+	results     chan bigmapResult
+	cancel      context.CancelFunc
+	entry       BigmapEntry
+	err         error
+}
+
+type bigmapResult struct {
+	idx   int
+	value micheline.Prim
+	err   error
+}
+
+// GetBigmapSnapshot lists all keys of bigmap at block id and returns a snapshot
+// iterator that fetches values using up to concurrency parallel requests. The block
+// id is resolved to a block hash first so that all values are read from the same
+// context even when id is relative to head.
+func (c *Client) GetBigmapSnapshot(ctx context.Context, bigmap int64, id BlockID, concurrency int) (*BigmapSnapshot, error) {
+	hash, err := c.GetBlockHash(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := c.ListBigmapKeys(ctx, bigmap, hash)
+	if err != nil {
+		return nil, err
+	}
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	return &BigmapSnapshot{
+		Bigmap:      bigmap,
+		Block:       hash,
+		Keys:        keys,
+		c:           c,
+		concurrency: concurrency,
+		retries:     3,
+		done:        make([]bool, len(keys)),
+	}, nil
+}
+
+// WithProgress registers a callback that is invoked after each delivered entry.
+func (s *BigmapSnapshot) WithProgress(fn BigmapProgressFunc) *BigmapSnapshot {
+	s.progress = fn
+	return s
+}
+
+// WithRetries sets how often a failed value request is retried before the
+// snapshot stops with an error. Default is 3.
+func (s *BigmapSnapshot) WithRetries(n int) *BigmapSnapshot {
+	s.retries = n
+	return s
+}
+
+// Len returns the total number of entries in the snapshot.
+func (s *BigmapSnapshot) Len() int {
+	return len(s.Keys)
+}
+
+// Done returns the number of entries delivered so far.
+func (s *BigmapSnapshot) Done() int {
+	return s.ndone
+}
+
+// Next fetches the next entry and returns true on success. It returns false when
+// all entries were delivered or an error occured. Use Err to distinguish both cases.
+func (s *BigmapSnapshot) Next(ctx context.Context) bool {
+	if s.err != nil || s.ndone == len(s.Keys) {
+		return false
+	}
+	if s.results == nil {
+		s.start(ctx)
+	}
+	select {
+	case <-ctx.Done():
+		s.err = ctx.Err()
+		s.stop()
+		return false
+	case r, ok := <-s.results:
+		if !ok {
+			s.stop()
+			return false
+		}
+		if r.err != nil {
+			s.err = r.err
+			s.stop()
+			return false
+		}
+		s.done[r.idx] = true
+		s.ndone++
+		s.entry = BigmapEntry{
+			Hash:  s.Keys[r.idx],
+			Value: r.value,
+		}
+		if s.progress != nil {
+			s.progress(s.ndone, len(s.Keys))
+		}
+		return true
+	}
+}
+
+// Entry returns the entry fetched by the last successful call to Next.
+func (s *BigmapSnapshot) Entry() BigmapEntry {
+	return s.entry
+}
+
+// Err returns the error that stopped iteration, if any.
+func (s *BigmapSnapshot) Err() error {
+	return s.err
+}
+
+// Resume clears a previous error so that the next call to Next continues
+// fetching all entries that have not been delivered yet.
+func (s *BigmapSnapshot) Resume() {
+	s.err = nil
+}
+
+// Close stops all running workers.
+func (s *BigmapSnapshot) Close() {
+	s.stop()
+}
+
+func (s *BigmapSnapshot) start(ctx context.Context) {
+	pending := make([]int, 0, len(s.Keys)-s.ndone)
+	for i, done := range s.done {
+		if !done {
+			pending = append(pending, i)
+		}
+	}
+	ctx, s.cancel = context.WithCancel(ctx)
+	jobs := make(chan int)
+	results := make(chan bigmapResult, s.concurrency)
+	s.results = results
+
+	// dispatcher
+	go func() {
+		defer close(jobs)
+		for _, idx := range pending {
+			select {
+			case <-ctx.Done():
+				return
+			case jobs <- idx:
+			}
+		}
+	}()
+
+	// workers
+	var wg sync.WaitGroup
+	for i := 0; i < s.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range jobs {
+				val, err := s.fetch(ctx, s.Keys[idx])
+				select {
+				case <-ctx.Done():
+					return
+				case results <- bigmapResult{idx: idx, value: val, err: err}:
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+}
+
+func (s *BigmapSnapshot) stop() {
+	if s.results == nil {
+		return
+	}
+	s.cancel()
+	// drain until all workers have exited
+	for range s.results {
+	}
+	s.results = nil
+	s.cancel = nil
+}
+
+func (s *BigmapSnapshot) fetch(ctx context.Context, key tezos.ExprHash) (micheline.Prim, error) {
+	for i := 0; ; i++ {
+		val, err := s.c.GetBigmapValue(ctx, s.Bigmap, key, s.Block)
+		if err == nil || i >= s.retries {
+			return val, err
+		}
+		// linear backoff, but return on cancel
+		select {
+		case <-ctx.Done():
+			return val, ctx.Err()
+		case <-time.After(time.Duration(i+1) * time.Second):
+		}
+	}
+}
+
+// BigmapValuesError collects per-key errors of GetBigmapValues keyed by the
+// base58 encoded key hash.
+type BigmapValuesError map[string]error
+
+// Error reports the number of failed keys and the error of the first failed
+// key in sort order.
+func (e BigmapValuesError) Error() string {
+	if len(e) == 0 {
+		return "rpc: fetching bigmap values failed"
+	}
+	keys := make([]string, 0, len(e))
+	for k := range e {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return fmt.Sprintf("rpc: fetching %d bigmap values failed, e.g. %s: %v", len(e), keys[0], e[keys[0]])
+}
+
+// GetBigmapValues fetches values for many key hashes from bigmap at block id
+// using up to concurrency parallel requests. Results are keyed by the base58
+// encoded key hash because ExprHash is not comparable. The block id is resolved
+// to a block hash first so that all values are read from the same block even
+// when id is relative to head. When some keys fail the values fetched so far
+// are returned together with a BigmapValuesError.
+func (c *Client) GetBigmapValues(ctx context.Context, bigmap int64, hashes []tezos.ExprHash, id BlockID, concurrency int) (map[string]micheline.Prim, error) {
+	vals := make(map[string]micheline.Prim, len(hashes))
+	if len(hashes) == 0 {
+		return vals, nil
+	}
+	hash, ok := id.(tezos.BlockHash)
+	if !ok {
+		var err error
+		if hash, err = c.GetBlockHash(ctx, id); err != nil {
+			return nil, err
+		}
+	}
+	var (
+		mu    sync.Mutex
+		wg    sync.WaitGroup
+		errs  = make(BigmapValuesError)
+		jobs  = make(chan tezos.ExprHash)
+		nwork = concurrency
+	)
+	if nwork < 1 {
+		nwork = 1
+	}
+	if nwork > len(hashes) {
+		nwork = len(hashes)
+	}
+	for i := 0; i < nwork; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for h := range jobs {
+				val, err := c.GetBigmapValue(ctx, bigmap, h, hash)
+				mu.Lock()
+				if err != nil {
+					errs[h.String()] = err
+				} else {
+					vals[h.String()] = val
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+dispatch:
+	for _, h := range hashes {
+		select {
+		case <-ctx.Done():
+			break dispatch
+		case jobs <- h:
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return vals, err
+	}
+	if len(errs) > 0 {
+		return vals, errs
+	}
+	return vals, nil
+}
@@ -0,0 +1,231 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"blockwatch.cc/tzgo/micheline"
+	"blockwatch.cc/tzgo/tezos"
+)
+
+var testSnapshotBlock = tezos.MustParseBlockHash("BLockGenesisGenesisGenesisGenesisGenesisf79b5d1CoW2")
+
+func testValueKey(key byte) tezos.ExprHash {
+	buf := make([]byte, 32)
+	buf[0] = key
+	return tezos.NewExprHash(buf)
+}
+
+// testBigmapValues registers values for keys 1..n of bigmap 42 at the test
+// block. Value of key i is i*10.
+func testBigmapValues(m *Mock, n int) []tezos.ExprHash {
+	keys := make([]tezos.ExprHash, n)
+	for i := range keys {
+		keys[i] = testValueKey(byte(i + 1))
+		m.On(http.MethodGet, fmt.Sprintf("chains/main/blocks/%s/context/big_maps/42/%s", testSnapshotBlock, keys[i]), micheline.NewInt64(int64(i+1)*10))
+	}
+	return keys
+}
+
+func TestGetBigmapValues(t *testing.T) {
+	for _, test := range []struct {
+		name        string
+		id          BlockID
+		keys        int
+		missing     []int
+		concurrency int
+	}{
+		{name: "head", id: Head, keys: 5, concurrency: 2},
+		{name: "hash", id: testSnapshotBlock, keys: 5, concurrency: 8},
+		{name: "zero concurrency", id: Head, keys: 3, concurrency: 0},
+		{name: "missing", id: Head, keys: 4, missing: []int{1, 3}, concurrency: 3},
+	} {
+		m := NewMock()
+		m.On(http.MethodGet, "chains/main/blocks/head/hash", testSnapshotBlock)
+		keys := testBigmapValues(m, test.keys)
+		for _, i := range test.missing {
+			keys = append(keys, testValueKey(byte(100+i)))
+		}
+		c, err := m.Client()
+		if err != nil {
+			t.Fatal(err)
+		}
+		vals, err := c.GetBigmapValues(context.Background(), 42, keys, test.id, test.concurrency)
+		if len(test.missing) > 0 {
+			var verr BigmapValuesError
+			if !errors.As(err, &verr) || len(verr) != len(test.missing) {
+				t.Errorf("%s: unexpected error %v", test.name, err)
+			}
+		} else if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if len(vals) != test.keys {
+			t.Errorf("%s: got %d values want %d", test.name, len(vals), test.keys)
+		}
+		for i, k := range keys[:test.keys] {
+			if v := vals[k.String()]; v.Int == nil || v.Int.Int64() != int64(i+1)*10 {
+				t.Errorf("%s: key %d: unexpected value %s", test.name, i+1, v.Dump())
+			}
+		}
+		// the block id is resolved once and all values are read from its hash
+		var nhash int
+		for _, r := range m.Requests() {
+			switch {
+			case strings.HasSuffix(r, "/hash"):
+				nhash++
+			case !strings.Contains(r, testSnapshotBlock.String()):
+				t.Errorf("%s: request %q not pinned to block hash", test.name, r)
+			}
+		}
+		if _, isHash := test.id.(tezos.BlockHash); isHash && nhash != 0 || !isHash && nhash != 1 {
+			t.Errorf("%s: resolved block id %d times", test.name, nhash)
+		}
+	}
+}
+
+func TestGetBigmapValuesResolveError(t *testing.T) {
+	m := NewMock()
+	keys := testBigmapValues(m, 2)
+	c, err := m.Client()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.GetBigmapValues(context.Background(), 42, keys, Head, 2); ErrorStatus(err) != http.StatusNotFound {
+		t.Errorf("unexpected error %v", err)
+	}
+	if n := len(m.Requests()); n != 1 {
+		t.Errorf("sent %d requests after failed resolve", n)
+	}
+}
+
+func TestBigmapValuesError(t *testing.T) {
+	errs := BigmapValuesError{
+		"exprc": errors.New("c"),
+		"expra": errors.New("a"),
+		"exprb": errors.New("b"),
+	}
+	want := "rpc: fetching 3 bigmap values failed, e.g. expra: a"
+	for i := 0; i < 10; i++ {
+		if got := errs.Error(); got != want {
+			t.Fatalf("got %q want %q", got, want)
+		}
+	}
+	if got, want := (BigmapValuesError{}).Error(), "rpc: fetching bigmap values failed"; got != want {
+		t.Errorf("empty: got %q want %q", got, want)
+	}
+}
+
+func TestBigmapSnapshot(t *testing.T) {
+	for _, test := range []struct {
+		name        string
+		keys        int
+		concurrency int
+	}{
+		{name: "empty", keys: 0, concurrency: 4},
+		{name: "sequential", keys: 5, concurrency: 1},
+		{name: "parallel", keys: 20, concurrency: 4},
+		{name: "more workers than keys", keys: 3, concurrency: 8},
+		{name: "zero concurrency", keys: 3, concurrency: 0},
+	} {
+		m := NewMock()
+		m.On(http.MethodGet, "chains/main/blocks/head/hash", testSnapshotBlock)
+		keys := testBigmapValues(m, test.keys)
+		m.On(http.MethodGet, fmt.Sprintf("chains/main/blocks/%s/context/raw/json/big_maps/index/42/contents", testSnapshotBlock), keys)
+		c, err := m.Client()
+		if err != nil {
+			t.Fatal(err)
+		}
+		ctx := context.Background()
+		snap, err := c.GetBigmapSnapshot(ctx, 42, Head, test.concurrency)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if !snap.Block.Equal(testSnapshotBlock) || snap.Len() != test.keys {
+			t.Errorf("%s: block %s len %d", test.name, snap.Block, snap.Len())
+		}
+		var progress []int
+		snap.WithProgress(func(done, total int) {
+			if total != test.keys {
+				t.Errorf("%s: progress total %d", test.name, total)
+			}
+			progress = append(progress, done)
+		})
+		seen := make(map[string]int64)
+		for snap.Next(ctx) {
+			e := snap.Entry()
+			seen[e.Hash.String()] = e.Value.Int.Int64()
+		}
+		if err := snap.Err(); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if len(seen) != test.keys || snap.Done() != test.keys {
+			t.Errorf("%s: delivered %d done %d", test.name, len(seen), snap.Done())
+		}
+		for i, k := range keys {
+			if v := seen[k.String()]; v != int64(i+1)*10 {
+				t.Errorf("%s: key %d: value %d", test.name, i+1, v)
+			}
+		}
+		for i, n := range progress {
+			if n != i+1 {
+				t.Errorf("%s: progress %v", test.name, progress)
+				break
+			}
+		}
+		for _, r := range m.Requests() {
+			if strings.Contains(r, "/context/") && !strings.Contains(r, testSnapshotBlock.String()) {
+				t.Errorf("%s: request %q not pinned to block hash", test.name, r)
+			}
+		}
+	}
+}
+
+func TestBigmapSnapshotResume(t *testing.T) {
+	m := NewMock()
+	keys := testBigmapValues(m, 4)
+	missing := testValueKey(5)
+	keys = append(keys, missing)
+	m.On(http.MethodGet, fmt.Sprintf("chains/main/blocks/%s/hash", testSnapshotBlock), testSnapshotBlock)
+	m.On(http.MethodGet, fmt.Sprintf("chains/main/blocks/%s/context/raw/json/big_maps/index/42/contents", testSnapshotBlock), keys)
+	c, err := m.Client()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	snap, err := c.GetBigmapSnapshot(ctx, 42, testSnapshotBlock, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	snap.WithRetries(0)
+	for snap.Next(ctx) {
+	}
+	if ErrorStatus(snap.Err()) != http.StatusNotFound || snap.Done() != 4 {
+		t.Fatalf("unexpected error %v after %d entries", snap.Err(), snap.Done())
+	}
+	// errors are sticky until resumed
+	if snap.Next(ctx) {
+		t.Errorf("next succeeded without resume")
+	}
+
+	m.On(http.MethodGet, fmt.Sprintf("chains/main/blocks/%s/context/big_maps/42/%s", testSnapshotBlock, missing), micheline.NewInt64(50))
+	before := len(m.Requests())
+	snap.Resume()
+	if !snap.Next(ctx) || snap.Entry().Hash.String() != missing.String() {
+		t.Fatalf("resume: unexpected entry %s %v", snap.Entry().Hash, snap.Err())
+	}
+	if snap.Next(ctx) || snap.Err() != nil || snap.Done() != 5 {
+		t.Errorf("resume: done %d err %v", snap.Done(), snap.Err())
+	}
+	// only the failed key is fetched again
+	if n := len(m.Requests()) - before; n != 1 {
+		t.Errorf("resume sent %d requests", n)
+	}
+	snap.Close()
+}
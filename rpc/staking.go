@@ -0,0 +1,336 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"blockwatch.cc/tzgo/codec"
+	"blockwatch.cc/tzgo/tezos"
+)
+
+// Staker is an external staker of a delegate (v018+).
+type Staker struct {
+	Staker         tezos.Address `json:"staker"`
+	FrozenDeposits tezos.Mutez   `json:"frozen_deposits"`
+}
+
+// UnstakeRequests lists pending unstake requests of a staker (v018+).
+type UnstakeRequests struct {
+	// requests that can be finalized now
+	Finalizable []FinalizableRequest `json:"finalizable"`
+	// requests still waiting for the unstake delay to pass
+	Unfinalizable UnfinalizableRequests `json:"unfinalizable"`
+}
+
+// FinalizableRequest is an unstake request that can be finalized.
+type FinalizableRequest struct {
+	Delegate tezos.Address `json:"delegate"`
+	Cycle    int64         `json:"cycle"`
+	Amount   tezos.Mutez   `json:"amount"`
+}
+
+// UnfinalizableRequests are unstake requests still frozen at delegate.
+type UnfinalizableRequests struct {
+	Delegate tezos.Address    `json:"delegate"`
+	Requests []UnstakeRequest `json:"requests"`
+}
+
+// UnstakeRequest is a pending unstake request created in cycle.
+type UnstakeRequest struct {
+	Cycle  int64       `json:"cycle"`
+	Amount tezos.Mutez `json:"amount"`
+}
+
+// FinalizableAmount returns the total amount that can be finalized.
+func (r UnstakeRequests) FinalizableAmount() tezos.Mutez {
+	var sum tezos.Mutez
+	for _, v := range r.Finalizable {
+		sum += v.Amount
+	}
+	return sum
+}
+
+// PendingAmount returns the total amount still waiting to become finalizable.
+func (r UnstakeRequests) PendingAmount() tezos.Mutez {
+	var sum tezos.Mutez
+	for _, v := range r.Unfinalizable.Requests {
+		sum += v.Amount
+	}
+	return sum
+}
+
+// ExpectedIssuance contains expected reward amounts for a future cycle (v018+).
+type ExpectedIssuance struct {
+	Cycle                    int64       `json:"cycle"`
+	BakingRewardFixedPortion tezos.Mutez `json:"baking_reward_fixed_portion"`
+	BakingRewardBonusPerSlot tezos.Mutez `json:"baking_reward_bonus_per_slot"`
+	AttestingRewardPerSlot   tezos.Mutez `json:"attesting_reward_per_slot"`
+	LiquidityBakingSubsidy   tezos.Mutez `json:"liquidity_baking_subsidy"`
+	SeedNonceRevelationTip   tezos.Mutez `json:"seed_nonce_revelation_tip"`
+	VdfRevelationTip         tezos.Mutez `json:"vdf_revelation_tip"`
+}
+
+// StakingEntrypoint returns the staking entrypoint called by transaction t
+// or an empty string when t is not a stake, unstake or finalize_unstake
+// pseudo-operation (v018+).
+func (t Transaction) StakingEntrypoint() string {
+	if t.Parameters == nil || !t.Source.Equal(t.Destination) || !codec.IsStakingEntrypoint(t.Parameters.Entrypoint) {
+		return ""
+	}
+	return t.Parameters.Entrypoint
+}
+
+// IsStaking returns true when t is a staking pseudo-operation.
+func (t Transaction) IsStaking() bool {
+	return t.StakingEntrypoint() != ""
+}
+
+// StakedAmount returns the amount moved by a staking pseudo-operation as
+// reported in its receipt, i.e. the amount frozen by stake, the amount moved
+// to unstaked deposits by unstake and the amount returned to the spendable
+// balance by finalize_unstake. Unlike the transaction amount this is the
+// effective amount, e.g. when unstaking everything. Returns zero for other
+// transactions and failed operations.
+func (t Transaction) StakedAmount() int64 {
+	if t.Metadata.Result.Status != tezos.OpStatusApplied {
+		return 0
+	}
+	var sum int64
+	for _, v := range t.Metadata.Result.BalanceUpdates {
+		if v.Change <= 0 {
+			continue
+		}
+		switch t.StakingEntrypoint() {
+		case codec.EntrypointStake:
+			if v.Kind == BalanceKindFreezer && v.Category == BalanceCategoryDeposits {
+				sum += v.Change
+			}
+		case codec.EntrypointUnstake:
+			if v.Kind == BalanceKindFreezer && v.Category == BalanceCategoryUnstakedDeposits {
+				sum += v.Change
+			}
+		case codec.EntrypointFinalizeUnstake:
+			if v.Kind == BalanceKindContract && v.Contract.Equal(t.Source) {
+				sum += v.Change
+			}
+		}
+	}
+	return sum
+}
+
+// GetDelegateStakers returns external stakers of delegate addr at block id.
+func (c *Client) GetDelegateStakers(ctx context.Context, addr tezos.Address, id BlockID) ([]Staker, error) {
+	u := fmt.Sprintf("chains/main/blocks/%s/context/delegates/%s/stakers", id, addr)
+	stakers := make([]Staker, 0)
+	if err := c.Get(ctx, u, &stakers); err != nil {
+		return nil, err
+	}
+	return stakers, nil
+}
+
+// GetDelegateStakingDenominator returns the total number of staking pseudotokens
+// issued by delegate addr at block id.
+func (c *Client) GetDelegateStakingDenominator(ctx context.Context, addr tezos.Address, id BlockID) (tezos.Z, error) {
+	u := fmt.Sprintf("chains/main/blocks/%s/context/delegates/%s/staking_denominator", id, addr)
+	var z tezos.Z
+	err := c.Get(ctx, u, &z)
+	return z, err
+}
+
+// GetContractStakedBalance returns the staked balance of addr at block id.
+func (c *Client) GetContractStakedBalance(ctx context.Context, addr tezos.Address, id BlockID) (tezos.Mutez, error) {
+	return c.getContractMutez(ctx, addr, id, "staked_balance")
+}
+
+// GetContractStakedBalanceBig returns the staked balance of addr at block id
+// as big integer.
+func (c *Client) GetContractStakedBalanceBig(ctx context.Context, addr tezos.Address, id BlockID) (*big.Int, error) {
+	u := fmt.Sprintf("chains/main/blocks/%s/context/contracts/%s/staked_balance", id, addr)
+	return c.getBig(ctx, u)
+}
+
+// GetContractUnstakedFrozenBalance returns the balance of addr that is unstaked
+// but still frozen at block id.
+func (c *Client) GetContractUnstakedFrozenBalance(ctx context.Context, addr tezos.Address, id BlockID) (tezos.Mutez, error) {
+	return c.getContractMutez(ctx, addr, id, "unstaked_frozen_balance")
+}
+
+// GetContractUnstakedFinalizableBalance returns the balance of addr that is
+// unstaked and can be finalized at block id.
+func (c *Client) GetContractUnstakedFinalizableBalance(ctx context.Context, addr tezos.Address, id BlockID) (tezos.Mutez, error) {
+	return c.getContractMutez(ctx, addr, id, "unstaked_finalizable_balance")
+}
+
+func (c *Client) getContractMutez(ctx context.Context, addr tezos.Address, id BlockID, field string) (tezos.Mutez, error) {
+	u := fmt.Sprintf("chains/main/blocks/%s/context/contracts/%s/%s", id, addr, field)
+	return c.getMutez(ctx, u)
+}
+
+// GetUnstakeRequests returns pending unstake requests of addr at block id. When
+// addr has no requests the result is nil.
+func (c *Client) GetUnstakeRequests(ctx context.Context, addr tezos.Address, id BlockID) (*UnstakeRequests, error) {
+	u := fmt.Sprintf("chains/main/blocks/%s/context/contracts/%s/unstake_requests", id, addr)
+	var req *UnstakeRequests
+	if err := c.Get(ctx, u, &req); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// GetExpectedIssuance returns expected rewards for the current and upcoming
+// cycles at block id.
+func (c *Client) GetExpectedIssuance(ctx context.Context, id BlockID) ([]ExpectedIssuance, error) {
+	u := fmt.Sprintf("chains/main/blocks/%s/context/issuance/expected_issuance", id)
+	list := make([]ExpectedIssuance, 0)
+	if err := c.Get(ctx, u, &list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// FrozenDeposits is the breakdown of a delegate's frozen stake at a block.
+// Before v018 all deposits are owned by the delegate.
+type FrozenDeposits struct {
+	Delegate tezos.Address
+	Block    string      // hash of the block the breakdown was read at
+	Total    tezos.Mutez // own and external staked balance
+	Own      tezos.Mutez // staked by the delegate
+	External tezos.Mutez // staked by external stakers (v018+)
+	Unstaked tezos.Mutez // unstake requests that are still frozen (v018+)
+}
+
+// StakingBalance is the breakdown of a delegate's staking balance at a block
+// into staked and delegated (i.e. spendable but unstaked) parts.
+type StakingBalance struct {
+	Delegate          tezos.Address
+	Block             string      // hash of the block the breakdown was read at
+	Total             tezos.Mutez // staked and delegated balance
+	OwnStaked         tezos.Mutez // staked by the delegate
+	ExternalStaked    tezos.Mutez // staked by external stakers (v018+)
+	OwnDelegated      tezos.Mutez // the delegate's unstaked balance
+	ExternalDelegated tezos.Mutez // balances of delegators
+}
+
+// Staked returns the total staked balance.
+func (b StakingBalance) Staked() tezos.Mutez {
+	return b.OwnStaked + b.ExternalStaked
+}
+
+// Delegated returns the total delegated balance.
+func (b StakingBalance) Delegated() tezos.Mutez {
+	return b.OwnDelegated + b.ExternalDelegated
+}
+
+// delegateStake contains stake related fields of delegate info from all
+// protocol versions. Fields are nil when missing.
+type delegateStake struct {
+	// v012+
+	FullBalance *tezos.Mutez `json:"full_balance"`
+
+	// v018+
+	UnstakedPerCycle []struct {
+		Cycle   int64       `json:"cycle"`
+		Deposit tezos.Mutez `json:"deposit"`
+	} `json:"total_unstaked_per_cycle"`
+
+	// v021+
+	TotalStaked       *tezos.Mutez `json:"total_staked"`
+	TotalDelegated    *tezos.Mutez `json:"total_delegated"`
+	OwnStaked         *tezos.Mutez `json:"own_staked"`
+	OwnDelegated      *tezos.Mutez `json:"own_delegated"`
+	ExternalStaked    *tezos.Mutez `json:"external_staked"`
+	ExternalDelegated *tezos.Mutez `json:"external_delegated"`
+}
+
+func mutezOrZero(m *tezos.Mutez) tezos.Mutez {
+	if m == nil {
+		return 0
+	}
+	return *m
+}
+
+// getStakingBalance reads delegate info and splits stake into own and external
+// parts. Before v021 frozen deposits, staking balance and the delegate's own
+// staked balance are read separately, so id is resolved to a block hash first
+// to read all values from the same block.
+func (c *Client) getStakingBalance(ctx context.Context, addr tezos.Address, id BlockID) (*StakingBalance, *delegateStake, error) {
+	hash, err := c.GetBlockHash(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	var info delegateStake
+	u := fmt.Sprintf("chains/main/blocks/%s/context/delegates/%s", hash, addr)
+	if err := c.Get(ctx, u, &info); err != nil {
+		return nil, nil, err
+	}
+	b := &StakingBalance{
+		Delegate: addr,
+		Block:    hash.String(),
+	}
+	if info.TotalStaked != nil {
+		b.OwnStaked = mutezOrZero(info.OwnStaked)
+		b.ExternalStaked = mutezOrZero(info.ExternalStaked)
+		b.OwnDelegated = mutezOrZero(info.OwnDelegated)
+		b.ExternalDelegated = mutezOrZero(info.ExternalDelegated)
+		b.Total = b.Staked() + b.Delegated()
+		return b, &info, nil
+	}
+	staked, err := c.GetDelegateFrozenDeposits(ctx, addr, hash)
+	if err != nil {
+		return nil, nil, err
+	}
+	total, err := c.GetDelegateStakingBalance(ctx, addr, hash)
+	if err != nil {
+		return nil, nil, err
+	}
+	own, err := c.GetContractStakedBalance(ctx, addr, hash)
+	switch {
+	case err == nil && own <= staked:
+		b.OwnStaked = own
+		b.ExternalStaked = staked - own
+	case err == nil, ErrorStatus(err) == 404:
+		// before v018 all deposits are owned by the delegate
+		b.OwnStaked = staked
+	default:
+		return nil, nil, err
+	}
+	b.Total = total
+	if full := mutezOrZero(info.FullBalance); full > b.OwnStaked {
+		b.OwnDelegated = full - b.OwnStaked
+	}
+	if rest := b.Total - b.Staked() - b.OwnDelegated; rest > 0 {
+		b.ExternalDelegated = rest
+	}
+	return b, &info, nil
+}
+
+// GetStakingBalance returns the breakdown of delegate addr's staking balance
+// into own and external staked and delegated balances at block id (v012+).
+func (c *Client) GetStakingBalance(ctx context.Context, addr tezos.Address, id BlockID) (*StakingBalance, error) {
+	b, _, err := c.getStakingBalance(ctx, addr, id)
+	return b, err
+}
+
+// GetFrozenDeposits returns the breakdown of delegate addr's frozen deposits
+// into own and external stake and frozen unstake requests at block id (v012+).
+func (c *Client) GetFrozenDeposits(ctx context.Context, addr tezos.Address, id BlockID) (*FrozenDeposits, error) {
+	b, info, err := c.getStakingBalance(ctx, addr, id)
+	if err != nil {
+		return nil, err
+	}
+	d := &FrozenDeposits{
+		Delegate: addr,
+		Block:    b.Block,
+		Total:    b.Staked(),
+		Own:      b.OwnStaked,
+		External: b.ExternalStaked,
+	}
+	for _, v := range info.UnstakedPerCycle {
+		d.Unstaked += v.Deposit
+	}
+	return d, nil
+}
@@ -0,0 +1,89 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package rpc
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"blockwatch.cc/tzgo/tezos"
+)
+
+func TestGetStakingBalance(t *testing.T) {
+	const hash = "BLockGenesisGenesisGenesisGenesisGenesisf79b5d1CoW2"
+	baker := tezos.MustParseAddress("tz1KqTpEZ7Yob7QbPE4Hy4Wo8fHG8LhKxZSx")
+	base := "chains/main/blocks/" + hash + "/context/"
+	ctx := context.Background()
+
+	// v018: totals and own stake are read from the resolved block only
+	m := NewMock()
+	m.On(http.MethodGet, "chains/main/blocks/head/hash", hash)
+	m.On(http.MethodGet, base+"delegates/"+baker.String(), map[string]interface{}{
+		"full_balance": "7000",
+		"total_unstaked_per_cycle": []map[string]interface{}{
+			{"cycle": 700, "deposit": "50"},
+			{"cycle": 701, "deposit": "25"},
+		},
+	})
+	m.On(http.MethodGet, base+"delegates/"+baker.String()+"/frozen_deposits", "3000")
+	m.On(http.MethodGet, base+"delegates/"+baker.String()+"/staking_balance", "20000")
+	m.On(http.MethodGet, base+"contracts/"+baker.String()+"/staked_balance", "2000")
+	c, err := m.Client()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := c.GetStakingBalance(ctx, baker, Head)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := StakingBalance{
+		Delegate:          baker,
+		Block:             hash,
+		Total:             20000,
+		OwnStaked:         2000,
+		ExternalStaked:    1000,
+		OwnDelegated:      5000,
+		ExternalDelegated: 12000,
+	}
+	if !reflect.DeepEqual(*b, want) {
+		t.Errorf("v018 staking balance:\n got  %+v\n want %+v", *b, want)
+	}
+	d, err := c.GetFrozenDeposits(ctx, baker, Head)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (FrozenDeposits{baker, hash, 3000, 2000, 1000, 75}); !reflect.DeepEqual(*d, want) {
+		t.Errorf("v018 frozen deposits:\n got  %+v\n want %+v", *d, want)
+	}
+
+	// v012: no staked_balance endpoint, all deposits are owned by the delegate
+	m.On(http.MethodGet, base+"contracts/"+baker.String()+"/staked_balance", nil).WithStatus(http.StatusNotFound)
+	b, err = c.GetStakingBalance(ctx, baker, Head)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.OwnStaked != 3000 || b.ExternalStaked != 0 || b.OwnDelegated != 4000 || b.ExternalDelegated != 13000 {
+		t.Errorf("v012 staking balance: %+v", *b)
+	}
+
+	// v021: the breakdown is part of delegate info
+	m.On(http.MethodGet, base+"delegates/"+baker.String(), map[string]interface{}{
+		"total_staked":       "3000",
+		"total_delegated":    "17000",
+		"own_staked":         "2000",
+		"own_delegated":      "5000",
+		"external_staked":    "1000",
+		"external_delegated": "12000",
+	})
+	m.On(http.MethodGet, base+"delegates/"+baker.String()+"/frozen_deposits", nil).WithStatus(http.StatusNotFound)
+	b, err = c.GetStakingBalance(ctx, baker, Head)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(*b, want) {
+		t.Errorf("v021 staking balance:\n got  %+v\n want %+v", *b, want)
+	}
+}
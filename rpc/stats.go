@@ -0,0 +1,129 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package rpc
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RequestStats describes a completed RPC request for logging and metrics.
+type RequestStats struct {
+	Method   string        // HTTP method
+	Path     string        // request path without query
+	Route    string        // path with block ids, hashes and numbers replaced, see RouteOf
+	Status   int           // HTTP status code, zero on transport errors
+	Bytes    int64         // response body bytes read
+	Duration time.Duration // time from sending until the response body was closed
+	Err      error         // transport error, if any
+}
+
+// StatsHook is called once per request after the response body was closed or
+// the request failed. For streaming monitors this happens when the stream ends.
+type StatsHook func(RequestStats)
+
+// WithStatsHook registers fn to receive stats for each request. Stats hooks
+// are response hooks and run in registration order together with hooks added
+// by WithResponseHook.
+func (c *Client) WithStatsHook(fn StatsHook) *Client {
+	return c.addResponseHook(func(req *http.Request, resp *http.Response, start time.Time, err error) {
+		trackStats(fn, req, resp, start, err)
+	})
+}
+
+// trackStats reports stats for a request started at start to fn. Successful
+// responses are reported when their body is closed.
+func trackStats(fn StatsHook, req *http.Request, resp *http.Response, start time.Time, err error) {
+	stats := RequestStats{
+		Method: req.Method,
+		Path:   req.URL.Path,
+		Route:  RouteOf(req.URL.Path),
+	}
+	if err != nil || resp.Body == nil {
+		stats.Err = err
+		if resp != nil {
+			stats.Status = resp.StatusCode
+		}
+		stats.Duration = time.Since(start)
+		fn(stats)
+		return
+	}
+	stats.Status = resp.StatusCode
+	resp.Body = &statsBody{ReadCloser: resp.Body, hook: fn, stats: stats, start: start}
+}
+
+// statsBody counts bytes read from a response body and reports stats on close.
+type statsBody struct {
+	io.ReadCloser
+	hook  StatsHook
+	stats RequestStats
+	start time.Time
+	once  sync.Once
+}
+
+func (b *statsBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.stats.Bytes += int64(n)
+	return n, err
+}
+
+func (b *statsBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(func() {
+		b.stats.Duration = time.Since(b.start)
+		b.hook(b.stats)
+	})
+	return err
+}
+
+// RouteOf returns a low-cardinality version of an RPC path suitable as metrics
+// label. Numeric segments become {n}, relative block ids like head~2 become
+// {block} and base58 hashes, addresses and keys become {hash}.
+func RouteOf(path string) string {
+	segs := strings.Split(path, "/")
+	for i, s := range segs {
+		switch {
+		case s == "":
+		case isNumeric(s):
+			segs[i] = "{n}"
+		case strings.ContainsAny(s, "~+") && (strings.HasPrefix(s, "head") || strings.HasPrefix(s, "genesis")):
+			segs[i] = "{block}"
+		case len(s) >= 36 && isBase58(s):
+			segs[i] = "{hash}"
+		}
+	}
+	return strings.Join(segs, "/")
+}
+
+func isNumeric(s string) bool {
+	if s[0] == '-' {
+		s = s[1:]
+	}
+	if len(s) == 0 {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func isBase58(s string) bool {
+	for _, c := range s {
+		switch {
+		case c >= '1' && c <= '9', c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z':
+			if c == 'I' || c == 'O' || c == 'l' {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
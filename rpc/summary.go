@@ -0,0 +1,155 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package rpc
+
+import (
+	"fmt"
+	"strings"
+
+	"blockwatch.cc/tzgo/micheline"
+	"blockwatch.cc/tzgo/tezos"
+)
+
+// Summary renders a human readable description of the operation with one line
+// per content and internal operation, e.g.
+//
+//	tz1VSUr8...jcjb -> KT1Hkg5q...CCA9 1.5 ꜩ call %transfer
+//	  KT1Hkg5q...CCA9 -> tz1burnb...YjjX 0.25 ꜩ
+//
+// Amounts are in tez, failed contents are marked with their status.
+func (o Operation) Summary() string {
+	var b strings.Builder
+	for i, op := range o.Contents {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		b.WriteString(summarizeOp(op))
+		writeStatus(&b, op.Result().Status)
+		for _, in := range op.Meta().InternalResults {
+			b.WriteString("\n  ")
+			b.WriteString(summarizeInternal(in))
+			writeStatus(&b, in.Result.Status)
+		}
+	}
+	return b.String()
+}
+
+func summarizeOp(op TypedOperation) string {
+	switch v := op.(type) {
+	case *Transaction:
+		return summarizeTransfer(v.Source, v.Destination, v.Amount, v.Parameters)
+	case *Origination:
+		s := fmt.Sprintf("%s originate", v.Source.Short())
+		for _, a := range v.Metadata.Result.OriginatedContracts {
+			s += " " + a.Short()
+		}
+		return s + " " + formatTez(v.Balance)
+	case *Delegation:
+		if !v.Delegate.IsValid() {
+			return fmt.Sprintf("%s withdraw delegation", v.Source.Short())
+		}
+		if v.Delegate.Equal(v.Source) {
+			return fmt.Sprintf("%s register as baker", v.Source.Short())
+		}
+		return fmt.Sprintf("%s delegate to %s", v.Source.Short(), v.Delegate.Short())
+	case *Reveal:
+		return fmt.Sprintf("%s reveal %s", v.Source.Short(), shortString(v.PublicKey.String()))
+	case *SetDepositsLimit:
+		if !v.HasLimit {
+			return fmt.Sprintf("%s unset deposits limit", v.Source.Short())
+		}
+		return fmt.Sprintf("%s set deposits limit %s", v.Source.Short(), formatTez(v.Limit))
+	case *IncreasePaidStorage:
+		return fmt.Sprintf("%s -> %s increase paid storage by %s bytes", v.Source.Short(), v.Destination.Short(), v.Amount)
+	case *ConstantRegistration:
+		s := fmt.Sprintf("%s register constant", v.Source.Short())
+		if h := v.Metadata.Result.GlobalAddress; h.IsValid() {
+			s += " " + h.Short()
+		}
+		return s
+	case *UpdateConsensusKey:
+		return fmt.Sprintf("%s update consensus key to %s", v.Source.Short(), shortString(v.PublicKey.String()))
+	case *DrainDelegate:
+		return fmt.Sprintf("%s drain -> %s", v.Delegate.Short(), v.Destination.Short())
+	case *Activation:
+		return fmt.Sprintf("activate %s", v.Pkh.Short())
+	case *Ballot:
+		return fmt.Sprintf("%s vote %s on %s", v.Source.Short(), v.Ballot, v.Proposal.Short())
+	case *Proposals:
+		list := make([]string, len(v.Proposals))
+		for i, p := range v.Proposals {
+			list[i] = p.Short()
+		}
+		return fmt.Sprintf("%s propose %s", v.Source.Short(), strings.Join(list, ", "))
+	case *Endorsement:
+		s := fmt.Sprintf("%s level %d", v.Kind(), v.GetLevel())
+		if d := v.Metadata.Delegate; d.IsValid() {
+			s = d.Short() + " " + s
+		}
+		return s
+	case *SeedNonce:
+		return fmt.Sprintf("%s level %d", v.Kind(), v.Level)
+	default:
+		return op.Kind().String()
+	}
+}
+
+func summarizeInternal(in *InternalResult) string {
+	switch in.Kind {
+	case tezos.OpTypeTransaction:
+		var dst tezos.Address
+		if in.Destination != nil {
+			dst = *in.Destination
+		}
+		return summarizeTransfer(in.Source, dst, in.Amount, in.Parameters)
+	case tezos.OpTypeOrigination:
+		s := fmt.Sprintf("%s originate", in.Source.Short())
+		for _, a := range in.Result.OriginatedContracts {
+			s += " " + a.Short()
+		}
+		return s + " " + formatTez(in.Balance)
+	case tezos.OpTypeDelegation:
+		if in.Delegate == nil || !in.Delegate.IsValid() {
+			return fmt.Sprintf("%s withdraw delegation", in.Source.Short())
+		}
+		return fmt.Sprintf("%s delegate to %s", in.Source.Short(), in.Delegate.Short())
+	case tezos.OpTypeEvent:
+		if in.Tag != "" {
+			return fmt.Sprintf("%s emit %%%s", in.Source.Short(), in.Tag)
+		}
+		return fmt.Sprintf("%s emit", in.Source.Short())
+	default:
+		return fmt.Sprintf("%s %s", in.Source.Short(), in.Kind)
+	}
+}
+
+func summarizeTransfer(src, dst tezos.Address, amount int64, params *micheline.Parameters) string {
+	s := fmt.Sprintf("%s -> %s %s", src.Short(), dst.Short(), formatTez(amount))
+	if params != nil && params.Entrypoint != "" && params.Entrypoint != "default" {
+		s += " call %" + params.Entrypoint
+	}
+	return s
+}
+
+func writeStatus(b *strings.Builder, status tezos.OpStatus) {
+	if status.IsValid() && !status.IsSuccess() {
+		b.WriteString(" [")
+		b.WriteString(status.String())
+		b.WriteString("]")
+	}
+}
+
+// formatTez renders mutez as tez without trailing zeros, e.g. 1.5 ꜩ.
+func formatTez(v int64) string {
+	s := tezos.Mutez(v).String()
+	s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	return s + " ꜩ"
+}
+
+func shortString(s string) string {
+	if len(s) < 12 {
+		return s
+	}
+	return s[:8] + "..." + s[len(s)-4:]
+}
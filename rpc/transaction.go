@@ -85,6 +85,15 @@ type InternalResult struct {
 	Amount      int64                 `json:"amount,string"`         // transaction
 	Balance     int64                 `json:"balance,string"`        // origination
 	Script      *micheline.Script     `json:"script,omitempty"`      // origination
+	Type        *micheline.Prim       `json:"type,omitempty"`        // event
+	Tag         string                `json:"tag,omitempty"`         // event
+	Payload     *micheline.Prim       `json:"payload,omitempty"`     // event
+}
+
+// IsEvent returns true when the internal result is a contract event emitted
+// by the EMIT instruction.
+func (r InternalResult) IsEvent() bool {
+	return r.Kind == tezos.OpTypeEvent
 }
 
 // found in block metadata from v010+
@@ -0,0 +1,153 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"blockwatch.cc/tzgo/micheline"
+)
+
+// TypecheckResult contains the stack types before and after each instruction
+// of successfully typechecked code.
+type TypecheckResult struct {
+	TypeMap []TypeMapEntry `json:"type_map"`
+	Gas     TraceGas       `json:"gas"`
+}
+
+// TypeMapEntry lists stack types around the instruction at Location.
+type TypeMapEntry struct {
+	Location    int              `json:"location"`
+	StackBefore []micheline.Prim `json:"stack_before"`
+	StackAfter  []micheline.Prim `json:"stack_after"`
+}
+
+// TypeError is a single Michelson error returned by the node. Location refers
+// to a node in the checked expression or is -1 when the error has no location.
+type TypeError struct {
+	ID              string          `json:"id"`
+	Kind            string          `json:"kind"`
+	Location        int             `json:"location"`
+	Primitive       string          `json:"primitive_name,omitempty"`
+	ExpectedType    *micheline.Prim `json:"expected_type,omitempty"`
+	WrongExpression *micheline.Prim `json:"wrong_expression,omitempty"`
+	Expr            micheline.Prim  `json:"-"` // node at location
+}
+
+func (e *TypeError) UnmarshalJSON(data []byte) error {
+	type alias TypeError
+	e.Location = -1
+	return json.Unmarshal(data, (*alias)(e))
+}
+
+// Name returns the protocol independent part of the error id,
+// e.g. bad_stack for proto.012-Psithaca.michelson_v1.bad_stack.
+func (e TypeError) Name() string {
+	return e.ID[strings.LastIndexByte(e.ID, '.')+1:]
+}
+
+func (e TypeError) Error() string {
+	if e.Location < 0 {
+		return fmt.Sprintf("rpc: %s", e.Name())
+	}
+	return fmt.Sprintf("rpc: %s at location %d", e.Name(), e.Location)
+}
+
+// ErrorID returns Tezos error id
+func (e TypeError) ErrorID() string {
+	return e.ID
+}
+
+// ErrorKind returns Tezos error kind
+func (e TypeError) ErrorKind() string {
+	return e.Kind
+}
+
+// TypecheckError is returned when the node rejects code or data as ill-typed.
+// Errors are listed in node order, outermost first.
+type TypecheckError struct {
+	HTTPError
+	Errors []TypeError
+}
+
+// Located returns the innermost error that refers to a location.
+func (e *TypecheckError) Located() (TypeError, bool) {
+	for i := len(e.Errors) - 1; i >= 0; i-- {
+		if e.Errors[i].Location >= 0 {
+			return e.Errors[i], true
+		}
+	}
+	return TypeError{}, false
+}
+
+func (e *TypecheckError) Error() string {
+	if err, ok := e.Located(); ok {
+		return err.Error()
+	}
+	if len(e.Errors) > 0 {
+		return e.Errors[0].Error()
+	}
+	return e.HTTPError.Error()
+}
+
+func (e *TypecheckError) Unwrap() error {
+	return e.HTTPError
+}
+
+// TypecheckCode typechecks a script's code at block id. When the node rejects
+// the code a *TypecheckError is returned with locations referring to code.
+func (c *Client) TypecheckCode(ctx context.Context, code micheline.Prim, id BlockID) (*TypecheckResult, error) {
+	u := fmt.Sprintf("chains/main/blocks/%s/helpers/scripts/typecheck_code", id)
+	req := struct {
+		Program micheline.Prim `json:"program"`
+	}{
+		Program: code,
+	}
+	var res TypecheckResult
+	if err := c.Post(ctx, u, &req, &res); err != nil {
+		return nil, newTypecheckError(err, code)
+	}
+	return &res, nil
+}
+
+// TypecheckData typechecks data against type typ at block id. When the node
+// rejects the data a *TypecheckError is returned with locations referring to data.
+func (c *Client) TypecheckData(ctx context.Context, data, typ micheline.Prim, id BlockID) error {
+	u := fmt.Sprintf("chains/main/blocks/%s/helpers/scripts/typecheck_data", id)
+	req := struct {
+		Data micheline.Prim `json:"data"`
+		Type micheline.Prim `json:"type"`
+	}{
+		Data: data,
+		Type: typ,
+	}
+	var res struct{}
+	if err := c.Post(ctx, u, &req, &res); err != nil {
+		return newTypecheckError(err, data)
+	}
+	return nil
+}
+
+// newTypecheckError decodes Michelson errors from the response body of err and
+// resolves their locations in expr. Other errors are returned unchanged.
+func newTypecheckError(err error, expr micheline.Prim) error {
+	herr, ok := err.(HTTPError)
+	if !ok {
+		return err
+	}
+	var errs []TypeError
+	if json.Unmarshal(herr.Body(), &errs) != nil || len(errs) == 0 {
+		return err
+	}
+	for i := range errs {
+		errs[i].Expr, _ = expr.Locate(errs[i].Location)
+	}
+	return &TypecheckError{
+		HTTPError: herr,
+		Errors:    errs,
+	}
+}
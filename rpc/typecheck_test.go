@@ -0,0 +1,155 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package rpc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"blockwatch.cc/tzgo/micheline"
+)
+
+const (
+	testTypecheckDataPath = "chains/main/blocks/head/helpers/scripts/typecheck_data"
+	testTypecheckCodePath = "chains/main/blocks/head/helpers/scripts/typecheck_code"
+)
+
+func TestTypeErrorName(t *testing.T) {
+	for _, test := range []struct {
+		id   string
+		loc  int
+		name string
+		msg  string
+	}{
+		{"proto.012-Psithaca.michelson_v1.bad_stack", 3, "bad_stack", "rpc: bad_stack at location 3"},
+		{"michelson_v1.invalid_constant", 0, "invalid_constant", "rpc: invalid_constant at location 0"},
+		{"ill_typed_data", -1, "ill_typed_data", "rpc: ill_typed_data"},
+	} {
+		e := TypeError{ID: test.id, Location: test.loc}
+		if e.Name() != test.name || e.Error() != test.msg {
+			t.Errorf("%s: got %q %q", test.id, e.Name(), e.Error())
+		}
+	}
+}
+
+func TestTypecheckData(t *testing.T) {
+	data := micheline.NewPair(micheline.NewInt64(1), micheline.NewString("a"))
+	typ := micheline.NewPairType(micheline.NewCode(micheline.T_NAT), micheline.NewCode(micheline.T_NAT))
+	for _, test := range []struct {
+		name    string
+		status  int
+		body    string
+		errs    int
+		located string
+		msg     string
+	}{
+		{name: "valid", status: http.StatusOK, body: `{}`},
+		{
+			name:   "invalid constant",
+			status: http.StatusBadRequest,
+			body: `[
+				{"kind":"permanent","id":"proto.012-Psithaca.michelson_v1.ill_typed_data","ill_typed_expression":{"int":"1"}},
+				{"kind":"permanent","id":"proto.012-Psithaca.michelson_v1.invalid_constant","location":2,"expected_type":{"prim":"nat"},"wrong_expression":{"string":"a"}}
+			]`,
+			errs:    2,
+			located: "invalid_constant",
+			msg:     "rpc: invalid_constant at location 2",
+		},
+		{
+			name:   "no location",
+			status: http.StatusBadRequest,
+			body:   `[{"kind":"permanent","id":"proto.012-Psithaca.michelson_v1.ill_typed_data"}]`,
+			errs:   1,
+			msg:    "rpc: ill_typed_data",
+		},
+	} {
+		m := NewMock()
+		m.On(http.MethodPost, testTypecheckDataPath, []byte(test.body)).WithStatus(test.status)
+		c, err := m.Client()
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = c.TypecheckData(context.Background(), data, typ, Head)
+		if test.errs == 0 {
+			if err != nil {
+				t.Errorf("%s: %v", test.name, err)
+			}
+			continue
+		}
+		var terr *TypecheckError
+		if !errors.As(err, &terr) || len(terr.Errors) != test.errs {
+			t.Errorf("%s: unexpected error %v", test.name, err)
+			continue
+		}
+		if terr.Error() != test.msg {
+			t.Errorf("%s: message %q want %q", test.name, terr.Error(), test.msg)
+		}
+		// the HTTP error stays accessible
+		if ErrorStatus(err) != test.status {
+			t.Errorf("%s: status %d", test.name, ErrorStatus(err))
+		}
+		located, ok := terr.Located()
+		if ok != (test.located != "") || ok && located.Name() != test.located {
+			t.Errorf("%s: located %v %+v", test.name, ok, located)
+			continue
+		}
+		if ok && (located.Expr.Type != micheline.PrimString || located.Expr.String != "a" || located.ExpectedType == nil || located.ExpectedType.OpCode != micheline.T_NAT) {
+			t.Errorf("%s: unexpected located error %+v", test.name, located)
+		}
+		if terr.Errors[0].Location != -1 {
+			t.Errorf("%s: missing location decoded as %d", test.name, terr.Errors[0].Location)
+		}
+	}
+}
+
+func TestTypecheckErrorPassthrough(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		status int
+		body   string
+	}{
+		{"plain text", http.StatusBadRequest, "Failed to parse the request body"},
+		{"empty list", http.StatusBadRequest, "[]"},
+		{"other object", http.StatusNotFound, `{"error":"not found"}`},
+	} {
+		m := NewMock()
+		m.On(http.MethodPost, testTypecheckCodePath, []byte(test.body)).WithStatus(test.status)
+		c, err := m.Client()
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = c.TypecheckCode(context.Background(), micheline.NewSeq(), Head)
+		var terr *TypecheckError
+		if err == nil || errors.As(err, &terr) || ErrorStatus(err) != test.status {
+			t.Errorf("%s: unexpected error %T %v", test.name, err, err)
+		}
+	}
+}
+
+func TestTypecheckCode(t *testing.T) {
+	m := NewMock()
+	m.On(http.MethodPost, testTypecheckCodePath, []byte(`{
+		"type_map":[
+			{"location":7,"stack_before":[{"prim":"pair","args":[{"prim":"nat"},{"prim":"nat"}]}],"stack_after":[{"prim":"nat"},{"prim":"nat"}]},
+			{"location":8,"stack_before":[{"prim":"nat"},{"prim":"nat"}],"stack_after":[{"prim":"nat"}]}
+		],
+		"gas":"1039.335"
+	}`))
+	c, err := m.Client()
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := c.TypecheckCode(context.Background(), testScriptCode(t).Prim(), Head)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.TypeMap) != 2 || res.Gas != 1039335 {
+		t.Fatalf("unexpected result %+v", res)
+	}
+	if e := res.TypeMap[1]; e.Location != 8 || len(e.StackBefore) != 2 || len(e.StackAfter) != 1 || e.StackAfter[0].OpCode != micheline.T_NAT {
+		t.Errorf("unexpected type map entry %+v", e)
+	}
+}
@@ -13,15 +13,56 @@ import (
 	"blockwatch.cc/tzgo/tezos"
 )
 
-// Voter holds information about a vote listing
+// Voter holds information about a vote listing. Until v011 voting power is
+// counted in rolls, from v012 in mutez.
 type Voter struct {
-	Delegate tezos.Address `json:"pkh"`
-	Rolls    int64         `json:"rolls"`
+	Delegate    tezos.Address `json:"pkh"`
+	Rolls       int64         `json:"rolls"`
+	VotingPower int64         `json:"voting_power"`
+}
+
+func (v *Voter) UnmarshalJSON(data []byte) error {
+	var val struct {
+		Delegate    tezos.Address `json:"pkh"`
+		Rolls       int64         `json:"rolls"`
+		VotingPower json.Number   `json:"voting_power"`
+	}
+	if err := json.Unmarshal(data, &val); err != nil {
+		return fmt.Errorf("rpc: voter: %w", err)
+	}
+	v.Delegate = val.Delegate
+	v.Rolls = val.Rolls
+	v.VotingPower = 0
+	if val.VotingPower != "" {
+		power, err := val.VotingPower.Int64()
+		if err != nil {
+			return fmt.Errorf("rpc: voter: %w", err)
+		}
+		v.VotingPower = power
+	}
+	return nil
+}
+
+// Power returns the voter's voting power in rolls (until v011) or mutez (v012+).
+func (v Voter) Power() int64 {
+	if v.VotingPower > 0 {
+		return v.VotingPower
+	}
+	return v.Rolls
 }
 
 // VoterList contains a list of voters
 type VoterList []Voter
 
+// TotalPower returns the sum of voting power of all voters.
+func (l VoterList) TotalPower() int64 {
+	var sum int64
+	for _, v := range l {
+		sum += v.Power()
+	}
+	return sum
+}
+
 // BallotInfo holds information about a vote listing
 type BallotInfo struct {
 	Delegate tezos.Address    `json:"pkh"`
@@ -38,6 +79,20 @@ type BallotSummary struct {
 	Pass int `json:"pass"`
 }
 
+// Participation returns the share of total voting power that has cast a ballot
+// as percent * 100, i.e. in the same unit as the vote quorum.
+func (s BallotSummary) Participation(total int64) int {
+	if total <= 0 {
+		return 0
+	}
+	return int(int64(s.Yay+s.Nay+s.Pass) * 10000 / total)
+}
+
+// ReachesQuorum returns true when participation meets quorum.
+func (s BallotSummary) ReachesQuorum(total int64, quorum int) bool {
+	return s.Participation(total) >= quorum
+}
+
 // Proposal holds information about a vote listing
 type Proposal struct {
 	Proposal tezos.ProtocolHash
@@ -85,6 +140,32 @@ func (c *Client) ListVoters(ctx context.Context, id BlockID) (VoterList, error)
 	return voters, nil
 }
 
+// GetCurrentPeriod returns the voting period containing block id and the block's
+// position within the period.
+func (c *Client) GetCurrentPeriod(ctx context.Context, id BlockID) (*VotingPeriodInfo, error) {
+	period := &VotingPeriodInfo{}
+	u := fmt.Sprintf("chains/main/blocks/%s/votes/current_period", id)
+	if err := c.Get(ctx, u, period); err != nil {
+		return nil, err
+	}
+	return period, nil
+}
+
+// GetSuccessorPeriod returns the voting period of the block following block id.
+func (c *Client) GetSuccessorPeriod(ctx context.Context, id BlockID) (*VotingPeriodInfo, error) {
+	period := &VotingPeriodInfo{}
+	u := fmt.Sprintf("chains/main/blocks/%s/votes/successor_period", id)
+	if err := c.Get(ctx, u, period); err != nil {
+		return nil, err
+	}
+	return period, nil
+}
+
+// GetVoteListings is an alias for ListVoters.
+func (c *Client) GetVoteListings(ctx context.Context, id BlockID) (VoterList, error) {
+	return c.ListVoters(ctx, id)
+}
+
 // GetVoteQuorum returns information about the current voring quorum at block id.
 // Returned value is percent * 10000 i.e. 5820 for 58.20%.
 func (c *Client) GetVoteQuorum(ctx context.Context, id BlockID) (int, error) {
@@ -96,6 +177,11 @@ func (c *Client) GetVoteQuorum(ctx context.Context, id BlockID) (int, error) {
 	return quorum, nil
 }
 
+// GetCurrentQuorum is an alias for GetVoteQuorum.
+func (c *Client) GetCurrentQuorum(ctx context.Context, id BlockID) (int, error) {
+	return c.GetVoteQuorum(ctx, id)
+}
+
 // GetVoteProposal returns the hash of the current voring proposal at block id.
 func (c *Client) GetVoteProposal(ctx context.Context, id BlockID) (tezos.ProtocolHash, error) {
 	var proposal tezos.ProtocolHash
@@ -0,0 +1,473 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"blockwatch.cc/tzgo/tezos"
+)
+
+type AddressEventType byte
+
+const (
+	AddressEventPending   AddressEventType = iota // operation seen in mempool
+	AddressEventIncluded                          // operation included in a block
+	AddressEventConfirmed                         // inclusion reached the configured confirmations
+	AddressEventOrphaned                          // inclusion block was reorganized out of the chain
+)
+
+func (t AddressEventType) String() string {
+	switch t {
+	case AddressEventPending:
+		return "pending"
+	case AddressEventIncluded:
+		return "included"
+	case AddressEventConfirmed:
+		return "confirmed"
+	case AddressEventOrphaned:
+		return "orphaned"
+	}
+	return ""
+}
+
+// AddressEvent reports a state change of an operation group involving a
+// watched address. Block and Height are empty for pending operations.
+type AddressEvent struct {
+	Type          AddressEventType
+	Op            *Operation
+	Block         tezos.BlockHash
+	Height        int64
+	Confirmations int64 // number of blocks on top of the inclusion block
+}
+
+// WatchAddressOptions configures WatchAddressWithOptions.
+type WatchAddressOptions struct {
+	// Number of blocks on top of the inclusion block after which a confirmed
+	// event is sent. Zero disables confirmed events.
+	Confirmations int64
+	// Max depth of a reorganization below the current head that is resolved
+	// to its fork point. Inclusions are tracked for this many blocks after
+	// they are confirmed. Levels missed after a head jump are always fetched.
+	MaxReorgDepth int64
+	// Do not watch the mempool for pending operations.
+	NoMempool bool
+}
+
+var DefaultWatchAddressOptions = WatchAddressOptions{
+	Confirmations: 2,
+	MaxReorgDepth: 10,
+}
+
+// WatchAddress watches mempool and new blocks for operations involving addr
+// using DefaultWatchAddressOptions. See WatchAddressWithOptions.
+func (c *Client) WatchAddress(ctx context.Context, addr tezos.Address) (<-chan AddressEvent, error) {
+	return c.WatchAddressWithOptions(ctx, addr, DefaultWatchAddressOptions)
+}
+
+// WatchAddressWithOptions delivers events for operation groups involving addr
+// as source, destination, delegate or originated contract, including internal
+// operations, when they appear in the mempool, get included in a block, reach
+// the configured confirmations or when their inclusion block is orphaned by a
+// reorganization. The channel is closed when ctx is canceled. Connection
+// errors are retried in the background.
+func (c *Client) WatchAddressWithOptions(ctx context.Context, addr tezos.Address, opts WatchAddressOptions) (<-chan AddressEvent, error) {
+	if !addr.IsValid() {
+		return nil, fmt.Errorf("rpc: invalid address %q", addr)
+	}
+	if opts.MaxReorgDepth <= 0 {
+		opts.MaxReorgDepth = DefaultWatchAddressOptions.MaxReorgDepth
+	}
+	mon := NewBlockHeaderMonitor()
+	if err := c.MonitorBlockHeader(ctx, mon); err != nil {
+		mon.Close()
+		return nil, err
+	}
+	w := newAddressWatcher(c, addr, opts)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		w.watchBlocks(ctx, mon)
+	}()
+	if !opts.NoMempool {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.watchMempool(ctx)
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(w.ch)
+	}()
+	return w.ch, nil
+}
+
+// Involves returns true when addr takes part in any of the operation's
+// contents or internal operations as source, destination, delegate or
+// originated contract.
+func (o *Operation) Involves(addr tezos.Address) bool {
+	for _, op := range o.Contents {
+		if contentInvolves(op, addr) {
+			return true
+		}
+		res := op.Result()
+		for _, v := range res.OriginatedContracts {
+			if v.Equal(addr) {
+				return true
+			}
+		}
+		for _, in := range op.Meta().InternalResults {
+			if in.Source.Equal(addr) {
+				return true
+			}
+			if in.Destination != nil && in.Destination.Equal(addr) {
+				return true
+			}
+			if in.Delegate != nil && in.Delegate.Equal(addr) {
+				return true
+			}
+			for _, v := range in.Result.OriginatedContracts {
+				if v.Equal(addr) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func contentInvolves(op TypedOperation, addr tezos.Address) bool {
+	switch o := op.(type) {
+	case *Transaction:
+		return o.Source.Equal(addr) || o.Destination.Equal(addr)
+	case *Origination:
+		return o.Source.Equal(addr) || (o.Delegate != nil && o.Delegate.Equal(addr))
+	case *Delegation:
+		return o.Source.Equal(addr) || o.Delegate.Equal(addr)
+	case *Reveal:
+		return o.Source.Equal(addr)
+	case *ConstantRegistration:
+		return o.Source.Equal(addr)
+	case *SetDepositsLimit:
+		return o.Source.Equal(addr)
+	case *UpdateConsensusKey:
+		return o.Source.Equal(addr)
+	case *DrainDelegate:
+		return o.Delegate.Equal(addr) || o.Destination.Equal(addr) || o.ConsensusKey.Equal(addr)
+	case *Activation:
+		return o.Pkh.Equal(addr)
+	}
+	return false
+}
+
+type addressInclusion struct {
+	op        *Operation
+	block     tezos.BlockHash
+	height    int64
+	confirmed bool
+}
+
+type addressWatcher struct {
+	c        *Client
+	addr     tezos.Address
+	opts     WatchAddressOptions
+	ch       chan AddressEvent
+	mu       sync.Mutex
+	pending  map[string]int64             // op hash -> head height when seen in mempool
+	included map[string]*addressInclusion // op hash -> inclusion
+	canon    map[int64]tezos.BlockHash    // height -> canonical block hash
+	head     int64
+	ttl      int64 // max_operations_ttl of the head block
+}
+
+func newAddressWatcher(c *Client, addr tezos.Address, opts WatchAddressOptions) *addressWatcher {
+	return &addressWatcher{
+		c:        c,
+		addr:     addr,
+		opts:     opts,
+		ch:       make(chan AddressEvent),
+		pending:  make(map[string]int64),
+		included: make(map[string]*addressInclusion),
+		canon:    make(map[int64]tezos.BlockHash),
+	}
+}
+
+func (w *addressWatcher) send(ctx context.Context, ev AddressEvent) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case w.ch <- ev:
+		return true
+	}
+}
+
+func (w *addressWatcher) watchBlocks(ctx context.Context, mon *BlockHeaderMonitor) {
+	defer func() {
+		if mon != nil {
+			mon.Close()
+		}
+	}()
+	for {
+		if mon == nil {
+			mon = NewBlockHeaderMonitor()
+			if err := w.c.MonitorBlockHeader(ctx, mon); err != nil {
+				w.c.debugf("rpc: address watcher: head monitor: %v", err)
+				mon.Close()
+				mon = nil
+				// wait 5 sec, but also return on close
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(5 * time.Second):
+				}
+				continue
+			}
+		}
+		head, err := mon.Recv(ctx)
+		if err != nil {
+			w.c.debugf("rpc: address watcher: reconnecting head monitor: %v", err)
+			mon.Close()
+			mon = nil
+			select {
+			case <-ctx.Done():
+				return
+			default:
+			}
+			continue
+		}
+		if err := w.handleHead(ctx, head.Hash); err != nil {
+			select {
+			case <-ctx.Done():
+				return
+			default:
+			}
+			w.c.debugf("rpc: address watcher: %v", err)
+		}
+	}
+}
+
+func (w *addressWatcher) watchMempool(ctx context.Context) {
+	var mon *MempoolMonitor
+	defer func() {
+		if mon != nil {
+			mon.Close()
+		}
+	}()
+	for {
+		if mon == nil {
+			mon = NewMempoolMonitor()
+			if err := w.c.MonitorMempool(ctx, mon); err != nil {
+				w.c.debugf("rpc: address watcher: mempool monitor: %v", err)
+				mon.Close()
+				mon = nil
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(5 * time.Second):
+				}
+				continue
+			}
+		}
+		ops, err := mon.Recv(ctx)
+		if err != nil {
+			// the node closes the stream on every new block
+			mon.Close()
+			mon = nil
+			select {
+			case <-ctx.Done():
+				return
+			default:
+			}
+			continue
+		}
+		if !w.handleMempool(ctx, ops) {
+			return
+		}
+	}
+}
+
+// handleMempool sends a pending event for each new operation involving the
+// watched address.
+func (w *addressWatcher) handleMempool(ctx context.Context, ops []*Operation) bool {
+	for _, op := range ops {
+		if !op.Involves(w.addr) {
+			continue
+		}
+		key := op.Hash.String()
+		w.mu.Lock()
+		_, seen := w.pending[key]
+		_, done := w.included[key]
+		if !seen && !done {
+			w.pending[key] = w.head
+		}
+		w.mu.Unlock()
+		if seen || done {
+			continue
+		}
+		if !w.send(ctx, AddressEvent{Type: AddressEventPending, Op: op}) {
+			return false
+		}
+	}
+	return true
+}
+
+// handleHead fetches the new head block and all missing ancestors down to the
+// last known canonical block, emits orphaned events for inclusions on the
+// abandoned branch, included events for matches on the new branch and
+// confirmed events for inclusions that are deep enough. After a head jump
+// every skipped level is fetched, reorganizations are resolved down to the
+// tracked window.
+func (w *addressWatcher) handleHead(ctx context.Context, hash tezos.BlockHash) error {
+	// collect the new branch in descending order
+	branch := make([]*Block, 0, 1)
+	for {
+		b, err := w.c.GetBlock(ctx, hash)
+		if err != nil {
+			return err
+		}
+		if known, ok := w.canon[b.Header.Level]; ok && known.Equal(b.Hash) {
+			// already processed
+			break
+		}
+		branch = append(branch, b)
+		if len(w.canon) == 0 {
+			break
+		}
+		if known, ok := w.canon[b.Header.Level-1]; ok && known.Equal(b.Header.Predecessor) {
+			break
+		}
+		if b.Header.Level-1 < w.minHeight() {
+			// fork point is below the tracked window
+			break
+		}
+		hash = b.Header.Predecessor
+	}
+	if len(branch) == 0 {
+		return nil
+	}
+
+	// update the canonical chain
+	fork := branch[len(branch)-1].Header.Level
+	for h := range w.canon {
+		if h >= fork {
+			delete(w.canon, h)
+		}
+	}
+	for _, b := range branch {
+		w.canon[b.Header.Level] = b.Hash.Clone()
+	}
+	w.mu.Lock()
+	w.head = branch[0].Header.Level
+	w.ttl = int64(branch[0].Metadata.MaxOperationsTTL)
+	w.mu.Unlock()
+
+	// orphan inclusions on the abandoned branch
+	for key, inc := range w.included {
+		if inc.height < fork {
+			continue
+		}
+		if known, ok := w.canon[inc.height]; ok && known.Equal(inc.block) {
+			continue
+		}
+		w.mu.Lock()
+		delete(w.included, key)
+		delete(w.pending, key)
+		w.mu.Unlock()
+		if !w.send(ctx, AddressEvent{
+			Type:   AddressEventOrphaned,
+			Op:     inc.op,
+			Block:  inc.block,
+			Height: inc.height,
+		}) {
+			return ctx.Err()
+		}
+	}
+
+	// scan the new branch in ascending order
+	for i := len(branch) - 1; i >= 0; i-- {
+		b := branch[i]
+		for _, list := range b.Operations {
+			for _, op := range list {
+				if !op.Involves(w.addr) {
+					continue
+				}
+				key := op.Hash.String()
+				inc := &addressInclusion{
+					op:     op,
+					block:  b.Hash.Clone(),
+					height: b.Header.Level,
+				}
+				w.mu.Lock()
+				w.included[key] = inc
+				delete(w.pending, key)
+				w.mu.Unlock()
+				if !w.send(ctx, AddressEvent{
+					Type:          AddressEventIncluded,
+					Op:            op,
+					Block:         inc.block,
+					Height:        inc.height,
+					Confirmations: w.head - inc.height,
+				}) {
+					return ctx.Err()
+				}
+			}
+		}
+	}
+
+	// confirm and expire inclusions
+	for key, inc := range w.included {
+		depth := w.head - inc.height
+		if !inc.confirmed && w.opts.Confirmations > 0 && depth >= w.opts.Confirmations {
+			inc.confirmed = true
+			if !w.send(ctx, AddressEvent{
+				Type:          AddressEventConfirmed,
+				Op:            inc.op,
+				Block:         inc.block,
+				Height:        inc.height,
+				Confirmations: depth,
+			}) {
+				return ctx.Err()
+			}
+		}
+		if depth > w.opts.Confirmations+w.opts.MaxReorgDepth {
+			w.mu.Lock()
+			delete(w.included, key)
+			w.mu.Unlock()
+		}
+	}
+	for h := range w.canon {
+		if h < w.minHeight() {
+			delete(w.canon, h)
+		}
+	}
+
+	// expire pending operations that can no longer be included
+	ttl := w.ttl
+	if ttl <= 0 {
+		ttl = tezos.DefaultParams.MaxOperationsTTL
+	}
+	w.mu.Lock()
+	for key, seen := range w.pending {
+		if seen == 0 {
+			// seen before the first head
+			w.pending[key] = w.head
+			continue
+		}
+		if w.head-seen > ttl {
+			delete(w.pending, key)
+		}
+	}
+	w.mu.Unlock()
+	return nil
+}
+
+// minHeight returns the lowest height of the tracked canonical chain window.
+func (w *addressWatcher) minHeight() int64 {
+	return w.head - w.opts.Confirmations - w.opts.MaxReorgDepth
+}
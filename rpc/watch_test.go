@@ -0,0 +1,181 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"blockwatch.cc/tzgo/tezos"
+)
+
+var (
+	watchAddr  = tezos.MustParseAddress("tz1KqTpEZ7Yob7QbPE4Hy4Wo8fHG8LhKxZSx")
+	watchOther = tezos.MustParseAddress("tz1burnburnburnburnburnburnburjAYjjX")
+)
+
+func testBlockHash(level int64, fork byte) tezos.BlockHash {
+	buf := make([]byte, 32)
+	buf[0], buf[1], buf[2] = fork, byte(level>>8), byte(level)
+	return tezos.NewBlockHash(buf)
+}
+
+func testOpHash(level int64, fork byte) tezos.OpHash {
+	buf := make([]byte, 32)
+	buf[0], buf[1], buf[2] = fork, byte(level>>8), byte(level)
+	return tezos.NewOpHash(buf)
+}
+
+// testChain registers blocks from..to on fork with their predecessor on
+// parent below from. Each block contains one transaction to the watched
+// address.
+func testChain(m *Mock, from, to int64, fork, parent byte) {
+	testChainAt(m, from, to, fork, parent, false)
+}
+
+// testChainAt works like testChain and optionally registers the blocks as
+// canonical under their level together with their ancestor lists. Parent
+// must be rooted at level 1.
+func testChainAt(m *Mock, from, to int64, fork, parent byte, canonical bool) {
+	for level := from; level <= to; level++ {
+		pred := testBlockHash(level-1, fork)
+		if level == from {
+			pred = testBlockHash(level-1, parent)
+		}
+		op := fmt.Sprintf(`{"hash":%q,"contents":[{"kind":"transaction","source":%q,"destination":%q,"amount":"1","fee":"0","counter":"1","gas_limit":"0","storage_limit":"0","metadata":{"operation_result":{"status":"applied"}}}]}`,
+			testOpHash(level, fork), watchOther, watchAddr)
+		block := fmt.Sprintf(`{"hash":%q,"header":{"level":%d,"predecessor":%q},"metadata":{"max_operations_ttl":120},"operations":[[],[],[],[%s]]}`,
+			testBlockHash(level, fork), level, pred, op)
+		m.On(http.MethodGet, "chains/main/blocks/"+testBlockHash(level, fork).String(), []byte(block))
+		if canonical {
+			m.On(http.MethodGet, fmt.Sprintf("chains/main/blocks/%d", level), []byte(block))
+			hashes := make([]tezos.BlockHash, 0)
+			for l := level; l > 0 && len(hashes) < 32; l-- {
+				if l >= from {
+					hashes = append(hashes, testBlockHash(l, fork))
+				} else {
+					hashes = append(hashes, testBlockHash(l, parent))
+				}
+				u := fmt.Sprintf("chains/main/blocks?length=%d&head=%s", len(hashes), testBlockHash(level, fork))
+				m.On(http.MethodGet, u, [][]tezos.BlockHash{hashes})
+			}
+		}
+	}
+}
+
+func newTestWatcher(t *testing.T, m *Mock, opts WatchAddressOptions) *addressWatcher {
+	t.Helper()
+	c, err := m.Client()
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := newAddressWatcher(c, watchAddr, opts)
+	w.ch = make(chan AddressEvent, 1000)
+	return w
+}
+
+// drain returns the types and heights of all queued events.
+func drain(w *addressWatcher) []string {
+	list := make([]string, 0)
+	for {
+		select {
+		case ev := <-w.ch:
+			list = append(list, fmt.Sprintf("%s@%d", ev.Type, ev.Height))
+		default:
+			return list
+		}
+	}
+}
+
+func TestAddressWatcherHeadJump(t *testing.T) {
+	m := NewMock()
+	testChain(m, 1, 40, 0, 0)
+	w := newTestWatcher(t, m, WatchAddressOptions{MaxReorgDepth: 2})
+	ctx := context.Background()
+
+	if err := w.handleHead(ctx, testBlockHash(10, 0)); err != nil {
+		t.Fatal(err)
+	}
+	drain(w)
+
+	// jump far beyond MaxReorgDepth, every skipped level must be scanned
+	if err := w.handleHead(ctx, testBlockHash(40, 0)); err != nil {
+		t.Fatal(err)
+	}
+	events := drain(w)
+	if len(events) != 30 {
+		t.Fatalf("expected 30 events, got %d: %v", len(events), events)
+	}
+	for i, ev := range events {
+		if want := fmt.Sprintf("included@%d", 11+i); ev != want {
+			t.Errorf("event %d: got %s want %s", i, ev, want)
+		}
+	}
+}
+
+func TestAddressWatcherReorg(t *testing.T) {
+	m := NewMock()
+	testChain(m, 1, 12, 0, 0)
+	testChain(m, 11, 13, 1, 0) // fork at level 11
+	w := newTestWatcher(t, m, WatchAddressOptions{Confirmations: 1, MaxReorgDepth: 5})
+	ctx := context.Background()
+
+	for _, level := range []int64{10, 11, 12} {
+		if err := w.handleHead(ctx, testBlockHash(level, 0)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	drain(w)
+
+	if err := w.handleHead(ctx, testBlockHash(13, 1)); err != nil {
+		t.Fatal(err)
+	}
+	events := drain(w)
+	want := map[string]bool{
+		"orphaned@11": true, "orphaned@12": true,
+		"included@11": true, "included@12": true, "included@13": true,
+		"confirmed@11": true, "confirmed@12": true,
+	}
+	if len(events) != len(want) {
+		t.Fatalf("unexpected events %v", events)
+	}
+	for _, ev := range events {
+		if !want[ev] {
+			t.Errorf("unexpected event %s in %v", ev, events)
+		}
+	}
+	if !w.canon[11].Equal(testBlockHash(11, 1)) || w.canon[10].Equal(testBlockHash(10, 1)) {
+		t.Errorf("canonical chain not updated")
+	}
+}
+
+func TestAddressWatcherPendingExpiry(t *testing.T) {
+	m := NewMock()
+	testChain(m, 1, 200, 0, 0)
+	w := newTestWatcher(t, m, WatchAddressOptions{MaxReorgDepth: 2})
+	ctx := context.Background()
+
+	if err := w.handleHead(ctx, testBlockHash(10, 0)); err != nil {
+		t.Fatal(err)
+	}
+	op := &Operation{Hash: testOpHash(1, 9)}
+	op.Contents = OperationList{&Transaction{Manager: Manager{Source: watchOther}, Destination: watchAddr}}
+	if !w.handleMempool(ctx, []*Operation{op}) || len(w.pending) != 1 {
+		t.Fatalf("pending op not tracked")
+	}
+	if err := w.handleHead(ctx, testBlockHash(100, 0)); err != nil {
+		t.Fatal(err)
+	}
+	if len(w.pending) != 1 {
+		t.Fatalf("pending op expired early")
+	}
+	if err := w.handleHead(ctx, testBlockHash(200, 0)); err != nil {
+		t.Fatal(err)
+	}
+	if len(w.pending) != 0 {
+		t.Errorf("pending op not expired after ttl")
+	}
+}
@@ -0,0 +1,179 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package ledger
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Transport exchanges raw APDU messages with a Ledger device. Implementations
+// typically wrap a USB HID connection, but tests may use a mock device.
+type Transport interface {
+	// Exchange sends a command APDU and returns the device response including
+	// the trailing 2 byte status word.
+	Exchange(apdu []byte) ([]byte, error)
+	Close() error
+}
+
+// APDU constants used by the Tezos Wallet and Baking apps
+const (
+	claTezos = 0x80
+
+	insVersion         = 0x00
+	insGetPublicKey    = 0x02
+	insPromptPublicKey = 0x03
+	insSign            = 0x04
+
+	p1First    = 0x00
+	p1Next     = 0x01
+	p1LastFlag = 0x80
+
+	maxChunkSize = 230
+)
+
+// Status words returned by the Tezos Ledger apps
+const (
+	swOk                  = 0x9000
+	swWrongParam          = 0x6b00
+	swWrongLength         = 0x6c00
+	swInvalidIns          = 0x6d00
+	swClassNotSupported   = 0x6e00
+	swAppNotOpen          = 0x6e01
+	swDeviceLocked        = 0x6804
+	swWrongLengthForIns   = 0x917e
+	swRejected            = 0x6985
+	swSecurity            = 0x6982
+	swParseError          = 0x9405
+	swReferencedNotFound  = 0x6a88
+	swWrongValues         = 0x6a80
+	swMemoryError         = 0x9200
+	swDashboardAppNotOpen = 0x6511
+)
+
+var (
+	ErrRejected       = errors.New("ledger: user rejected")
+	ErrAppNotOpen     = errors.New("ledger: Tezos app not open")
+	ErrParse          = errors.New("ledger: parsing error")
+	ErrLocked         = errors.New("ledger: device locked")
+	ErrSecurity       = errors.New("ledger: security condition not satisfied")
+	ErrInvalidData    = errors.New("ledger: invalid data")
+	ErrShortResponse  = errors.New("ledger: short response")
+	ErrUnsupported    = errors.New("ledger: operation not supported by app")
+	ErrInvalidPath    = errors.New("ledger: invalid derivation path")
+	ErrInvalidDataLen = errors.New("ledger: data too large")
+)
+
+// StatusError is returned for status words that have no specific error mapping.
+type StatusError uint16
+
+func (e StatusError) Error() string {
+	return fmt.Sprintf("ledger: unexpected status word 0x%04x", uint16(e))
+}
+
+// statusError maps a Ledger status word to an error.
+func statusError(sw uint16) error {
+	switch sw {
+	case swOk:
+		return nil
+	case swRejected:
+		return ErrRejected
+	case swClassNotSupported, swAppNotOpen, swInvalidIns, swDashboardAppNotOpen:
+		return ErrAppNotOpen
+	case swParseError:
+		return ErrParse
+	case swDeviceLocked:
+		return ErrLocked
+	case swSecurity:
+		return ErrSecurity
+	case swWrongParam, swWrongLength, swWrongLengthForIns, swWrongValues, swReferencedNotFound:
+		return ErrInvalidData
+	default:
+		return StatusError(sw)
+	}
+}
+
+// exchange sends a single APDU and strips the status word from the response.
+func exchange(t Transport, ins, p1, p2 byte, data []byte) ([]byte, error) {
+	if len(data) > 255 {
+		return nil, ErrInvalidDataLen
+	}
+	apdu := make([]byte, 5, 5+len(data))
+	apdu[0] = claTezos
+	apdu[1] = ins
+	apdu[2] = p1
+	apdu[3] = p2
+	apdu[4] = byte(len(data))
+	apdu = append(apdu, data...)
+	resp, err := t.Exchange(apdu)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp) < 2 {
+		return nil, ErrShortResponse
+	}
+	sw := binary.BigEndian.Uint16(resp[len(resp)-2:])
+	if err := statusError(sw); err != nil {
+		return nil, err
+	}
+	return resp[:len(resp)-2], nil
+}
+
+// Path is a BIP32 derivation path.
+type Path []uint32
+
+const hardened = 0x80000000
+
+// DefaultPath is the default Tezos derivation path 44'/1729'/0'/0'.
+var DefaultPath = Path{44 | hardened, 1729 | hardened, 0 | hardened, 0 | hardened}
+
+// ParsePath parses a derivation path like "44'/1729'/0'/0'" with an optional
+// leading "m/". Both ' and h are accepted as hardened markers.
+func ParsePath(s string) (Path, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "m/")
+	if s == "" {
+		return nil, ErrInvalidPath
+	}
+	fields := strings.Split(s, "/")
+	p := make(Path, len(fields))
+	for i, f := range fields {
+		var h uint32
+		if strings.HasSuffix(f, "'") || strings.HasSuffix(f, "h") {
+			h = hardened
+			f = f[:len(f)-1]
+		}
+		v, err := strconv.ParseUint(f, 10, 31)
+		if err != nil {
+			return nil, fmt.Errorf("%w %q: %v", ErrInvalidPath, s, err)
+		}
+		p[i] = uint32(v) | h
+	}
+	return p, nil
+}
+
+func (p Path) String() string {
+	fields := make([]string, len(p))
+	for i, v := range p {
+		if v&hardened > 0 {
+			fields[i] = strconv.FormatUint(uint64(v&^hardened), 10) + "'"
+		} else {
+			fields[i] = strconv.FormatUint(uint64(v), 10)
+		}
+	}
+	return strings.Join(fields, "/")
+}
+
+// Bytes returns the APDU encoding of the path: a length byte followed by
+// big-endian encoded path components.
+func (p Path) Bytes() []byte {
+	buf := make([]byte, 1+4*len(p))
+	buf[0] = byte(len(p))
+	for i, v := range p {
+		binary.BigEndian.PutUint32(buf[1+4*i:], v)
+	}
+	return buf
+}
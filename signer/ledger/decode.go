@@ -0,0 +1,93 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package ledger
+
+import (
+	"fmt"
+
+	"blockwatch.cc/tzgo/tezos"
+)
+
+// decodePublicKey parses a length-prefixed public key response. Ed25519 keys
+// are prefixed with 0x02, ECDSA keys are returned in uncompressed form.
+func decodePublicKey(typ tezos.KeyType, resp []byte) (tezos.Key, error) {
+	if len(resp) < 1 || len(resp) < 1+int(resp[0]) {
+		return tezos.InvalidKey, ErrShortResponse
+	}
+	buf := resp[1 : 1+int(resp[0])]
+	switch typ {
+	case tezos.KeyTypeEd25519:
+		if len(buf) != 33 || buf[0] != 0x02 {
+			return tezos.InvalidKey, fmt.Errorf("ledger: invalid ed25519 public key")
+		}
+		return tezos.Key{Type: typ, Data: append([]byte{}, buf[1:]...)}, nil
+	case tezos.KeyTypeSecp256k1, tezos.KeyTypeP256:
+		if len(buf) != 65 || buf[0] != 0x04 {
+			return tezos.InvalidKey, fmt.Errorf("ledger: invalid %s public key", typ)
+		}
+		// compress: prefix 0x02 or 0x03 depending on the parity of y
+		data := make([]byte, 33)
+		data[0] = 0x02 | (buf[64] & 0x01)
+		copy(data[1:], buf[1:33])
+		return tezos.Key{Type: typ, Data: data}, nil
+	default:
+		return tezos.InvalidKey, fmt.Errorf("ledger: unsupported key type %s", typ)
+	}
+}
+
+// decodeSignature converts a sign response into a Tezos signature. Ed25519
+// signatures are returned in raw form, ECDSA signatures are DER encoded with
+// the parity bit stored in the first byte.
+func decodeSignature(typ tezos.KeyType, resp []byte) (tezos.Signature, error) {
+	switch typ {
+	case tezos.KeyTypeEd25519:
+		if len(resp) != 64 {
+			return tezos.InvalidSignature, fmt.Errorf("ledger: invalid ed25519 signature length %d", len(resp))
+		}
+		return tezos.Signature{Type: tezos.SignatureTypeEd25519, Data: append([]byte{}, resp...)}, nil
+	case tezos.KeyTypeSecp256k1, tezos.KeyTypeP256:
+		data, err := decodeDER(resp)
+		if err != nil {
+			return tezos.InvalidSignature, err
+		}
+		sigType := tezos.SignatureTypeSecp256k1
+		if typ == tezos.KeyTypeP256 {
+			sigType = tezos.SignatureTypeP256
+		}
+		return tezos.Signature{Type: sigType, Data: data}, nil
+	default:
+		return tezos.InvalidSignature, fmt.Errorf("ledger: unsupported key type %s", typ)
+	}
+}
+
+// decodeDER parses a DER encoded ECDSA signature into 64 bytes r || s.
+func decodeDER(buf []byte) ([]byte, error) {
+	// 0x30|parity len 0x02 rlen r 0x02 slen s
+	if len(buf) < 8 || buf[0]&0xfe != 0x30 || int(buf[1]) != len(buf)-2 {
+		return nil, fmt.Errorf("ledger: invalid DER signature")
+	}
+	out := make([]byte, 64)
+	pos := 2
+	for i := 0; i < 2; i++ {
+		if pos+2 > len(buf) || buf[pos] != 0x02 {
+			return nil, fmt.Errorf("ledger: invalid DER signature")
+		}
+		n := int(buf[pos+1])
+		pos += 2
+		if pos+n > len(buf) {
+			return nil, fmt.Errorf("ledger: invalid DER signature")
+		}
+		v := buf[pos : pos+n]
+		// strip sign padding
+		for len(v) > 32 && v[0] == 0 {
+			v = v[1:]
+		}
+		if len(v) > 32 {
+			return nil, fmt.Errorf("ledger: invalid DER signature")
+		}
+		copy(out[32*(i+1)-len(v):32*(i+1)], v)
+		pos += n
+	}
+	return out, nil
+}
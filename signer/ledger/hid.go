@@ -0,0 +1,104 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package ledger
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// Ledger HID framing constants
+const (
+	hidChannel    = 0x0101
+	hidTagAPDU    = 0x05
+	hidPacketSize = 64
+)
+
+// HIDTransport implements Transport on top of a raw USB HID connection to a
+// Ledger device. APDUs are split into 64 byte HID frames with channel, tag and
+// sequence header as defined by the Ledger HID protocol.
+//
+// Opening the HID device is platform specific and left to the caller. Any
+// HID library that exposes a device as io.ReadWriteCloser with one report per
+// Read and Write call can be used.
+type HIDTransport struct {
+	dev io.ReadWriteCloser
+}
+
+// NewHIDTransport returns a transport that exchanges APDUs over dev.
+func NewHIDTransport(dev io.ReadWriteCloser) *HIDTransport {
+	return &HIDTransport{dev: dev}
+}
+
+// Exchange sends a command APDU and returns the device response including
+// the trailing status word.
+func (t *HIDTransport) Exchange(apdu []byte) ([]byte, error) {
+	for _, frame := range wrapHID(apdu) {
+		if _, err := t.dev.Write(frame); err != nil {
+			return nil, fmt.Errorf("ledger: hid write: %w", err)
+		}
+	}
+	return t.readHID()
+}
+
+// Close closes the HID device.
+func (t *HIDTransport) Close() error {
+	return t.dev.Close()
+}
+
+// wrapHID splits an APDU into HID frames. The first frame carries the total
+// APDU length, all frames are zero padded to the HID packet size.
+func wrapHID(apdu []byte) [][]byte {
+	data := make([]byte, 2+len(apdu))
+	binary.BigEndian.PutUint16(data, uint16(len(apdu)))
+	copy(data[2:], apdu)
+	frames := make([][]byte, 0)
+	for seq := 0; len(data) > 0; seq++ {
+		frame := make([]byte, hidPacketSize)
+		binary.BigEndian.PutUint16(frame, hidChannel)
+		frame[2] = hidTagAPDU
+		binary.BigEndian.PutUint16(frame[3:], uint16(seq))
+		n := copy(frame[5:], data)
+		data = data[n:]
+		frames = append(frames, frame)
+	}
+	return frames
+}
+
+// readHID reads HID frames until a complete response has been received.
+func (t *HIDTransport) readHID() ([]byte, error) {
+	var (
+		resp []byte
+		size = -1
+		buf  = make([]byte, hidPacketSize)
+	)
+	for seq := 0; size < 0 || len(resp) < size; seq++ {
+		n, err := t.dev.Read(buf)
+		if err != nil {
+			return nil, fmt.Errorf("ledger: hid read: %w", err)
+		}
+		frame := buf[:n]
+		if len(frame) < 5 ||
+			binary.BigEndian.Uint16(frame) != hidChannel ||
+			frame[2] != hidTagAPDU ||
+			binary.BigEndian.Uint16(frame[3:]) != uint16(seq) {
+			return nil, fmt.Errorf("ledger: invalid hid frame %d", seq)
+		}
+		frame = frame[5:]
+		if seq == 0 {
+			if len(frame) < 2 {
+				return nil, ErrShortResponse
+			}
+			size = int(binary.BigEndian.Uint16(frame))
+			frame = frame[2:]
+			resp = make([]byte, 0, size)
+		}
+		if rest := size - len(resp); len(frame) > rest {
+			frame = frame[:rest]
+		}
+		resp = append(resp, frame...)
+	}
+	return resp, nil
+}
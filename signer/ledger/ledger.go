@@ -0,0 +1,275 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+// Package ledger implements a signer backed by the Tezos Wallet or Baking app
+// running on a Ledger hardware wallet.
+//
+// The device is accessed through the Transport interface. HIDTransport
+// implements the Ledger HID framing on top of an already opened USB HID device
+// (Ledger devices use vendor id 0x2c97). Device discovery and opening are
+// platform specific and out of scope for this package. Tests can use a mock
+// Transport instead.
+package ledger
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"blockwatch.cc/tzgo/codec"
+	"blockwatch.cc/tzgo/micheline"
+	"blockwatch.cc/tzgo/signer"
+	"blockwatch.cc/tzgo/tezos"
+)
+
+// Ensure Signer implements the signer.Signer interface.
+var _ signer.Signer = (*Signer)(nil)
+
+// AppType identifies the Tezos Ledger app running on the device.
+type AppType byte
+
+const (
+	AppWallet AppType = 0
+	AppBaking AppType = 1
+)
+
+func (t AppType) String() string {
+	switch t {
+	case AppWallet:
+		return "wallet"
+	case AppBaking:
+		return "baking"
+	default:
+		return "unknown"
+	}
+}
+
+// Version is the app version reported by the device.
+type Version struct {
+	App   AppType
+	Major byte
+	Minor byte
+	Patch byte
+}
+
+func (v Version) String() string {
+	return fmt.Sprintf("%s %d.%d.%d", v.App, v.Major, v.Minor, v.Patch)
+}
+
+// Signer is a remote signer that uses a Ledger device for key derivation and
+// signing. Private keys never leave the device.
+type Signer struct {
+	mu        sync.Mutex
+	transport Transport
+	path      Path
+	typ       tezos.KeyType
+	version   *Version
+	key       tezos.Key
+}
+
+// New returns a signer using transport t that derives keys of type typ from path.
+func New(t Transport, path Path, typ tezos.KeyType) *Signer {
+	if path == nil {
+		path = DefaultPath
+	}
+	return &Signer{
+		transport: t,
+		path:      path,
+		typ:       typ,
+	}
+}
+
+// Close closes the underlying transport.
+func (s *Signer) Close() error {
+	return s.transport.Close()
+}
+
+// Path returns the derivation path used by this signer.
+func (s *Signer) Path() Path {
+	return s.path
+}
+
+// Version returns the version and type of the Tezos app running on the device.
+func (s *Signer) Version(ctx context.Context) (Version, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.getVersion(ctx)
+}
+
+// Address returns the address for the signer's derivation path.
+func (s *Signer) Address(ctx context.Context) (tezos.Address, error) {
+	key, err := s.Key(ctx)
+	if err != nil {
+		return tezos.InvalidAddress, err
+	}
+	return key.Address(), nil
+}
+
+// Key returns the public key for the signer's derivation path without
+// user interaction.
+func (s *Signer) Key(ctx context.Context) (tezos.Key, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.key.IsValid() {
+		return s.key, nil
+	}
+	return s.getKey(ctx, insGetPublicKey)
+}
+
+// PromptKey returns the public key for the signer's derivation path after
+// displaying the address on the device and waiting for user confirmation.
+func (s *Signer) PromptKey(ctx context.Context) (tezos.Key, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.getKey(ctx, insPromptPublicKey)
+}
+
+// SignMessage signs msg as packed Micheline string. Message signing is only
+// supported by the Wallet app.
+func (s *Signer) SignMessage(ctx context.Context, msg string) (tezos.Signature, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, err := s.getVersion(ctx)
+	if err != nil {
+		return tezos.InvalidSignature, err
+	}
+	if v.App != AppWallet {
+		return tezos.InvalidSignature, fmt.Errorf("%w: message signing requires wallet app", ErrUnsupported)
+	}
+	return s.sign(ctx, micheline.NewString(msg).Pack())
+}
+
+// SignOperation signs a watermarked operation. The Baking app only signs
+// consensus operations, reveals and delegations.
+func (s *Signer) SignOperation(ctx context.Context, op *codec.Op) (tezos.Signature, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, err := s.getVersion(ctx)
+	if err != nil {
+		return tezos.InvalidSignature, err
+	}
+	if v.App == AppBaking {
+		for _, c := range op.Contents {
+			switch c.Kind() {
+			case tezos.OpTypeEndorsement,
+				tezos.OpTypeEndorsementWithSlot,
+				tezos.OpTypePreEndorsement,
+				tezos.OpTypeReveal,
+				tezos.OpTypeDelegation:
+			default:
+				return tezos.InvalidSignature, fmt.Errorf("%w: baking app cannot sign %s", ErrUnsupported, c.Kind())
+			}
+		}
+	}
+	buf := op.WatermarkedBytes()
+	if buf == nil {
+		return tezos.InvalidSignature, fmt.Errorf("ledger: empty operation or missing branch")
+	}
+	return s.sign(ctx, buf)
+}
+
+// SignBlock signs a watermarked block header. Block signing is only supported
+// by the Baking app.
+func (s *Signer) SignBlock(ctx context.Context, head *codec.BlockHeader) (tezos.Signature, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, err := s.getVersion(ctx)
+	if err != nil {
+		return tezos.InvalidSignature, err
+	}
+	if v.App != AppBaking {
+		return tezos.InvalidSignature, fmt.Errorf("%w: block signing requires baking app", ErrUnsupported)
+	}
+	return s.sign(ctx, head.WatermarkedBytes())
+}
+
+func (s *Signer) curve() (byte, error) {
+	switch s.typ {
+	case tezos.KeyTypeEd25519:
+		return 0x00, nil
+	case tezos.KeyTypeSecp256k1:
+		return 0x01, nil
+	case tezos.KeyTypeP256:
+		return 0x02, nil
+	default:
+		return 0, fmt.Errorf("ledger: unsupported key type %s", s.typ)
+	}
+}
+
+func (s *Signer) getVersion(ctx context.Context) (Version, error) {
+	if s.version != nil {
+		return *s.version, nil
+	}
+	if err := ctx.Err(); err != nil {
+		return Version{}, err
+	}
+	resp, err := exchange(s.transport, insVersion, 0, 0, nil)
+	if err != nil {
+		return Version{}, err
+	}
+	if len(resp) < 4 {
+		return Version{}, ErrShortResponse
+	}
+	s.version = &Version{
+		App:   AppType(resp[0]),
+		Major: resp[1],
+		Minor: resp[2],
+		Patch: resp[3],
+	}
+	return *s.version, nil
+}
+
+func (s *Signer) getKey(ctx context.Context, ins byte) (tezos.Key, error) {
+	curve, err := s.curve()
+	if err != nil {
+		return tezos.InvalidKey, err
+	}
+	if err := ctx.Err(); err != nil {
+		return tezos.InvalidKey, err
+	}
+	resp, err := exchange(s.transport, ins, 0, curve, s.path.Bytes())
+	if err != nil {
+		return tezos.InvalidKey, err
+	}
+	key, err := decodePublicKey(s.typ, resp)
+	if err != nil {
+		return tezos.InvalidKey, err
+	}
+	s.key = key
+	return key, nil
+}
+
+// sign sends watermarked data in chunks. The first message contains the
+// derivation path, the last chunk is marked with a flag.
+func (s *Signer) sign(ctx context.Context, data []byte) (tezos.Signature, error) {
+	curve, err := s.curve()
+	if err != nil {
+		return tezos.InvalidSignature, err
+	}
+	if err := ctx.Err(); err != nil {
+		return tezos.InvalidSignature, err
+	}
+	if _, err := exchange(s.transport, insSign, p1First, curve, s.path.Bytes()); err != nil {
+		return tezos.InvalidSignature, err
+	}
+	var resp []byte
+	for len(data) > 0 {
+		if err := ctx.Err(); err != nil {
+			return tezos.InvalidSignature, err
+		}
+		n := len(data)
+		if n > maxChunkSize {
+			n = maxChunkSize
+		}
+		p1 := byte(p1Next)
+		if n == len(data) {
+			p1 |= p1LastFlag
+		}
+		resp, err = exchange(s.transport, insSign, p1, curve, data[:n])
+		if err != nil {
+			return tezos.InvalidSignature, err
+		}
+		data = data[n:]
+	}
+	return decodeSignature(s.typ, resp)
+}
@@ -0,0 +1,247 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package ledger
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"blockwatch.cc/tzgo/tezos"
+)
+
+// fakeDevice is a Transport that records command APDUs and replies with
+// scripted responses.
+type fakeDevice struct {
+	sent    [][]byte
+	replies [][]byte
+	closed  bool
+}
+
+func (d *fakeDevice) Exchange(apdu []byte) ([]byte, error) {
+	d.sent = append(d.sent, append([]byte{}, apdu...))
+	if len(d.replies) == 0 {
+		return nil, io.EOF
+	}
+	resp := d.replies[0]
+	d.replies = d.replies[1:]
+	return resp, nil
+}
+
+func (d *fakeDevice) Close() error {
+	d.closed = true
+	return nil
+}
+
+func (d *fakeDevice) reply(data []byte, sw uint16) {
+	d.replies = append(d.replies, append(append([]byte{}, data...), byte(sw>>8), byte(sw)))
+}
+
+func TestExchange(t *testing.T) {
+	d := &fakeDevice{}
+	d.reply([]byte{1, 2, 3}, swOk)
+	resp, err := exchange(d, insGetPublicKey, 0x01, 0x02, []byte{0xaa, 0xbb})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []byte{claTezos, insGetPublicKey, 0x01, 0x02, 0x02, 0xaa, 0xbb}; !bytes.Equal(d.sent[0], want) {
+		t.Errorf("apdu: got %x want %x", d.sent[0], want)
+	}
+	if !bytes.Equal(resp, []byte{1, 2, 3}) {
+		t.Errorf("response: got %x", resp)
+	}
+
+	if _, err := exchange(d, insSign, 0, 0, make([]byte, 256)); err != ErrInvalidDataLen {
+		t.Errorf("oversized data: got %v", err)
+	}
+
+	for _, test := range []struct {
+		sw   uint16
+		want error
+	}{
+		{swRejected, ErrRejected},
+		{swAppNotOpen, ErrAppNotOpen},
+		{swClassNotSupported, ErrAppNotOpen},
+		{swDashboardAppNotOpen, ErrAppNotOpen},
+		{swParseError, ErrParse},
+		{swDeviceLocked, ErrLocked},
+		{swSecurity, ErrSecurity},
+		{swWrongLength, ErrInvalidData},
+		{0x6f00, StatusError(0x6f00)},
+	} {
+		d.reply(nil, test.sw)
+		if _, err := exchange(d, insVersion, 0, 0, nil); err != test.want {
+			t.Errorf("sw 0x%04x: got %v want %v", test.sw, err, test.want)
+		}
+	}
+
+	d.replies = append(d.replies, []byte{0x90})
+	if _, err := exchange(d, insVersion, 0, 0, nil); err != ErrShortResponse {
+		t.Errorf("short response: got %v", err)
+	}
+}
+
+func TestSignChunks(t *testing.T) {
+	d := &fakeDevice{}
+	d.reply([]byte{byte(AppWallet), 2, 3, 4}, swOk)
+	d.reply(nil, swOk)
+	for i := 0; i < 2; i++ {
+		d.reply(nil, swOk)
+	}
+	sig := bytes.Repeat([]byte{0x5a}, 64)
+	d.reply(sig, swOk)
+
+	s := New(d, nil, tezos.KeyTypeEd25519)
+	data := make([]byte, 2*maxChunkSize+40)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	if _, err := s.Version(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	got, err := s.sign(context.Background(), data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Type != tezos.SignatureTypeEd25519 || !bytes.Equal(got.Data, sig) {
+		t.Errorf("signature: got %s", got)
+	}
+
+	// version, path, 3 data chunks
+	if len(d.sent) != 5 {
+		t.Fatalf("sent %d apdus", len(d.sent))
+	}
+	if want := append([]byte{claTezos, insSign, p1First, 0x00, 17}, DefaultPath.Bytes()...); !bytes.Equal(d.sent[1], want) {
+		t.Errorf("path apdu: got %x want %x", d.sent[1], want)
+	}
+	var sent []byte
+	for i, p1 := range []byte{p1Next, p1Next, p1Next | p1LastFlag} {
+		apdu := d.sent[2+i]
+		if apdu[1] != insSign || apdu[2] != p1 || int(apdu[4]) != len(apdu)-5 {
+			t.Errorf("chunk %d: unexpected header %x", i, apdu[:5])
+		}
+		sent = append(sent, apdu[5:]...)
+	}
+	if !bytes.Equal(sent, data) {
+		t.Errorf("chunks do not reassemble data")
+	}
+}
+
+func TestSignRejected(t *testing.T) {
+	d := &fakeDevice{}
+	d.reply([]byte{byte(AppWallet), 2, 3, 4}, swOk)
+	d.reply(nil, swOk)
+	d.reply(nil, swRejected)
+	s := New(d, nil, tezos.KeyTypeEd25519)
+	if _, err := s.SignMessage(context.Background(), "hello"); !errors.Is(err, ErrRejected) {
+		t.Errorf("expected ErrRejected, got %v", err)
+	}
+}
+
+func TestBakingAppRestrictions(t *testing.T) {
+	d := &fakeDevice{}
+	d.reply([]byte{byte(AppBaking), 2, 3, 4}, swOk)
+	s := New(d, nil, tezos.KeyTypeEd25519)
+	if _, err := s.SignMessage(context.Background(), "hello"); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("expected ErrUnsupported, got %v", err)
+	}
+	// the version is cached and nothing is sent to the device
+	if len(d.sent) != 1 {
+		t.Errorf("sent %d apdus", len(d.sent))
+	}
+}
+
+func TestGetKey(t *testing.T) {
+	pk := bytes.Repeat([]byte{0x11}, 32)
+	d := &fakeDevice{}
+	d.reply(append([]byte{33, 0x02}, pk...), swOk)
+	s := New(d, nil, tezos.KeyTypeEd25519)
+	key, err := s.Key(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key.Type != tezos.KeyTypeEd25519 || !bytes.Equal(key.Data, pk) {
+		t.Errorf("key: got %s", key)
+	}
+	if want := append([]byte{claTezos, insGetPublicKey, 0x00, 0x00, 17}, DefaultPath.Bytes()...); !bytes.Equal(d.sent[0], want) {
+		t.Errorf("apdu: got %x want %x", d.sent[0], want)
+	}
+	// cached
+	if _, err := s.Key(context.Background()); err != nil || len(d.sent) != 1 {
+		t.Errorf("key not cached: sent=%d err=%v", len(d.sent), err)
+	}
+	if err := s.Close(); err != nil || !d.closed {
+		t.Errorf("transport not closed")
+	}
+}
+
+// fakeHID is a HID device that records written frames and replies with
+// queued frames, one per Read.
+type fakeHID struct {
+	written [][]byte
+	frames  [][]byte
+}
+
+func (h *fakeHID) Write(p []byte) (int, error) {
+	h.written = append(h.written, append([]byte{}, p...))
+	return len(p), nil
+}
+
+func (h *fakeHID) Read(p []byte) (int, error) {
+	if len(h.frames) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(p, h.frames[0])
+	h.frames = h.frames[1:]
+	return n, nil
+}
+
+func (h *fakeHID) Close() error {
+	return nil
+}
+
+func TestHIDTransport(t *testing.T) {
+	apdu := make([]byte, 5+maxChunkSize)
+	for i := range apdu {
+		apdu[i] = byte(i)
+	}
+	resp := append(bytes.Repeat([]byte{0x77}, 100), 0x90, 0x00)
+
+	dev := &fakeHID{frames: wrapHID(resp)}
+	tr := NewHIDTransport(dev)
+	got, err := tr.Exchange(apdu)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, resp) {
+		t.Errorf("response: got %x want %x", got, resp)
+	}
+
+	// 2 length bytes + 235 APDU bytes span 5 frames of 59 payload bytes
+	if len(dev.written) != 5 {
+		t.Fatalf("wrote %d frames", len(dev.written))
+	}
+	var sent []byte
+	for i, frame := range dev.written {
+		if len(frame) != hidPacketSize {
+			t.Errorf("frame %d: size %d", i, len(frame))
+		}
+		if want := []byte{0x01, 0x01, hidTagAPDU, 0x00, byte(i)}; !bytes.Equal(frame[:5], want) {
+			t.Errorf("frame %d: header %x want %x", i, frame[:5], want)
+		}
+		sent = append(sent, frame[5:]...)
+	}
+	if sent[0] != 0x00 || sent[1] != byte(len(apdu)) || !bytes.Equal(sent[2:2+len(apdu)], apdu) {
+		t.Errorf("frames do not reassemble apdu")
+	}
+
+	// out of order frames are rejected
+	frames := wrapHID(resp)
+	dev = &fakeHID{frames: [][]byte{frames[1], frames[0]}}
+	if _, err := NewHIDTransport(dev).Exchange(apdu); err == nil {
+		t.Errorf("expected error for out of order frames")
+	}
+}
@@ -0,0 +1,51 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package signer
+
+import (
+	"context"
+	"errors"
+
+	"blockwatch.cc/tzgo/codec"
+	"blockwatch.cc/tzgo/tezos"
+)
+
+var _ Signer = (*MemorySigner)(nil)
+
+// MemorySigner signs with a private key held in memory.
+type MemorySigner struct {
+	key tezos.PrivateKey
+}
+
+// NewFromKey returns a signer for private key k.
+func NewFromKey(k tezos.PrivateKey) *MemorySigner {
+	return &MemorySigner{key: k}
+}
+
+func (s *MemorySigner) Address(_ context.Context) (tezos.Address, error) {
+	return s.key.Address(), nil
+}
+
+func (s *MemorySigner) Key(_ context.Context) (tezos.Key, error) {
+	return s.key.Public(), nil
+}
+
+// SignMessage signs msg as packed Micheline string.
+func (s *MemorySigner) SignMessage(_ context.Context, msg string) (tezos.Signature, error) {
+	digest := tezos.Digest(PackMessage(msg))
+	return s.key.Sign(digest[:])
+}
+
+// SignOperation signs the watermarked digest of op.
+func (s *MemorySigner) SignOperation(_ context.Context, op *codec.Op) (tezos.Signature, error) {
+	if op.WatermarkedBytes() == nil {
+		return tezos.InvalidSignature, errors.New("signer: empty operation or missing branch")
+	}
+	return s.key.Sign(op.Digest())
+}
+
+// SignBlock signs the watermarked digest of head.
+func (s *MemorySigner) SignBlock(_ context.Context, head *codec.BlockHeader) (tezos.Signature, error) {
+	return s.key.Sign(head.Digest())
+}
@@ -0,0 +1,52 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package signer
+
+import (
+	"context"
+
+	"blockwatch.cc/tzgo/codec"
+	"blockwatch.cc/tzgo/micheline"
+	"blockwatch.cc/tzgo/tezos"
+)
+
+// NewMessageOp wraps an arbitrary message into a failing_noop operation. Such
+// operations are guaranteed to fail when injected, so signatures over them cannot
+// be replayed on chain. When branch is invalid the zero block hash is used.
+func NewMessageOp(branch tezos.BlockHash, msg []byte) *codec.Op {
+	if !branch.IsValid() {
+		branch = tezos.ZeroBlockHash
+	}
+	return codec.NewOp().
+		WithBranch(branch).
+		WithContents(&codec.FailingNoop{Arbitrary: string(msg)})
+}
+
+// SignMessageOp signs msg wrapped into a failing_noop operation using s.
+func SignMessageOp(ctx context.Context, s Signer, branch tezos.BlockHash, msg []byte) (tezos.Signature, error) {
+	return s.SignOperation(ctx, NewMessageOp(branch, msg))
+}
+
+// VerifyMessageOp checks a signature created by SignMessageOp.
+func VerifyMessageOp(key tezos.Key, branch tezos.BlockHash, msg []byte, sig tezos.Signature) error {
+	return key.Verify(NewMessageOp(branch, msg).Digest(), sig)
+}
+
+// PackMessage returns msg as packed Micheline string, i.e. 0x05 0x01 followed by
+// the 4 byte length and the UTF-8 bytes of msg. This is the format dapps use for
+// off-chain signing requests like "Sign in with Tezos".
+func PackMessage(msg string) []byte {
+	return micheline.NewString(msg).Pack()
+}
+
+// SignPackedMessage signs msg as packed Micheline string using s.
+func SignPackedMessage(ctx context.Context, s Signer, msg string) (tezos.Signature, error) {
+	return s.SignMessage(ctx, msg)
+}
+
+// VerifyPackedMessage checks a signature created by SignPackedMessage.
+func VerifyPackedMessage(key tezos.Key, msg string, sig tezos.Signature) error {
+	digest := tezos.Digest(PackMessage(msg))
+	return key.Verify(digest[:], sig)
+}
@@ -0,0 +1,108 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package signer
+
+import (
+	"context"
+	"encoding/hex"
+	"testing"
+
+	"blockwatch.cc/tzgo/tezos"
+)
+
+func TestPackMessage(t *testing.T) {
+	for _, test := range []struct {
+		msg  string
+		want string
+	}{
+		{"", "050100000000"},
+		{"hi", "0501000000026869"},
+		{"é", "050100000002c3a9"},
+		{"Tezos Signed Message: x", "050100000017" + hex.EncodeToString([]byte("Tezos Signed Message: x"))},
+	} {
+		if got := hex.EncodeToString(PackMessage(test.msg)); got != test.want {
+			t.Errorf("%q: got %s want %s", test.msg, got, test.want)
+		}
+	}
+}
+
+func TestNewMessageOp(t *testing.T) {
+	branch := tezos.MustParseBlockHash("BLockGenesisGenesisGenesisGenesisGenesisf79b5d1CoW2")
+	for _, test := range []struct {
+		name   string
+		branch tezos.BlockHash
+		msg    []byte
+		want   string
+	}{
+		{"zero branch", tezos.BlockHash{}, []byte("hi"), hex.EncodeToString(tezos.ZeroBlockHash.Bytes()) + "11000000026869"},
+		{"branch", branch, []byte("hi"), hex.EncodeToString(branch.Bytes()) + "11000000026869"},
+		{"empty", branch, nil, hex.EncodeToString(branch.Bytes()) + "1100000000"},
+	} {
+		if got := hex.EncodeToString(NewMessageOp(test.branch, test.msg).Bytes()); got != test.want {
+			t.Errorf("%s: got %s want %s", test.name, got, test.want)
+		}
+	}
+}
+
+func TestMessageSignatures(t *testing.T) {
+	ctx := context.Background()
+	branch := tezos.MustParseBlockHash("BLockGenesisGenesisGenesisGenesisGenesisf79b5d1CoW2")
+	msg := []byte("hello")
+	other, err := tezos.GenerateKey(tezos.KeyTypeEd25519)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, typ := range []tezos.KeyType{tezos.KeyTypeEd25519, tezos.KeyTypeSecp256k1, tezos.KeyTypeP256} {
+		sk, err := tezos.GenerateKey(typ)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s := NewFromKey(sk)
+		pk := sk.Public()
+
+		sig, err := SignMessageOp(ctx, s, branch, msg)
+		if err != nil {
+			t.Fatalf("%s: %v", typ, err)
+		}
+		for _, test := range []struct {
+			name   string
+			key    tezos.Key
+			branch tezos.BlockHash
+			msg    []byte
+			ok     bool
+		}{
+			{"valid", pk, branch, msg, true},
+			{"other message", pk, branch, []byte("hello!"), false},
+			{"other branch", pk, tezos.ZeroBlockHash, msg, false},
+			{"other key", other.Public(), branch, msg, false},
+		} {
+			if err := VerifyMessageOp(test.key, test.branch, test.msg, sig); (err == nil) != test.ok {
+				t.Errorf("%s op %s: verify got %v", typ, test.name, err)
+			}
+		}
+
+		sig, err = SignPackedMessage(ctx, s, string(msg))
+		if err != nil {
+			t.Fatalf("%s: %v", typ, err)
+		}
+		for _, test := range []struct {
+			name string
+			key  tezos.Key
+			msg  string
+			ok   bool
+		}{
+			{"valid", pk, "hello", true},
+			{"other message", pk, "hello!", false},
+			{"other key", other.Public(), "hello", false},
+		} {
+			if err := VerifyPackedMessage(test.key, test.msg, sig); (err == nil) != test.ok {
+				t.Errorf("%s packed %s: verify got %v", typ, test.name, err)
+			}
+		}
+		// packed and operation signatures are not interchangeable
+		if err := VerifyMessageOp(pk, branch, msg, sig); err == nil {
+			t.Errorf("%s: packed signature verifies as operation", typ)
+		}
+	}
+}
@@ -11,9 +11,9 @@ import (
 )
 
 type Signer interface {
-	Address(context.Context) (tezos.Address, error) // returns address
-	Key(context.Context) (tezos.Key, error)         // returns public key
-	SignMessage(context.Context, string) (tezos.Signature, error)
+	Address(context.Context) (tezos.Address, error)               // returns address
+	Key(context.Context) (tezos.Key, error)                       // returns public key
+	SignMessage(context.Context, string) (tezos.Signature, error) // signs packed Micheline string
 	SignOperation(context.Context, *codec.Op) (tezos.Signature, error)
 	SignBlock(context.Context, *codec.BlockHeader) (tezos.Signature, error)
 }
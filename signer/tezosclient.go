@@ -0,0 +1,215 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package signer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"blockwatch.cc/tzgo/tezos"
+)
+
+// Secret key locator schemes used by tezos-client.
+const (
+	SchemeUnencrypted = "unencrypted:"
+	SchemeEncrypted   = "encrypted:"
+	SchemeLedger      = "ledger://"
+)
+
+var ErrNoSecretKey = errors.New("signer: no secret key")
+
+// ClientAccount is an account imported from a tezos-client data directory.
+type ClientAccount struct {
+	Name      string        // alias
+	Address   tezos.Address // public key hash
+	Key       tezos.Key     // public key, invalid when unknown
+	SecretURI string        // secret key locator, empty for watch-only accounts
+}
+
+// IsEncrypted returns true when the secret key is stored encrypted.
+func (a ClientAccount) IsEncrypted() bool {
+	return strings.HasPrefix(a.SecretURI, SchemeEncrypted)
+}
+
+// IsLedger returns true when the secret key is held by a Ledger device.
+func (a ClientAccount) IsLedger() bool {
+	return strings.HasPrefix(a.SecretURI, SchemeLedger)
+}
+
+// Ledger returns the key type and full derivation path of a Ledger account
+// as used by the ledger signer. Ledger URIs have the form
+// ledger://<animals>/<curve>/<path> with path relative to 44'/1729'.
+func (a ClientAccount) Ledger() (tezos.KeyType, string, error) {
+	if !a.IsLedger() {
+		return tezos.KeyTypeInvalid, "", fmt.Errorf("signer: %s is not a ledger account", a.Name)
+	}
+	parts := strings.SplitN(strings.TrimPrefix(a.SecretURI, SchemeLedger), "/", 3)
+	if len(parts) < 2 {
+		return tezos.KeyTypeInvalid, "", fmt.Errorf("signer: invalid ledger uri %q", a.SecretURI)
+	}
+	var typ tezos.KeyType
+	switch parts[1] {
+	case "ed25519", "bip25519":
+		typ = tezos.KeyTypeEd25519
+	case "secp256k1":
+		typ = tezos.KeyTypeSecp256k1
+	case "P-256", "p256":
+		typ = tezos.KeyTypeP256
+	default:
+		return tezos.KeyTypeInvalid, "", fmt.Errorf("signer: unsupported ledger curve %q", parts[1])
+	}
+	path := "44'/1729'"
+	if len(parts) == 3 && parts[2] != "" {
+		path += "/" + parts[2]
+	}
+	return typ, path, nil
+}
+
+// PrivateKey returns the account's private key. Encrypted keys are decrypted
+// with a passphrase obtained from fn.
+func (a ClientAccount) PrivateKey(fn tezos.PassphraseFunc) (tezos.PrivateKey, error) {
+	switch {
+	case a.SecretURI == "":
+		return tezos.PrivateKey{}, ErrNoSecretKey
+	case strings.HasPrefix(a.SecretURI, SchemeUnencrypted):
+		return tezos.ParsePrivateKey(strings.TrimPrefix(a.SecretURI, SchemeUnencrypted))
+	case a.IsEncrypted():
+		return tezos.ParseEncryptedPrivateKey(strings.TrimPrefix(a.SecretURI, SchemeEncrypted), fn)
+	default:
+		return tezos.PrivateKey{}, fmt.Errorf("signer: unsupported secret key locator for %s", a.Name)
+	}
+}
+
+// Signer returns an in-memory signer for accounts with unencrypted or encrypted
+// secret keys. Ledger accounts must be opened with the ledger package, see
+// Ledger for the derivation path.
+func (a ClientAccount) Signer(fn tezos.PassphraseFunc) (Signer, error) {
+	sk, err := a.PrivateKey(fn)
+	if err != nil {
+		return nil, err
+	}
+	return NewFromKey(sk), nil
+}
+
+// DefaultTezosClientDir returns the default tezos-client data directory.
+func DefaultTezosClientDir() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".tezos-client")
+}
+
+type clientEntry struct {
+	Name  string          `json:"name"`
+	Value json.RawMessage `json:"value"`
+}
+
+// LoadFromTezosClient reads accounts from the public_key_hashs, public_keys
+// and secret_keys files in a tezos-client data directory. When dir is empty
+// the default directory is used. Missing files are ignored. Accounts are
+// returned in the order of the public_key_hashs file.
+func LoadFromTezosClient(dir string) ([]ClientAccount, error) {
+	if dir == "" {
+		dir = DefaultTezosClientDir()
+	}
+	pkhs, err := readClientFile(dir, "public_key_hashs")
+	if err != nil {
+		return nil, err
+	}
+	pks, err := readClientFile(dir, "public_keys")
+	if err != nil {
+		return nil, err
+	}
+	sks, err := readClientFile(dir, "secret_keys")
+	if err != nil {
+		return nil, err
+	}
+
+	accounts := make([]ClientAccount, 0, len(pkhs))
+	index := make(map[string]int)
+	get := func(name string) *ClientAccount {
+		i, ok := index[name]
+		if !ok {
+			i = len(accounts)
+			index[name] = i
+			accounts = append(accounts, ClientAccount{Name: name})
+		}
+		return &accounts[i]
+	}
+	for _, v := range pkhs {
+		var s string
+		if err := json.Unmarshal(v.Value, &s); err != nil {
+			return nil, fmt.Errorf("signer: public key hash %s: %w", v.Name, err)
+		}
+		addr, err := tezos.ParseAddress(s)
+		if err != nil {
+			return nil, fmt.Errorf("signer: public key hash %s: %w", v.Name, err)
+		}
+		get(v.Name).Address = addr
+	}
+	for _, v := range pks {
+		// newer versions store {"locator":..,"key":..}, older ones the locator only
+		var (
+			s   string
+			loc struct {
+				Locator string `json:"locator"`
+				Key     string `json:"key"`
+			}
+		)
+		if err := json.Unmarshal(v.Value, &loc); err == nil {
+			s = loc.Key
+			if s == "" {
+				s = loc.Locator
+			}
+		} else if err := json.Unmarshal(v.Value, &s); err != nil {
+			return nil, fmt.Errorf("signer: public key %s: %w", v.Name, err)
+		}
+		if i := strings.LastIndexAny(s, ":/"); i >= 0 {
+			s = s[i+1:]
+		}
+		key, err := tezos.ParseKey(s)
+		if err != nil {
+			// ledger locators do not contain the key
+			continue
+		}
+		acc := get(v.Name)
+		acc.Key = key
+		if !acc.Address.IsValid() {
+			acc.Address = key.Address()
+		}
+	}
+	for _, v := range sks {
+		var s string
+		if err := json.Unmarshal(v.Value, &s); err != nil {
+			return nil, fmt.Errorf("signer: secret key %s: %w", v.Name, err)
+		}
+		acc := get(v.Name)
+		acc.SecretURI = s
+		if !acc.Address.IsValid() && strings.HasPrefix(s, SchemeUnencrypted) {
+			if sk, err := tezos.ParsePrivateKey(strings.TrimPrefix(s, SchemeUnencrypted)); err == nil {
+				acc.Key = sk.Public()
+				acc.Address = sk.Address()
+			}
+		}
+	}
+	return accounts, nil
+}
+
+func readClientFile(dir, name string) ([]clientEntry, error) {
+	buf, err := ioutil.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var list []clientEntry
+	if err := json.Unmarshal(buf, &list); err != nil {
+		return nil, fmt.Errorf("signer: reading %s: %w", name, err)
+	}
+	return list, nil
+}
@@ -0,0 +1,200 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package signer
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"blockwatch.cc/tzgo/tezos"
+)
+
+func TestClientAccountLedger(t *testing.T) {
+	for _, test := range []struct {
+		uri   string
+		typ   tezos.KeyType
+		path  string
+		error bool
+	}{
+		{uri: "ledger://major-squirrel-thick-hedgehog/ed25519/0h/0h", typ: tezos.KeyTypeEd25519, path: "44'/1729'/0h/0h"},
+		{uri: "ledger://major-squirrel-thick-hedgehog/bip25519/0'/1'", typ: tezos.KeyTypeEd25519, path: "44'/1729'/0'/1'"},
+		{uri: "ledger://major-squirrel-thick-hedgehog/secp256k1/0h/0h", typ: tezos.KeyTypeSecp256k1, path: "44'/1729'/0h/0h"},
+		{uri: "ledger://major-squirrel-thick-hedgehog/P-256/1h", typ: tezos.KeyTypeP256, path: "44'/1729'/1h"},
+		{uri: "ledger://major-squirrel-thick-hedgehog/p256", typ: tezos.KeyTypeP256, path: "44'/1729'"},
+		{uri: "ledger://major-squirrel-thick-hedgehog/ed25519/", typ: tezos.KeyTypeEd25519, path: "44'/1729'"},
+		{uri: "ledger://major-squirrel-thick-hedgehog", error: true},
+		{uri: "ledger://major-squirrel-thick-hedgehog/bls/0h", error: true},
+		{uri: "unencrypted:edsk", error: true},
+		{uri: "", error: true},
+	} {
+		acc := ClientAccount{Name: "test", SecretURI: test.uri}
+		typ, path, err := acc.Ledger()
+		if test.error {
+			if err == nil {
+				t.Errorf("%q: expected error", test.uri)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", test.uri, err)
+			continue
+		}
+		if typ != test.typ || path != test.path {
+			t.Errorf("%q: got %s %s want %s %s", test.uri, typ, path, test.typ, test.path)
+		}
+	}
+}
+
+// writeClientFile writes a tezos-client alias file with name/value entries.
+func writeClientFile(t *testing.T, dir, name string, entries ...interface{}) {
+	t.Helper()
+	list := make([]map[string]interface{}, 0, len(entries)/2)
+	for i := 0; i < len(entries); i += 2 {
+		list = append(list, map[string]interface{}{"name": entries[i], "value": entries[i+1]})
+	}
+	buf, err := json.Marshal(list)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, name), buf, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func mustGenerateKey(t *testing.T, typ tezos.KeyType) tezos.PrivateKey {
+	t.Helper()
+	sk, err := tezos.GenerateKey(typ)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sk
+}
+
+func TestLoadFromTezosClient(t *testing.T) {
+	var (
+		alice  = mustGenerateKey(t, tezos.KeyTypeEd25519)
+		bob    = mustGenerateKey(t, tezos.KeyTypeSecp256k1)
+		ledger = mustGenerateKey(t, tezos.KeyTypeEd25519)
+		watch  = mustGenerateKey(t, tezos.KeyTypeP256)
+		orphan = mustGenerateKey(t, tezos.KeyTypeEd25519)
+		uri    = "ledger://major-squirrel-thick-hedgehog/ed25519/0h/0h"
+	)
+	enc, err := bob.Encrypt(tezos.Passphrase("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	writeClientFile(t, dir, "public_key_hashs",
+		"alice", alice.Address().String(),
+		"bob", bob.Address().String(),
+		"ledger", ledger.Address().String(),
+		"watch", watch.Address().String(),
+	)
+	writeClientFile(t, dir, "public_keys",
+		// current format with locator and key
+		"alice", map[string]string{"locator": "unencrypted:" + alice.Public().String(), "key": alice.Public().String()},
+		// old format with locator only
+		"bob", "unencrypted:"+bob.Public().String(),
+		// ledger locators carry no key
+		"ledger", map[string]string{"locator": uri},
+		"watch", map[string]string{"locator": "unencrypted:" + watch.Public().String(), "key": watch.Public().String()},
+	)
+	writeClientFile(t, dir, "secret_keys",
+		"alice", "unencrypted:"+alice.String(),
+		"bob", "encrypted:"+enc,
+		"ledger", uri,
+		"orphan", "unencrypted:"+orphan.String(),
+	)
+
+	accounts, err := LoadFromTezosClient(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, test := range []struct {
+		name      string
+		sk        tezos.PrivateKey
+		hasKey    bool
+		secret    bool
+		encrypted bool
+		ledger    bool
+	}{
+		{name: "alice", sk: alice, hasKey: true, secret: true},
+		{name: "bob", sk: bob, hasKey: true, secret: true, encrypted: true},
+		{name: "ledger", sk: ledger, ledger: true},
+		{name: "watch", sk: watch, hasKey: true},
+		{name: "orphan", sk: orphan, hasKey: true, secret: true},
+	} {
+		if i >= len(accounts) {
+			t.Fatalf("missing account %s", test.name)
+		}
+		acc := accounts[i]
+		if acc.Name != test.name || !acc.Address.Equal(test.sk.Address()) {
+			t.Errorf("%d: got %s %s want %s %s", i, acc.Name, acc.Address, test.name, test.sk.Address())
+		}
+		if acc.Key.IsValid() != test.hasKey || test.hasKey && acc.Key.String() != test.sk.Public().String() {
+			t.Errorf("%s: unexpected key %s", test.name, acc.Key)
+		}
+		if acc.IsEncrypted() != test.encrypted || acc.IsLedger() != test.ledger {
+			t.Errorf("%s: encrypted=%t ledger=%t", test.name, acc.IsEncrypted(), acc.IsLedger())
+		}
+		sk, err := acc.PrivateKey(tezos.Passphrase("secret"))
+		switch {
+		case test.secret && err != nil:
+			t.Errorf("%s: private key: %v", test.name, err)
+		case test.secret && sk.String() != test.sk.String():
+			t.Errorf("%s: private key mismatch", test.name)
+		case !test.secret && err == nil:
+			t.Errorf("%s: expected private key error", test.name)
+		}
+	}
+	if len(accounts) != 5 {
+		t.Errorf("got %d accounts", len(accounts))
+	}
+	if _, err := accounts[3].PrivateKey(nil); err != ErrNoSecretKey {
+		t.Errorf("watch-only: unexpected error %v", err)
+	}
+	if _, err := accounts[1].Signer(tezos.Passphrase("wrong")); err == nil {
+		t.Errorf("expected error for wrong passphrase")
+	}
+	if s, err := accounts[0].Signer(nil); err != nil || s == nil {
+		t.Errorf("signer: %v", err)
+	}
+}
+
+func TestLoadFromTezosClientErrors(t *testing.T) {
+	addr := mustGenerateKey(t, tezos.KeyTypeEd25519).Address().String()
+	for _, test := range []struct {
+		name  string
+		file  string
+		value interface{}
+		raw   string
+	}{
+		{name: "invalid json", file: "public_key_hashs", raw: "{"},
+		{name: "invalid address", file: "public_key_hashs", value: "tz1invalid"},
+		{name: "non-string address", file: "public_key_hashs", value: 1},
+		{name: "non-string public key", file: "public_keys", value: 1},
+		{name: "non-string secret key", file: "secret_keys", value: []string{}},
+	} {
+		dir := t.TempDir()
+		writeClientFile(t, dir, "public_key_hashs", "a", addr)
+		if test.raw != "" {
+			if err := ioutil.WriteFile(filepath.Join(dir, test.file), []byte(test.raw), 0600); err != nil {
+				t.Fatal(err)
+			}
+		} else {
+			writeClientFile(t, dir, test.file, "a", test.value)
+		}
+		if _, err := LoadFromTezosClient(dir); err == nil {
+			t.Errorf("%s: expected error", test.name)
+		}
+	}
+
+	// missing files are ignored
+	accounts, err := LoadFromTezosClient(t.TempDir())
+	if err != nil || len(accounts) != 0 {
+		t.Errorf("empty dir: %v %v", accounts, err)
+	}
+}
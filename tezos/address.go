@@ -7,6 +7,7 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"sort"
 	"strings"
 
 	"blockwatch.cc/tzgo/base58"
@@ -42,6 +43,9 @@ const (
 	AddressTypeBlinded
 	AddressTypeBaker
 	AddressTypeSapling
+	AddressTypeTxRollup
+	AddressTypeSmartRollup
+	AddressTypeBls12_381
 )
 
 func ParseAddressType(s string) AddressType {
@@ -60,6 +64,12 @@ func ParseAddressType(s string) AddressType {
 		return AddressTypeBaker
 	case "sapling", SAPLING_ADDRESS_PREFIX:
 		return AddressTypeSapling
+	case "tx_rollup", TX_ROLLUP_ADDRESS_PREFIX:
+		return AddressTypeTxRollup
+	case "smart_rollup", SMART_ROLLUP_ADDRESS_PREFIX:
+		return AddressTypeSmartRollup
+	case "bls12_381", BLS12_381_PUBLIC_KEY_HASH_PREFIX:
+		return AddressTypeBls12_381
 	default:
 		return AddressTypeInvalid
 	}
@@ -85,6 +95,12 @@ func (t AddressType) String() string {
 		return "baker"
 	case AddressTypeSapling:
 		return "sapling"
+	case AddressTypeTxRollup:
+		return "tx_rollup"
+	case AddressTypeSmartRollup:
+		return "smart_rollup"
+	case AddressTypeBls12_381:
+		return "bls12_381"
 	default:
 		return "invalid"
 	}
@@ -106,6 +122,12 @@ func (t AddressType) Prefix() string {
 		return BAKER_PUBLIC_KEY_HASH_PREFIX
 	case AddressTypeSapling:
 		return SAPLING_ADDRESS_PREFIX
+	case AddressTypeTxRollup:
+		return TX_ROLLUP_ADDRESS_PREFIX
+	case AddressTypeSmartRollup:
+		return SMART_ROLLUP_ADDRESS_PREFIX
+	case AddressTypeBls12_381:
+		return BLS12_381_PUBLIC_KEY_HASH_PREFIX
 	default:
 		return ""
 	}
@@ -163,6 +185,9 @@ func HasAddressPrefix(s string) bool {
 		BLINDED_PUBLIC_KEY_HASH_PREFIX,
 		BAKER_PUBLIC_KEY_HASH_PREFIX,
 		SAPLING_ADDRESS_PREFIX,
+		TX_ROLLUP_ADDRESS_PREFIX,
+		SMART_ROLLUP_ADDRESS_PREFIX,
+		BLS12_381_PUBLIC_KEY_HASH_PREFIX,
 	} {
 		if strings.HasPrefix(s, prefix) {
 			return true
@@ -187,6 +212,12 @@ func (t AddressType) HashType() HashType {
 		return HashTypePkhBaker
 	case AddressTypeSapling:
 		return HashTypeSaplingAddress
+	case AddressTypeTxRollup:
+		return HashTypeTxRollupAddress
+	case AddressTypeSmartRollup:
+		return HashTypeSmartRollupAddress
+	case AddressTypeBls12_381:
+		return HashTypePkhBls12_381
 	default:
 		return HashTypeInvalid
 	}
@@ -200,6 +231,8 @@ func (t AddressType) KeyType() KeyType {
 		return KeyTypeSecp256k1
 	case AddressTypeP256:
 		return KeyTypeP256
+	case AddressTypeBls12_381:
+		return KeyTypeBls12_381
 	default:
 		return KeyTypeInvalid
 	}
@@ -225,7 +258,7 @@ func (a Address) IsValid() bool {
 
 func (a Address) IsEOA() bool {
 	switch a.Type {
-	case AddressTypeEd25519, AddressTypeSecp256k1, AddressTypeP256:
+	case AddressTypeEd25519, AddressTypeSecp256k1, AddressTypeP256, AddressTypeBls12_381:
 		return true
 	default:
 		return false
@@ -240,6 +273,34 @@ func (a Address) Equal(b Address) bool {
 	return a.Type == b.Type && bytes.Equal(a.Hash, b.Hash)
 }
 
+// Compare orders addresses by their 22 byte binary encoding as used by the
+// protocol (e.g. for Micheline address comparison). This sorts implicit
+// accounts (tz1 < tz2 < tz3) before originated contracts (KT1) and
+// addresses of the same type by their hash bytes. Invalid addresses sort
+// first.
+func (a Address) Compare(b Address) int {
+	x, y := a.Bytes22(), b.Bytes22()
+	if x == nil || y == nil {
+		// types without binary encoding sort by type and hash
+		switch {
+		case x != nil:
+			return 1
+		case y != nil:
+			return -1
+		case a.Type != b.Type:
+			return int(a.Type) - int(b.Type)
+		}
+		return bytes.Compare(a.Hash, b.Hash)
+	}
+	return bytes.Compare(x, y)
+}
+
+// SortAddresses sorts a slice of addresses in place in canonical
+// protocol order.
+func SortAddresses(addrs []Address) {
+	sort.Slice(addrs, func(i, j int) bool { return addrs[i].Compare(addrs[j]) < 0 })
+}
+
 func (a Address) Clone() Address {
 	x := Address{
 		Type: a.Type,
@@ -291,32 +352,24 @@ func (a Address) Bytes() []byte {
 	return append([]byte{a.Type.Tag()}, a.Hash...)
 }
 
-// Bytes22 returns the 22 byte tagged and padded binary encoding for contracts
-// and EOAs (tz1/2/3). In contrast to Bytes which outputs the 21 byte address for EOAs
-// here we add a leading 0-byte.
+// Bytes22 returns the 22 byte tagged and padded binary encoding as produced by
+// EncodeBinary. In contrast to Bytes which outputs the 21 byte address for
+// implicit accounts here we add a leading 0-byte. Returns nil for address
+// types without a binary encoding.
 func (a Address) Bytes22() []byte {
-	if !a.Type.IsValid() {
+	buf, err := a.EncodeBinary()
+	if err != nil {
 		return nil
 	}
-	if a.Type == AddressTypeContract {
-		buf := append([]byte{01}, a.Hash...)
-		buf = append(buf, byte(0)) // padding
-		return buf
-	}
-	return append([]byte{00, a.Type.Tag()}, a.Hash...)
+	return buf
 }
 
-// MarshalBinary always output the 22 byte version for contracts and EOAs.
+// MarshalBinary always outputs the 22 byte version, see EncodeBinary.
 func (a Address) MarshalBinary() ([]byte, error) {
 	if !a.Type.IsValid() {
 		return nil, ErrUnknownAddressType
 	}
-	if a.Type == AddressTypeContract {
-		buf := append([]byte{01}, a.Hash...)
-		buf = append(buf, byte(0)) // padding
-		return buf, nil
-	}
-	return append([]byte{00, a.Type.Tag()}, a.Hash...), nil
+	return a.EncodeBinary()
 }
 
 // UnmarshalBinary reads a 21 byte or 22 byte address versions and is
@@ -324,16 +377,13 @@ func (a Address) MarshalBinary() ([]byte, error) {
 // (e.g. an entrypoint suffix as found in smart contract data).
 func (a *Address) UnmarshalBinary(b []byte) error {
 	switch true {
-	case len(b) >= 22 && (b[0] == 0 || b[0] == 1):
-		if b[0] == 0 {
-			a.Type = ParseAddressTag(b[1])
-			b = b[2:22]
-		} else if b[0] == 1 {
-			a.Type = AddressTypeContract
-			b = b[1:21]
-		} else {
-			return fmt.Errorf("tezos: invalid binary address prefix %x", b[0])
+	case isAddressBytes22(b):
+		addr, _, err := DecodeAddress(b)
+		if err != nil {
+			return err
 		}
+		a.Type = addr.Type
+		b = addr.Hash
 	case len(b) >= 21:
 		a.Type = ParseAddressTag(b[0])
 		b = b[1:21]
@@ -352,15 +402,97 @@ func (a *Address) UnmarshalBinary(b []byte) error {
 	return nil
 }
 
-// IsAddressBytes checks whether a buffer likely contains a binary encoded address.
-func IsAddressBytes(b []byte) bool {
-	if len(b) < 21 {
+// EncodeBinary returns the 22 byte forged contract id of the address as used
+// for transaction destinations and Micheline address values. Implicit
+// accounts are encoded as 0x00 followed by the 21 byte public key hash,
+// originated contracts (0x01), tx rollups (0x02) and smart rollups (0x03) as
+// tag followed by the 20 byte hash and a 0x00 padding byte. Use Bytes for the
+// 21 byte public key hash form of implicit accounts.
+func (a Address) EncodeBinary() ([]byte, error) {
+	if len(a.Hash) != 20 {
+		return nil, fmt.Errorf("tezos: invalid address hash length %d", len(a.Hash))
+	}
+	buf := make([]byte, 22)
+	switch a.Type {
+	case AddressTypeEd25519, AddressTypeSecp256k1, AddressTypeP256:
+		buf[1] = a.Type.Tag()
+		copy(buf[2:], a.Hash)
+		return buf, nil
+	case AddressTypeBls12_381:
+		buf[1] = 3
+		copy(buf[2:], a.Hash)
+		return buf, nil
+	case AddressTypeContract:
+		buf[0] = 1
+	case AddressTypeTxRollup:
+		buf[0] = 2
+	case AddressTypeSmartRollup:
+		buf[0] = 3
+	default:
+		return nil, fmt.Errorf("tezos: cannot forge %s address", a.Type)
+	}
+	copy(buf[1:], a.Hash)
+	return buf, nil
+}
+
+// DecodeAddress reads a 22 byte forged contract id from the start of buf and
+// returns the address and the number of bytes read. See EncodeBinary.
+func DecodeAddress(buf []byte) (Address, int, error) {
+	if len(buf) < 22 {
+		return InvalidAddress, 0, fmt.Errorf("tezos: short binary address length %d", len(buf))
+	}
+	var typ AddressType
+	switch buf[0] {
+	case 0:
+		switch buf[1] {
+		case 0, 1, 2:
+			typ = ParseAddressTag(buf[1])
+		case 3:
+			typ = AddressTypeBls12_381
+		default:
+			return InvalidAddress, 0, fmt.Errorf("tezos: invalid implicit address tag %x", buf[1])
+		}
+		return NewAddress(typ, buf[2:22]), 22, nil
+	case 1:
+		typ = AddressTypeContract
+	case 2:
+		typ = AddressTypeTxRollup
+	case 3:
+		typ = AddressTypeSmartRollup
+	default:
+		return InvalidAddress, 0, fmt.Errorf("tezos: invalid binary address tag %x", buf[0])
+	}
+	if buf[21] != 0 {
+		return InvalidAddress, 0, fmt.Errorf("tezos: invalid binary address padding %x", buf[21])
+	}
+	return NewAddress(typ, buf[1:21]), 22, nil
+}
+
+// isAddressBytes22 checks whether b starts with a 22 byte forged contract id.
+// Rollup tags collide with 21 byte implicit tags, so they are only accepted
+// with a padding byte.
+func isAddressBytes22(b []byte) bool {
+	if len(b) < 22 {
 		return false
 	}
-	switch true {
-	case len(b) == 22 && (b[0] == 0 || b[0] == 1):
+	switch b[0] {
+	case 0:
+		return b[1] <= 3
+	case 1:
 		return true
-	case len(b) == 21:
+	case 2, 3:
+		return b[21] == 0
+	default:
+		return false
+	}
+}
+
+// IsAddressBytes checks whether a buffer likely contains a binary encoded address.
+func IsAddressBytes(b []byte) bool {
+	switch len(b) {
+	case 22:
+		return isAddressBytes22(b)
+	case 21:
 		return ParseAddressTag(b[0]) != AddressTypeInvalid
 	default:
 		return false
@@ -398,6 +530,14 @@ func ParseAddress(addr string) (Address, error) {
 	if strings.HasPrefix(addr, BLINDED_PUBLIC_KEY_HASH_PREFIX) {
 		return DecodeBlindedAddress(addr)
 	}
+	// tx rollup addresses use a 4 byte prefix
+	if strings.HasPrefix(addr, TX_ROLLUP_ADDRESS_PREFIX) {
+		h, err := decodeHash(addr)
+		if err != nil {
+			return a, err
+		}
+		return Address{Type: AddressTypeTxRollup, Hash: h.Hash}, nil
+	}
 	decoded, version, err := base58.CheckDecode(addr, 3, nil)
 	if err != nil {
 		if err == base58.ErrChecksum {
@@ -421,6 +561,10 @@ func ParseAddress(addr string) (Address, error) {
 		return Address{Type: AddressTypeContract, Hash: decoded}, nil
 	case bytes.Equal(version, SAPLING_ADDRESS_ID):
 		return Address{Type: AddressTypeSapling, Hash: decoded}, nil
+	case bytes.Equal(version, SMART_ROLLUP_ADDRESS_ID):
+		return Address{Type: AddressTypeSmartRollup, Hash: decoded}, nil
+	case bytes.Equal(version, BLS12_381_PUBLIC_KEY_HASH_ID):
+		return Address{Type: AddressTypeBls12_381, Hash: decoded}, nil
 	default:
 		return a, fmt.Errorf("tezos: decoded address %s is of unknown type %x", addr, version)
 	}
@@ -445,6 +589,12 @@ func EncodeAddress(typ AddressType, addrhash []byte) (string, error) {
 		return base58.CheckEncode(addrhash, BLINDED_PUBLIC_KEY_HASH_ID), nil
 	case AddressTypeSapling:
 		return base58.CheckEncode(addrhash, SAPLING_ADDRESS_ID), nil
+	case AddressTypeTxRollup:
+		return base58.CheckEncode(addrhash, TX_ROLLUP_ADDRESS_ID), nil
+	case AddressTypeSmartRollup:
+		return base58.CheckEncode(addrhash, SMART_ROLLUP_ADDRESS_ID), nil
+	case AddressTypeBls12_381:
+		return base58.CheckEncode(addrhash, BLS12_381_PUBLIC_KEY_HASH_ID), nil
 	default:
 		return "", fmt.Errorf("tezos: unknown address type %s for hash=%x", typ, addrhash)
 	}
@@ -0,0 +1,88 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package tezos
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+func TestAddressBinary(t *testing.T) {
+	hash := bytes.Repeat([]byte{0xa5}, 20)
+	for _, c := range []struct {
+		typ AddressType
+		hex string
+	}{
+		{AddressTypeEd25519, "0000" + hex.EncodeToString(hash)},
+		{AddressTypeSecp256k1, "0001" + hex.EncodeToString(hash)},
+		{AddressTypeP256, "0002" + hex.EncodeToString(hash)},
+		{AddressTypeBls12_381, "0003" + hex.EncodeToString(hash)},
+		{AddressTypeContract, "01" + hex.EncodeToString(hash) + "00"},
+		{AddressTypeTxRollup, "02" + hex.EncodeToString(hash) + "00"},
+		{AddressTypeSmartRollup, "03" + hex.EncodeToString(hash) + "00"},
+	} {
+		a := NewAddress(c.typ, hash)
+		buf, err := a.EncodeBinary()
+		if err != nil {
+			t.Errorf("%s: encode error: %v", c.typ, err)
+			continue
+		}
+		if have := hex.EncodeToString(buf); have != c.hex {
+			t.Errorf("%s: encoded %s want %s", c.typ, have, c.hex)
+		}
+		// trailing data must not be consumed
+		b, n, err := DecodeAddress(append(buf, 0xff))
+		if err != nil {
+			t.Errorf("%s: decode error: %v", c.typ, err)
+			continue
+		}
+		if n != 22 || !b.Equal(a) {
+			t.Errorf("%s: decoded %s (%d bytes)", c.typ, b, n)
+		}
+		// legacy encoders must agree with the forged form
+		if have := hex.EncodeToString(a.Bytes22()); have != c.hex {
+			t.Errorf("%s: Bytes22 %s want %s", c.typ, have, c.hex)
+		}
+		if m, err := a.MarshalBinary(); err != nil || !bytes.Equal(m, buf) {
+			t.Errorf("%s: MarshalBinary %x err=%v", c.typ, m, err)
+		}
+		if !IsAddressBytes(buf) {
+			t.Errorf("%s: IsAddressBytes false for %x", c.typ, buf)
+		}
+		var u Address
+		if err := u.UnmarshalBinary(buf); err != nil || !u.Equal(a) {
+			t.Errorf("%s: UnmarshalBinary %s err=%v", c.typ, u, err)
+		}
+		// string roundtrip
+		p, err := ParseAddress(a.String())
+		if err != nil || !p.Equal(a) {
+			t.Errorf("%s: parsed %s from %s err=%v", c.typ, p, a, err)
+		}
+	}
+
+	for _, v := range []string{
+		"",
+		"01" + hex.EncodeToString(hash),        // short
+		"01" + hex.EncodeToString(hash) + "01", // bad padding
+		"0004" + hex.EncodeToString(hash),      // unknown implicit tag
+		"04" + hex.EncodeToString(hash) + "00", // unknown tag
+	} {
+		buf, _ := hex.DecodeString(v)
+		if _, _, err := DecodeAddress(buf); err == nil {
+			t.Errorf("expected error for %s", v)
+		}
+	}
+
+	blinded := NewAddress(AddressTypeBlinded, hash)
+	if _, err := blinded.EncodeBinary(); err == nil {
+		t.Errorf("expected error for blinded address")
+	}
+	if _, err := blinded.MarshalBinary(); err == nil {
+		t.Errorf("expected marshal error for blinded address")
+	}
+	if b := blinded.Bytes22(); b != nil {
+		t.Errorf("unexpected Bytes22 %x for blinded address", b)
+	}
+}
@@ -0,0 +1,127 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package tezos
+
+import (
+	"bytes"
+	"fmt"
+
+	"blockwatch.cc/tzgo/base58"
+)
+
+// Base58CheckEncode encodes payload with a binary prefix (e.g. ED25519_PUBLIC_KEY_HASH_ID)
+// into a base58check string.
+func Base58CheckEncode(prefix []byte, payload []byte) string {
+	return base58.CheckEncode(payload, prefix)
+}
+
+// Base58CheckDecode decodes a base58check string with one of the known Tezos
+// prefixes (see HashType) and returns the binary prefix and payload.
+// Unknown prefixes, checksum errors and unexpected payload lengths are
+// reported as error.
+func Base58CheckDecode(s string) (prefix, payload []byte, err error) {
+	typ := ParseHashType(s)
+	if typ == HashTypeInvalid {
+		return nil, nil, fmt.Errorf("%w: unknown base58 prefix or length in %q", ErrUnknownHashType, s)
+	}
+	return decodeBase58Check(typ, s)
+}
+
+// DetectHashType identifies the type of a base58 encoded hash, address, key or
+// signature by its prefix and length and validates the checksum.
+func DetectHashType(s string) (HashType, error) {
+	typ := ParseHashType(s)
+	if typ == HashTypeInvalid {
+		return typ, fmt.Errorf("%w: unknown base58 prefix or length in %q", ErrUnknownHashType, s)
+	}
+	if _, _, err := decodeBase58Check(typ, s); err != nil {
+		return HashTypeInvalid, err
+	}
+	return typ, nil
+}
+
+func decodeBase58Check(typ HashType, s string) (prefix, payload []byte, err error) {
+	payload, prefix, err = base58.CheckDecode(s, len(typ.PrefixBytes()), nil)
+	if err != nil {
+		if err == base58.ErrChecksum {
+			return nil, nil, ErrChecksumMismatch
+		}
+		return nil, nil, fmt.Errorf("tezos: invalid base58 %s string: %w", typ, err)
+	}
+	if !bytes.Equal(prefix, typ.PrefixBytes()) {
+		return nil, nil, fmt.Errorf("tezos: invalid prefix '%x' for %s", prefix, typ)
+	}
+	if have, want := len(payload), typ.Len(); have != want {
+		return nil, nil, fmt.Errorf("tezos: invalid payload length for %s have=%d want=%d", typ, have, want)
+	}
+	return prefix, payload, nil
+}
+
+// ParseAny detects the type of a base58 encoded string by its prefix and
+// length, validates the checksum and returns a typed value. Depending on the
+// prefix the result is an Address (implicit accounts including tz4, contracts
+// and rollups), Key, PrivateKey, Signature, OpHash, BlockHash, ProtocolHash,
+// ChainIdHash, ExprHash, ContextHash, NonceHash, OpListListHash or
+// PayloadHash. Other known types without a dedicated Go type (e.g. encrypted
+// keys) are returned as Hash. Unknown prefixes return an error wrapping
+// ErrUnknownHashType.
+//
+//	switch v := x.(type) {
+//	case tezos.Address:
+//	case tezos.OpHash:
+//	}
+func ParseAny(s string) (interface{}, error) {
+	typ, err := DetectHashType(s)
+	if err != nil {
+		return nil, err
+	}
+	switch typ {
+	case HashTypePkhEd25519,
+		HashTypePkhSecp256k1,
+		HashTypePkhP256,
+		HashTypePkhBls12_381,
+		HashTypePkhNocurve,
+		HashTypePkhBlinded,
+		HashTypePkhBaker,
+		HashTypeTxRollupAddress,
+		HashTypeSmartRollupAddress:
+		return ParseAddress(s)
+	case HashTypePkEd25519,
+		HashTypePkSecp256k1,
+		HashTypePkP256,
+		HashTypePkBls12_381:
+		return ParseKey(s)
+	case HashTypeSeedEd25519,
+		HashTypeSkEd25519,
+		HashTypeSkSecp256k1,
+		HashTypeSkP256:
+		return ParsePrivateKey(s)
+	case HashTypeSigEd25519,
+		HashTypeSigSecp256k1,
+		HashTypeSigP256,
+		HashTypeSigGeneric,
+		HashTypeSigBls12_381:
+		return ParseSignature(s)
+	case HashTypeOperation:
+		return ParseOpHash(s)
+	case HashTypeBlock:
+		return ParseBlockHash(s)
+	case HashTypeProtocol:
+		return ParseProtocolHash(s)
+	case HashTypeChainId:
+		return ParseChainIdHash(s)
+	case HashTypeScriptExpr:
+		return ParseExprHash(s)
+	case HashTypeContext:
+		return ParseContextHash(s)
+	case HashTypeNonce:
+		return ParseNonceHash(s)
+	case HashTypeOperationListList:
+		return ParseOpListListHash(s)
+	case HashTypeBlockPayload:
+		return ParsePayloadHash(s)
+	default:
+		return ParseHash(s)
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package tezos
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestBase58Check(t *testing.T) {
+	for _, typ := range []HashType{
+		HashTypePkhEd25519,
+		HashTypePkhBls12_381,
+		HashTypePkhNocurve,
+		HashTypePkhBlinded,
+		HashTypeScriptExpr,
+		HashTypeOperation,
+		HashTypeBlock,
+		HashTypePkEd25519,
+		HashTypePkBls12_381,
+		HashTypeSkBls12_381,
+		HashTypeSigBls12_381,
+		HashTypeTxRollupAddress,
+		HashTypeSmartRollupAddress,
+	} {
+		payload := bytes.Repeat([]byte{0xa5}, typ.Len())
+		s := Base58CheckEncode(typ.PrefixBytes(), payload)
+		if len(s) != typ.Base58Len() || !typ.MatchPrefix(s) {
+			t.Errorf("%s: unexpected encoding %q", typ, s)
+			continue
+		}
+		prefix, p, err := Base58CheckDecode(s)
+		if err != nil {
+			t.Errorf("%s: decode error: %v", typ, err)
+			continue
+		}
+		if !bytes.Equal(prefix, typ.PrefixBytes()) || !bytes.Equal(p, payload) {
+			t.Errorf("%s: roundtrip mismatch prefix=%x payload=%x", typ, prefix, p)
+		}
+		if have, err := DetectHashType(s); err != nil || have != typ {
+			t.Errorf("%s: detected type %s err=%v", typ, have, err)
+		}
+		// flip last char to break the checksum
+		bad := []byte(s)
+		if bad[len(bad)-1] == '1' {
+			bad[len(bad)-1] = '2'
+		} else {
+			bad[len(bad)-1] = '1'
+		}
+		if _, err := DetectHashType(string(bad)); err == nil {
+			t.Errorf("%s: expected checksum error for %q", typ, string(bad))
+		}
+	}
+	if _, _, err := Base58CheckDecode("xyz1234"); !errors.Is(err, ErrUnknownHashType) {
+		t.Errorf("expected unknown hash type error, got %v", err)
+	}
+}
+
+func TestParseAny(t *testing.T) {
+	addr := NewAddress(AddressTypeContract, bytes.Repeat([]byte{1}, 20))
+	tz4 := NewAddress(AddressTypeBls12_381, bytes.Repeat([]byte{7}, 20))
+	op := NewOpHash(bytes.Repeat([]byte{2}, 32))
+	block := NewBlockHash(bytes.Repeat([]byte{3}, 32))
+	expr := NewExprHash(bytes.Repeat([]byte{4}, 32))
+	for _, v := range []interface{}{addr, tz4, op, block, expr} {
+		s := v.(interface{ String() string }).String()
+		x, err := ParseAny(s)
+		if err != nil {
+			t.Errorf("%s: %v", s, err)
+			continue
+		}
+		if have, want := fmt.Sprintf("%T %s", x, x), fmt.Sprintf("%T %s", v, s); have != want {
+			t.Errorf("parsed %s want %s", have, want)
+		}
+	}
+	key := Base58CheckEncode(HashTypePkEd25519.PrefixBytes(), bytes.Repeat([]byte{5}, 32))
+	if x, err := ParseAny(key); err != nil {
+		t.Error(err)
+	} else if _, ok := x.(Key); !ok {
+		t.Errorf("expected key, got %T", x)
+	}
+	ctx := Base58CheckEncode(HashTypeContext.PrefixBytes(), bytes.Repeat([]byte{6}, 32))
+	if x, err := ParseAny(ctx); err != nil {
+		t.Error(err)
+	} else if _, ok := x.(ContextHash); !ok {
+		t.Errorf("expected context hash, got %T", x)
+	}
+	if _, err := ParseAny("abc123"); !errors.Is(err, ErrUnknownHashType) {
+		t.Errorf("expected unknown hash type error, got %v", err)
+	}
+	bad := []byte(op.String())
+	bad[len(bad)-1] ^= 1
+	if _, err := ParseAny(string(bad)); err == nil {
+		t.Errorf("expected checksum error")
+	}
+}
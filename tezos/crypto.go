@@ -4,6 +4,8 @@
 package tezos
 
 import (
+    "encoding/asn1"
+    "errors"
     "fmt"
     "math/big"
 
@@ -58,16 +60,66 @@ func ecSign(sk *ecdsa.PrivateKey, hash []byte) ([]byte, error) {
     return buf, nil
 }
 
+// ecVerifySignature checks a raw 64 byte signature. Like the node (which uses
+// libsecp256k1) it rejects secp256k1 signatures that are not in low-S form.
 func ecVerifySignature(pk *ecdsa.PublicKey, hash []byte, sig Signature) bool {
+    if len(sig.Data) != 64 {
+        return false
+    }
     r := new(big.Int).SetBytes(sig.Data[:32])
     s := new(big.Int).SetBytes(sig.Data[32:])
+    if pk.Curve == secp256k1.S256() && !ecIsLowS(s, pk.Curve) {
+        return false
+    }
     return ecdsa.Verify(pk, hash, r, s)
 }
 
+func ecIsLowS(s *big.Int, c elliptic.Curve) bool {
+    quo := new(big.Int).Rsh(c.Params().N, 1)
+    return s.Cmp(quo) <= 0
+}
+
+// ecdsaDER is the ASN.1 structure of a DER encoded ECDSA signature.
+type ecdsaDER struct {
+    R, S *big.Int
+}
+
+// ecDecodeDER converts a DER encoded signature into normalized raw form.
+func ecDecodeDER(der []byte, c elliptic.Curve) ([]byte, error) {
+    var sig ecdsaDER
+    rest, err := asn1.Unmarshal(der, &sig)
+    if err != nil {
+        return nil, fmt.Errorf("tezos: invalid DER signature: %w", err)
+    }
+    if len(rest) > 0 {
+        return nil, errors.New("tezos: trailing data after DER signature")
+    }
+    n := c.Params().N
+    if sig.R.Sign() <= 0 || sig.S.Sign() <= 0 || sig.R.Cmp(n) >= 0 || sig.S.Cmp(n) >= 0 {
+        return nil, errors.New("tezos: DER signature out of range")
+    }
+    r, s := ecNormalizeSignature(sig.R, sig.S, c)
+    buf := make([]byte, 64)
+    r.FillBytes(buf[:32])
+    s.FillBytes(buf[32:])
+    return buf, nil
+}
+
+// ecEncodeDER converts a raw 64 byte signature into DER encoding.
+func ecEncodeDER(raw []byte) ([]byte, error) {
+    if len(raw) != 64 {
+        return nil, fmt.Errorf("tezos: invalid signature length %d", len(raw))
+    }
+    return asn1.Marshal(ecdsaDER{
+        R: new(big.Int).SetBytes(raw[:32]),
+        S: new(big.Int).SetBytes(raw[32:]),
+    })
+}
+
 func ecPrivateKeyFromBytes(b []byte, curve elliptic.Curve) (key *ecdsa.PrivateKey, err error) {
     k := new(big.Int).SetBytes(b)
     curveOrder := curve.Params().N
-    if k.Cmp(curveOrder) >= 0 {
+    if k.Sign() == 0 || k.Cmp(curveOrder) >= 0 {
         return nil, fmt.Errorf("tezos: invalid private key for curve %s", curve.Params().Name)
     }
 
@@ -109,9 +161,7 @@ func ecUnmarshalCompressed(curve elliptic.Curve, data []byte) (pk *ecdsa.PublicK
     }
     y.Add(y, curve.Params().B)
     y.Mod(y, curve.Params().P)
-    y.ModSqrt(y, p)
-
-    if y == nil {
+    if y.ModSqrt(y, p) == nil {
         return nil, fmt.Errorf("tezos: (%s) invalid public key", curve.Params().Name)
     }
     if byte(y.Bit(0)) != data[0]&1 {
@@ -146,6 +196,9 @@ func decryptPrivateKey(enc []byte, fn PassphraseFunc) ([]byte, error) {
         return nil, ErrPassphrase
     }
 
+    if len(enc) < 8+secretbox.Overhead {
+        return nil, fmt.Errorf("tezos: invalid encrypted private key length %d", len(enc))
+    }
     salt, box := enc[:8], enc[8:]
     secretboxKey := pbkdf2.Key(passphrase, salt, encIterations, encKeyLen, sha512.New)
 
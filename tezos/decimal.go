@@ -0,0 +1,72 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package tezos
+
+import (
+	"strings"
+)
+
+// scaleDecimal parses a signed decimal string such as "-1.5" and returns the
+// digits of its absolute value scaled by 10^decimals, e.g. "1500000" for 6
+// decimals. Fractional digits beyond decimals are returned in excess and left
+// for the caller to reject or drop. ok is false for malformed input.
+func scaleDecimal(s string, decimals int) (neg bool, digits, excess string, ok bool) {
+	v := s
+	neg = strings.HasPrefix(v, "-")
+	if neg || strings.HasPrefix(v, "+") {
+		v = v[1:]
+	}
+	whole, frac := v, ""
+	if i := strings.IndexByte(v, '.'); i >= 0 {
+		whole, frac = v[:i], v[i+1:]
+	}
+	if whole == "" && frac == "" || !isDigits(whole) || !isDigits(frac) {
+		return false, "", "", false
+	}
+	if len(frac) > decimals {
+		frac, excess = frac[:decimals], frac[decimals:]
+	}
+	digits = whole + frac + strings.Repeat("0", decimals-len(frac))
+	if digits == "" {
+		digits = "0"
+	}
+	return neg, digits, excess, true
+}
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// formatDecimal formats the digits of an absolute amount already scaled and
+// rounded to prec decimals. Integer digits are grouped in thousands separated
+// by sep when sep is not empty. Negative amounts that round to zero are
+// formatted without sign.
+func formatDecimal(neg bool, digits string, prec int, sep string) string {
+	if len(digits) <= prec {
+		digits = strings.Repeat("0", prec-len(digits)+1) + digits
+	}
+	whole, frac := digits[:len(digits)-prec], digits[len(digits)-prec:]
+	if sep != "" && len(whole) > 3 {
+		var b strings.Builder
+		for i, c := range whole {
+			if i > 0 && (len(whole)-i)%3 == 0 {
+				b.WriteString(sep)
+			}
+			b.WriteRune(c)
+		}
+		whole = b.String()
+	}
+	if neg && strings.Trim(digits, "0") != "" {
+		whole = "-" + whole
+	}
+	if prec == 0 {
+		return whole
+	}
+	return whole + "." + frac
+}
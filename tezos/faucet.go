@@ -0,0 +1,76 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package tezos
+
+import (
+	"crypto/ed25519"
+	"crypto/sha512"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// FaucetAccount is a testnet faucet account as downloaded from the Tezos faucet.
+// The private key is derived from the BIP39 mnemonic with email and password
+// as passphrase. Secret is the activation code required to claim Amount.
+type FaucetAccount struct {
+	Mnemonic []string   `json:"mnemonic"`
+	Secret   HexBytes   `json:"secret"`
+	Amount   Z          `json:"amount"`
+	Pkh      Address    `json:"pkh"`
+	Password string     `json:"password"`
+	Email    string     `json:"email"`
+	Key      PrivateKey `json:"-"`
+}
+
+// LoadFaucetAccount reads a faucet account file and derives its private key.
+// Fails when the derived key does not match the account's public key hash.
+func LoadFaucetAccount(filename string) (*FaucetAccount, error) {
+	buf, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	acc := &FaucetAccount{}
+	if err := json.Unmarshal(buf, acc); err != nil {
+		return nil, fmt.Errorf("tezos: reading faucet account: %w", err)
+	}
+	if len(acc.Mnemonic) == 0 {
+		return nil, fmt.Errorf("tezos: faucet account without mnemonic")
+	}
+	acc.Key = NewFundraiserKey(strings.Join(acc.Mnemonic, " "), acc.Email, acc.Password)
+	if acc.Pkh.IsValid() && !acc.Key.Address().Equal(acc.Pkh) {
+		return nil, fmt.Errorf("tezos: faucet key address %s does not match %s", acc.Key.Address(), acc.Pkh)
+	}
+	return acc, nil
+}
+
+// BlindedAddress returns the blinded public key hash (btz1) the account's
+// activation commitment was registered under.
+func (a FaucetAccount) BlindedAddress() (Address, error) {
+	addr := a.Pkh
+	if !addr.IsValid() {
+		addr = a.Key.Address()
+	}
+	return BlindAddress(addr, a.Secret)
+}
+
+// NewFundraiserKey derives the ed25519 key of a fundraiser or faucet account
+// from its space separated BIP39 mnemonic, email and password. The mnemonic
+// is not checked against the BIP39 word list.
+func NewFundraiserKey(mnemonic, email, password string) PrivateKey {
+	seed := pbkdf2.Key(
+		[]byte(strings.Join(strings.Fields(mnemonic), " ")),
+		[]byte("mnemonic"+email+password),
+		2048,
+		64,
+		sha512.New,
+	)
+	return PrivateKey{
+		Type: KeyTypeEd25519,
+		Data: []byte(ed25519.NewKeyFromSeed(seed[:ed25519.SeedSize])),
+	}
+}
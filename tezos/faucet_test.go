@@ -0,0 +1,85 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package tezos
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+func TestFundraiserKey(t *testing.T) {
+	key := NewFundraiserKey("word1 word2  word3", "user@example.com", "secret")
+	if key.Type != KeyTypeEd25519 || !key.IsValid() {
+		t.Fatalf("invalid key %s", key.Type)
+	}
+	if other := NewFundraiserKey(" word1 word2 word3 ", "user@example.com", "secret"); !other.Address().Equal(key.Address()) {
+		t.Errorf("whitespace changed derived address %s != %s", other.Address(), key.Address())
+	}
+	if other := NewFundraiserKey("word1 word2 word3", "user@example.com", "other"); other.Address().Equal(key.Address()) {
+		t.Errorf("password did not change derived address")
+	}
+
+	secret := bytes.Repeat([]byte{0x42}, 20)
+	acc := FaucetAccount{Key: key, Secret: secret}
+	blinded, err := acc.BlindedAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if blinded.Type != AddressTypeBlinded || !MatchBlindedAddress(key.Address(), blinded, secret) {
+		t.Errorf("blinded address %s does not match %s", blinded, key.Address())
+	}
+	parsed, err := ParseAddress(blinded.String())
+	if err != nil || !parsed.Equal(blinded) {
+		t.Errorf("parse %s: %v", blinded, err)
+	}
+}
+
+// Known answers for fundraiser key derivation. The seeds are the BIP39
+// reference vectors with passphrase "TREZOR", split into email and password
+// which the fundraiser scheme concatenates. Keys and addresses were computed
+// with an independent implementation (ed25519 checked against RFC 8032).
+func TestFundraiserKeyKnownAnswer(t *testing.T) {
+	secret := make([]byte, 20)
+	for i := range secret {
+		secret[i] = byte(i + 1)
+	}
+	for _, test := range []struct {
+		mnemonic, email, password string
+		seed, pk, pkh, blinded    string
+	}{
+		{
+			"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about",
+			"", "TREZOR",
+			"c55257c360c07c72029aebc1b53c05ed0362ada38ead3e3e9efa3708e5349553",
+			"edpkuG1bNHxCXwimdU3ybvn5wV4CuQn4qWmbYrVd9fASXYU7L59Nmt",
+			"tz1W1VHYWCTYuzsFMD56XJ7hmXt6TkymNmDo",
+			"btz1e7ikGATyK75q6w81xv3CoPwjdaDos9FBK",
+		},
+		{
+			"legal winner thank year wave sausage worth useful legal winner thank yellow",
+			"TREZOR", "",
+			"2e8905819b8723fe2c1d161860e5ee1830318dbf49a83bd451cfb8440c28bd6f",
+			"edpkuAfEJCEatRgFpRGg3gn3FdWniLXBoubARreRwuVZPWufkgDBvR",
+			"tz1dtmCcU7Ng29oWrEc5xJcrQfMnKgoqT7mn",
+			"btz1gqLEjL9NGmvCacTgR5vLvcisaRurUXU3M",
+		},
+	} {
+		key := NewFundraiserKey(test.mnemonic, test.email, test.password)
+		if seed := hex.EncodeToString(key.Data[:32]); seed != test.seed {
+			t.Errorf("%s: seed %s want %s", test.pkh, seed, test.seed)
+		}
+		if pk := key.Public().String(); pk != test.pk {
+			t.Errorf("%s: public key %s want %s", test.pkh, pk, test.pk)
+		}
+		if addr := key.Address().String(); addr != test.pkh {
+			t.Errorf("address %s want %s", addr, test.pkh)
+		}
+		acc := FaucetAccount{Key: key, Secret: secret}
+		blinded, err := acc.BlindedAddress()
+		if err != nil || blinded.String() != test.blinded {
+			t.Errorf("%s: blinded %s want %s (%v)", test.pkh, blinded, test.blinded, err)
+		}
+	}
+}
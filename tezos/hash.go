@@ -11,6 +11,7 @@ import (
 	"strings"
 
 	"blockwatch.cc/tzgo/base58"
+	"golang.org/x/crypto/blake2b"
 )
 
 var (
@@ -72,6 +73,14 @@ const (
 	HashTypeEncryptedSecp256k1Scalar
 	HashTypeSaplingSpendingKey
 	HashTypeSaplingAddress
+
+	HashTypePkhBls12_381
+	HashTypePkBls12_381
+	HashTypeSkBls12_381
+	HashTypeSigBls12_381
+
+	HashTypeTxRollupAddress
+	HashTypeSmartRollupAddress
 )
 
 func ParseHashType(s string) HashType {
@@ -94,10 +103,19 @@ func ParseHashType(s string) HashType {
 			return HashTypePkhP256
 		case strings.HasPrefix(s, NOCURVE_PUBLIC_KEY_HASH_PREFIX):
 			return HashTypePkhNocurve
-		case strings.HasPrefix(s, BLINDED_PUBLIC_KEY_HASH_PREFIX):
-			return HashTypePkhBlinded
 		case strings.HasPrefix(s, BAKER_PUBLIC_KEY_HASH_PREFIX):
 			return HashTypePkhBaker
+		case strings.HasPrefix(s, BLS12_381_PUBLIC_KEY_HASH_PREFIX):
+			return HashTypePkhBls12_381
+		case strings.HasPrefix(s, SMART_ROLLUP_ADDRESS_PREFIX):
+			return HashTypeSmartRollupAddress
+		}
+	case 37:
+		switch true {
+		case strings.HasPrefix(s, BLINDED_PUBLIC_KEY_HASH_PREFIX):
+			return HashTypePkhBlinded
+		case strings.HasPrefix(s, TX_ROLLUP_ADDRESS_PREFIX):
+			return HashTypeTxRollupAddress
 		}
 	case 43:
 		switch true {
@@ -153,6 +171,8 @@ func ParseHashType(s string) HashType {
 			return HashTypeElementSecp256k1
 		case strings.HasPrefix(s, SCRIPT_EXPR_HASH_PREFIX):
 			return HashTypeScriptExpr
+		case strings.HasPrefix(s, BLS12_381_SECRET_KEY_PREFIX):
+			return HashTypeSkBls12_381
 		}
 	case 55:
 		switch true {
@@ -161,6 +181,10 @@ func ParseHashType(s string) HashType {
 		case strings.HasPrefix(s, P256_PUBLIC_KEY_PREFIX):
 			return HashTypePkP256
 		}
+	case 76:
+		if strings.HasPrefix(s, BLS12_381_PUBLIC_KEY_PREFIX) {
+			return HashTypePkBls12_381
+		}
 	case 88:
 		switch true {
 		case strings.HasPrefix(s, ED25519_ENCRYPTED_SEED_PREFIX):
@@ -193,6 +217,10 @@ func ParseHashType(s string) HashType {
 		case strings.HasPrefix(s, SECP256K1_SIGNATURE_PREFIX):
 			return HashTypeSigSecp256k1
 		}
+	case 142:
+		if strings.HasPrefix(s, BLS12_381_SIGNATURE_PREFIX) {
+			return HashTypeSigBls12_381
+		}
 	case 169:
 		switch true {
 		case strings.HasPrefix(s, SAPLING_SPENDING_KEY_PREFIX):
@@ -232,6 +260,10 @@ func (t HashType) Prefix() string {
 		return BLINDED_PUBLIC_KEY_HASH_PREFIX
 	case HashTypePkhBaker:
 		return BAKER_PUBLIC_KEY_HASH_PREFIX
+	case HashTypeTxRollupAddress:
+		return TX_ROLLUP_ADDRESS_PREFIX
+	case HashTypeSmartRollupAddress:
+		return SMART_ROLLUP_ADDRESS_PREFIX
 	case HashTypeBlock:
 		return BLOCK_HASH_PREFIX
 	case HashTypeOperation:
@@ -296,6 +328,14 @@ func (t HashType) Prefix() string {
 		return SAPLING_SPENDING_KEY_PREFIX
 	case HashTypeSaplingAddress:
 		return SAPLING_ADDRESS_PREFIX
+	case HashTypePkhBls12_381:
+		return BLS12_381_PUBLIC_KEY_HASH_PREFIX
+	case HashTypePkBls12_381:
+		return BLS12_381_PUBLIC_KEY_PREFIX
+	case HashTypeSkBls12_381:
+		return BLS12_381_SECRET_KEY_PREFIX
+	case HashTypeSigBls12_381:
+		return BLS12_381_SIGNATURE_PREFIX
 	default:
 		return ""
 	}
@@ -319,6 +359,10 @@ func (t HashType) PrefixBytes() []byte {
 		return BLINDED_PUBLIC_KEY_HASH_ID
 	case HashTypePkhBaker:
 		return BAKER_PUBLIC_KEY_HASH_ID
+	case HashTypeTxRollupAddress:
+		return TX_ROLLUP_ADDRESS_ID
+	case HashTypeSmartRollupAddress:
+		return SMART_ROLLUP_ADDRESS_ID
 	case HashTypeBlock:
 		return BLOCK_HASH_ID
 	case HashTypeOperation:
@@ -383,6 +427,14 @@ func (t HashType) PrefixBytes() []byte {
 		return SAPLING_SPENDING_KEY_ID
 	case HashTypeSaplingAddress:
 		return SAPLING_ADDRESS_ID
+	case HashTypePkhBls12_381:
+		return BLS12_381_PUBLIC_KEY_HASH_ID
+	case HashTypePkBls12_381:
+		return BLS12_381_PUBLIC_KEY_ID
+	case HashTypeSkBls12_381:
+		return BLS12_381_SECRET_KEY_ID
+	case HashTypeSigBls12_381:
+		return BLS12_381_SIGNATURE_ID
 	default:
 		return nil
 	}
@@ -399,7 +451,10 @@ func (t HashType) Len() int {
 		HashTypePkhP256,
 		HashTypePkhNocurve,
 		HashTypePkhBlinded,
-		HashTypePkhBaker:
+		HashTypePkhBaker,
+		HashTypePkhBls12_381,
+		HashTypeTxRollupAddress,
+		HashTypeSmartRollupAddress:
 		return 20
 	case HashTypeBlock,
 		HashTypeOperation,
@@ -417,7 +472,8 @@ func (t HashType) Len() int {
 		HashTypeBlockMetadata,
 		HashTypeOperationMetadata,
 		HashTypeOperationMetadataList,
-		HashTypeOperationMetadataListList:
+		HashTypeOperationMetadataListList,
+		HashTypeSkBls12_381:
 		return 32
 	case HashTypePkSecp256k1,
 		HashTypePkP256,
@@ -426,6 +482,8 @@ func (t HashType) Len() int {
 		return 33
 	case HashTypeSaplingAddress:
 		return 43
+	case HashTypePkBls12_381:
+		return 48
 	case HashTypeEncryptedSeedEd25519,
 		HashTypeEncryptedSkSecp256k1,
 		HashTypeEncryptedSkP256:
@@ -438,6 +496,8 @@ func (t HashType) Len() int {
 		HashTypeSigP256,
 		HashTypeSigGeneric:
 		return 64
+	case HashTypeSigBls12_381:
+		return 96
 	case HashTypeSaplingSpendingKey:
 		return 169
 	default:
@@ -454,9 +514,12 @@ func (t HashType) Base58Len() int {
 		HashTypePkhSecp256k1,
 		HashTypePkhP256,
 		HashTypePkhNocurve,
-		HashTypePkhBaker:
+		HashTypePkhBaker,
+		HashTypePkhBls12_381,
+		HashTypeSmartRollupAddress:
 		return 36
-	case HashTypePkhBlinded:
+	case HashTypePkhBlinded,
+		HashTypeTxRollupAddress:
 		return 37
 	case HashTypeBlock,
 		HashTypeOperation,
@@ -479,13 +542,16 @@ func (t HashType) Base58Len() int {
 		HashTypeSkSecp256k1,
 		HashTypeSkP256,
 		HashTypeElementSecp256k1,
-		HashTypeScriptExpr:
+		HashTypeScriptExpr,
+		HashTypeSkBls12_381:
 		return 54
 	case HashTypePkSecp256k1,
 		HashTypePkP256:
 		return 55
 	case HashTypeSaplingAddress:
 		return 69
+	case HashTypePkBls12_381:
+		return 76
 	case HashTypeEncryptedSeedEd25519,
 		HashTypeEncryptedSkSecp256k1,
 		HashTypeEncryptedSkP256:
@@ -500,6 +566,8 @@ func (t HashType) Base58Len() int {
 	case HashTypeSigEd25519,
 		HashTypeSigSecp256k1:
 		return 99
+	case HashTypeSigBls12_381:
+		return 142
 	case HashTypeSaplingSpendingKey:
 		return 241
 	default:
@@ -584,6 +652,47 @@ func (h Hash) MarshalBinary() ([]byte, error) {
 	return h.Hash, nil
 }
 
+// UnmarshalBinary sets the hash from its raw bytes. The hash type cannot be
+// derived from binary data, so h.Type must be set before decoding. Use
+// typed hashes like BlockHash when the type is not known in advance.
+func (h *Hash) UnmarshalBinary(data []byte) error {
+	if !h.Type.IsValid() {
+		return ErrUnknownHashType
+	}
+	if l := len(data); l > 0 && l != h.Type.Len() {
+		return fmt.Errorf("tezos: invalid len %d for %s hash", l, h.Type)
+	}
+	h.Hash = make([]byte, len(data))
+	copy(h.Hash, data)
+	return nil
+}
+
+// ComputeHash returns the blake2b hash of data with the digest size of typ.
+func ComputeHash(typ HashType, data []byte) Hash {
+	h, err := blake2b.New(typ.Len(), nil)
+	if err != nil {
+		return InvalidHash
+	}
+	h.Write(data)
+	return NewHash(typ, h.Sum(nil))
+}
+
+// ComputeExprHash returns the script expression hash of a packed Micheline
+// value. Packed data must start with the 0x05 pack prefix.
+func ComputeExprHash(packed []byte) ExprHash {
+	return ExprHash{ComputeHash(HashTypeScriptExpr, packed)}
+}
+
+// ComputeOpHash returns the hash of a binary encoded signed operation.
+func ComputeOpHash(op []byte) OpHash {
+	return OpHash{ComputeHash(HashTypeOperation, op)}
+}
+
+// ComputeBlockHash returns the hash of a binary encoded signed block header.
+func ComputeBlockHash(header []byte) BlockHash {
+	return BlockHash{ComputeHash(HashTypeBlock, header)}
+}
+
 // ChainIdHash
 type ChainIdHash struct {
 	Hash
@@ -632,10 +741,19 @@ func (h *ChainIdHash) UnmarshalBinary(data []byte) error {
 	return nil
 }
 
+// Uint32 returns the 4 byte binary chain id as big-endian integer.
 func (h ChainIdHash) Uint32() uint32 {
 	return binary.BigEndian.Uint32(h.Hash.Hash[:])
 }
 
+// NewChainIdHashFromUint32 creates a chain id from its big-endian integer representation
+// as returned by Uint32.
+func NewChainIdHashFromUint32(v uint32) ChainIdHash {
+	var buf [4]byte
+	binary.BigEndian.PutUint32(buf[:], v)
+	return NewChainIdHash(buf[:])
+}
+
 func MustParseChainIdHash(s string) ChainIdHash {
 	h, err := ParseChainIdHash(s)
 	if err != nil {
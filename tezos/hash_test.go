@@ -0,0 +1,54 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package tezos
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+func TestComputeHash(t *testing.T) {
+	// blake2b-256 of empty input
+	empty, _ := hex.DecodeString("0e5751c026e543b2e8ab2eb06099daa1d1e5df47778f7787faab45cdf12fe3a8")
+	if h := ComputeBlockHash(nil); !h.Equal(NewBlockHash(empty)) {
+		t.Errorf("block hash mismatch got=%s want=%s", h, NewBlockHash(empty))
+	}
+	if h := ComputeOpHash([]byte{}); !h.Equal(NewOpHash(empty)) {
+		t.Errorf("op hash mismatch got=%s want=%s", h, NewOpHash(empty))
+	}
+	if h := ComputeExprHash(nil); !h.Equal(NewExprHash(empty)) {
+		t.Errorf("expr hash mismatch got=%s want=%s", h, NewExprHash(empty))
+	}
+	data := []byte("tezos")
+	d := Digest(data)
+	if h := ComputeHash(HashTypeContext, data); !h.IsValid() || !bytes.Equal(h.Hash, d[:]) {
+		t.Errorf("context hash mismatch got=%x want=%x", h.Hash, d)
+	}
+	if h := ComputeHash(HashTypePkhEd25519, data); !h.IsValid() || h.Type.Len() != 20 {
+		t.Errorf("invalid pkh hash %x", h.Hash)
+	}
+	if h := ComputeHash(HashTypeInvalid, data); h.IsValid() {
+		t.Errorf("expected invalid hash")
+	}
+}
+
+func TestHashBinary(t *testing.T) {
+	h := ComputeHash(HashTypeOperation, []byte("op"))
+	buf, _ := h.MarshalBinary()
+	h2 := Hash{Type: HashTypeOperation}
+	if err := h2.UnmarshalBinary(buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !h.Equal(h2) {
+		t.Errorf("mismatch got=%s want=%s", h2, h)
+	}
+	if err := h2.UnmarshalBinary(buf[1:]); err == nil {
+		t.Errorf("expected length error")
+	}
+	var h3 Hash
+	if err := h3.UnmarshalBinary(buf); err == nil {
+		t.Errorf("expected error for unknown type")
+	}
+}
@@ -37,6 +37,13 @@ var (
 // PassphraseFunc is a callback used to obtain a passphrase for decrypting a private key
 type PassphraseFunc func() ([]byte, error)
 
+// Passphrase returns a PassphraseFunc that always returns the static passphrase s.
+func Passphrase(s string) PassphraseFunc {
+	return func() ([]byte, error) {
+		return []byte(s), nil
+	}
+}
+
 // KeyType is a type that describes which cryptograhic curve is used by a public or
 // private key
 type KeyType byte
@@ -46,10 +53,11 @@ const (
 	KeyTypeSecp256k1
 	KeyTypeP256
 	KeyTypeInvalid
+	KeyTypeBls12_381 // appended to keep the values of existing types stable
 )
 
 func (t KeyType) IsValid() bool {
-	return t < KeyTypeInvalid
+	return t < KeyTypeInvalid || t == KeyTypeBls12_381
 }
 
 func (t KeyType) String() string {
@@ -75,6 +83,8 @@ func (t KeyType) PkHashType() HashType {
 		return HashTypePkSecp256k1
 	case KeyTypeP256:
 		return HashTypePkP256
+	case KeyTypeBls12_381:
+		return HashTypePkBls12_381
 	default:
 		return HashTypeInvalid
 	}
@@ -88,11 +98,29 @@ func (t KeyType) SkHashType() HashType {
 		return HashTypeSkSecp256k1
 	case KeyTypeP256:
 		return HashTypeSkP256
+	case KeyTypeBls12_381:
+		return HashTypeSkBls12_381
 	default:
 		return HashTypeInvalid
 	}
 }
 
+// SignatureType returns the type of signatures created with keys of type t.
+func (t KeyType) SignatureType() SignatureType {
+	switch t {
+	case KeyTypeEd25519:
+		return SignatureTypeEd25519
+	case KeyTypeSecp256k1:
+		return SignatureTypeSecp256k1
+	case KeyTypeP256:
+		return SignatureTypeP256
+	case KeyTypeBls12_381:
+		return SignatureTypeBls12_381
+	default:
+		return SignatureTypeInvalid
+	}
+}
+
 func (t KeyType) AddressType() AddressType {
 	switch t {
 	case KeyTypeEd25519:
@@ -114,6 +142,8 @@ func (t KeyType) PkPrefixBytes() []byte {
 		return SECP256K1_PUBLIC_KEY_ID
 	case KeyTypeP256:
 		return P256_PUBLIC_KEY_ID
+	case KeyTypeBls12_381:
+		return BLS12_381_PUBLIC_KEY_ID
 	default:
 		return nil
 	}
@@ -127,6 +157,8 @@ func (t KeyType) PkPrefix() string {
 		return SECP256K1_PUBLIC_KEY_PREFIX
 	case KeyTypeP256:
 		return P256_PUBLIC_KEY_PREFIX
+	case KeyTypeBls12_381:
+		return BLS12_381_PUBLIC_KEY_PREFIX
 	default:
 		return ""
 	}
@@ -140,6 +172,8 @@ func (t KeyType) SkPrefixBytes() []byte {
 		return SECP256K1_SECRET_KEY_ID
 	case KeyTypeP256:
 		return P256_SECRET_KEY_ID
+	case KeyTypeBls12_381:
+		return BLS12_381_SECRET_KEY_ID
 	default:
 		return nil
 	}
@@ -166,6 +200,8 @@ func (t KeyType) SkPrefix() string {
 		return SECP256K1_SECRET_KEY_PREFIX
 	case KeyTypeP256:
 		return P256_SECRET_KEY_PREFIX
+	case KeyTypeBls12_381:
+		return BLS12_381_SECRET_KEY_PREFIX
 	default:
 		return ""
 	}
@@ -192,6 +228,8 @@ func (t KeyType) Tag() byte {
 		return 1
 	case KeyTypeP256:
 		return 2
+	case KeyTypeBls12_381:
+		return 3
 	default:
 		return 255
 	}
@@ -205,6 +243,8 @@ func ParseKeyTag(b byte) KeyType {
 		return KeyTypeSecp256k1
 	case 2:
 		return KeyTypeP256
+	case 3:
+		return KeyTypeBls12_381
 	default:
 		return KeyTypeInvalid
 	}
@@ -234,6 +274,7 @@ func IsPublicKey(s string) bool {
 		ED25519_PUBLIC_KEY_PREFIX,
 		SECP256K1_PUBLIC_KEY_PREFIX,
 		P256_PUBLIC_KEY_PREFIX,
+		BLS12_381_PUBLIC_KEY_PREFIX,
 	} {
 		if strings.HasPrefix(s, prefix) {
 			return true
@@ -289,11 +330,21 @@ func NewKey(typ KeyType, data []byte) Key {
 	}
 }
 
-// Verify verifies the signature using the public key.
+// Verify verifies the signature over hash (usually a 32 byte Blake2b digest)
+// using the public key. Generic signatures are checked against the key's
+// curve, typed signatures must match the key type. Like the node, high-S
+// secp256k1 signatures are rejected. Malformed keys and signatures return
+// an error.
 func (k Key) Verify(hash []byte, sig Signature) error {
+	if sig.Type != SignatureTypeGeneric && sig.Type != k.Type.SignatureType() {
+		return ErrSignature
+	}
 	switch k.Type {
 	case KeyTypeEd25519:
 		pk := ed25519.PublicKey(k.Data)
+		if len(pk) != ed25519.PublicKeySize {
+			return ErrSignature
+		}
 		if ok := ed25519.Verify(pk, hash, sig.Data); !ok {
 			return ErrSignature
 		}
@@ -306,10 +357,27 @@ func (k Key) Verify(hash []byte, sig Signature) error {
 		if ok := ecVerifySignature(pk, hash, sig); !ok {
 			return ErrSignature
 		}
+	default:
+		return ErrUnknownKeyType
 	}
 	return nil
 }
 
+// operationWatermark is the magic byte prefix of manager and anonymous
+// operations before signing.
+const operationWatermark byte = 0x03
+
+// VerifyOperation checks sig over a forged operation (without signature) as
+// signed by wallets, i.e. over the Blake2b digest of the operation bytes
+// prefixed with the generic operation watermark.
+func VerifyOperation(pub Key, opBytes []byte, sig Signature) error {
+	buf := make([]byte, 0, len(opBytes)+1)
+	buf = append(buf, operationWatermark)
+	buf = append(buf, opBytes...)
+	digest := Digest(buf)
+	return pub.Verify(digest[:], sig)
+}
+
 func (k Key) IsValid() bool {
 	return k.Type.IsValid() && k.Type.PkHashType().Len() == len(k.Data)
 }
@@ -375,6 +443,15 @@ func (k Key) Bytes() []byte {
 	return append([]byte{k.Type.Tag()}, k.Data...)
 }
 
+// EncodeBinary returns the binary encoding of a public key as used in reveal
+// operations, i.e. a one byte curve tag followed by the key data.
+func (k Key) EncodeBinary() ([]byte, error) {
+	if !k.IsValid() {
+		return nil, fmt.Errorf("tezos: invalid %s key length %d", k.Type, len(k.Data))
+	}
+	return k.Bytes(), nil
+}
+
 func DecodeKey(buf []byte) (Key, error) {
 	k := Key{}
 	if len(buf) == 0 {
@@ -386,6 +463,24 @@ func DecodeKey(buf []byte) (Key, error) {
 	return k, nil
 }
 
+// DecodeBinaryKey reads a tagged binary public key from the start of buf and
+// returns the key and the number of bytes read. Unlike DecodeKey trailing data
+// is allowed and empty input is an error. See EncodeBinary.
+func DecodeBinaryKey(buf []byte) (Key, int, error) {
+	if len(buf) == 0 {
+		return InvalidKey, 0, fmt.Errorf("tezos: short binary key length %d", len(buf))
+	}
+	typ := ParseKeyTag(buf[0])
+	if !typ.IsValid() {
+		return InvalidKey, 0, fmt.Errorf("tezos: invalid binary key type %x", buf[0])
+	}
+	n := typ.PkHashType().Len() + 1
+	if len(buf) < n {
+		return InvalidKey, 0, fmt.Errorf("tezos: short binary %s key length %d", typ, len(buf))
+	}
+	return NewKey(typ, append([]byte{}, buf[1:n]...)), n, nil
+}
+
 func (k *Key) UnmarshalBinary(b []byte) error {
 	l := len(b)
 	if l < 33 {
@@ -448,6 +543,8 @@ func ParseKey(s string) (Key, error) {
 		k.Type = KeyTypeSecp256k1
 	case bytes.Equal(version, P256_PUBLIC_KEY_ID):
 		k.Type = KeyTypeP256
+	case bytes.Equal(version, BLS12_381_PUBLIC_KEY_ID):
+		k.Type = KeyTypeBls12_381
 	default:
 		return k, fmt.Errorf("tezos: unknown version %x for key %s", version, s)
 	}
@@ -526,6 +623,8 @@ func GenerateKey(typ KeyType) (PrivateKey, error) {
 		}
 		key.Data = make([]byte, typ.SkHashType().Len())
 		ecKey.D.FillBytes(key.Data)
+	default:
+		return key, ErrUnknownKeyType
 	}
 	return key, nil
 }
@@ -550,7 +649,9 @@ func (k PrivateKey) Public() Key {
 	return pk
 }
 
-// Encrypt encrypts the private key with a passphrase obtained from calling fn.
+// Encrypt encrypts the private key with a passphrase obtained from calling fn
+// into the edesk, spesk or p2esk format used by tezos-client. Use Passphrase to
+// wrap a static passphrase.
 func (k PrivateKey) Encrypt(fn PassphraseFunc) (string, error) {
 	var buf []byte
 	switch k.Type {
@@ -662,3 +763,12 @@ func ParseEncryptedPrivateKey(s string, fn PassphraseFunc) (k PrivateKey, err er
 func ParsePrivateKey(s string) (PrivateKey, error) {
 	return ParseEncryptedPrivateKey(s, nil)
 }
+
+// DecryptPrivateKey decrypts an encrypted private key in edesk, spesk or p2esk
+// format as written by tezos-client using passphrase.
+func DecryptPrivateKey(s, passphrase string) (PrivateKey, error) {
+	if !IsEncryptedKey(s) {
+		return PrivateKey{}, fmt.Errorf("tezos: private key is not encrypted")
+	}
+	return ParseEncryptedPrivateKey(s, Passphrase(passphrase))
+}
@@ -0,0 +1,241 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package tezos
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"math/big"
+	"testing"
+)
+
+func TestKeySignVerify(t *testing.T) {
+	hash := sha256.Sum256([]byte("tzgo"))
+	for _, typ := range []KeyType{KeyTypeEd25519, KeyTypeSecp256k1, KeyTypeP256} {
+		sk, err := GenerateKey(typ)
+		if err != nil {
+			t.Fatalf("%s: generate: %v", typ, err)
+		}
+		// private key string round-trip
+		sk2, err := ParsePrivateKey(sk.String())
+		if err != nil {
+			t.Fatalf("%s: parse private key: %v", typ, err)
+		}
+		pk := sk2.Public()
+		if !pk.IsEqual(sk.Public()) {
+			t.Fatalf("%s: public key mismatch", typ)
+		}
+		if have, want := pk.Address().Type, typ.AddressType(); have != want {
+			t.Errorf("%s: address type have=%s want=%s", typ, have, want)
+		}
+		// public key string round-trip
+		pk2, err := ParseKey(pk.String())
+		if err != nil || !pk2.IsEqual(pk) {
+			t.Fatalf("%s: parse public key: %v", typ, err)
+		}
+		sig, err := sk.Sign(hash[:])
+		if err != nil {
+			t.Fatalf("%s: sign: %v", typ, err)
+		}
+		if err := pk.Verify(hash[:], sig); err != nil {
+			t.Errorf("%s: verify: %v", typ, err)
+		}
+		sig2, err := ParseSignature(sig.String())
+		if err != nil || !sig2.IsEqual(sig) {
+			t.Errorf("%s: parse signature: %v", typ, err)
+		}
+		if err := pk.Verify(hash[1:], sig); err != ErrSignature {
+			t.Errorf("%s: expected signature mismatch, got %v", typ, err)
+		}
+		if typ == KeyTypeEd25519 {
+			if _, err := sig.DER(); err == nil {
+				t.Errorf("%s: expected DER error", typ)
+			}
+			continue
+		}
+
+		// DER round-trip
+		der, err := sig.DER()
+		if err != nil {
+			t.Fatalf("%s: DER: %v", typ, err)
+		}
+		sig3, err := ParseDERSignature(sig.Type, der)
+		if err != nil || !sig3.IsEqual(sig) {
+			t.Fatalf("%s: parse DER: %v", typ, err)
+		}
+
+		// high-S variant normalizes on DER decode and is rejected for secp256k1
+		n := typ.Curve().Params().N
+		s := new(big.Int).SetBytes(sig.Data[32:])
+		high := sig.Clone()
+		new(big.Int).Sub(n, s).FillBytes(high.Data[32:])
+		err = pk.Verify(hash[:], high)
+		if typ == KeyTypeSecp256k1 && err != ErrSignature {
+			t.Errorf("%s: expected high-S rejection, got %v", typ, err)
+		}
+		if typ == KeyTypeP256 && err != nil {
+			t.Errorf("%s: verify high-S: %v", typ, err)
+		}
+		der, _ = high.DER()
+		sig4, err := ParseDERSignature(sig.Type, der)
+		if err != nil || !sig4.IsEqual(sig) {
+			t.Errorf("%s: DER low-S normalization failed: %v", typ, err)
+		}
+	}
+	if _, err := GenerateKey(KeyTypeInvalid); err != ErrUnknownKeyType {
+		t.Errorf("expected unknown key type error, got %v", err)
+	}
+}
+
+func TestKeyEncrypt(t *testing.T) {
+	for _, typ := range []KeyType{KeyTypeEd25519, KeyTypeSecp256k1, KeyTypeP256} {
+		sk, _ := GenerateKey(typ)
+		enc, err := sk.Encrypt(Passphrase("secret"))
+		if err != nil {
+			t.Fatalf("%s: encrypt: %v", typ, err)
+		}
+		if !IsEncryptedKey(enc) {
+			t.Fatalf("%s: unexpected format %s", typ, enc)
+		}
+		dec, err := DecryptPrivateKey(enc, "secret")
+		if err != nil {
+			t.Fatalf("%s: decrypt: %v", typ, err)
+		}
+		if dec.String() != sk.String() {
+			t.Errorf("%s: key mismatch", typ)
+		}
+		if _, err := DecryptPrivateKey(enc, "wrong"); err == nil {
+			t.Errorf("%s: expected error for wrong passphrase", typ)
+		}
+	}
+	sk, _ := GenerateKey(KeyTypeEd25519)
+	if _, err := DecryptPrivateKey(sk.String(), "secret"); err == nil {
+		t.Errorf("expected error for unencrypted key")
+	}
+}
+
+func TestKeySignatureBinary(t *testing.T) {
+	for i, typ := range []KeyType{KeyTypeEd25519, KeyTypeSecp256k1, KeyTypeP256, KeyTypeBls12_381} {
+		pk := NewKey(typ, bytes.Repeat([]byte{byte(i + 1)}, typ.PkHashType().Len()))
+		buf, err := pk.EncodeBinary()
+		if err != nil {
+			t.Fatalf("%s: encode key: %v", typ, err)
+		}
+		if buf[0] != byte(i) || len(buf) != len(pk.Data)+1 {
+			t.Errorf("%s: unexpected key encoding %x", typ, buf)
+		}
+		pk2, n, err := DecodeBinaryKey(append(buf, 0xff))
+		if err != nil || n != len(buf) || !pk2.IsEqual(pk) {
+			t.Errorf("%s: decode key n=%d err=%v", typ, n, err)
+		}
+		if pk3, err := ParseKey(pk.String()); err != nil || !pk3.IsEqual(pk) {
+			t.Errorf("%s: parse key: %v", typ, err)
+		}
+		if _, _, err := DecodeBinaryKey(buf[:len(buf)-1]); err == nil {
+			t.Errorf("%s: expected short key error", typ)
+		}
+	}
+	if _, _, err := DecodeBinaryKey([]byte{4}); err == nil {
+		t.Errorf("expected invalid key tag error")
+	}
+	if k, err := DecodeKey(nil); err != nil || k.Type != KeyTypeEd25519 || k.Data != nil {
+		t.Errorf("empty key: got %v %v", k, err)
+	}
+	if k, err := DecodeKey(append([]byte{2}, make([]byte, 33)...)); err != nil || k.Type != KeyTypeP256 {
+		t.Errorf("decode key: got %v %v", k, err)
+	}
+	// existing enum values are stable
+	if KeyTypeInvalid != 3 || SignatureTypeInvalid != 4 || !KeyTypeBls12_381.IsValid() || !SignatureTypeBls12_381.IsValid() {
+		t.Errorf("unexpected key or signature type values")
+	}
+
+	for i, typ := range []SignatureType{SignatureTypeEd25519, SignatureTypeSecp256k1, SignatureTypeP256, SignatureTypeBls12_381} {
+		sig := NewSignature(typ, bytes.Repeat([]byte{byte(i + 1)}, typ.Len()))
+		buf, err := sig.EncodeBinary()
+		if err != nil {
+			t.Fatalf("%s: encode signature: %v", typ, err)
+		}
+		if buf[0] != byte(i) || len(buf) != typ.Len()+1 {
+			t.Errorf("%s: unexpected signature encoding %x", typ, buf)
+		}
+		sig2, n, err := DecodeSignature(buf)
+		if err != nil || n != len(buf) || !sig2.IsEqual(sig) {
+			t.Errorf("%s: decode signature n=%d err=%v", typ, n, err)
+		}
+		var sig3 Signature
+		if err := sig3.UnmarshalBinary(buf); err != nil || !sig3.IsEqual(sig) {
+			t.Errorf("%s: unmarshal signature: %v", typ, err)
+		}
+		if sig4, err := ParseSignature(sig.String()); err != nil || !sig4.IsEqual(sig) {
+			t.Errorf("%s: parse signature: %v", typ, err)
+		}
+	}
+
+	// generic signatures have no tag
+	sig := NewSignature(SignatureTypeGeneric, bytes.Repeat([]byte{0xaa}, 64))
+	buf, err := sig.EncodeBinary()
+	if err != nil || !bytes.Equal(buf, sig.Data) {
+		t.Fatalf("generic: unexpected encoding %x %v", buf, err)
+	}
+	sig2, n, err := DecodeGenericSignature(append(buf, 0xff))
+	if err != nil || n != 64 || !sig2.IsEqual(sig) {
+		t.Errorf("generic: decode n=%d err=%v", n, err)
+	}
+	bls := bytes.Repeat([]byte{0xbb}, 96)
+	if sig3, n, err := DecodeBlsSignature(bls); err != nil || n != 96 || sig3.Type != SignatureTypeBls12_381 || !bytes.Equal(sig3.Data, bls) {
+		t.Errorf("bls: decode n=%d err=%v", n, err)
+	}
+	if _, _, err := DecodeBlsSignature(bls[:64]); err == nil {
+		t.Errorf("bls: expected short signature error")
+	}
+	if _, err := NewSignature(SignatureTypeEd25519, buf[:32]).EncodeBinary(); err == nil {
+		t.Errorf("expected invalid signature length error")
+	}
+}
+
+func TestVerifyOperation(t *testing.T) {
+	op := bytes.Repeat([]byte{0xab}, 100)
+	digest := Digest(append([]byte{0x03}, op...))
+	for _, typ := range []KeyType{KeyTypeEd25519, KeyTypeSecp256k1, KeyTypeP256} {
+		sk, err := GenerateKey(typ)
+		if err != nil {
+			t.Fatalf("%s: generate: %v", typ, err)
+		}
+		pk := sk.Public()
+		sig, err := sk.Sign(digest[:])
+		if err != nil {
+			t.Fatalf("%s: sign: %v", typ, err)
+		}
+		if err := VerifyOperation(pk, op, sig); err != nil {
+			t.Errorf("%s: verify: %v", typ, err)
+		}
+		// generic signatures verify against the key's curve
+		generic := NewSignature(SignatureTypeGeneric, sig.Data)
+		if err := VerifyOperation(pk, op, generic); err != nil {
+			t.Errorf("%s: verify generic: %v", typ, err)
+		}
+		if err := VerifyOperation(pk, op[1:], sig); err != ErrSignature {
+			t.Errorf("%s: expected mismatch, got %v", typ, err)
+		}
+		// typed signatures of another curve never verify
+		other := NewSignature((sig.Type+1)%SignatureTypeGeneric, sig.Data)
+		if err := VerifyOperation(pk, op, other); err != ErrSignature {
+			t.Errorf("%s: expected type mismatch, got %v", typ, err)
+		}
+		// malformed signatures and keys must not panic
+		for _, bad := range []Signature{
+			{Type: sig.Type},
+			{Type: SignatureTypeGeneric, Data: sig.Data[:10]},
+			{Type: SignatureTypeGeneric, Data: bytes.Repeat([]byte{0xff}, 64)},
+			InvalidSignature,
+		} {
+			if err := VerifyOperation(pk, op, bad); err == nil {
+				t.Errorf("%s: malformed signature %x verified", typ, bad.Data)
+			}
+		}
+		if err := VerifyOperation(Key{Type: typ, Data: pk.Data[:5]}, op, sig); err == nil {
+			t.Errorf("%s: truncated key verified", typ)
+		}
+	}
+}
@@ -20,6 +20,9 @@ const (
 	NOCURVE_PUBLIC_KEY_HASH_PREFIX   = "KT1"  // originated contract identifier
 	BAKER_PUBLIC_KEY_HASH_PREFIX     = "SG1"  // baker contract (undeployed)
 	BLINDED_PUBLIC_KEY_HASH_PREFIX   = "btz1" // blinded tz1
+	BLS12_381_PUBLIC_KEY_HASH_PREFIX = "tz4"  // "\006\161\166" (* tz4(36) *)
+	TX_ROLLUP_ADDRESS_PREFIX         = "txr1" // transaction rollup
+	SMART_ROLLUP_ADDRESS_PREFIX      = "sr1"  // smart rollup
 
 	// base58 prefixes for 32 byte hash magics
 	BLOCK_HASH_PREFIX               = "B"
@@ -64,6 +67,11 @@ const (
 	P256_SIGNATURE_PREFIX      = "p2sig"
 	GENERIC_SIGNATURE_PREFIX   = "sig"
 
+	// base58 prefixes for BLS12-381 magics
+	BLS12_381_PUBLIC_KEY_PREFIX = "BLpk"  // "\006\149\135\204" (* BLpk(76) *) // 48 bytes
+	BLS12_381_SECRET_KEY_PREFIX = "BLsk"  // "\003\150\192\040" (* BLsk(54) *) // 32 bytes
+	BLS12_381_SIGNATURE_PREFIX  = "BLsig" // "\040\171\064\207" (* BLsig(142) *) // 96 bytes
+
 	// base58 prefixes for Sapling byte hash magics
 	SAPLING_SPENDING_KEY_PREFIX = "sask" // "\011\237\020\092" (* sask(241) *) // 169 bytes
 	SAPLING_ADDRESS_PREFIX      = "zet1" // "\018\071\040\223" (* zet1(69) *) // 43 bytes
@@ -83,6 +91,9 @@ var (
 	NOCURVE_PUBLIC_KEY_HASH_ID   = []byte{0x02, 0x5A, 0x79}       // "\002\090\121" (* KT1(36) *)
 	BAKER_PUBLIC_KEY_HASH_ID     = []byte{0x03, 0x38, 0xE2}       // "\003\056\226" (* SG1(36) *)
 	BLINDED_PUBLIC_KEY_HASH_ID   = []byte{0x01, 0x02, 0x31, 0xDF} // "\002\090\121" (* btz1(37) *)
+	BLS12_381_PUBLIC_KEY_HASH_ID = []byte{0x06, 0xA1, 0xA6}       // "\006\161\166" (* tz4(36) *)
+	TX_ROLLUP_ADDRESS_ID         = []byte{0x01, 0x80, 0x78, 0x1F} // "\001\128\120\031" (* txr1(37) *)
+	SMART_ROLLUP_ADDRESS_ID      = []byte{0x06, 0x7C, 0x75}       // "\006\124\117" (* sr1(36) *)
 
 	// 32 byte hash magics
 	BLOCK_HASH_ID               = []byte{0x01, 0x34}       // "\001\052" (* B(51) *)
@@ -128,6 +139,11 @@ var (
 	P256_SIGNATURE_ID      = []byte{0x36, 0xF0, 0x2C, 0x34}       // "\054\240\044\052" (* p2sig(98) *)
 	GENERIC_SIGNATURE_ID   = []byte{0x04, 0x82, 0x2B}             // "\004\130\043" (* sig(96) *)
 
+	// BLS12-381 magics
+	BLS12_381_PUBLIC_KEY_ID = []byte{0x06, 0x95, 0x87, 0xCC} // "\006\149\135\204" (* BLpk(76) *)
+	BLS12_381_SECRET_KEY_ID = []byte{0x03, 0x96, 0xC0, 0x28} // "\003\150\192\040" (* BLsk(54) *)
+	BLS12_381_SIGNATURE_ID  = []byte{0x28, 0xAB, 0x40, 0xCF} // "\040\171\064\207" (* BLsig(142) *)
+
 	// Sapling magics
 	SAPLING_SPENDING_KEY_ID = []byte{0x0b, 0xED, 0x14, 0x5C} // "\011\237\020\092" (* sask(241) *)
 	SAPLING_ADDRESS_ID      = []byte{0x12, 0x47, 0x28, 0xDF} // "\018\071\040\223" (* zet1(69) *)
@@ -0,0 +1,156 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package tezos
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+var (
+	// ErrMutezOverflow is returned when an arithmetic operation on Mutez values
+	// exceeds the int64 range.
+	ErrMutezOverflow = errors.New("tezos: mutez overflow")
+
+	// ErrMutezNegative is returned when an arithmetic operation would produce
+	// a negative Mutez value.
+	ErrMutezNegative = errors.New("tezos: negative mutez")
+)
+
+// Mutez represents an amount of tez in micro tez (1 tez = 1,000,000 mutez).
+// Arithmetic methods check for overflows and negative results which are
+// invalid amounts on chain. JSON and text encodings use the node's decimal
+// string convention.
+type Mutez int64
+
+// ParseMutez parses a decimal mutez string.
+func ParseMutez(s string) (Mutez, error) {
+	i, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("tezos: invalid mutez amount %q: %w", s, err)
+	}
+	return Mutez(i), nil
+}
+
+// ParseTez parses a decimal tez string with up to 6 fractional digits such as
+// "12.345678" into a Mutez amount.
+func ParseTez(s string) (Mutez, error) {
+	neg, digits, excess, ok := scaleDecimal(s, 6)
+	if !ok || excess != "" {
+		return 0, fmt.Errorf("tezos: invalid tez amount %q", s)
+	}
+	v, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("tezos: invalid tez amount %q: %w", s, ErrMutezOverflow)
+	}
+	if neg {
+		v = -v
+	}
+	return Mutez(v), nil
+}
+
+// Int64 returns the amount in mutez.
+func (m Mutez) Int64() int64 {
+	return int64(m)
+}
+
+// Float64 returns the amount in tez.
+func (m Mutez) Float64() float64 {
+	return float64(m) / 1000000
+}
+
+// IsZero returns true when the amount is zero.
+func (m Mutez) IsZero() bool {
+	return m == 0
+}
+
+// Add returns m + x or an error on overflow.
+func (m Mutez) Add(x Mutez) (Mutez, error) {
+	if (x > 0 && m > math.MaxInt64-x) || (x < 0 && m < math.MinInt64-x) {
+		return 0, ErrMutezOverflow
+	}
+	return m + x, nil
+}
+
+// Sub returns m - x or an error when the result would be negative.
+func (m Mutez) Sub(x Mutez) (Mutez, error) {
+	if (x < 0 && m > math.MaxInt64+x) || (x > 0 && m < math.MinInt64+x) {
+		return 0, ErrMutezOverflow
+	}
+	if m-x < 0 {
+		return 0, ErrMutezNegative
+	}
+	return m - x, nil
+}
+
+// Mul returns m * n or an error on overflow or when n is negative.
+func (m Mutez) Mul(n int64) (Mutez, error) {
+	if n < 0 {
+		return 0, ErrMutezNegative
+	}
+	if m == 0 || n == 0 {
+		return 0, nil
+	}
+	r := int64(m) * n
+	if r/n != int64(m) {
+		return 0, ErrMutezOverflow
+	}
+	return Mutez(r), nil
+}
+
+// String formats the amount in tez with 6 decimals, e.g. 1.500000.
+func (m Mutez) String() string {
+	v := int64(m)
+	sign := ""
+	if v < 0 {
+		sign = "-"
+	}
+	u := uint64(v)
+	if v < 0 {
+		u = uint64(-v)
+	}
+	return fmt.Sprintf("%s%d.%06d", sign, u/1000000, u%1000000)
+}
+
+// FormatTez formats the amount in tez rounded to prec decimals (0..6) and
+// groups integer digits in thousands separated by sep when sep is not empty,
+// e.g. FormatTez(2, ",") returns 1,234.57 for 1234567891 mutez.
+func (m Mutez) FormatTez(prec int, sep string) string {
+	if prec < 0 {
+		prec = 0
+	}
+	if prec > 6 {
+		prec = 6
+	}
+	v := int64(m)
+	u := uint64(v)
+	if v < 0 {
+		u = uint64(-v)
+	}
+	scale := uint64(1)
+	for i := prec; i < 6; i++ {
+		scale *= 10
+	}
+	// round half away from zero
+	if scale > 1 && u%scale >= scale/2 {
+		u += scale
+	}
+	u /= scale
+	return formatDecimal(v < 0, strconv.FormatUint(u, 10), prec, sep)
+}
+
+func (m Mutez) MarshalText() ([]byte, error) {
+	return []byte(strconv.FormatInt(int64(m), 10)), nil
+}
+
+func (m *Mutez) UnmarshalText(data []byte) error {
+	v, err := ParseMutez(string(data))
+	if err != nil {
+		return err
+	}
+	*m = v
+	return nil
+}
@@ -0,0 +1,85 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package tezos
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+func TestParseTez(t *testing.T) {
+	for _, test := range []struct {
+		s    string
+		want Mutez
+		err  bool
+	}{
+		{"12.345678", 12345678, false},
+		{"12", 12000000, false},
+		{"0.1", 100000, false},
+		{".5", 500000, false},
+		{"-1.000001", -1000001, false},
+		{"1.0000001", 0, true},
+		{"1,5", 0, true},
+		{"", 0, true},
+		{".", 0, true},
+		{"9223372036854.775807", math.MaxInt64, false},
+		{"9223372036854.775808", 0, true},
+		{"9223372036855", 0, true},
+	} {
+		m, err := ParseTez(test.s)
+		if (err != nil) != test.err {
+			t.Errorf("%q: unexpected error %v", test.s, err)
+			continue
+		}
+		if m != test.want {
+			t.Errorf("%q: have=%d want=%d", test.s, m, test.want)
+		}
+	}
+}
+
+func TestMutezFormat(t *testing.T) {
+	for _, test := range []struct {
+		m    Mutez
+		prec int
+		sep  string
+		want string
+	}{
+		{1234567891, 2, ",", "1,234.57"},
+		{1234567891, 6, "", "1234.567891"},
+		{1234567891, 0, "'", "1'235"},
+		{-1500000, 0, ",", "-2"},
+		{-1, 2, ",", "0.00"},
+		{999999, 3, ",", "1.000"},
+		{math.MaxInt64, 6, ",", "9,223,372,036,854.775807"},
+		{100, 6, ",", "0.000100"},
+	} {
+		if have := test.m.FormatTez(test.prec, test.sep); have != test.want {
+			t.Errorf("%d/%d: have=%s want=%s", test.m, test.prec, have, test.want)
+		}
+	}
+}
+
+func TestMutezArithmetic(t *testing.T) {
+	if _, err := Mutez(math.MaxInt64).Add(1); err != ErrMutezOverflow {
+		t.Errorf("expected overflow, got %v", err)
+	}
+	if _, err := Mutez(1).Sub(2); err != ErrMutezNegative {
+		t.Errorf("expected negative error, got %v", err)
+	}
+	if _, err := Mutez(math.MaxInt64 / 2).Mul(3); err != ErrMutezOverflow {
+		t.Errorf("expected overflow, got %v", err)
+	}
+	// node encodes amounts as JSON strings
+	var v struct {
+		Amount Mutez `json:"amount"`
+	}
+	if err := json.Unmarshal([]byte(`{"amount":"1500000"}`), &v); err != nil || v.Amount != 1500000 {
+		t.Fatalf("unmarshal: %d %v", v.Amount, err)
+	}
+	buf, _ := json.Marshal(v)
+	if string(buf) != `{"amount":"1500000"}` {
+		t.Errorf("marshal: %s", buf)
+	}
+}
@@ -95,6 +95,10 @@ const (
 	OpTypePreEndorsement                             // 20 v012
 	OpTypeDoublePreEndorsementEvidence               // 21 v012
 	OpTypeSetDepositsLimit                           // 22 v012
+	OpTypeEvent                                      // 23 v014 internal only
+	OpTypeUpdateConsensusKey                         // 24 v015
+	OpTypeDrainDelegate                              // 25 v015
+	OpTypeIncreasePaidStorage                        // 26 v014
 	OpTypeBatch                        = 254         // indexer only, output-only
 	OpTypeInvalid                      = 255
 )
@@ -166,6 +170,14 @@ func ParseOpType(s string) OpType {
 		return OpTypeDoublePreEndorsementEvidence
 	case "set_deposits_limit":
 		return OpTypeSetDepositsLimit
+	case "event":
+		return OpTypeEvent
+	case "update_consensus_key":
+		return OpTypeUpdateConsensusKey
+	case "drain_delegate":
+		return OpTypeDrainDelegate
+	case "increase_paid_storage":
+		return OpTypeIncreasePaidStorage
 	default:
 		return OpTypeInvalid
 	}
@@ -221,6 +233,14 @@ func (t OpType) String() string {
 		return "double_preendorsement_evidence"
 	case OpTypeSetDepositsLimit:
 		return "set_deposits_limit"
+	case OpTypeEvent:
+		return "event"
+	case OpTypeUpdateConsensusKey:
+		return "update_consensus_key"
+	case OpTypeDrainDelegate:
+		return "drain_delegate"
+	case OpTypeIncreasePaidStorage:
+		return "increase_paid_storage"
 	default:
 		return ""
 	}
@@ -276,6 +296,9 @@ var (
 		OpTypeEndorsement:                  21,  // v012
 		OpTypeDoublePreEndorsementEvidence: 7,   // v012
 		OpTypeSetDepositsLimit:             112, // v012
+		OpTypeIncreasePaidStorage:          113, // v014
+		OpTypeUpdateConsensusKey:           114, // v015
+		OpTypeDrainDelegate:                9,   // v015
 	}
 )
 
@@ -356,6 +379,9 @@ var (
 		20:  43,               // OpTypePreEndorsement // v012
 		21:  43,               // OpTypeEndorsement // v012
 		112: 27,               // OpTypeSetDepositsLimit // v012
+		113: 26 + 1 + 22,      // OpTypeIncreasePaidStorage // v014
+		114: 26 + 32,          // OpTypeUpdateConsensusKey // v015 (assuming shortest pk)
+		9:   1 + 3*21,         // OpTypeDrainDelegate // v015
 	}
 )
 
@@ -384,14 +410,17 @@ func (t OpType) ListId() int {
 		OpTypeDoubleBakingEvidence,
 		OpTypeDoubleEndorsementEvidence,
 		OpTypeSeedNonceRevelation,
-		OpTypeDoublePreEndorsementEvidence:
+		OpTypeDoublePreEndorsementEvidence,
+		OpTypeDrainDelegate:
 		return 2
 	case OpTypeTransaction, // generic user operations
 		OpTypeOrigination,
 		OpTypeDelegation,
 		OpTypeReveal,
 		OpTypeRegisterConstant,
-		OpTypeSetDepositsLimit:
+		OpTypeSetDepositsLimit,
+		OpTypeIncreasePaidStorage,
+		OpTypeUpdateConsensusKey:
 		return 3
 	case OpTypeBake, OpTypeUnfreeze, OpTypeSeedSlash:
 		return -1 // block level ops
@@ -441,6 +470,12 @@ func ParseOpTag(t byte) OpType {
 		return OpTypeDoublePreEndorsementEvidence
 	case 112:
 		return OpTypeSetDepositsLimit
+	case 113:
+		return OpTypeIncreasePaidStorage
+	case 114:
+		return OpTypeUpdateConsensusKey
+	case 9:
+		return OpTypeDrainDelegate
 	default:
 		return OpTypeInvalid
 	}
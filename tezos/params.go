@@ -206,17 +206,22 @@ func (p *Params) CycleFromHeight(height int64) int64 {
 	if height == 0 {
 		return 0
 	}
-	correct := int64(0)
-	if p.StartBlockOffset == height {
-		correct = 1
-	}
 	pp := p
 	if !p.ContainsHeight(height) {
 		pp = p.ForHeight(height)
 	}
+	correct := int64(0)
+	if pp.StartBlockOffset == height {
+		correct = 1
+	}
 	return pp.StartCycle + (height-pp.StartBlockOffset-1)/pp.BlocksPerCycle - correct
 }
 
+// CyclePosition returns the 0-based position of height within its cycle.
+func (p *Params) CyclePosition(height int64) int64 {
+	return height - p.CycleStartHeight(p.CycleFromHeight(height))
+}
+
 func (p *Params) CycleStartHeight(cycle int64) int64 {
 	pp := p
 	if !p.ContainsCycle(cycle) {
@@ -339,7 +344,15 @@ func (p *Params) ContainsHeight(height int64) bool {
 }
 
 func (p *Params) ContainsCycle(cycle int64) bool {
-	return p.StartCycle == 0 || p.StartCycle <= cycle
+	if p.StartCycle > cycle {
+		return false
+	}
+	// protocols may end in the middle of a cycle or change cycle length
+	if p.EndHeight > 0 && p.BlocksPerCycle > 0 {
+		last := p.StartCycle + (p.EndHeight-p.StartBlockOffset-1)/p.BlocksPerCycle
+		return cycle <= last
+	}
+	return true
 }
 
 func (p *Params) IsMainnet() bool {
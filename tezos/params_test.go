@@ -0,0 +1,46 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package tezos
+
+import (
+	"testing"
+)
+
+type cycleTest struct {
+	Name   string
+	Height int64
+	Cycle  int64
+	Start  int64
+	End    int64
+}
+
+var mainnetCycleTests = []cycleTest{
+	{Name: "genesis", Height: 1, Cycle: 0, Start: 1, End: 4096},
+	{Name: "v001 cycle 1", Height: 4097, Cycle: 1, Start: 4097, End: 8192},
+	{Name: "florence last", Height: 1589247, Cycle: 387, Start: 1585153, End: 1589248},
+	{Name: "granada first", Height: 1589248, Cycle: 387, Start: 1585153, End: 1589248},
+	{Name: "granada cycle 388", Height: 1589249, Cycle: 388, Start: 1589249, End: 1597440},
+	{Name: "granada cycle 388 end", Height: 1597440, Cycle: 388, Start: 1589249, End: 1597440},
+	{Name: "hangzhou last", Height: 2244608, Cycle: 467, Start: 2236417, End: 2244608},
+	{Name: "ithaca first", Height: 2244609, Cycle: 468, Start: 2244609, End: 2252800},
+	{Name: "ithaca cycle 500", Height: 2506753, Cycle: 500, Start: 2506753, End: 2514944},
+}
+
+func TestCycleMath(t *testing.T) {
+	p := NewParams().ForNetwork(Mainnet).ForProtocol(ProtoV012_2)
+	for _, test := range mainnetCycleTests {
+		if got := p.CycleFromHeight(test.Height); got != test.Cycle {
+			t.Errorf("%s: cycle from height %d want=%d got=%d", test.Name, test.Height, test.Cycle, got)
+		}
+		if got := p.CycleStartHeight(test.Cycle); got != test.Start {
+			t.Errorf("%s: start of cycle %d want=%d got=%d", test.Name, test.Cycle, test.Start, got)
+		}
+		if got := p.CycleEndHeight(test.Cycle); got != test.End {
+			t.Errorf("%s: end of cycle %d want=%d got=%d", test.Name, test.Cycle, test.End, got)
+		}
+		if got, want := p.CyclePosition(test.Height), test.Height-test.Start; got != want {
+			t.Errorf("%s: position of height %d want=%d got=%d", test.Name, test.Height, want, got)
+		}
+	}
+}
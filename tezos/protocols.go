@@ -40,6 +40,9 @@ var (
 	Ithacanet    = MustParseChainIdHash("NetXbhmtAbMukLc")
 	Ithacanet2   = MustParseChainIdHash("NetXnHfVqm9iesp")
 
+	// Ghostnet is the long-running testnet which continues the Ithacanet2 chain
+	Ghostnet = Ithacanet2
+
 	// Order of deployed protocols on different networks
 	// required to lookup correct block/vote/cycle offsets
 	ProtocolVersions = map[uint32][]ProtocolHash{
@@ -132,6 +135,12 @@ func (p *Params) ForProtocol(proto ProtocolHash) *Params {
 		pp.ReactivateByTx = true
 		pp.HasOriginationBug = true
 		pp.SilentSpendable = true
+		if Mainnet.Equal(p.ChainId) {
+			pp.BlocksPerCycle = 4096
+			pp.BlocksPerCommitment = 32
+			pp.BlocksPerRollSnapshot = 256
+			pp.BlocksPerVotingPeriod = 32768
+		}
 		pp.StartHeight = 1
 		pp.EndHeight = 1
 
@@ -140,6 +149,12 @@ func (p *Params) ForProtocol(proto ProtocolHash) *Params {
 		pp.ReactivateByTx = true
 		pp.HasOriginationBug = true
 		pp.SilentSpendable = true
+		if Mainnet.Equal(p.ChainId) {
+			pp.BlocksPerCycle = 4096
+			pp.BlocksPerCommitment = 32
+			pp.BlocksPerRollSnapshot = 256
+			pp.BlocksPerVotingPeriod = 32768
+		}
 		pp.StartHeight = 2
 		pp.EndHeight = 28082
 
@@ -147,6 +162,12 @@ func (p *Params) ForProtocol(proto ProtocolHash) *Params {
 		pp.Version = 2
 		pp.ReactivateByTx = true
 		pp.SilentSpendable = true
+		if Mainnet.Equal(p.ChainId) {
+			pp.BlocksPerCycle = 4096
+			pp.BlocksPerCommitment = 32
+			pp.BlocksPerRollSnapshot = 256
+			pp.BlocksPerVotingPeriod = 32768
+		}
 		pp.StartHeight = 28083
 		pp.EndHeight = 204761
 
@@ -154,6 +175,12 @@ func (p *Params) ForProtocol(proto ProtocolHash) *Params {
 		pp.Version = 3
 		pp.ReactivateByTx = true
 		pp.SilentSpendable = true
+		if Mainnet.Equal(p.ChainId) {
+			pp.BlocksPerCycle = 4096
+			pp.BlocksPerCommitment = 32
+			pp.BlocksPerRollSnapshot = 256
+			pp.BlocksPerVotingPeriod = 32768
+		}
 		pp.StartHeight = 204762
 		pp.EndHeight = 458752
 
@@ -163,6 +190,12 @@ func (p *Params) ForProtocol(proto ProtocolHash) *Params {
 		pp.Invoices = map[string]int64{
 			"tz1iSQEcaGpUn6EW5uAy3XhPiNg7BHMnRSXi": 100 * 1000000,
 		}
+		if Mainnet.Equal(p.ChainId) {
+			pp.BlocksPerCycle = 4096
+			pp.BlocksPerCommitment = 32
+			pp.BlocksPerRollSnapshot = 256
+			pp.BlocksPerVotingPeriod = 32768
+		}
 		pp.StartHeight = 458753
 		pp.EndHeight = 655360
 
@@ -173,6 +206,12 @@ func (p *Params) ForProtocol(proto ProtocolHash) *Params {
 			"KT1DUfaMfTRZZkvZAYQT5b3byXnvqoAykc43": 500 * 1000000,
 		}
 		pp.OperationTagsVersion = 1
+		if Mainnet.Equal(p.ChainId) {
+			pp.BlocksPerCycle = 4096
+			pp.BlocksPerCommitment = 32
+			pp.BlocksPerRollSnapshot = 256
+			pp.BlocksPerVotingPeriod = 32768
+		}
 		pp.StartHeight = 655361
 		pp.EndHeight = 851968
 
@@ -180,6 +219,12 @@ func (p *Params) ForProtocol(proto ProtocolHash) *Params {
 		// no invoice
 		pp.Version = 6
 		pp.OperationTagsVersion = 1
+		if Mainnet.Equal(p.ChainId) {
+			pp.BlocksPerCycle = 4096
+			pp.BlocksPerCommitment = 32
+			pp.BlocksPerRollSnapshot = 256
+			pp.BlocksPerVotingPeriod = 32768
+		}
 		pp.StartHeight = 851969
 		pp.EndHeight = 1212416
 
@@ -401,7 +446,7 @@ func (p *Params) ForCycle(c int64) *Params {
 	pp := p.Clean()
 	for i := len(versions) - 1; i >= 0; i-- {
 		pp = pp.Clean().ForNetwork(p.ChainId).ForProtocol(versions[i])
-		if pp.StartCycle == 0 || pp.StartCycle < c {
+		if pp.StartCycle == 0 || pp.StartCycle <= c {
 			return pp
 		}
 	}
@@ -4,16 +4,71 @@
 package tezos
 
 import (
-	"blockwatch.cc/tzgo/hash"
+	"bytes"
+	"encoding/json"
+	"sort"
 )
 
+// AddressKey is a comparable fixed size binary form of an address. Use it as
+// map key instead of the base58 string to avoid encoding costs in hot loops,
+// e.g. map[AddressKey]T serves as typed address map (see AddressMap for an
+// untyped container). Keys use the 22 byte binary address encoding so that
+// byte order matches protocol order. Keys encode as base58 strings in JSON, also as
+// map keys.
+type AddressKey [22]byte
+
+// Key returns the address key of a. Addresses without binary encoding use a
+// fallback layout with 0xff prefix and sort after all other keys.
+func (a Address) Key() AddressKey {
+	var k AddressKey
+	if buf := a.Bytes22(); buf != nil {
+		copy(k[:], buf)
+		return k
+	}
+	k[0] = 0xff
+	k[1] = byte(a.Type)
+	copy(k[2:], a.Hash)
+	return k
+}
+
+// Address converts the key back into an address.
+func (k AddressKey) Address() Address {
+	if k[0] == 0xff {
+		return NewAddress(AddressType(k[1]), k[2:])
+	}
+	a, _, err := DecodeAddress(k[:])
+	if err != nil {
+		return InvalidAddress
+	}
+	return a
+}
+
+func (k AddressKey) String() string {
+	return k.Address().String()
+}
+
+func (k AddressKey) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+func (k *AddressKey) UnmarshalText(data []byte) error {
+	a, err := ParseAddress(string(data))
+	if err != nil {
+		return err
+	}
+	*k = a.Key()
+	return nil
+}
+
+// AddressSet is a set of addresses keyed by AddressKey. The zero value is an
+// empty set ready to use. Sets encode as JSON arrays of base58 strings.
 type AddressSet struct {
-	set map[uint64]Address
+	set map[AddressKey]Address
 }
 
 func NewAddressSet(addrs ...Address) *AddressSet {
 	set := &AddressSet{
-		set: make(map[uint64]Address),
+		set: make(map[AddressKey]Address),
 	}
 	for _, v := range addrs {
 		if !v.IsValid() {
@@ -24,34 +79,58 @@ func NewAddressSet(addrs ...Address) *AddressSet {
 	return set
 }
 
-func (s AddressSet) hash(addr Address) uint64 {
-	h := hash.NewInlineFNV64a()
-	h.Write([]byte{byte(addr.Type)})
-	h.Write(addr.Hash)
-	return h.Sum64()
+func (s *AddressSet) init() {
+	if s.set == nil {
+		s.set = make(map[AddressKey]Address)
+	}
 }
 
+// AddUnique adds addr and returns true when it was not yet a member.
 func (s *AddressSet) AddUnique(addr Address) bool {
-	h := s.hash(addr)
-	_, ok := s.set[h]
-	s.set[h] = addr.Clone()
+	s.init()
+	k := addr.Key()
+	_, ok := s.set[k]
+	if !ok {
+		s.set[k] = addr.Clone()
+	}
 	return !ok
 }
 
 func (s *AddressSet) Add(addr Address) {
-	s.set[s.hash(addr)] = addr.Clone()
+	s.AddUnique(addr)
+}
+
+// Insert adds all addrs to the set.
+func (s *AddressSet) Insert(addrs ...Address) {
+	for _, v := range addrs {
+		s.AddUnique(v)
+	}
 }
 
 func (s *AddressSet) Remove(addr Address) {
-	delete(s.set, s.hash(addr))
+	delete(s.set, addr.Key())
+}
+
+// Delete removes all addrs from the set.
+func (s *AddressSet) Delete(addrs ...Address) {
+	for _, v := range addrs {
+		delete(s.set, v.Key())
+	}
 }
 
 func (s AddressSet) Contains(addr Address) bool {
-	_, ok := s.set[s.hash(addr)]
+	_, ok := s.set[addr.Key()]
+	return ok
+}
+
+// ContainsKey returns true when the address with key k is a member.
+func (s AddressSet) ContainsKey(k AddressKey) bool {
+	_, ok := s.set[k]
 	return ok
 }
 
 func (s *AddressSet) Merge(b *AddressSet) {
+	s.init()
 	for n, v := range b.set {
 		s.set[n] = v.Clone()
 	}
@@ -61,10 +140,34 @@ func (s *AddressSet) Len() int {
 	return len(s.set)
 }
 
-func (s AddressSet) Map() map[uint64]Address {
+func (s AddressSet) Map() map[AddressKey]Address {
 	return s.set
 }
 
+// Range calls fn for each member in random order until fn returns false.
+func (s AddressSet) Range(fn func(Address) bool) {
+	for _, v := range s.set {
+		if !fn(v) {
+			return
+		}
+	}
+}
+
+// RangeSorted calls fn for each member in key order until fn returns false.
+// Use it when iteration order must be deterministic.
+func (s AddressSet) RangeSorted(fn func(Address) bool) {
+	keys := make([]AddressKey, 0, len(s.set))
+	for k := range s.set {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return bytes.Compare(keys[i][:], keys[j][:]) < 0 })
+	for _, k := range keys {
+		if !fn(s.set[k]) {
+			return
+		}
+	}
+}
+
 func (s AddressSet) Slice() []Address {
 	if len(s.set) == 0 {
 		return nil
@@ -75,3 +178,141 @@ func (s AddressSet) Slice() []Address {
 	}
 	return a
 }
+
+// Sorted returns set members as slice in canonical protocol order.
+func (s AddressSet) Sorted() []Address {
+	a := s.Slice()
+	SortAddresses(a)
+	return a
+}
+
+// MarshalJSON encodes the set as sorted array of base58 strings.
+func (s AddressSet) MarshalJSON() ([]byte, error) {
+	a := make([]Address, 0, len(s.set))
+	s.RangeSorted(func(v Address) bool {
+		a = append(a, v)
+		return true
+	})
+	return json.Marshal(a)
+}
+
+func (s *AddressSet) UnmarshalJSON(data []byte) error {
+	var a []Address
+	if err := json.Unmarshal(data, &a); err != nil {
+		return err
+	}
+	s.set = make(map[AddressKey]Address, len(a))
+	s.Insert(a...)
+	return nil
+}
+
+// AddressMap maps addresses to arbitrary values keyed by AddressKey. The zero
+// value is an empty map ready to use. Maps encode as JSON objects with base58
+// address keys.
+type AddressMap struct {
+	m map[AddressKey]interface{}
+}
+
+func NewAddressMap() *AddressMap {
+	return &AddressMap{
+		m: make(map[AddressKey]interface{}),
+	}
+}
+
+func (m *AddressMap) init() {
+	if m.m == nil {
+		m.m = make(map[AddressKey]interface{})
+	}
+}
+
+// Get returns the value stored for addr and true when addr is a member.
+func (m AddressMap) Get(addr Address) (interface{}, bool) {
+	v, ok := m.m[addr.Key()]
+	return v, ok
+}
+
+// GetKey returns the value stored for key k and true when k is a member.
+func (m AddressMap) GetKey(k AddressKey) (interface{}, bool) {
+	v, ok := m.m[k]
+	return v, ok
+}
+
+func (m *AddressMap) Set(addr Address, v interface{}) {
+	m.init()
+	m.m[addr.Key()] = v
+}
+
+// Delete removes all addrs from the map.
+func (m *AddressMap) Delete(addrs ...Address) {
+	for _, v := range addrs {
+		delete(m.m, v.Key())
+	}
+}
+
+func (m AddressMap) Contains(addr Address) bool {
+	_, ok := m.m[addr.Key()]
+	return ok
+}
+
+func (m AddressMap) Len() int {
+	return len(m.m)
+}
+
+// Range calls fn for each entry in random order until fn returns false.
+func (m AddressMap) Range(fn func(Address, interface{}) bool) {
+	for k, v := range m.m {
+		if !fn(k.Address(), v) {
+			return
+		}
+	}
+}
+
+// RangeSorted calls fn for each entry in key order until fn returns false.
+// Use it when iteration order must be deterministic.
+func (m AddressMap) RangeSorted(fn func(Address, interface{}) bool) {
+	for _, k := range m.sortedKeys() {
+		if !fn(k.Address(), m.m[k]) {
+			return
+		}
+	}
+}
+
+// Keys returns all addresses in key order.
+func (m AddressMap) Keys() []Address {
+	if len(m.m) == 0 {
+		return nil
+	}
+	a := make([]Address, 0, len(m.m))
+	for _, k := range m.sortedKeys() {
+		a = append(a, k.Address())
+	}
+	return a
+}
+
+func (m AddressMap) sortedKeys() []AddressKey {
+	keys := make([]AddressKey, 0, len(m.m))
+	for k := range m.m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return bytes.Compare(keys[i][:], keys[j][:]) < 0 })
+	return keys
+}
+
+// MarshalJSON encodes the map as JSON object with base58 address keys.
+func (m AddressMap) MarshalJSON() ([]byte, error) {
+	if m.m == nil {
+		return []byte("{}"), nil
+	}
+	return json.Marshal(m.m)
+}
+
+// UnmarshalJSON decodes a JSON object with base58 address keys. Values are
+// decoded into generic JSON types as with map[string]interface{}.
+func (m *AddressMap) UnmarshalJSON(data []byte) error {
+	mm := make(map[AddressKey]interface{})
+	if err := json.Unmarshal(data, &mm); err != nil {
+		return err
+	}
+	m.m = mm
+	return nil
+}
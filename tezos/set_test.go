@@ -0,0 +1,182 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package tezos
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/rand"
+	"testing"
+)
+
+func randAddresses(n int) []Address {
+	rnd := rand.New(rand.NewSource(1))
+	types := []AddressType{AddressTypeEd25519, AddressTypeSecp256k1, AddressTypeP256, AddressTypeContract, AddressTypeSmartRollup}
+	addrs := make([]Address, n)
+	for i := range addrs {
+		h := make([]byte, 20)
+		rnd.Read(h)
+		addrs[i] = NewAddress(types[rnd.Intn(len(types))], h)
+	}
+	return addrs
+}
+
+func TestAddressKey(t *testing.T) {
+	tz4 := NewAddress(AddressTypeBls12_381, bytes.Repeat([]byte{0xff}, 20))
+	for _, a := range append(randAddresses(100), tz4, NewAddress(AddressTypeBaker, make([]byte, 20))) {
+		if b := a.Key().Address(); !b.Equal(a) {
+			t.Errorf("key round-trip mismatch have=%s want=%s", b, a)
+		}
+	}
+	// keys and addresses order identical for tz/KT
+	tz := NewAddress(AddressTypeP256, bytes.Repeat([]byte{0xff}, 20))
+	kt := NewAddress(AddressTypeContract, make([]byte, 20))
+	s := NewAddressSet(kt, tz4, tz)
+	var order []Address
+	s.RangeSorted(func(a Address) bool {
+		order = append(order, a)
+		return true
+	})
+	if len(order) != 3 || !order[0].Equal(tz) || !order[1].Equal(tz4) || !order[2].Equal(kt) {
+		t.Errorf("unexpected order %v", order)
+	}
+	if tz4.Compare(kt) >= 0 || tz.Compare(tz4) >= 0 {
+		t.Errorf("key order differs from address order")
+	}
+	// map keys encode as base58 strings
+	m := map[AddressKey]int{tz.Key(): 1}
+	buf, err := json.Marshal(m)
+	if err != nil || string(buf) != `{"`+tz.String()+`":1}` {
+		t.Fatalf("marshal map: %s %v", buf, err)
+	}
+	m2 := make(map[AddressKey]int)
+	if err := json.Unmarshal(buf, &m2); err != nil || m2[tz.Key()] != 1 {
+		t.Errorf("unmarshal map: %v %v", m2, err)
+	}
+}
+
+func TestAddressSet(t *testing.T) {
+	addrs := randAddresses(50)
+	var s AddressSet
+	s.Insert(addrs...)
+	s.Insert(addrs[:10]...)
+	if s.Len() != 50 {
+		t.Fatalf("len have=%d want=50", s.Len())
+	}
+	for _, a := range addrs {
+		if !s.Contains(a) || !s.ContainsKey(a.Key()) {
+			t.Fatalf("missing %s", a)
+		}
+	}
+	if s.AddUnique(addrs[0]) {
+		t.Errorf("expected duplicate")
+	}
+	s.Delete(addrs[:5]...)
+	if s.Len() != 45 || s.Contains(addrs[0]) {
+		t.Errorf("delete failed")
+	}
+	n := 0
+	s.Range(func(Address) bool {
+		n++
+		return n < 3
+	})
+	if n != 3 {
+		t.Errorf("range did not stop, n=%d", n)
+	}
+	buf, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var s2 AddressSet
+	if err := json.Unmarshal(buf, &s2); err != nil || s2.Len() != 45 {
+		t.Fatalf("unmarshal: %v len=%d", err, s2.Len())
+	}
+	buf2, _ := json.Marshal(s2)
+	if string(buf) != string(buf2) {
+		t.Errorf("non-deterministic encoding")
+	}
+}
+
+func TestAddressMap(t *testing.T) {
+	addrs := randAddresses(50)
+	var m AddressMap
+	for i, a := range addrs {
+		m.Set(a, i)
+	}
+	if m.Len() != 50 {
+		t.Fatalf("len have=%d want=50", m.Len())
+	}
+	for i, a := range addrs {
+		if v, ok := m.Get(a); !ok || v != i {
+			t.Fatalf("get %s: have=%v want=%d", a, v, i)
+		}
+	}
+	m.Delete(addrs[:5]...)
+	if m.Len() != 45 || m.Contains(addrs[0]) {
+		t.Errorf("delete failed")
+	}
+	keys := m.Keys()
+	for i := 1; i < len(keys); i++ {
+		if keys[i-1].Compare(keys[i]) >= 0 {
+			t.Fatalf("keys not sorted at %d", i)
+		}
+	}
+	buf, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m2 AddressMap
+	if err := json.Unmarshal(buf, &m2); err != nil || m2.Len() != 45 {
+		t.Fatalf("unmarshal: %v len=%d", err, m2.Len())
+	}
+	m2.RangeSorted(func(a Address, v interface{}) bool {
+		if want, _ := m.Get(a); v != float64(want.(int)) {
+			t.Errorf("value mismatch for %s: have=%v want=%v", a, v, want)
+		}
+		return true
+	})
+}
+
+func BenchmarkAddressSetContains(b *testing.B) {
+	addrs := randAddresses(10000)
+	s := NewAddressSet(addrs...)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = s.Contains(addrs[i%len(addrs)])
+	}
+}
+
+func BenchmarkAddressKeyMap(b *testing.B) {
+	addrs := randAddresses(10000)
+	m := make(map[AddressKey]int64)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m[addrs[i%len(addrs)].Key()] += 1
+	}
+}
+
+func BenchmarkAddressStringMap(b *testing.B) {
+	addrs := randAddresses(10000)
+	m := make(map[string]int64)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m[addrs[i%len(addrs)].String()] += 1
+	}
+}
+
+func BenchmarkStringSetContains(b *testing.B) {
+	addrs := randAddresses(10000)
+	s := make(map[string]struct{})
+	for _, a := range addrs {
+		s[a.String()] = struct{}{}
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = s[addrs[i%len(addrs)].String()]
+	}
+}
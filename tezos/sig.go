@@ -5,6 +5,7 @@ package tezos
 
 import (
 	"bytes"
+	"crypto/elliptic"
 	"errors"
 	"fmt"
 	"strings"
@@ -36,10 +37,11 @@ const (
 	SignatureTypeP256
 	SignatureTypeGeneric
 	SignatureTypeInvalid
+	SignatureTypeBls12_381 // appended to keep the values of existing types stable
 )
 
 func (t SignatureType) IsValid() bool {
-	return t < SignatureTypeInvalid
+	return t < SignatureTypeInvalid || t == SignatureTypeBls12_381
 }
 
 func (t SignatureType) HashType() HashType {
@@ -52,6 +54,8 @@ func (t SignatureType) HashType() HashType {
 		return HashTypeSigP256
 	case SignatureTypeGeneric:
 		return HashTypeSigGeneric
+	case SignatureTypeBls12_381:
+		return HashTypeSigBls12_381
 	default:
 		return HashTypeInvalid
 	}
@@ -67,6 +71,8 @@ func (t SignatureType) PrefixBytes() []byte {
 		return P256_SIGNATURE_ID
 	case SignatureTypeGeneric:
 		return GENERIC_SIGNATURE_ID
+	case SignatureTypeBls12_381:
+		return BLS12_381_SIGNATURE_ID
 	default:
 		return nil
 	}
@@ -82,6 +88,8 @@ func (t SignatureType) Prefix() string {
 		return P256_SIGNATURE_PREFIX
 	case SignatureTypeGeneric:
 		return GENERIC_SIGNATURE_PREFIX
+	case SignatureTypeBls12_381:
+		return BLS12_381_SIGNATURE_PREFIX
 	default:
 		return ""
 	}
@@ -91,6 +99,8 @@ func (t SignatureType) String() string {
 	return t.Prefix()
 }
 
+// Tag returns the curve tag used in binary encodings. Generic signatures
+// have no tag.
 func (t SignatureType) Tag() byte {
 	switch t {
 	case SignatureTypeEd25519:
@@ -99,7 +109,7 @@ func (t SignatureType) Tag() byte {
 		return 1
 	case SignatureTypeP256:
 		return 2
-	case SignatureTypeGeneric:
+	case SignatureTypeBls12_381:
 		return 3
 	default:
 		return 255
@@ -115,7 +125,7 @@ func ParseSignatureTag(b byte) SignatureType {
 	case 2:
 		return SignatureTypeP256
 	case 3:
-		return SignatureTypeGeneric
+		return SignatureTypeBls12_381
 	default:
 		return SignatureTypeInvalid
 	}
@@ -127,6 +137,7 @@ func HasSignaturePrefix(s string) bool {
 		SECP256K1_SIGNATURE_PREFIX,
 		P256_SIGNATURE_PREFIX,
 		GENERIC_SIGNATURE_PREFIX,
+		BLS12_381_SIGNATURE_PREFIX,
 	} {
 		if strings.HasPrefix(s, prefix) {
 			return true
@@ -136,10 +147,14 @@ func HasSignaturePrefix(s string) bool {
 }
 
 func (t SignatureType) Len() int {
-	if t.IsValid() {
+	switch {
+	case t == SignatureTypeBls12_381:
+		return 96
+	case t.IsValid():
 		return 64
+	default:
+		return 0
 	}
-	return 0
 }
 
 func IsSignature(s string) bool {
@@ -148,6 +163,7 @@ func IsSignature(s string) bool {
 		SECP256K1_SIGNATURE_PREFIX,
 		P256_SIGNATURE_PREFIX,
 		GENERIC_SIGNATURE_PREFIX,
+		BLS12_381_SIGNATURE_PREFIX,
 	} {
 		if strings.HasPrefix(s, prefix) {
 			return true
@@ -215,9 +231,61 @@ func (s *Signature) UnmarshalText(data []byte) error {
 }
 
 func (s Signature) Bytes() []byte {
+	if s.Type == SignatureTypeGeneric {
+		return append([]byte{}, s.Data...)
+	}
 	return append([]byte{s.Type.Tag()}, s.Data...)
 }
 
+// EncodeBinary returns the binary encoding of a signature. Typed signatures
+// start with a one byte curve tag, generic signatures are written without tag
+// as used in operations where the curve is implied by the signer.
+func (s Signature) EncodeBinary() ([]byte, error) {
+	if !s.IsValid() {
+		return nil, fmt.Errorf("tezos: invalid %s signature length %d", s.Type, len(s.Data))
+	}
+	return s.Bytes(), nil
+}
+
+// DecodeSignature reads a tagged binary signature from the start of buf and
+// returns the signature and the number of bytes read. See EncodeBinary and
+// DecodeGenericSignature for untagged signatures.
+func DecodeSignature(buf []byte) (Signature, int, error) {
+	if len(buf) == 0 {
+		return InvalidSignature, 0, fmt.Errorf("tezos: short binary signature length %d", len(buf))
+	}
+	typ := ParseSignatureTag(buf[0])
+	if !typ.IsValid() {
+		return InvalidSignature, 0, fmt.Errorf("tezos: invalid binary signature type %x", buf[0])
+	}
+	n := typ.Len() + 1
+	if len(buf) < n {
+		return InvalidSignature, 0, fmt.Errorf("tezos: short binary %s signature length %d", typ, len(buf))
+	}
+	return NewSignature(typ, append([]byte{}, buf[1:n]...)), n, nil
+}
+
+// DecodeGenericSignature reads an untagged 64 byte signature from the start
+// of buf and returns a generic signature and the number of bytes read.
+func DecodeGenericSignature(buf []byte) (Signature, int, error) {
+	n := SignatureTypeGeneric.Len()
+	if len(buf) < n {
+		return InvalidSignature, 0, fmt.Errorf("tezos: short binary signature length %d", len(buf))
+	}
+	return NewSignature(SignatureTypeGeneric, append([]byte{}, buf[:n]...)), n, nil
+}
+
+// DecodeBlsSignature reads an untagged 96 byte BLS12-381 signature from the
+// start of buf as used in Michelson values and returns the signature and the
+// number of bytes read.
+func DecodeBlsSignature(buf []byte) (Signature, int, error) {
+	n := SignatureTypeBls12_381.Len()
+	if len(buf) < n {
+		return InvalidSignature, 0, fmt.Errorf("tezos: short binary bls12_381 signature length %d", len(buf))
+	}
+	return NewSignature(SignatureTypeBls12_381, append([]byte{}, buf[:n]...)), n, nil
+}
+
 func (s *Signature) DecodeBuffer(buf *bytes.Buffer) error {
 	l := buf.Len()
 	if l < 64 {
@@ -234,6 +302,9 @@ func (s *Signature) DecodeBuffer(buf *bytes.Buffer) error {
 		}
 	}
 	l = s.Type.Len()
+	if buf.Len() < l {
+		return fmt.Errorf("tezos: invalid %s signature length %d", s.Type, buf.Len())
+	}
 	s.Data = make([]byte, l)
 	copy(s.Data, buf.Next(l))
 	if !s.IsValid() {
@@ -253,8 +324,8 @@ func (s *Signature) UnmarshalBinary(b []byte) error {
 	switch len(b) {
 	case 64:
 		s.Type = SignatureTypeGeneric
-	case 65:
-		if typ := ParseSignatureTag(b[0]); !typ.IsValid() {
+	case 65, 97:
+		if typ := ParseSignatureTag(b[0]); typ.Len() != len(b)-1 {
 			return fmt.Errorf("tezos: invalid binary signature type %x", b[0])
 		} else {
 			s.Type = typ
@@ -268,7 +339,7 @@ func (s *Signature) UnmarshalBinary(b []byte) error {
 	} else {
 		s.Data = s.Data[:s.Type.Len()]
 	}
-	copy(s.Data, b[1:])
+	copy(s.Data, b)
 	return nil
 }
 
@@ -291,6 +362,10 @@ func ParseSignature(s string) (Signature, error) {
 		dec, ver, err = base58.CheckDecode(s, 4, nil)
 		typ = SignatureTypeP256
 
+	case strings.HasPrefix(s, BLS12_381_SIGNATURE_PREFIX):
+		dec, ver, err = base58.CheckDecode(s, 4, nil)
+		typ = SignatureTypeBls12_381
+
 	case strings.HasPrefix(s, GENERIC_SIGNATURE_PREFIX):
 		dec, ver, err = base58.CheckDecode(s, 3, nil)
 		typ = SignatureTypeGeneric
@@ -327,3 +402,33 @@ func MustParseSignature(s string) Signature {
 	}
 	return sig
 }
+
+// ParseDERSignature converts an ASN.1 DER encoded ECDSA signature as produced
+// by HSMs and cloud key managers into a Tezos signature of type typ. S is
+// normalized to low-S form as required by the node.
+func ParseDERSignature(typ SignatureType, der []byte) (Signature, error) {
+	var curve elliptic.Curve
+	switch typ {
+	case SignatureTypeSecp256k1:
+		curve = KeyTypeSecp256k1.Curve()
+	case SignatureTypeP256:
+		curve = KeyTypeP256.Curve()
+	default:
+		return Signature{}, fmt.Errorf("tezos: DER encoding not supported for %s signatures", typ)
+	}
+	buf, err := ecDecodeDER(der, curve)
+	if err != nil {
+		return Signature{}, err
+	}
+	return NewSignature(typ, buf), nil
+}
+
+// DER returns the ASN.1 DER encoding of a secp256k1 or P-256 signature.
+func (s Signature) DER() ([]byte, error) {
+	switch s.Type {
+	case SignatureTypeSecp256k1, SignatureTypeP256:
+		return ecEncodeDER(s.Data)
+	default:
+		return nil, fmt.Errorf("tezos: DER encoding not supported for %s signatures", s.Type)
+	}
+}
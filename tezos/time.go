@@ -0,0 +1,91 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package tezos
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Timestamp represents a Tezos timestamp with second precision. Depending on
+// context nodes encode timestamps as RFC3339 strings or as unix seconds (either
+// as JSON number or numeric string). Timestamp accepts all forms and always
+// marshals to RFC3339.
+type Timestamp int64
+
+// NewTimestamp converts t to a Timestamp, truncating sub-second precision.
+func NewTimestamp(t time.Time) Timestamp {
+	return Timestamp(t.Unix())
+}
+
+// ParseTimestamp parses RFC3339 strings and decimal unix seconds.
+func ParseTimestamp(s string) (Timestamp, error) {
+	if s == "" {
+		return 0, fmt.Errorf("tezos: empty timestamp")
+	}
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return Timestamp(i), nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return 0, fmt.Errorf("tezos: invalid timestamp %q: %w", s, err)
+	}
+	return NewTimestamp(t), nil
+}
+
+// Time returns the timestamp as UTC time.
+func (t Timestamp) Time() time.Time {
+	return time.Unix(int64(t), 0).UTC()
+}
+
+// Unix returns the timestamp as unix seconds.
+func (t Timestamp) Unix() int64 {
+	return int64(t)
+}
+
+// IsZero returns true when the timestamp is unset.
+func (t Timestamp) IsZero() bool {
+	return t == 0
+}
+
+// String returns the RFC3339 encoding of the timestamp.
+func (t Timestamp) String() string {
+	return t.Time().Format(time.RFC3339)
+}
+
+func (t Timestamp) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+func (t *Timestamp) UnmarshalText(data []byte) error {
+	v, err := ParseTimestamp(string(data))
+	if err != nil {
+		return err
+	}
+	*t = v
+	return nil
+}
+
+// UnmarshalJSON accepts quoted RFC3339 or numeric strings as well as plain
+// JSON numbers. Null values are ignored.
+func (t *Timestamp) UnmarshalJSON(data []byte) error {
+	if len(data) == 0 || bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+	if data[0] == '"' {
+		s, err := strconv.Unquote(string(data))
+		if err != nil {
+			return fmt.Errorf("tezos: invalid timestamp %s: %w", string(data), err)
+		}
+		return t.UnmarshalText([]byte(s))
+	}
+	i, err := strconv.ParseInt(string(data), 10, 64)
+	if err != nil {
+		return fmt.Errorf("tezos: invalid timestamp %s: %w", string(data), err)
+	}
+	*t = Timestamp(i)
+	return nil
+}
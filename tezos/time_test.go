@@ -0,0 +1,30 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package tezos
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestTimestampJSON(t *testing.T) {
+	want := Timestamp(1647270566)
+	for _, in := range []string{
+		`"2022-03-14T15:09:26Z"`,
+		`"1647270566"`,
+		`1647270566`,
+	} {
+		var ts Timestamp
+		if err := json.Unmarshal([]byte(in), &ts); err != nil {
+			t.Fatalf("%s: unexpected error: %v", in, err)
+		}
+		if ts != want {
+			t.Errorf("%s: got=%d want=%d", in, ts, want)
+		}
+		buf, _ := json.Marshal(ts)
+		if got := string(buf); got != `"2022-03-14T15:09:26Z"` {
+			t.Errorf("%s: marshal got=%s", in, got)
+		}
+	}
+}
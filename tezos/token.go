@@ -0,0 +1,164 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package tezos
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+var (
+	// ErrTokenDecimals is returned when token amounts with different decimals
+	// are combined or compared.
+	ErrTokenDecimals = errors.New("tezos: token decimals mismatch")
+
+	// ErrTokenNegative is returned when an arithmetic operation would produce
+	// a negative token amount.
+	ErrTokenNegative = errors.New("tezos: negative token amount")
+
+	// ErrTokenPrecision is returned when a strictly parsed amount has more
+	// fractional digits than the token's decimals.
+	ErrTokenPrecision = errors.New("tezos: token amount exceeds precision")
+)
+
+// TokenParseMode defines how ParseTokenAmount handles fractional digits beyond
+// a token's decimals.
+type TokenParseMode byte
+
+const (
+	// TokenParseStrict rejects amounts with excess precision.
+	TokenParseStrict TokenParseMode = iota
+	// TokenParseTruncate drops excess fractional digits.
+	TokenParseTruncate
+)
+
+// TokenAmount is a raw token balance (as stored in FA1.2 and FA2 ledgers) with
+// the number of decimals from the token's TZIP-12 metadata used for display.
+// Arithmetic and comparison refuse to mix amounts of different decimals.
+type TokenAmount struct {
+	Value    Z   `json:"value"`
+	Decimals int `json:"decimals"`
+}
+
+// NewTokenAmount creates a token amount from a raw value.
+func NewTokenAmount(value Z, decimals int) TokenAmount {
+	return TokenAmount{Value: value.Clone(), Decimals: decimals}
+}
+
+// ParseTokenAmount parses a human readable decimal string such as "1.5" into
+// a raw token amount, e.g. 1500000 with 6 decimals. Mode defines whether
+// excess fractional digits are rejected or truncated.
+func ParseTokenAmount(s string, decimals int, mode TokenParseMode) (TokenAmount, error) {
+	if decimals < 0 {
+		return TokenAmount{}, fmt.Errorf("tezos: invalid token decimals %d", decimals)
+	}
+	neg, digits, excess, ok := scaleDecimal(s, decimals)
+	if !ok {
+		return TokenAmount{}, fmt.Errorf("tezos: invalid token amount %q", s)
+	}
+	if mode == TokenParseStrict && strings.TrimRight(excess, "0") != "" {
+		return TokenAmount{}, fmt.Errorf("%w: %q has more than %d decimals", ErrTokenPrecision, s, decimals)
+	}
+	x, ok := new(big.Int).SetString(digits, 10)
+	if !ok {
+		return TokenAmount{}, fmt.Errorf("tezos: invalid token amount %q", s)
+	}
+	if neg {
+		x.Neg(x)
+	}
+	var a TokenAmount
+	a.Value.Set(x)
+	a.Decimals = decimals
+	return a, nil
+}
+
+// Big returns the raw amount.
+func (a TokenAmount) Big() *big.Int {
+	return new(big.Int).Set(a.Value.Big())
+}
+
+// IsZero returns true when the amount is zero.
+func (a TokenAmount) IsZero() bool {
+	return a.Value.Big().Sign() == 0
+}
+
+// Equal returns true when both amounts have the same value and decimals.
+func (a TokenAmount) Equal(x TokenAmount) bool {
+	return a.Decimals == x.Decimals && a.Value.Equal(x.Value)
+}
+
+// Cmp compares a and x and returns -1, 0 or +1. It fails when decimals differ.
+func (a TokenAmount) Cmp(x TokenAmount) (int, error) {
+	if a.Decimals != x.Decimals {
+		return 0, ErrTokenDecimals
+	}
+	return a.Value.Big().Cmp(x.Value.Big()), nil
+}
+
+// Add returns a + x or an error when decimals differ.
+func (a TokenAmount) Add(x TokenAmount) (TokenAmount, error) {
+	if a.Decimals != x.Decimals {
+		return TokenAmount{}, ErrTokenDecimals
+	}
+	var r TokenAmount
+	r.Value.Set(new(big.Int).Add(a.Value.Big(), x.Value.Big()))
+	r.Decimals = a.Decimals
+	return r, nil
+}
+
+// Sub returns a - x or an error when decimals differ or the result would be
+// negative.
+func (a TokenAmount) Sub(x TokenAmount) (TokenAmount, error) {
+	if a.Decimals != x.Decimals {
+		return TokenAmount{}, ErrTokenDecimals
+	}
+	v := new(big.Int).Sub(a.Value.Big(), x.Value.Big())
+	if v.Sign() < 0 {
+		return TokenAmount{}, ErrTokenNegative
+	}
+	var r TokenAmount
+	r.Value.Set(v)
+	r.Decimals = a.Decimals
+	return r, nil
+}
+
+// Mul returns a * n or an error when n is negative.
+func (a TokenAmount) Mul(n int64) (TokenAmount, error) {
+	if n < 0 {
+		return TokenAmount{}, ErrTokenNegative
+	}
+	var r TokenAmount
+	r.Value.Set(new(big.Int).Mul(a.Value.Big(), big.NewInt(n)))
+	r.Decimals = a.Decimals
+	return r, nil
+}
+
+// String formats the amount with all decimals, e.g. 1.500000.
+func (a TokenAmount) String() string {
+	return a.FormatAmount(a.Decimals, "")
+}
+
+// FormatAmount formats the amount rounded to prec decimals (0..Decimals) and
+// groups integer digits in thousands separated by sep when sep is not empty.
+func (a TokenAmount) FormatAmount(prec int, sep string) string {
+	if prec < 0 {
+		prec = 0
+	}
+	if prec > a.Decimals {
+		prec = a.Decimals
+	}
+	u := new(big.Int).Abs(a.Value.Big())
+	if drop := a.Decimals - prec; drop > 0 {
+		// round half away from zero
+		scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(drop)), nil)
+		q, r := new(big.Int).QuoRem(u, scale, new(big.Int))
+		if r.Lsh(r, 1).Cmp(scale) >= 0 {
+			q.Add(q, big.NewInt(1))
+		}
+		u = q
+	}
+	return formatDecimal(a.Value.Big().Sign() < 0, u.Text(10), prec, sep)
+}
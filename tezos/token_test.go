@@ -0,0 +1,101 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package tezos
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseTokenAmount(t *testing.T) {
+	for _, test := range []struct {
+		s        string
+		decimals int
+		mode     TokenParseMode
+		want     string
+		err      bool
+	}{
+		{"1.5", 6, TokenParseStrict, "1500000", false},
+		{"1", 0, TokenParseStrict, "1", false},
+		{"0.000001", 6, TokenParseStrict, "1", false},
+		{"1.50", 1, TokenParseStrict, "15", false},
+		{"1.55", 1, TokenParseStrict, "", true},
+		{"1.55", 1, TokenParseTruncate, "15", false},
+		{"123456789012345678901234567890", 18, TokenParseStrict, "123456789012345678901234567890000000000000000000", false},
+		{"-2.5", 2, TokenParseStrict, "-250", false},
+		{"1e5", 2, TokenParseStrict, "", true},
+		{"", 2, TokenParseStrict, "", true},
+	} {
+		a, err := ParseTokenAmount(test.s, test.decimals, test.mode)
+		if (err != nil) != test.err {
+			t.Errorf("%q: unexpected error %v", test.s, err)
+			continue
+		}
+		if err == nil && (a.Value.String() != test.want || a.Decimals != test.decimals) {
+			t.Errorf("%q: have=%s/%d want=%s", test.s, a.Value, a.Decimals, test.want)
+		}
+	}
+	if _, err := ParseTokenAmount("1.55", 1, TokenParseStrict); !errors.Is(err, ErrTokenPrecision) {
+		t.Errorf("expected precision error, got %v", err)
+	}
+}
+
+func TestTokenAmountFormat(t *testing.T) {
+	for _, test := range []struct {
+		v        int64
+		decimals int
+		prec     int
+		sep      string
+		want     string
+	}{
+		{1500000, 6, 6, "", "1.500000"},
+		{1500000, 6, 0, "", "2"},
+		{1234567891, 6, 2, ",", "1,234.57"},
+		{5, 6, 6, "", "0.000005"},
+		{42, 0, 2, ",", "42"},
+		{-1250, 3, 1, "", "-1.3"},
+	} {
+		a := NewTokenAmount(NewZ(test.v), test.decimals)
+		if have := a.FormatAmount(test.prec, test.sep); have != test.want {
+			t.Errorf("%d/%d: have=%s want=%s", test.v, test.decimals, have, test.want)
+		}
+	}
+	if s := NewTokenAmount(NewZ(15), 1).String(); s != "1.5" {
+		t.Errorf("string: %s", s)
+	}
+}
+
+func TestTokenAmountArithmetic(t *testing.T) {
+	a := NewTokenAmount(NewZ(100), 2)
+	b := NewTokenAmount(NewZ(30), 2)
+	if r, err := a.Add(b); err != nil || r.Value.Int64() != 130 || r.Decimals != 2 {
+		t.Errorf("add: %v %v", r, err)
+	}
+	if r, err := a.Sub(b); err != nil || r.Value.Int64() != 70 {
+		t.Errorf("sub: %v %v", r, err)
+	}
+	if _, err := b.Sub(a); err != ErrTokenNegative {
+		t.Errorf("expected negative error, got %v", err)
+	}
+	if r, err := a.Mul(3); err != nil || r.Value.Int64() != 300 {
+		t.Errorf("mul: %v %v", r, err)
+	}
+	if c, err := a.Cmp(b); err != nil || c != 1 {
+		t.Errorf("cmp: %d %v", c, err)
+	}
+	c := NewTokenAmount(NewZ(100), 6)
+	if _, err := a.Add(c); err != ErrTokenDecimals {
+		t.Errorf("expected decimals error, got %v", err)
+	}
+	if _, err := a.Cmp(c); err != ErrTokenDecimals {
+		t.Errorf("expected decimals error, got %v", err)
+	}
+	if a.Equal(c) {
+		t.Errorf("amounts with different decimals must not be equal")
+	}
+	// operands must not be modified
+	if a.Value.Int64() != 100 || b.Value.Int64() != 30 {
+		t.Errorf("operand modified")
+	}
+}
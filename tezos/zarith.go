@@ -215,7 +215,7 @@ func (n *N) DecodeBuffer(buf *bytes.Buffer) error {
             return io.ErrShortBuffer
         }
         if b[0] < 0x80 {
-            if i > 9 || i == 9 && b[0] > 1 {
+            if i > 9 || i == 9 && b[0] > 0 {
                 return fmt.Errorf("tezos: numeric overflow")
             }
             x = x | int64(b[0])<<s
@@ -230,6 +230,9 @@ func (n *N) DecodeBuffer(buf *bytes.Buffer) error {
 
 func (n N) EncodeBuffer(buf *bytes.Buffer) error {
     x := int64(n)
+    if x < 0 {
+        return fmt.Errorf("tezos: negative natural number %d", x)
+    }
     for x >= 0x80 {
         buf.WriteByte(byte(x) | 0x80)
         x >>= 7
@@ -0,0 +1,96 @@
+// Copyright (c) 2020-2022 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package tezos
+
+import (
+	"bytes"
+	"math"
+	"math/big"
+	"math/rand"
+	"testing"
+)
+
+func TestZarithZRoundTrip(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	vals := []*big.Int{
+		big.NewInt(0),
+		big.NewInt(1),
+		big.NewInt(-1),
+		big.NewInt(0x3f),
+		big.NewInt(-0x3f),
+		big.NewInt(0x40),
+		big.NewInt(-0x40),
+		big.NewInt(math.MaxInt64),
+		big.NewInt(math.MinInt64),
+	}
+	for i := 0; i < 1000; i++ {
+		x := new(big.Int).Rand(rnd, new(big.Int).Lsh(big.NewInt(1), uint(rnd.Intn(256)+1)))
+		if rnd.Intn(2) == 0 {
+			x.Neg(x)
+		}
+		vals = append(vals, x)
+	}
+	for _, x := range vals {
+		var z Z
+		z.Set(x)
+		buf, err := z.MarshalBinary()
+		if err != nil {
+			t.Fatalf("%s: marshal: %v", x, err)
+		}
+		if neg := buf[0]&0x40 > 0; neg != (x.Sign() < 0) {
+			t.Errorf("%s: wrong sign bit in %x", x, buf)
+		}
+		var z2 Z
+		b := bytes.NewBuffer(append(buf, 0xaa))
+		if err := z2.DecodeBuffer(b); err != nil {
+			t.Fatalf("%s: decode %x: %v", x, buf, err)
+		}
+		if z2.Big().Cmp(x) != 0 {
+			t.Errorf("round-trip mismatch have=%s want=%s enc=%x", z2.Big(), x, buf)
+		}
+		if b.Len() != 1 {
+			t.Errorf("%s: decode consumed %d extra bytes", x, 1-b.Len())
+		}
+		if err := z2.UnmarshalBinary(buf[:len(buf)-1]); len(buf) > 1 && err == nil {
+			t.Errorf("%s: expected short buffer error", x)
+		}
+	}
+}
+
+func TestZarithNRoundTrip(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	vals := []int64{0, 1, 0x7f, 0x80, 0x3fff, 0x4000, math.MaxInt64}
+	for i := 0; i < 1000; i++ {
+		vals = append(vals, rnd.Int63()>>uint(rnd.Intn(63)))
+	}
+	for _, x := range vals {
+		buf, err := NewN(x).MarshalBinary()
+		if err != nil {
+			t.Fatalf("%d: marshal: %v", x, err)
+		}
+		var n N
+		if err := n.UnmarshalBinary(buf); err != nil {
+			t.Fatalf("%d: unmarshal %x: %v", x, buf, err)
+		}
+		if n.Int64() != x {
+			t.Errorf("round-trip mismatch have=%d want=%d enc=%x", n, x, buf)
+		}
+		// N and Z agree on the magnitude bits of non-negative numbers
+		var z Z
+		z.SetInt64(x)
+		zbuf, _ := z.MarshalBinary()
+		if len(zbuf) < len(buf) {
+			t.Errorf("%d: unexpected zarith length %d < %d", x, len(zbuf), len(buf))
+		}
+	}
+	if _, err := NewN(-1).MarshalBinary(); err == nil {
+		t.Errorf("expected error for negative natural")
+	}
+	// 2^63 does not fit int64
+	var n N
+	over := []byte{0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x01}
+	if err := n.UnmarshalBinary(over); err == nil {
+		t.Errorf("expected overflow error, got %d", n)
+	}
+}